@@ -5,8 +5,10 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/joho/godotenv"
 
+	"github.com/lealre/movies-backend/internal/archive"
 	"github.com/lealre/movies-backend/internal/auth"
 	"github.com/lealre/movies-backend/internal/models"
 	"github.com/lealre/movies-backend/internal/postgres"
@@ -26,6 +29,8 @@ func main() {
 
 	migrate := flag.Bool("migrate", false, "apply the embedded goose schema migrations")
 	superuser := flag.Bool("superuser", false, "create a superuser if it does not exist")
+	exportPath := flag.String("export", "", "write a portable archive of the whole instance to `file`")
+	importPath := flag.String("import", "", "restore the portable archive in `file` into an empty, migrated database")
 	flag.Parse()
 
 	ctx := context.Background()
@@ -48,12 +53,72 @@ func main() {
 		}
 		fmt.Println("✅ Superuser command ran successfully!")
 
+	case *exportPath != "":
+		pool, err := postgres.Connect(ctx)
+		if err != nil {
+			log.Fatalf("Failed to connect to Postgres: %v", err)
+		}
+		defer pool.Close()
+		counts, err := exportArchive(ctx, postgres.New(pool), *exportPath)
+		if err != nil {
+			log.Fatalf("Failed to export the instance: %v", err)
+		}
+		fmt.Printf("✅ Exported %s to %s\n", formatCounts(counts), *exportPath)
+
+	case *importPath != "":
+		pool, err := postgres.Connect(ctx)
+		if err != nil {
+			log.Fatalf("Failed to connect to Postgres: %v", err)
+		}
+		defer pool.Close()
+		counts, err := archive.Import(ctx, postgres.New(pool), func() (io.ReadCloser, error) { return os.Open(*importPath) })
+		if err != nil {
+			log.Fatalf("Failed to import %s: %v", *importPath, err)
+		}
+		fmt.Printf("✅ Imported %s from %s\n", formatCounts(counts), *importPath)
+
 	default:
 		fmt.Println("No valid command specified.")
 		flag.Usage()
 	}
 }
 
+// exportArchive writes the instance's archive to path. It is written beside
+// path first and moved into place once complete, so a failed export never
+// leaves a truncated file where the last good one was.
+func exportArchive(ctx context.Context, db store.Archive, path string) (archive.Counts, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	counts, err := archive.Export(ctx, db, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func formatCounts(counts archive.Counts) string {
+	parts := []string{
+		fmt.Sprintf("%d users", counts[archive.KindUser]),
+		fmt.Sprintf("%d titles", counts[archive.KindTitle]),
+		fmt.Sprintf("%d groups", counts[archive.KindGroup]),
+		fmt.Sprintf("%d ratings", counts[archive.KindRating]),
+		fmt.Sprintf("%d comments", counts[archive.KindComment]),
+		fmt.Sprintf("%d activity events", counts[archive.KindActivityEvent]),
+	}
+	return strings.Join(parts, ", ")
+}
+
 func createSuperuser(ctx context.Context, db store.Store) error {
 	username := strings.TrimSpace(os.Getenv("SUPERUSER_USERNAME"))
 	email := strings.TrimSpace(os.Getenv("SUPERUSER_EMAIL"))
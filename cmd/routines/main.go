@@ -6,15 +6,18 @@ import (
 	"log"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/lealre/movies-backend/internal/models"
 	"github.com/lealre/movies-backend/internal/postgres"
+	"github.com/lealre/movies-backend/internal/services/availability"
 	"github.com/lealre/movies-backend/internal/services/titles"
 	"github.com/lealre/movies-backend/internal/store"
 	"github.com/lealre/movies-backend/internal/titleprovider"
 	"github.com/lealre/movies-backend/internal/titleprovider/factory"
+	"github.com/lealre/movies-backend/internal/titleprovider/streaming"
 )
 
 func main() {
@@ -25,12 +28,6 @@ func main() {
 	log.Println("🎬 Starting titles update...")
 	log.Println("==========================================")
 
-	provider, err := factory.NewFromEnv()
-	if err != nil {
-		log.Fatalf("Failed to build title provider: %v", err)
-	}
-	log.Printf("Using title provider: %s", provider.Name())
-
 	ctx := context.Background()
 	pool, err := postgres.Connect(ctx)
 	if err != nil {
@@ -40,6 +37,16 @@ func main() {
 
 	st := postgres.New(pool)
 
+	// No persistent cache tier here: this routine exists to fetch fresh data,
+	// and reading yesterday's fetch back out of Postgres would defeat it. OMDb
+	// requests are metered against the budget the server spends from too, as
+	// background work that leaves the interactive reserve alone.
+	provider, err := factory.NewFromEnv(factory.Options{Quota: st, Background: true})
+	if err != nil {
+		log.Fatalf("Failed to build title provider: %v", err)
+	}
+	log.Printf("Using title provider: %s", provider.Name())
+
 	log.Println("Fetching all title IDs from database...")
 	titleIDs, err := st.ListTitleIds(ctx)
 	if err != nil {
@@ -51,19 +58,59 @@ func main() {
 		log.Fatalf("Failed to sync titles: %v", err)
 	}
 	log.Println("Sync completed successfully")
+
+	streamingProvider, err := factory.NewStreamingFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to build streaming availability provider: %v", err)
+	}
+	if streamingProvider != nil {
+		log.Printf("Refreshing streaming availability from %s...", streamingProvider.Name())
+		refreshed := refreshAvailability(ctx, streamingProvider, st, titleIDs)
+		log.Printf("Refreshed streaming availability for %d of %d titles", refreshed, len(titleIDs))
+	}
+
+	removed, err := st.DeleteExpiredProviderCacheEntries(ctx)
+	if err != nil {
+		log.Fatalf("Failed to clean up the title provider cache: %v", err)
+	}
+	log.Printf("Removed %d expired title provider cache entries", removed)
 }
 
+// syncTitles refreshes every title in titleIDs. Once the provider reports its
+// daily quota spent, the remaining titles are skipped rather than tried one by
+// one — each would fail the same way — and left for the next day's run.
 func syncTitles(ctx context.Context, provider titleprovider.Provider, st *postgres.Store, titleIDs []string) error {
 	jobs := make(chan string, len(titleIDs))
 	wg := sync.WaitGroup{}
 	workerCount := 5
 
+	syncCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	var exhausted sync.Once
+	var skipped atomic.Int64
+
 	for i := 0; i < workerCount; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for titleID := range jobs {
-				if err := processTitle(ctx, provider, st, titleID); err != nil {
+				if syncCtx.Err() != nil {
+					skipped.Add(1)
+					continue
+				}
+				err := processTitle(syncCtx, provider, st, titleID)
+				switch {
+				case err == nil:
+				case errors.Is(err, titleprovider.ErrQuotaExhausted):
+					exhausted.Do(func() {
+						log.Printf("WARN: stopping the sync: %v", err)
+						stop()
+					})
+					skipped.Add(1)
+				case syncCtx.Err() != nil:
+					// Cut off mid-request by the stop above.
+					skipped.Add(1)
+				default:
 					log.Printf("failed processing %s: %v", titleID, err)
 				}
 			}
@@ -75,9 +122,44 @@ func syncTitles(ctx context.Context, provider titleprovider.Provider, st *postgr
 	}
 	close(jobs)
 	wg.Wait()
+
+	if n := skipped.Load(); n > 0 {
+		log.Printf("Skipped %d of %d titles for lack of title provider quota; the next run picks them up", n, len(titleIDs))
+	}
 	return nil
 }
 
+// refreshAvailability replaces the stored streaming availability of every title
+// in titleIDs and returns how many it refreshed. A title that fails keeps what
+// it had, and is retried on the next run.
+func refreshAvailability(ctx context.Context, provider streaming.Provider, st *postgres.Store, titleIDs []string) int64 {
+	jobs := make(chan string, len(titleIDs))
+	wg := sync.WaitGroup{}
+	workerCount := 5
+	var refreshed atomic.Int64
+
+	for i := 0; i < workerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for titleID := range jobs {
+				if err := availability.Refresh(st, ctx, provider, titleID); err != nil {
+					log.Printf("failed refreshing availability of %s: %v", titleID, err)
+					continue
+				}
+				refreshed.Add(1)
+			}
+		}()
+	}
+
+	for _, id := range titleIDs {
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+	return refreshed.Load()
+}
+
 func processTitle(ctx context.Context, provider titleprovider.Provider, st *postgres.Store, titleID string) error {
 	dbTitle, err := st.GetTitleById(ctx, titleID)
 	if err != nil {
@@ -92,6 +174,7 @@ func processTitle(ctx context.Context, provider titleprovider.Provider, st *post
 	if err != nil {
 		return err
 	}
+	apiTitle.Source = titleprovider.SourceOf(provider, apiTitle)
 
 	updated, changed := refreshTitle(dbTitle, apiTitle, time.Now())
 	if err := st.UpdateTitle(ctx, updated); err != nil {
@@ -107,9 +190,10 @@ func processTitle(ctx context.Context, provider titleprovider.Provider, st *post
 }
 
 // refreshTitle applies the provider's current values for the synced fields
-// (primaryImage, seasons, episodes, rating, metacritic) onto the stored
-// title, always stamping UpdatedAt with now — the same semantics the previous
-// routines had. The bool reports whether any content field changed.
+// (primaryImage, seasons, episodes, rating, metacritic, and the source they
+// came from when the provider reports one) onto the stored title, always
+// stamping UpdatedAt with now — the same semantics the previous routines had.
+// The bool reports whether any content field changed.
 func refreshTitle(dbTitle models.Title, apiTitle *titleprovider.Title, now time.Time) (models.Title, bool) {
 	changed := false
 
@@ -146,6 +230,11 @@ func refreshTitle(dbTitle models.Title, apiTitle *titleprovider.Title, now time.
 		changed = true
 	}
 
+	if apiTitle.Source != "" && dbTitle.Source != apiTitle.Source {
+		dbTitle.Source = apiTitle.Source
+		changed = true
+	}
+
 	dbTitle.UpdatedAt = &now
 	return dbTitle, changed
 }
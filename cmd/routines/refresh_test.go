@@ -57,4 +57,17 @@ func TestRefreshTitle(t *testing.T) {
 		assert.Equal(t, "a plot", got.Plot)
 		assert.Equal(t, []string{"Drama"}, got.Genres)
 	})
+
+	t.Run("the serving provider is recorded when it changes", func(t *testing.T) {
+		api := &titleprovider.Title{
+			PrimaryImage: titleprovider.Image{URL: "old.png", Width: 1, Height: 1},
+			Rating:       titleprovider.Rating{AggregateRating: 7.0, VoteCount: 10},
+			Source:       "omdb",
+		}
+		fromTmdb := base
+		fromTmdb.Source = "tmdb"
+		got, changed := refreshTitle(fromTmdb, api, now)
+		assert.True(t, changed)
+		assert.Equal(t, "omdb", got.Source)
+	})
 }
@@ -12,10 +12,16 @@
 // stored — the process can die, or the sink can be down, between commit and
 // flush (see the spec's "Delivery guarantee").
 //
+// Outbox mode (ACTIVITY_OUTBOX_ENABLED) closes that gap without changing a
+// single call site. The request runs in one store transaction, and the
+// buffered events are written to an outbox in that same transaction just
+// before it commits, so they land exactly when the write does. A Relay then
+// delivers them to the sinks after the commit, at least once.
+//
 // Record on a context with no recorder is a silent no-op — no panic, nothing
 // buffered. That is also how the feature turns off: when the flag is
 // disabled the middleware is never installed, no recorder is ever seeded, and
-// every one of the eleven call sites becomes a no-op without any of them
+// every one of the seventeen call sites becomes a no-op without any of them
 // having to know it.
 package activity
 
@@ -27,6 +33,7 @@ import (
 
 const (
 	KindTitleAdded           = "title_added"
+	KindTitlesAdded          = "titles_added"
 	KindTitleRemoved         = "title_removed"
 	KindTitleWatchedChanged  = "title_watched_changed"
 	KindRatingAdded          = "rating_added"
@@ -37,8 +44,21 @@ const (
 	KindCommentUpdated       = "comment_updated"
 	KindCommentDeleted       = "comment_deleted"
 	KindCommentSeasonDeleted = "comment_season_deleted"
+	KindMemberAdded          = "member_added"
+	KindMemberLeft           = "member_left"
+	KindGroupUpdated         = "group_updated"
+	KindGroupDeleted         = "group_deleted"
 )
 
+// Kinds is every kind above, for the places that take one from a client and
+// have to say which are valid (webhook subscriptions, feed filters).
+var Kinds = []string{
+	KindTitleAdded, KindTitlesAdded, KindTitleRemoved, KindTitleWatchedChanged,
+	KindRatingAdded, KindRatingUpdated, KindRatingDeleted, KindRatingSeasonDeleted,
+	KindCommentAdded, KindCommentUpdated, KindCommentDeleted, KindCommentSeasonDeleted,
+	KindMemberAdded, KindMemberLeft, KindGroupUpdated, KindGroupDeleted,
+}
+
 // Event is what happened, minus who and when: the actor and the timestamp are
 // stamped centrally at flush time from the request's authenticated user.
 type Event struct {
@@ -90,6 +110,27 @@ func TitleAdded(groupId, titleId, titleName string) Event {
 	return Event{GroupId: groupId, Kind: KindTitleAdded, TitleId: tid, TitleName: tname}
 }
 
+// titlesAddedListed is how many titles a TitlesAdded payload names; count
+// always carries the total.
+const titlesAddedListed = 10
+
+// TitlesAdded is one event for a bulk add, in place of a TitleAdded per title:
+// seeding a group with two hundred titles is one line in the feed — "added
+// Dune, Arrival and 198 more" — not two hundred. The payload names the first
+// titlesAddedListed of titleIds (with titleNames, index for index) and counts
+// them all; the event has no title of its own.
+func TitlesAdded(groupId string, titleIds, titleNames []string) Event {
+	n := min(len(titleIds), titlesAddedListed)
+	listed := make([]map[string]any, 0, n)
+	for i := range n {
+		listed = append(listed, map[string]any{"id": titleIds[i], "name": titleNames[i]})
+	}
+	return Event{GroupId: groupId, Kind: KindTitlesAdded, Payload: map[string]any{
+		"count":  len(titleIds),
+		"titles": listed,
+	}}
+}
+
 func TitleRemoved(groupId, titleId, titleName string) Event {
 	tid, tname := title(titleId, titleName)
 	return Event{GroupId: groupId, Kind: KindTitleRemoved, TitleId: tid, TitleName: tname}
@@ -200,3 +241,48 @@ func commentEvent(kind, groupId, titleId, titleName string, season *int) Event {
 	}
 	return Event{GroupId: groupId, Kind: kind, TitleId: tid, TitleName: tname, Payload: p}
 }
+
+// MemberAdded is the actor adding userId to the group. username is copied for
+// the reason actor_name is: the line has to stay readable after they leave.
+func MemberAdded(groupId, userId, username string) Event {
+	return Event{GroupId: groupId, Kind: KindMemberAdded, Payload: map[string]any{
+		"userId":   userId,
+		"username": username,
+	}}
+}
+
+// MemberLeft is the actor leaving the group. There is nothing to add: the
+// actor is the member. They do not see it themselves — they are its actor,
+// and by the time it is read they are no longer in the group.
+func MemberLeft(groupId string) Event {
+	return Event{GroupId: groupId, Kind: KindMemberLeft}
+}
+
+// GroupUpdated carries each group field that changed with its previous value,
+// the naming RatingUpdated set: name and previousName, description and
+// previousDescription. A field that did not change is absent, so the feed can
+// say "renamed" without comparing strings. The caller records it only when
+// something did change.
+func GroupUpdated(groupId, name, previousName, description, previousDescription string) Event {
+	p := map[string]any{}
+	if name != previousName {
+		p["name"] = name
+		p["previousName"] = previousName
+	}
+	if description != previousDescription {
+		p["description"] = description
+		p["previousDescription"] = previousDescription
+	}
+	return Event{GroupId: groupId, Kind: KindGroupUpdated, Payload: p}
+}
+
+// GroupDeleted is the actor deleting the group. memberIds is who was in it,
+// and it is not just for display: deleting a group ends every membership, so
+// this is the one event read by people no longer in its group, and memberIds
+// is how the feed and the hub know who they are (see activity_visible_events
+// and Hub.Publish).
+func GroupDeleted(groupId string, memberIds []string) Event {
+	return Event{GroupId: groupId, Kind: KindGroupDeleted, Payload: map[string]any{
+		"memberIds": memberIds,
+	}}
+}
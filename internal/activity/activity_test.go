@@ -2,6 +2,7 @@ package activity
 
 import (
 	"context"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -64,3 +65,32 @@ func TestRatingEventsCarryExactNotes(t *testing.T) {
 		t.Errorf("RatingSeasonDeleted previousNote = %v, want 8.7", seasonDeleted.Payload["previousNote"])
 	}
 }
+
+func TestTitlesAdded(t *testing.T) {
+	var ids, names []string
+	for i := range titlesAddedListed + 5 {
+		ids = append(ids, "tt"+strconv.Itoa(i))
+		names = append(names, "Title "+strconv.Itoa(i))
+	}
+
+	e := TitlesAdded("g1", ids, names)
+	require.Equal(t, KindTitlesAdded, e.Kind)
+	require.Nil(t, e.TitleId, "a bulk add is about no one title")
+	require.Equal(t, len(ids), e.Payload["count"])
+
+	listed := e.Payload["titles"].([]map[string]any)
+	require.Len(t, listed, titlesAddedListed, "only the first titles are named")
+	require.Equal(t, map[string]any{"id": "tt0", "name": "Title 0"}, listed[0])
+}
+
+func TestGroupUpdated(t *testing.T) {
+	renamed := GroupUpdated("g1", "Film club", "Movies", "same", "same")
+	require.Equal(t, map[string]any{"name": "Film club", "previousName": "Movies"}, renamed.Payload,
+		"only the field that changed is carried, with what it was")
+
+	both := GroupUpdated("g1", "b", "a", "new", "")
+	require.Equal(t, map[string]any{
+		"name": "b", "previousName": "a",
+		"description": "new", "previousDescription": "",
+	}, both.Payload, "a description added where there was none still says so")
+}
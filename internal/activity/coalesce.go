@@ -0,0 +1,205 @@
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// coalescedKinds are the kinds one user repeats in quick succession about the
+// same thing: edits of state that already exists. Each is a change from a
+// previous value to a current one (comment_updated, whose payload carries
+// neither, is just "edited"), so a run of them reads as one change from the
+// first previous value to the last current one. Adds and deletes are never
+// merged: each is a fact of its own.
+var coalescedKinds = []string{KindRatingUpdated, KindTitleWatchedChanged, KindCommentUpdated}
+
+// transactor is the part of store.ActivityOutbox a Coalescer needs. A store
+// that has it gets the lookup, the merge and the append in one transaction.
+type transactor interface {
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Coalescer is a Sink that merges an edit into the same user's previous edit
+// of the same thing, when it comes within window of it, before handing the
+// events on to sink. "The same thing" is the same kind, group, title and
+// season. Nudging a rating from 7.0 to 7.5 to 8.0 becomes one rating_updated
+// from 7.0 to 8.0; marking a title watched and then not watched again changes
+// nothing, and leaves nothing behind.
+//
+// It sits in front of every other sink, so the feed, the streams and webhooks
+// all see the same merged event. The merged event is the later one — its id,
+// its time, its current values — with the earlier one's previous values, and
+// the earlier one is deleted from the log: the one exception to its being
+// append-only. Anyone who has the earlier one already, on an open stream or
+// at a webhook, is sent the merged one as new; the earlier one is gone from
+// their feed the next time they read it.
+//
+// The earlier event is looked for in the batch first and then in the log.
+// With a store that has transactions the whole Append is one, so a failed
+// delivery leaves the log as it was and a retry merges the same way again.
+// A retry after a committed merge that came to nothing is the exception: the
+// earlier event is gone, so the later one is appended on its own.
+type Coalescer struct {
+	store  store.Store
+	window time.Duration
+	sink   Sink
+}
+
+// NewCoalescer returns a Coalescer in front of sink. A window of zero or less
+// merges nothing.
+func NewCoalescer(st store.Store, window time.Duration, sink Sink) *Coalescer {
+	return &Coalescer{store: st, window: window, sink: sink}
+}
+
+func (c *Coalescer) Append(ctx context.Context, events []models.ActivityEvent) error {
+	if c.window <= 0 || !slices.ContainsFunc(events, coalesced) {
+		return c.sink.Append(ctx, events)
+	}
+	if tx, ok := c.store.(transactor); ok {
+		return tx.InTransaction(ctx, func(ctx context.Context) error {
+			return c.append(ctx, events)
+		})
+	}
+	return c.append(ctx, events)
+}
+
+func (c *Coalescer) append(ctx context.Context, events []models.ActivityEvent) error {
+	out := make([]models.ActivityEvent, 0, len(events))
+	var superseded []string
+
+	for _, event := range events {
+		if !coalesced(event) {
+			out = append(out, event)
+			continue
+		}
+
+		if i := slices.IndexFunc(out, func(earlier models.ActivityEvent) bool {
+			return sameThing(earlier, event) && recordedAt(event).Sub(recordedAt(earlier)) <= c.window
+		}); i >= 0 {
+			merged, changed := merge(out[i], event)
+			out = slices.Delete(out, i, i+1)
+			if changed {
+				out = append(out, merged)
+			}
+			continue
+		}
+
+		earlier, err := c.store.GetCoalescibleActivityEvent(ctx, event, recordedAt(event).Add(-c.window))
+		if errors.Is(err, store.ErrRecordNotFound) {
+			out = append(out, event)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		superseded = append(superseded, earlier.Id)
+		if merged, changed := merge(earlier, event); changed {
+			out = append(out, merged)
+		}
+	}
+
+	// Appended before the earlier events are deleted: without a transaction,
+	// a failure in between leaves both rather than neither.
+	if len(out) > 0 {
+		if err := c.sink.Append(ctx, out); err != nil {
+			return err
+		}
+	}
+	for _, id := range superseded {
+		if err := c.store.DeleteActivityEvent(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func coalesced(event models.ActivityEvent) bool {
+	return event.TitleId != nil && slices.Contains(coalescedKinds, event.Kind)
+}
+
+// recordedAt is when event happened; one with no time yet is being recorded
+// now, as InsertActivityEvents will stamp it.
+func recordedAt(event models.ActivityEvent) time.Time {
+	if event.CreatedAt.IsZero() {
+		return time.Now()
+	}
+	return event.CreatedAt
+}
+
+// sameThing mirrors GetCoalescibleActivityEvent's match, for two events of
+// one batch.
+func sameThing(a, b models.ActivityEvent) bool {
+	return a.ActorId == b.ActorId &&
+		a.GroupId == b.GroupId &&
+		a.Kind == b.Kind &&
+		*a.TitleId == *b.TitleId &&
+		sameValue(a.Payload["season"], b.Payload["season"])
+}
+
+// merge is later with earlier's previous values, and whether the result still
+// changes anything. A payload with nothing to compare — comment_updated's —
+// always does.
+func merge(earlier, later models.ActivityEvent) (models.ActivityEvent, bool) {
+	payload := make(map[string]any, len(later.Payload))
+	for key, value := range later.Payload {
+		if !strings.HasPrefix(key, "previous") {
+			payload[key] = value
+		}
+	}
+	for key, value := range earlier.Payload {
+		if strings.HasPrefix(key, "previous") {
+			payload[key] = value
+		}
+	}
+	merged := later
+	merged.Payload = payload
+	return merged, changes(payload)
+}
+
+// changes reports whether payload's current values differ from its previous
+// ones. It pairs each field with its previous* counterpart by name — note and
+// previousNote, watchedAt and previousWatchedAt — so a field present on one
+// side only, a date added or cleared, is a change too.
+func changes(payload map[string]any) bool {
+	fields := map[string]bool{}
+	for key := range payload {
+		if field, ok := strings.CutPrefix(key, "previous"); ok && field != "" {
+			fields[strings.ToLower(field[:1])+field[1:]] = true
+		}
+	}
+	if len(fields) == 0 {
+		return true
+	}
+	for field := range fields {
+		previous := "previous" + strings.ToUpper(field[:1]) + field[1:]
+		if !sameValue(payload[field], payload[previous]) {
+			return true
+		}
+	}
+	// A current field with no previous one at all is a value that was added.
+	for key := range payload {
+		if key != "season" && !strings.HasPrefix(key, "previous") && !fields[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// sameValue compares two payload values as JSON, which is how they are stored:
+// one that has been through the log reads back as a float64 or a string where
+// the one just recorded is still an int or a time.Time.
+func sameValue(a, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
@@ -0,0 +1,170 @@
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// logStore is the activity log as a slice, and the sink that appends to it.
+// Payloads go through JSON on the way in, as they do through JSONB, so an
+// event read back is typed the way the real store's is.
+type logStore struct {
+	store.Store
+	events []models.ActivityEvent
+}
+
+func (s *logStore) Append(_ context.Context, events []models.ActivityEvent) error {
+	for _, e := range events {
+		raw, err := json.Marshal(e.Payload)
+		if err != nil {
+			return err
+		}
+		e.Payload = nil
+		if err := json.Unmarshal(raw, &e.Payload); err != nil {
+			return err
+		}
+		s.events = append(s.events, e)
+	}
+	return nil
+}
+
+func (s *logStore) GetCoalescibleActivityEvent(_ context.Context, event models.ActivityEvent, since time.Time) (models.ActivityEvent, error) {
+	for i := len(s.events) - 1; i >= 0; i-- {
+		e := s.events[i]
+		if e.Id != event.Id && sameThing(e, event) && !e.CreatedAt.Before(since) {
+			return e, nil
+		}
+	}
+	return models.ActivityEvent{}, store.ErrRecordNotFound
+}
+
+func (s *logStore) DeleteActivityEvent(_ context.Context, id string) error {
+	for i, e := range s.events {
+		if e.Id == id {
+			s.events = append(s.events[:i], s.events[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *logStore) payloads() []map[string]any {
+	out := make([]map[string]any, len(s.events))
+	for i, e := range s.events {
+		out[i] = e.Payload
+	}
+	return out
+}
+
+func TestCoalescer(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, time.March, 1, 20, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	stamp := func(id string, e Event, when time.Time) models.ActivityEvent {
+		return models.ActivityEvent{Id: id, GroupId: e.GroupId, ActorId: "alice", Kind: e.Kind,
+			TitleId: e.TitleId, TitleName: e.TitleName, Payload: e.Payload, CreatedAt: when}
+	}
+	one := func(e models.ActivityEvent) []models.ActivityEvent { return []models.ActivityEvent{e} }
+
+	t.Run("a run of edits is one event from the first previous value to the last", func(t *testing.T) {
+		log := &logStore{}
+		c := NewCoalescer(log, 2*time.Minute, log)
+
+		require.NoError(t, c.Append(ctx, one(stamp("e1", RatingUpdated("g1", "tt1", "Dune", 7.5, 7.0, nil), at(0)))))
+		require.NoError(t, c.Append(ctx, one(stamp("e2", RatingUpdated("g1", "tt1", "Dune", 8.0, 7.5, nil), at(90*time.Second)))))
+		require.NoError(t, c.Append(ctx, one(stamp("e3", RatingUpdated("g1", "tt1", "Dune", 8.5, 8.0, nil), at(3*time.Minute)))))
+
+		require.Len(t, log.events, 1, "each edit was within the window of the one before it")
+		require.Equal(t, "e3", log.events[0].Id, "the merged event is the latest one")
+		require.Equal(t, map[string]any{"note": 8.5, "previousNote": 7.0}, log.events[0].Payload)
+	})
+
+	t.Run("an edit back to where it started leaves nothing", func(t *testing.T) {
+		log := &logStore{}
+		c := NewCoalescer(log, 2*time.Minute, log)
+		watchedAt := at(-24 * time.Hour)
+
+		require.NoError(t, c.Append(ctx, one(stamp("e1",
+			TitleWatchedChanged("g1", "tt1", "Dune", WatchedState{Watched: true, WatchedAt: &watchedAt}, WatchedState{}, nil), at(0)))))
+		require.NoError(t, c.Append(ctx, one(stamp("e2",
+			TitleWatchedChanged("g1", "tt1", "Dune", WatchedState{}, WatchedState{Watched: true, WatchedAt: &watchedAt}, nil), at(time.Minute)))))
+
+		require.Empty(t, log.events, "watched and then not watched again is no change at all")
+	})
+
+	t.Run("a date added in between is still a change", func(t *testing.T) {
+		log := &logStore{}
+		c := NewCoalescer(log, 2*time.Minute, log)
+		watchedAt := at(-24 * time.Hour)
+
+		require.NoError(t, c.Append(ctx, one(stamp("e1",
+			TitleWatchedChanged("g1", "tt1", "Dune", WatchedState{Watched: true}, WatchedState{}, nil), at(0)))))
+		require.NoError(t, c.Append(ctx, one(stamp("e2",
+			TitleWatchedChanged("g1", "tt1", "Dune", WatchedState{Watched: true, WatchedAt: &watchedAt}, WatchedState{Watched: true}, nil), at(time.Minute)))))
+
+		require.Len(t, log.events, 1)
+		require.Equal(t, map[string]any{"watched": true, "previousWatched": false, "watchedAt": watchedAt.Format(time.RFC3339Nano)},
+			log.payloads()[0], "from not watched to watched on a date, with no previous date")
+	})
+
+	t.Run("only the same actor, kind, title and season within the window merge", func(t *testing.T) {
+		log := &logStore{}
+		c := NewCoalescer(log, 2*time.Minute, log)
+		season1, season2 := 1, 2
+
+		require.NoError(t, c.Append(ctx, one(stamp("e1", RatingUpdated("g1", "tt1", "Dune", 8, 7, &season1), at(0)))))
+		require.NoError(t, c.Append(ctx, one(stamp("e2", RatingUpdated("g1", "tt1", "Dune", 8, 7, &season2), at(10*time.Second)))))
+		require.NoError(t, c.Append(ctx, one(stamp("e3", RatingUpdated("g1", "tt2", "Arrival", 8, 7, nil), at(20*time.Second)))))
+		require.NoError(t, c.Append(ctx, one(stamp("e4", RatingUpdated("g2", "tt1", "Dune", 8, 7, nil), at(30*time.Second)))))
+		require.NoError(t, c.Append(ctx, one(stamp("e5", RatingAdded("g1", "tt1", "Dune", 8, &season1), at(40*time.Second)))))
+		other := stamp("e6", RatingUpdated("g1", "tt1", "Dune", 9, 8, &season1), at(50*time.Second))
+		other.ActorId = "bob"
+		require.NoError(t, c.Append(ctx, one(other)))
+		require.NoError(t, c.Append(ctx, one(stamp("e7", RatingUpdated("g1", "tt1", "Dune", 9, 8, &season1), at(5*time.Minute)))))
+
+		require.Len(t, log.events, 7, "nothing here is the same thing within the window")
+	})
+
+	t.Run("a season read back from the log still matches", func(t *testing.T) {
+		log := &logStore{}
+		c := NewCoalescer(log, 2*time.Minute, log)
+		season := 2
+
+		require.NoError(t, c.Append(ctx, one(stamp("e1", RatingUpdated("g1", "tt1", "Dune", 8, 7, &season), at(0)))))
+		require.NoError(t, c.Append(ctx, one(stamp("e2", RatingUpdated("g1", "tt1", "Dune", 9, 8, &season), at(time.Minute)))))
+
+		require.Len(t, log.events, 1, "an int season and the float64 it reads back as are one season")
+		require.Equal(t, 7.0, log.events[0].Payload["previousNote"])
+	})
+
+	t.Run("edits in one batch merge with each other", func(t *testing.T) {
+		log := &logStore{}
+		c := NewCoalescer(log, 2*time.Minute, log)
+
+		require.NoError(t, c.Append(ctx, []models.ActivityEvent{
+			stamp("e1", CommentUpdated("g1", "tt1", "Dune", nil), at(0)),
+			stamp("e2", TitleAdded("g1", "tt2", "Arrival"), at(0)),
+			stamp("e3", CommentUpdated("g1", "tt1", "Dune", nil), at(time.Second)),
+		}))
+
+		require.Equal(t, []string{"e2", "e3"}, []string{log.events[0].Id, log.events[1].Id},
+			"an edited comment is one edit however often it was saved")
+	})
+
+	t.Run("a zero window passes everything through", func(t *testing.T) {
+		log := &logStore{}
+		c := NewCoalescer(log, 0, log)
+
+		require.NoError(t, c.Append(ctx, one(stamp("e1", RatingUpdated("g1", "tt1", "Dune", 8, 7, nil), at(0)))))
+		require.NoError(t, c.Append(ctx, one(stamp("e2", RatingUpdated("g1", "tt1", "Dune", 7, 8, nil), at(time.Second)))))
+
+		require.Len(t, log.events, 2)
+	})
+}
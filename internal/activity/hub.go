@@ -1,7 +1,10 @@
 package activity
 
 import (
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/lealre/movies-backend/internal/models"
 )
@@ -13,22 +16,88 @@ import (
 // a bigger buffer.
 const subscriberBufferSize = 16
 
-// Subscriber is one connected client's mailbox. GroupIds is captured once, at
-// Subscribe time: a user who joins a group mid-stream starts seeing its
-// activity on their next connect, not retroactively. Re-resolving membership
-// on every Publish was rejected because it would turn every push into a
-// query — see the phase 2 design doc.
+// Subscriber is one connected client's mailbox. Its groups start as the
+// membership read when the client connected, and are kept current from then
+// on by ApplyMembership: a user added to a group starts receiving its events
+// on the stream they already have open, and one removed stops at once.
+// Re-resolving membership on every Publish was rejected because it would turn
+// every push into a query — see the phase 2 design doc — so membership
+// changes are pushed to the hub instead, the way events are. The user's
+// notification preferences are kept the same way, by ApplyPreferences.
 type Subscriber struct {
-	UserId   string
-	GroupIds []string
-	Events   chan models.ActivityEvent
+	UserId string
+	Events chan models.ActivityEvent
+
+	mu          sync.RWMutex
+	groupIds    []string
+	preferences preferences
+	// applied is set once ApplyPreferences has reached s, after which the
+	// hub's copy is at least as new as any read RefreshPreferences was given.
+	applied bool
+	changed chan struct{}
 
 	closeOnce sync.Once
+	dropped   atomic.Uint64
+}
+
+// GroupIds returns the groups s receives events for, as of now.
+func (s *Subscriber) GroupIds() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]string(nil), s.groupIds...)
+}
+
+// Changed is signalled after ApplyMembership changes s's groups, or
+// ApplyPreferences its user's preferences. It holds at most one signal, so a
+// reader that wakes up to it sees every change made before it calls GroupIds,
+// however many there were.
+func (s *Subscriber) Changed() <-chan struct{} {
+	return s.changed
+}
+
+// RefreshPreferences gives s prefs, read again after s was subscribed, and
+// signals Changed if they differ from the ones it was subscribed with. It
+// covers a change saved between the opener's first read and Subscribe, whose
+// notification may have reached the hub before s was in it. Once
+// ApplyPreferences has reached s it does nothing: changes are applied in
+// commit order from then on, and a read that raced one of them may be older.
+func (s *Subscriber) RefreshPreferences(prefs models.ActivityPreferences) {
+	p := newPreferences(prefs)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.applied || s.preferences.equal(p) {
+		return
+	}
+	s.preferences = p
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// QuietAt reports whether at falls in the quiet hours s was given, the same
+// test Publish makes before pushing anything to it.
+func (s *Subscriber) QuietAt(at time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.preferences.quietAt(at)
+}
+
+// Dropped reports how many events Publish has dropped for s because its
+// buffer was full. It only ever grows, so a reader that remembers the last
+// value it saw knows it has missed something when the value moves — which the
+// WebSocket handler turns into a resync rather than leaving the client to
+// notice on its own.
+func (s *Subscriber) Dropped() uint64 {
+	return s.dropped.Load()
 }
 
 // Hub is the in-process fan-out for activity events: one process-wide LISTEN
-// loop publishes into it, and every SSE connection on this process holds a
-// Subscriber registered with it.
+// loop publishes into it, and every stream connection on this process, SSE or
+// WebSocket, holds a Subscriber registered with it.
 //
 // It deliberately knows nothing about Postgres, sqlc, or HTTP — it deals only
 // in models.ActivityEvent and the Subscriber it hands back. The LISTEN loop
@@ -37,20 +106,28 @@ type Subscriber struct {
 type Hub struct {
 	mu          sync.RWMutex
 	subscribers map[*Subscriber]struct{}
+
+	// now is what quiet hours are checked against; tests in this package
+	// override it, as they do TicketStore's.
+	now func() time.Time
 }
 
 func NewHub() *Hub {
-	return &Hub{subscribers: make(map[*Subscriber]struct{})}
+	return &Hub{subscribers: make(map[*Subscriber]struct{}), now: time.Now}
 }
 
-// Subscribe registers a new subscriber and returns its mailbox. Callers must
-// Unsubscribe when the connection ends, or the entry — and its channel — leaks
-// for the life of the process.
-func (h *Hub) Subscribe(userId string, groupIds []string) *Subscriber {
+// Subscribe registers a new subscriber with prefs and returns its mailbox.
+// The preferences are in place before the subscriber is registered, so no
+// Publish can reach it without them. Callers must Unsubscribe when the
+// connection ends, or the entry — and its channel — leaks for the life of the
+// process.
+func (h *Hub) Subscribe(userId string, groupIds []string, prefs models.ActivityPreferences) *Subscriber {
 	s := &Subscriber{
-		UserId:   userId,
-		GroupIds: append([]string(nil), groupIds...),
-		Events:   make(chan models.ActivityEvent, subscriberBufferSize),
+		UserId:      userId,
+		Events:      make(chan models.ActivityEvent, subscriberBufferSize),
+		groupIds:    append([]string(nil), groupIds...),
+		preferences: newPreferences(prefs),
+		changed:     make(chan struct{}, 1),
 	}
 
 	h.mu.Lock()
@@ -93,33 +170,147 @@ func (h *Hub) SubscriberCount() int {
 //
 // The visibility predicate mirrors GetActivityFeedRows in
 // sql/queries/activity.sql: the event's group must be one of the subscriber's
-// groups, and the subscriber must not be the event's own actor. Keeping these
-// in sync is deliberate — if they diverge, the stream shows the reader
-// something the feed itself would not.
+// groups, and the subscriber must not be the event's own actor. A
+// group_deleted event is the exception, as it is in activity_visible_events:
+// the group's memberships are gone by the time it is published, so it goes to
+// whoever its memberIds name instead. Keeping these in sync is deliberate — if
+// they diverge, the stream shows the reader something the feed itself would
+// not.
+//
+// The subscriber's preferences apply on top: an event they muted is not sent,
+// as the view leaves it out of their feed, and nothing at all is sent during
+// their quiet hours. Quiet hours are the one place the stream and the feed
+// differ on purpose — what they held back is in the feed, and the badge, as
+// usual.
 func (h *Hub) Publish(event models.ActivityEvent) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
+	now := h.now()
 	for s := range h.subscribers {
-		if !visible(event, s) {
+		if !visible(event, s, now) {
 			continue
 		}
 		select {
 		case s.Events <- event:
 		default:
 			// Full buffer: drop rather than block. See the Publish doc comment.
+			s.dropped.Add(1)
 		}
 	}
 }
 
-func visible(event models.ActivityEvent, s *Subscriber) bool {
+// ApplyMembership brings every subscriber change concerns up to date with it,
+// and signals those whose groups changed. A MembershipReset evicts every
+// subscriber instead: the hub cannot tell whose groups are stale, and a
+// client that reconnects has them read afresh.
+//
+// Like Publish it never blocks, and it runs on the same LISTEN goroutine, so
+// a change and the events around it reach a subscriber in commit order.
+func (h *Hub) ApplyMembership(change models.MembershipChange) {
+	if change.Kind == models.MembershipReset {
+		h.mu.Lock()
+		evicted := h.subscribers
+		h.subscribers = make(map[*Subscriber]struct{})
+		h.mu.Unlock()
+
+		for s := range evicted {
+			s.closeOnce.Do(func() { close(s.Events) })
+		}
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for s := range h.subscribers {
+		if change.UserId != "" && change.UserId != s.UserId {
+			continue
+		}
+		if !s.apply(change) {
+			continue
+		}
+		select {
+		case s.changed <- struct{}{}:
+		default:
+			// A signal is already waiting, and it covers this change too.
+		}
+	}
+}
+
+// ApplyPreferences gives every subscriber of prefs.UserId the new preferences,
+// and signals them: what they muted or unmuted changes what their feed and
+// badge hold, and a client has to read those again to show it.
+func (h *Hub) ApplyPreferences(prefs models.ActivityPreferences) {
+	p := newPreferences(prefs)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for s := range h.subscribers {
+		if s.UserId != prefs.UserId {
+			continue
+		}
+		s.mu.Lock()
+		s.preferences = p
+		s.applied = true
+		s.mu.Unlock()
+		select {
+		case s.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// apply changes s's groups as change says, and reports whether they changed.
+func (s *Subscriber) apply(change models.MembershipChange) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.Index(s.groupIds, change.GroupId)
+	switch change.Kind {
+	case models.MembershipAdded:
+		if i >= 0 {
+			return false
+		}
+		s.groupIds = append(s.groupIds, change.GroupId)
+		return true
+	case models.MembershipRemoved, models.MembershipGroupDeleted:
+		if i < 0 {
+			return false
+		}
+		s.groupIds = slices.Delete(s.groupIds, i, i+1)
+		return true
+	default:
+		return false
+	}
+}
+
+func visible(event models.ActivityEvent, s *Subscriber, now time.Time) bool {
 	if event.ActorId == s.UserId {
 		return false
 	}
-	for _, g := range s.GroupIds {
-		if g == event.GroupId {
-			return true
-		}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.preferences.muted(event) || s.preferences.quietAt(now) {
+		return false
+	}
+	if event.Kind == KindGroupDeleted {
+		return wasMember(event, s.UserId)
+	}
+	return slices.Contains(s.groupIds, event.GroupId)
+}
+
+// wasMember reports whether a group_deleted event names userId among the
+// group's members. The payload is []string when the event was built in this
+// process and []any once it has been through JSON, as a published one has.
+func wasMember(event models.ActivityEvent, userId string) bool {
+	switch ids := event.Payload["memberIds"].(type) {
+	case []string:
+		return slices.Contains(ids, userId)
+	case []any:
+		return slices.Contains(ids, any(userId))
 	}
 	return false
 }
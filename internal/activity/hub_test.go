@@ -13,7 +13,7 @@ import (
 func TestHub(t *testing.T) {
 	t.Run("subscriber in the group receives", func(t *testing.T) {
 		h := NewHub()
-		sub := h.Subscribe("alice", []string{"g1"})
+		sub := h.Subscribe("alice", []string{"g1"}, models.ActivityPreferences{})
 		defer h.Unsubscribe(sub)
 
 		event := models.ActivityEvent{Id: "e1", GroupId: "g1", ActorId: "bob"}
@@ -29,7 +29,7 @@ func TestHub(t *testing.T) {
 
 	t.Run("subscriber in a different group does not receive", func(t *testing.T) {
 		h := NewHub()
-		sub := h.Subscribe("alice", []string{"g2"})
+		sub := h.Subscribe("alice", []string{"g2"}, models.ActivityPreferences{})
 		defer h.Unsubscribe(sub)
 
 		h.Publish(models.ActivityEvent{Id: "e1", GroupId: "g1", ActorId: "bob"})
@@ -43,7 +43,7 @@ func TestHub(t *testing.T) {
 
 	t.Run("the actor does not receive their own event", func(t *testing.T) {
 		h := NewHub()
-		sub := h.Subscribe("alice", []string{"g1"})
+		sub := h.Subscribe("alice", []string{"g1"}, models.ActivityPreferences{})
 		defer h.Unsubscribe(sub)
 
 		h.Publish(models.ActivityEvent{Id: "e1", GroupId: "g1", ActorId: "alice"})
@@ -57,7 +57,7 @@ func TestHub(t *testing.T) {
 
 	t.Run("a full channel drops rather than blocking", func(t *testing.T) {
 		h := NewHub()
-		sub := h.Subscribe("alice", []string{"g1"})
+		sub := h.Subscribe("alice", []string{"g1"}, models.ActivityPreferences{})
 		defer h.Unsubscribe(sub)
 
 		for i := range subscriberBufferSize {
@@ -78,11 +78,12 @@ func TestHub(t *testing.T) {
 		}
 
 		require.Len(t, sub.Events, subscriberBufferSize, "the overflow event should have been dropped, not queued")
+		require.Equal(t, uint64(1), sub.Dropped(), "a dropped event must be counted, or the reader cannot know to resync")
 	})
 
 	t.Run("Unsubscribe closes the channel and is safe to call twice", func(t *testing.T) {
 		h := NewHub()
-		sub := h.Subscribe("alice", []string{"g1"})
+		sub := h.Subscribe("alice", []string{"g1"}, models.ActivityPreferences{})
 
 		h.Unsubscribe(sub)
 
@@ -92,6 +93,144 @@ func TestHub(t *testing.T) {
 		require.NotPanics(t, func() { h.Unsubscribe(sub) }, "a second Unsubscribe must not panic")
 	})
 
+	t.Run("a membership change takes effect on the open subscription", func(t *testing.T) {
+		h := NewHub()
+		alice := h.Subscribe("alice", []string{"g1"}, models.ActivityPreferences{})
+		defer h.Unsubscribe(alice)
+		carol := h.Subscribe("carol", []string{"g1"}, models.ActivityPreferences{})
+		defer h.Unsubscribe(carol)
+
+		h.ApplyMembership(models.MembershipChange{Kind: models.MembershipAdded, UserId: "alice", GroupId: "g2"})
+		h.ApplyMembership(models.MembershipChange{Kind: models.MembershipRemoved, UserId: "alice", GroupId: "g1"})
+
+		require.Equal(t, []string{"g2"}, alice.GroupIds())
+		require.Equal(t, []string{"g1"}, carol.GroupIds(), "a change names one user and must not touch anyone else")
+		require.Len(t, alice.Changed(), 1, "two changes before the reader looked are one signal")
+		require.Empty(t, carol.Changed())
+
+		h.Publish(models.ActivityEvent{Id: "e1", GroupId: "g1", ActorId: "bob"})
+		h.Publish(models.ActivityEvent{Id: "e2", GroupId: "g2", ActorId: "bob"})
+		got := <-alice.Events
+		require.Equal(t, "e2", got.Id, "a removed member must stop receiving the group at once, and a new one start")
+		require.Empty(t, alice.Events)
+	})
+
+	t.Run("a deleted group is gone for every subscriber", func(t *testing.T) {
+		h := NewHub()
+		alice := h.Subscribe("alice", []string{"g1", "g2"}, models.ActivityPreferences{})
+		defer h.Unsubscribe(alice)
+		carol := h.Subscribe("carol", []string{"g1"}, models.ActivityPreferences{})
+		defer h.Unsubscribe(carol)
+
+		h.ApplyMembership(models.MembershipChange{Kind: models.MembershipGroupDeleted, GroupId: "g1"})
+
+		require.Equal(t, []string{"g2"}, alice.GroupIds())
+		require.Empty(t, carol.GroupIds())
+	})
+
+	t.Run("a group's deletion reaches the members it names, after their memberships are gone", func(t *testing.T) {
+		h := NewHub()
+		alice := h.Subscribe("alice", []string{"g1"}, models.ActivityPreferences{})
+		defer h.Unsubscribe(alice)
+		carol := h.Subscribe("carol", []string{"g1"}, models.ActivityPreferences{})
+		defer h.Unsubscribe(carol)
+		dave := h.Subscribe("dave", []string{"g2"}, models.ActivityPreferences{})
+		defer h.Unsubscribe(dave)
+
+		h.ApplyMembership(models.MembershipChange{Kind: models.MembershipGroupDeleted, GroupId: "g1"})
+		// As it arrives from the store: the payload has been through JSON.
+		h.Publish(models.ActivityEvent{Id: "e1", GroupId: "g1", ActorId: "alice", Kind: KindGroupDeleted,
+			Payload: map[string]any{"memberIds": []any{"alice", "carol"}}})
+
+		got := <-carol.Events
+		require.Equal(t, "e1", got.Id, "a former member hears the group is gone")
+		require.Empty(t, alice.Events, "the owner who deleted it does not")
+		require.Empty(t, dave.Events, "nor does anyone who was never in it")
+	})
+
+	t.Run("muted events are not pushed, and a change of preferences applies at once", func(t *testing.T) {
+		h := NewHub()
+		alice := h.Subscribe("alice", []string{"g1", "g2"},
+			models.ActivityPreferences{UserId: "alice", Mutes: []models.ActivityMute{{GroupId: "g2"}}})
+		defer h.Unsubscribe(alice)
+		carol := h.Subscribe("carol", []string{"g1"}, models.ActivityPreferences{})
+		defer h.Unsubscribe(carol)
+		require.Empty(t, alice.Changed(), "the opener's own preferences are not a change")
+
+		h.Publish(models.ActivityEvent{Id: "e1", GroupId: "g2", ActorId: "bob"})
+		h.Publish(models.ActivityEvent{Id: "e2", GroupId: "g1", ActorId: "bob", Kind: KindRatingAdded})
+		got := <-alice.Events
+		require.Equal(t, "e2", got.Id, "the muted group is held back, the other is not")
+
+		h.ApplyPreferences(models.ActivityPreferences{UserId: "alice", Mutes: []models.ActivityMute{{Kind: KindRatingAdded}}})
+		require.Len(t, alice.Changed(), 1, "a client has to reread its feed and badge")
+		require.Empty(t, carol.Changed(), "preferences name one user and must not touch anyone else")
+
+		h.Publish(models.ActivityEvent{Id: "e3", GroupId: "g1", ActorId: "bob", Kind: KindRatingAdded})
+		h.Publish(models.ActivityEvent{Id: "e4", GroupId: "g2", ActorId: "bob", Kind: KindCommentAdded})
+		got = <-alice.Events
+		require.Equal(t, "e4", got.Id, "the new mute replaces the old one")
+		require.Empty(t, alice.Events)
+		require.Len(t, carol.Events, 2, "carol muted nothing, so both of g1's reached them")
+	})
+
+	t.Run("nothing is pushed during quiet hours", func(t *testing.T) {
+		h := NewHub()
+		alice := h.Subscribe("alice", []string{"g1"}, models.ActivityPreferences{UserId: "alice",
+			QuietHours: &models.QuietHours{StartMinute: 22 * 60, EndMinute: 7 * 60, TimeZone: "UTC"}})
+		defer h.Unsubscribe(alice)
+
+		h.now = fixedClock(time.Date(2026, time.March, 1, 23, 30, 0, 0, time.UTC))
+		h.Publish(models.ActivityEvent{Id: "e1", GroupId: "g1", ActorId: "bob"})
+		h.now = fixedClock(time.Date(2026, time.March, 2, 7, 0, 0, 0, time.UTC))
+		h.Publish(models.ActivityEvent{Id: "e2", GroupId: "g1", ActorId: "bob"})
+
+		got := <-alice.Events
+		require.Equal(t, "e2", got.Id, "the window ends at 07:00, exclusive")
+		require.Empty(t, alice.Events)
+	})
+
+	t.Run("a change read again after subscribing is applied and signalled", func(t *testing.T) {
+		h := NewHub()
+		alice := h.Subscribe("alice", []string{"g1"}, models.ActivityPreferences{UserId: "alice"})
+		defer h.Unsubscribe(alice)
+
+		alice.RefreshPreferences(models.ActivityPreferences{UserId: "alice"})
+		require.Empty(t, alice.Changed(), "the same preferences read twice are not a change")
+
+		alice.RefreshPreferences(models.ActivityPreferences{UserId: "alice", Mutes: []models.ActivityMute{{GroupId: "g1"}}})
+		require.Len(t, alice.Changed(), 1, "a change saved before the subscriber was registered reaches the client")
+
+		h.Publish(models.ActivityEvent{Id: "e1", GroupId: "g1", ActorId: "bob"})
+		require.Empty(t, alice.Events, "and is applied as well as signalled")
+	})
+
+	t.Run("a read again after the hub applied a change does not undo it", func(t *testing.T) {
+		h := NewHub()
+		alice := h.Subscribe("alice", []string{"g1"}, models.ActivityPreferences{UserId: "alice"})
+		defer h.Unsubscribe(alice)
+
+		h.ApplyPreferences(models.ActivityPreferences{UserId: "alice", Mutes: []models.ActivityMute{{GroupId: "g1"}}})
+		<-alice.Changed()
+		alice.RefreshPreferences(models.ActivityPreferences{UserId: "alice"})
+		require.Empty(t, alice.Changed())
+
+		h.Publish(models.ActivityEvent{Id: "e1", GroupId: "g1", ActorId: "bob"})
+		require.Empty(t, alice.Events, "the hub's copy is the newer one")
+	})
+
+	t.Run("a reset evicts every subscriber", func(t *testing.T) {
+		h := NewHub()
+		sub := h.Subscribe("alice", []string{"g1"}, models.ActivityPreferences{})
+
+		h.ApplyMembership(models.MembershipChange{Kind: models.MembershipReset})
+
+		_, ok := <-sub.Events
+		require.False(t, ok, "an evicted subscriber's channel is closed, so its handler ends the connection")
+		require.Zero(t, h.SubscriberCount())
+		require.NotPanics(t, func() { h.Unsubscribe(sub) }, "the handler's deferred Unsubscribe must still be safe")
+	})
+
 	t.Run("concurrent Publish, Subscribe and Unsubscribe", func(t *testing.T) {
 		h := NewHub()
 		stop := make(chan struct{})
@@ -125,7 +264,7 @@ func TestHub(t *testing.T) {
 					case <-stop:
 						return
 					default:
-						sub := h.Subscribe(fmt.Sprintf("user%d", i), []string{"g1"})
+						sub := h.Subscribe(fmt.Sprintf("user%d", i), []string{"g1"}, models.ActivityPreferences{})
 						select {
 						case <-sub.Events:
 						default:
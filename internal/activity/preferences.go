@@ -0,0 +1,85 @@
+package activity
+
+import (
+	"slices"
+	"time"
+	// Quiet hours are read in the user's own time zone, and the production
+	// image has no zoneinfo of its own; without this every zone but UTC would
+	// fail to load there.
+	_ "time/tzdata"
+
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+// preferences is a subscriber's models.ActivityPreferences as Publish reads
+// them: the mutes as they are, and the quiet hours' zone loaded once rather
+// than per event.
+type preferences struct {
+	mutes []models.ActivityMute
+	quiet *models.QuietHours
+	zone  *time.Location
+}
+
+func newPreferences(prefs models.ActivityPreferences) preferences {
+	p := preferences{mutes: prefs.Mutes, quiet: prefs.QuietHours}
+	if p.quiet != nil {
+		zone, err := time.LoadLocation(p.quiet.TimeZone)
+		if err != nil {
+			// The zone was checked when it was saved; one this build cannot
+			// load is read as UTC rather than switching quiet hours off.
+			zone = time.UTC
+		}
+		p.zone = zone
+	}
+	return p
+}
+
+// equal reports whether p and other hold the same mutes, in the same order,
+// and the same quiet hours. Both come from the same store read, so the order
+// is the store's and a reordering alone is never reported as a change.
+func (p preferences) equal(other preferences) bool {
+	if !slices.Equal(p.mutes, other.mutes) {
+		return false
+	}
+	if p.quiet == nil || other.quiet == nil {
+		return p.quiet == other.quiet
+	}
+	return *p.quiet == *other.quiet
+}
+
+// muted reports whether one of the mutes matches event. It mirrors the mute
+// predicate of activity_visible_events (sql/schema/018_activity_preferences.sql)
+// the way visible mirrors the rest of the view: a kind mute matches the kind
+// anywhere, a group mute everything in the group, and a title mute the
+// events about that title in that group.
+func (p preferences) muted(event models.ActivityEvent) bool {
+	for _, mute := range p.mutes {
+		switch {
+		case mute.Kind != "":
+			if mute.Kind == event.Kind {
+				return true
+			}
+		case mute.GroupId != event.GroupId:
+		case mute.TitleId == "":
+			return true
+		case event.TitleId != nil && *event.TitleId == mute.TitleId:
+			return true
+		}
+	}
+	return false
+}
+
+// quietAt reports whether at falls in the quiet hours, read on the wall clock
+// of their zone: 22:00 to 07:00 stays 22:00 to 07:00 across a change to
+// daylight saving time.
+func (p preferences) quietAt(at time.Time) bool {
+	if p.quiet == nil {
+		return false
+	}
+	local := at.In(p.zone)
+	minute := local.Hour()*60 + local.Minute()
+	if p.quiet.StartMinute < p.quiet.EndMinute {
+		return minute >= p.quiet.StartMinute && minute < p.quiet.EndMinute
+	}
+	return minute >= p.quiet.StartMinute || minute < p.quiet.EndMinute
+}
@@ -0,0 +1,64 @@
+package activity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+func TestPreferences(t *testing.T) {
+	tt1, tt2 := "tt1", "tt2"
+
+	t.Run("each shape of mute matches what the view leaves out", func(t *testing.T) {
+		p := newPreferences(models.ActivityPreferences{Mutes: []models.ActivityMute{
+			{Kind: KindCommentAdded},
+			{GroupId: "g1"},
+			{GroupId: "g2", TitleId: tt1},
+		}})
+
+		require.True(t, p.muted(models.ActivityEvent{GroupId: "g3", Kind: KindCommentAdded}), "a kind is muted in every group")
+		require.True(t, p.muted(models.ActivityEvent{GroupId: "g1", Kind: KindRatingAdded}), "a group is muted whole")
+		require.True(t, p.muted(models.ActivityEvent{GroupId: "g2", Kind: KindRatingAdded, TitleId: &tt1}))
+		require.False(t, p.muted(models.ActivityEvent{GroupId: "g2", Kind: KindRatingAdded, TitleId: &tt2}),
+			"a title mute leaves the group's other titles")
+		require.False(t, p.muted(models.ActivityEvent{GroupId: "g2", Kind: KindGroupUpdated}),
+			"nor does it mute what is about no title")
+		require.False(t, p.muted(models.ActivityEvent{GroupId: "g3", Kind: KindTitleAdded, TitleId: &tt1}),
+			"and it is that title in that group only")
+	})
+
+	t.Run("no preferences mute nothing and are never quiet", func(t *testing.T) {
+		var p preferences
+
+		require.False(t, p.muted(models.ActivityEvent{GroupId: "g1", Kind: KindTitleAdded}))
+		require.False(t, p.quietAt(time.Now()))
+	})
+
+	t.Run("quiet hours are read on the wall clock of their zone", func(t *testing.T) {
+		p := newPreferences(models.ActivityPreferences{
+			QuietHours: &models.QuietHours{StartMinute: 22 * 60, EndMinute: 7 * 60, TimeZone: "America/New_York"},
+		})
+
+		// 03:00 UTC is 22:00 in New York in winter (UTC-5) and 23:00 in summer
+		// (UTC-4): quiet either way. 11:30 UTC is 06:30 and 07:30.
+		require.True(t, p.quietAt(time.Date(2026, time.January, 15, 3, 0, 0, 0, time.UTC)))
+		require.True(t, p.quietAt(time.Date(2026, time.July, 15, 3, 0, 0, 0, time.UTC)))
+		require.True(t, p.quietAt(time.Date(2026, time.January, 15, 11, 30, 0, 0, time.UTC)))
+		require.False(t, p.quietAt(time.Date(2026, time.July, 15, 11, 30, 0, 0, time.UTC)),
+			"07:30 in summer is after the window, which follows the clock, not UTC")
+		require.False(t, p.quietAt(time.Date(2026, time.January, 15, 2, 59, 0, 0, time.UTC)), "21:59 is before it")
+	})
+
+	t.Run("a window within one day", func(t *testing.T) {
+		p := newPreferences(models.ActivityPreferences{
+			QuietHours: &models.QuietHours{StartMinute: 9 * 60, EndMinute: 17 * 60, TimeZone: "UTC"},
+		})
+
+		require.True(t, p.quietAt(time.Date(2026, time.March, 1, 9, 0, 0, 0, time.UTC)), "the start is inclusive")
+		require.False(t, p.quietAt(time.Date(2026, time.March, 1, 17, 0, 0, 0, time.UTC)), "the end is not")
+		require.False(t, p.quietAt(time.Date(2026, time.March, 1, 23, 0, 0, 0, time.UTC)))
+	})
+}
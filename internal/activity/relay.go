@@ -0,0 +1,99 @@
+package activity
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// relayBatchSize is how many outbox rows one delivery hands to the sinks. A
+// request rarely records more than one event, so this only matters when the
+// relay is catching up on a backlog — after a sink outage, say — where it
+// bounds how long the batch's rows stay locked.
+const relayBatchSize = 100
+
+// Relay delivers the events in the outbox to its sinks, in the order they were
+// written, at least once. It is the other half of outbox mode: the request
+// only writes its events to the outbox, in its own transaction, and the relay
+// is what gets them to the feed afterwards.
+//
+// At least once, not exactly once: a batch is removed from the outbox only
+// after every sink has taken it, so a sink that fails, or a process that dies
+// between a delivery and its removal, means the batch is delivered again. A
+// sink must therefore ignore an event whose id it already holds — StoreSink
+// does, through InsertActivityEvents.
+//
+// A failed delivery is logged and retried on the next tick, not in a loop: the
+// likeliest cause is a sink that is down, and hammering it helps nobody.
+type Relay struct {
+	outbox   store.ActivityOutbox
+	sinks    []Sink
+	interval time.Duration
+	wake     chan struct{}
+}
+
+// NewRelay returns a relay from outbox to sinks that looks for events every
+// interval, and whenever it is woken.
+func NewRelay(outbox store.ActivityOutbox, interval time.Duration, sinks ...Sink) *Relay {
+	return &Relay{
+		outbox:   outbox,
+		sinks:    sinks,
+		interval: interval,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Wake asks the relay to look at the outbox now rather than at its next tick.
+// It never blocks: a wake already pending covers this one too, since the
+// relay empties the outbox whenever it looks.
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers until ctx is cancelled. It looks once straight away, so events
+// left in the outbox by the last process are not kept waiting a tick.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.drain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// drain delivers batch after batch until the outbox is empty or a delivery
+// fails.
+func (r *Relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := r.outbox.RelayActivityOutbox(ctx, relayBatchSize, r.deliver)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("ERROR: relaying activity events failed, retrying in %v: %v", r.interval, err)
+			}
+			return
+		}
+		if n < relayBatchSize {
+			return
+		}
+	}
+}
+
+func (r *Relay) deliver(ctx context.Context, events []models.ActivityEvent) error {
+	for _, sink := range r.sinks {
+		if err := sink.Append(ctx, events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+// memOutbox is the outbox half of the store, in memory: events are handed out
+// oldest first and removed only when deliver succeeds.
+type memOutbox struct {
+	mu     sync.Mutex
+	events []models.ActivityEvent
+}
+
+func (o *memOutbox) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (o *memOutbox) EnqueueActivityEvents(_ context.Context, events []models.ActivityEvent) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, events...)
+	return nil
+}
+
+func (o *memOutbox) RelayActivityOutbox(ctx context.Context, limit int, deliver func(context.Context, []models.ActivityEvent) error) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	batch := append([]models.ActivityEvent(nil), o.events[:min(limit, len(o.events))]...)
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if err := deliver(ctx, batch); err != nil {
+		return 0, err
+	}
+	o.events = o.events[len(batch):]
+	return len(batch), nil
+}
+
+func (o *memOutbox) pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.events)
+}
+
+// memSink keeps what it is given, and fails while err is set.
+type memSink struct {
+	mu     sync.Mutex
+	err    error
+	events []models.ActivityEvent
+}
+
+func (s *memSink) Append(_ context.Context, events []models.ActivityEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *memSink) ids() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, len(s.events))
+	for i, e := range s.events {
+		ids[i] = e.Id
+	}
+	return ids
+}
+
+func outboxEvents(n int) []models.ActivityEvent {
+	events := make([]models.ActivityEvent, n)
+	for i := range events {
+		events[i] = models.ActivityEvent{Id: "e" + strconv.Itoa(i), GroupId: "g1", Kind: KindTitleAdded}
+	}
+	return events
+}
+
+func TestRelay(t *testing.T) {
+	t.Run("every event reaches every sink in order and leaves the outbox", func(t *testing.T) {
+		ctx := context.Background()
+		outbox := &memOutbox{}
+		events := outboxEvents(relayBatchSize*2 + 5)
+		require.NoError(t, outbox.EnqueueActivityEvents(ctx, events))
+		first, second := &memSink{}, &memSink{}
+
+		NewRelay(outbox, time.Hour, first, second).drain(ctx)
+
+		want := make([]string, len(events))
+		for i, e := range events {
+			want[i] = e.Id
+		}
+		require.Equal(t, want, first.ids(), "a backlog bigger than a batch must be delivered whole, in order")
+		require.Equal(t, want, second.ids())
+		require.Zero(t, outbox.pending())
+	})
+
+	t.Run("a failed delivery stays in the outbox and is delivered again", func(t *testing.T) {
+		ctx := context.Background()
+		outbox := &memOutbox{}
+		require.NoError(t, outbox.EnqueueActivityEvents(ctx, outboxEvents(2)))
+		first, second := &memSink{}, &memSink{err: errors.New("sink down")}
+		relay := NewRelay(outbox, time.Hour, first, second)
+
+		relay.drain(ctx)
+		require.Equal(t, 2, outbox.pending(), "nothing may leave the outbox until every sink has it")
+		require.Empty(t, second.ids())
+
+		second.mu.Lock()
+		second.err = nil
+		second.mu.Unlock()
+		relay.drain(ctx)
+
+		require.Zero(t, outbox.pending())
+		require.Equal(t, []string{"e0", "e1"}, second.ids())
+		require.Equal(t, []string{"e0", "e1", "e0", "e1"}, first.ids(),
+			"delivery is at least once: a sink that took the failed batch is handed it again")
+	})
+
+	t.Run("Run delivers when woken and stops with its context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		outbox := &memOutbox{}
+		sink := &memSink{}
+		relay := NewRelay(outbox, time.Hour, sink)
+		done := make(chan struct{})
+		go func() {
+			relay.Run(ctx)
+			close(done)
+		}()
+
+		require.NoError(t, outbox.EnqueueActivityEvents(ctx, outboxEvents(1)))
+		relay.Wake()
+		require.Eventually(t, func() bool { return len(sink.ids()) == 1 }, time.Second, 5*time.Millisecond,
+			"a wake must not wait for the hourly tick")
+
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Run did not return after its context was cancelled")
+		}
+	})
+}
@@ -2,6 +2,7 @@ package activity
 
 import (
 	"context"
+	"errors"
 
 	"github.com/lealre/movies-backend/internal/models"
 	"github.com/lealre/movies-backend/internal/store"
@@ -30,3 +31,25 @@ func NewStoreSink(st store.Store) StoreSink { return StoreSink{store: st} }
 func (s StoreSink) Append(ctx context.Context, events []models.ActivityEvent) error {
 	return s.store.InsertActivityEvents(ctx, events)
 }
+
+// Fanout is a Sink that appends to each of its sinks in turn: the feed's own
+// StoreSink plus whatever else wants the same events (webhooks).
+//
+// Every sink is tried even when an earlier one fails, so an outage in one
+// destination does not starve the others, and the failures come back joined.
+// A caller that retries — the outbox relay does — hands the events to the
+// sinks that succeeded again, which is why each must ignore an event id it
+// already has.
+type Fanout []Sink
+
+func NewFanout(sinks ...Sink) Fanout { return Fanout(sinks) }
+
+func (f Fanout) Append(ctx context.Context, events []models.ActivityEvent) error {
+	var errs []error
+	for _, sink := range f {
+		if err := sink.Append(ctx, events); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
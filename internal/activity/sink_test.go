@@ -0,0 +1,26 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanout(t *testing.T) {
+	t.Run("every sink gets the events even when one before it fails", func(t *testing.T) {
+		down := errors.New("sink down")
+		first, failing, last := &memSink{}, &memSink{err: down}, &memSink{}
+
+		err := NewFanout(first, failing, last).Append(context.Background(), outboxEvents(2))
+
+		require.ErrorIs(t, err, down, "the failure must still be reported, so the caller can log or retry it")
+		require.Equal(t, []string{"e0", "e1"}, first.ids())
+		require.Equal(t, []string{"e0", "e1"}, last.ids())
+	})
+
+	t.Run("no sinks is a no-op", func(t *testing.T) {
+		require.NoError(t, NewFanout().Append(context.Background(), outboxEvents(1)))
+	})
+}
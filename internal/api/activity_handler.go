@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
 
@@ -10,23 +11,90 @@ import (
 	"github.com/lealre/movies-backend/internal/services/activity"
 )
 
+// GetActivityFeed is the caller's feed across their groups. Besides the
+// before/limit cursor it takes the feed filters: groupId and kind may repeat
+// and match any of their values, and titleId, actorId, createdAfter and
+// createdBefore narrow it further.
 func (api *API) GetActivityFeed(w http.ResponseWriter, r *http.Request) {
 	logger := logx.FromContext(r.Context())
 	currentUser := auth.GetUserFromContext(r.Context())
 
 	limit := generics.StringToInt(r.URL.Query().Get("limit"))
+	before, ok := parseActivityBefore(w, r)
+	if !ok {
+		return
+	}
+
+	feed, err := activity.GetFeed(api.Db, r.Context(), currentUser.Id, activityFeedQuery(r), before, limit)
+	if err != nil {
+		if code, ok := activity.ErrorMap[err]; ok {
+			respondWithError(w, code, err.Error())
+			return
+		}
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, feed)
+}
+
+// GetGroupActivity is one group's history, the caller's own actions included.
+// It takes the feed's cursor and filters, groupId aside.
+func (api *API) GetGroupActivity(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId := r.PathValue("id")
+	if groupId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group Id is required")
+		return
+	}
 
-	var before *int64
-	if raw := r.URL.Query().Get("before"); raw != "" {
-		parsed, err := strconv.ParseInt(raw, 10, 64)
-		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "before must be a number")
+	limit := generics.StringToInt(r.URL.Query().Get("limit"))
+	before, ok := parseActivityBefore(w, r)
+	if !ok {
+		return
+	}
+
+	feed, err := activity.GetGroupHistory(api.Db, r.Context(), currentUser.Id, groupId, activityFeedQuery(r), before, limit)
+	if err != nil {
+		if code, ok := activity.ErrorMap[err]; ok {
+			respondWithError(w, code, err.Error())
 			return
 		}
-		before = &parsed
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, feed)
+}
+
+// GetGroupTitleActivity is what happened to one title in one group: the
+// history behind a title's panel. It only checks the group, not that the title
+// is still in it, so a title removed from the group keeps its history.
+func (api *API) GetGroupTitleActivity(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId := r.PathValue("groupId")
+	if groupId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group Id is required")
+		return
+	}
+
+	titleId := r.PathValue("titleId")
+	if titleId == "" {
+		respondWithError(w, http.StatusBadRequest, "Title Id is required")
+		return
+	}
+
+	limit := generics.StringToInt(r.URL.Query().Get("limit"))
+	before, ok := parseActivityBefore(w, r)
+	if !ok {
+		return
 	}
 
-	feed, err := activity.GetFeed(api.Db, r.Context(), currentUser.Id, before, limit)
+	feed, err := activity.GetTitleHistory(api.Db, r.Context(), currentUser.Id, groupId, titleId, activityFeedQuery(r), before, limit)
 	if err != nil {
 		if code, ok := activity.ErrorMap[err]; ok {
 			respondWithError(w, code, err.Error())
@@ -39,6 +107,33 @@ func (api *API) GetActivityFeed(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, feed)
 }
 
+// parseActivityBefore reads the feeds' seq cursor, answering a 400 itself when
+// it is not a number.
+func parseActivityBefore(w http.ResponseWriter, r *http.Request) (*int64, bool) {
+	raw := r.URL.Query().Get("before")
+	if raw == "" {
+		return nil, true
+	}
+	parsed, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "before must be a number")
+		return nil, false
+	}
+	return &parsed, true
+}
+
+func activityFeedQuery(r *http.Request) activity.FeedQuery {
+	query := r.URL.Query()
+	return activity.FeedQuery{
+		GroupIds:      query["groupId"],
+		TitleId:       query.Get("titleId"),
+		Kinds:         query["kind"],
+		ActorId:       query.Get("actorId"),
+		CreatedAfter:  query.Get("createdAfter"),
+		CreatedBefore: query.Get("createdBefore"),
+	}
+}
+
 func (api *API) GetActivityUnreadCount(w http.ResponseWriter, r *http.Request) {
 	logger := logx.FromContext(r.Context())
 	currentUser := auth.GetUserFromContext(r.Context())
@@ -87,3 +182,43 @@ func (api *API) MarkAllActivityRead(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetActivityPreferences is what the caller has muted, and their quiet hours.
+func (api *API) GetActivityPreferences(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	prefs, err := activity.GetPreferences(api.Db, r.Context(), currentUser.Id)
+	if err != nil {
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// SetActivityPreferences replaces the caller's preferences whole and answers
+// with them as stored. The caller's open streams pick them up without
+// reconnecting.
+func (api *API) SetActivityPreferences(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	var req activity.PreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	prefs, err := activity.SetPreferences(api.Db, r.Context(), currentUser.Id, req)
+	if err != nil {
+		if code, ok := activity.ErrorMap[err]; ok {
+			respondWithError(w, code, err.Error())
+			return
+		}
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, prefs)
+}
@@ -33,6 +33,10 @@ func (api *API) IssueActivityStreamTicket(w http.ResponseWriter, r *http.Request
 // StreamActivity holds an SSE connection open and writes the caller's group
 // activity to it as it happens.
 //
+// A client resuming after a drop passes the seq of the last frame it saw, as
+// Last-Event-ID or ?lastEventId=, and is first sent what it missed; when that
+// cannot be replayed it gets a resync event and takes a fresh snapshot.
+//
 // It authenticates by ticket rather than Bearer token, which is why
 // "GET /activity/stream" is in PublicPaths: EventSource cannot set headers, so
 // AuthMiddleware would reject every stream before this handler ran. Public to
@@ -68,6 +72,22 @@ func (api *API) StreamActivity(w http.ResponseWriter, r *http.Request) {
 	// a dropped connection is the normal way a stream ends.
 	defer api.Stream.CloseStream(subscriber)
 
+	// Resuming: the browser sends Last-Event-ID itself when EventSource
+	// reconnects, but a ticket is single-use, so a real client reconnects with
+	// a new EventSource and a new ticket, and has to pass the seq it last saw
+	// in the query instead. The header wins when both are present.
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("lastEventId")
+	}
+	replay, err := activity.ReplaySince(api.Db, r.Context(), subscriber, lastEventId)
+	if err != nil {
+		// The ticket is spent, so failing the request would fail the retry
+		// too. A resync costs the client one snapshot it could have avoided.
+		logger.Printf("ERROR: replaying activity after %q failed, asking for a resync: %v", lastEventId, err)
+		replay = activity.Replay{Resync: true}
+	}
+
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
@@ -81,6 +101,28 @@ func (api *API) StreamActivity(w http.ResponseWriter, r *http.Request) {
 	// its snapshot then, so the headers must not wait for the first event.
 	flusher.Flush()
 
+	// What the replay sent, so the same event arriving live as well — it was
+	// committed between the subscription and the replay's read — is not sent
+	// twice.
+	replayed := make(map[string]struct{}, len(replay.Events))
+	if replay.Resync {
+		if _, err := w.Write(activity.ResyncFrame()); err != nil {
+			return
+		}
+	}
+	for _, event := range replay.Events {
+		frame, err := activity.StreamFrame(event)
+		if err != nil {
+			logger.Printf("ERROR: dropping unserializable activity event %q: %v", event.Id, err)
+			continue
+		}
+		if _, err := w.Write(frame); err != nil {
+			return
+		}
+		replayed[event.Id] = struct{}{}
+	}
+	flusher.Flush()
+
 	ping := time.NewTicker(streamPingInterval)
 	defer ping.Stop()
 
@@ -97,6 +139,9 @@ func (api *API) StreamActivity(w http.ResponseWriter, r *http.Request) {
 				// The hub closed us out. Nothing further will arrive.
 				return
 			}
+			if _, ok := replayed[event.Id]; ok {
+				continue
+			}
 			frame, err := activity.StreamFrame(event)
 			if err != nil {
 				// One unserializable event must not end a stream that is
@@ -111,6 +156,15 @@ func (api *API) StreamActivity(w http.ResponseWriter, r *http.Request) {
 			// fills, which for a low-traffic feed is effectively never.
 			flusher.Flush()
 
+		case <-subscriber.Changed():
+			// The user joined or left a group. The hub already sends the
+			// right events from here on; what the client has on screen is
+			// the old set's, and a snapshot is how it gets the new one.
+			if _, err := w.Write(activity.ResyncFrame()); err != nil {
+				return
+			}
+			flusher.Flush()
+
 		case <-ping.C:
 			// A comment line: clients ignore it, proxies see traffic.
 			if _, err := io.WriteString(w, ":ping\n\n"); err != nil {
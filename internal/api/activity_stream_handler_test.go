@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -36,24 +37,63 @@ const (
 )
 
 // stubStreamStore satisfies store.Store by embedding the interface, so only the
-// two methods these handlers reach need bodies. Any other call panics on the
+// methods these handlers reach need bodies. Any other call panics on the
 // nil embedded interface, which is the point: it proves nothing else is
 // touched.
 type stubStreamStore struct {
 	store.Store
-	user    models.User
-	userErr error
-	feed    []models.ActivityEvent
+	user     models.User
+	userErr  error
+	feed     []models.ActivityEvent
+	log      []models.ActivityEvent
+	sinceErr error
+	unread   int64
+	prefs    models.ActivityPreferences
 }
 
 func (s stubStreamStore) GetUserById(context.Context, string) (models.User, error) {
 	return s.user, s.userErr
 }
 
-func (s stubStreamStore) GetActivityFeed(context.Context, string, *int64, int) ([]models.ActivityEvent, error) {
+func (s stubStreamStore) GetActivityFeed(context.Context, string, models.ActivityFilter, *int64, int) ([]models.ActivityEvent, error) {
 	return s.feed, nil
 }
 
+func (s stubStreamStore) GetActivityUnreadCount(context.Context, string) (int64, error) {
+	return s.unread, nil
+}
+
+func (s stubStreamStore) GetActivityPreferences(_ context.Context, userId string) (models.ActivityPreferences, error) {
+	prefs := s.prefs
+	prefs.UserId = userId
+	return prefs, nil
+}
+
+// MarkActivityEventRead knows only the events in log.
+func (s stubStreamStore) MarkActivityEventRead(_ context.Context, _ string, eventId string) error {
+	for _, e := range s.log {
+		if e.Id == eventId {
+			return nil
+		}
+	}
+	return store.ErrRecordNotFound
+}
+
+// GetActivityEventsSince answers from log, which stands for the events visible
+// to the reader, oldest first.
+func (s stubStreamStore) GetActivityEventsSince(_ context.Context, _ string, afterSeq int64, limit int) ([]models.ActivityEvent, error) {
+	if s.sinceErr != nil {
+		return nil, s.sinceErr
+	}
+	var out []models.ActivityEvent
+	for _, e := range s.log {
+		if e.Seq > afterSeq && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
 // refusingTickets is a TicketAuthority that refuses every redemption, standing
 // in for the cases activity.TicketStore collapses into a single false: expired,
 // unknown, already used. The store's own clock-driven expiry is pinned in
@@ -88,11 +128,12 @@ type streamHarness struct {
 	api     *API
 }
 
-// newStreamHarness builds the two stream handlers (plus the feed handler, for
-// the serialization comparison) over a real hub and a real ticket store.
+// newStreamHarness builds the stream handlers, SSE and WebSocket (plus the feed
+// handler, for the serialization comparison) over a real hub and a real ticket
+// store.
 //
-// The stand-in for AuthMiddleware injects the user for every route EXCEPT
-// GET /activity/stream, mirroring api.PublicPaths: the stream must authenticate
+// The stand-in for AuthMiddleware injects the user for every route EXCEPT the
+// two stream routes, mirroring api.PublicPaths: a stream must authenticate
 // itself from its ticket and must not quietly depend on a context user it will
 // never have in production.
 func newStreamHarness(t *testing.T, st store.Store) *streamHarness {
@@ -107,11 +148,12 @@ func newStreamHarness(t *testing.T, st store.Store) *streamHarness {
 	mux := http.NewServeMux()
 	mux.HandleFunc("POST /activity/stream-ticket", a.IssueActivityStreamTicket)
 	mux.HandleFunc("GET /activity/stream", a.StreamActivity)
+	mux.HandleFunc("GET /activity/ws", a.StreamActivitySocket)
 	mux.HandleFunc("GET /activity", a.GetActivityFeed)
 
 	user := models.User{Id: streamUserId, Username: "reader", IsActive: true, Groups: []string{streamGroupId}}
 	withUser := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/activity/stream" {
+		if r.URL.Path != "/activity/stream" && r.URL.Path != "/activity/ws" {
 			r = r.WithContext(auth.WithUser(r.Context(), user))
 		}
 		mux.ServeHTTP(w, r)
@@ -152,12 +194,22 @@ func (h *streamHarness) mintTicket(t *testing.T) activity.StreamTicket {
 // stream that stops delivering ends the test instead of hanging it.
 func (h *streamHarness) openStream(t *testing.T, ticket string) (*http.Response, *bufio.Reader) {
 	t.Helper()
+	return h.resumeStream(t, ticket, "")
+}
+
+// resumeStream is openStream for a client that has seen the stream before,
+// sending lastEventId as the Last-Event-ID header when it is not empty.
+func (h *streamHarness) resumeStream(t *testing.T, ticket, lastEventId string) (*http.Response, *bufio.Reader) {
+	t.Helper()
 
 	ctx, cancel := context.WithTimeout(t.Context(), streamTestTimeout)
 	t.Cleanup(cancel)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.server.URL+"/activity/stream?ticket="+ticket, nil)
 	require.NoError(t, err, "failed to build the stream request")
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
 
 	// The client returns as soon as the response headers arrive, which the
 	// handler writes and flushes only after the ticket has been redeemed.
@@ -458,3 +510,119 @@ func TestActivityStreamHandlers(t *testing.T) {
 			"one fact, one serialization: the stream frame and the feed element must be byte-identical")
 	})
 }
+
+// loggedEvent is sampleEvent at seq, with an id to match.
+func loggedEvent(seq int64) models.ActivityEvent {
+	event := sampleEvent()
+	event.Seq = seq
+	event.Id = fmt.Sprintf("event-%d", seq)
+	return event
+}
+
+// quietNow is a quiet-hours window in UTC from an hour before now to an hour
+// after, so the stream's own clock is inside it wherever the test runs.
+func quietNow() *models.QuietHours {
+	now := time.Now().UTC()
+	minute := now.Hour()*60 + now.Minute()
+	return &models.QuietHours{StartMinute: (minute + 23*60) % (24 * 60), EndMinute: (minute + 60) % (24 * 60), TimeZone: "UTC"}
+}
+
+func TestActivityStreamResume(t *testing.T) {
+	production := streamPingInterval
+	streamPingInterval = testPingInterval
+	t.Cleanup(func() { streamPingInterval = production })
+
+	activeUser := models.User{Id: streamUserId, Username: "reader", IsActive: true, Groups: []string{streamGroupId}}
+	log := []models.ActivityEvent{loggedEvent(1), loggedEvent(2), loggedEvent(3)}
+
+	t.Run("a reconnect with Last-Event-ID replays what came after it, in order, then goes live", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser, log: log})
+		ticket := h.mintTicket(t)
+
+		_, body := h.resumeStream(t, ticket.Ticket, "1")
+
+		require.Contains(t, readActivityFrame(t, body), "id: 2\n")
+		require.Contains(t, readActivityFrame(t, body), "id: 3\n")
+
+		requireSubscribers(t, h.hub, 1, "the resumed client must be subscribed")
+		h.hub.Publish(loggedEvent(4))
+		require.Contains(t, readActivityFrame(t, body), "id: 4\n", "after the replay the stream is live")
+	})
+
+	t.Run("the seq can be passed in the query, for a client on a new ticket", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser, log: log})
+		ticket := h.mintTicket(t)
+
+		_, body := h.openStream(t, ticket.Ticket+"&lastEventId=2")
+
+		require.Contains(t, readActivityFrame(t, body), "id: 3\n")
+	})
+
+	t.Run("an event both replayed and published is sent once", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser, log: log})
+		ticket := h.mintTicket(t)
+
+		_, body := h.resumeStream(t, ticket.Ticket, "2")
+		require.Contains(t, readActivityFrame(t, body), "id: 3\n")
+
+		// Committed between the subscription and the replay's read: the hub
+		// delivers it too.
+		requireSubscribers(t, h.hub, 1, "the resumed client must be subscribed")
+		h.hub.Publish(loggedEvent(3))
+		h.hub.Publish(loggedEvent(4))
+		require.Contains(t, readActivityFrame(t, body), "id: 4\n", "the duplicate must be skipped")
+	})
+
+	t.Run("more missed events than the cap is a resync", func(t *testing.T) {
+		t.Setenv("ACTIVITY_STREAM_REPLAY_LIMIT", "2")
+		h := newStreamHarness(t, stubStreamStore{user: activeUser, log: log})
+		ticket := h.mintTicket(t)
+
+		_, body := h.resumeStream(t, ticket.Ticket, "0")
+
+		require.Equal(t, "event: resync\ndata: {}\n", readActivityFrame(t, body),
+			"a replay past the cap is replaced by one control event, not truncated")
+		requireSubscribers(t, h.hub, 1, "a resync keeps the stream open")
+		h.hub.Publish(loggedEvent(4))
+		require.Contains(t, readActivityFrame(t, body), "id: 4\n")
+	})
+
+	t.Run("a Last-Event-ID that is not a seq is a resync", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser, log: log})
+		ticket := h.mintTicket(t)
+
+		_, body := h.resumeStream(t, ticket.Ticket, "not-a-seq")
+
+		require.Equal(t, "event: resync\ndata: {}\n", readActivityFrame(t, body))
+	})
+
+	t.Run("a store failure during the replay is a resync, not a failed stream", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser, sinceErr: errors.New("database is down")})
+		ticket := h.mintTicket(t)
+
+		resp, body := h.resumeStream(t, ticket.Ticket, "1")
+
+		require.Equal(t, http.StatusOK, resp.StatusCode, "the ticket is spent, so failing would fail the retry too")
+		require.Equal(t, "event: resync\ndata: {}\n", readActivityFrame(t, body))
+	})
+
+	t.Run("a reconnect during quiet hours replays nothing", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser, log: log,
+			prefs: models.ActivityPreferences{QuietHours: quietNow()}})
+		ticket := h.mintTicket(t)
+
+		_, body := h.resumeStream(t, ticket.Ticket, "1")
+
+		require.Equal(t, ":ping\n", readMessage(t, body),
+			"what Publish holds back must not arrive in a burst on a reconnect")
+	})
+
+	t.Run("a first connect replays nothing", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser, log: log})
+		ticket := h.mintTicket(t)
+
+		_, body := h.openStream(t, ticket.Ticket)
+
+		require.Equal(t, ":ping\n", readMessage(t, body), "with no Last-Event-ID the client snapshots instead")
+	})
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"github.com/lealre/movies-backend/internal/logx"
+	"github.com/lealre/movies-backend/internal/services/activity"
+)
+
+// socketWriteTimeout bounds every write to a WebSocket, pings included. The
+// hub never waits on a connection, but this handler does, and a client that
+// stops reading would otherwise hold it — and its hub subscriber — forever.
+// Past the timeout the connection is closed and the client reconnects, the
+// same repair the SSE stream relies on.
+//
+// A var, not a const, for the same reason streamPingInterval is one.
+var socketWriteTimeout = 10 * time.Second
+
+// socketReadLimit caps one client message. The largest a client has reason to
+// send is a subscribe naming every one of its groups; anything bigger is not
+// the protocol, and the library closes the connection for it.
+const socketReadLimit = 16 << 10
+
+// StreamActivitySocket is the WebSocket twin of StreamActivity: the same
+// tickets, the same hub, the same events, plus a small JSON protocol on top —
+// see activity.Socket for what each message does.
+//
+// It is in PublicPaths for the reason the SSE route is: a browser WebSocket
+// cannot set headers either, so it authenticates with ?ticket= here, and an
+// unredeemable ticket is a 401 before any upgrade. A client resuming after a
+// drop passes ?lastEventId=, as on the SSE route.
+//
+// Cross-origin upgrades are refused: there is no CORS on the REST API, so a
+// page that can call it is on its own origin, and a socket has no reason to
+// answer anyone else.
+func (api *API) StreamActivitySocket(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+
+	subscriber, err := api.Stream.OpenStream(api.Db, r.Context(), r.URL.Query().Get("ticket"))
+	if err != nil {
+		if code, ok := activity.ErrorMap[err]; ok {
+			respondWithError(w, code, err.Error())
+			return
+		}
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+	defer api.Stream.CloseStream(subscriber)
+
+	// Accept answers a failed upgrade itself, and the ticket is spent either
+	// way; a client that fails here has a bug a retry will not fix.
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		logger.Printf("WARN: activity socket upgrade failed: %v", err)
+		return
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(socketReadLimit)
+
+	// The request context is not cancelled when a hijacked connection drops,
+	// so the reader cancels this one when the client goes away.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	lastEventId := r.URL.Query().Get("lastEventId")
+	replay, err := activity.ReplaySince(api.Db, ctx, subscriber, lastEventId)
+	if err != nil {
+		logger.Printf("ERROR: replaying activity after %q failed, asking for a resync: %v", lastEventId, err)
+		replay = activity.Replay{Resync: true}
+	}
+	socket, greeting, err := activity.OpenSocket(api.Db, ctx, subscriber, replay)
+	if err != nil {
+		logger.Printf("ERROR: %v", err)
+		conn.Close(websocket.StatusInternalError, "Unexpected error occurred")
+		return
+	}
+	if writeSocketMessages(ctx, conn, greeting) != nil {
+		return
+	}
+
+	// One goroutine reads and this one does everything else, so Socket is
+	// only ever touched from here. The channel is unbuffered: a client that
+	// sends faster than its messages are handled waits on its own TCP window,
+	// which is the inbound half of the backpressure.
+	incoming := make(chan []byte)
+	go func() {
+		defer cancel()
+		for {
+			_, data, err := conn.Read(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case incoming <- data:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// A protocol ping, which browsers answer on their own: it keeps proxies
+	// from reaping an idle connection, and one that is gone without a close
+	// frame is noticed here rather than never. It has a goroutine of its own
+	// because Ping returns only once the reader has seen the pong, and the
+	// reader may be waiting on this loop to take a message: were this loop
+	// the one waiting on Ping, neither would move and a healthy connection
+	// would be closed at the timeout.
+	pinged := make(chan struct{}, 1)
+	go func() {
+		defer cancel()
+		ping := time.NewTicker(streamPingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ping.C:
+			}
+			pingCtx, pingCancel := context.WithTimeout(ctx, socketWriteTimeout)
+			err := conn.Ping(pingCtx)
+			pingCancel()
+			if err != nil {
+				return
+			}
+			select {
+			case pinged <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	for {
+		var messages []any
+		var err error
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-subscriber.Events:
+			if !ok {
+				// The hub closed us out, which it does when it can no longer
+				// vouch for the user's groups. Reconnecting reads them again.
+				conn.Close(websocket.StatusGoingAway, "")
+				return
+			}
+			messages, err = socket.Deliver(ctx, event)
+
+		case <-subscriber.Changed():
+			messages, err = socket.MembershipChanged(ctx)
+
+		case data := <-incoming:
+			messages, err = socket.Handle(ctx, data)
+
+		case <-pinged:
+			messages, err = socket.CheckDropped(ctx)
+		}
+
+		if err != nil {
+			// The store failed; the connection has not. What was decided
+			// before the failure still goes out, and the client carries on.
+			logger.Printf("ERROR: %v", err)
+			messages = append(messages, activity.ErrorMessage{Type: activity.SocketError, Error: "Unexpected error occurred"})
+		}
+		if writeSocketMessages(ctx, conn, messages) != nil {
+			return
+		}
+	}
+}
+
+// writeSocketMessages writes messages in order, each within
+// socketWriteTimeout.
+func writeSocketMessages(ctx context.Context, conn *websocket.Conn, messages []any) error {
+	for _, message := range messages {
+		writeCtx, cancel := context.WithTimeout(ctx, socketWriteTimeout)
+		err := wsjson.Write(writeCtx, conn, message)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/services/activity"
+)
+
+// dialSocket opens the WebSocket with ticket and whatever else query holds.
+// The connection is closed by cleanup, and every read on it is bounded by
+// streamTestTimeout through readSocket.
+func (h *streamHarness) dialSocket(t *testing.T, query string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(t.Context(), streamTestTimeout)
+	defer cancel()
+
+	url := "ws" + strings.TrimPrefix(h.server.URL, "http") + "/activity/ws?" + query
+	conn, resp, err := websocket.Dial(ctx, url, &websocket.DialOptions{HTTPClient: h.server.Client()})
+	if conn != nil {
+		t.Cleanup(func() { conn.CloseNow() })
+	}
+	return conn, resp, err
+}
+
+// openSocket dials with a fresh ticket and reads past the greeting, checking
+// that it is the one a first connect gets.
+func (h *streamHarness) openSocket(t *testing.T, unread int64) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := h.dialSocket(t, "ticket="+h.mintTicket(t).Ticket)
+	require.NoError(t, err, "the upgrade must succeed for a valid ticket")
+
+	require.JSONEq(t, `{"type":"subscribed","groupIds":["`+streamGroupId+`"]}`, readSocket(t, conn),
+		"a connection starts subscribed to every group the user is in")
+	require.JSONEq(t, `{"type":"unread","unread":`+jsonInt(unread)+`}`, readSocket(t, conn),
+		"the greeting carries the badge count")
+	return conn
+}
+
+// readSocket reads one message as raw JSON.
+func readSocket(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(t.Context(), streamTestTimeout)
+	defer cancel()
+
+	var message json.RawMessage
+	require.NoError(t, wsjson.Read(ctx, conn, &message), "the socket closed or timed out before a message arrived")
+	return string(message)
+}
+
+func sendSocket(t *testing.T, conn *websocket.Conn, message string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(t.Context(), streamTestTimeout)
+	defer cancel()
+
+	require.NoError(t, conn.Write(ctx, websocket.MessageText, []byte(message)), "failed to send %s", message)
+}
+
+func jsonInt(n int64) string {
+	b, _ := json.Marshal(n)
+	return string(b)
+}
+
+func TestActivityStreamSocket(t *testing.T) {
+	production := streamPingInterval
+	streamPingInterval = testPingInterval
+	t.Cleanup(func() { streamPingInterval = production })
+
+	activeUser := models.User{Id: streamUserId, Username: "reader", IsActive: true, Groups: []string{streamGroupId}}
+	log := []models.ActivityEvent{loggedEvent(1), loggedEvent(2), loggedEvent(3)}
+
+	t.Run("an unredeemable ticket is a 401 before any upgrade", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser})
+
+		_, resp, err := h.dialSocket(t, "ticket=never-issued")
+
+		require.Error(t, err)
+		require.NotNil(t, resp, "a refused upgrade still answers over HTTP")
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		require.Zero(t, h.hub.SubscriberCount())
+	})
+
+	t.Run("a ticket works once, on either stream", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser})
+		ticket := h.mintTicket(t)
+
+		_, _, err := h.dialSocket(t, "ticket="+ticket.Ticket)
+		require.NoError(t, err)
+
+		_, resp, err := h.dialSocket(t, "ticket="+ticket.Ticket)
+		require.Error(t, err)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode, "a redeemed ticket must not open a second socket")
+	})
+
+	t.Run("a published event is pushed as the feed element, with the badge it raised", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser, unread: 3})
+		conn := h.openSocket(t, 3)
+
+		event := sampleEvent()
+		h.hub.Publish(event)
+
+		want, err := json.Marshal(activity.MapDbEventToApiEvent(event))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"type":"event","event":`+string(want)+`}`, readSocket(t, conn),
+			"the pushed event must be the same Event GET /activity returns")
+		require.JSONEq(t, `{"type":"unread","unread":4}`, readSocket(t, conn))
+	})
+
+	t.Run("an unsubscribed group is not pushed, but still counts", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser})
+		conn := h.openSocket(t, 0)
+
+		sendSocket(t, conn, `{"type":"unsubscribe","groupIds":["`+streamGroupId+`"]}`)
+		require.JSONEq(t, `{"type":"subscribed","groupIds":[]}`, readSocket(t, conn))
+
+		h.hub.Publish(sampleEvent())
+		require.JSONEq(t, `{"type":"unread","unread":1}`, readSocket(t, conn),
+			"the badge counts every group, whatever the socket is subscribed to")
+
+		sendSocket(t, conn, `{"type":"subscribe"}`)
+		require.JSONEq(t, `{"type":"subscribed","groupIds":["`+streamGroupId+`"]}`, readSocket(t, conn),
+			"a subscribe without groupIds is every group")
+	})
+
+	t.Run("a group the user is not in cannot be subscribed to", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser})
+		conn := h.openSocket(t, 0)
+
+		sendSocket(t, conn, `{"type":"subscribe","groupIds":["someone-elses-group"]}`)
+
+		require.Contains(t, readSocket(t, conn), `"type":"error"`)
+	})
+
+	t.Run("an ack marks the event read and answers with the badge", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser, log: log, unread: 2})
+		conn := h.openSocket(t, 2)
+
+		sendSocket(t, conn, `{"type":"ack","eventId":"event-1"}`)
+		require.JSONEq(t, `{"type":"unread","unread":2}`, readSocket(t, conn))
+
+		sendSocket(t, conn, `{"type":"ack","eventId":"no-such-event"}`)
+		require.JSONEq(t, `{"type":"error","error":"activity event not found","eventId":"no-such-event"}`, readSocket(t, conn))
+	})
+
+	t.Run("ping is answered, and a bad message does not end the connection", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser})
+		conn := h.openSocket(t, 0)
+
+		sendSocket(t, conn, `not json`)
+		require.Contains(t, readSocket(t, conn), `"type":"error"`)
+		sendSocket(t, conn, `{"type":"shout"}`)
+		require.Contains(t, readSocket(t, conn), `"type":"error"`)
+
+		sendSocket(t, conn, `{"type":"ping"}`)
+		require.JSONEq(t, `{"type":"pong"}`, readSocket(t, conn))
+	})
+
+	t.Run("a message sent while a ping is waiting for its pong does not close the connection", func(t *testing.T) {
+		production := socketWriteTimeout
+		socketWriteTimeout = streamTestTimeout / 2
+		t.Cleanup(func() { socketWriteTimeout = production })
+
+		h := newStreamHarness(t, stubStreamStore{user: activeUser})
+		conn := h.openSocket(t, 0)
+
+		// Not reading leaves the server's ping unanswered, since the client
+		// answers pings as it reads; the message then arrives while the
+		// server is still waiting for the pong.
+		time.Sleep(3 * testPingInterval)
+		sendSocket(t, conn, `{"type":"ping"}`)
+		require.JSONEq(t, `{"type":"pong"}`, readSocket(t, conn))
+
+		time.Sleep(3 * testPingInterval)
+		sendSocket(t, conn, `{"type":"ping"}`)
+		require.JSONEq(t, `{"type":"pong"}`, readSocket(t, conn), "and the connection is still up pings later")
+	})
+
+	t.Run("lastEventId replays what came after it, then goes live", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser, log: log})
+		conn, _, err := h.dialSocket(t, "ticket="+h.mintTicket(t).Ticket+"&lastEventId=1")
+		require.NoError(t, err)
+
+		readSocket(t, conn) // subscribed
+		readSocket(t, conn) // unread
+		require.Contains(t, readSocket(t, conn), `"seq":2`)
+		require.Contains(t, readSocket(t, conn), `"seq":3`)
+
+		h.hub.Publish(loggedEvent(3))
+		h.hub.Publish(loggedEvent(4))
+		require.Contains(t, readSocket(t, conn), `"seq":4`, "a replayed event arriving live too is sent once")
+	})
+
+	t.Run("lastEventId during quiet hours replays nothing", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser, log: log,
+			prefs: models.ActivityPreferences{QuietHours: quietNow()}})
+		conn, _, err := h.dialSocket(t, "ticket="+h.mintTicket(t).Ticket+"&lastEventId=1")
+		require.NoError(t, err)
+
+		readSocket(t, conn) // subscribed
+		readSocket(t, conn) // unread
+		sendSocket(t, conn, `{"type":"ping"}`)
+		require.JSONEq(t, `{"type":"pong"}`, readSocket(t, conn), "no event came before the answer")
+	})
+
+	t.Run("joining and leaving groups updates the subscribed set and the badge", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser, unread: 5})
+		conn := h.openSocket(t, 5)
+
+		h.hub.ApplyMembership(models.MembershipChange{Kind: models.MembershipAdded, UserId: streamUserId, GroupId: "joined-later"})
+		require.JSONEq(t, `{"type":"subscribed","groupIds":["`+streamGroupId+`","joined-later"]}`, readSocket(t, conn),
+			"a group joined while connected is subscribed to like the others")
+		require.JSONEq(t, `{"type":"unread","unread":5}`, readSocket(t, conn))
+
+		h.hub.ApplyMembership(models.MembershipChange{Kind: models.MembershipRemoved, UserId: streamUserId, GroupId: streamGroupId})
+		require.JSONEq(t, `{"type":"subscribed","groupIds":["joined-later"]}`, readSocket(t, conn))
+		readSocket(t, conn) // unread
+
+		sendSocket(t, conn, `{"type":"subscribe","groupIds":["`+streamGroupId+`"]}`)
+		require.Contains(t, readSocket(t, conn), `"type":"error"`, "a group the user left cannot be subscribed to again")
+	})
+
+	t.Run("a closed socket unsubscribes", func(t *testing.T) {
+		h := newStreamHarness(t, stubStreamStore{user: activeUser})
+		conn := h.openSocket(t, 0)
+		requireSubscribers(t, h.hub, 1, "an open socket holds one subscriber")
+
+		require.NoError(t, conn.Close(websocket.StatusNormalClosure, ""))
+
+		requireSubscribers(t, h.hub, 0, "a closed socket must not leave its subscriber behind")
+	})
+}
@@ -1,9 +1,16 @@
 package api
 
 import (
+	"context"
+
 	"github.com/lealre/movies-backend/internal/services/activity"
+	"github.com/lealre/movies-backend/internal/services/imports"
+	"github.com/lealre/movies-backend/internal/services/stats"
+	"github.com/lealre/movies-backend/internal/services/titles"
 	"github.com/lealre/movies-backend/internal/store"
 	"github.com/lealre/movies-backend/internal/titleprovider"
+	"github.com/lealre/movies-backend/internal/titleprovider/streaming"
+	"github.com/lealre/movies-backend/internal/titleprovider/titleref"
 )
 
 type ErrorResponse struct {
@@ -11,6 +18,16 @@ type ErrorResponse struct {
 	ErrorMessage string `json:"errorMessage"`
 }
 
+// TitleReferenceErrorResponse is ErrorResponse for a title link that could not
+// be resolved: Source names the site it was recognised as, and Candidates
+// lists the titles an ambiguous link matched, so the client can offer them.
+type TitleReferenceErrorResponse struct {
+	StatusCode   int            `json:"statusCode"`
+	ErrorMessage string         `json:"errorMessage"`
+	Source       string         `json:"source,omitempty"`
+	Candidates   []titles.Title `json:"candidates,omitempty"`
+}
+
 type DefaultResponse struct {
 	Message string `json:"message"`
 }
@@ -20,15 +37,39 @@ type API struct {
 	Secret   *string
 	Provider titleprovider.Provider
 
+	// Resolver turns the title links users paste into IMDb IDs; see
+	// titleref.
+	Resolver *titleref.Resolver
+
+	// Streaming fetches where a newly added title streams. Nil unless
+	// STREAMING_PROVIDER is set; availability already stored is served
+	// either way.
+	Streaming streaming.Provider
+
 	// Stream is nil unless ACTIVITY_FEED_ENABLED is on: the server only builds
 	// it — and only registers the two routes that use it — inside the flag
 	// branch, so with the feature off there is no hub, no ticket store and no
 	// handler that could reach them.
 	Stream *activity.Streamer
+
+	// Stats holds computed group dashboards. It is safe to share across
+	// requests; see stats.Cache for when an entry is served.
+	Stats *stats.Cache
+
+	// Imports runs CSV imports in the background. NewAPI's runs them on
+	// context.Background(); the server replaces it with one bound to its own
+	// context, so shutting down stops them.
+	Imports *imports.Jobs
 }
 
 func NewAPI(db store.Store, provider titleprovider.Provider) *API {
-	return &API{Db: db, Provider: provider}
+	return &API{
+		Db:       db,
+		Provider: provider,
+		Resolver: titleref.NewResolver(provider, nil),
+		Stats:    stats.NewCache(stats.DefaultCacheSize),
+		Imports:  imports.NewJobs(context.Background()),
+	}
 }
 
 var PublicPaths = map[string]bool{
@@ -40,4 +81,7 @@ var PublicPaths = map[string]bool{
 	// is deliberately absent from this map — it needs a real authenticated
 	// user, or anyone could mint a ticket for anyone.
 	"GET /activity/stream": true,
+	// The same ticket, for the same reason: a browser WebSocket cannot set
+	// headers either.
+	"GET /activity/ws": true,
 }
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/lealre/movies-backend/internal/auth"
+	"github.com/lealre/movies-backend/internal/logx"
+	"github.com/lealre/movies-backend/internal/services/availability"
+)
+
+// GetMyStreamingServices serves GET /users/me/streaming-services: the services
+// the caller subscribes to and their region, or the defaults if they have
+// never set them.
+func (api *API) GetMyStreamingServices(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	prefs, err := availability.GetPreferences(api.Db, r.Context(), currentUser.Id)
+	if err != nil {
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// SetMyStreamingServices serves PUT /users/me/streaming-services, replacing
+// the caller's services and region. They decide both the region group titles
+// show availability for and what ?availableOnMyServices=true keeps.
+func (api *API) SetMyStreamingServices(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	var req availability.PreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	prefs, err := availability.SetPreferences(api.Db, r.Context(), currentUser.Id, req)
+	if err != nil {
+		if statusCode, ok := availability.ErrorMap[err]; ok {
+			respondWithError(w, statusCode, formatErrorMessage(err))
+			return
+		}
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, prefs)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/lealre/movies-backend/internal/auth"
+	"github.com/lealre/movies-backend/internal/logx"
+	"github.com/lealre/movies-backend/internal/services/digests"
+)
+
+// GetActivityDigest is how often the caller is emailed a digest of their
+// unread activity — "off" if never — and when the next one is due.
+func (api *API) GetActivityDigest(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	settings, err := digests.GetSettings(api.Db, r.Context(), currentUser.Id)
+	if err != nil {
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, settings)
+}
+
+// SetActivityDigest subscribes the caller to a daily or weekly digest, or
+// unsubscribes them with "off", and answers with the settings as stored.
+func (api *API) SetActivityDigest(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	var req digests.SettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	settings, err := digests.SetSettings(api.Db, r.Context(), currentUser.Id, req)
+	if err != nil {
+		if code, ok := digests.ErrorMap[err]; ok {
+			respondWithError(w, code, err.Error())
+			return
+		}
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, settings)
+}
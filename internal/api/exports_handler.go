@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/lealre/movies-backend/internal/auth"
+	"github.com/lealre/movies-backend/internal/logx"
+	"github.com/lealre/movies-backend/internal/services/exports"
+)
+
+// ExportGroup downloads the group as ?format=json (the default, which POST
+// /groups/{id}/imports reads back), csv, or letterboxd (the caller's own
+// ratings, for letterboxd.com/import). The body is streamed as it is read.
+func (api *API) ExportGroup(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId := r.PathValue("id")
+	if groupId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group id is required")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exports.FormatJSON
+	}
+
+	export, err := exports.NewExport(api.Db, r.Context(), groupId, currentUser.Id, format)
+	if err != nil {
+		if statusCode, ok := exports.ErrorMap[err]; ok {
+			respondWithError(w, statusCode, formatErrorMessage(err))
+			return
+		}
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="`+export.Filename()+`"`)
+	w.WriteHeader(http.StatusOK)
+	if err := export.Write(r.Context(), w); err != nil {
+		// Too late for an error status: the download ends short.
+		logger.Printf("ERROR: exporting group %s: %v", groupId, err)
+	}
+}
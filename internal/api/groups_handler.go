@@ -2,15 +2,18 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"regexp"
 	"strings"
 
 	"github.com/lealre/movies-backend/internal/activity"
 	"github.com/lealre/movies-backend/internal/auth"
 	"github.com/lealre/movies-backend/internal/generics"
 	"github.com/lealre/movies-backend/internal/logx"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/services/availability"
 	"github.com/lealre/movies-backend/internal/services/groups"
 	"github.com/lealre/movies-backend/internal/services/titles"
 	"github.com/lealre/movies-backend/internal/services/users"
@@ -82,7 +85,7 @@ func (api *API) UpdateGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	group, err := groups.UpdateGroupInfo(api.Db, r.Context(), groupId, currentUser.Id, req.Name, req.Description)
+	group, previous, err := groups.UpdateGroupInfo(api.Db, r.Context(), groupId, currentUser.Id, req.Name, req.Description)
 	if err != nil {
 		if code, ok := groups.ErrorMap[err]; ok {
 			respondWithError(w, code, formatErrorMessage(err))
@@ -92,6 +95,12 @@ func (api *API) UpdateGroup(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
 		return
 	}
+
+	// A save that changed nothing is not news.
+	if group.Name != previous.Name || group.Description != previous.Description {
+		activity.Record(r.Context(), activity.GroupUpdated(groupId, group.Name, previous.Name, group.Description, previous.Description))
+	}
+
 	respondWithJSON(w, http.StatusOK, group)
 }
 
@@ -105,7 +114,8 @@ func (api *API) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := groups.SoftDeleteGroup(api.Db, r.Context(), groupId, currentUser.Id); err != nil {
+	group, err := groups.SoftDeleteGroup(api.Db, r.Context(), groupId, currentUser.Id)
+	if err != nil {
 		if code, ok := groups.ErrorMap[err]; ok {
 			respondWithError(w, code, formatErrorMessage(err))
 			return
@@ -114,6 +124,9 @@ func (api *API) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
 		return
 	}
+
+	activity.Record(r.Context(), activity.GroupDeleted(groupId, group.Users))
+
 	respondWithJSON(w, http.StatusOK, DefaultResponse{Message: "Group deleted"})
 }
 
@@ -143,6 +156,9 @@ func (api *API) RemoveUserFromGroup(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
 		return
 	}
+
+	activity.Record(r.Context(), activity.MemberLeft(groupId))
+
 	respondWithJSON(w, http.StatusOK, DefaultResponse{Message: "Left group"})
 }
 
@@ -189,7 +205,7 @@ func (api *API) AddUserToGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 3 - Add user to group and update user group list
-	err := groups.AddUserToGroup(api.Db, r.Context(), groupId, currentUser.Id, req.UserId)
+	added, err := groups.AddUserToGroup(api.Db, r.Context(), groupId, currentUser.Id, req.UserId)
 	if err != nil {
 		if statusCode, ok := groups.ErrorMap[err]; ok {
 			respondWithError(w, statusCode, formatErrorMessage(err))
@@ -200,6 +216,8 @@ func (api *API) AddUserToGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	activity.Record(r.Context(), activity.MemberAdded(groupId, added.Id, added.Username))
+
 	respondWithJSON(w, http.StatusOK, DefaultResponse{Message: fmt.Sprintf("User %s added to group %s", req.UserId, groupId)})
 }
 
@@ -237,7 +255,27 @@ func (api *API) GetTitlesFromGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	titles, err := groups.GetTitlesFromGroup(api.Db, r.Context(), groupId, size, page, orderBy, watched, ascending, titleTypePtr)
+	region, err := availability.RegionFor(api.Db, r.Context(), currentUser.Id)
+	if err != nil {
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+	var availableOn *models.StreamingFilter
+	if onMyServices := parseUrlQueryToBool(r.URL.Query().Get("availableOnMyServices")); onMyServices != nil && *onMyServices {
+		availableOn, err = availability.FilterFor(api.Db, r.Context(), currentUser.Id)
+		if err != nil {
+			if statusCode, ok := availability.ErrorMap[err]; ok {
+				respondWithError(w, statusCode, formatErrorMessage(err))
+				return
+			}
+			logger.Printf("ERROR: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+			return
+		}
+	}
+
+	titles, err := groups.GetTitlesFromGroup(api.Db, r.Context(), groupId, size, page, orderBy, watched, ascending, titleTypePtr, region, availableOn)
 	if err != nil {
 		if statusCode, ok := groups.ErrorMap[err]; ok {
 			respondWithError(w, statusCode, formatErrorMessage(err))
@@ -288,7 +326,14 @@ func (api *API) GetTitleFromGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	detail, err := groups.GetGroupTitleDetail(api.Db, r.Context(), groupId, titleId)
+	region, err := availability.RegionFor(api.Db, r.Context(), currentUser.Id)
+	if err != nil {
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+
+	detail, err := groups.GetGroupTitleDetail(api.Db, r.Context(), groupId, titleId, region)
 	if err != nil {
 		if statusCode, ok := groups.ErrorMap[err]; ok {
 			respondWithError(w, statusCode, formatErrorMessage(err))
@@ -345,7 +390,7 @@ func (api *API) AddTitleToGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if req.URL == "" {
-		respondWithError(w, http.StatusBadRequest, "Imdb url is required")
+		respondWithError(w, http.StatusBadRequest, "Title url is required")
 		return
 	}
 
@@ -359,14 +404,19 @@ func (api *API) AddTitleToGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Accept URLs like https://www.imdb.com/title/tt8009428/ and extract the ID (tt...)
-	re := regexp.MustCompile(`^https?://(?:www\.)?imdb\.com/title/(tt[0-9]+)/?`)
-	m := re.FindStringSubmatch(req.URL)
-	if len(m) != 2 {
-		respondWithError(w, http.StatusBadRequest, "Invalid IMDb title URL")
+	titleID, err := titles.ResolveTitleReference(api.Resolver, r.Context(), req.URL)
+	if err != nil {
+		if respondWithTitleReferenceError(w, err) {
+			return
+		}
+		if code, ok := titles.ErrorMap[err]; ok {
+			respondWithError(w, code, err.Error())
+			return
+		}
+		logger.Printf("ERROR: resolving %q: %v", req.URL, err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
 		return
 	}
-	titleID := m[1]
 
 	// If titles id is not in the main titles collection, add it
 	titleExists, err := titles.TitleExists(api.Db, r.Context(), titleID)
@@ -415,6 +465,55 @@ func (api *API) AddTitleToGroup(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, DefaultResponse{Message: fmt.Sprintf("Title %s added to group %s", titleID, groupId)})
 }
 
+// BulkAddTitlesToGroup serves POST /groups/{id}/titles/bulk: it adds every
+// title the request lists and answers 200 with what became of each one, even
+// when some could not be added. The feed gets one event for the lot.
+func (api *API) BulkAddTitlesToGroup(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId := r.PathValue("id")
+	if groupId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group id is required")
+		return
+	}
+
+	var req groups.BulkAddTitlesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	resp, err := groups.BulkAddTitlesToGroup(api.Db, api.Provider, api.Resolver, r.Context(), groupId, currentUser.Id, req.URLs)
+	if err != nil {
+		if statusCode, ok := groups.ErrorMap[err]; ok {
+			respondWithError(w, statusCode, formatErrorMessage(err))
+			return
+		}
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+
+	var addedIds, addedNames []string
+	for _, result := range resp.Results {
+		if result.Status == groups.BulkAdded {
+			addedIds = append(addedIds, result.TitleId)
+			addedNames = append(addedNames, result.TitleName)
+		}
+	}
+	switch len(addedIds) {
+	case 0:
+	case 1:
+		activity.Record(r.Context(), activity.TitleAdded(groupId, addedIds[0], addedNames[0]))
+	default:
+		activity.Record(r.Context(), activity.TitlesAdded(groupId, addedIds, addedNames))
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
 func (api *API) UpdateGroupTitleWatched(w http.ResponseWriter, r *http.Request) {
 	logger := logx.FromContext(r.Context())
 	currentUser := auth.GetUserFromContext(r.Context())
@@ -527,3 +626,46 @@ func (api *API) DeleteTitleFromGroup(w http.ResponseWriter, r *http.Request) {
 
 	respondWithJSON(w, http.StatusOK, DefaultResponse{Message: fmt.Sprintf("Title %s deleted from group %s", titleId, groupId)})
 }
+
+func (api *API) PickGroupTitle(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId := r.PathValue("id")
+	if groupId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group id is required")
+		return
+	}
+
+	// Every field is optional, so an empty body is a valid request.
+	var req groups.PickRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	// The only existence/membership guard: PickTitle reads the group through
+	// GetGroupTitlesPage, which checks neither, exactly as the titles list does.
+	if ok, err := groups.GroupExists(api.Db, r.Context(), groupId, currentUser.Id); err != nil {
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	} else if !ok {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("Group with id %s not found", groupId))
+		return
+	}
+
+	pick, err := groups.PickTitle(api.Db, r.Context(), groupId, currentUser.Id, req)
+	if err != nil {
+		if statusCode, ok := groups.ErrorMap[err]; ok {
+			respondWithError(w, statusCode, formatErrorMessage(err))
+			return
+		}
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, pick)
+}
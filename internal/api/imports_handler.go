@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/lealre/movies-backend/internal/auth"
+	"github.com/lealre/movies-backend/internal/logx"
+	"github.com/lealre/movies-backend/internal/services/groups"
+	"github.com/lealre/movies-backend/internal/services/imports"
+)
+
+// maxImportBytes bounds an uploaded export. Ten thousand IMDb rows, the most
+// an import takes, come to about 2 MB; a group export of as many titles with
+// a few comments each fits as well.
+const maxImportBytes = 10 << 20
+
+// StartImport takes a group's JSON export (GET /groups/{id}/export), or an
+// IMDb or Letterboxd CSV export, uploaded as the "file" field of a multipart
+// form, and imports it into the group in the background.
+// It answers 202 with the job; poll GET /groups/{id}/imports/{jobId} for its
+// progress and report.
+func (api *API) StartImport(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId := r.PathValue("id")
+	if groupId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group id is required")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithError(w, imports.ErrorMap[imports.ErrImportTooLarge], formatErrorMessage(imports.ErrImportTooLarge))
+			return
+		}
+		respondWithError(w, imports.ErrorMap[imports.ErrImportFileRequired], formatErrorMessage(imports.ErrImportFileRequired))
+		return
+	}
+	defer file.Close()
+
+	job, err := imports.StartImport(api.Db, api.Provider, api.Resolver, api.Imports, r.Context(), groupId, currentUser.Id, file)
+	if err != nil {
+		if statusCode, ok := imports.ErrorMap[err]; ok {
+			respondWithError(w, statusCode, formatErrorMessage(err))
+			return
+		}
+		if statusCode, ok := groups.ErrorMap[err]; ok {
+			respondWithError(w, statusCode, formatErrorMessage(err))
+			return
+		}
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, job)
+}
+
+// GetImport returns an import's progress, and once it is done its report of
+// the rows that were not, or only partly, imported.
+func (api *API) GetImport(w http.ResponseWriter, r *http.Request) {
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId, jobId := r.PathValue("id"), r.PathValue("jobId")
+	if groupId == "" || jobId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group id and import id are required")
+		return
+	}
+
+	job, err := imports.GetImport(api.Imports, groupId, currentUser.Id, jobId)
+	if err != nil {
+		if statusCode, ok := imports.ErrorMap[err]; ok {
+			respondWithError(w, statusCode, formatErrorMessage(err))
+			return
+		}
+		logx.FromContext(r.Context()).Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, job)
+}
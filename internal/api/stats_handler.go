@@ -0,0 +1,136 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/lealre/movies-backend/internal/auth"
+	"github.com/lealre/movies-backend/internal/logx"
+	"github.com/lealre/movies-backend/internal/services/groups"
+	"github.com/lealre/movies-backend/internal/services/stats"
+)
+
+func (api *API) GetGroupStats(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId := r.PathValue("id")
+	if groupId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group id is required")
+		return
+	}
+
+	// Checked here rather than left to the service: a cached dashboard is
+	// served without loading the group, so the cache must never be reachable
+	// by someone who is not a member.
+	if ok, err := groups.GroupExists(api.Db, r.Context(), groupId, currentUser.Id); err != nil {
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	} else if !ok {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("Group with id %s not found", groupId))
+		return
+	}
+
+	query := stats.StatsQuery{
+		From: r.URL.Query().Get("from"),
+		To:   r.URL.Query().Get("to"),
+	}
+
+	result, err := stats.GetGroupStats(api.Db, r.Context(), api.Stats, groupId, currentUser.Id, query)
+	if err != nil {
+		if statusCode, ok := stats.ErrorMap[err]; ok {
+			respondWithError(w, statusCode, formatErrorMessage(err))
+			return
+		}
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+func (api *API) GetGroupYearInReview(w http.ResponseWriter, r *http.Request) {
+	review, ok := api.groupYearInReview(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, review)
+}
+
+func (api *API) GetGroupYearInReviewCard(w http.ResponseWriter, r *http.Request) {
+	review, ok := api.groupYearInReview(w, r)
+	if !ok {
+		return
+	}
+	api.respondWithReviewCard(w, r, review)
+}
+
+func (api *API) GetMyYearInReview(w http.ResponseWriter, r *http.Request) {
+	review, ok := api.userYearInReview(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, review)
+}
+
+func (api *API) GetMyYearInReviewCard(w http.ResponseWriter, r *http.Request) {
+	review, ok := api.userYearInReview(w, r)
+	if !ok {
+		return
+	}
+	api.respondWithReviewCard(w, r, review)
+}
+
+// groupYearInReview is the part the JSON and card routes share: it writes the
+// error response itself and reports false when there is nothing to render.
+func (api *API) groupYearInReview(w http.ResponseWriter, r *http.Request) (stats.YearInReview, bool) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId := r.PathValue("id")
+	if groupId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group id is required")
+		return stats.YearInReview{}, false
+	}
+
+	review, err := stats.GetGroupYearInReview(api.Db, r.Context(), groupId, currentUser.Id, r.PathValue("year"))
+	if err != nil {
+		if statusCode, ok := stats.ErrorMap[err]; ok {
+			respondWithError(w, statusCode, formatErrorMessage(err))
+			return stats.YearInReview{}, false
+		}
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return stats.YearInReview{}, false
+	}
+	return review, true
+}
+
+func (api *API) userYearInReview(w http.ResponseWriter, r *http.Request) (stats.YearInReview, bool) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	review, err := stats.GetUserYearInReview(api.Db, r.Context(), currentUser.Id, r.PathValue("year"))
+	if err != nil {
+		if statusCode, ok := stats.ErrorMap[err]; ok {
+			respondWithError(w, statusCode, formatErrorMessage(err))
+			return stats.YearInReview{}, false
+		}
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return stats.YearInReview{}, false
+	}
+	return review, true
+}
+
+func (api *API) respondWithReviewCard(w http.ResponseWriter, r *http.Request, review stats.YearInReview) {
+	card, err := stats.RenderYearInReviewCard(review)
+	if err != nil {
+		logx.FromContext(r.Context()).Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+	respondWithHTML(w, http.StatusOK, card)
+}
@@ -4,15 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"regexp"
+	"time"
 
 	"github.com/lealre/movies-backend/internal/auth"
 	"github.com/lealre/movies-backend/internal/config"
 	"github.com/lealre/movies-backend/internal/generics"
 	"github.com/lealre/movies-backend/internal/logx"
 	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/services/availability"
 	"github.com/lealre/movies-backend/internal/services/titles"
 	"github.com/lealre/movies-backend/internal/store"
+	"github.com/lealre/movies-backend/internal/titleprovider/cache"
 )
 
 func (api *API) GetTitles(w http.ResponseWriter, r *http.Request) {
@@ -55,18 +57,23 @@ func (api *API) AddTitle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if req.URL == "" {
-		respondWithError(w, http.StatusBadRequest, "Imdb url is required")
+		respondWithError(w, http.StatusBadRequest, "Title url is required")
 		return
 	}
 
-	// Accept URLs like https://www.imdb.com/title/tt8009428/ and extract the ID (tt...)
-	re := regexp.MustCompile(`^https?://(?:www\.)?imdb\.com/title/(tt[0-9]+)/?`)
-	m := re.FindStringSubmatch(req.URL)
-	if len(m) != 2 {
-		respondWithError(w, titles.ErrorMap[titles.ErrInvalidIMDbURL], titles.ErrInvalidIMDbURL.Error())
+	titleID, err := titles.ResolveTitleReference(api.Resolver, r.Context(), req.URL)
+	if err != nil {
+		if respondWithTitleReferenceError(w, err) {
+			return
+		}
+		if code, ok := titles.ErrorMap[err]; ok {
+			respondWithError(w, code, err.Error())
+			return
+		}
+		logger.Printf("ERROR: resolving %q: %v", req.URL, err)
+		respondWithError(w, http.StatusInternalServerError, "Error adding title")
 		return
 	}
-	titleID := m[1]
 
 	if titleExists, err := titles.TitleExists(api.Db, r.Context(), titleID); titleExists {
 		respondWithError(w, titles.ErrorMap[titles.ErrTitleAlreadyExists], titles.ErrTitleAlreadyExists.Error())
@@ -88,6 +95,14 @@ func (api *API) AddTitle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Best effort: the title is added either way, and the routine fetches
+	// whatever availability this misses on its next run.
+	if api.Streaming != nil {
+		if err := availability.Refresh(api.Db, r.Context(), api.Streaming, titleID); err != nil {
+			logger.Printf("WARN: fetching streaming availability for %s: %v", titleID, err)
+		}
+	}
+
 	respondWithJSON(w, http.StatusCreated, title)
 }
 
@@ -139,14 +154,18 @@ func (api *API) SearchTitles(w http.ResponseWriter, r *http.Request) {
 		limit = config.DefaultSearchLimit()
 	}
 
-	titles, err := titles.SearchTitles(api.Provider, r.Context(), searchQuery, limit)
+	results, err := titles.SearchTitles(api.Provider, r.Context(), searchQuery, limit)
 	if err != nil {
+		if code, ok := titles.ErrorMap[err]; ok {
+			respondWithError(w, code, err.Error())
+			return
+		}
 		logger.Printf("ERROR: %v", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to search titles")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, titles)
+	respondWithJSON(w, http.StatusOK, results)
 }
 
 // GetTitleEpisodes returns a title's episodes on demand (lazy-loaded by the UI
@@ -173,3 +192,49 @@ func (api *API) GetTitleEpisodes(w http.ResponseWriter, r *http.Request) {
 
 	respondWithJSON(w, http.StatusOK, map[string]any{"episodes": episodes})
 }
+
+// GetTitleProviderCacheStats reports the title provider cache's counters since
+// the process started. Admin only; 404 when the cache is not enabled
+// (TITLE_CACHE_ENABLED), since there is nothing to report on.
+func (api *API) GetTitleProviderCacheStats(w http.ResponseWriter, r *http.Request) {
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	if currentUser.Role != models.RoleAdmin {
+		respondWithForbidden(w)
+		return
+	}
+
+	cached, ok := api.Provider.(*cache.Provider)
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Title provider cache is not enabled")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, cached.Stats())
+}
+
+// GetTitleProviderQuota reports today's (UTC) title provider requests against
+// the daily budgets, as counted across the server and the titles routine.
+// Admin only; 404 when the store does not count requests.
+func (api *API) GetTitleProviderQuota(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	if currentUser.Role != models.RoleAdmin {
+		respondWithForbidden(w)
+		return
+	}
+
+	report, err := titles.GetProviderQuota(api.Db, r.Context(), time.Now())
+	if err != nil {
+		if code, ok := titles.ErrorMap[err]; ok {
+			respondWithError(w, code, err.Error())
+			return
+		}
+		logger.Printf("ERROR: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
@@ -5,6 +5,9 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+
+	"github.com/lealre/movies-backend/internal/services/titles"
+	"github.com/lealre/movies-backend/internal/titleprovider/titleref"
 )
 
 var ErrForbidden = errors.New("you do not have permission to perform this action")
@@ -21,6 +24,16 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) error
 	return nil
 }
 
+// respondWithHTML writes a self-contained HTML document. The CSP forbids
+// every fetch but inline styles, so a page rendered from stored names can
+// neither load nor run anything.
+func respondWithHTML(w http.ResponseWriter, code int, body []byte) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
 func respondWithError(w http.ResponseWriter, code int, msg string) error {
 	messageBody := ErrorResponse{
 		StatusCode:   code,
@@ -38,6 +51,24 @@ func respondWithForbidden(w http.ResponseWriter) error {
 	return respondWithJSON(w, statusCode, messageBody)
 }
 
+// respondWithTitleReferenceError answers err when it is a title link that could
+// not be resolved, and reports whether it was; other errors are left to the
+// caller.
+func respondWithTitleReferenceError(w http.ResponseWriter, err error) bool {
+	var refErr *titleref.Error
+	if !errors.As(err, &refErr) {
+		return false
+	}
+	statusCode := titles.TitleReferenceStatus(refErr)
+	respondWithJSON(w, statusCode, TitleReferenceErrorResponse{
+		StatusCode:   statusCode,
+		ErrorMessage: formatErrorMessage(refErr),
+		Source:       refErr.Source,
+		Candidates:   titles.MapProviderSearchItemsToTitles(refErr.Candidates),
+	})
+	return true
+}
+
 func RespondWithUnauthorized(w http.ResponseWriter, err error) error {
 	statusCode := http.StatusUnauthorized
 	messageBody := ErrorResponse{
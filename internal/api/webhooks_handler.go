@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/lealre/movies-backend/internal/auth"
+	"github.com/lealre/movies-backend/internal/generics"
+	"github.com/lealre/movies-backend/internal/logx"
+	"github.com/lealre/movies-backend/internal/services/webhooks"
+)
+
+// CreateWebhook registers an endpoint on a group the caller owns. The response
+// carries the signing secret, and is the only one that ever will.
+func (api *API) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId := r.PathValue("id")
+	if groupId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group id is required")
+		return
+	}
+
+	var req webhooks.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	webhook, err := webhooks.Create(api.Db, r.Context(), groupId, currentUser.Id, req)
+	if err != nil {
+		respondWithWebhookError(w, logger, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, webhook)
+}
+
+func (api *API) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId := r.PathValue("id")
+	if groupId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group id is required")
+		return
+	}
+
+	result, err := webhooks.List(api.Db, r.Context(), groupId, currentUser.Id)
+	if err != nil {
+		respondWithWebhookError(w, logger, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+func (api *API) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId, webhookId := r.PathValue("id"), r.PathValue("webhookId")
+	if groupId == "" || webhookId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group id and webhook id are required")
+		return
+	}
+
+	var req webhooks.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	webhook, err := webhooks.Update(api.Db, r.Context(), groupId, webhookId, currentUser.Id, req)
+	if err != nil {
+		respondWithWebhookError(w, logger, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, webhook)
+}
+
+func (api *API) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId, webhookId := r.PathValue("id"), r.PathValue("webhookId")
+	if groupId == "" || webhookId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group id and webhook id are required")
+		return
+	}
+
+	if err := webhooks.Delete(api.Db, r.Context(), groupId, webhookId, currentUser.Id); err != nil {
+		respondWithWebhookError(w, logger, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, DefaultResponse{Message: "Webhook deleted"})
+}
+
+// GetWebhookDeliveries pages through an endpoint's delivery log, newest first,
+// with the activity feed's limit and before parameters.
+func (api *API) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId, webhookId := r.PathValue("id"), r.PathValue("webhookId")
+	if groupId == "" || webhookId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group id and webhook id are required")
+		return
+	}
+
+	limit := generics.StringToInt(r.URL.Query().Get("limit"))
+
+	var before *int64
+	if raw := r.URL.Query().Get("before"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "before must be a number")
+			return
+		}
+		before = &parsed
+	}
+
+	page, err := webhooks.ListDeliveries(api.Db, r.Context(), groupId, webhookId, currentUser.Id, before, limit)
+	if err != nil {
+		respondWithWebhookError(w, logger, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// RedeliverWebhookDelivery queues a delivery to be sent again. It answers 202:
+// the delivery is queued, not sent, and its log entry says how it went.
+func (api *API) RedeliverWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	logger := logx.FromContext(r.Context())
+	currentUser := auth.GetUserFromContext(r.Context())
+
+	groupId, webhookId, deliveryId := r.PathValue("id"), r.PathValue("webhookId"), r.PathValue("deliveryId")
+	if groupId == "" || webhookId == "" || deliveryId == "" {
+		respondWithError(w, http.StatusBadRequest, "Group id, webhook id and delivery id are required")
+		return
+	}
+
+	delivery, err := webhooks.Redeliver(api.Db, r.Context(), groupId, webhookId, deliveryId, currentUser.Id)
+	if err != nil {
+		respondWithWebhookError(w, logger, err)
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, delivery)
+}
+
+// respondWithWebhookError is the error half shared by every handler above,
+// which all answer from the same ErrorMap.
+func respondWithWebhookError(w http.ResponseWriter, logger *log.Logger, err error) {
+	if code, ok := webhooks.ErrorMap[err]; ok {
+		respondWithError(w, code, formatErrorMessage(err))
+		return
+	}
+	logger.Printf("ERROR: %v", err)
+	respondWithError(w, http.StatusInternalServerError, "Unexpected error occurred")
+}
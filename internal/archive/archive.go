@@ -0,0 +1,638 @@
+// Package archive writes and reads whole-instance backups that do not depend
+// on the store behind them: every user, title, group (with its members and
+// titles), rating, comment and activity event, and the read state and
+// notification preferences that go with them, read out through store.Archive
+// and restored through it into another store, of the same kind or not.
+//
+// An archive is a gzip-compressed stream of JSON lines:
+//
+//	{"format":"movies-backend-archive","version":1,"createdAt":"..."}
+//	{"kind":"user","data":{...}}
+//	... one line per record, kind by kind in the order of kinds ...
+//	{"kind":"end","data":{"counts":{"user":3,...}}}
+//
+// data is the record's internal/models value as encoding/json writes it, the
+// same shape titles are stored in already. A change to those models that an
+// older archive cannot be read into fails the import loudly (unknown fields
+// are rejected) rather than dropping data; that is the time to raise Version
+// and teach the reader the old shape.
+//
+// Left out on purpose: the title provider cache and request counters, which
+// are not data, and streaming availability, which the titles routine fetches
+// again. Webhooks are left out too — their endpoints, the endpoints' signing
+// secrets and the delivery log. A secret is a credential shared with the
+// receiver, and an archive is a file that gets copied around; restoring one
+// would also point a second instance's deliveries at the first one's
+// receivers. Owners register their webhooks again after a restore.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+const (
+	// Format names the file type in every archive's header.
+	Format = "movies-backend-archive"
+	// Version is the archive layout this package writes, and the newest it
+	// reads.
+	Version = 1
+
+	// pageSize is how many records are read from, or written to, the store
+	// at once.
+	pageSize = 500
+)
+
+// Record kinds, in the order an archive holds them: a record only refers to
+// kinds before its own, so restoring in this order never writes a reference
+// ahead of what it refers to.
+const (
+	KindUser                  = "user"
+	KindTitle                 = "title"
+	KindGroup                 = "group"
+	KindRecommendationSharing = "recommendation_sharing"
+	KindStreamingPreferences  = "streaming_preferences"
+	KindRating                = "rating"
+	KindComment               = "comment"
+	KindActivityEvent         = "activity_event"
+	KindActivityEventRead     = "activity_event_read"
+	KindActivityReadFloor     = "activity_read_floor"
+	KindActivityPreferences   = "activity_preferences"
+	KindActivityDigest        = "activity_digest"
+
+	kindEnd = "end"
+)
+
+var kinds = []string{
+	KindUser,
+	KindTitle,
+	KindGroup,
+	KindRecommendationSharing,
+	KindStreamingPreferences,
+	KindRating,
+	KindComment,
+	KindActivityEvent,
+	KindActivityEventRead,
+	KindActivityReadFloor,
+	KindActivityPreferences,
+	KindActivityDigest,
+}
+
+var (
+	ErrNotAnArchive       = errors.New("not an instance archive")
+	ErrUnsupportedVersion = errors.New("the archive was written by a newer version; upgrade before importing it")
+	ErrCorrupt            = errors.New("the archive is corrupt")
+	ErrNotEmpty           = errors.New("the database is not empty; an archive is only imported into an empty one")
+)
+
+// Counts is how many records of each kind an archive holds.
+type Counts map[string]int
+
+type header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type line struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+type trailer struct {
+	Counts Counts `json:"counts"`
+}
+
+// Export writes every record in db to w as an archive, a page at a time, and
+// returns how many of each kind it wrote.
+//
+// The pages are separate reads, not one snapshot: run it with the server
+// stopped, as the backup scripts stop it for pg_dump, or a rating added
+// mid-export may be in the archive while the title it was added with is not.
+func Export(ctx context.Context, db store.Archive, w io.Writer) (Counts, error) {
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	counts := Counts{}
+
+	if err := enc.Encode(header{Format: Format, Version: Version, CreatedAt: time.Now().UTC()}); err != nil {
+		return nil, err
+	}
+	write := func(kind string, record any) error {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		counts[kind]++
+		return enc.Encode(line{Kind: kind, Data: data})
+	}
+
+	if err := exportPages(ctx, KindUser, write, func(last *models.User) ([]models.User, error) {
+		after := ""
+		if last != nil {
+			after = last.Id
+		}
+		return db.ListUsers(ctx, after, pageSize)
+	}); err != nil {
+		return nil, err
+	}
+	if err := exportPages(ctx, KindTitle, write, func(last *models.Title) ([]models.Title, error) {
+		after := ""
+		if last != nil {
+			after = last.ID
+		}
+		return db.ListTitles(ctx, after, pageSize)
+	}); err != nil {
+		return nil, err
+	}
+	if err := exportPages(ctx, KindGroup, write, func(last *models.Group) ([]models.Group, error) {
+		after := ""
+		if last != nil {
+			after = last.Id
+		}
+		return db.ListGroups(ctx, after, pageSize)
+	}); err != nil {
+		return nil, err
+	}
+	if err := exportPages(ctx, KindRecommendationSharing, write, func(last *models.RecommendationSharing) ([]models.RecommendationSharing, error) {
+		after := ""
+		if last != nil {
+			after = last.GroupId
+		}
+		return db.ListRecommendationSharing(ctx, after, pageSize)
+	}); err != nil {
+		return nil, err
+	}
+	if err := exportPages(ctx, KindStreamingPreferences, write, func(last *models.StreamingPreferences) ([]models.StreamingPreferences, error) {
+		after := ""
+		if last != nil {
+			after = last.UserID
+		}
+		return db.ListStreamingPreferences(ctx, after, pageSize)
+	}); err != nil {
+		return nil, err
+	}
+	if err := exportPages(ctx, KindRating, write, func(last *models.UserRating) ([]models.UserRating, error) {
+		after := ""
+		if last != nil {
+			after = last.Id
+		}
+		return db.ListRatings(ctx, after, pageSize)
+	}); err != nil {
+		return nil, err
+	}
+	if err := exportPages(ctx, KindComment, write, func(last *models.Comment) ([]models.Comment, error) {
+		after := ""
+		if last != nil {
+			after = last.Id
+		}
+		return db.ListComments(ctx, after, pageSize)
+	}); err != nil {
+		return nil, err
+	}
+	if err := exportPages(ctx, KindActivityEvent, write, func(last *models.ActivityEvent) ([]models.ActivityEvent, error) {
+		var after int64
+		if last != nil {
+			after = last.Seq
+		}
+		return db.ListActivityEvents(ctx, after, pageSize)
+	}); err != nil {
+		return nil, err
+	}
+	if err := exportPages(ctx, KindActivityEventRead, write, func(last *models.ActivityEventRead) ([]models.ActivityEventRead, error) {
+		afterUser, afterEvent := "", ""
+		if last != nil {
+			afterUser, afterEvent = last.UserId, last.EventId
+		}
+		return db.ListActivityEventReads(ctx, afterUser, afterEvent, pageSize)
+	}); err != nil {
+		return nil, err
+	}
+	if err := exportPages(ctx, KindActivityReadFloor, write, func(last *models.ActivityReadFloor) ([]models.ActivityReadFloor, error) {
+		after := ""
+		if last != nil {
+			after = last.UserId
+		}
+		return db.ListActivityReadFloors(ctx, after, pageSize)
+	}); err != nil {
+		return nil, err
+	}
+	if err := exportPages(ctx, KindActivityPreferences, write, func(last *models.ActivityPreferences) ([]models.ActivityPreferences, error) {
+		after := ""
+		if last != nil {
+			after = last.UserId
+		}
+		return db.ListActivityPreferences(ctx, after, pageSize)
+	}); err != nil {
+		return nil, err
+	}
+	if err := exportPages(ctx, KindActivityDigest, write, func(last *models.ActivityDigest) ([]models.ActivityDigest, error) {
+		after := ""
+		if last != nil {
+			after = last.UserId
+		}
+		return db.ListActivityDigests(ctx, after, pageSize)
+	}); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(trailer{Counts: counts})
+	if err != nil {
+		return nil, err
+	}
+	if err := enc.Encode(line{Kind: kindEnd, Data: data}); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// exportPages writes every record next returns, page after page. next is
+// given the last record of the page before, nil for the first, to read the
+// page after it from.
+func exportPages[T any](ctx context.Context, kind string, write func(kind string, record any) error, next func(last *T) ([]T, error)) error {
+	var last *T
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page, err := next(last)
+		if err != nil {
+			return fmt.Errorf("read %s records: %w", kind, err)
+		}
+		for _, record := range page {
+			if err := write(kind, record); err != nil {
+				return err
+			}
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+		last = &page[len(page)-1]
+	}
+}
+
+// Import restores the archive open returns into db, which must be empty, and
+// returns how many records of each kind it restored.
+//
+// open is called twice: the archive is checked whole before anything is
+// written — its header, that it is not cut short (the trailer's counts),
+// unique keys, and that every reference the data model enforces points at a
+// record in the archive — so a bad file leaves db as it was. The second pass
+// writes it, a page at a time. Should a write fail part way, db is no longer
+// empty; empty it (drop and migrate the database again) before retrying.
+func Import(ctx context.Context, db store.Archive, open func() (io.ReadCloser, error)) (Counts, error) {
+	if _, err := readArchive(open, nil); err != nil {
+		return nil, err
+	}
+
+	empty, err := db.ArchiveIsEmpty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !empty {
+		return nil, ErrNotEmpty
+	}
+
+	r := &restorer{ctx: ctx, db: db}
+	counts, err := readArchive(open, r.add)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.flush(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// readArchive reads and checks the archive open returns, handing each record
+// to each, if set, in order. It returns the archive's counts.
+func readArchive(open func() (io.ReadCloser, error), each func(kind string, record any) error) (Counts, error) {
+	f, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return nil, ErrNotAnArchive
+	}
+	lines := bufio.NewScanner(zr)
+	lines.Buffer(make([]byte, 0, 64*1024), 64<<20)
+
+	if !lines.Scan() {
+		if err := lines.Err(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		return nil, ErrNotAnArchive
+	}
+	var h header
+	if err := json.Unmarshal(lines.Bytes(), &h); err != nil || h.Format != Format {
+		return nil, ErrNotAnArchive
+	}
+	if h.Version > Version {
+		return nil, ErrUnsupportedVersion
+	}
+
+	c := newChecker()
+	n := 1
+	var end *trailer
+	for lines.Scan() {
+		n++
+		if end != nil {
+			return nil, corrupt(n, "records after the end of the archive")
+		}
+		var l line
+		if err := json.Unmarshal(lines.Bytes(), &l); err != nil {
+			return nil, corrupt(n, "not a record")
+		}
+		if l.Kind == kindEnd {
+			end = &trailer{}
+			if err := json.Unmarshal(l.Data, end); err != nil {
+				return nil, corrupt(n, "unreadable counts")
+			}
+			continue
+		}
+		record, err := c.check(l)
+		if err != nil {
+			return nil, corrupt(n, err.Error())
+		}
+		if each != nil {
+			if err := each(l.Kind, record); err != nil {
+				return nil, fmt.Errorf("restore line %d: %w", n, err)
+			}
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	if end == nil {
+		return nil, fmt.Errorf("%w: it ends after line %d without its counts, so it was cut short", ErrCorrupt, n)
+	}
+	for _, kind := range kinds {
+		if end.Counts[kind] != c.counts[kind] {
+			return nil, fmt.Errorf("%w: it says it holds %d %s records, but holds %d", ErrCorrupt, end.Counts[kind], kind, c.counts[kind])
+		}
+	}
+	return c.counts, nil
+}
+
+func corrupt(line int, reason string) error {
+	return fmt.Errorf("%w: line %d: %s", ErrCorrupt, line, reason)
+}
+
+// checker decodes records and checks them against the ones before: kinds in
+// order, keys unique, and the references the data model enforces resolved.
+// It keeps the keys it has seen, which is one string per record: a few
+// megabytes for an instance of this app's size.
+type checker struct {
+	kind   int
+	counts Counts
+	seen   map[string]map[string]bool
+}
+
+func newChecker() *checker {
+	seen := make(map[string]map[string]bool, len(kinds))
+	for _, kind := range kinds {
+		seen[kind] = map[string]bool{}
+	}
+	return &checker{counts: Counts{}, seen: seen}
+}
+
+func (c *checker) check(l line) (any, error) {
+	kind := -1
+	for i, k := range kinds {
+		if k == l.Kind {
+			kind = i
+		}
+	}
+	if kind < 0 {
+		return nil, fmt.Errorf("unknown record kind %q", l.Kind)
+	}
+	if kind < c.kind {
+		return nil, fmt.Errorf("%s record after the %s records", l.Kind, kinds[c.kind])
+	}
+	c.kind = kind
+
+	var (
+		record any
+		key    string
+		refs   [][2]string // kind, key
+	)
+	switch l.Kind {
+	case KindUser:
+		var u models.User
+		if err := decode(l.Data, &u); err != nil {
+			return nil, err
+		}
+		record, key = u, u.Id
+	case KindTitle:
+		var t models.Title
+		if err := decode(l.Data, &t); err != nil {
+			return nil, err
+		}
+		record, key = t, t.ID
+	case KindGroup:
+		var g models.Group
+		if err := decode(l.Data, &g); err != nil {
+			return nil, err
+		}
+		record, key = g, g.Id
+	case KindRecommendationSharing:
+		var s models.RecommendationSharing
+		if err := decode(l.Data, &s); err != nil {
+			return nil, err
+		}
+		record, key = s, s.GroupId
+		refs = append(refs, [2]string{KindGroup, s.GroupId})
+	case KindStreamingPreferences:
+		var p models.StreamingPreferences
+		if err := decode(l.Data, &p); err != nil {
+			return nil, err
+		}
+		record, key = p, p.UserID
+		refs = append(refs, [2]string{KindUser, p.UserID})
+	case KindRating:
+		var r models.UserRating
+		if err := decode(l.Data, &r); err != nil {
+			return nil, err
+		}
+		record, key = r, r.Id
+		refs = append(refs, [2]string{KindGroup, r.GroupId})
+		if !c.unique(KindRating+":identity", r.UserId+"\x00"+r.TitleId+"\x00"+r.GroupId) {
+			return nil, fmt.Errorf("a second rating of %s by %s in group %s", r.TitleId, r.UserId, r.GroupId)
+		}
+	case KindComment:
+		var cm models.Comment
+		if err := decode(l.Data, &cm); err != nil {
+			return nil, err
+		}
+		record, key = cm, cm.Id
+		refs = append(refs, [2]string{KindGroup, cm.GroupId})
+		if !c.unique(KindComment+":identity", cm.UserId+"\x00"+cm.TitleId+"\x00"+cm.GroupId) {
+			return nil, fmt.Errorf("a second comment on %s by %s in group %s", cm.TitleId, cm.UserId, cm.GroupId)
+		}
+	case KindActivityEvent:
+		var e models.ActivityEvent
+		if err := decode(l.Data, &e); err != nil {
+			return nil, err
+		}
+		if e.Seq <= 0 {
+			return nil, fmt.Errorf("activity event %s has no seq", e.Id)
+		}
+		if !c.unique(KindActivityEvent+":seq", fmt.Sprint(e.Seq)) {
+			return nil, fmt.Errorf("a second activity event with seq %d", e.Seq)
+		}
+		record, key = e, e.Id
+		refs = append(refs, [2]string{KindGroup, e.GroupId})
+	case KindActivityEventRead:
+		var r models.ActivityEventRead
+		if err := decode(l.Data, &r); err != nil {
+			return nil, err
+		}
+		record, key = r, r.UserId+"\x00"+r.EventId
+		refs = append(refs, [2]string{KindUser, r.UserId}, [2]string{KindActivityEvent, r.EventId})
+	case KindActivityReadFloor:
+		var f models.ActivityReadFloor
+		if err := decode(l.Data, &f); err != nil {
+			return nil, err
+		}
+		record, key = f, f.UserId
+		refs = append(refs, [2]string{KindUser, f.UserId})
+	case KindActivityPreferences:
+		var p models.ActivityPreferences
+		if err := decode(l.Data, &p); err != nil {
+			return nil, err
+		}
+		record, key = p, p.UserId
+		refs = append(refs, [2]string{KindUser, p.UserId})
+		for _, mute := range p.Mutes {
+			if mute.GroupId != "" {
+				refs = append(refs, [2]string{KindGroup, mute.GroupId})
+			}
+		}
+	case KindActivityDigest:
+		var d models.ActivityDigest
+		if err := decode(l.Data, &d); err != nil {
+			return nil, err
+		}
+		record, key = d, d.UserId
+		refs = append(refs, [2]string{KindUser, d.UserId})
+	}
+
+	if key == "" {
+		return nil, fmt.Errorf("%s record without an id", l.Kind)
+	}
+	if !c.unique(l.Kind, key) {
+		return nil, fmt.Errorf("a second %s record with the id %q", l.Kind, key)
+	}
+	for _, ref := range refs {
+		if !c.seen[ref[0]][ref[1]] {
+			return nil, fmt.Errorf("%s record %q refers to %s %q, which is not in the archive", l.Kind, key, ref[0], ref[1])
+		}
+	}
+	c.counts[l.Kind]++
+	return record, nil
+}
+
+// unique records key under set, reporting whether it was new.
+func (c *checker) unique(set, key string) bool {
+	if c.seen[set] == nil {
+		c.seen[set] = map[string]bool{}
+	}
+	if c.seen[set][key] {
+		return false
+	}
+	c.seen[set][key] = true
+	return true
+}
+
+// decode reads a record strictly: a field the model does not have means the
+// archive and this version disagree on its shape, and reading past it would
+// drop data.
+func decode(data json.RawMessage, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("unreadable record: %v", err)
+	}
+	return nil
+}
+
+// restorer collects records into pages of one kind and writes each page with
+// the matching Restore method.
+type restorer struct {
+	ctx     context.Context
+	db      store.Archive
+	kind    string
+	pending []any
+}
+
+func (r *restorer) add(kind string, record any) error {
+	if kind != r.kind || len(r.pending) == pageSize {
+		if err := r.flush(); err != nil {
+			return err
+		}
+		r.kind = kind
+	}
+	r.pending = append(r.pending, record)
+	return nil
+}
+
+func (r *restorer) flush() error {
+	if len(r.pending) == 0 {
+		return nil
+	}
+	var err error
+	switch r.kind {
+	case KindUser:
+		err = r.db.RestoreUsers(r.ctx, pending[models.User](r.pending))
+	case KindTitle:
+		err = r.db.RestoreTitles(r.ctx, pending[models.Title](r.pending))
+	case KindGroup:
+		err = r.db.RestoreGroups(r.ctx, pending[models.Group](r.pending))
+	case KindRecommendationSharing:
+		err = r.db.RestoreRecommendationSharing(r.ctx, pending[models.RecommendationSharing](r.pending))
+	case KindStreamingPreferences:
+		err = r.db.RestoreStreamingPreferences(r.ctx, pending[models.StreamingPreferences](r.pending))
+	case KindRating:
+		err = r.db.RestoreRatings(r.ctx, pending[models.UserRating](r.pending))
+	case KindComment:
+		err = r.db.RestoreComments(r.ctx, pending[models.Comment](r.pending))
+	case KindActivityEvent:
+		err = r.db.RestoreActivityEvents(r.ctx, pending[models.ActivityEvent](r.pending))
+	case KindActivityEventRead:
+		err = r.db.RestoreActivityEventReads(r.ctx, pending[models.ActivityEventRead](r.pending))
+	case KindActivityReadFloor:
+		err = r.db.RestoreActivityReadFloors(r.ctx, pending[models.ActivityReadFloor](r.pending))
+	case KindActivityPreferences:
+		err = r.db.RestoreActivityPreferences(r.ctx, pending[models.ActivityPreferences](r.pending))
+	case KindActivityDigest:
+		err = r.db.RestoreActivityDigests(r.ctx, pending[models.ActivityDigest](r.pending))
+	}
+	if err != nil {
+		return fmt.Errorf("write %s records: %w", r.kind, err)
+	}
+	r.pending = r.pending[:0]
+	return nil
+}
+
+func pending[T any](records []any) []T {
+	out := make([]T, len(records))
+	for i, record := range records {
+		out[i] = record.(T)
+	}
+	return out
+}
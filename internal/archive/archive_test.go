@@ -0,0 +1,330 @@
+package archive
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+// memArchive is a store.Archive over plain slices.
+type memArchive struct {
+	users       []models.User
+	titles      []models.Title
+	groups      []models.Group
+	sharing     []models.RecommendationSharing
+	preferences []models.StreamingPreferences
+	ratings     []models.UserRating
+	comments    []models.Comment
+	events      []models.ActivityEvent
+	reads       []models.ActivityEventRead
+	floors      []models.ActivityReadFloor
+	mutes       []models.ActivityPreferences
+	digests     []models.ActivityDigest
+
+	// failRatings makes RestoreRatings fail.
+	failRatings bool
+}
+
+var _ store.Archive = (*memArchive)(nil)
+
+// page returns up to limit of records, sorted by key, after the key after.
+func page[T any](records []T, key func(T) string, after string, limit int) []T {
+	sorted := slices.Clone(records)
+	slices.SortFunc(sorted, func(a, b T) int { return cmp.Compare(key(a), key(b)) })
+	var out []T
+	for _, r := range sorted {
+		if key(r) > after && len(out) < limit {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (m *memArchive) ArchiveIsEmpty(context.Context) (bool, error) {
+	return len(m.users) == 0 && len(m.titles) == 0 && len(m.groups) == 0, nil
+}
+
+func (m *memArchive) ListUsers(_ context.Context, after string, limit int) ([]models.User, error) {
+	return page(m.users, func(u models.User) string { return u.Id }, after, limit), nil
+}
+
+func (m *memArchive) ListTitles(_ context.Context, after string, limit int) ([]models.Title, error) {
+	return page(m.titles, func(t models.Title) string { return t.ID }, after, limit), nil
+}
+
+func (m *memArchive) ListGroups(_ context.Context, after string, limit int) ([]models.Group, error) {
+	return page(m.groups, func(g models.Group) string { return g.Id }, after, limit), nil
+}
+
+func (m *memArchive) ListRecommendationSharing(_ context.Context, after string, limit int) ([]models.RecommendationSharing, error) {
+	return page(m.sharing, func(s models.RecommendationSharing) string { return s.GroupId }, after, limit), nil
+}
+
+func (m *memArchive) ListStreamingPreferences(_ context.Context, after string, limit int) ([]models.StreamingPreferences, error) {
+	return page(m.preferences, func(p models.StreamingPreferences) string { return p.UserID }, after, limit), nil
+}
+
+func (m *memArchive) ListRatings(_ context.Context, after string, limit int) ([]models.UserRating, error) {
+	return page(m.ratings, func(r models.UserRating) string { return r.Id }, after, limit), nil
+}
+
+func (m *memArchive) ListComments(_ context.Context, after string, limit int) ([]models.Comment, error) {
+	return page(m.comments, func(c models.Comment) string { return c.Id }, after, limit), nil
+}
+
+func (m *memArchive) ListActivityEvents(_ context.Context, after int64, limit int) ([]models.ActivityEvent, error) {
+	seqKey := func(e models.ActivityEvent) string { return fmt.Sprintf("%020d", e.Seq) }
+	return page(m.events, seqKey, fmt.Sprintf("%020d", after), limit), nil
+}
+
+func (m *memArchive) ListActivityEventReads(_ context.Context, afterUser, afterEvent string, limit int) ([]models.ActivityEventRead, error) {
+	key := func(r models.ActivityEventRead) string { return r.UserId + "\x00" + r.EventId }
+	after := ""
+	if afterUser != "" || afterEvent != "" {
+		after = afterUser + "\x00" + afterEvent
+	}
+	return page(m.reads, key, after, limit), nil
+}
+
+func (m *memArchive) ListActivityReadFloors(_ context.Context, after string, limit int) ([]models.ActivityReadFloor, error) {
+	return page(m.floors, func(f models.ActivityReadFloor) string { return f.UserId }, after, limit), nil
+}
+
+func (m *memArchive) ListActivityPreferences(_ context.Context, after string, limit int) ([]models.ActivityPreferences, error) {
+	return page(m.mutes, func(p models.ActivityPreferences) string { return p.UserId }, after, limit), nil
+}
+
+func (m *memArchive) ListActivityDigests(_ context.Context, after string, limit int) ([]models.ActivityDigest, error) {
+	return page(m.digests, func(d models.ActivityDigest) string { return d.UserId }, after, limit), nil
+}
+
+func (m *memArchive) RestoreUsers(_ context.Context, users []models.User) error {
+	m.users = append(m.users, users...)
+	return nil
+}
+
+func (m *memArchive) RestoreTitles(_ context.Context, titles []models.Title) error {
+	m.titles = append(m.titles, titles...)
+	return nil
+}
+
+func (m *memArchive) RestoreGroups(_ context.Context, groups []models.Group) error {
+	m.groups = append(m.groups, groups...)
+	return nil
+}
+
+func (m *memArchive) RestoreRecommendationSharing(_ context.Context, sharing []models.RecommendationSharing) error {
+	m.sharing = append(m.sharing, sharing...)
+	return nil
+}
+
+func (m *memArchive) RestoreStreamingPreferences(_ context.Context, prefs []models.StreamingPreferences) error {
+	m.preferences = append(m.preferences, prefs...)
+	return nil
+}
+
+func (m *memArchive) RestoreRatings(_ context.Context, ratings []models.UserRating) error {
+	if m.failRatings {
+		return errors.New("disk full")
+	}
+	m.ratings = append(m.ratings, ratings...)
+	return nil
+}
+
+func (m *memArchive) RestoreComments(_ context.Context, comments []models.Comment) error {
+	m.comments = append(m.comments, comments...)
+	return nil
+}
+
+func (m *memArchive) RestoreActivityEvents(_ context.Context, events []models.ActivityEvent) error {
+	m.events = append(m.events, events...)
+	return nil
+}
+
+func (m *memArchive) RestoreActivityEventReads(_ context.Context, reads []models.ActivityEventRead) error {
+	m.reads = append(m.reads, reads...)
+	return nil
+}
+
+func (m *memArchive) RestoreActivityReadFloors(_ context.Context, floors []models.ActivityReadFloor) error {
+	m.floors = append(m.floors, floors...)
+	return nil
+}
+
+func (m *memArchive) RestoreActivityPreferences(_ context.Context, prefs []models.ActivityPreferences) error {
+	m.mutes = append(m.mutes, prefs...)
+	return nil
+}
+
+func (m *memArchive) RestoreActivityDigests(_ context.Context, digests []models.ActivityDigest) error {
+	m.digests = append(m.digests, digests...)
+	return nil
+}
+
+// seeded returns an instance with a little of every kind, and more users than
+// fit in a page.
+func seeded() *memArchive {
+	at := time.Date(2024, 5, 4, 10, 0, 0, 0, time.UTC)
+	comment := "kept"
+	titleId := "tt0068646"
+	m := &memArchive{}
+	for i := range pageSize*2 + 3 {
+		m.users = append(m.users, models.User{
+			Id:           fmt.Sprintf("u%04d", i),
+			Username:     fmt.Sprintf("user%d", i),
+			PasswordHash: "hash",
+			Role:         models.RoleUser,
+			IsActive:     true,
+			CreatedAt:    at,
+			UpdatedAt:    at,
+		})
+	}
+	m.titles = []models.Title{{ID: titleId, Type: "movie", PrimaryTitle: "The Godfather", StartYear: 1972, AddedAt: &at}}
+	m.groups = []models.Group{
+		{
+			Id:      "g1",
+			Name:    "Movie Night",
+			OwnerId: "u0000",
+			Users:   []string{"u0000", "u0001"},
+			Titles: models.GroupTitles{titleId: {
+				TitleId:   titleId,
+				Watched:   true,
+				WatchedAt: &at,
+				AddedAt:   at,
+				UpdatedAt: at,
+			}},
+			CreatedAt: at,
+			UpdatedAt: at,
+		},
+		{Id: "g2", Name: "Gone", OwnerId: "u0002", Users: []string{"u0002"}, Deleted: true, DeletedAt: &at, CreatedAt: at, UpdatedAt: at},
+	}
+	m.sharing = []models.RecommendationSharing{{GroupId: "g1", EnabledBy: "u0000", EnabledAt: at}}
+	m.preferences = []models.StreamingPreferences{{UserID: "u0001", Region: "US", ServiceIDs: []int{8}, UpdatedAt: at}}
+	m.ratings = []models.UserRating{{Id: "r1", TitleId: titleId, UserId: "u0000", GroupId: "g1", Note: 9.5, CreatedAt: at, UpdatedAt: at}}
+	m.comments = []models.Comment{{Id: "c1", TitleId: titleId, UserId: "u0001", GroupId: "g1", Comment: &comment, CreatedAt: at, UpdatedAt: at}}
+	m.events = []models.ActivityEvent{
+		{Id: "e1", Seq: 1, GroupId: "g1", ActorId: "u0000", ActorName: "user0", Kind: "title_added", TitleId: &titleId, CreatedAt: at},
+		{Id: "e2", Seq: 7, GroupId: "g1", ActorId: "u0000", ActorName: "user0", Kind: "rating_added", Payload: map[string]any{"note": 9.5}, CreatedAt: at},
+	}
+	m.reads = []models.ActivityEventRead{{UserId: "u0001", EventId: "e2", ReadAt: at}}
+	m.floors = []models.ActivityReadFloor{{UserId: "u0001", FloorSeq: 1, ReadAt: at}}
+	m.mutes = []models.ActivityPreferences{
+		{UserId: "u0001", Mutes: []models.ActivityMute{{Kind: "rating_added"}, {GroupId: "g1", TitleId: titleId}}},
+		{UserId: "u0002", QuietHours: &models.QuietHours{StartMinute: 22 * 60, EndMinute: 7 * 60, TimeZone: "Europe/Lisbon"}},
+	}
+	m.digests = []models.ActivityDigest{
+		{UserId: "u0001", Frequency: models.DigestWeekly, DigestedSeq: 7, NextDueAt: at.Add(7 * 24 * time.Hour), LastSentAt: &at, CreatedAt: at},
+	}
+	return m
+}
+
+func export(t *testing.T, m *memArchive) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	_, err := Export(context.Background(), m, &buf)
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func opener(data []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+}
+
+// gzipLines builds an archive by hand, one JSON line per argument.
+func gzipLines(t *testing.T, lines ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := io.WriteString(zw, strings.Join(lines, "\n")+"\n")
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+const validHeader = `{"format":"movies-backend-archive","version":1,"createdAt":"2024-05-04T10:00:00Z"}`
+
+func TestExportImport(t *testing.T) {
+	source := seeded()
+	data := export(t, source)
+
+	target := &memArchive{}
+	counts, err := Import(context.Background(), target, opener(data))
+	require.NoError(t, err)
+	require.Equal(t, len(source.users), counts[KindUser])
+	require.Equal(t, 2, counts[KindActivityEvent])
+
+	require.Equal(t, source.users, target.users)
+	require.Equal(t, source.titles, target.titles)
+	require.Equal(t, source.groups, target.groups)
+	require.Equal(t, source.sharing, target.sharing)
+	require.Equal(t, source.preferences, target.preferences)
+	require.Equal(t, source.ratings, target.ratings)
+	require.Equal(t, source.comments, target.comments)
+	require.Equal(t, source.events, target.events)
+	require.Equal(t, source.reads, target.reads)
+	require.Equal(t, source.floors, target.floors)
+	require.Equal(t, source.mutes, target.mutes)
+	require.Equal(t, source.digests, target.digests)
+}
+
+func TestImportRejects(t *testing.T) {
+	user := `{"kind":"user","data":{"Id":"u1","Username":"ana"}}`
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"not gzip", []byte("Const,Your Rating\n"), ErrNotAnArchive},
+		{"another file", gzipLines(t, `{"hello":"world"}`), ErrNotAnArchive},
+		{"a newer version", gzipLines(t, `{"format":"movies-backend-archive","version":2}`), ErrUnsupportedVersion},
+		{"cut short", gzipLines(t, validHeader, user), ErrCorrupt},
+		{"counts that disagree", gzipLines(t, validHeader, user, `{"kind":"end","data":{"counts":{"user":2}}}`), ErrCorrupt},
+		{"records after the end", gzipLines(t, validHeader, `{"kind":"end","data":{"counts":{}}}`, user), ErrCorrupt},
+		{"an unknown field", gzipLines(t, validHeader, `{"kind":"user","data":{"Id":"u1","Nickname":"a"}}`, `{"kind":"end","data":{"counts":{"user":1}}}`), ErrCorrupt},
+		{"a duplicate id", gzipLines(t, validHeader, user, user, `{"kind":"end","data":{"counts":{"user":2}}}`), ErrCorrupt},
+		{"kinds out of order", gzipLines(t, validHeader, `{"kind":"title","data":{"ID":"tt1"}}`, user, `{"kind":"end","data":{"counts":{"user":1,"title":1}}}`), ErrCorrupt},
+		{"a rating of a group not in the archive", gzipLines(t, validHeader, user,
+			`{"kind":"rating","data":{"Id":"r1","TitleId":"tt1","UserId":"u1","GroupId":"g9","Note":5}}`,
+			`{"kind":"end","data":{"counts":{"user":1,"rating":1}}}`), ErrCorrupt},
+		{"a read of an event not in the archive", gzipLines(t, validHeader, user,
+			`{"kind":"activity_event_read","data":{"UserId":"u1","EventId":"e9"}}`,
+			`{"kind":"end","data":{"counts":{"user":1,"activity_event_read":1}}}`), ErrCorrupt},
+		{"a mute of a group not in the archive", gzipLines(t, validHeader, user,
+			`{"kind":"activity_preferences","data":{"UserId":"u1","Mutes":[{"GroupId":"g9"}]}}`,
+			`{"kind":"end","data":{"counts":{"user":1,"activity_preferences":1}}}`), ErrCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := &memArchive{}
+			_, err := Import(context.Background(), target, opener(tt.data))
+			require.ErrorIs(t, err, tt.want)
+			require.Empty(t, target.users, "nothing may be written from a rejected archive")
+		})
+	}
+}
+
+func TestImportIntoNonEmptyStore(t *testing.T) {
+	target := &memArchive{users: []models.User{{Id: "existing"}}}
+	_, err := Import(context.Background(), target, opener(export(t, seeded())))
+	require.ErrorIs(t, err, ErrNotEmpty)
+	require.Len(t, target.users, 1)
+}
+
+func TestImportReportsWriteFailures(t *testing.T) {
+	target := &memArchive{failRatings: true}
+	_, err := Import(context.Background(), target, opener(export(t, seeded())))
+	require.ErrorContains(t, err, "write rating records: disk full")
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Pagination defaults (used when the corresponding env var is unset/invalid).
@@ -67,6 +68,101 @@ func envInt(key string, def int) int {
 // changes nothing in production until it is deliberately enabled.
 func ActivityFeedEnabled() bool { return envBool("ACTIVITY_FEED_ENABLED", false) }
 
+// ActivityOutboxEnabled reports whether activity events are delivered through
+// the transactional outbox instead of flushed after the response: each
+// mutating request then runs in one store transaction that also writes its
+// events, and a relay delivers them to the feed. Off by default; only read
+// when ActivityFeedEnabled is on. Override with ACTIVITY_OUTBOX_ENABLED.
+func ActivityOutboxEnabled() bool { return envBool("ACTIVITY_OUTBOX_ENABLED", false) }
+
+// defaultActivityOutboxPollInterval is how often the relay looks at the outbox
+// when nothing has woken it.
+const defaultActivityOutboxPollInterval = 5 * time.Second
+
+// ActivityOutboxPollInterval is how often the outbox relay checks for events
+// no request on this process told it about: those written by another process,
+// or left behind by a failed delivery or a crash. A request on this process
+// wakes its relay straight away. Override with ACTIVITY_OUTBOX_POLL_INTERVAL
+// (a Go duration, e.g. "10s").
+func ActivityOutboxPollInterval() time.Duration {
+	return envDuration("ACTIVITY_OUTBOX_POLL_INTERVAL", defaultActivityOutboxPollInterval)
+}
+
+// defaultActivityStreamReplayLimit is how many missed events a resuming
+// stream is sent before it is told to resync instead.
+const defaultActivityStreamReplayLimit = 200
+
+// ActivityStreamReplayLimit caps the replay a stream reconnecting with
+// Last-Event-ID gets. Past it, a fresh snapshot is one request where the
+// replay would be a frame per event, so the stream asks the client to resync.
+// Override with ACTIVITY_STREAM_REPLAY_LIMIT.
+func ActivityStreamReplayLimit() int {
+	return envInt("ACTIVITY_STREAM_REPLAY_LIMIT", defaultActivityStreamReplayLimit)
+}
+
+// defaultActivityCoalesceWindow is how close together two edits must be for
+// the feed to show them as one.
+const defaultActivityCoalesceWindow = 2 * time.Minute
+
+// ActivityCoalesceWindow is how soon after an edit the same user's next edit
+// of the same thing is merged into it rather than recorded beside it: nudging
+// a rating from 7.0 to 7.5 to 8.0 is one "7.0 to 8.0" in the feed. The window
+// runs from the latest edit, so a run of edits each within it of the last is
+// one event however long it takes. Override with ACTIVITY_COALESCE_WINDOW (a
+// Go duration); "0s" turns merging off.
+func ActivityCoalesceWindow() time.Duration {
+	if v := strings.TrimSpace(os.Getenv("ACTIVITY_COALESCE_WINDOW")); v != "" {
+		// Not envDuration: zero is a setting here, not a mistake.
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return defaultActivityCoalesceWindow
+}
+
+// WebhooksEnabled reports whether group owners can register webhooks and
+// activity events are sent to them. Off by default; only read when
+// ActivityFeedEnabled is on, since the events come from the feed. Override
+// with WEBHOOKS_ENABLED.
+func WebhooksEnabled() bool { return envBool("WEBHOOKS_ENABLED", false) }
+
+// Webhook delivery defaults (used when the corresponding env var is
+// unset/invalid).
+const (
+	defaultWebhookMaxAttempts  = 10
+	defaultWebhookDisableAfter = 20
+	defaultWebhookTimeout      = 10 * time.Second
+)
+
+// WebhookMaxAttempts is how many times one delivery is tried before it is
+// marked failed. With the back-off doubling from 30s, the default spreads the
+// attempts over about four hours. Override with WEBHOOK_MAX_ATTEMPTS.
+func WebhookMaxAttempts() int { return envInt("WEBHOOK_MAX_ATTEMPTS", defaultWebhookMaxAttempts) }
+
+// WebhookDisableAfter is how many failed attempts in a row, across all of an
+// endpoint's deliveries, disable it until its owner enables it again.
+// Override with WEBHOOK_DISABLE_AFTER.
+func WebhookDisableAfter() int { return envInt("WEBHOOK_DISABLE_AFTER", defaultWebhookDisableAfter) }
+
+// WebhookTimeout bounds one delivery attempt, response included. Override with
+// WEBHOOK_TIMEOUT (a Go duration, e.g. "5s").
+func WebhookTimeout() time.Duration { return envDuration("WEBHOOK_TIMEOUT", defaultWebhookTimeout) }
+
+// WebhookAllowPrivateHosts lets webhooks be registered for, and delivered to,
+// loopback, private and link-local addresses, which are refused by default so
+// an endpoint cannot be used to reach the server's own network. Meant for
+// tests and local development against a receiver on the same machine.
+// Override with WEBHOOK_ALLOW_PRIVATE_HOSTS.
+func WebhookAllowPrivateHosts() bool { return envBool("WEBHOOK_ALLOW_PRIVATE_HOSTS", false) }
+
+// DigestsEnabled reports whether users can subscribe to a daily or weekly
+// email digest of their unread activity, and the job that sends them runs.
+// Off by default; only read when ActivityFeedEnabled is on, since a digest is
+// the feed's unread events. The mailer is configured separately (MAILER);
+// without one, subscriptions are kept but nothing is sent. Override with
+// DIGESTS_ENABLED.
+func DigestsEnabled() bool { return envBool("DIGESTS_ENABLED", false) }
+
 func envBool(key string, def bool) bool {
 	v, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(key)))
 	if err != nil {
@@ -74,3 +170,144 @@ func envBool(key string, def bool) bool {
 	}
 	return v
 }
+
+// Title provider cache defaults (used when the corresponding env var is
+// unset/invalid).
+const (
+	defaultTitleCacheSize      = 1000
+	defaultTitleCacheTTL       = 24 * time.Hour
+	defaultTitleCacheSearchTTL = 10 * time.Minute
+)
+
+// TitleCacheEnabled reports whether title provider calls go through the cache
+// (internal/titleprovider/cache). Off by default, like the activity feed: a
+// cache changes how fresh title data is, so it is switched on deliberately.
+// Override with TITLE_CACHE_ENABLED.
+func TitleCacheEnabled() bool { return envBool("TITLE_CACHE_ENABLED", false) }
+
+// TitleCachePersistent reports whether the cache also keeps fetched titles in
+// Postgres, so they survive a restart. Only read when TitleCacheEnabled is on.
+// Override with TITLE_CACHE_PERSISTENT.
+func TitleCachePersistent() bool { return envBool("TITLE_CACHE_PERSISTENT", false) }
+
+// TitleCacheSize bounds how many entries (titles and searches together) the
+// in-memory cache holds. Override with TITLE_CACHE_SIZE.
+func TitleCacheSize() int { return envInt("TITLE_CACHE_SIZE", defaultTitleCacheSize) }
+
+// TitleCacheTTL is how long a fetched title is served from the cache.
+// Override with TITLE_CACHE_TTL (a Go duration, e.g. "12h").
+func TitleCacheTTL() time.Duration { return envDuration("TITLE_CACHE_TTL", defaultTitleCacheTTL) }
+
+// TitleCacheSearchTTL is how long a search result is served from the cache.
+// Shorter than TitleCacheTTL: it only has to outlast a user typing and
+// retyping a query. Override with TITLE_CACHE_SEARCH_TTL.
+func TitleCacheSearchTTL() time.Duration {
+	return envDuration("TITLE_CACHE_SEARCH_TTL", defaultTitleCacheSearchTTL)
+}
+
+// envDuration returns a positive duration from the named env var, or def when
+// the var is unset, blank, unparseable, or non-positive.
+func envDuration(key string, def time.Duration) time.Duration {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return def
+}
+
+// Title provider chain defaults (TITLE_PROVIDER=chain:...; used when the
+// corresponding env var is unset/invalid).
+const (
+	defaultTitleProviderTimeout         = 10 * time.Second
+	defaultTitleProviderBreakerFailures = 5
+	defaultTitleProviderBreakerCooldown = 30 * time.Second
+)
+
+// TitleProviderTimeout bounds each call a provider chain makes to one of its
+// providers, so a hung provider falls through to the next instead of holding
+// the request. Override with TITLE_PROVIDER_TIMEOUT.
+func TitleProviderTimeout() time.Duration {
+	return envDuration("TITLE_PROVIDER_TIMEOUT", defaultTitleProviderTimeout)
+}
+
+// TitleProviderBreakerFailures is how many consecutive failures take a chained
+// provider out of rotation. Override with TITLE_PROVIDER_BREAKER_FAILURES.
+func TitleProviderBreakerFailures() int {
+	return envInt("TITLE_PROVIDER_BREAKER_FAILURES", defaultTitleProviderBreakerFailures)
+}
+
+// TitleProviderBreakerCooldown is how long a chained provider stays out of
+// rotation before a single probe call is let through to see whether it has
+// recovered. Override with TITLE_PROVIDER_BREAKER_COOLDOWN.
+func TitleProviderBreakerCooldown() time.Duration {
+	return envDuration("TITLE_PROVIDER_BREAKER_COOLDOWN", defaultTitleProviderBreakerCooldown)
+}
+
+// defaultTitleFixtureDir is where the fixture provider reads, and recording
+// writes, when TITLE_FIXTURE_DIR is unset.
+const defaultTitleFixtureDir = "fixtures/titleprovider"
+
+// TitleFixtureDir is the directory the fixture provider (TITLE_PROVIDER=fixture)
+// serves titles and searches from, and the one TitleFixtureRecord writes to.
+// Override with TITLE_FIXTURE_DIR.
+func TitleFixtureDir() string {
+	if v := strings.TrimSpace(os.Getenv("TITLE_FIXTURE_DIR")); v != "" {
+		return v
+	}
+	return defaultTitleFixtureDir
+}
+
+// TitleFixtureRecord reports whether every answer of the selected provider is
+// also written into TitleFixtureDir, building fixtures the fixture provider can
+// serve offline later. Off by default. Override with TITLE_FIXTURE_RECORD.
+func TitleFixtureRecord() bool { return envBool("TITLE_FIXTURE_RECORD", false) }
+
+// OMDb daily quota defaults (used when the corresponding env var is
+// unset/invalid). 1,000 is the free tier's allowance.
+const (
+	defaultOmdbDailyBudget        = 1000
+	defaultOmdbInteractiveReserve = 100
+)
+
+// OmdbDailyBudget is how many OMDb requests every process together may make
+// per UTC day. Override with OMDB_DAILY_BUDGET, e.g. on a paid tier.
+func OmdbDailyBudget() int { return envInt("OMDB_DAILY_BUDGET", defaultOmdbDailyBudget) }
+
+// OmdbInteractiveReserve is the part of OmdbDailyBudget that background work
+// (the titles routine) may not spend, so that adding a title interactively
+// still works after a library refresh. Override with OMDB_INTERACTIVE_RESERVE.
+func OmdbInteractiveReserve() int {
+	return envInt("OMDB_INTERACTIVE_RESERVE", defaultOmdbInteractiveReserve)
+}
+
+// defaultStreamingRegion is the region availability is shown for when a user
+// has not picked one.
+const defaultStreamingRegion = "US"
+
+// StreamingDefaultRegion is the region (ISO 3166-1 alpha-2) whose streaming
+// availability is shown to users who have not set their own. Override with
+// STREAMING_DEFAULT_REGION.
+func StreamingDefaultRegion() string {
+	if v := strings.ToUpper(strings.TrimSpace(os.Getenv("STREAMING_DEFAULT_REGION"))); v != "" {
+		return v
+	}
+	return defaultStreamingRegion
+}
+
+// Bulk add defaults (POST /groups/{id}/titles/bulk; used when the
+// corresponding env var is unset/invalid).
+const (
+	defaultBulkAddMaxTitles   = 200
+	defaultBulkAddConcurrency = 4
+)
+
+// BulkAddMaxTitles is how many title references one bulk add may carry.
+// Override with BULK_ADD_MAX_TITLES.
+func BulkAddMaxTitles() int { return envInt("BULK_ADD_MAX_TITLES", defaultBulkAddMaxTitles) }
+
+// BulkAddConcurrency is how many provider calls one bulk add makes at once,
+// resolving references and fetching titles the catalogue lacks. Kept low so a
+// bulk add stays inside the provider's rate limit rather than racing to it.
+// Override with BULK_ADD_CONCURRENCY.
+func BulkAddConcurrency() int { return envInt("BULK_ADD_CONCURRENCY", defaultBulkAddConcurrency) }
@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestPaginationDefaults(t *testing.T) {
 	t.Run("defaults when unset", func(t *testing.T) {
@@ -30,3 +33,143 @@ func TestPaginationDefaults(t *testing.T) {
 		}
 	})
 }
+
+func TestTitleCacheSettings(t *testing.T) {
+	t.Run("defaults when unset", func(t *testing.T) {
+		t.Setenv("TITLE_CACHE_ENABLED", "")
+		t.Setenv("TITLE_CACHE_TTL", "")
+		t.Setenv("TITLE_CACHE_SEARCH_TTL", "")
+		if TitleCacheEnabled() || TitleCacheTTL() != 24*time.Hour || TitleCacheSearchTTL() != 10*time.Minute {
+			t.Fatalf("defaults wrong: %v %v %v", TitleCacheEnabled(), TitleCacheTTL(), TitleCacheSearchTTL())
+		}
+	})
+
+	t.Run("durations parse, and invalid/non-positive falls back", func(t *testing.T) {
+		t.Setenv("TITLE_CACHE_TTL", "90m")
+		t.Setenv("TITLE_CACHE_SEARCH_TTL", "-1s")
+		if TitleCacheTTL() != 90*time.Minute || TitleCacheSearchTTL() != 10*time.Minute {
+			t.Fatalf("got %v %v", TitleCacheTTL(), TitleCacheSearchTTL())
+		}
+	})
+}
+
+func TestOmdbQuotaSettings(t *testing.T) {
+	t.Setenv("OMDB_DAILY_BUDGET", "")
+	t.Setenv("OMDB_INTERACTIVE_RESERVE", "")
+	if OmdbDailyBudget() != 1000 || OmdbInteractiveReserve() != 100 {
+		t.Fatalf("defaults wrong: %d %d", OmdbDailyBudget(), OmdbInteractiveReserve())
+	}
+
+	t.Setenv("OMDB_DAILY_BUDGET", "100000")
+	t.Setenv("OMDB_INTERACTIVE_RESERVE", "-5")
+	if OmdbDailyBudget() != 100000 || OmdbInteractiveReserve() != 100 {
+		t.Fatalf("got %d %d", OmdbDailyBudget(), OmdbInteractiveReserve())
+	}
+}
+
+func TestStreamingDefaultRegion(t *testing.T) {
+	t.Setenv("STREAMING_DEFAULT_REGION", "")
+	if got := StreamingDefaultRegion(); got != "US" {
+		t.Fatalf("default = %q, want US", got)
+	}
+
+	t.Setenv("STREAMING_DEFAULT_REGION", " br ")
+	if got := StreamingDefaultRegion(); got != "BR" {
+		t.Fatalf("got %q, want BR", got)
+	}
+}
+
+func TestBulkAddSettings(t *testing.T) {
+	t.Setenv("BULK_ADD_MAX_TITLES", "")
+	t.Setenv("BULK_ADD_CONCURRENCY", "")
+	if BulkAddMaxTitles() != 200 || BulkAddConcurrency() != 4 {
+		t.Fatalf("defaults wrong: %d %d", BulkAddMaxTitles(), BulkAddConcurrency())
+	}
+
+	t.Setenv("BULK_ADD_MAX_TITLES", "500")
+	t.Setenv("BULK_ADD_CONCURRENCY", "0")
+	if BulkAddMaxTitles() != 500 || BulkAddConcurrency() != 4 {
+		t.Fatalf("got %d %d", BulkAddMaxTitles(), BulkAddConcurrency())
+	}
+}
+
+func TestActivityOutboxSettings(t *testing.T) {
+	t.Setenv("ACTIVITY_OUTBOX_ENABLED", "")
+	t.Setenv("ACTIVITY_OUTBOX_POLL_INTERVAL", "")
+	if ActivityOutboxEnabled() || ActivityOutboxPollInterval() != 5*time.Second {
+		t.Fatalf("defaults wrong: %v %v", ActivityOutboxEnabled(), ActivityOutboxPollInterval())
+	}
+
+	t.Setenv("ACTIVITY_OUTBOX_ENABLED", "true")
+	t.Setenv("ACTIVITY_OUTBOX_POLL_INTERVAL", "0s")
+	if !ActivityOutboxEnabled() || ActivityOutboxPollInterval() != 5*time.Second {
+		t.Fatalf("got %v %v", ActivityOutboxEnabled(), ActivityOutboxPollInterval())
+	}
+}
+
+func TestWebhookSettings(t *testing.T) {
+	for _, key := range []string{"WEBHOOKS_ENABLED", "WEBHOOK_MAX_ATTEMPTS", "WEBHOOK_DISABLE_AFTER", "WEBHOOK_TIMEOUT"} {
+		t.Setenv(key, "")
+	}
+	if WebhooksEnabled() || WebhookMaxAttempts() != 10 || WebhookDisableAfter() != 20 || WebhookTimeout() != 10*time.Second {
+		t.Fatalf("defaults wrong: %v %v %v %v", WebhooksEnabled(), WebhookMaxAttempts(), WebhookDisableAfter(), WebhookTimeout())
+	}
+
+	t.Setenv("WEBHOOKS_ENABLED", "true")
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "3")
+	t.Setenv("WEBHOOK_DISABLE_AFTER", "-1")
+	t.Setenv("WEBHOOK_TIMEOUT", "2s")
+	if !WebhooksEnabled() || WebhookMaxAttempts() != 3 || WebhookDisableAfter() != 20 || WebhookTimeout() != 2*time.Second {
+		t.Fatalf("got %v %v %v %v", WebhooksEnabled(), WebhookMaxAttempts(), WebhookDisableAfter(), WebhookTimeout())
+	}
+}
+
+func TestWebhookAllowPrivateHosts(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "")
+	if WebhookAllowPrivateHosts() {
+		t.Fatalf("default = true, want false")
+	}
+
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "true")
+	if !WebhookAllowPrivateHosts() {
+		t.Fatalf("got false with WEBHOOK_ALLOW_PRIVATE_HOSTS=true")
+	}
+}
+
+func TestDigestsEnabled(t *testing.T) {
+	t.Setenv("DIGESTS_ENABLED", "")
+	if DigestsEnabled() {
+		t.Fatalf("default = true, want false")
+	}
+
+	t.Setenv("DIGESTS_ENABLED", "true")
+	if !DigestsEnabled() {
+		t.Fatalf("got false with DIGESTS_ENABLED=true")
+	}
+}
+
+func TestActivityStreamReplayLimit(t *testing.T) {
+	t.Setenv("ACTIVITY_STREAM_REPLAY_LIMIT", "")
+	if got := ActivityStreamReplayLimit(); got != 200 {
+		t.Fatalf("default = %d, want 200", got)
+	}
+	t.Setenv("ACTIVITY_STREAM_REPLAY_LIMIT", "50")
+	if got := ActivityStreamReplayLimit(); got != 50 {
+		t.Fatalf("got %d, want 50", got)
+	}
+}
+
+func TestActivityCoalesceWindow(t *testing.T) {
+	t.Setenv("ACTIVITY_COALESCE_WINDOW", "")
+	if got := ActivityCoalesceWindow(); got != 2*time.Minute {
+		t.Fatalf("default = %v, want 2m", got)
+	}
+	t.Setenv("ACTIVITY_COALESCE_WINDOW", "0s")
+	if got := ActivityCoalesceWindow(); got != 0 {
+		t.Fatalf("got %v, want 0: zero turns merging off", got)
+	}
+	t.Setenv("ACTIVITY_COALESCE_WINDOW", "-1m")
+	if got := ActivityCoalesceWindow(); got != 2*time.Minute {
+		t.Fatalf("got %v, want the default for a negative window", got)
+	}
+}
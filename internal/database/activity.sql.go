@@ -30,6 +30,17 @@ func (q *Queries) CountActivityUnread(ctx context.Context, userID string) (int64
 	return count, err
 }
 
+const deleteActivityEvent = `-- name: DeleteActivityEvent :exec
+DELETE FROM activity_events WHERE id = $1
+`
+
+// The one exception to the log being append-only: an event merged into a
+// later one. Its read rows go with it.
+func (q *Queries) DeleteActivityEvent(ctx context.Context, id string) error {
+	_, err := q.db.Exec(ctx, deleteActivityEvent, id)
+	return err
+}
+
 const getActivityEventById = `-- name: GetActivityEventById :one
 SELECT e.id, e.seq, e.group_id, e.actor_id, e.actor_name, e.kind, e.title_id,
        e.title_name, e.payload, e.created_at, g.name AS group_name,
@@ -82,6 +93,77 @@ func (q *Queries) GetActivityEventById(ctx context.Context, id string) (GetActiv
 	return i, err
 }
 
+const getActivityEventsSinceRows = `-- name: GetActivityEventsSinceRows :many
+SELECT v.id, v.seq, v.group_id, v.actor_id, v.actor_name, v.kind, v.title_id,
+       v.title_name, v.payload, v.created_at, v.group_name,
+       (v.seq <= COALESCE(f.floor_seq, 0) OR r.event_id IS NOT NULL)::boolean AS read_by_me
+FROM activity_visible_events v
+LEFT JOIN activity_read_floors f ON f.user_id = v.reader_id
+LEFT JOIN activity_event_reads r ON r.event_id = v.id AND r.user_id = v.reader_id
+WHERE v.reader_id = $1
+  AND v.seq > $2::bigint
+ORDER BY v.seq
+LIMIT $3::bigint
+`
+
+type GetActivityEventsSinceRowsParams struct {
+	UserID   string
+	AfterSeq int64
+	RowLimit int64
+}
+
+type GetActivityEventsSinceRowsRow struct {
+	ID        string
+	Seq       int64
+	GroupID   string
+	ActorID   string
+	ActorName string
+	Kind      string
+	TitleID   pgtype.Text
+	TitleName pgtype.Text
+	Payload   []byte
+	CreatedAt pgtype.Timestamptz
+	GroupName string
+	ReadByMe  bool
+}
+
+// What a resuming stream missed: the events visible to the reader after the
+// seq it last saw, oldest first, so they can be written in the order they
+// happened. The same view and the same read_by_me as GetActivityFeedRows, so a
+// replayed frame is the feed's element for that event.
+func (q *Queries) GetActivityEventsSinceRows(ctx context.Context, arg GetActivityEventsSinceRowsParams) ([]GetActivityEventsSinceRowsRow, error) {
+	rows, err := q.db.Query(ctx, getActivityEventsSinceRows, arg.UserID, arg.AfterSeq, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetActivityEventsSinceRowsRow
+	for rows.Next() {
+		var i GetActivityEventsSinceRowsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Seq,
+			&i.GroupID,
+			&i.ActorID,
+			&i.ActorName,
+			&i.Kind,
+			&i.TitleID,
+			&i.TitleName,
+			&i.Payload,
+			&i.CreatedAt,
+			&i.GroupName,
+			&i.ReadByMe,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getActivityFeedRows = `-- name: GetActivityFeedRows :many
 SELECT v.id, v.seq, v.group_id, v.actor_id, v.actor_name, v.kind, v.title_id,
        v.title_name, v.payload, v.created_at, v.group_name,
@@ -91,14 +173,26 @@ LEFT JOIN activity_read_floors f ON f.user_id = v.reader_id
 LEFT JOIN activity_event_reads r ON r.event_id = v.id AND r.user_id = v.reader_id
 WHERE v.reader_id = $1
   AND ($2::bigint IS NULL OR v.seq < $2::bigint)
+  AND ($3::text[] IS NULL OR v.group_id = ANY($3::text[]))
+  AND ($4::text IS NULL OR v.title_id = $4::text)
+  AND ($5::text[] IS NULL OR v.kind = ANY($5::text[]))
+  AND ($6::text IS NULL OR v.actor_id = $6::text)
+  AND ($7::timestamptz IS NULL OR v.created_at >= $7::timestamptz)
+  AND ($8::timestamptz IS NULL OR v.created_at < $8::timestamptz)
 ORDER BY v.seq DESC
-LIMIT $3::bigint
+LIMIT $9::bigint
 `
 
 type GetActivityFeedRowsParams struct {
-	UserID   string
-	Before   pgtype.Int8
-	RowLimit int64
+	UserID        string
+	Before        pgtype.Int8
+	GroupIds      []string
+	TitleID       pgtype.Text
+	Kinds         []string
+	ActorID       pgtype.Text
+	CreatedAfter  pgtype.Timestamptz
+	CreatedBefore pgtype.Timestamptz
+	RowLimit      int64
 }
 
 type GetActivityFeedRowsRow struct {
@@ -124,8 +218,22 @@ type GetActivityFeedRowsRow struct {
 // read_by_me is the reader's own state, joined per row: the same event is read
 // for one member and unread for another, which is why it cannot live on
 // activity_events.
+//
+// Every filter is optional: NULL turns it off, so one statement serves the
+// bare feed and any combination of filters. A group the reader is not in
+// matches nothing rather than failing, since the view never holds it.
 func (q *Queries) GetActivityFeedRows(ctx context.Context, arg GetActivityFeedRowsParams) ([]GetActivityFeedRowsRow, error) {
-	rows, err := q.db.Query(ctx, getActivityFeedRows, arg.UserID, arg.Before, arg.RowLimit)
+	rows, err := q.db.Query(ctx, getActivityFeedRows,
+		arg.UserID,
+		arg.Before,
+		arg.GroupIds,
+		arg.TitleID,
+		arg.Kinds,
+		arg.ActorID,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.RowLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -157,12 +265,189 @@ func (q *Queries) GetActivityFeedRows(ctx context.Context, arg GetActivityFeedRo
 	return items, nil
 }
 
+const getActivityHistoryRows = `-- name: GetActivityHistoryRows :many
+SELECT e.id, e.seq, e.group_id, e.actor_id, e.actor_name, e.kind, e.title_id,
+       e.title_name, e.payload, e.created_at, g.name AS group_name,
+       (e.actor_id = m.user_id OR e.seq <= COALESCE(f.floor_seq, 0) OR r.event_id IS NOT NULL)::boolean AS read_by_me
+FROM activity_events e
+JOIN group_members m ON m.group_id = e.group_id
+JOIN groups g ON g.id = e.group_id AND NOT g.deleted
+LEFT JOIN activity_read_floors f ON f.user_id = m.user_id
+LEFT JOIN activity_event_reads r ON r.event_id = e.id AND r.user_id = m.user_id
+WHERE m.user_id = $1
+  AND ($2::bigint IS NULL OR e.seq < $2::bigint)
+  AND ($3::text[] IS NULL OR e.group_id = ANY($3::text[]))
+  AND ($4::text IS NULL OR e.title_id = $4::text)
+  AND ($5::text[] IS NULL OR e.kind = ANY($5::text[]))
+  AND ($6::text IS NULL OR e.actor_id = $6::text)
+  AND ($7::timestamptz IS NULL OR e.created_at >= $7::timestamptz)
+  AND ($8::timestamptz IS NULL OR e.created_at < $8::timestamptz)
+ORDER BY e.seq DESC
+LIMIT $9::bigint
+`
+
+type GetActivityHistoryRowsParams struct {
+	UserID        string
+	Before        pgtype.Int8
+	GroupIds      []string
+	TitleID       pgtype.Text
+	Kinds         []string
+	ActorID       pgtype.Text
+	CreatedAfter  pgtype.Timestamptz
+	CreatedBefore pgtype.Timestamptz
+	RowLimit      int64
+}
+
+type GetActivityHistoryRowsRow struct {
+	ID        string
+	Seq       int64
+	GroupID   string
+	ActorID   string
+	ActorName string
+	Kind      string
+	TitleID   pgtype.Text
+	TitleName pgtype.Text
+	Payload   []byte
+	CreatedAt pgtype.Timestamptz
+	GroupName string
+	ReadByMe  bool
+}
+
+// The history of the reader's groups, their own actions included: what a
+// group page or a title's "what happened" panel shows, where leaving out the
+// reader's own rating would leave a hole in the story. That is the one thing
+// it does differently from activity_visible_events, which exists to decide
+// what is unread, so it joins membership and deleted groups the same way
+// rather than going through the view.
+//
+// The reader's own actions read as read: they were never unread, the badge
+// never counted them, and they cannot be marked read either. The filters are
+// GetActivityFeedRows'.
+func (q *Queries) GetActivityHistoryRows(ctx context.Context, arg GetActivityHistoryRowsParams) ([]GetActivityHistoryRowsRow, error) {
+	rows, err := q.db.Query(ctx, getActivityHistoryRows,
+		arg.UserID,
+		arg.Before,
+		arg.GroupIds,
+		arg.TitleID,
+		arg.Kinds,
+		arg.ActorID,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetActivityHistoryRowsRow
+	for rows.Next() {
+		var i GetActivityHistoryRowsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Seq,
+			&i.GroupID,
+			&i.ActorID,
+			&i.ActorName,
+			&i.Kind,
+			&i.TitleID,
+			&i.TitleName,
+			&i.Payload,
+			&i.CreatedAt,
+			&i.GroupName,
+			&i.ReadByMe,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCoalescibleActivityEvent = `-- name: GetCoalescibleActivityEvent :one
+SELECT e.id, e.seq, e.group_id, e.actor_id, e.actor_name, e.kind, e.title_id,
+       e.title_name, e.payload, e.created_at, g.name AS group_name,
+       FALSE AS read_by_me
+FROM activity_events e
+JOIN groups g ON g.id = e.group_id
+WHERE e.group_id = $1
+  AND e.actor_id = $2
+  AND e.kind = $3
+  AND e.title_id = $4
+  AND COALESCE(e.payload ->> 'season', '') = $5::text
+  AND e.created_at >= $6
+  AND e.id <> $7
+ORDER BY e.seq DESC
+LIMIT 1
+`
+
+type GetCoalescibleActivityEventParams struct {
+	GroupID   string
+	ActorID   string
+	Kind      string
+	TitleID   pgtype.Text
+	Season    string
+	Since     pgtype.Timestamptz
+	ExcludeID string
+}
+
+type GetCoalescibleActivityEventRow struct {
+	ID        string
+	Seq       int64
+	GroupID   string
+	ActorID   string
+	ActorName string
+	Kind      string
+	TitleID   pgtype.Text
+	TitleName pgtype.Text
+	Payload   []byte
+	CreatedAt pgtype.Timestamptz
+	GroupName string
+	ReadByMe  bool
+}
+
+// The event a new one would be merged into: the same actor's latest event of
+// the same kind, about the same title and season in the same group, recorded
+// since the window opened. The new event's own id is left out, so delivering
+// it a second time does not find it merged into itself. Same columns as
+// GetActivityEventById, for the same reason.
+func (q *Queries) GetCoalescibleActivityEvent(ctx context.Context, arg GetCoalescibleActivityEventParams) (GetCoalescibleActivityEventRow, error) {
+	row := q.db.QueryRow(ctx, getCoalescibleActivityEvent,
+		arg.GroupID,
+		arg.ActorID,
+		arg.Kind,
+		arg.TitleID,
+		arg.Season,
+		arg.Since,
+		arg.ExcludeID,
+	)
+	var i GetCoalescibleActivityEventRow
+	err := row.Scan(
+		&i.ID,
+		&i.Seq,
+		&i.GroupID,
+		&i.ActorID,
+		&i.ActorName,
+		&i.Kind,
+		&i.TitleID,
+		&i.TitleName,
+		&i.Payload,
+		&i.CreatedAt,
+		&i.GroupName,
+		&i.ReadByMe,
+	)
+	return i, err
+}
+
 const insertActivityEventRow = `-- name: InsertActivityEventRow :one
 INSERT INTO activity_events (
     id, group_id, actor_id, actor_name, kind, title_id, title_name, payload, created_at
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7, $8, $9
 )
+ON CONFLICT (id) DO NOTHING
 RETURNING id, seq, group_id, actor_id, actor_name, kind, title_id, title_name, payload, created_at
 `
 
@@ -178,6 +463,9 @@ type InsertActivityEventRowParams struct {
 	CreatedAt pgtype.Timestamptz
 }
 
+// An id already in the log inserts nothing and returns no row: the outbox
+// relay delivers at least once, so the same event can arrive twice, and the
+// second arrival must neither duplicate the row nor notify again.
 func (q *Queries) InsertActivityEventRow(ctx context.Context, arg InsertActivityEventRowParams) (ActivityEvent, error) {
 	row := q.db.QueryRow(ctx, insertActivityEventRow,
 		arg.ID,
@@ -309,3 +597,15 @@ func (q *Queries) NotifyActivityEvent(ctx context.Context, eventID string) error
 	_, err := q.db.Exec(ctx, notifyActivityEvent, eventID)
 	return err
 }
+
+const notifyActivityMembership = `-- name: NotifyActivityMembership :exec
+SELECT pg_notify('activity_membership', $1::text)
+`
+
+// Fired with every change to who is in a group, in the change's transaction, so
+// live streams stop showing a group's activity to someone who left it. The
+// payload is the change as JSON: it is a few ids, far under pg_notify's cap.
+func (q *Queries) NotifyActivityMembership(ctx context.Context, change string) error {
+	_, err := q.db.Exec(ctx, notifyActivityMembership, change)
+	return err
+}
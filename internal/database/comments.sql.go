@@ -99,6 +99,43 @@ func (q *Queries) GetCommentRowsByTitleId(ctx context.Context, arg GetCommentRow
 	return items, nil
 }
 
+const getCommentRowsByTitleIds = `-- name: GetCommentRowsByTitleIds :many
+SELECT id, title_id, user_id, comment, created_at, updated_at, group_id FROM comments WHERE title_id = ANY($1::text[]) AND group_id = $2
+`
+
+type GetCommentRowsByTitleIdsParams struct {
+	Column1 []string
+	GroupID string
+}
+
+func (q *Queries) GetCommentRowsByTitleIds(ctx context.Context, arg GetCommentRowsByTitleIdsParams) ([]Comment, error) {
+	rows, err := q.db.Query(ctx, getCommentRowsByTitleIds, arg.Column1, arg.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Comment
+	for rows.Next() {
+		var i Comment
+		if err := rows.Scan(
+			&i.ID,
+			&i.TitleID,
+			&i.UserID,
+			&i.Comment,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.GroupID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getCommentSeasons = `-- name: GetCommentSeasons :many
 SELECT comment_id, season, comment, added_at, updated_at FROM comment_seasons WHERE comment_id = $1
 `
@@ -17,19 +17,32 @@ JOIN titles t ON t.id = gt.title_id
 WHERE gt.group_id = $1
   AND ($2::boolean IS NULL OR gt.watched = $2)
   AND ($3::text[] IS NULL OR t.type = ANY($3::text[]))
+  AND ($4::int[] IS NULL OR EXISTS (
+      SELECT 1 FROM title_availability_offers o
+      WHERE o.title_id = gt.title_id AND o.kind = 'flatrate'
+        AND o.region = $5::text
+        AND o.service_id = ANY($4::int[])))
 `
 
 type CountGroupTitlesParams struct {
 	GroupID    string
 	Watched    pgtype.Bool
 	TitleTypes []string
+	ServiceIds []int32
+	Region     pgtype.Text
 }
 
 // Companion to GetGroupTitlesPage: the window-function total disappears when
 // an out-of-range page returns zero rows, so the store method falls back to
 // this (same WHERE) only in that case. Hot path stays one round trip.
 func (q *Queries) CountGroupTitles(ctx context.Context, arg CountGroupTitlesParams) (int64, error) {
-	row := q.db.QueryRow(ctx, countGroupTitles, arg.GroupID, arg.Watched, arg.TitleTypes)
+	row := q.db.QueryRow(ctx, countGroupTitles,
+		arg.GroupID,
+		arg.Watched,
+		arg.TitleTypes,
+		arg.ServiceIds,
+		arg.Region,
+	)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
@@ -432,33 +445,40 @@ JOIN titles t ON t.id = gt.title_id
 WHERE gt.group_id = $1
   AND ($2::boolean IS NULL OR gt.watched = $2)
   AND ($3::text[] IS NULL OR t.type = ANY($3::text[]))
+  AND ($4::int[] IS NULL OR EXISTS (
+      SELECT 1 FROM title_availability_offers o
+      WHERE o.title_id = gt.title_id AND o.kind = 'flatrate'
+        AND o.region = $5::text
+        AND o.service_id = ANY($4::int[])))
 ORDER BY
-    CASE WHEN $4::text = 'watched'   AND NOT $5::bool THEN gt.watched END ASC,
-    CASE WHEN $4::text = 'watched'   AND $5::bool     THEN gt.watched END DESC,
-    CASE WHEN $4::text = 'watchedAt' AND NOT $5::bool THEN gt.watched_at END ASC NULLS LAST,
-    CASE WHEN $4::text = 'watchedAt' AND $5::bool     THEN gt.watched_at END DESC NULLS LAST,
-    CASE WHEN $4::text = 'addedAt'   AND NOT $5::bool THEN gt.added_at END ASC,
-    CASE WHEN $4::text = 'addedAt'   AND $5::bool     THEN gt.added_at END DESC,
-    CASE WHEN $4::text IN ('', 'primaryTitle') AND NOT $5::bool THEN t.primary_title END ASC,
-    CASE WHEN $4::text IN ('', 'primaryTitle') AND $5::bool     THEN t.primary_title END DESC,
-    CASE WHEN $4::text = 'imdbRating' AND NOT $5::bool THEN t.rating_aggregate END ASC,
-    CASE WHEN $4::text = 'imdbRating' AND $5::bool     THEN t.rating_aggregate END DESC,
-    CASE WHEN $4::text = 'startYear'  AND NOT $5::bool THEN t.start_year END ASC,
-    CASE WHEN $4::text = 'startYear'  AND $5::bool     THEN t.start_year END DESC,
-    CASE WHEN $4::text = 'type'       AND NOT $5::bool THEN t.type END ASC,
-    CASE WHEN $4::text = 'type'       AND $5::bool     THEN t.type END DESC,
-    CASE WHEN $4::text = 'voteCount'  AND NOT $5::bool THEN t.vote_count END ASC,
-    CASE WHEN $4::text = 'voteCount'  AND $5::bool     THEN t.vote_count END DESC,
-    CASE WHEN $4::text = 'updatedAt'  AND NOT $5::bool THEN t.updated_at END ASC,
-    CASE WHEN $4::text = 'updatedAt'  AND $5::bool     THEN t.updated_at END DESC,
+    CASE WHEN $6::text = 'watched'   AND NOT $7::bool THEN gt.watched END ASC,
+    CASE WHEN $6::text = 'watched'   AND $7::bool     THEN gt.watched END DESC,
+    CASE WHEN $6::text = 'watchedAt' AND NOT $7::bool THEN gt.watched_at END ASC NULLS LAST,
+    CASE WHEN $6::text = 'watchedAt' AND $7::bool     THEN gt.watched_at END DESC NULLS LAST,
+    CASE WHEN $6::text = 'addedAt'   AND NOT $7::bool THEN gt.added_at END ASC,
+    CASE WHEN $6::text = 'addedAt'   AND $7::bool     THEN gt.added_at END DESC,
+    CASE WHEN $6::text IN ('', 'primaryTitle') AND NOT $7::bool THEN t.primary_title END ASC,
+    CASE WHEN $6::text IN ('', 'primaryTitle') AND $7::bool     THEN t.primary_title END DESC,
+    CASE WHEN $6::text = 'imdbRating' AND NOT $7::bool THEN t.rating_aggregate END ASC,
+    CASE WHEN $6::text = 'imdbRating' AND $7::bool     THEN t.rating_aggregate END DESC,
+    CASE WHEN $6::text = 'startYear'  AND NOT $7::bool THEN t.start_year END ASC,
+    CASE WHEN $6::text = 'startYear'  AND $7::bool     THEN t.start_year END DESC,
+    CASE WHEN $6::text = 'type'       AND NOT $7::bool THEN t.type END ASC,
+    CASE WHEN $6::text = 'type'       AND $7::bool     THEN t.type END DESC,
+    CASE WHEN $6::text = 'voteCount'  AND NOT $7::bool THEN t.vote_count END ASC,
+    CASE WHEN $6::text = 'voteCount'  AND $7::bool     THEN t.vote_count END DESC,
+    CASE WHEN $6::text = 'updatedAt'  AND NOT $7::bool THEN t.updated_at END ASC,
+    CASE WHEN $6::text = 'updatedAt'  AND $7::bool     THEN t.updated_at END DESC,
     t.id ASC
-LIMIT $7::bigint OFFSET $6::bigint
+LIMIT $9::bigint OFFSET $8::bigint
 `
 
 type GetGroupTitlesPageParams struct {
 	GroupID    string
 	Watched    pgtype.Bool
 	TitleTypes []string
+	ServiceIds []int32
+	Region     pgtype.Text
 	OrderBy    string
 	Descending bool
 	PageOffset int64
@@ -491,11 +511,17 @@ type GetGroupTitlesPageRow struct {
 //
 // page_size/page_offset are cast to bigint so sqlc generates int64 params —
 // see the same note on GetTitlesPage.
+//
+// service_ids/region is the "available on my services" filter: titles a
+// subscription (flatrate) service among service_ids carries in region. An
+// empty service_ids matches nothing; NULL is no filter.
 func (q *Queries) GetGroupTitlesPage(ctx context.Context, arg GetGroupTitlesPageParams) ([]GetGroupTitlesPageRow, error) {
 	rows, err := q.db.Query(ctx, getGroupTitlesPage,
 		arg.GroupID,
 		arg.Watched,
 		arg.TitleTypes,
+		arg.ServiceIds,
+		arg.Region,
 		arg.OrderBy,
 		arg.Descending,
 		arg.PageOffset,
@@ -534,6 +560,57 @@ func (q *Queries) GetGroupTitlesPage(ctx context.Context, arg GetGroupTitlesPage
 	return items, nil
 }
 
+const getTitleIdsSeenByUsers = `-- name: GetTitleIdsSeenByUsers :many
+SELECT r.title_id
+FROM ratings r
+WHERE r.user_id = ANY($1::text[])
+  AND r.title_id = ANY($2::text[])
+UNION
+SELECT gt.title_id
+FROM group_titles gt
+JOIN group_members gm ON gm.group_id = gt.group_id
+JOIN groups g ON g.id = gt.group_id AND NOT g.deleted
+WHERE gm.user_id = ANY($1::text[])
+  AND gt.title_id = ANY($2::text[])
+  AND gt.group_id <> $3
+  AND (gt.watched OR EXISTS (
+      SELECT 1 FROM group_title_seasons s
+      WHERE s.group_id = gt.group_id AND s.title_id = gt.title_id AND s.watched
+  ))
+`
+
+type GetTitleIdsSeenByUsersParams struct {
+	UserIds  []string
+	TitleIds []string
+	GroupID  string
+}
+
+// Which of title_ids any of user_ids has already seen: rated in any group
+// (a rating is a statement of having watched it), or watched — the whole
+// title or any season — in another non-deleted group they belong to. The
+// asking group's own watch state is left out: its caller only ever asks about
+// titles that group has not finished, and a series it is halfway through is
+// one to carry on with, not one its members have seen.
+func (q *Queries) GetTitleIdsSeenByUsers(ctx context.Context, arg GetTitleIdsSeenByUsersParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, getTitleIdsSeenByUsers, arg.UserIds, arg.TitleIds, arg.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var title_id string
+		if err := rows.Scan(&title_id); err != nil {
+			return nil, err
+		}
+		items = append(items, title_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const groupContainsTitle = `-- name: GroupContainsTitle :one
 SELECT EXISTS (
     SELECT 1 FROM groups g
@@ -610,6 +687,11 @@ SELECT EXISTS (
     WHERE gt.group_id = $1
       AND ($2::boolean IS NULL OR gt.watched = $2)
       AND ($3::text[] IS NULL OR t.type = ANY($3::text[]))
+      AND ($4::int[] IS NULL OR EXISTS (
+          SELECT 1 FROM title_availability_offers o
+          WHERE o.title_id = gt.title_id AND o.kind = 'flatrate'
+            AND o.region = $5::text
+            AND o.service_id = ANY($4::int[])))
 )
 `
 
@@ -617,6 +699,8 @@ type GroupHasTitleEntriesParams struct {
 	GroupID    string
 	Watched    pgtype.Bool
 	TitleTypes []string
+	ServiceIds []int32
+	Region     pgtype.Text
 }
 
 // Does the group hold any title entry matching the filters, counting entries
@@ -634,7 +718,13 @@ type GroupHasTitleEntriesParams struct {
 // EXISTS, not count: the caller only needs zero vs. non-zero, and this runs
 // only on the already-empty path.
 func (q *Queries) GroupHasTitleEntries(ctx context.Context, arg GroupHasTitleEntriesParams) (bool, error) {
-	row := q.db.QueryRow(ctx, groupHasTitleEntries, arg.GroupID, arg.Watched, arg.TitleTypes)
+	row := q.db.QueryRow(ctx, groupHasTitleEntries,
+		arg.GroupID,
+		arg.Watched,
+		arg.TitleTypes,
+		arg.ServiceIds,
+		arg.Region,
+	)
 	var exists bool
 	err := row.Scan(&exists)
 	return exists, err
@@ -678,6 +768,42 @@ func (q *Queries) InsertGroup(ctx context.Context, arg InsertGroupParams) (Group
 	return i, err
 }
 
+const insertGroupTitles = `-- name: InsertGroupTitles :many
+INSERT INTO group_titles (group_id, title_id, watched, watched_at, added_at, updated_at)
+SELECT $1::text, t.title_id, false, NULL, $2::timestamptz, $2::timestamptz
+FROM unnest($3::text[]) AS t(title_id)
+ON CONFLICT (group_id, title_id) DO NOTHING
+RETURNING title_id
+`
+
+type InsertGroupTitlesParams struct {
+	GroupID  string
+	AddedAt  pgtype.Timestamptz
+	TitleIds []string
+}
+
+// Adds each of title_ids to the group, unwatched, skipping any it already has,
+// and returns the ids actually inserted.
+func (q *Queries) InsertGroupTitles(ctx context.Context, arg InsertGroupTitlesParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, insertGroupTitles, arg.GroupID, arg.AddedAt, arg.TitleIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var title_id string
+		if err := rows.Scan(&title_id); err != nil {
+			return nil, err
+		}
+		items = append(items, title_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const softDeleteGroupRow = `-- name: SoftDeleteGroupRow :execrows
 UPDATE groups
 SET deleted = true, deleted_at = now(), updated_at = now()
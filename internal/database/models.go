@@ -8,6 +8,15 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ActivityDigest struct {
+	UserID      string
+	Frequency   string
+	DigestedSeq int64
+	NextDueAt   pgtype.Timestamptz
+	LastSentAt  pgtype.Timestamptz
+	CreatedAt   pgtype.Timestamptz
+}
+
 type ActivityEvent struct {
 	ID        string
 	Seq       int64
@@ -27,6 +36,33 @@ type ActivityEventRead struct {
 	ReadAt  pgtype.Timestamptz
 }
 
+type ActivityMute struct {
+	UserID  string
+	GroupID pgtype.Text
+	TitleID pgtype.Text
+	Kind    pgtype.Text
+}
+
+type ActivityOutbox struct {
+	Seq       int64
+	ID        string
+	GroupID   string
+	ActorID   string
+	ActorName string
+	Kind      string
+	TitleID   pgtype.Text
+	TitleName pgtype.Text
+	Payload   []byte
+	CreatedAt pgtype.Timestamptz
+}
+
+type ActivityQuietHour struct {
+	UserID      string
+	StartMinute int16
+	EndMinute   int16
+	TimeZone    string
+}
+
 type ActivityReadFloor struct {
 	UserID   string
 	FloorSeq int64
@@ -82,6 +118,12 @@ type GroupMember struct {
 	UserID  string
 }
 
+type GroupRecommendationSharing struct {
+	GroupID   string
+	EnabledBy string
+	EnabledAt pgtype.Timestamptz
+}
+
 type GroupTitle struct {
 	GroupID   string
 	TitleID   string
@@ -101,6 +143,19 @@ type GroupTitleSeason struct {
 	UpdatedAt pgtype.Timestamptz
 }
 
+type ProviderCache struct {
+	Key       string
+	Value     []byte
+	ExpiresAt pgtype.Timestamptz
+	StoredAt  pgtype.Timestamptz
+}
+
+type ProviderRequestCount struct {
+	Provider string
+	Day      pgtype.Date
+	Requests int32
+}
+
 type Rating struct {
 	ID        string
 	TitleID   string
@@ -131,6 +186,23 @@ type Title struct {
 	Metadata        []byte
 }
 
+type TitleAvailability struct {
+	TitleID   string
+	Provider  string
+	Links     []byte
+	FetchedAt pgtype.Timestamptz
+}
+
+type TitleAvailabilityOffer struct {
+	TitleID         string
+	Region          string
+	Kind            string
+	ServiceID       int32
+	ServiceName     string
+	LogoUrl         string
+	DisplayPriority int32
+}
+
 type User struct {
 	ID           string
 	Name         string
@@ -144,3 +216,40 @@ type User struct {
 	CreatedAt    pgtype.Timestamptz
 	UpdatedAt    pgtype.Timestamptz
 }
+
+type UserStreamingService struct {
+	UserID     string
+	Region     string
+	ServiceIds []int32
+	UpdatedAt  pgtype.Timestamptz
+}
+
+type WebhookDelivery struct {
+	ID             string
+	Seq            int64
+	EndpointID     string
+	EventID        string
+	Kind           string
+	Body           string
+	Status         string
+	Attempts       int32
+	NextAttemptAt  pgtype.Timestamptz
+	LastAttemptAt  pgtype.Timestamptz
+	ResponseStatus pgtype.Int4
+	LastError      string
+	DeliveredAt    pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+}
+
+type WebhookEndpoint struct {
+	ID                  string
+	GroupID             string
+	Url                 string
+	Secret              string
+	Kinds               []string
+	CreatedBy           string
+	ConsecutiveFailures int32
+	DisabledAt          pgtype.Timestamptz
+	CreatedAt           pgtype.Timestamptz
+	UpdatedAt           pgtype.Timestamptz
+}
@@ -86,6 +86,38 @@ func (q *Queries) GetRatingRowByUserTitle(ctx context.Context, arg GetRatingRowB
 	return i, err
 }
 
+const getRatingRowsByGroupId = `-- name: GetRatingRowsByGroupId :many
+SELECT id, title_id, user_id, note, created_at, updated_at, group_id FROM ratings WHERE group_id = $1 ORDER BY title_id, user_id
+`
+
+func (q *Queries) GetRatingRowsByGroupId(ctx context.Context, groupID string) ([]Rating, error) {
+	rows, err := q.db.Query(ctx, getRatingRowsByGroupId, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Rating
+	for rows.Next() {
+		var i Rating
+		if err := rows.Scan(
+			&i.ID,
+			&i.TitleID,
+			&i.UserID,
+			&i.Note,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.GroupID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getRatingRowsByTitleId = `-- name: GetRatingRowsByTitleId :many
 SELECT id, title_id, user_id, note, created_at, updated_at, group_id FROM ratings WHERE title_id = $1 AND group_id = $2
 `
@@ -55,6 +55,40 @@ func (q *Queries) GetTitleById(ctx context.Context, id string) (Title, error) {
 	return i, err
 }
 
+const getTitleRowsByIds = `-- name: GetTitleRowsByIds :many
+SELECT id, primary_title, type, start_year, rating_aggregate, vote_count, added_at, updated_at, metadata FROM titles WHERE id = ANY($1::text[]) ORDER BY id
+`
+
+func (q *Queries) GetTitleRowsByIds(ctx context.Context, ids []string) ([]Title, error) {
+	rows, err := q.db.Query(ctx, getTitleRowsByIds, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Title
+	for rows.Next() {
+		var i Title
+		if err := rows.Scan(
+			&i.ID,
+			&i.PrimaryTitle,
+			&i.Type,
+			&i.StartYear,
+			&i.RatingAggregate,
+			&i.VoteCount,
+			&i.AddedAt,
+			&i.UpdatedAt,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTitlesPage = `-- name: GetTitlesPage :many
 SELECT id, primary_title, type, start_year, rating_aggregate, vote_count, added_at, updated_at, metadata
 FROM titles
@@ -0,0 +1,270 @@
+// Package mailer sends email. Mailer is the one thing the rest of the code
+// depends on; SMTP is the implementation that talks to a real relay, and
+// internal/mailer/mailtest is a local SMTP server to point it at in tests.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is one email to one recipient, in plain text and HTML. Either body
+// may be empty, but not both.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends a message, or says why it could not. A nil error means the
+// relay accepted it, not that it was delivered.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// defaultSMTPPort is the submission port, which is what a relay that takes
+// mail from applications normally listens on.
+const defaultSMTPPort = 587
+
+// defaultSMTPTimeout bounds one Send, connection included, when the context
+// has no deadline of its own.
+const defaultSMTPTimeout = 30 * time.Second
+
+// SMTPOptions configure an SMTP mailer. Username and Password are optional: a
+// relay on a private network often takes mail without them.
+type SMTPOptions struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTP sends through one SMTP relay, one connection per message. The
+// connection is upgraded with STARTTLS whenever the relay offers it, and
+// credentials are only sent over it — net/smtp's PlainAuth refuses them in the
+// clear to anything but localhost.
+type SMTP struct {
+	addr    string
+	host    string
+	from    *mail.Address
+	auth    smtp.Auth
+	timeout time.Duration
+}
+
+// NewSMTP returns an SMTP mailer for opts. It fails if Host is empty or From
+// is not an address; a Port of zero is the submission port.
+func NewSMTP(opts SMTPOptions) (*SMTP, error) {
+	if opts.Host == "" {
+		return nil, errors.New("mailer: an SMTP host is required")
+	}
+	from, err := mail.ParseAddress(opts.From)
+	if err != nil {
+		return nil, fmt.Errorf("mailer: invalid from address %q: %w", opts.From, err)
+	}
+	port := opts.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	m := &SMTP{
+		addr:    net.JoinHostPort(opts.Host, strconv.Itoa(port)),
+		host:    opts.Host,
+		from:    from,
+		timeout: defaultSMTPTimeout,
+	}
+	if opts.Username != "" {
+		m.auth = smtp.PlainAuth("", opts.Username, opts.Password, opts.Host)
+	}
+	return m, nil
+}
+
+// NewFromEnv builds the mailer selected by the MAILER env var, or returns nil
+// when it is unset: nothing in the service needs to send email to work, and
+// what would have been sent is simply not. Allowed values:
+//   - "smtp" : an SMTP relay at SMTP_HOST and SMTP_PORT (default 587), with
+//     SMTP_USERNAME and SMTP_PASSWORD if it wants them, sending as MAIL_FROM
+func NewFromEnv() (Mailer, error) {
+	switch name := os.Getenv("MAILER"); name {
+	case "":
+		return nil, nil
+	case "smtp":
+		port := 0
+		if v := strings.TrimSpace(os.Getenv("SMTP_PORT")); v != "" {
+			p, err := strconv.Atoi(v)
+			if err != nil || p <= 0 || p > 65535 {
+				return nil, fmt.Errorf("MAILER=smtp needs SMTP_PORT to be a port number, not %q", v)
+			}
+			port = p
+		}
+		if os.Getenv("SMTP_HOST") == "" {
+			return nil, fmt.Errorf("MAILER=smtp requires SMTP_HOST to be set")
+		}
+		if os.Getenv("MAIL_FROM") == "" {
+			return nil, fmt.Errorf("MAILER=smtp requires MAIL_FROM to be set")
+		}
+		m, err := NewSMTP(SMTPOptions{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q (allowed: smtp, or unset for none)", name)
+	}
+}
+
+// Send delivers msg to the relay. ctx bounds the whole conversation, not just
+// the dial: net/smtp takes no context, so its deadline is set on the
+// connection instead.
+func (m *SMTP) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("mailer: invalid recipient %q: %w", msg.To, err)
+	}
+	body, err := compose(m.from, to, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: m.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(m.timeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("mailer: SMTP credentials are set but the relay does not take them")
+		}
+		if err := c.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(m.from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// compose renders msg as a MIME message: multipart/alternative with the plain
+// text first, so a client that cannot show HTML shows that, and a single part
+// when there is only one body. Bodies are quoted-printable, which keeps lines
+// short and non-ASCII safe; the subject is encoded for the same reason, and a
+// line break in it is refused rather than let it start a header of its own.
+func compose(from, to *mail.Address, msg Message, now time.Time) ([]byte, error) {
+	if msg.Text == "" && msg.HTML == "" {
+		return nil, errors.New("mailer: a message needs a text or an HTML body")
+	}
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, errors.New("mailer: the subject must be one line")
+	}
+
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if msg.Text == "" || msg.HTML == "" {
+		contentType, content := "text/plain; charset=utf-8", msg.Text
+		if msg.Text == "" {
+			contentType, content = "text/html; charset=utf-8", msg.HTML
+		}
+		header("Content-Type", contentType)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, content); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": parts.Boundary()}))
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
@@ -0,0 +1,152 @@
+package mailer_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/mailer"
+	"github.com/lealre/movies-backend/internal/mailer/mailtest"
+)
+
+func newSMTP(t *testing.T, server *mailtest.Server, username string) *mailer.SMTP {
+	t.Helper()
+	m, err := mailer.NewSMTP(mailer.SMTPOptions{
+		Host:     server.Host,
+		Port:     server.Port,
+		Username: username,
+		Password: "secret",
+		From:     "Aftercredits <digest@example.com>",
+	})
+	require.NoError(t, err)
+	return m
+}
+
+func TestSMTP_Send(t *testing.T) {
+	server := mailtest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+
+	t.Run("text and HTML arrive as alternatives", func(t *testing.T) {
+		server.Reset()
+		err := newSMTP(t, server, "").Send(ctx, mailer.Message{
+			To:      "Ana <ana@example.com>",
+			Subject: "Your weekly digest — 3 groups",
+			Text:    "Hello Ana,\nDune was added.\n",
+			HTML:    "<p>Hello Ana,</p><p>Dune was added.</p>",
+		})
+		require.NoError(t, err)
+
+		messages := server.Messages()
+		require.Len(t, messages, 1, "one message should have reached the server")
+		got := messages[0]
+		assert.Equal(t, "digest@example.com", got.From)
+		assert.Equal(t, []string{"ana@example.com"}, got.To)
+		assert.Equal(t, "Your weekly digest — 3 groups", got.Subject, "the subject should survive its encoding")
+		assert.Equal(t, "Hello Ana,\nDune was added.\n", got.Text)
+		assert.Equal(t, "<p>Hello Ana,</p><p>Dune was added.</p>", got.HTML)
+		assert.Contains(t, got.Header.Get("Content-Type"), "multipart/alternative")
+		assert.Empty(t, got.Username, "no credentials were configured")
+	})
+
+	t.Run("one body is sent as a single part", func(t *testing.T) {
+		server.Reset()
+		err := newSMTP(t, server, "").Send(ctx, mailer.Message{To: "ana@example.com", Subject: "Plain", Text: "just text"})
+		require.NoError(t, err)
+
+		messages := server.Messages()
+		require.Len(t, messages, 1)
+		assert.True(t, strings.HasPrefix(messages[0].Header.Get("Content-Type"), "text/plain"))
+		// SMTP ends the content with a line break of its own.
+		assert.Equal(t, "just text\n", messages[0].Text)
+		assert.Empty(t, messages[0].HTML)
+	})
+
+	t.Run("credentials are used when configured", func(t *testing.T) {
+		server.Reset()
+		err := newSMTP(t, server, "relay-user").Send(ctx, mailer.Message{To: "ana@example.com", Subject: "Auth", Text: "x"})
+		require.NoError(t, err)
+
+		messages := server.Messages()
+		require.Len(t, messages, 1)
+		assert.Equal(t, "relay-user", messages[0].Username)
+	})
+
+	t.Run("a refused message is an error", func(t *testing.T) {
+		server.Reset()
+		server.Reject("mailbox unavailable")
+		defer server.Reject("")
+
+		err := newSMTP(t, server, "").Send(ctx, mailer.Message{To: "ana@example.com", Subject: "No", Text: "x"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "mailbox unavailable")
+		assert.Empty(t, server.Messages())
+	})
+
+	t.Run("a header cannot be smuggled in", func(t *testing.T) {
+		server.Reset()
+		m := newSMTP(t, server, "")
+
+		err := m.Send(ctx, mailer.Message{To: "ana@example.com", Subject: "Hi\r\nBcc: eve@example.com", Text: "x"})
+		require.Error(t, err, "a line break in the subject must be refused")
+
+		err = m.Send(ctx, mailer.Message{To: "ana@example.com\r\nBcc: eve@example.com", Subject: "Hi", Text: "x"})
+		require.Error(t, err, "a recipient that is not one address must be refused")
+
+		assert.Empty(t, server.Messages(), "nothing should have been sent")
+	})
+
+	t.Run("a message needs a body", func(t *testing.T) {
+		err := newSMTP(t, server, "").Send(ctx, mailer.Message{To: "ana@example.com", Subject: "Empty"})
+		require.Error(t, err)
+	})
+}
+
+func TestNewFromEnv(t *testing.T) {
+	for _, key := range []string{"MAILER", "SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD", "MAIL_FROM"} {
+		t.Setenv(key, "")
+	}
+
+	t.Run("unset means no mailer", func(t *testing.T) {
+		m, err := mailer.NewFromEnv()
+		require.NoError(t, err)
+		assert.Nil(t, m)
+	})
+
+	t.Run("smtp needs a host and a sender", func(t *testing.T) {
+		t.Setenv("MAILER", "smtp")
+		_, err := mailer.NewFromEnv()
+		require.Error(t, err)
+
+		t.Setenv("SMTP_HOST", "localhost")
+		_, err = mailer.NewFromEnv()
+		require.Error(t, err, "MAIL_FROM is still missing")
+
+		t.Setenv("MAIL_FROM", "digest@example.com")
+		m, err := mailer.NewFromEnv()
+		require.NoError(t, err)
+		assert.NotNil(t, m)
+
+		t.Setenv("SMTP_PORT", "not-a-port")
+		_, err = mailer.NewFromEnv()
+		require.Error(t, err)
+	})
+
+	t.Run("a bad sender is no mailer at all", func(t *testing.T) {
+		t.Setenv("MAILER", "smtp")
+		t.Setenv("SMTP_HOST", "localhost")
+		t.Setenv("MAIL_FROM", "not an address")
+		m, err := mailer.NewFromEnv()
+		require.Error(t, err)
+		assert.Nil(t, m, "a failed build must not hand back a typed nil")
+	})
+
+	t.Run("an unknown mailer is refused", func(t *testing.T) {
+		t.Setenv("MAILER", "carrier-pigeon")
+		_, err := mailer.NewFromEnv()
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,275 @@
+// Package mailtest is a local SMTP server for tests, the way net/http/httptest
+// is a local HTTP one: point a mailer.SMTP at Host and Port, send, and read
+// back what arrived with Messages. It speaks just enough SMTP for net/smtp —
+// EHLO, AUTH PLAIN, MAIL, RCPT, DATA — and keeps every message in memory.
+// It offers no STARTTLS, so credentials are only ever sent to it on localhost.
+package mailtest
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Message is one message the server accepted: the envelope, the headers, and
+// the text and HTML bodies decoded from whatever MIME shape they came in.
+type Message struct {
+	From     string
+	To       []string
+	Username string // who authenticated, if anyone did
+	Header   mail.Header
+	Subject  string
+	Text     string
+	HTML     string
+	Raw      []byte
+}
+
+// Server is a running SMTP server on a loopback port.
+type Server struct {
+	Host string
+	Port int
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []Message
+	reject   string
+}
+
+// NewServer starts a server on 127.0.0.1 at a free port. Close it when done.
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("mailtest: failed to listen on a port: " + err.Error())
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+	s := &Server{Host: addr.IP.String(), Port: addr.Port, listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Addr is Host and Port as one "host:port".
+func (s *Server) Addr() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// Close stops the server and waits for open conversations to end.
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// Messages returns every message accepted so far, oldest first.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Reset forgets the messages accepted so far.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
+
+// Reject makes the server refuse every message from now on, after its
+// content, with a permanent failure carrying reason. An empty reason accepts
+// them again.
+func (s *Server) Reject(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reject = reason
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.converse(textproto.NewConn(conn))
+		}()
+	}
+}
+
+// converse runs one SMTP session. Anything it does not understand is answered
+// 502, which net/smtp reports as an error rather than hanging on.
+func (s *Server) converse(c *textproto.Conn) {
+	reply := func(code int, text string) bool {
+		return c.PrintfLine("%d %s", code, text) == nil
+	}
+	if !reply(220, "mailtest ESMTP ready") {
+		return
+	}
+
+	var msg Message
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			if c.PrintfLine("250-mailtest") != nil || c.PrintfLine("250-8BITMIME") != nil || !reply(250, "AUTH PLAIN") {
+				return
+			}
+		case "HELO", "NOOP":
+			if !reply(250, "OK") {
+				return
+			}
+		case "RSET":
+			msg = Message{Username: msg.Username}
+			if !reply(250, "OK") {
+				return
+			}
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			if !strings.EqualFold(mechanism, "PLAIN") {
+				if !reply(504, "only PLAIN is supported") {
+					return
+				}
+				continue
+			}
+			if initial == "" {
+				if !reply(334, "") {
+					return
+				}
+				if initial, err = c.ReadLine(); err != nil {
+					return
+				}
+			}
+			// PLAIN is authzid NUL authcid NUL password; any password will do.
+			decoded, err := base64.StdEncoding.DecodeString(initial)
+			fields := strings.Split(string(decoded), "\x00")
+			if err != nil || len(fields) != 3 {
+				if !reply(501, "malformed credentials") {
+					return
+				}
+				continue
+			}
+			msg.Username = fields[1]
+			if !reply(235, "authenticated") {
+				return
+			}
+		case "MAIL":
+			msg = Message{Username: msg.Username, From: address(arg, "FROM:")}
+			if !reply(250, "OK") {
+				return
+			}
+		case "RCPT":
+			msg.To = append(msg.To, address(arg, "TO:"))
+			if !reply(250, "OK") {
+				return
+			}
+		case "DATA":
+			if msg.From == "" || len(msg.To) == 0 {
+				if !reply(503, "MAIL and RCPT first") {
+					return
+				}
+				continue
+			}
+			if !reply(354, "end with <CRLF>.<CRLF>") {
+				return
+			}
+			raw, err := io.ReadAll(c.DotReader())
+			if err != nil {
+				return
+			}
+			if reason := s.accept(msg, raw); reason != "" {
+				if !reply(554, reason) {
+					return
+				}
+			} else if !reply(250, "queued") {
+				return
+			}
+			msg = Message{Username: msg.Username}
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			if !reply(502, "command not implemented") {
+				return
+			}
+		}
+	}
+}
+
+// accept keeps msg with raw as its content, or returns why it was refused.
+func (s *Server) accept(msg Message, raw []byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reject != "" {
+		return s.reject
+	}
+	msg.Raw = raw
+	if parsed, err := mail.ReadMessage(strings.NewReader(string(raw))); err == nil {
+		msg.Header = parsed.Header
+		msg.Subject, _ = new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+		msg.Text, msg.HTML = bodies(parsed.Header, parsed.Body)
+	}
+	s.messages = append(s.messages, msg)
+	return ""
+}
+
+// bodies reads the text/plain and text/html bodies out of a message, whether
+// it is one of them or a multipart/alternative of both.
+func bodies(header mail.Header, body io.Reader) (text, html string) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return "", ""
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		parts := multipart.NewReader(body, params["boundary"])
+		for {
+			// multipart.Reader undoes quoted-printable on its own.
+			part, err := parts.NextPart()
+			if err != nil {
+				return text, html
+			}
+			t, h := bodies(mail.Header(part.Header), part)
+			text += t
+			html += h
+		}
+	}
+
+	if strings.EqualFold(header.Get("Content-Transfer-Encoding"), "quoted-printable") {
+		body = quotedprintable.NewReader(body)
+	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return "", ""
+	}
+	switch mediaType {
+	case "text/plain":
+		return string(content), ""
+	case "text/html":
+		return "", string(content)
+	}
+	return "", ""
+}
+
+// address is the path out of "FROM:<a@b.c> SIZE=…".
+func address(arg, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	path, _, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	return strings.Trim(path, "<>")
+}
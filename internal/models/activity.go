@@ -29,3 +29,112 @@ type ActivityEvent struct {
 	CreatedAt time.Time
 	Read      bool
 }
+
+// ActivityFilter narrows an activity read. Every field is optional, and the
+// ones that are set must all hold: GroupIds and Kinds match any of their
+// values, CreatedAfter is inclusive and CreatedBefore exclusive, so adjacent
+// ranges neither overlap nor leave a gap. The zero value filters nothing.
+type ActivityFilter struct {
+	GroupIds      []string
+	TitleId       *string
+	Kinds         []string
+	ActorId       *string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// ActivityEventRead says UserId has read the event EventId. Read state is
+// otherwise only ever seen through ActivityEvent.Read; as rows of their own,
+// these are read and written by whole-instance archives alone (see
+// store.Archive).
+type ActivityEventRead struct {
+	UserId  string
+	EventId string
+	ReadAt  time.Time
+}
+
+// ActivityReadFloor is a reader's read floor: every event with a Seq at or
+// below FloorSeq is read for UserId, whatever ActivityEventRead rows say. Like
+// ActivityEventRead, only archives handle it as a value.
+type ActivityReadFloor struct {
+	UserId   string
+	FloorSeq int64
+	ReadAt   time.Time
+}
+
+// ActivityPreferences is what one user has asked not to be told about. An
+// event one of Mutes matches is left out of their feed, their badge and their
+// live streams alike; QuietHours, when set, holds back live push alone, and
+// what it held back is in the feed as usual. The zero value mutes nothing.
+type ActivityPreferences struct {
+	UserId     string
+	Mutes      []ActivityMute
+	QuietHours *QuietHours
+}
+
+// ActivityMute is one thing a user muted: every event of a Kind, every event
+// in a group, or, with TitleId as well, one title's thread in a group. Exactly
+// one of Kind and GroupId is set; an empty field is an unset one.
+type ActivityMute struct {
+	GroupId string
+	TitleId string
+	Kind    string
+}
+
+// QuietHours is a daily window in which nothing is pushed live. StartMinute
+// and EndMinute are minutes after midnight in TimeZone, an IANA name; the
+// window includes its start and not its end, and one whose end comes before
+// its start runs across midnight.
+type QuietHours struct {
+	StartMinute int
+	EndMinute   int
+	TimeZone    string
+}
+
+// The kinds of MembershipChange. MembershipReset is never written: the LISTEN
+// loop makes one up when it reconnects, since changes may have been missed
+// while it was away.
+const (
+	MembershipAdded        = "added"
+	MembershipRemoved      = "removed"
+	MembershipGroupDeleted = "group_deleted"
+	MembershipReset        = "reset"
+)
+
+// MembershipChange says who can now see which group's activity, as it is
+// pushed to live streams: UserId joined or left GroupId, or, for
+// MembershipGroupDeleted, GroupId is gone for everyone and UserId is empty. A
+// MembershipReset names nobody and no group: any membership may be stale.
+type MembershipChange struct {
+	Kind    string `json:"kind"`
+	UserId  string `json:"userId,omitempty"`
+	GroupId string `json:"groupId"`
+}
+
+// The frequencies an ActivityDigest can have.
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// ActivityDigest is one user's subscription to an email digest of their unread
+// activity. DigestedSeq is the newest event a digest has covered: the next one
+// starts after it. NextDueAt is when the next one is due, and LastSentAt when
+// one was last mailed, nil before the first.
+type ActivityDigest struct {
+	UserId      string
+	Frequency   string
+	DigestedSeq int64
+	NextDueAt   time.Time
+	LastSentAt  *time.Time
+	CreatedAt   time.Time
+}
+
+// ActivityDigestDispatch is a claimed digest with who it goes to.
+type ActivityDigestDispatch struct {
+	Digest   ActivityDigest
+	Name     string
+	Username string
+	Email    string
+	IsActive bool
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Kinds of streaming offer: on a subscription the viewer already pays for,
+// to rent, or to buy.
+const (
+	OfferFlatrate = "flatrate"
+	OfferRent     = "rent"
+	OfferBuy      = "buy"
+)
+
+// StreamingService is one service offering a title, as the availability
+// provider identifies it. ID is the provider's, and is what users pick their
+// subscriptions by.
+type StreamingService struct {
+	ID              int
+	Name            string
+	LogoURL         string
+	DisplayPriority int
+}
+
+// RegionAvailability is where a title can be watched in one region, each list
+// in the provider's display order.
+type RegionAvailability struct {
+	Link     string
+	Flatrate []StreamingService
+	Rent     []StreamingService
+	Buy      []StreamingService
+}
+
+// TitleAvailability is where a title can be watched, by region code (ISO
+// 3166-1, e.g. "US"), as of FetchedAt. A region missing from Regions has no
+// offers there.
+type TitleAvailability struct {
+	TitleID   string
+	Provider  string
+	FetchedAt time.Time
+	Regions   map[string]RegionAvailability
+}
+
+// StreamingPreferences are the services a user subscribes to, and the region
+// they watch from.
+type StreamingPreferences struct {
+	UserID     string
+	Region     string
+	ServiceIDs []int
+	UpdatedAt  time.Time
+}
+
+// StreamingFilter narrows a title listing to titles that one of ServiceIDs
+// carries on a subscription in Region. An empty ServiceIDs matches nothing.
+type StreamingFilter struct {
+	Region     string
+	ServiceIDs []int
+}
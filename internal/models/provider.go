@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// ProviderRequestCount is how many requests one title provider was sent on
+// one UTC day, by every process sharing the store.
+type ProviderRequestCount struct {
+	Provider string
+	Day      time.Time
+	Requests int
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RecommendationCandidate is a catalogue title a group has not seen yet, as
+// offered to the recommendation model. InGroup is true when the title is
+// already on the group's list (added, not watched), false when it is only in
+// the catalogue.
+type RecommendationCandidate struct {
+	Title   Title
+	InGroup bool
+}
+
+// RecommendationSharing is a group's consent to have its ratings pooled into
+// other groups' recommendations: who gave it, and when. A group without one
+// has not consented.
+type RecommendationSharing struct {
+	GroupId   string
+	EnabledBy string
+	EnabledAt time.Time
+}
@@ -43,8 +43,11 @@ type Title struct {
 	Interests       []Interest
 	Seasons         []Seasons
 	Episodes        []Episode
-	AddedAt         *time.Time
-	UpdatedAt       *time.Time
+	// Source is the title provider the stored data last came from (e.g.
+	// "tmdb"). Empty for titles stored before it was recorded.
+	Source    string
+	AddedAt   *time.Time
+	UpdatedAt *time.Time
 }
 
 type Image struct {
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// WebhookEndpoint is a URL a group owner registered to be told about the
+// group's activity: every event of one of Kinds is POSTed to it, signed with
+// Secret. DisabledAt is set when it fails ConsecutiveFailures times in a row
+// past the configured limit, and nothing is sent to it until it is enabled
+// again.
+type WebhookEndpoint struct {
+	Id                  string
+	GroupId             string
+	URL                 string
+	Secret              string
+	Kinds               []string
+	CreatedBy           string
+	ConsecutiveFailures int
+	DisabledAt          *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// The states of a WebhookDelivery. A pending delivery is due at NextAttemptAt;
+// the other two are final until someone asks for a redelivery.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event on its way to one endpoint, and what happened
+// the last time it was sent. Body is exactly what is POSTed on every attempt.
+type WebhookDelivery struct {
+	Id             string
+	Seq            int64
+	EndpointId     string
+	EventId        string
+	Kind           string
+	Body           []byte
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastAttemptAt  *time.Time
+	ResponseStatus *int
+	LastError      string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+}
+
+// WebhookDispatch is a claimed delivery with what it takes to send it.
+type WebhookDispatch struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// WebhookAttempt is the outcome of sending a delivery once. A failure with a
+// RetryAt is tried again then; one without has used up its attempts. Each
+// failure counts against the endpoint, which is disabled when DisableAfter
+// failures in a row are reached; a success clears the count.
+type WebhookAttempt struct {
+	DeliveryId     string
+	EndpointId     string
+	At             time.Time
+	Succeeded      bool
+	ResponseStatus *int
+	Error          string
+	RetryAt        *time.Time
+	DisableAfter   int
+}
@@ -3,9 +3,11 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 
 	"github.com/lealre/movies-backend/internal/database"
 	"github.com/lealre/movies-backend/internal/models"
@@ -17,7 +19,12 @@ import (
 // lands or none of it does.
 //
 // Ids and created_at are generated here, matching every other write in this
-// package. seq is assigned by the database.
+// package, unless the event already has them — as one the outbox relay
+// delivers does. seq is assigned by the database.
+//
+// An event whose id is already in the log is skipped, not an error: the relay
+// delivers at least once, so a batch can arrive a second time after a crash.
+// It is not notified again either.
 func (s *Store) InsertActivityEvents(ctx context.Context, events []models.ActivityEvent) error {
 	if len(events) == 0 {
 		return nil
@@ -25,15 +32,13 @@ func (s *Store) InsertActivityEvents(ctx context.Context, events []models.Activi
 	return s.inTx(ctx, func(q *database.Queries) error {
 		now := time.Now()
 		for _, e := range events {
-			var payload []byte
-			if e.Payload == nil {
-				payload = []byte(`{}`)
-			} else {
-				var err error
-				payload, err = json.Marshal(e.Payload)
-				if err != nil {
-					return err
-				}
+			payload, err := activityPayloadToJSON(e.Payload)
+			if err != nil {
+				return err
+			}
+			createdAt := e.CreatedAt
+			if createdAt.IsZero() {
+				createdAt = now
 			}
 			row, err := q.InsertActivityEventRow(ctx, database.InsertActivityEventRowParams{
 				ID:        firstNonEmpty(e.Id, uuid.NewString()),
@@ -44,8 +49,11 @@ func (s *Store) InsertActivityEvents(ctx context.Context, events []models.Activi
 				TitleID:   ptrToText(e.TitleId),
 				TitleName: ptrToText(e.TitleName),
 				Payload:   payload,
-				CreatedAt: timeToTimestamptz(now),
+				CreatedAt: timeToTimestamptz(createdAt),
 			})
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
 			if err != nil {
 				return err
 			}
@@ -59,16 +67,80 @@ func (s *Store) InsertActivityEvents(ctx context.Context, events []models.Activi
 }
 
 // GetActivityFeed returns the events visible to userId, newest first, excluding
-// their own. before is an exclusive seq cursor; nil starts at the newest. Each
-// event carries userId's own read state, so a row can be rendered read or
-// unread without a second query.
-func (s *Store) GetActivityFeed(ctx context.Context, userId string, before *int64, limit int) ([]models.ActivityEvent, error) {
+// their own, narrowed by filter. before is an exclusive seq cursor; nil starts
+// at the newest. Each event carries userId's own read state, so a row can be
+// rendered read or unread without a second query.
+func (s *Store) GetActivityFeed(ctx context.Context, userId string, filter models.ActivityFilter, before *int64, limit int) ([]models.ActivityEvent, error) {
 	if limit <= 0 {
 		return []models.ActivityEvent{}, nil
 	}
-	rows, err := s.q.GetActivityFeedRows(ctx, database.GetActivityFeedRowsParams{
+	rows, err := s.queries(ctx).GetActivityFeedRows(ctx, database.GetActivityFeedRowsParams{
+		UserID:        userId,
+		Before:        int64PtrToNullable(before),
+		GroupIds:      filter.GroupIds,
+		TitleID:       ptrToText(filter.TitleId),
+		Kinds:         filter.Kinds,
+		ActorID:       ptrToText(filter.ActorId),
+		CreatedAfter:  ptrToTimestamptz(filter.CreatedAfter),
+		CreatedBefore: ptrToTimestamptz(filter.CreatedBefore),
+		RowLimit:      int64(limit),
+	})
+	if err != nil {
+		return []models.ActivityEvent{}, err
+	}
+	events := make([]models.ActivityEvent, 0, len(rows))
+	for _, row := range rows {
+		event, err := activityEventRowToModel(row)
+		if err != nil {
+			return []models.ActivityEvent{}, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// GetActivityHistory is GetActivityFeed with userId's own actions left in,
+// which read as read. Everything else — the groups it covers, the filters, the
+// cursor — is the feed's.
+func (s *Store) GetActivityHistory(ctx context.Context, userId string, filter models.ActivityFilter, before *int64, limit int) ([]models.ActivityEvent, error) {
+	if limit <= 0 {
+		return []models.ActivityEvent{}, nil
+	}
+	rows, err := s.queries(ctx).GetActivityHistoryRows(ctx, database.GetActivityHistoryRowsParams{
+		UserID:        userId,
+		Before:        int64PtrToNullable(before),
+		GroupIds:      filter.GroupIds,
+		TitleID:       ptrToText(filter.TitleId),
+		Kinds:         filter.Kinds,
+		ActorID:       ptrToText(filter.ActorId),
+		CreatedAfter:  ptrToTimestamptz(filter.CreatedAfter),
+		CreatedBefore: ptrToTimestamptz(filter.CreatedBefore),
+		RowLimit:      int64(limit),
+	})
+	if err != nil {
+		return []models.ActivityEvent{}, err
+	}
+	events := make([]models.ActivityEvent, 0, len(rows))
+	for _, row := range rows {
+		event, err := activityEventRowToModel(database.GetActivityFeedRowsRow(row))
+		if err != nil {
+			return []models.ActivityEvent{}, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// GetActivityEventsSince returns the events visible to userId with a seq
+// after afterSeq, oldest first — what a resuming stream missed. Visibility and
+// read state are the feed's.
+func (s *Store) GetActivityEventsSince(ctx context.Context, userId string, afterSeq int64, limit int) ([]models.ActivityEvent, error) {
+	if limit <= 0 {
+		return []models.ActivityEvent{}, nil
+	}
+	rows, err := s.queries(ctx).GetActivityEventsSinceRows(ctx, database.GetActivityEventsSinceRowsParams{
 		UserID:   userId,
-		Before:   int64PtrToNullable(before),
+		AfterSeq: afterSeq,
 		RowLimit: int64(limit),
 	})
 	if err != nil {
@@ -76,7 +148,9 @@ func (s *Store) GetActivityFeed(ctx context.Context, userId string, before *int6
 	}
 	events := make([]models.ActivityEvent, 0, len(rows))
 	for _, row := range rows {
-		event, err := activityEventRowToModel(row)
+		// The same columns as GetActivityFeedRows, so the same conversion as
+		// GetActivityEventById.
+		event, err := activityEventRowToModel(database.GetActivityFeedRowsRow(row))
 		if err != nil {
 			return []models.ActivityEvent{}, err
 		}
@@ -90,7 +164,7 @@ func (s *Store) GetActivityFeed(ctx context.Context, userId string, before *int6
 // a notification carries only an id, and this turns it into the row that gets
 // fanned out to subscribers.
 func (s *Store) GetActivityEventById(ctx context.Context, id string) (models.ActivityEvent, error) {
-	row, err := s.q.GetActivityEventById(ctx, id)
+	row, err := s.queries(ctx).GetActivityEventById(ctx, id)
 	if err != nil {
 		return models.ActivityEvent{}, notFound(err)
 	}
@@ -101,11 +175,58 @@ func (s *Store) GetActivityEventById(ctx context.Context, id string) (models.Act
 	return activityEventRowToModel(database.GetActivityFeedRowsRow(row))
 }
 
+// GetCoalescibleActivityEvent returns the latest event event would be merged
+// into: its actor's, of its kind, about its title and season in its group,
+// recorded at or after since, and not event itself. ErrRecordNotFound when
+// there is none.
+func (s *Store) GetCoalescibleActivityEvent(ctx context.Context, event models.ActivityEvent, since time.Time) (models.ActivityEvent, error) {
+	season, err := activitySeason(event.Payload)
+	if err != nil {
+		return models.ActivityEvent{}, err
+	}
+	row, err := s.queries(ctx).GetCoalescibleActivityEvent(ctx, database.GetCoalescibleActivityEventParams{
+		GroupID:   event.GroupId,
+		ActorID:   event.ActorId,
+		Kind:      event.Kind,
+		TitleID:   ptrToText(event.TitleId),
+		Season:    season,
+		Since:     timeToTimestamptz(since),
+		ExcludeID: event.Id,
+	})
+	if err != nil {
+		return models.ActivityEvent{}, notFound(err)
+	}
+	return activityEventRowToModel(database.GetActivityFeedRowsRow(row))
+}
+
+// DeleteActivityEvent removes one event from the log, with its read rows. It
+// is only for an event merged into a later one; deleting one that is not there
+// is not an error.
+func (s *Store) DeleteActivityEvent(ctx context.Context, id string) error {
+	return s.queries(ctx).DeleteActivityEvent(ctx, id)
+}
+
+// activitySeason is a payload's season as the ->> operator reads it out of
+// JSONB, or "" for none. It goes through JSON because the payload may have
+// been: a season is an int as recorded but a float64 once it has been through
+// the outbox, and both must match the same stored event.
+func activitySeason(payload map[string]any) (string, error) {
+	season, ok := payload["season"]
+	if !ok {
+		return "", nil
+	}
+	b, err := json.Marshal(season)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
 // GetActivityUnreadCount counts the events visible to userId that they have no
 // read row for — the same visibility view the feed reads through, so the badge
 // and the feed cannot disagree.
 func (s *Store) GetActivityUnreadCount(ctx context.Context, userId string) (int64, error) {
-	return s.q.CountActivityUnread(ctx, userId)
+	return s.queries(ctx).CountActivityUnread(ctx, userId)
 }
 
 // MarkActivityEventRead records that userId has read exactly one event, leaving
@@ -117,7 +238,7 @@ func (s *Store) GetActivityUnreadCount(ctx context.Context, userId string) (int6
 // three are deliberately one answer: telling them apart would tell a caller
 // which event ids exist in groups they are not in.
 func (s *Store) MarkActivityEventRead(ctx context.Context, userId, eventId string) error {
-	_, err := s.q.MarkActivityEventRead(ctx, database.MarkActivityEventReadParams{
+	_, err := s.queries(ctx).MarkActivityEventRead(ctx, database.MarkActivityEventReadParams{
 		ReadAt:  timeToTimestamptz(time.Now()),
 		UserID:  userId,
 		EventID: eventId,
@@ -132,7 +253,7 @@ func (s *Store) MarkActivityEventRead(ctx context.Context, userId, eventId strin
 // visible to them, in one statement. Events that arrive afterwards are unread,
 // and an event they cannot see is not touched.
 func (s *Store) MarkAllActivityEventsRead(ctx context.Context, userId string) error {
-	return s.q.MarkAllActivityEventsRead(ctx, database.MarkAllActivityEventsReadParams{
+	return s.queries(ctx).MarkAllActivityEventsRead(ctx, database.MarkAllActivityEventsReadParams{
 		ReadAt: timeToTimestamptz(time.Now()),
 		UserID: userId,
 	})
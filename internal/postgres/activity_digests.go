@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/database"
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+// GetActivityDigest returns userId's digest subscription, or ErrRecordNotFound
+// when they have none.
+func (s *Store) GetActivityDigest(ctx context.Context, userId string) (models.ActivityDigest, error) {
+	row, err := s.queries(ctx).GetActivityDigest(ctx, userId)
+	if err != nil {
+		return models.ActivityDigest{}, notFound(err)
+	}
+	return activityDigestRowToModel(row), nil
+}
+
+// SetActivityDigest subscribes digest.UserId at digest.Frequency, first due at
+// digest.NextDueAt. For a user already subscribed it changes the frequency,
+// and reschedules them to digest.NextDueAt only if that changed it. A new
+// subscription starts after the newest event in the log; an existing one
+// keeps its place.
+func (s *Store) SetActivityDigest(ctx context.Context, digest models.ActivityDigest) (models.ActivityDigest, error) {
+	row, err := s.queries(ctx).UpsertActivityDigest(ctx, database.UpsertActivityDigestParams{
+		UserID:    digest.UserId,
+		Frequency: digest.Frequency,
+		NextDueAt: timeToTimestamptz(digest.NextDueAt),
+	})
+	if err != nil {
+		return models.ActivityDigest{}, err
+	}
+	return activityDigestRowToModel(row), nil
+}
+
+// DeleteActivityDigest unsubscribes userId. Unsubscribing someone who is not
+// subscribed is not an error.
+func (s *Store) DeleteActivityDigest(ctx context.Context, userId string) error {
+	return s.queries(ctx).DeleteActivityDigest(ctx, userId)
+}
+
+// ClaimActivityDigests returns up to limit digests that are due, with the
+// name and address of who they go to, and leases them until leaseUntil.
+func (s *Store) ClaimActivityDigests(ctx context.Context, limit int, leaseUntil time.Time) ([]models.ActivityDigestDispatch, error) {
+	if limit <= 0 {
+		return []models.ActivityDigestDispatch{}, nil
+	}
+	rows, err := s.queries(ctx).ClaimActivityDigests(ctx, database.ClaimActivityDigestsParams{
+		LeaseUntil: timeToTimestamptz(leaseUntil),
+		Now:        timeToTimestamptz(time.Now()),
+		RowLimit:   int64(limit),
+	})
+	if err != nil {
+		return []models.ActivityDigestDispatch{}, err
+	}
+	dispatches := make([]models.ActivityDigestDispatch, len(rows))
+	for i, row := range rows {
+		dispatches[i] = models.ActivityDigestDispatch{
+			Digest: activityDigestRowToModel(database.ActivityDigest{
+				UserID:      row.UserID,
+				Frequency:   row.Frequency,
+				DigestedSeq: row.DigestedSeq,
+				NextDueAt:   row.NextDueAt,
+				LastSentAt:  row.LastSentAt,
+				CreatedAt:   row.CreatedAt,
+			}),
+			Name:     row.Name,
+			Username: row.Username,
+			Email:    row.Email,
+			IsActive: row.IsActive,
+		}
+	}
+	return dispatches, nil
+}
+
+// GetActivityDigestEvents returns userId's unread events of kinds with a seq
+// after afterSeq, oldest first. Unread means what it means for the badge, so
+// nothing read, muted or from a group they have left is in it.
+func (s *Store) GetActivityDigestEvents(ctx context.Context, userId string, afterSeq int64, kinds []string, limit int) ([]models.ActivityEvent, error) {
+	if limit <= 0 || len(kinds) == 0 {
+		return []models.ActivityEvent{}, nil
+	}
+	rows, err := s.queries(ctx).GetActivityDigestEventRows(ctx, database.GetActivityDigestEventRowsParams{
+		UserID:   userId,
+		AfterSeq: afterSeq,
+		Kinds:    kinds,
+		RowLimit: int64(limit),
+	})
+	if err != nil {
+		return []models.ActivityEvent{}, err
+	}
+	events := make([]models.ActivityEvent, 0, len(rows))
+	for _, row := range rows {
+		event, err := activityEventRowToModel(database.GetActivityFeedRowsRow(row))
+		if err != nil {
+			return []models.ActivityEvent{}, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// CompleteActivityDigest records a run of the digest job for digest.UserId:
+// it raises DigestedSeq (never lowers it), sets LastSentAt when the run sent
+// a digest, and schedules the next one at NextDueAt.
+func (s *Store) CompleteActivityDigest(ctx context.Context, digest models.ActivityDigest) error {
+	return s.queries(ctx).CompleteActivityDigest(ctx, database.CompleteActivityDigestParams{
+		DigestedSeq: digest.DigestedSeq,
+		LastSentAt:  ptrToTimestamptz(digest.LastSentAt),
+		NextDueAt:   timeToTimestamptz(digest.NextDueAt),
+		UserID:      digest.UserId,
+	})
+}
+
+func activityDigestRowToModel(row database.ActivityDigest) models.ActivityDigest {
+	return models.ActivityDigest{
+		UserId:      row.UserID,
+		Frequency:   row.Frequency,
+		DigestedSeq: row.DigestedSeq,
+		NextDueAt:   row.NextDueAt.Time,
+		LastSentAt:  timestamptzToPtr(row.LastSentAt),
+		CreatedAt:   row.CreatedAt.Time,
+	}
+}
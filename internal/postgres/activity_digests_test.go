@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+func TestStore_ActivityDigests(t *testing.T) {
+	t.Run("a subscription starts after the newest event and keeps its schedule unless the frequency changes", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		actor := addTestUser(t, s)
+		reader := addTestUser(t, s)
+		group, err := s.CreateGroup(ctx, newTestGroup(t, "digests", actor))
+		require.NoError(t, err)
+		require.NoError(t, s.AddUserToGroup(ctx, group.Id, actor, reader))
+		require.NoError(t, s.InsertActivityEvents(ctx, []models.ActivityEvent{
+			{Id: "e1", GroupId: group.Id, ActorId: actor, ActorName: "a", Kind: "title_added"},
+		}))
+		before, err := s.GetActivityEventById(ctx, "e1")
+		require.NoError(t, err)
+
+		_, err = s.GetActivityDigest(ctx, reader)
+		require.ErrorIs(t, err, store.ErrRecordNotFound)
+
+		due := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+		digest, err := s.SetActivityDigest(ctx, models.ActivityDigest{UserId: reader, Frequency: models.DigestDaily, NextDueAt: due})
+		require.NoError(t, err)
+		require.Equal(t, before.Seq, digest.DigestedSeq, "what happened before subscribing is not mailed")
+		require.True(t, due.Equal(digest.NextDueAt))
+		require.Nil(t, digest.LastSentAt)
+
+		digest, err = s.SetActivityDigest(ctx, models.ActivityDigest{UserId: reader, Frequency: models.DigestDaily, NextDueAt: due.Add(time.Hour)})
+		require.NoError(t, err)
+		require.True(t, due.Equal(digest.NextDueAt), "saving the same frequency keeps the schedule")
+
+		weekly := due.Add(6 * 24 * time.Hour)
+		digest, err = s.SetActivityDigest(ctx, models.ActivityDigest{UserId: reader, Frequency: models.DigestWeekly, NextDueAt: weekly})
+		require.NoError(t, err)
+		require.Equal(t, models.DigestWeekly, digest.Frequency)
+		require.True(t, weekly.Equal(digest.NextDueAt), "a new frequency starts a new schedule")
+		require.Equal(t, before.Seq, digest.DigestedSeq, "and keeps its place in the log")
+
+		require.NoError(t, s.DeleteActivityDigest(ctx, reader))
+		require.NoError(t, s.DeleteActivityDigest(ctx, reader), "unsubscribing twice is not an error")
+		_, err = s.GetActivityDigest(ctx, reader)
+		require.ErrorIs(t, err, store.ErrRecordNotFound)
+	})
+
+	t.Run("only due digests are claimed, and a claim holds them until the lease runs out", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		dueUser := newTestUser(t)
+		dueUser.Name = "Due"
+		require.NoError(t, s.AddUser(ctx, dueUser))
+		laterUser := addTestUser(t, s)
+
+		past := time.Now().Add(-time.Minute)
+		_, err := s.SetActivityDigest(ctx, models.ActivityDigest{UserId: dueUser.Id, Frequency: models.DigestDaily, NextDueAt: past})
+		require.NoError(t, err)
+		_, err = s.SetActivityDigest(ctx, models.ActivityDigest{UserId: laterUser, Frequency: models.DigestDaily, NextDueAt: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+
+		lease := time.Now().Add(15 * time.Minute).UTC().Truncate(time.Second)
+		claimed, err := s.ClaimActivityDigests(ctx, 10, lease)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		require.Equal(t, dueUser.Id, claimed[0].Digest.UserId)
+		require.Equal(t, "Due", claimed[0].Name)
+		require.Equal(t, dueUser.Email, claimed[0].Email)
+		require.True(t, claimed[0].IsActive)
+		require.True(t, lease.Equal(claimed[0].Digest.NextDueAt), "the claim is the lease")
+
+		claimed, err = s.ClaimActivityDigests(ctx, 10, lease)
+		require.NoError(t, err)
+		require.Empty(t, claimed, "a leased digest is not claimed again")
+
+		require.NoError(t, s.CompleteActivityDigest(ctx, models.ActivityDigest{UserId: dueUser.Id, NextDueAt: past}))
+		claimed, err = s.ClaimActivityDigests(ctx, 10, lease)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1, "a digest put back in the past is due again")
+	})
+
+	t.Run("a digest's events are the reader's unread ones of its kinds", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		actor := addTestUser(t, s)
+		reader := addTestUser(t, s)
+		films, err := s.CreateGroup(ctx, newTestGroup(t, "films", actor))
+		require.NoError(t, err)
+		series, err := s.CreateGroup(ctx, newTestGroup(t, "series", actor))
+		require.NoError(t, err)
+		for _, g := range []string{films.Id, series.Id} {
+			require.NoError(t, s.AddUserToGroup(ctx, g, actor, reader))
+		}
+
+		require.NoError(t, s.InsertActivityEvents(ctx, []models.ActivityEvent{
+			{Id: "e1", GroupId: films.Id, ActorId: actor, ActorName: "a", Kind: "title_added"},
+			{Id: "e2", GroupId: films.Id, ActorId: actor, ActorName: "a", Kind: "comment_added"},
+			{Id: "e3", GroupId: films.Id, ActorId: actor, ActorName: "a", Kind: "title_added"},
+			{Id: "e4", GroupId: series.Id, ActorId: actor, ActorName: "a", Kind: "title_added"},
+			{Id: "e5", GroupId: films.Id, ActorId: actor, ActorName: "a", Kind: "member_added"},
+			{Id: "e6", GroupId: films.Id, ActorId: actor, ActorName: "a", Kind: "rating_added"},
+		}))
+		first, err := s.GetActivityEventById(ctx, "e1")
+		require.NoError(t, err)
+
+		ids := func(afterSeq int64, limit int) []string {
+			t.Helper()
+			events, err := s.GetActivityDigestEvents(ctx, reader, afterSeq, []string{"title_added", "comment_added", "rating_added"}, limit)
+			require.NoError(t, err)
+			out := []string{}
+			for _, e := range events {
+				out = append(out, e.Id)
+			}
+			return out
+		}
+
+		require.Equal(t, []string{"e1", "e2", "e3", "e4", "e6"}, ids(0, 50), "oldest first, and not the kinds left out")
+		require.Equal(t, []string{"e2", "e3"}, ids(first.Seq, 2), "after the digested seq, up to the limit")
+
+		require.NoError(t, s.MarkActivityEventRead(ctx, reader, "e3"))
+		_, err = s.SetActivityPreferences(ctx, models.ActivityPreferences{
+			UserId: reader,
+			Mutes:  []models.ActivityMute{{GroupId: series.Id}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"e1", "e2", "e6"}, ids(0, 50), "nothing read in the app, nor muted")
+
+		require.NoError(t, s.MarkAllActivityEventsRead(ctx, reader))
+		require.Empty(t, ids(0, 50), "clearing the badge clears the digest")
+
+		events, err := s.GetActivityDigestEvents(ctx, actor, 0, nil, 50)
+		require.NoError(t, err)
+		require.Empty(t, events, "no kinds, no events")
+	})
+
+	t.Run("completing never moves the watermark back, and only a send stamps last sent", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		reader := addTestUser(t, s)
+		_, err := s.SetActivityDigest(ctx, models.ActivityDigest{UserId: reader, Frequency: models.DigestDaily, NextDueAt: time.Now()})
+		require.NoError(t, err)
+
+		sentAt := time.Now().UTC().Truncate(time.Second)
+		next := sentAt.Add(24 * time.Hour)
+		require.NoError(t, s.CompleteActivityDigest(ctx, models.ActivityDigest{UserId: reader, DigestedSeq: 7, LastSentAt: &sentAt, NextDueAt: next}))
+		require.NoError(t, s.CompleteActivityDigest(ctx, models.ActivityDigest{UserId: reader, DigestedSeq: 3, NextDueAt: next.Add(time.Hour)}))
+
+		digest, err := s.GetActivityDigest(ctx, reader)
+		require.NoError(t, err)
+		require.Equal(t, int64(7), digest.DigestedSeq)
+		require.NotNil(t, digest.LastSentAt)
+		require.True(t, sentAt.Equal(*digest.LastSentAt), "a run that sent nothing keeps the last send")
+		require.True(t, next.Add(time.Hour).Equal(digest.NextDueAt))
+
+		require.NoError(t, s.DeleteActivityDigest(ctx, reader))
+		require.NoError(t, s.CompleteActivityDigest(ctx, models.ActivityDigest{UserId: reader, DigestedSeq: 9, NextDueAt: next}),
+			"a user who unsubscribed mid-run is left unsubscribed")
+		_, err = s.GetActivityDigest(ctx, reader)
+		require.ErrorIs(t, err, store.ErrRecordNotFound)
+	})
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/lealre/movies-backend/internal/database"
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+// GetActivityPreferences returns userId's mutes and quiet hours. A user who
+// has set none gets the zero value, which mutes nothing, rather than an error:
+// having no preferences is the ordinary case.
+func (s *Store) GetActivityPreferences(ctx context.Context, userId string) (models.ActivityPreferences, error) {
+	q := s.queries(ctx)
+
+	rows, err := q.GetActivityMutes(ctx, userId)
+	if err != nil {
+		return models.ActivityPreferences{}, err
+	}
+	prefs := models.ActivityPreferences{UserId: userId, Mutes: make([]models.ActivityMute, len(rows))}
+	for i, row := range rows {
+		prefs.Mutes[i] = models.ActivityMute{
+			GroupId: row.GroupID.String,
+			TitleId: row.TitleID.String,
+			Kind:    row.Kind.String,
+		}
+	}
+
+	quiet, err := q.GetActivityQuietHours(ctx, userId)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return models.ActivityPreferences{}, err
+	}
+	if err == nil {
+		prefs.QuietHours = &models.QuietHours{
+			StartMinute: int(quiet.StartMinute),
+			EndMinute:   int(quiet.EndMinute),
+			TimeZone:    quiet.TimeZone,
+		}
+	}
+	return prefs, nil
+}
+
+// SetActivityPreferences replaces prefs.UserId's preferences whole, tells
+// live streams in the same transaction, and returns them as stored.
+func (s *Store) SetActivityPreferences(ctx context.Context, prefs models.ActivityPreferences) (models.ActivityPreferences, error) {
+	err := s.inTx(ctx, func(q *database.Queries) error {
+		if err := q.DeleteActivityMutes(ctx, prefs.UserId); err != nil {
+			return err
+		}
+		for _, mute := range prefs.Mutes {
+			if err := q.InsertActivityMute(ctx, database.InsertActivityMuteParams{
+				UserID:  prefs.UserId,
+				GroupID: stringToText(mute.GroupId),
+				TitleID: stringToText(mute.TitleId),
+				Kind:    stringToText(mute.Kind),
+			}); err != nil {
+				return err
+			}
+		}
+
+		if prefs.QuietHours == nil {
+			if err := q.DeleteActivityQuietHours(ctx, prefs.UserId); err != nil {
+				return err
+			}
+		} else if err := q.UpsertActivityQuietHours(ctx, database.UpsertActivityQuietHoursParams{
+			UserID:      prefs.UserId,
+			StartMinute: int16(prefs.QuietHours.StartMinute),
+			EndMinute:   int16(prefs.QuietHours.EndMinute),
+			TimeZone:    prefs.QuietHours.TimeZone,
+		}); err != nil {
+			return err
+		}
+
+		return q.NotifyActivityPreferences(ctx, prefs.UserId)
+	})
+	if err != nil {
+		return models.ActivityPreferences{}, err
+	}
+	return s.GetActivityPreferences(ctx, prefs.UserId)
+}
+
+// stringToText is ptrToText for a field whose empty value means unset.
+func stringToText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+func TestStore_ActivityPreferences(t *testing.T) {
+	t.Run("a user with no preferences gets the zero value, and a save replaces them whole", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		owner := addTestUser(t, s)
+		group, err := s.CreateGroup(ctx, newTestGroup(t, "prefs", owner))
+		require.NoError(t, err)
+
+		prefs, err := s.GetActivityPreferences(ctx, owner)
+		require.NoError(t, err, "having no preferences is not an error")
+		require.Equal(t, owner, prefs.UserId)
+		require.Empty(t, prefs.Mutes)
+		require.Nil(t, prefs.QuietHours)
+
+		saved, err := s.SetActivityPreferences(ctx, models.ActivityPreferences{
+			UserId: owner,
+			Mutes: []models.ActivityMute{
+				{GroupId: group.Id, TitleId: "tt1"},
+				{GroupId: group.Id},
+				{Kind: "rating_added"},
+				{Kind: "rating_added"},
+			},
+			QuietHours: &models.QuietHours{StartMinute: 1320, EndMinute: 420, TimeZone: "Europe/Lisbon"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []models.ActivityMute{
+			{Kind: "rating_added"},
+			{GroupId: group.Id},
+			{GroupId: group.Id, TitleId: "tt1"},
+		}, saved.Mutes, "kinds first, then groups before their titles, and a repeated mute is one")
+		require.Equal(t, &models.QuietHours{StartMinute: 1320, EndMinute: 420, TimeZone: "Europe/Lisbon"}, saved.QuietHours)
+
+		saved, err = s.SetActivityPreferences(ctx, models.ActivityPreferences{
+			UserId: owner,
+			Mutes:  []models.ActivityMute{{Kind: "comment_added"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []models.ActivityMute{{Kind: "comment_added"}}, saved.Mutes, "a mute left out is unmuted")
+		require.Nil(t, saved.QuietHours, "quiet hours left out are off")
+	})
+
+	t.Run("muted events leave the feed, the badge and marking read, and unmuting restores them", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		actor := addTestUser(t, s)
+		reader := addTestUser(t, s)
+		films, err := s.CreateGroup(ctx, newTestGroup(t, "films", actor))
+		require.NoError(t, err)
+		series, err := s.CreateGroup(ctx, newTestGroup(t, "series", actor))
+		require.NoError(t, err)
+		for _, g := range []string{films.Id, series.Id} {
+			require.NoError(t, s.AddUserToGroup(ctx, g, actor, reader))
+		}
+
+		tt1, tt2 := "tt1", "tt2"
+		require.NoError(t, s.InsertActivityEvents(ctx, []models.ActivityEvent{
+			{Id: "e1", GroupId: films.Id, ActorId: actor, ActorName: "a", Kind: "title_added", TitleId: &tt1},
+			{Id: "e2", GroupId: films.Id, ActorId: actor, ActorName: "a", Kind: "title_added", TitleId: &tt2},
+			{Id: "e3", GroupId: series.Id, ActorId: actor, ActorName: "a", Kind: "title_added", TitleId: &tt1},
+			{Id: "e4", GroupId: series.Id, ActorId: actor, ActorName: "a", Kind: "comment_added", TitleId: &tt2},
+		}))
+
+		ids := func() []string {
+			t.Helper()
+			feed, err := s.GetActivityFeed(ctx, reader, models.ActivityFilter{}, nil, 50)
+			require.NoError(t, err)
+			out := []string{}
+			for _, e := range feed {
+				out = append(out, e.Id)
+			}
+			return out
+		}
+		unread := func() int64 {
+			t.Helper()
+			n, err := s.GetActivityUnreadCount(ctx, reader)
+			require.NoError(t, err)
+			return n
+		}
+
+		_, err = s.SetActivityPreferences(ctx, models.ActivityPreferences{
+			UserId: reader,
+			Mutes: []models.ActivityMute{
+				{Kind: "comment_added"},
+				{GroupId: films.Id, TitleId: tt1},
+			},
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"e3", "e2"}, ids(), "the kind is muted everywhere, the title only in its group")
+		require.Equal(t, int64(2), unread(), "the badge counts what the feed shows")
+		require.ErrorIs(t, s.MarkActivityEventRead(ctx, reader, "e1"), store.ErrRecordNotFound,
+			"a muted event cannot be marked read, as one the reader cannot see")
+
+		history, err := s.GetActivityHistory(ctx, reader, models.ActivityFilter{GroupIds: []string{films.Id}}, nil, 50)
+		require.NoError(t, err)
+		require.Len(t, history, 2, "a group's own history is not narrowed by mutes")
+
+		_, err = s.SetActivityPreferences(ctx, models.ActivityPreferences{
+			UserId: reader,
+			Mutes:  []models.ActivityMute{{GroupId: series.Id}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"e2", "e1"}, ids(), "unmuting puts events back as they were; the group mute takes the rest")
+		require.Equal(t, int64(2), unread())
+
+		_, err = s.SetActivityPreferences(ctx, models.ActivityPreferences{UserId: reader})
+		require.NoError(t, err)
+		require.Len(t, ids(), 4)
+
+		// The actor muted nothing: one reader's mutes are theirs alone.
+		_, err = s.SetActivityPreferences(ctx, models.ActivityPreferences{UserId: actor, Mutes: []models.ActivityMute{{GroupId: films.Id}}})
+		require.NoError(t, err)
+		require.Equal(t, int64(4), unread())
+	})
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/lealre/movies-backend/internal/activity"
 	"github.com/lealre/movies-backend/internal/models"
 	"github.com/lealre/movies-backend/internal/store"
 )
@@ -19,7 +20,7 @@ import (
 func readStateById(t *testing.T, s *Store, userId string) map[string]bool {
 	t.Helper()
 
-	feed, err := s.GetActivityFeed(context.Background(), userId, nil, 100)
+	feed, err := s.GetActivityFeed(context.Background(), userId, models.ActivityFilter{}, nil, 100)
 	require.NoError(t, err, "failed to read the feed")
 
 	state := map[string]bool{}
@@ -46,7 +47,7 @@ func TestStore_ActivityEvents(t *testing.T) {
 			{Id: "e2", GroupId: group.Id, ActorId: reader, ActorName: "reader", Kind: "rating_added"},
 		}), "failed to insert the events")
 
-		feed, err := s.GetActivityFeed(ctx, reader, nil, 50)
+		feed, err := s.GetActivityFeed(ctx, reader, models.ActivityFilter{}, nil, 50)
 		require.NoError(t, err)
 		require.Len(t, feed, 1, "the reader must see exactly the actor's event, not their own")
 		require.Equal(t, "e1", feed[0].Id)
@@ -66,7 +67,7 @@ func TestStore_ActivityEvents(t *testing.T) {
 			{Id: "e1", GroupId: group.Id, ActorId: actor, ActorName: "actor", Kind: "title_added"},
 		}))
 
-		feed, err := s.GetActivityFeed(ctx, outsider, nil, 50)
+		feed, err := s.GetActivityFeed(ctx, outsider, models.ActivityFilter{}, nil, 50)
 		require.NoError(t, err)
 		require.Empty(t, feed, "a non-member must see none of the group's events")
 		require.NotNil(t, feed, "read-many must return an empty slice, never nil")
@@ -90,13 +91,13 @@ func TestStore_ActivityEvents(t *testing.T) {
 			{Id: "e1", GroupId: group.Id, ActorId: actor, ActorName: "actor", Kind: "title_added"},
 		}))
 
-		feed, err := s.GetActivityFeed(ctx, reader, nil, 50)
+		feed, err := s.GetActivityFeed(ctx, reader, models.ActivityFilter{}, nil, 50)
 		require.NoError(t, err)
 		require.Len(t, feed, 1, "while still a member, the reader must see the actor's event")
 
 		require.NoError(t, s.RemoveUserFromGroup(ctx, group.Id, reader))
 
-		feed, err = s.GetActivityFeed(ctx, reader, nil, 50)
+		feed, err = s.GetActivityFeed(ctx, reader, models.ActivityFilter{}, nil, 50)
 		require.NoError(t, err)
 		require.Empty(t, feed, "a departed member must lose the group's history immediately")
 		require.NotNil(t, feed, "read-many must return an empty slice, never nil")
@@ -280,7 +281,7 @@ func TestStore_ActivityEvents(t *testing.T) {
 		seen := map[string]int{}
 		var before *int64
 		for {
-			page, err := s.GetActivityFeed(ctx, reader, before, 10)
+			page, err := s.GetActivityFeed(ctx, reader, models.ActivityFilter{}, before, 10)
 			require.NoError(t, err)
 			if len(page) == 0 {
 				break
@@ -296,6 +297,170 @@ func TestStore_ActivityEvents(t *testing.T) {
 			require.Equal(t, 1, n, "event %s was returned %d times", id, n)
 		}
 	})
+
+	t.Run("events since a seq come back oldest first, with the feed's visibility and read state", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		actor := addTestUser(t, s)
+		reader := addTestUser(t, s)
+		group, err := s.CreateGroup(ctx, newTestGroup(t, "resume", actor))
+		require.NoError(t, err)
+		require.NoError(t, s.AddUserToGroup(ctx, group.Id, actor, reader))
+		require.NoError(t, s.InsertActivityEvents(ctx, []models.ActivityEvent{
+			{Id: "e1", GroupId: group.Id, ActorId: actor, ActorName: "a", Kind: "title_added"},
+			{Id: "e2", GroupId: group.Id, ActorId: actor, ActorName: "a", Kind: "title_added"},
+			{Id: "own", GroupId: group.Id, ActorId: reader, ActorName: "r", Kind: "title_added"},
+			{Id: "e3", GroupId: group.Id, ActorId: actor, ActorName: "a", Kind: "title_added"},
+			{Id: "e4", GroupId: group.Id, ActorId: actor, ActorName: "a", Kind: "title_added"},
+		}))
+		require.NoError(t, s.MarkActivityEventRead(ctx, reader, "e3"))
+
+		all, err := s.GetActivityEventsSince(ctx, reader, 0, 10)
+		require.NoError(t, err)
+		ids := make([]string, len(all))
+		for i, e := range all {
+			ids[i] = e.Id
+		}
+		require.Equal(t, []string{"e1", "e2", "e3", "e4"}, ids, "oldest first, and never the reader's own")
+
+		since, err := s.GetActivityEventsSince(ctx, reader, all[0].Seq, 2)
+		require.NoError(t, err)
+		require.Len(t, since, 2, "the limit caps the replay")
+		require.Equal(t, "e2", since[0].Id, "the seq given is exclusive")
+		require.True(t, since[1].Read, "a replayed event carries the reader's read state")
+	})
+
+	t.Run("a group's deletion stays visible to the members it names", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		owner := addTestUser(t, s)
+		reader := addTestUser(t, s)
+		outsider := addTestUser(t, s)
+		group, err := s.CreateGroup(ctx, newTestGroup(t, "doomed", owner))
+		require.NoError(t, err)
+		require.NoError(t, s.AddUserToGroup(ctx, group.Id, owner, reader))
+		require.NoError(t, s.InsertActivityEvents(ctx, []models.ActivityEvent{
+			{Id: "before", GroupId: group.Id, ActorId: owner, ActorName: "o", Kind: "title_added"},
+		}))
+
+		// What deleting a group does, in order: the group goes, then every
+		// membership, and only then is the event recorded.
+		require.NoError(t, s.SoftDeleteGroup(ctx, group.Id))
+		require.NoError(t, s.RemoveGroupFromUser(ctx, reader, group.Id))
+		require.NoError(t, s.RemoveGroupFromUser(ctx, owner, group.Id))
+		require.NoError(t, s.InsertActivityEvents(ctx, []models.ActivityEvent{
+			{Id: "deleted", GroupId: group.Id, ActorId: owner, ActorName: "o", Kind: "group_deleted",
+				Payload: map[string]any{"memberIds": []string{owner, reader}}},
+		}))
+
+		feed, err := s.GetActivityFeed(ctx, reader, models.ActivityFilter{}, nil, 50)
+		require.NoError(t, err)
+		require.Len(t, feed, 1, "the deletion, and nothing else from the deleted group")
+		require.Equal(t, "deleted", feed[0].Id)
+		require.Equal(t, "doomed", feed[0].GroupName)
+
+		n, err := s.GetActivityUnreadCount(ctx, reader)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n, "the badge counts it like any other event")
+		require.NoError(t, s.MarkActivityEventRead(ctx, reader, "deleted"))
+
+		for _, userId := range []string{owner, outsider} {
+			feed, err := s.GetActivityFeed(ctx, userId, models.ActivityFilter{}, nil, 50)
+			require.NoError(t, err)
+			require.Empty(t, feed, "neither its actor nor someone never in the group sees it")
+		}
+	})
+
+	t.Run("every filter narrows the feed, and they combine", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		alice := addTestUser(t, s)
+		bob := addTestUser(t, s)
+		reader := addTestUser(t, s)
+		films, err := s.CreateGroup(ctx, newTestGroup(t, "films", alice))
+		require.NoError(t, err)
+		series, err := s.CreateGroup(ctx, newTestGroup(t, "series", alice))
+		require.NoError(t, err)
+		for _, g := range []string{films.Id, series.Id} {
+			require.NoError(t, s.AddUserToGroup(ctx, g, alice, bob))
+			require.NoError(t, s.AddUserToGroup(ctx, g, alice, reader))
+		}
+
+		title := "tt1"
+		day := func(d int) time.Time { return time.Date(2026, time.March, d, 12, 0, 0, 0, time.UTC) }
+		require.NoError(t, s.InsertActivityEvents(ctx, []models.ActivityEvent{
+			{Id: "e1", GroupId: films.Id, ActorId: alice, ActorName: "a", Kind: "title_added", TitleId: &title, CreatedAt: day(1)},
+			{Id: "e2", GroupId: films.Id, ActorId: bob, ActorName: "b", Kind: "rating_added", TitleId: &title, CreatedAt: day(2)},
+			{Id: "e3", GroupId: series.Id, ActorId: alice, ActorName: "a", Kind: "rating_added", CreatedAt: day(3)},
+			{Id: "e4", GroupId: series.Id, ActorId: bob, ActorName: "b", Kind: "comment_added", TitleId: &title, CreatedAt: day(4)},
+		}))
+
+		ids := func(filter models.ActivityFilter) []string {
+			t.Helper()
+			feed, err := s.GetActivityFeed(ctx, reader, filter, nil, 50)
+			require.NoError(t, err)
+			out := []string{}
+			for _, e := range feed {
+				out = append(out, e.Id)
+			}
+			return out
+		}
+		after, before := day(2), day(4)
+
+		require.Equal(t, []string{"e4", "e3", "e2", "e1"}, ids(models.ActivityFilter{}), "the zero filter is the whole feed")
+		require.Equal(t, []string{"e2", "e1"}, ids(models.ActivityFilter{GroupIds: []string{films.Id}}))
+		require.Equal(t, []string{"e4", "e2", "e1"}, ids(models.ActivityFilter{TitleId: &title}))
+		require.Equal(t, []string{"e4", "e1"}, ids(models.ActivityFilter{Kinds: []string{"title_added", "comment_added"}}))
+		require.Equal(t, []string{"e3", "e1"}, ids(models.ActivityFilter{ActorId: &alice}))
+		require.Equal(t, []string{"e3", "e2"}, ids(models.ActivityFilter{CreatedAfter: &after, CreatedBefore: &before}),
+			"createdAfter is inclusive and createdBefore exclusive")
+		require.Equal(t, []string{"e2"}, ids(models.ActivityFilter{TitleId: &title, ActorId: &bob, GroupIds: []string{films.Id}}),
+			"set filters must all hold")
+		require.Empty(t, ids(models.ActivityFilter{GroupIds: []string{"not-my-group"}}), "a group the reader is not in matches nothing")
+	})
+
+	t.Run("history includes the reader's own actions, read", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		actor := addTestUser(t, s)
+		reader := addTestUser(t, s)
+		outsider := addTestUser(t, s)
+		group, err := s.CreateGroup(ctx, newTestGroup(t, "history", actor))
+		require.NoError(t, err)
+		other, err := s.CreateGroup(ctx, newTestGroup(t, "other", actor))
+		require.NoError(t, err)
+		require.NoError(t, s.AddUserToGroup(ctx, group.Id, actor, reader))
+		require.NoError(t, s.InsertActivityEvents(ctx, []models.ActivityEvent{
+			{Id: "e1", GroupId: group.Id, ActorId: actor, ActorName: "a", Kind: "title_added"},
+			{Id: "own", GroupId: group.Id, ActorId: reader, ActorName: "r", Kind: "rating_added"},
+			{Id: "elsewhere", GroupId: other.Id, ActorId: actor, ActorName: "a", Kind: "title_added"},
+		}))
+
+		history, err := s.GetActivityHistory(ctx, reader, models.ActivityFilter{GroupIds: []string{group.Id}}, nil, 50)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		require.Equal(t, "own", history[0].Id, "the reader's own action is part of the group's history")
+		require.True(t, history[0].Read, "an own action was never unread")
+		require.Equal(t, "e1", history[1].Id)
+		require.False(t, history[1].Read, "everyone else's read state is the feed's")
+
+		history, err = s.GetActivityHistory(ctx, outsider, models.ActivityFilter{GroupIds: []string{group.Id}}, nil, 50)
+		require.NoError(t, err)
+		require.Empty(t, history, "a non-member sees no history, whatever group they name")
+		require.NotNil(t, history, "read-many must return an empty slice, never nil")
+
+		n, err := s.GetActivityUnreadCount(ctx, reader)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n, "the badge still counts only other members' events")
+	})
 }
 
 func TestStore_InsertActivityEvents_Notifies(t *testing.T) {
@@ -381,3 +546,78 @@ func TestStore_GetActivityEventById(t *testing.T) {
 		require.ErrorIs(t, err, store.ErrRecordNotFound)
 	})
 }
+
+func TestStore_CoalesceActivityEvents(t *testing.T) {
+	t.Run("an edit merges into the one before it, which is gone with its read state", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		actor := addTestUser(t, s)
+		reader := addTestUser(t, s)
+		group, err := s.CreateGroup(ctx, newTestGroup(t, "edits", actor))
+		require.NoError(t, err)
+		require.NoError(t, s.AddUserToGroup(ctx, group.Id, actor, reader))
+		require.NoError(t, s.MarkAllActivityEventsRead(ctx, reader))
+
+		sink := activity.NewCoalescer(s, 2*time.Minute, activity.NewStoreSink(s))
+		season := 2
+		stamp := func(id string, e activity.Event, at time.Time) []models.ActivityEvent {
+			return []models.ActivityEvent{{Id: id, GroupId: e.GroupId, ActorId: actor, ActorName: "a", Kind: e.Kind,
+				TitleId: e.TitleId, TitleName: e.TitleName, Payload: e.Payload, CreatedAt: at}}
+		}
+		start := time.Now().Add(-time.Hour)
+
+		require.NoError(t, sink.Append(ctx, stamp("e1", activity.RatingUpdated(group.Id, "tt1", "Dune", 7.5, 7.0, &season), start)))
+		require.NoError(t, s.MarkActivityEventRead(ctx, reader, "e1"))
+		require.NoError(t, sink.Append(ctx, stamp("e2", activity.RatingUpdated(group.Id, "tt1", "Dune", 8.0, 7.5, &season), start.Add(time.Minute))))
+
+		feed, err := s.GetActivityFeed(ctx, reader, models.ActivityFilter{}, nil, 50)
+		require.NoError(t, err)
+		require.Len(t, feed, 1, "two edits within the window are one event")
+		require.Equal(t, "e2", feed[0].Id)
+		require.Equal(t, map[string]any{"note": 8.0, "previousNote": 7.0, "season": float64(season)}, feed[0].Payload)
+		require.False(t, feed[0].Read, "the merged event is news, whatever was read of the first")
+
+		_, err = s.GetActivityEventById(ctx, "e1")
+		require.ErrorIs(t, err, store.ErrRecordNotFound, "the earlier event is deleted, not hidden")
+
+		require.NoError(t, sink.Append(ctx, stamp("e3", activity.RatingUpdated(group.Id, "tt1", "Dune", 7.0, 8.0, &season), start.Add(2*time.Minute))))
+		n, err := s.GetActivityUnreadCount(ctx, reader)
+		require.NoError(t, err)
+		require.Zero(t, n, "back where it started is no change, and leaves nothing")
+	})
+
+	t.Run("the lookup is bounded by the window and skips the event itself", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		actor := addTestUser(t, s)
+		group, err := s.CreateGroup(ctx, newTestGroup(t, "edits", actor))
+		require.NoError(t, err)
+
+		at := time.Now().Add(-time.Hour)
+		edit := activity.RatingUpdated(group.Id, "tt1", "Dune", 8, 7, nil)
+		stored := models.ActivityEvent{Id: "e1", GroupId: group.Id, ActorId: actor, ActorName: "a", Kind: edit.Kind,
+			TitleId: edit.TitleId, TitleName: edit.TitleName, Payload: edit.Payload, CreatedAt: at}
+		require.NoError(t, s.InsertActivityEvents(ctx, []models.ActivityEvent{stored}))
+
+		next := stored
+		next.Id = "e2"
+		found, err := s.GetCoalescibleActivityEvent(ctx, next, at)
+		require.NoError(t, err)
+		require.Equal(t, "e1", found.Id)
+
+		_, err = s.GetCoalescibleActivityEvent(ctx, next, at.Add(time.Second))
+		require.ErrorIs(t, err, store.ErrRecordNotFound, "an event before the window opened is not merged into")
+
+		_, err = s.GetCoalescibleActivityEvent(ctx, stored, at)
+		require.ErrorIs(t, err, store.ErrRecordNotFound, "an event redelivered is not merged into itself")
+
+		season := 1
+		next.Payload = map[string]any{"note": 8.0, "previousNote": 7.0, "season": season}
+		_, err = s.GetCoalescibleActivityEvent(ctx, next, at)
+		require.ErrorIs(t, err, store.ErrRecordNotFound, "a season is another thing than the whole title")
+	})
+}
@@ -0,0 +1,474 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/lealre/movies-backend/internal/database"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+var _ store.Archive = (*Store)(nil)
+
+func (s *Store) ArchiveIsEmpty(ctx context.Context) (bool, error) {
+	return s.q.ArchiveIsEmpty(ctx)
+}
+
+func (s *Store) ListUsers(ctx context.Context, afterId string, limit int) ([]models.User, error) {
+	rows, err := s.q.ListUserRows(ctx, database.ListUserRowsParams{AfterID: afterId, RowLimit: int64(limit)})
+	if err != nil {
+		return nil, err
+	}
+	users := make([]models.User, len(rows))
+	for i, row := range rows {
+		// Groups is left out: it is membership seen from the user's side, and
+		// the archive carries membership on the groups.
+		users[i] = userRowToModel(row, nil)
+	}
+	return users, nil
+}
+
+func (s *Store) ListTitles(ctx context.Context, afterId string, limit int) ([]models.Title, error) {
+	rows, err := s.q.ListTitleRows(ctx, database.ListTitleRowsParams{AfterID: afterId, RowLimit: int64(limit)})
+	if err != nil {
+		return nil, err
+	}
+	titles := make([]models.Title, len(rows))
+	for i, row := range rows {
+		t, err := rowToTitle(row)
+		if err != nil {
+			return nil, err
+		}
+		titles[i] = t
+	}
+	return titles, nil
+}
+
+func (s *Store) ListGroups(ctx context.Context, afterId string, limit int) ([]models.Group, error) {
+	rows, err := s.q.ListGroupRows(ctx, database.ListGroupRowsParams{AfterID: afterId, RowLimit: int64(limit)})
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]models.Group, len(rows))
+	for i, row := range rows {
+		users, err := s.q.GetGroupMemberIds(ctx, row.ID)
+		if err != nil {
+			return nil, err
+		}
+		titles, err := s.assembleGroupTitles(ctx, row.ID)
+		if err != nil {
+			return nil, err
+		}
+		groups[i] = groupRowToModel(row, users, titles)
+	}
+	return groups, nil
+}
+
+func (s *Store) ListRecommendationSharing(ctx context.Context, afterGroupId string, limit int) ([]models.RecommendationSharing, error) {
+	rows, err := s.q.ListRecommendationSharingRows(ctx, database.ListRecommendationSharingRowsParams{AfterID: afterGroupId, RowLimit: int64(limit)})
+	if err != nil {
+		return nil, err
+	}
+	sharing := make([]models.RecommendationSharing, len(rows))
+	for i, row := range rows {
+		sharing[i] = models.RecommendationSharing{GroupId: row.GroupID, EnabledBy: row.EnabledBy, EnabledAt: row.EnabledAt.Time}
+	}
+	return sharing, nil
+}
+
+func (s *Store) ListStreamingPreferences(ctx context.Context, afterUserId string, limit int) ([]models.StreamingPreferences, error) {
+	rows, err := s.q.ListUserStreamingServiceRows(ctx, database.ListUserStreamingServiceRowsParams{AfterID: afterUserId, RowLimit: int64(limit)})
+	if err != nil {
+		return nil, err
+	}
+	prefs := make([]models.StreamingPreferences, len(rows))
+	for i, row := range rows {
+		prefs[i] = streamingPreferencesFromRow(row)
+	}
+	return prefs, nil
+}
+
+func (s *Store) ListRatings(ctx context.Context, afterId string, limit int) ([]models.UserRating, error) {
+	rows, err := s.q.ListRatingRows(ctx, database.ListRatingRowsParams{AfterID: afterId, RowLimit: int64(limit)})
+	if err != nil {
+		return nil, err
+	}
+	return s.assembleRatingRows(ctx, rows)
+}
+
+func (s *Store) ListComments(ctx context.Context, afterId string, limit int) ([]models.Comment, error) {
+	rows, err := s.q.ListCommentRows(ctx, database.ListCommentRowsParams{AfterID: afterId, RowLimit: int64(limit)})
+	if err != nil {
+		return nil, err
+	}
+	return s.assembleCommentRows(ctx, rows)
+}
+
+func (s *Store) ListActivityEvents(ctx context.Context, afterSeq int64, limit int) ([]models.ActivityEvent, error) {
+	rows, err := s.q.ListActivityEventRows(ctx, database.ListActivityEventRowsParams{AfterSeq: afterSeq, RowLimit: int64(limit)})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]models.ActivityEvent, len(rows))
+	for i, row := range rows {
+		// GroupName and Read describe the event as one reader sees it, which
+		// an archive has no reader for; both are left zero.
+		event, err := activityEventRowToModel(database.GetActivityFeedRowsRow{
+			ID:        row.ID,
+			Seq:       row.Seq,
+			GroupID:   row.GroupID,
+			ActorID:   row.ActorID,
+			ActorName: row.ActorName,
+			Kind:      row.Kind,
+			TitleID:   row.TitleID,
+			TitleName: row.TitleName,
+			Payload:   row.Payload,
+			CreatedAt: row.CreatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		events[i] = event
+	}
+	return events, nil
+}
+
+func (s *Store) ListActivityEventReads(ctx context.Context, afterUserId, afterEventId string, limit int) ([]models.ActivityEventRead, error) {
+	rows, err := s.q.ListActivityEventReadRows(ctx, database.ListActivityEventReadRowsParams{
+		AfterUserID:  afterUserId,
+		AfterEventID: afterEventId,
+		RowLimit:     int64(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	reads := make([]models.ActivityEventRead, len(rows))
+	for i, row := range rows {
+		reads[i] = models.ActivityEventRead{UserId: row.UserID, EventId: row.EventID, ReadAt: row.ReadAt.Time}
+	}
+	return reads, nil
+}
+
+func (s *Store) ListActivityReadFloors(ctx context.Context, afterUserId string, limit int) ([]models.ActivityReadFloor, error) {
+	rows, err := s.q.ListActivityReadFloorRows(ctx, database.ListActivityReadFloorRowsParams{AfterID: afterUserId, RowLimit: int64(limit)})
+	if err != nil {
+		return nil, err
+	}
+	floors := make([]models.ActivityReadFloor, len(rows))
+	for i, row := range rows {
+		floors[i] = models.ActivityReadFloor{UserId: row.UserID, FloorSeq: row.FloorSeq, ReadAt: row.ReadAt.Time}
+	}
+	return floors, nil
+}
+
+func (s *Store) ListActivityPreferences(ctx context.Context, afterUserId string, limit int) ([]models.ActivityPreferences, error) {
+	userIds, err := s.q.ListActivityPreferenceUserIds(ctx, database.ListActivityPreferenceUserIdsParams{AfterID: afterUserId, RowLimit: int64(limit)})
+	if err != nil {
+		return nil, err
+	}
+	prefs := make([]models.ActivityPreferences, len(userIds))
+	for i, userId := range userIds {
+		p, err := s.GetActivityPreferences(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		prefs[i] = p
+	}
+	return prefs, nil
+}
+
+func (s *Store) ListActivityDigests(ctx context.Context, afterUserId string, limit int) ([]models.ActivityDigest, error) {
+	rows, err := s.q.ListActivityDigestRows(ctx, database.ListActivityDigestRowsParams{AfterID: afterUserId, RowLimit: int64(limit)})
+	if err != nil {
+		return nil, err
+	}
+	digests := make([]models.ActivityDigest, len(rows))
+	for i, row := range rows {
+		digests[i] = activityDigestRowToModel(row)
+	}
+	return digests, nil
+}
+
+func (s *Store) RestoreUsers(ctx context.Context, users []models.User) error {
+	return s.inTx(ctx, func(q *database.Queries) error {
+		for _, user := range users {
+			if err := q.CreateUser(ctx, database.CreateUserParams{
+				ID:           user.Id,
+				Name:         user.Name,
+				Email:        user.Email,
+				Username:     user.Username,
+				PasswordHash: user.PasswordHash,
+				AvatarUrl:    ptrToText(user.AvatarURL),
+				Role:         string(user.Role),
+				IsActive:     user.IsActive,
+				LastLoginAt:  ptrToTimestamptz(user.LastLoginAt),
+				CreatedAt:    timeToTimestamptz(user.CreatedAt),
+				UpdatedAt:    timeToTimestamptz(user.UpdatedAt),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) RestoreTitles(ctx context.Context, titles []models.Title) error {
+	return s.inTx(ctx, func(q *database.Queries) error {
+		for _, title := range titles {
+			params, err := titleToRow(title)
+			if err != nil {
+				return err
+			}
+			if err := q.InsertTitle(ctx, params); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) RestoreGroups(ctx context.Context, groups []models.Group) error {
+	return s.inTx(ctx, func(q *database.Queries) error {
+		for _, group := range groups {
+			if err := q.RestoreGroupRow(ctx, database.RestoreGroupRowParams{
+				ID:          group.Id,
+				Name:        group.Name,
+				Description: group.Description,
+				OwnerID:     group.OwnerId,
+				Deleted:     group.Deleted,
+				DeletedAt:   ptrToTimestamptz(group.DeletedAt),
+				CreatedAt:   timeToTimestamptz(group.CreatedAt),
+				UpdatedAt:   timeToTimestamptz(group.UpdatedAt),
+			}); err != nil {
+				return err
+			}
+			for _, userId := range group.Users {
+				if err := q.AddGroupMember(ctx, database.AddGroupMemberParams{GroupID: group.Id, UserID: userId}); err != nil {
+					return err
+				}
+			}
+			for titleId, item := range group.Titles {
+				if _, err := q.UpsertGroupTitle(ctx, database.UpsertGroupTitleParams{
+					GroupID:   group.Id,
+					TitleID:   titleId,
+					Watched:   item.Watched,
+					WatchedAt: ptrToTimestamptz(item.WatchedAt),
+					AddedAt:   timeToTimestamptz(item.AddedAt),
+					UpdatedAt: timeToTimestamptz(item.UpdatedAt),
+				}); err != nil {
+					return err
+				}
+				if item.SeasonsWatched == nil {
+					continue
+				}
+				for season, seasonItem := range *item.SeasonsWatched {
+					if _, err := q.UpsertGroupTitleSeason(ctx, database.UpsertGroupTitleSeasonParams{
+						GroupID:   group.Id,
+						TitleID:   titleId,
+						Season:    season,
+						Watched:   seasonItem.Watched,
+						WatchedAt: ptrToTimestamptz(seasonItem.WatchedAt),
+						AddedAt:   timeToTimestamptz(seasonItem.AddedAt),
+						UpdatedAt: timeToTimestamptz(seasonItem.UpdatedAt),
+					}); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) RestoreRecommendationSharing(ctx context.Context, sharing []models.RecommendationSharing) error {
+	return s.inTx(ctx, func(q *database.Queries) error {
+		for _, consent := range sharing {
+			if err := q.RestoreRecommendationSharingRow(ctx, database.RestoreRecommendationSharingRowParams{
+				GroupID:   consent.GroupId,
+				EnabledBy: consent.EnabledBy,
+				EnabledAt: timeToTimestamptz(consent.EnabledAt),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) RestoreStreamingPreferences(ctx context.Context, prefs []models.StreamingPreferences) error {
+	return s.inTx(ctx, func(q *database.Queries) error {
+		for _, p := range prefs {
+			serviceIds := make([]int32, 0, len(p.ServiceIDs))
+			for _, id := range p.ServiceIDs {
+				serviceIds = append(serviceIds, clampToInt32(id))
+			}
+			if _, err := q.UpsertUserStreamingServices(ctx, database.UpsertUserStreamingServicesParams{
+				UserID:     p.UserID,
+				Region:     p.Region,
+				ServiceIds: serviceIds,
+				UpdatedAt:  timeToTimestamptz(p.UpdatedAt),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) RestoreRatings(ctx context.Context, ratings []models.UserRating) error {
+	return s.inTx(ctx, func(q *database.Queries) error {
+		for _, rating := range ratings {
+			if _, err := q.InsertRating(ctx, database.InsertRatingParams{
+				ID:        rating.Id,
+				TitleID:   rating.TitleId,
+				UserID:    rating.UserId,
+				GroupID:   rating.GroupId,
+				Note:      rating.Note,
+				CreatedAt: timeToTimestamptz(rating.CreatedAt),
+				UpdatedAt: timeToTimestamptz(rating.UpdatedAt),
+			}); err != nil {
+				return err
+			}
+			if err := insertRatingSeasons(ctx, q, rating.Id, rating.SeasonsRatings); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) RestoreComments(ctx context.Context, comments []models.Comment) error {
+	return s.inTx(ctx, func(q *database.Queries) error {
+		for _, comment := range comments {
+			if _, err := q.InsertComment(ctx, database.InsertCommentParams{
+				ID:        comment.Id,
+				TitleID:   comment.TitleId,
+				UserID:    comment.UserId,
+				GroupID:   comment.GroupId,
+				Comment:   ptrToText(comment.Comment),
+				CreatedAt: timeToTimestamptz(comment.CreatedAt),
+				UpdatedAt: timeToTimestamptz(comment.UpdatedAt),
+			}); err != nil {
+				return err
+			}
+			if err := insertCommentSeasons(ctx, q, comment.Id, comment.SeasonsComments); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RestoreActivityEvents writes events with their own Seq, and then moves the
+// seq sequence past the highest one, so that the next event recorded live
+// does not collide with a restored one. Nothing is notified: no one can be
+// listening for events that happened before the restore.
+func (s *Store) RestoreActivityEvents(ctx context.Context, events []models.ActivityEvent) error {
+	return s.inTx(ctx, func(q *database.Queries) error {
+		for _, e := range events {
+			payload := []byte(`{}`)
+			if e.Payload != nil {
+				var err error
+				payload, err = json.Marshal(e.Payload)
+				if err != nil {
+					return err
+				}
+			}
+			if err := q.RestoreActivityEventRow(ctx, database.RestoreActivityEventRowParams{
+				ID:        e.Id,
+				Seq:       e.Seq,
+				GroupID:   e.GroupId,
+				ActorID:   e.ActorId,
+				ActorName: e.ActorName,
+				Kind:      e.Kind,
+				TitleID:   ptrToText(e.TitleId),
+				TitleName: ptrToText(e.TitleName),
+				Payload:   payload,
+				CreatedAt: timeToTimestamptz(e.CreatedAt),
+			}); err != nil {
+				return err
+			}
+		}
+		return q.ResetActivityEventSeq(ctx)
+	})
+}
+
+func (s *Store) RestoreActivityEventReads(ctx context.Context, reads []models.ActivityEventRead) error {
+	return s.inTx(ctx, func(q *database.Queries) error {
+		for _, r := range reads {
+			if err := q.RestoreActivityEventReadRow(ctx, database.RestoreActivityEventReadRowParams{
+				UserID:  r.UserId,
+				EventID: r.EventId,
+				ReadAt:  timeToTimestamptz(r.ReadAt),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) RestoreActivityReadFloors(ctx context.Context, floors []models.ActivityReadFloor) error {
+	return s.inTx(ctx, func(q *database.Queries) error {
+		for _, f := range floors {
+			if err := q.RestoreActivityReadFloorRow(ctx, database.RestoreActivityReadFloorRowParams{
+				UserID:   f.UserId,
+				FloorSeq: f.FloorSeq,
+				ReadAt:   timeToTimestamptz(f.ReadAt),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RestoreActivityPreferences writes the rows SetActivityPreferences does, but
+// sends no notification: nothing is listening to a store being restored.
+func (s *Store) RestoreActivityPreferences(ctx context.Context, prefs []models.ActivityPreferences) error {
+	return s.inTx(ctx, func(q *database.Queries) error {
+		for _, p := range prefs {
+			for _, mute := range p.Mutes {
+				if err := q.InsertActivityMute(ctx, database.InsertActivityMuteParams{
+					UserID:  p.UserId,
+					GroupID: stringToText(mute.GroupId),
+					TitleID: stringToText(mute.TitleId),
+					Kind:    stringToText(mute.Kind),
+				}); err != nil {
+					return err
+				}
+			}
+			if p.QuietHours == nil {
+				continue
+			}
+			if err := q.UpsertActivityQuietHours(ctx, database.UpsertActivityQuietHoursParams{
+				UserID:      p.UserId,
+				StartMinute: int16(p.QuietHours.StartMinute),
+				EndMinute:   int16(p.QuietHours.EndMinute),
+				TimeZone:    p.QuietHours.TimeZone,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) RestoreActivityDigests(ctx context.Context, digests []models.ActivityDigest) error {
+	return s.inTx(ctx, func(q *database.Queries) error {
+		for _, d := range digests {
+			if err := q.RestoreActivityDigestRow(ctx, database.RestoreActivityDigestRowParams{
+				UserID:      d.UserId,
+				Frequency:   d.Frequency,
+				DigestedSeq: d.DigestedSeq,
+				NextDueAt:   timeToTimestamptz(d.NextDueAt),
+				LastSentAt:  ptrToTimestamptz(d.LastSentAt),
+				CreatedAt:   timeToTimestamptz(d.CreatedAt),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,333 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+// instanceDump is everything the Archive List methods return, read with a
+// page size of 2 so that every kind with more than two records is paged.
+type instanceDump struct {
+	Users       []models.User
+	Titles      []models.Title
+	Groups      []models.Group
+	Sharing     []models.RecommendationSharing
+	Preferences []models.StreamingPreferences
+	Ratings     []models.UserRating
+	Comments    []models.Comment
+	Events      []models.ActivityEvent
+	Reads       []models.ActivityEventRead
+	Floors      []models.ActivityReadFloor
+	Mutes       []models.ActivityPreferences
+	Digests     []models.ActivityDigest
+}
+
+func dumpInstance(t *testing.T, s *Store) instanceDump {
+	t.Helper()
+	ctx := context.Background()
+	const page = 2
+	var d instanceDump
+
+	for after := ""; ; {
+		batch, err := s.ListUsers(ctx, after, page)
+		require.NoError(t, err)
+		d.Users = append(d.Users, batch...)
+		if len(batch) < page {
+			break
+		}
+		after = batch[len(batch)-1].Id
+	}
+	for after := ""; ; {
+		batch, err := s.ListTitles(ctx, after, page)
+		require.NoError(t, err)
+		d.Titles = append(d.Titles, batch...)
+		if len(batch) < page {
+			break
+		}
+		after = batch[len(batch)-1].ID
+	}
+	for after := ""; ; {
+		batch, err := s.ListGroups(ctx, after, page)
+		require.NoError(t, err)
+		d.Groups = append(d.Groups, batch...)
+		if len(batch) < page {
+			break
+		}
+		after = batch[len(batch)-1].Id
+	}
+	for after := ""; ; {
+		batch, err := s.ListRecommendationSharing(ctx, after, page)
+		require.NoError(t, err)
+		d.Sharing = append(d.Sharing, batch...)
+		if len(batch) < page {
+			break
+		}
+		after = batch[len(batch)-1].GroupId
+	}
+	for after := ""; ; {
+		batch, err := s.ListStreamingPreferences(ctx, after, page)
+		require.NoError(t, err)
+		d.Preferences = append(d.Preferences, batch...)
+		if len(batch) < page {
+			break
+		}
+		after = batch[len(batch)-1].UserID
+	}
+	for after := ""; ; {
+		batch, err := s.ListRatings(ctx, after, page)
+		require.NoError(t, err)
+		d.Ratings = append(d.Ratings, batch...)
+		if len(batch) < page {
+			break
+		}
+		after = batch[len(batch)-1].Id
+	}
+	for after := ""; ; {
+		batch, err := s.ListComments(ctx, after, page)
+		require.NoError(t, err)
+		d.Comments = append(d.Comments, batch...)
+		if len(batch) < page {
+			break
+		}
+		after = batch[len(batch)-1].Id
+	}
+	for after := int64(0); ; {
+		batch, err := s.ListActivityEvents(ctx, after, page)
+		require.NoError(t, err)
+		d.Events = append(d.Events, batch...)
+		if len(batch) < page {
+			break
+		}
+		after = batch[len(batch)-1].Seq
+	}
+	for afterUser, afterEvent := "", ""; ; {
+		batch, err := s.ListActivityEventReads(ctx, afterUser, afterEvent, page)
+		require.NoError(t, err)
+		d.Reads = append(d.Reads, batch...)
+		if len(batch) < page {
+			break
+		}
+		afterUser, afterEvent = batch[len(batch)-1].UserId, batch[len(batch)-1].EventId
+	}
+	for after := ""; ; {
+		batch, err := s.ListActivityReadFloors(ctx, after, page)
+		require.NoError(t, err)
+		d.Floors = append(d.Floors, batch...)
+		if len(batch) < page {
+			break
+		}
+		after = batch[len(batch)-1].UserId
+	}
+	for after := ""; ; {
+		batch, err := s.ListActivityPreferences(ctx, after, page)
+		require.NoError(t, err)
+		d.Mutes = append(d.Mutes, batch...)
+		if len(batch) < page {
+			break
+		}
+		after = batch[len(batch)-1].UserId
+	}
+	for after := ""; ; {
+		batch, err := s.ListActivityDigests(ctx, after, page)
+		require.NoError(t, err)
+		d.Digests = append(d.Digests, batch...)
+		if len(batch) < page {
+			break
+		}
+		after = batch[len(batch)-1].UserId
+	}
+	return d
+}
+
+func restoreInstance(t *testing.T, s *Store, d instanceDump) {
+	t.Helper()
+	ctx := context.Background()
+	require.NoError(t, s.RestoreUsers(ctx, d.Users))
+	require.NoError(t, s.RestoreTitles(ctx, d.Titles))
+	require.NoError(t, s.RestoreGroups(ctx, d.Groups))
+	require.NoError(t, s.RestoreRecommendationSharing(ctx, d.Sharing))
+	require.NoError(t, s.RestoreStreamingPreferences(ctx, d.Preferences))
+	require.NoError(t, s.RestoreRatings(ctx, d.Ratings))
+	require.NoError(t, s.RestoreComments(ctx, d.Comments))
+	require.NoError(t, s.RestoreActivityEvents(ctx, d.Events))
+	require.NoError(t, s.RestoreActivityEventReads(ctx, d.Reads))
+	require.NoError(t, s.RestoreActivityReadFloors(ctx, d.Floors))
+	require.NoError(t, s.RestoreActivityPreferences(ctx, d.Mutes))
+	require.NoError(t, s.RestoreActivityDigests(ctx, d.Digests))
+}
+
+// seedInstance fills the database with a little of everything an archive
+// carries, through the ordinary Store methods.
+func seedInstance(t *testing.T, s *Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	owner := addTestUser(t, s)
+	member := addTestUser(t, s)
+	loner := addTestUser(t, s)
+
+	movie := newTestMovieTitle(t, "tt-archive-movie", "Archived Movie", 7.5)
+	series := newTestSeriesTitle(t)
+	require.NoError(t, s.AddTitle(ctx, movie))
+	require.NoError(t, s.AddTitle(ctx, series))
+
+	group, err := s.CreateGroup(ctx, newTestGroup(t, "archived", owner))
+	require.NoError(t, err)
+	require.NoError(t, s.AddUserToGroup(ctx, group.Id, owner, member))
+	_, err = s.AddGroupTitles(ctx, group.Id, []string{movie.ID, series.ID})
+	require.NoError(t, err)
+	watchedAt := time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC)
+	_, err = s.UpdateGroupTitleWatchedForMovie(ctx, group.Id, movie.ID, boolPtr(true), flexDate(watchedAt))
+	require.NoError(t, err)
+	_, err = s.UpdateGroupTitleWatchedForTVSeries(ctx, group.Id, series.ID, boolPtr(true), flexDate(watchedAt), 1, owner)
+	require.NoError(t, err)
+
+	deleted, err := s.CreateGroup(ctx, newTestGroup(t, "gone", loner))
+	require.NoError(t, err)
+	require.NoError(t, s.SoftDeleteGroup(ctx, deleted.Id))
+
+	_, err = s.AddRating(ctx, newTestMovieRating(t, movie.ID, owner, group.Id, 8))
+	require.NoError(t, err)
+	_, err = s.AddRating(ctx, newTestSeriesRating(t, series.ID, member, group.Id, map[string]float64{"1": 9, "2": 7}))
+	require.NoError(t, err)
+	_, err = s.AddRating(ctx, newTestMovieRating(t, movie.ID, member, group.Id, 6.5))
+	require.NoError(t, err)
+	_, err = s.AddComment(ctx, newTestMovieComment(t, movie.ID, owner, group.Id, "kept"))
+	require.NoError(t, err)
+	_, err = s.AddComment(ctx, newTestSeriesComment(t, series.ID, member, group.Id, map[string]string{"1": "s1"}))
+	require.NoError(t, err)
+
+	require.NoError(t, s.SetGroupRecommendationSharing(ctx, group.Id, owner, true))
+	_, err = s.SetStreamingPreferences(ctx, models.StreamingPreferences{UserID: member, Region: "US", ServiceIDs: []int{8, 9}, UpdatedAt: watchedAt})
+	require.NoError(t, err)
+
+	require.NoError(t, s.InsertActivityEvents(ctx, []models.ActivityEvent{
+		{Id: "e1", GroupId: group.Id, ActorId: owner, ActorName: "owner", Kind: "title_added", TitleId: &movie.ID, TitleName: &movie.PrimaryTitle},
+		{Id: "e2", GroupId: group.Id, ActorId: owner, ActorName: "owner", Kind: "rating_added", Payload: map[string]any{"note": 8.0}},
+	}))
+	require.NoError(t, s.MarkAllActivityEventsRead(ctx, member))
+	require.NoError(t, s.InsertActivityEvents(ctx, []models.ActivityEvent{
+		{Id: "e3", GroupId: group.Id, ActorId: owner, ActorName: "owner", Kind: "comment_added"},
+	}))
+	require.NoError(t, s.MarkActivityEventRead(ctx, member, "e3"))
+
+	_, err = s.SetActivityPreferences(ctx, models.ActivityPreferences{
+		UserId: member,
+		Mutes:  []models.ActivityMute{{Kind: "rating_added"}, {GroupId: group.Id, TitleId: series.ID}},
+	})
+	require.NoError(t, err)
+	_, err = s.SetActivityPreferences(ctx, models.ActivityPreferences{
+		UserId:     owner,
+		QuietHours: &models.QuietHours{StartMinute: 22 * 60, EndMinute: 7 * 60, TimeZone: "Europe/Lisbon"},
+	})
+	require.NoError(t, err)
+	_, err = s.SetActivityPreferences(ctx, models.ActivityPreferences{
+		UserId:     loner,
+		Mutes:      []models.ActivityMute{{GroupId: deleted.Id}},
+		QuietHours: &models.QuietHours{StartMinute: 0, EndMinute: 6 * 60, TimeZone: "UTC"},
+	})
+	require.NoError(t, err)
+
+	_, err = s.SetActivityDigest(ctx, models.ActivityDigest{UserId: member, Frequency: models.DigestDaily, NextDueAt: watchedAt})
+	require.NoError(t, err)
+	require.NoError(t, s.CompleteActivityDigest(ctx, models.ActivityDigest{UserId: member, DigestedSeq: 2, LastSentAt: &watchedAt, NextDueAt: watchedAt.Add(24 * time.Hour)}))
+	_, err = s.SetActivityDigest(ctx, models.ActivityDigest{UserId: owner, Frequency: models.DigestWeekly, NextDueAt: watchedAt})
+	require.NoError(t, err)
+}
+
+func TestStore_Archive(t *testing.T) {
+	t.Run("a restore reproduces every record the List methods read", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+		seedInstance(t, s)
+
+		before := dumpInstance(t, s)
+		require.Len(t, before.Users, 3)
+		require.Len(t, before.Groups, 2, "soft-deleted groups must be read too")
+		require.Len(t, before.Ratings, 3)
+		require.Len(t, before.Events, 3)
+		require.Len(t, before.Floors, 1)
+		require.Len(t, before.Reads, 1)
+		require.Len(t, before.Mutes, 3, "mutes and quiet hours, each user's read whole")
+		require.Len(t, before.Digests, 2)
+
+		resetDB(t)
+		empty, err := s.ArchiveIsEmpty(ctx)
+		require.NoError(t, err)
+		require.True(t, empty)
+
+		restoreInstance(t, s, before)
+		require.Equal(t, before, dumpInstance(t, s))
+
+		empty, err = s.ArchiveIsEmpty(ctx)
+		require.NoError(t, err)
+		require.False(t, empty)
+	})
+
+	t.Run("events recorded after a restore are numbered after the restored ones", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+		seedInstance(t, s)
+		before := dumpInstance(t, s)
+		resetDB(t)
+		restoreInstance(t, s, before)
+
+		groupId := before.Events[0].GroupId
+		require.NoError(t, s.InsertActivityEvents(ctx, []models.ActivityEvent{
+			{Id: "e4", GroupId: groupId, ActorId: before.Events[0].ActorId, ActorName: "owner", Kind: "title_added"},
+		}))
+		after := dumpInstance(t, s)
+		require.Len(t, after.Events, 4)
+		require.Greater(t, after.Events[3].Seq, before.Events[2].Seq)
+	})
+
+	t.Run("the read state survives a restore", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+		seedInstance(t, s)
+		before := dumpInstance(t, s)
+		member := before.Reads[0].UserId
+		count, err := s.GetActivityUnreadCount(ctx, member)
+		require.NoError(t, err)
+		require.Zero(t, count)
+
+		resetDB(t)
+		restoreInstance(t, s, before)
+
+		count, err = s.GetActivityUnreadCount(ctx, member)
+		require.NoError(t, err)
+		require.Zero(t, count, "the floor and the read row must both come back")
+	})
+
+	t.Run("a digest picks up where it stopped after a restore", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+		seedInstance(t, s)
+		before := dumpInstance(t, s)
+
+		resetDB(t)
+		restoreInstance(t, s, before)
+
+		var digested *models.ActivityDigest
+		for i := range before.Digests {
+			if before.Digests[i].LastSentAt != nil {
+				digested = &before.Digests[i]
+			}
+		}
+		require.NotNil(t, digested)
+		digest, err := s.GetActivityDigest(ctx, digested.UserId)
+		require.NoError(t, err)
+		require.Equal(t, digested.DigestedSeq, digest.DigestedSeq, "restored as it was, not from the newest event")
+		require.True(t, digested.LastSentAt.Equal(*digest.LastSentAt))
+		require.True(t, digested.NextDueAt.Equal(digest.NextDueAt), "and on the same schedule")
+	})
+}
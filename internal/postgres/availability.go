@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/lealre/movies-backend/internal/database"
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+// ReplaceTitleAvailability stores a fresh fetch of a title's availability in
+// one transaction: the header row (provider, fetch time, and each region's
+// link) is upserted and every offer the title had before is replaced, so a
+// service that dropped the title since the last fetch is gone with it.
+func (s *Store) ReplaceTitleAvailability(ctx context.Context, availability models.TitleAvailability) error {
+	links := make(map[string]string, len(availability.Regions))
+	var offers database.InsertTitleAvailabilityOffersParams
+	offers.TitleID = availability.TitleID
+	for region, r := range availability.Regions {
+		if r.Link != "" {
+			links[region] = r.Link
+		}
+		for kind, services := range map[string][]models.StreamingService{
+			models.OfferFlatrate: r.Flatrate,
+			models.OfferRent:     r.Rent,
+			models.OfferBuy:      r.Buy,
+		} {
+			for _, svc := range services {
+				offers.Regions = append(offers.Regions, region)
+				offers.Kinds = append(offers.Kinds, kind)
+				offers.ServiceIds = append(offers.ServiceIds, clampToInt32(svc.ID))
+				offers.ServiceNames = append(offers.ServiceNames, svc.Name)
+				offers.LogoUrls = append(offers.LogoUrls, svc.LogoURL)
+				offers.DisplayPriorities = append(offers.DisplayPriorities, clampToInt32(svc.DisplayPriority))
+			}
+		}
+	}
+	linksJSON, err := json.Marshal(links)
+	if err != nil {
+		return err
+	}
+
+	return s.inTx(ctx, func(q *database.Queries) error {
+		if err := q.UpsertTitleAvailability(ctx, database.UpsertTitleAvailabilityParams{
+			TitleID:   availability.TitleID,
+			Provider:  availability.Provider,
+			Links:     linksJSON,
+			FetchedAt: timeToTimestamptz(availability.FetchedAt),
+		}); err != nil {
+			return err
+		}
+		if err := q.DeleteTitleAvailabilityOffers(ctx, availability.TitleID); err != nil {
+			return err
+		}
+		if len(offers.Regions) == 0 {
+			return nil
+		}
+		return q.InsertTitleAvailabilityOffers(ctx, offers)
+	})
+}
+
+// GetTitleAvailability returns the stored availability in region of each of
+// titleIds that has been fetched. A fetched title with nothing in region is
+// present with an empty Regions map, so callers can tell "not on any service
+// here" from "not fetched yet".
+func (s *Store) GetTitleAvailability(ctx context.Context, titleIds []string, region string) (map[string]models.TitleAvailability, error) {
+	result := map[string]models.TitleAvailability{}
+	if len(titleIds) == 0 {
+		return result, nil
+	}
+
+	headers, err := s.queries(ctx).GetTitleAvailabilityHeaders(ctx, database.GetTitleAvailabilityHeadersParams{
+		Region:   region,
+		TitleIds: titleIds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	links := make(map[string]string, len(headers))
+	for _, h := range headers {
+		links[h.TitleID] = h.Link
+		result[h.TitleID] = models.TitleAvailability{
+			TitleID:   h.TitleID,
+			Provider:  h.Provider,
+			FetchedAt: h.FetchedAt.Time,
+			Regions:   map[string]models.RegionAvailability{},
+		}
+	}
+
+	offers, err := s.queries(ctx).GetTitleAvailabilityOffers(ctx, database.GetTitleAvailabilityOffersParams{
+		TitleIds: titleIds,
+		Region:   region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range offers {
+		availability, ok := result[o.TitleID]
+		if !ok {
+			continue
+		}
+		r, ok := availability.Regions[region]
+		if !ok {
+			r.Link = links[o.TitleID]
+		}
+		svc := models.StreamingService{
+			ID:              int(o.ServiceID),
+			Name:            o.ServiceName,
+			LogoURL:         o.LogoUrl,
+			DisplayPriority: int(o.DisplayPriority),
+		}
+		switch o.Kind {
+		case models.OfferFlatrate:
+			r.Flatrate = append(r.Flatrate, svc)
+		case models.OfferRent:
+			r.Rent = append(r.Rent, svc)
+		case models.OfferBuy:
+			r.Buy = append(r.Buy, svc)
+		}
+		availability.Regions[region] = r
+	}
+	return result, nil
+}
+
+// GetStreamingPreferences returns userId's subscriptions, or
+// store.ErrRecordNotFound when they have set none.
+func (s *Store) GetStreamingPreferences(ctx context.Context, userId string) (models.StreamingPreferences, error) {
+	row, err := s.queries(ctx).GetUserStreamingServices(ctx, userId)
+	if err != nil {
+		return models.StreamingPreferences{}, notFound(err)
+	}
+	return streamingPreferencesFromRow(row), nil
+}
+
+// SetStreamingPreferences replaces prefs.UserID's subscriptions and returns
+// them as stored.
+func (s *Store) SetStreamingPreferences(ctx context.Context, prefs models.StreamingPreferences) (models.StreamingPreferences, error) {
+	serviceIds := make([]int32, 0, len(prefs.ServiceIDs))
+	for _, id := range prefs.ServiceIDs {
+		serviceIds = append(serviceIds, clampToInt32(id))
+	}
+	row, err := s.queries(ctx).UpsertUserStreamingServices(ctx, database.UpsertUserStreamingServicesParams{
+		UserID:     prefs.UserID,
+		Region:     prefs.Region,
+		ServiceIds: serviceIds,
+		UpdatedAt:  timeToTimestamptz(prefs.UpdatedAt),
+	})
+	if err != nil {
+		return models.StreamingPreferences{}, err
+	}
+	return streamingPreferencesFromRow(row), nil
+}
+
+func streamingPreferencesFromRow(row database.UserStreamingService) models.StreamingPreferences {
+	serviceIds := make([]int, 0, len(row.ServiceIds))
+	for _, id := range row.ServiceIds {
+		serviceIds = append(serviceIds, int(id))
+	}
+	return models.StreamingPreferences{
+		UserID:     row.UserID,
+		Region:     row.Region,
+		ServiceIDs: serviceIds,
+		UpdatedAt:  row.UpdatedAt.Time,
+	}
+}
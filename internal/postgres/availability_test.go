@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_TitleAvailability(t *testing.T) {
+	fetchedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	netflix := models.StreamingService{ID: 8, Name: "Netflix", LogoURL: "https://img/n.jpg", DisplayPriority: 1}
+	apple := models.StreamingService{ID: 2, Name: "Apple TV", DisplayPriority: 3}
+
+	t.Run("round trip, one region at a time", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+		title := addTestTitleWithType(t, s, "tt-avail-1", "Streamed", "movie")
+		unfetched := addTestTitleWithType(t, s, "tt-avail-2", "Never fetched", "movie")
+
+		require.NoError(t, s.ReplaceTitleAvailability(ctx, models.TitleAvailability{
+			TitleID:   title.ID,
+			Provider:  "tmdb",
+			FetchedAt: fetchedAt,
+			Regions: map[string]models.RegionAvailability{
+				"US": {Link: "https://watch/us", Flatrate: []models.StreamingService{netflix}, Rent: []models.StreamingService{apple}},
+				"BR": {Buy: []models.StreamingService{apple}},
+			},
+		}))
+
+		got, err := s.GetTitleAvailability(ctx, []string{title.ID, unfetched.ID}, "US")
+		require.NoError(t, err)
+		require.Len(t, got, 1, "Expected only the fetched title")
+		us := got[title.ID]
+		require.Equal(t, "tmdb", us.Provider)
+		require.True(t, fetchedAt.Equal(us.FetchedAt))
+		require.Equal(t, models.RegionAvailability{
+			Link:     "https://watch/us",
+			Flatrate: []models.StreamingService{netflix},
+			Rent:     []models.StreamingService{apple},
+		}, us.Regions["US"])
+
+		got, err = s.GetTitleAvailability(ctx, []string{title.ID}, "FR")
+		require.NoError(t, err)
+		require.Empty(t, got[title.ID].Regions, "Expected a fetched title with nothing in FR")
+	})
+
+	t.Run("a new fetch replaces every offer", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+		title := addTestTitleWithType(t, s, "tt-avail-1", "Streamed", "movie")
+
+		require.NoError(t, s.ReplaceTitleAvailability(ctx, models.TitleAvailability{
+			TitleID: title.ID, Provider: "tmdb", FetchedAt: fetchedAt,
+			Regions: map[string]models.RegionAvailability{"US": {Flatrate: []models.StreamingService{netflix}}},
+		}))
+		require.NoError(t, s.ReplaceTitleAvailability(ctx, models.TitleAvailability{
+			TitleID: title.ID, Provider: "tmdb", FetchedAt: fetchedAt.Add(24 * time.Hour),
+			Regions: map[string]models.RegionAvailability{},
+		}))
+
+		got, err := s.GetTitleAvailability(ctx, []string{title.ID}, "US")
+		require.NoError(t, err)
+		require.Empty(t, got[title.ID].Regions)
+		require.True(t, fetchedAt.Add(24*time.Hour).Equal(got[title.ID].FetchedAt))
+	})
+}
+
+func TestStore_StreamingPreferences(t *testing.T) {
+	resetDB(t)
+	s := newTestStore(t)
+	ctx := context.Background()
+	userId := addTestUser(t, s)
+
+	_, err := s.GetStreamingPreferences(ctx, userId)
+	require.ErrorIs(t, err, store.ErrRecordNotFound)
+
+	updatedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	set, err := s.SetStreamingPreferences(ctx, models.StreamingPreferences{
+		UserID: userId, Region: "BR", ServiceIDs: []int{8, 337}, UpdatedAt: updatedAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{8, 337}, set.ServiceIDs)
+
+	got, err := s.GetStreamingPreferences(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, "BR", got.Region)
+	require.Equal(t, []int{8, 337}, got.ServiceIDs)
+	require.True(t, updatedAt.Equal(got.UpdatedAt))
+}
+
+func TestStore_GetGroupTitlesPage_AvailableOn(t *testing.T) {
+	resetDB(t)
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	owner := addTestUser(t, s)
+	group, err := s.CreateGroup(ctx, newTestGroup(t, "streaming", owner))
+	require.NoError(t, err)
+
+	onNetflix := addTestTitleWithType(t, s, "tt-on-netflix", "On Netflix", "movie")
+	rentOnly := addTestTitleWithType(t, s, "tt-rent-only", "Rent only", "movie")
+	unfetched := addTestTitleWithType(t, s, "tt-unfetched", "Unfetched", "movie")
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, ti := range []models.Title{onNetflix, rentOnly, unfetched} {
+		addGroupTitleRow(t, s, group.Id, ti.ID, ti.Type, false, nil, now, now)
+	}
+
+	netflix := models.StreamingService{ID: 8, Name: "Netflix"}
+	require.NoError(t, s.ReplaceTitleAvailability(ctx, models.TitleAvailability{
+		TitleID: onNetflix.ID, Provider: "tmdb", FetchedAt: now,
+		Regions: map[string]models.RegionAvailability{"US": {Flatrate: []models.StreamingService{netflix}}},
+	}))
+	require.NoError(t, s.ReplaceTitleAvailability(ctx, models.TitleAvailability{
+		TitleID: rentOnly.ID, Provider: "tmdb", FetchedAt: now,
+		Regions: map[string]models.RegionAvailability{"US": {Rent: []models.StreamingService{netflix}}},
+	}))
+
+	got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil,
+		&models.StreamingFilter{Region: "US", ServiceIDs: []int{8, 9}}, "", nil, 10, 1)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total, "Expected only the title on a subscription")
+	require.Equal(t, onNetflix.ID, got[0].Title.ID)
+
+	_, total, err = s.GetGroupTitlesPage(ctx, group.Id, nil, nil,
+		&models.StreamingFilter{Region: "BR", ServiceIDs: []int{8}}, "", nil, 10, 1)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, total, "Expected offers in another region not to count")
+
+	hasEntries, err := s.GroupHasTitleEntries(ctx, group.Id, nil, nil, &models.StreamingFilter{Region: "US", ServiceIDs: []int{}})
+	require.NoError(t, err)
+	require.False(t, hasEntries, "Expected no services to match nothing")
+}
@@ -430,6 +430,43 @@ func TestStore_GetCommentsByTitleId_FiltersByGroup(t *testing.T) {
 	require.Equal(t, []models.Comment{}, empty)
 }
 
+func TestStore_GetCommentsByTitleIds(t *testing.T) {
+	resetDB(t)
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	groupId := addTestGroup(t, s)
+	otherGroupId := addTestGroup(t, s)
+	movieId := "tt-" + uuid.NewString()
+	seriesId := "tt-" + uuid.NewString()
+
+	movie, err := s.AddComment(ctx, newTestMovieComment(t, movieId, "user-1", groupId, "movie"))
+	require.NoError(t, err)
+	series, err := s.AddComment(ctx, newTestSeriesComment(t, seriesId, "user-2", groupId, map[string]string{"1": "s1", "2": "s2"}))
+	require.NoError(t, err)
+	_, err = s.AddComment(ctx, newTestMovieComment(t, movieId, "user-1", otherGroupId, "other group"))
+	require.NoError(t, err)
+	_, err = s.AddComment(ctx, newTestMovieComment(t, "tt-unasked-"+uuid.NewString(), "user-1", groupId, "title not asked for"))
+	require.NoError(t, err)
+
+	got, err := s.GetCommentsByTitleIds(ctx, []string{movieId, seriesId}, groupId)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+
+	byId := map[string]models.Comment{}
+	for _, c := range got {
+		byId[c.Id] = c
+	}
+	require.Contains(t, byId, movie.Id)
+	require.Contains(t, byId, series.Id)
+	require.NotNil(t, byId[series.Id].SeasonsComments)
+	require.Len(t, *byId[series.Id].SeasonsComments, 2, "seasons must be assembled for every comment in the batch")
+
+	empty, err := s.GetCommentsByTitleIds(ctx, []string{}, groupId)
+	require.NoError(t, err)
+	require.Equal(t, []models.Comment{}, empty)
+}
+
 func TestStore_DeleteComment(t *testing.T) {
 	t.Run("deletes comment and cascades its seasons", func(t *testing.T) {
 		resetDB(t)
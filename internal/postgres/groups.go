@@ -20,12 +20,12 @@ import (
 // always reports a titles map, even when it holds none; each item's
 // SeasonsWatched is nil when it has no season rows.
 func (s *Store) assembleGroupTitles(ctx context.Context, groupId string) (models.GroupTitles, error) {
-	titleRows, err := s.q.GetGroupTitleRows(ctx, groupId)
+	titleRows, err := s.queries(ctx).GetGroupTitleRows(ctx, groupId)
 	if err != nil {
 		return nil, err
 	}
 
-	seasonRows, err := s.q.GetGroupTitleSeasonRows(ctx, groupId)
+	seasonRows, err := s.queries(ctx).GetGroupTitleSeasonRows(ctx, groupId)
 	if err != nil {
 		return nil, err
 	}
@@ -68,10 +68,7 @@ func (s *Store) CreateGroup(ctx context.Context, group models.Group) (models.Gro
 		}
 
 		for _, userId := range group.Users {
-			if err := q.AddGroupMember(ctx, database.AddGroupMemberParams{
-				GroupID: id,
-				UserID:  userId,
-			}); err != nil {
+			if err := addGroupMember(ctx, q, id, userId); err != nil {
 				return err
 			}
 		}
@@ -91,13 +88,13 @@ func (s *Store) CreateGroup(ctx context.Context, group models.Group) (models.Gro
 // GroupExists reports whether a non-deleted group with the given id exists and
 // has userId as a member.
 func (s *Store) GroupExists(ctx context.Context, groupId, userId string) (bool, error) {
-	return s.q.GroupExists(ctx, database.GroupExistsParams{ID: groupId, UserID: userId})
+	return s.queries(ctx).GroupExists(ctx, database.GroupExistsParams{ID: groupId, UserID: userId})
 }
 
 // GroupContainsTitle reports whether a non-deleted group with the given id has
 // userId as a member and contains titleId.
 func (s *Store) GroupContainsTitle(ctx context.Context, groupId, titleId, userId string) (bool, error) {
-	return s.q.GroupContainsTitle(ctx, database.GroupContainsTitleParams{
+	return s.queries(ctx).GroupContainsTitle(ctx, database.GroupContainsTitleParams{
 		ID:      groupId,
 		TitleID: titleId,
 		UserID:  userId,
@@ -109,12 +106,12 @@ func (s *Store) GroupContainsTitle(ctx context.Context, groupId, titleId, userId
 // group, or one userId is not a member of, is reported as
 // store.ErrRecordNotFound.
 func (s *Store) GetGroupById(ctx context.Context, groupId, userId string) (models.Group, error) {
-	row, err := s.q.GetGroupRow(ctx, database.GetGroupRowParams{ID: groupId, UserID: userId})
+	row, err := s.queries(ctx).GetGroupRow(ctx, database.GetGroupRowParams{ID: groupId, UserID: userId})
 	if err != nil {
 		return models.Group{}, notFound(err)
 	}
 
-	users, err := s.q.GetGroupMemberIds(ctx, groupId)
+	users, err := s.queries(ctx).GetGroupMemberIds(ctx, groupId)
 	if err != nil {
 		return models.Group{}, err
 	}
@@ -141,10 +138,7 @@ func (s *Store) AddUserToGroup(ctx context.Context, groupId, ownerId, userToAddI
 			return store.ErrRecordNotFound
 		}
 
-		if err := q.AddGroupMember(ctx, database.AddGroupMemberParams{
-			GroupID: groupId,
-			UserID:  userToAddId,
-		}); err != nil {
+		if err := addGroupMember(ctx, q, groupId, userToAddId); err != nil {
 			return err
 		}
 
@@ -158,11 +152,11 @@ func (s *Store) AddUserToGroup(ctx context.Context, groupId, ownerId, userToAddI
 // group, or one userId is not a member of, is reported as
 // store.ErrRecordNotFound.
 func (s *Store) GetUsersFromGroup(ctx context.Context, groupId, userId string) ([]models.User, error) {
-	if _, err := s.q.GetGroupRow(ctx, database.GetGroupRowParams{ID: groupId, UserID: userId}); err != nil {
+	if _, err := s.queries(ctx).GetGroupRow(ctx, database.GetGroupRowParams{ID: groupId, UserID: userId}); err != nil {
 		return []models.User{}, notFound(err)
 	}
 
-	rows, err := s.q.GetGroupMemberUsers(ctx, groupId)
+	rows, err := s.queries(ctx).GetGroupMemberUsers(ctx, groupId)
 	if err != nil {
 		return []models.User{}, err
 	}
@@ -199,6 +193,33 @@ func (s *Store) AddNewGroupTitle(ctx context.Context, groupId string, titleId st
 	})
 }
 
+// AddGroupTitles adds titleIds to the group in one transaction, each as
+// AddNewGroupTitle would, and returns the ones it inserted: a title the group
+// already has is left untouched — its watched state and added date kept — and
+// absent from the result.
+func (s *Store) AddGroupTitles(ctx context.Context, groupId string, titleIds []string) ([]string, error) {
+	var added []string
+	err := s.inTx(ctx, func(q *database.Queries) error {
+		var err error
+		added, err = q.InsertGroupTitles(ctx, database.InsertGroupTitlesParams{
+			GroupID:  groupId,
+			AddedAt:  timeToTimestamptz(time.Now()),
+			TitleIds: titleIds,
+		})
+		if err != nil || len(added) == 0 {
+			return err
+		}
+		return q.TouchGroup(ctx, groupId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if added == nil {
+		added = []string{}
+	}
+	return added, nil
+}
+
 // UpdateGroupTitleWatchedForMovie sets the top-level watched/watchedAt on a
 // group's title: watched and
 // watchedAt are each only touched when their argument is non-nil, and a
@@ -378,7 +399,7 @@ func (s *Store) UpdateGroupTitleWatchedForTVSeries(ctx context.Context, groupId
 // index is reported as store.ErrDuplicatedRecord; a missing/deleted group as
 // store.ErrRecordNotFound.
 func (s *Store) UpdateGroupInfo(ctx context.Context, groupId, name, description string) error {
-	n, err := s.q.UpdateGroupInfoRow(ctx, database.UpdateGroupInfoRowParams{
+	n, err := s.queries(ctx).UpdateGroupInfoRow(ctx, database.UpdateGroupInfoRowParams{
 		ID:          groupId,
 		Name:        name,
 		Description: description,
@@ -395,18 +416,20 @@ func (s *Store) UpdateGroupInfo(ctx context.Context, groupId, name, description
 	return nil
 }
 
-// SoftDeleteGroup marks a non-deleted group as deleted.
-// An already-deleted or missing group is reported as
+// SoftDeleteGroup marks a non-deleted group as deleted, and tells live streams
+// the group is gone. An already-deleted or missing group is reported as
 // store.ErrRecordNotFound.
 func (s *Store) SoftDeleteGroup(ctx context.Context, groupId string) error {
-	n, err := s.q.SoftDeleteGroupRow(ctx, groupId)
-	if err != nil {
-		return err
-	}
-	if n == 0 {
-		return store.ErrRecordNotFound
-	}
-	return nil
+	return s.inTx(ctx, func(q *database.Queries) error {
+		n, err := q.SoftDeleteGroupRow(ctx, groupId)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return store.ErrRecordNotFound
+		}
+		return notifyMembership(ctx, q, models.MembershipChange{Kind: models.MembershipGroupDeleted, GroupId: groupId})
+	})
 }
 
 // RemoveUserFromGroup removes userId from a non-deleted group's members,
@@ -422,10 +445,7 @@ func (s *Store) RemoveUserFromGroup(ctx context.Context, groupId, userId string)
 			return store.ErrRecordNotFound
 		}
 
-		if err := q.RemoveGroupMember(ctx, database.RemoveGroupMemberParams{
-			GroupID: groupId,
-			UserID:  userId,
-		}); err != nil {
+		if err := removeGroupMember(ctx, q, groupId, userId); err != nil {
 			return err
 		}
 
@@ -448,22 +468,39 @@ var groupTitlesOrderKeys = map[string]bool{
 // those, so its total alone cannot tell an empty group from a fully orphaned
 // one). See the query comment in sql/queries/groups.sql for why the join side
 // is a LEFT JOIN and what the caller does with the answer.
-func (s *Store) GroupHasTitleEntries(ctx context.Context, groupId string, watched *bool, titleTypes []string) (bool, error) {
+func (s *Store) GroupHasTitleEntries(ctx context.Context, groupId string, watched *bool, titleTypes []string, availableOn *models.StreamingFilter) (bool, error) {
 	var watchedArg pgtype.Bool
 	if watched != nil {
 		watchedArg = pgtype.Bool{Bool: *watched, Valid: true}
 	}
-	return s.q.GroupHasTitleEntries(ctx, database.GroupHasTitleEntriesParams{
+	serviceIds, region := streamingFilterArgs(availableOn)
+	return s.queries(ctx).GroupHasTitleEntries(ctx, database.GroupHasTitleEntriesParams{
 		GroupID:    groupId,
 		Watched:    watchedArg,
 		TitleTypes: titleTypes, // nil slice -> SQL NULL -> filter off
+		ServiceIds: serviceIds,
+		Region:     region,
 	})
 }
 
+// streamingFilterArgs is the query arguments for an optional
+// StreamingFilter. No filter is a nil slice (SQL NULL, filter off); a filter
+// with no services is an empty, non-nil one, which matches nothing.
+func streamingFilterArgs(f *models.StreamingFilter) ([]int32, pgtype.Text) {
+	if f == nil {
+		return nil, pgtype.Text{}
+	}
+	serviceIds := make([]int32, 0, len(f.ServiceIDs))
+	for _, id := range f.ServiceIDs {
+		serviceIds = append(serviceIds, clampToInt32(id))
+	}
+	return serviceIds, pgtype.Text{String: f.Region, Valid: true}
+}
+
 // GetGroupTitlesPage returns one page of a group's titles — full title plus
 // this group's watch-state, seasons stitched in — with the post-filter total.
 // Filters are nil-defaulted; the ORDER BY is total (ends in t.id ASC).
-func (s *Store) GetGroupTitlesPage(ctx context.Context, groupId string, watched *bool, titleTypes []string, orderBy string, ascending *bool, size, page int) ([]models.GroupPagedTitle, int64, error) {
+func (s *Store) GetGroupTitlesPage(ctx context.Context, groupId string, watched *bool, titleTypes []string, availableOn *models.StreamingFilter, orderBy string, ascending *bool, size, page int) ([]models.GroupPagedTitle, int64, error) {
 	if !groupTitlesOrderKeys[orderBy] {
 		orderBy = ""
 	}
@@ -473,13 +510,15 @@ func (s *Store) GetGroupTitlesPage(ctx context.Context, groupId string, watched
 	if watched != nil {
 		watchedArg = pgtype.Bool{Bool: *watched, Valid: true}
 	}
+	serviceIds, region := streamingFilterArgs(availableOn)
 
 	// Whenever no row can be returned, the window-function total goes with
 	// them, so the total has to come from the companion count over the same
 	// WHERE. Every such exit uses this.
 	emptyPage := func() ([]models.GroupPagedTitle, int64, error) {
-		total, err := s.q.CountGroupTitles(ctx, database.CountGroupTitlesParams{
+		total, err := s.queries(ctx).CountGroupTitles(ctx, database.CountGroupTitlesParams{
 			GroupID: groupId, Watched: watchedArg, TitleTypes: titleTypes,
+			ServiceIds: serviceIds, Region: region,
 		})
 		if err != nil {
 			return nil, 0, err
@@ -495,10 +534,12 @@ func (s *Store) GetGroupTitlesPage(ctx context.Context, groupId string, watched
 		return emptyPage()
 	}
 
-	rows, err := s.q.GetGroupTitlesPage(ctx, database.GetGroupTitlesPageParams{
+	rows, err := s.queries(ctx).GetGroupTitlesPage(ctx, database.GetGroupTitlesPageParams{
 		GroupID:    groupId,
 		Watched:    watchedArg,
 		TitleTypes: titleTypes, // nil slice -> SQL NULL -> filter off
+		ServiceIds: serviceIds,
+		Region:     region,
 		OrderBy:    orderBy,
 		Descending: descending,
 		PageSize:   int64(size),
@@ -540,7 +581,7 @@ func (s *Store) GetGroupTitlesPage(ctx context.Context, groupId string, watched
 		for _, r := range rows {
 			titleIds = append(titleIds, r.ID)
 		}
-		seasonRows, err := s.q.GetGroupTitleSeasonRowsForTitles(ctx, database.GetGroupTitleSeasonRowsForTitlesParams{
+		seasonRows, err := s.queries(ctx).GetGroupTitleSeasonRowsForTitles(ctx, database.GetGroupTitleSeasonRowsForTitlesParams{
 			GroupID: groupId, TitleIds: titleIds,
 		})
 		if err != nil {
@@ -610,7 +651,7 @@ func groupPagedTitleFromRow(
 // catalogue, which the join drops just as it drops it from a page — is reported
 // as store.ErrRecordNotFound.
 func (s *Store) GetGroupTitle(ctx context.Context, groupId, titleId string) (models.GroupPagedTitle, error) {
-	row, err := s.q.GetGroupTitleWithTitle(ctx, database.GetGroupTitleWithTitleParams{
+	row, err := s.queries(ctx).GetGroupTitleWithTitle(ctx, database.GetGroupTitleWithTitleParams{
 		GroupID: groupId,
 		TitleID: titleId,
 	})
@@ -623,7 +664,7 @@ func (s *Store) GetGroupTitle(ctx context.Context, groupId, titleId string) (mod
 	// answer is known to be empty — the same decision the page makes.
 	var seasonRows []database.GroupTitleSeason
 	if models.IsSeriesTitleType(row.Type) {
-		seasonRows, err = s.q.GetGroupTitleSeasonRowsForTitle(ctx, database.GetGroupTitleSeasonRowsForTitleParams{
+		seasonRows, err = s.queries(ctx).GetGroupTitleSeasonRowsForTitle(ctx, database.GetGroupTitleSeasonRowsForTitleParams{
 			GroupID: groupId,
 			TitleID: titleId,
 		})
@@ -663,3 +704,48 @@ func (s *Store) RemoveTitleFromGroup(ctx context.Context, groupId, titleId, user
 		return q.TouchGroup(ctx, groupId)
 	})
 }
+
+// GetTitleIdsSeenByUsers returns which of titleIds any of userIds has already
+// seen outside groupId's own watch state (see the query for what counts). The
+// slice is empty, never nil, when none has been seen or either list is empty.
+func (s *Store) GetTitleIdsSeenByUsers(ctx context.Context, userIds, titleIds []string, groupId string) ([]string, error) {
+	if len(userIds) == 0 || len(titleIds) == 0 {
+		return []string{}, nil
+	}
+
+	seen, err := s.queries(ctx).GetTitleIdsSeenByUsers(ctx, database.GetTitleIdsSeenByUsersParams{
+		UserIds:  userIds,
+		TitleIds: titleIds,
+		GroupID:  groupId,
+	})
+	if err != nil {
+		return []string{}, err
+	}
+	if seen == nil {
+		return []string{}, nil
+	}
+	return seen, nil
+}
+
+// addGroupMember makes userId a member of groupId and tells live streams so.
+// Every membership write goes through it or removeGroupMember, so a stream
+// never learns about one change and misses another.
+func addGroupMember(ctx context.Context, q *database.Queries, groupId, userId string) error {
+	if err := q.AddGroupMember(ctx, database.AddGroupMemberParams{
+		GroupID: groupId,
+		UserID:  userId,
+	}); err != nil {
+		return err
+	}
+	return notifyMembership(ctx, q, models.MembershipChange{Kind: models.MembershipAdded, UserId: userId, GroupId: groupId})
+}
+
+func removeGroupMember(ctx context.Context, q *database.Queries, groupId, userId string) error {
+	if err := q.RemoveGroupMember(ctx, database.RemoveGroupMemberParams{
+		GroupID: groupId,
+		UserID:  userId,
+	}); err != nil {
+		return err
+	}
+	return notifyMembership(ctx, q, models.MembershipChange{Kind: models.MembershipRemoved, UserId: userId, GroupId: groupId})
+}
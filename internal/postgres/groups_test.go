@@ -238,6 +238,37 @@ func TestStore_AddNewGroupTitle(t *testing.T) {
 	require.False(t, contains)
 }
 
+func TestStore_AddGroupTitles(t *testing.T) {
+	resetDB(t)
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	owner := addTestUser(t, s)
+	created, err := s.CreateGroup(ctx, newTestGroup(t, "bulk", owner))
+	require.NoError(t, err)
+
+	existing := "tt-" + uuid.NewString()
+	require.NoError(t, s.AddNewGroupTitle(ctx, created.Id, existing))
+	when := time.Now().UTC().Truncate(time.Second)
+	_, err = s.UpdateGroupTitleWatchedForMovie(ctx, created.Id, existing, boolPtr(true), flexDate(when))
+	require.NoError(t, err)
+
+	first, second := "tt-"+uuid.NewString(), "tt-"+uuid.NewString()
+	added, err := s.AddGroupTitles(ctx, created.Id, []string{first, existing, second})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{first, second}, added, "only titles the group did not have are reported added")
+
+	got, err := s.GetGroupById(ctx, created.Id, owner)
+	require.NoError(t, err)
+	require.Len(t, got.Titles, 3)
+	require.False(t, got.Titles[first].Watched)
+	require.True(t, got.Titles[existing].Watched, "a title already in the group keeps its watched state")
+
+	added, err = s.AddGroupTitles(ctx, created.Id, []string{first})
+	require.NoError(t, err)
+	require.Empty(t, added)
+}
+
 func TestStore_UpdateGroupTitleWatchedForMovie(t *testing.T) {
 	resetDB(t)
 	s := newTestStore(t)
@@ -496,7 +527,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 			require.NoError(t, s.AddNewGroupTitle(ctx, group.Id, ti.ID))
 		}
 
-		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "", nil, 10, 1)
+		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "", nil, 10, 1)
 		require.NoError(t, err)
 		require.EqualValues(t, 3, total, "the window-function total must be present and correct on a full page")
 		require.Len(t, got, 3)
@@ -525,14 +556,14 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		addGroupTitleRow(t, s, group.Id, watchedTitle.ID, "movie", true, &now, now, now)
 		addGroupTitleRow(t, s, group.Id, unwatchedTitle.ID, "movie", false, nil, now, now)
 
-		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, boolPtr(true), nil, "", nil, 10, 1)
+		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, boolPtr(true), nil, nil, "", nil, 10, 1)
 		require.NoError(t, err)
 		require.EqualValues(t, 1, total)
 		require.Len(t, got, 1)
 		require.Equal(t, watchedTitle.ID, got[0].Title.ID)
 		require.True(t, got[0].Item.Watched)
 
-		got, total, err = s.GetGroupTitlesPage(ctx, group.Id, boolPtr(false), nil, "", nil, 10, 1)
+		got, total, err = s.GetGroupTitlesPage(ctx, group.Id, boolPtr(false), nil, nil, "", nil, 10, 1)
 		require.NoError(t, err)
 		require.EqualValues(t, 1, total)
 		require.Len(t, got, 1)
@@ -558,13 +589,13 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 			addGroupTitleRow(t, s, group.Id, ti.ID, ti.Type, false, nil, now, now)
 		}
 
-		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, []string{"movie"}, "", nil, 10, 1)
+		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, []string{"movie"}, nil, "", nil, 10, 1)
 		require.NoError(t, err)
 		require.EqualValues(t, 1, total)
 		require.Len(t, got, 1)
 		require.Equal(t, movie.ID, got[0].Title.ID)
 
-		got, total, err = s.GetGroupTitlesPage(ctx, group.Id, nil, []string{"movie", "tvSeries"}, "", nil, 10, 1)
+		got, total, err = s.GetGroupTitlesPage(ctx, group.Id, nil, []string{"movie", "tvSeries"}, nil, "", nil, 10, 1)
 		require.NoError(t, err)
 		require.EqualValues(t, 2, total)
 		require.Len(t, got, 2)
@@ -589,7 +620,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		addGroupTitleRow(t, s, group.Id, movieUnwatched.ID, "movie", false, nil, now, now)
 		addGroupTitleRow(t, s, group.Id, seriesWatched.ID, "tvSeries", true, &now, now, now)
 
-		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, boolPtr(true), []string{"movie"}, "", nil, 10, 1)
+		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, boolPtr(true), []string{"movie"}, nil, "", nil, 10, 1)
 		require.NoError(t, err)
 		require.EqualValues(t, 1, total)
 		require.Len(t, got, 1)
@@ -616,7 +647,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		addGroupTitleRow(t, s, group.Id, low.ID, "movie", false, nil, now, now)
 		addGroupTitleRow(t, s, group.Id, high.ID, "movie", false, nil, now, now)
 
-		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "imdbRating", nil, 10, 1)
+		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "imdbRating", nil, 10, 1)
 		require.NoError(t, err)
 		require.Equal(t, []string{low.ID, high.ID}, []string{got[0].Title.ID, got[1].Title.ID})
 	})
@@ -640,7 +671,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		addGroupTitleRow(t, s, group.Id, early.ID, "movie", false, nil, now, now)
 		addGroupTitleRow(t, s, group.Id, late.ID, "movie", false, nil, now, now)
 
-		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "startYear", nil, 10, 1)
+		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "startYear", nil, 10, 1)
 		require.NoError(t, err)
 		require.Equal(t, []string{early.ID, late.ID}, []string{got[0].Title.ID, got[1].Title.ID})
 	})
@@ -660,7 +691,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		addGroupTitleRow(t, s, group.Id, movieT.ID, "movie", false, nil, now, now)
 		addGroupTitleRow(t, s, group.Id, seriesT.ID, "tvSeries", false, nil, now, now)
 
-		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "type", nil, 10, 1)
+		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "type", nil, 10, 1)
 		require.NoError(t, err)
 		require.Equal(t, []string{movieT.ID, seriesT.ID}, []string{got[0].Title.ID, got[1].Title.ID},
 			`"movie" sorts before "tvSeries" lexically`)
@@ -685,7 +716,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		addGroupTitleRow(t, s, group.Id, few.ID, "movie", false, nil, now, now)
 		addGroupTitleRow(t, s, group.Id, many.ID, "movie", false, nil, now, now)
 
-		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "voteCount", nil, 10, 1)
+		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "voteCount", nil, 10, 1)
 		require.NoError(t, err)
 		require.Equal(t, []string{few.ID, many.ID}, []string{got[0].Title.ID, got[1].Title.ID})
 	})
@@ -709,7 +740,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		addGroupTitleRow(t, s, group.Id, nullUpdated.ID, "movie", false, nil, now, now)
 
 		descending := false // ascending=false means descending, per the store's contract
-		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "updatedAt", &descending, 10, 1)
+		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "updatedAt", &descending, 10, 1)
 		require.NoError(t, err)
 		require.Equal(t, []string{nullUpdated.ID, dated.ID}, []string{got[0].Title.ID, got[1].Title.ID},
 			"unlike watchedAt, updatedAt carries no explicit NULLS clause, so Postgres' DESC default (NULLS FIRST) decides")
@@ -732,7 +763,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		addGroupTitleRow(t, s, group.Id, older.ID, "movie", false, nil, now.Add(-48*time.Hour), now)
 		addGroupTitleRow(t, s, group.Id, newer.ID, "movie", false, nil, now, now)
 
-		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "addedAt", nil, 10, 1)
+		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "addedAt", nil, 10, 1)
 		require.NoError(t, err)
 		require.Equal(t, []string{older.ID, newer.ID}, []string{got[0].Title.ID, got[1].Title.ID})
 	})
@@ -754,13 +785,13 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		addGroupTitleRow(t, s, group.Id, watchedTitle.ID, "movie", true, &now, now, now)
 		addGroupTitleRow(t, s, group.Id, unwatchedTitle.ID, "movie", false, nil, now, now)
 
-		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "watched", nil, 10, 1)
+		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "watched", nil, 10, 1)
 		require.NoError(t, err)
 		require.Equal(t, []string{unwatchedTitle.ID, watchedTitle.ID}, []string{got[0].Title.ID, got[1].Title.ID},
 			"ascending: false sorts before true")
 
 		descending := false // ascending=false means descending, per the store's contract
-		got, _, err = s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "watched", &descending, 10, 1)
+		got, _, err = s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "watched", &descending, 10, 1)
 		require.NoError(t, err)
 		require.Equal(t, []string{watchedTitle.ID, unwatchedTitle.ID}, []string{got[0].Title.ID, got[1].Title.ID},
 			"descending: true sorts before false")
@@ -789,14 +820,14 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		addGroupTitleRow(t, s, group.Id, lateWatched.ID, "movie", true, &late, now, now)
 		addGroupTitleRow(t, s, group.Id, neverWatched.ID, "movie", false, nil, now, now)
 
-		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "watchedAt", nil, 10, 1)
+		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "watchedAt", nil, 10, 1)
 		require.NoError(t, err)
 		require.Equal(t, []string{earlyWatched.ID, lateWatched.ID, neverWatched.ID},
 			[]string{got[0].Title.ID, got[1].Title.ID, got[2].Title.ID},
 			"ascending: earliest watchedAt first, nil last")
 
 		descending := false // ascending=false means descending, per the store's contract
-		got, _, err = s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "watchedAt", &descending, 10, 1)
+		got, _, err = s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "watchedAt", &descending, 10, 1)
 		require.NoError(t, err)
 		require.Equal(t, []string{lateWatched.ID, earlyWatched.ID, neverWatched.ID},
 			[]string{got[0].Title.ID, got[1].Title.ID, got[2].Title.ID},
@@ -821,7 +852,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		}
 
 		descending := false // ascending=false means descending, per the store's contract
-		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "garbage", &descending, 10, 1)
+		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "garbage", &descending, 10, 1)
 		require.NoError(t, err)
 		require.EqualValues(t, 3, total)
 		require.Equal(t, []string{"Charlie", "Bravo", "Alpha"},
@@ -846,7 +877,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 
 		var got []string
 		for page := 1; page <= 3; page++ {
-			titles, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "", nil, 2, page)
+			titles, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "", nil, 2, page)
 			require.NoError(t, err)
 			require.EqualValues(t, 5, total, "the total must not move while paging")
 			for _, ti := range titles {
@@ -855,7 +886,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		}
 		require.Equal(t, names, got, "pages must partition the sorted set exactly, with no duplicate or skipped row")
 
-		empty, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "", nil, 2, 4)
+		empty, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "", nil, 2, 4)
 		require.NoError(t, err)
 		require.Empty(t, empty)
 		require.EqualValues(t, 5, total, "an out-of-range page must still report the correct total")
@@ -899,7 +930,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		}
 		for _, tc := range cases {
 			t.Run(tc.name, func(t *testing.T) {
-				got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "", nil, 100, tc.page)
+				got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "", nil, 100, tc.page)
 				require.NoError(t, err)
 				require.Equal(t, []models.GroupPagedTitle{}, got)
 				require.EqualValues(t, len(names), total)
@@ -943,7 +974,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 						err   error
 					)
 					require.NotPanics(t, func() {
-						got, total, err = s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "", nil, size, page)
+						got, total, err = s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "", nil, size, page)
 					}, "size=%d page=%d must not panic", size, page)
 					require.NoError(t, err, "size=%d page=%d must not error", size, page)
 					require.Equal(t, []models.GroupPagedTitle{}, got, "size=%d page=%d must page to nothing", size, page)
@@ -974,7 +1005,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 			require.NoError(t, s.AddNewGroupTitle(ctx, group.Id, title.ID))
 		}
 
-		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "", nil, math.MaxInt32+1, 1)
+		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "", nil, math.MaxInt32+1, 1)
 		require.NoError(t, err, "a size past int32 must not wrap into a negative LIMIT")
 		require.Len(t, got, len(names), "a size that large must simply return every row")
 		require.EqualValues(t, len(names), total)
@@ -999,7 +1030,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		now := time.Now().UTC().Truncate(time.Second)
 		addGroupTitleRow(t, s, group.Id, "tt-missing-title", "movie", false, nil, now, now)
 
-		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "", nil, 10, 1)
+		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "", nil, 10, 1)
 		require.NoError(t, err)
 		require.EqualValues(t, 1, total)
 		require.Len(t, got, 1)
@@ -1027,7 +1058,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		addGroupTitleSeasonRow(t, s, group.Id, series.ID, "1", true, &now, now, now)
 		addGroupTitleSeasonRow(t, s, group.Id, series.ID, "2", false, nil, now, now)
 
-		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "", nil, 10, 1)
+		got, _, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "", nil, 10, 1)
 		require.NoError(t, err)
 		require.Len(t, got, 2)
 
@@ -1049,7 +1080,7 @@ func TestStore_GetGroupTitlesPage(t *testing.T) {
 		group, err := s.CreateGroup(ctx, newTestGroup(t, "empty", owner))
 		require.NoError(t, err)
 
-		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, "", nil, 10, 1)
+		got, total, err := s.GetGroupTitlesPage(ctx, group.Id, nil, nil, nil, "", nil, 10, 1)
 		require.NoError(t, err)
 		require.Equal(t, []models.GroupPagedTitle{}, got)
 		require.EqualValues(t, 0, total)
@@ -2,19 +2,28 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 
+	"github.com/lealre/movies-backend/internal/database"
 	"github.com/lealre/movies-backend/internal/models"
 	"github.com/lealre/movies-backend/internal/store"
 )
 
 // listenChannel is the pg_notify channel InsertActivityEvents fires on (see
-// activity.go and sql/queries/activity.sql's NotifyActivityEvent).
-const listenChannel = "activity_events"
+// activity.go and sql/queries/activity.sql's NotifyActivityEvent),
+// membershipChannel the one every membership write fires on (notifyMembership),
+// and preferencesChannel the one SetActivityPreferences fires on. The names
+// are repeated in the SQL, which cannot take them as parameters.
+const (
+	listenChannel      = "activity_events"
+	membershipChannel  = "activity_membership"
+	preferencesChannel = "activity_preferences"
+)
 
 // listenMinBackoff and listenMaxBackoff bound the reconnect delay: fast
 // enough that a blip recovers quickly, capped so a genuinely down database
@@ -27,7 +36,12 @@ const (
 // ListenActivity is the LISTEN loop backing real-time activity push: it
 // dials its own standalone connection (never the shared pool), LISTENs on
 // listenChannel, and turns every notified id into a row (via
-// GetActivityEventById) handed to publish.
+// GetActivityEventById) handed to publish. On the same connection it LISTENs
+// on membershipChannel and hands every membership change to membership, so
+// the two arrive in the order they were committed: an event in a group
+// committed before someone left it reaches them, and none after. Preference
+// changes come the same way, on preferencesChannel: the notified user id is
+// read back into their preferences, which are handed to preferences.
 //
 // The connection is dedicated on purpose: LISTEN is connection-scoped state,
 // and a pooled connection that got handed back to the pool between the
@@ -43,18 +57,27 @@ const (
 // currently happening. A connection dialed and Closed outright, never
 // touching the pool, cannot leak that way.
 //
-// publish takes a plain func rather than *activity.Hub so this package never
-// imports internal/activity: internal/postgres names a concrete database,
-// and the hub must stay database-agnostic (CONVENTIONS §2). The caller
-// (server.go) wires ListenActivity's publish to hub.Publish.
+// publish, membership and preferences are plain funcs rather than
+// *activity.Hub so this package never imports internal/activity:
+// internal/postgres names a concrete database, and the hub must stay
+// database-agnostic (CONVENTIONS §2). The caller (server.go) wires them to
+// hub.Publish, hub.ApplyMembership and hub.ApplyPreferences.
 //
 // On any connection error — the dedicated connection dying, the Postgres
 // backend restarting — this logs, backs off (capped at listenMaxBackoff),
 // and reconnects, re-issuing LISTEN. Events that land during that gap are not
-// replayed: nothing here tracks a resume position, and the design deliberately
-// leans on clients repairing themselves via their next snapshot read (see
-// "Snapshot, then stream" in the phase 2 design doc) rather than the backend
-// trying to guarantee delivery.
+// published: nothing here tracks a resume position. A client whose own
+// connection dropped gets what it missed from the log when it resumes with
+// Last-Event-ID (GetActivityEventsSince); one that stayed connected through
+// this loop's gap repairs itself via its next snapshot read (see "Snapshot,
+// then stream" in the phase 2 design doc).
+//
+// Membership changes lost in that gap cannot wait for a snapshot: a member
+// removed meanwhile would go on receiving the group's events. So every
+// reconnect after the first hands membership a models.MembershipReset, and
+// the hub evicts every subscriber, whose clients reconnect and have their
+// membership read afresh — and their preferences, which covers any of those
+// lost in the gap too.
 //
 // A notified id whose row is gone by the time it's read (deleted between the
 // NOTIFY and this loop's read — not possible for activity_events today, since
@@ -62,7 +85,7 @@ const (
 // skipped, never fatal.
 //
 // Returns nil when ctx is cancelled, so a normal shutdown is not an error.
-func (s *Store) ListenActivity(ctx context.Context, publish func(models.ActivityEvent)) error {
+func (s *Store) ListenActivity(ctx context.Context, publish func(models.ActivityEvent), membership func(models.MembershipChange), preferences func(models.ActivityPreferences)) error {
 	// s.pool.Config() already returns a defensive copy (pgxpool.Pool.Config),
 	// and pgx.ConnectConfig copies it again internally before each dial, so
 	// reusing this same *pgx.ConnConfig across every reconnect attempt below
@@ -71,13 +94,20 @@ func (s *Store) ListenActivity(ctx context.Context, publish func(models.Activity
 	connConfig := s.pool.Config().ConnConfig
 
 	backoff := listenMinBackoff
+	connected := false
 
 	for {
 		if ctx.Err() != nil {
 			return nil
 		}
 
-		err := s.listenOnce(ctx, connConfig, publish, func() { backoff = listenMinBackoff })
+		err := s.listenOnce(ctx, connConfig, publish, membership, preferences, func() {
+			backoff = listenMinBackoff
+			if connected {
+				membership(models.MembershipChange{Kind: models.MembershipReset})
+			}
+			connected = true
+		})
 		if ctx.Err() != nil {
 			return nil
 		}
@@ -105,15 +135,17 @@ func (s *Store) ListenActivity(ctx context.Context, publish func(models.Activity
 // The connection is held for the entire call and Closed on the way out via
 // the defer — never returned to any pool, so nothing else can ever be handed
 // this exact session with a LISTEN still attached to it.
-func (s *Store) listenOnce(ctx context.Context, connConfig *pgx.ConnConfig, publish func(models.ActivityEvent), onConnected func()) error {
+func (s *Store) listenOnce(ctx context.Context, connConfig *pgx.ConnConfig, publish func(models.ActivityEvent), membership func(models.MembershipChange), preferences func(models.ActivityPreferences), onConnected func()) error {
 	conn, err := pgx.ConnectConfig(ctx, connConfig)
 	if err != nil {
 		return err
 	}
 	defer conn.Close(context.Background())
 
-	if _, err := conn.Exec(ctx, "LISTEN "+listenChannel); err != nil {
-		return err
+	for _, channel := range []string{listenChannel, membershipChannel, preferencesChannel} {
+		if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+			return err
+		}
 	}
 	onConnected()
 
@@ -126,6 +158,26 @@ func (s *Store) listenOnce(ctx context.Context, connConfig *pgx.ConnConfig, publ
 			return err
 		}
 
+		if n.Channel == membershipChannel {
+			var change models.MembershipChange
+			if err := json.Unmarshal([]byte(n.Payload), &change); err != nil {
+				log.Printf("activity: unreadable membership change %q, skipping: %v", n.Payload, err)
+				continue
+			}
+			membership(change)
+			continue
+		}
+
+		if n.Channel == preferencesChannel {
+			prefs, err := s.GetActivityPreferences(ctx, n.Payload)
+			if err != nil {
+				log.Printf("activity: failed to read preferences of %q, skipping: %v", n.Payload, err)
+				continue
+			}
+			preferences(prefs)
+			continue
+		}
+
 		event, err := s.GetActivityEventById(ctx, n.Payload)
 		if err != nil {
 			if errors.Is(err, store.ErrRecordNotFound) {
@@ -138,3 +190,13 @@ func (s *Store) listenOnce(ctx context.Context, connConfig *pgx.ConnConfig, publ
 		publish(event)
 	}
 }
+
+// notifyMembership tells every process's LISTEN loop about change. Called in
+// the change's own transaction, it is delivered only if that commits.
+func notifyMembership(ctx context.Context, q *database.Queries, change models.MembershipChange) error {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return q.NotifyActivityMembership(ctx, string(payload))
+}
@@ -19,12 +19,16 @@ import (
 const listenTestTimeout = 5 * time.Second
 
 // publishRecorder collects events handed to ListenActivity's publish
-// callback and lets a test wait for the next one without a race on a plain
-// slice (the loop runs on its own goroutine while the test reads).
+// callback, membership changes handed to its membership callback and
+// preferences handed to its preferences callback, and lets a test wait for
+// the next one without a race on a plain slice (the loop runs on its own
+// goroutine while the test reads).
 type publishRecorder struct {
-	mu     sync.Mutex
-	events []models.ActivityEvent
-	notify chan struct{}
+	mu          sync.Mutex
+	events      []models.ActivityEvent
+	changes     []models.MembershipChange
+	preferences []models.ActivityPreferences
+	notify      chan struct{}
 }
 
 func newPublishRecorder() *publishRecorder {
@@ -41,6 +45,68 @@ func (r *publishRecorder) publish(e models.ActivityEvent) {
 	}
 }
 
+func (r *publishRecorder) membership(c models.MembershipChange) {
+	r.mu.Lock()
+	r.changes = append(r.changes, c)
+	r.mu.Unlock()
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (r *publishRecorder) preferencesChanged(p models.ActivityPreferences) {
+	r.mu.Lock()
+	r.preferences = append(r.preferences, p)
+	r.mu.Unlock()
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// waitForPreferences blocks until at least n preference changes have been
+// recorded, or fails the test once timeout elapses.
+func (r *publishRecorder) waitForPreferences(t *testing.T, n int, timeout time.Duration) []models.ActivityPreferences {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		r.mu.Lock()
+		snapshot := append([]models.ActivityPreferences(nil), r.preferences...)
+		r.mu.Unlock()
+
+		if len(snapshot) >= n {
+			return snapshot
+		}
+		select {
+		case <-r.notify:
+		case <-deadline:
+			t.Fatalf("timed out after %s waiting for %d preference change(s), got %d", timeout, n, len(snapshot))
+		}
+	}
+}
+
+// waitForChanges blocks until at least n membership changes have been
+// recorded, or fails the test once timeout elapses.
+func (r *publishRecorder) waitForChanges(t *testing.T, n int, timeout time.Duration) []models.MembershipChange {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		r.mu.Lock()
+		snapshot := append([]models.MembershipChange(nil), r.changes...)
+		r.mu.Unlock()
+
+		if len(snapshot) >= n {
+			return snapshot
+		}
+		select {
+		case <-r.notify:
+		case <-deadline:
+			t.Fatalf("timed out after %s waiting for %d membership change(s), got %d", timeout, n, len(snapshot))
+		}
+	}
+}
+
 // waitForCount blocks until at least n events have been recorded, or fails
 // the test once timeout elapses.
 func (r *publishRecorder) waitForCount(t *testing.T, n int, timeout time.Duration) []models.ActivityEvent {
@@ -64,12 +130,13 @@ func (r *publishRecorder) waitForCount(t *testing.T, n int, timeout time.Duratio
 }
 
 // countListeners returns how many sessions currently show the exact LISTEN
-// command as their last query in pg_stat_activity.
+// command as their last query in pg_stat_activity. The loop LISTENs on
+// preferencesChannel last, so that is the command a ready loop shows.
 func countListeners(t *testing.T, pool *pgxpool.Pool) int {
 	t.Helper()
 	var count int
 	err := pool.QueryRow(context.Background(),
-		`SELECT count(*) FROM pg_stat_activity WHERE query = 'LISTEN '||$1`, listenChannel).Scan(&count)
+		`SELECT count(*) FROM pg_stat_activity WHERE query = 'LISTEN '||$1`, preferencesChannel).Scan(&count)
 	require.NoError(t, err, "failed to query pg_stat_activity")
 	return count
 }
@@ -108,7 +175,7 @@ func waitForListenerReady(t *testing.T, pool *pgxpool.Pool, baseline int, timeou
 func listenerPids(t *testing.T, pool *pgxpool.Pool) map[int32]bool {
 	t.Helper()
 	rows, err := pool.Query(context.Background(),
-		`SELECT pid FROM pg_stat_activity WHERE query = 'LISTEN '||$1`, listenChannel)
+		`SELECT pid FROM pg_stat_activity WHERE query = 'LISTEN '||$1`, preferencesChannel)
 	require.NoError(t, err, "failed to query pg_stat_activity")
 	defer rows.Close()
 
@@ -167,7 +234,7 @@ func (b *syncBuffer) String() string {
 // until its dedicated connection has actually issued LISTEN (via
 // waitForListenerReady) before returning — so callers can insert/notify
 // immediately afterward without racing the loop's own startup.
-func startListening(t *testing.T, s *Store, publish func(models.ActivityEvent)) context.Context {
+func startListening(t *testing.T, s *Store, rec *publishRecorder) context.Context {
 	t.Helper()
 
 	pool := newTestPool(t)
@@ -175,7 +242,7 @@ func startListening(t *testing.T, s *Store, publish func(models.ActivityEvent))
 
 	ctx, cancel := context.WithCancel(context.Background())
 	done := make(chan error, 1)
-	go func() { done <- s.ListenActivity(ctx, publish) }()
+	go func() { done <- s.ListenActivity(ctx, rec.publish, rec.membership, rec.preferencesChanged) }()
 	t.Cleanup(func() {
 		cancel()
 		select {
@@ -196,7 +263,7 @@ func TestStore_ListenActivity(t *testing.T) {
 		s := newTestStore(t)
 
 		rec := newPublishRecorder()
-		ctx := startListening(t, s, rec.publish)
+		ctx := startListening(t, s, rec)
 
 		actor := addTestUser(t, s)
 		group, err := s.CreateGroup(ctx, newTestGroup(t, "listen", actor))
@@ -218,7 +285,7 @@ func TestStore_ListenActivity(t *testing.T) {
 		s := newTestStore(t)
 
 		rec := newPublishRecorder()
-		ctx := startListening(t, s, rec.publish)
+		ctx := startListening(t, s, rec)
 
 		_, err := newTestPool(t).Exec(ctx, "SELECT pg_notify($1, 'no-such-event-id')", listenChannel)
 		require.NoError(t, err, "failed to fire the bogus notification")
@@ -257,7 +324,7 @@ func TestStore_ListenActivity(t *testing.T) {
 
 		rec := newPublishRecorder()
 		before := listenerPids(t, pool)
-		ctx := startListening(t, s, rec.publish)
+		ctx := startListening(t, s, rec)
 
 		firstPid := waitForNewListenerPid(t, pool, before, listenTestTimeout)
 
@@ -294,6 +361,54 @@ func TestStore_ListenActivity(t *testing.T) {
 		}}))
 		events := rec.waitForCount(t, 2, listenTestTimeout)
 		require.Equal(t, "After", events[1].ActorName, "the event after reconnect must still be delivered")
+
+		changes := rec.waitForChanges(t, 2, listenTestTimeout)
+		require.Equal(t, models.MembershipChange{Kind: models.MembershipReset}, changes[len(changes)-1],
+			"changes may have been missed while the loop was away, so the hub must be told to evict")
+	})
+
+	t.Run("membership changes arrive in commit order, interleaved with events", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+
+		rec := newPublishRecorder()
+		ctx := startListening(t, s, rec)
+
+		owner := addTestUser(t, s)
+		member := addTestUser(t, s)
+		group, err := s.CreateGroup(ctx, newTestGroup(t, "membership", owner))
+		require.NoError(t, err, "failed to seed the group")
+		require.NoError(t, s.AddUserToGroup(ctx, group.Id, owner, member))
+		require.NoError(t, s.RemoveUserFromGroup(ctx, group.Id, member))
+		require.NoError(t, s.SoftDeleteGroup(ctx, group.Id))
+
+		changes := rec.waitForChanges(t, 4, listenTestTimeout)
+		require.Equal(t, []models.MembershipChange{
+			{Kind: models.MembershipAdded, UserId: owner, GroupId: group.Id},
+			{Kind: models.MembershipAdded, UserId: member, GroupId: group.Id},
+			{Kind: models.MembershipRemoved, UserId: member, GroupId: group.Id},
+			{Kind: models.MembershipGroupDeleted, GroupId: group.Id},
+		}, changes)
+	})
+
+	t.Run("a preferences change arrives as the preferences it saved", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+
+		rec := newPublishRecorder()
+		ctx := startListening(t, s, rec)
+
+		user := addTestUser(t, s)
+		_, err := s.SetActivityPreferences(ctx, models.ActivityPreferences{
+			UserId: user,
+			Mutes:  []models.ActivityMute{{Kind: "comment_added"}},
+		})
+		require.NoError(t, err)
+
+		got := rec.waitForPreferences(t, 1, listenTestTimeout)
+		require.Equal(t, user, got[0].UserId)
+		require.Equal(t, []models.ActivityMute{{Kind: "comment_added"}}, got[0].Mutes,
+			"the notification names the user; the loop reads back what they saved")
 	})
 
 	t.Run("returns nil when ctx is cancelled", func(t *testing.T) {
@@ -302,7 +417,9 @@ func TestStore_ListenActivity(t *testing.T) {
 
 		ctx, cancel := context.WithCancel(context.Background())
 		done := make(chan error, 1)
-		go func() { done <- s.ListenActivity(ctx, func(models.ActivityEvent) {}) }()
+		go func() {
+			done <- s.ListenActivity(ctx, func(models.ActivityEvent) {}, func(models.MembershipChange) {}, func(models.ActivityPreferences) {})
+		}()
 
 		cancel()
 		select {
@@ -274,6 +274,39 @@ func activityEventRowToModel(row database.GetActivityFeedRowsRow) (models.Activi
 	}, nil
 }
 
+// activityOutboxRowToModel converts an outbox row into the event the relay
+// delivers: everything a sink needs to write it, with the id and created_at it
+// was given when enqueued. Seq, GroupName and Read are the feed's and stay
+// zero.
+func activityOutboxRowToModel(row database.ActivityOutbox) (models.ActivityEvent, error) {
+	var payload map[string]any
+	if len(row.Payload) > 0 {
+		if err := json.Unmarshal(row.Payload, &payload); err != nil {
+			return models.ActivityEvent{}, err
+		}
+	}
+	return models.ActivityEvent{
+		Id:        row.ID,
+		GroupId:   row.GroupID,
+		ActorId:   row.ActorID,
+		ActorName: row.ActorName,
+		Kind:      row.Kind,
+		TitleId:   textToPtr(row.TitleID),
+		TitleName: textToPtr(row.TitleName),
+		Payload:   payload,
+		CreatedAt: row.CreatedAt.Time,
+	}, nil
+}
+
+// activityPayloadToJSON encodes an event payload for its JSONB column; a nil
+// payload is stored as {} to match the column default.
+func activityPayloadToJSON(payload map[string]any) ([]byte, error) {
+	if payload == nil {
+		return []byte(`{}`), nil
+	}
+	return json.Marshal(payload)
+}
+
 // int64PtrToNullable adapts an optional cursor to the generated nullable param.
 func int64PtrToNullable(v *int64) pgtype.Int8 {
 	if v == nil {
@@ -0,0 +1,258 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/lealre/movies-backend/internal/database"
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+// requestTx is the transaction InTransaction opens, carried on the context so
+// that every Store method called with that context runs in it.
+//
+// Each statement runs in a savepoint of its own. A statement that fails in a
+// Postgres transaction aborts the whole transaction, and the service code was
+// written against autocommit — it treats a duplicate insert as an answer and
+// carries on — so without the savepoint the first expected failure would turn
+// every later statement of the request into an error.
+//
+// mu serialises the statements: a transaction is one connection, and the
+// request's work is not always on one goroutine (a bulk add resolves titles
+// concurrently). It is held from the savepoint to its release, which for a
+// query is until its rows are closed; the generated queries always read their
+// rows to the end before returning, so nothing waits on it for long.
+type requestTx struct {
+	mu sync.Mutex
+	tx pgx.Tx
+}
+
+type requestTxKey struct{}
+
+func requestTxFrom(ctx context.Context) *requestTx {
+	rt, _ := ctx.Value(requestTxKey{}).(*requestTx)
+	return rt
+}
+
+// queries is what a single-statement method runs on: the request's
+// transaction under InTransaction, and the pool otherwise.
+//
+// The provider cache and quota deliberately use s.q instead. A fetched title
+// and a spent upstream request stay true when the request that caused them
+// fails, and rolling them back would only cost quota the next time.
+func (s *Store) queries(ctx context.Context) *database.Queries {
+	if rt := requestTxFrom(ctx); rt != nil {
+		return database.New(rt)
+	}
+	return s.q
+}
+
+// InTransaction runs fn in one transaction that every Store method called
+// with fn's context joins. A call made under another InTransaction simply
+// runs fn in the outer one.
+func (s *Store) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if requestTxFrom(ctx) != nil {
+		return fn(ctx)
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := fn(context.WithValue(ctx, requestTxKey{}, &requestTx{tx: tx})); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// savepoint runs fn in a savepoint of the request's transaction, holding mu
+// throughout.
+func (rt *requestTx) savepoint(ctx context.Context, fn func(sp pgx.Tx) error) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	sp, err := rt.tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	return endSavepoint(ctx, sp, fn(sp))
+}
+
+// endSavepoint releases sp when err is nil and rolls back to it otherwise,
+// returning err. pgx.ErrNoRows is not a failed statement — it is a query that
+// found nothing — so it releases too.
+func endSavepoint(ctx context.Context, sp pgx.Tx, err error) error {
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		_ = sp.Rollback(ctx)
+		return err
+	}
+	if releaseErr := sp.Commit(ctx); releaseErr != nil {
+		return releaseErr
+	}
+	return err
+}
+
+func (rt *requestTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := rt.savepoint(ctx, func(sp pgx.Tx) error {
+		var err error
+		tag, err = sp.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+func (rt *requestTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	rt.mu.Lock()
+	sp, err := rt.tx.Begin(ctx)
+	if err != nil {
+		rt.mu.Unlock()
+		return nil, err
+	}
+	rows, err := sp.Query(ctx, sql, args...)
+	if err != nil {
+		err = endSavepoint(ctx, sp, err)
+		rt.mu.Unlock()
+		return nil, err
+	}
+	return &savepointRows{Rows: rows, ctx: ctx, sp: sp, unlock: rt.mu.Unlock}, nil
+}
+
+func (rt *requestTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	rt.mu.Lock()
+	sp, err := rt.tx.Begin(ctx)
+	if err != nil {
+		rt.mu.Unlock()
+		return errRow{err}
+	}
+	return &savepointRow{row: sp.QueryRow(ctx, sql, args...), ctx: ctx, sp: sp, unlock: rt.mu.Unlock}
+}
+
+// savepointRows ends its savepoint, and lets the next statement in, once the
+// rows are done with: read to the end or closed, whichever comes first.
+type savepointRows struct {
+	pgx.Rows
+	ctx    context.Context
+	sp     pgx.Tx
+	unlock func()
+	closed bool
+	err    error
+}
+
+func (r *savepointRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.Close()
+	return false
+}
+
+func (r *savepointRows) Close() {
+	if r.closed {
+		return
+	}
+	r.closed = true
+	r.Rows.Close()
+	r.err = endSavepoint(r.ctx, r.sp, r.Rows.Err())
+	r.unlock()
+}
+
+func (r *savepointRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.Rows.Err()
+}
+
+// savepointRow is savepointRows for QueryRow, done with once scanned.
+type savepointRow struct {
+	row    pgx.Row
+	ctx    context.Context
+	sp     pgx.Tx
+	unlock func()
+}
+
+func (r *savepointRow) Scan(dest ...any) error {
+	defer r.unlock()
+	return endSavepoint(r.ctx, r.sp, r.row.Scan(dest...))
+}
+
+type errRow struct{ err error }
+
+func (r errRow) Scan(...any) error { return r.err }
+
+// EnqueueActivityEvents writes events to the outbox for the relay to deliver.
+// Under InTransaction they commit with the request's writes or not at all,
+// which is the point of the outbox.
+//
+// The id is settled here and created_at is now, the moment of the write, not
+// of its delivery.
+func (s *Store) EnqueueActivityEvents(ctx context.Context, events []models.ActivityEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return s.inTx(ctx, func(q *database.Queries) error {
+		now := time.Now()
+		for _, e := range events {
+			payload, err := activityPayloadToJSON(e.Payload)
+			if err != nil {
+				return err
+			}
+			if err := q.InsertActivityOutboxRow(ctx, database.InsertActivityOutboxRowParams{
+				ID:        firstNonEmpty(e.Id, uuid.NewString()),
+				GroupID:   e.GroupId,
+				ActorID:   e.ActorId,
+				ActorName: e.ActorName,
+				Kind:      e.Kind,
+				TitleID:   ptrToText(e.TitleId),
+				TitleName: ptrToText(e.TitleName),
+				Payload:   payload,
+				CreatedAt: timeToTimestamptz(now),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RelayActivityOutbox claims the oldest outbox rows, hands them to deliver and
+// deletes them, all in one transaction: the rows stay locked against other
+// relays while deliver runs, and if it fails, or the process dies, they are
+// released as they were for the next attempt.
+func (s *Store) RelayActivityOutbox(ctx context.Context, limit int, deliver func(ctx context.Context, events []models.ActivityEvent) error) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+	var delivered int
+	err := s.inTx(ctx, func(q *database.Queries) error {
+		rows, err := q.ClaimActivityOutboxRows(ctx, int64(limit))
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+		events := make([]models.ActivityEvent, 0, len(rows))
+		seqs := make([]int64, 0, len(rows))
+		for _, row := range rows {
+			event, err := activityOutboxRowToModel(row)
+			if err != nil {
+				return err
+			}
+			events = append(events, event)
+			seqs = append(seqs, row.Seq)
+		}
+		if err := deliver(ctx, events); err != nil {
+			return err
+		}
+		delivered = len(events)
+		return q.DeleteActivityOutboxRows(ctx, seqs)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return delivered, nil
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+func countRows(t *testing.T, s *Store, table string) int {
+	t.Helper()
+	var n int
+	require.NoError(t, s.pool.QueryRow(context.Background(), `SELECT count(*) FROM `+table).Scan(&n))
+	return n
+}
+
+// relayAll delivers the whole outbox to the activity log, as StoreSink would.
+func relayAll(t *testing.T, s *Store) int {
+	t.Helper()
+	n, err := s.RelayActivityOutbox(context.Background(), 100, s.InsertActivityEvents)
+	require.NoError(t, err)
+	return n
+}
+
+func TestStore_ActivityOutbox(t *testing.T) {
+	t.Run("events commit with the writes of their transaction and are relayed with their ids", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+		owner := addTestUser(t, s)
+		movie := newTestMovieTitle(t, "tt-outbox", "Outboxed", 7)
+		require.NoError(t, s.AddTitle(ctx, movie))
+		group, err := s.CreateGroup(ctx, newTestGroup(t, "outbox", owner))
+		require.NoError(t, err)
+
+		err = s.InTransaction(ctx, func(ctx context.Context) error {
+			if _, err := s.AddComment(ctx, newTestMovieComment(t, movie.ID, owner, group.Id, "hello")); err != nil {
+				return err
+			}
+			require.Zero(t, countRows(t, s, "comments"), "the write must not be visible outside the transaction before it commits")
+			return s.EnqueueActivityEvents(ctx, []models.ActivityEvent{
+				{Id: "o1", GroupId: group.Id, ActorId: owner, ActorName: "owner", Kind: "comment_added"},
+			})
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, countRows(t, s, "comments"))
+		require.Equal(t, 1, countRows(t, s, "activity_outbox"))
+
+		require.Equal(t, 1, relayAll(t, s))
+		require.Zero(t, countRows(t, s, "activity_outbox"), "a delivered event leaves the outbox")
+		event, err := s.GetActivityEventById(ctx, "o1")
+		require.NoError(t, err)
+		require.Equal(t, "comment_added", event.Kind)
+	})
+
+	t.Run("a rolled back transaction leaves neither the write nor its events", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+		owner := addTestUser(t, s)
+		group, err := s.CreateGroup(ctx, newTestGroup(t, "outbox", owner))
+		require.NoError(t, err)
+		failed := errors.New("the handler failed")
+
+		err = s.InTransaction(ctx, func(ctx context.Context) error {
+			require.NoError(t, s.AddUser(ctx, newTestUser(t)))
+			require.NoError(t, s.EnqueueActivityEvents(ctx, []models.ActivityEvent{
+				{GroupId: group.Id, ActorId: owner, ActorName: "owner", Kind: "title_added"},
+			}))
+			return failed
+		})
+		require.ErrorIs(t, err, failed)
+		require.Equal(t, 1, countRows(t, s, "users"))
+		require.Zero(t, countRows(t, s, "activity_outbox"))
+	})
+
+	t.Run("a failed statement does not abort the rest of the transaction", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+		user := newTestUser(t)
+		require.NoError(t, s.AddUser(ctx, user))
+
+		err := s.InTransaction(ctx, func(ctx context.Context) error {
+			require.ErrorIs(t, s.AddUser(ctx, user), store.ErrDuplicatedRecord)
+			return s.AddUser(ctx, newTestUser(t))
+		})
+		require.NoError(t, err, "service code relies on carrying on after an expected failure, as it can in autocommit")
+		require.Equal(t, 2, countRows(t, s, "users"))
+	})
+
+	t.Run("a failed delivery keeps the events, and delivering them again adds nothing", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+		owner := addTestUser(t, s)
+		group, err := s.CreateGroup(ctx, newTestGroup(t, "outbox", owner))
+		require.NoError(t, err)
+		require.NoError(t, s.EnqueueActivityEvents(ctx, []models.ActivityEvent{
+			{Id: "o1", GroupId: group.Id, ActorId: owner, ActorName: "owner", Kind: "title_added"},
+			{Id: "o2", GroupId: group.Id, ActorId: owner, ActorName: "owner", Kind: "title_removed"},
+		}))
+
+		// The sink took the batch, and then the delivery failed before the
+		// outbox was told: the next attempt hands over the same events.
+		_, err = s.RelayActivityOutbox(ctx, 100, func(ctx context.Context, events []models.ActivityEvent) error {
+			require.NoError(t, s.InsertActivityEvents(ctx, events))
+			return errors.New("lost the connection")
+		})
+		require.Error(t, err)
+		require.Equal(t, 2, countRows(t, s, "activity_outbox"))
+
+		require.Equal(t, 2, relayAll(t, s))
+		require.Equal(t, 2, countRows(t, s, "activity_events"), "a redelivered id must not be logged twice")
+		require.Zero(t, relayAll(t, s))
+	})
+}
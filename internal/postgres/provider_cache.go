@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/database"
+)
+
+// GetProviderCacheEntry returns the cached value stored under key and when it
+// expires, or store.ErrRecordNotFound when there is none or it has expired.
+func (s *Store) GetProviderCacheEntry(ctx context.Context, key string) ([]byte, time.Time, error) {
+	row, err := s.q.GetProviderCacheEntry(ctx, key)
+	if err != nil {
+		return nil, time.Time{}, notFound(err)
+	}
+	return row.Value, row.ExpiresAt.Time, nil
+}
+
+// PutProviderCacheEntry stores value under key until expiresAt, replacing any
+// entry already there.
+func (s *Store) PutProviderCacheEntry(ctx context.Context, key string, value []byte, expiresAt time.Time) error {
+	return s.q.UpsertProviderCacheEntry(ctx, database.UpsertProviderCacheEntryParams{
+		Key:       key,
+		Value:     value,
+		ExpiresAt: timeToTimestamptz(expiresAt),
+	})
+}
+
+// DeleteExpiredProviderCacheEntries removes every provider cache entry past its
+// expiry and returns how many it removed. Reads already skip them; this only
+// keeps the table from holding titles nobody has fetched in a long time.
+func (s *Store) DeleteExpiredProviderCacheEntries(ctx context.Context) (int64, error) {
+	return s.q.DeleteExpiredProviderCacheEntries(ctx)
+}
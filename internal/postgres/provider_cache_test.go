@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+func TestStore_ProviderCache(t *testing.T) {
+	t.Run("round trip, overwrite, and expiry", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+		require.NoError(t, s.PutProviderCacheEntry(ctx, "title:tt1", []byte(`{"ID":"tt1"}`), expiresAt))
+
+		value, gotExpiry, err := s.GetProviderCacheEntry(ctx, "title:tt1")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"ID":"tt1"}`, string(value))
+		require.True(t, expiresAt.Equal(gotExpiry), "expiry = %v, want %v", gotExpiry, expiresAt)
+
+		require.NoError(t, s.PutProviderCacheEntry(ctx, "title:tt1", []byte(`{"ID":"tt1","Plot":"new"}`), expiresAt))
+		value, _, err = s.GetProviderCacheEntry(ctx, "title:tt1")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"ID":"tt1","Plot":"new"}`, string(value), "Expected a second put to replace the entry")
+
+		require.NoError(t, s.PutProviderCacheEntry(ctx, "title:tt2", []byte(`{}`), time.Now().Add(-time.Minute)))
+		_, _, err = s.GetProviderCacheEntry(ctx, "title:tt2")
+		require.ErrorIs(t, err, store.ErrRecordNotFound, "Expected an expired entry to read as absent")
+
+		removed, err := s.DeleteExpiredProviderCacheEntries(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), removed, "Expected only the expired entry to be removed")
+	})
+}
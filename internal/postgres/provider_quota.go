@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/lealre/movies-backend/internal/database"
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+// ReserveProviderRequest counts one request to provider against today's (UTC)
+// total if the total is still under limit, and reports whether it did. A
+// non-positive limit reserves nothing.
+func (s *Store) ReserveProviderRequest(ctx context.Context, provider string, limit int) (bool, error) {
+	if limit <= 0 {
+		return false, nil
+	}
+	_, err := s.q.ReserveProviderRequest(ctx, database.ReserveProviderRequestParams{
+		Provider:     provider,
+		RequestLimit: clampToInt32(limit),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetProviderRequestCountsToday returns today's (UTC) request count for every
+// provider that has made a request today, ordered by provider. The slice is
+// empty, never nil, when none has.
+func (s *Store) GetProviderRequestCountsToday(ctx context.Context) ([]models.ProviderRequestCount, error) {
+	rows, err := s.q.GetProviderRequestCountsToday(ctx)
+	if err != nil {
+		return []models.ProviderRequestCount{}, err
+	}
+
+	counts := make([]models.ProviderRequestCount, 0, len(rows))
+	for _, row := range rows {
+		counts = append(counts, models.ProviderRequestCount{
+			Provider: row.Provider,
+			Day:      row.Day.Time,
+			Requests: int(row.Requests),
+		})
+	}
+	return counts, nil
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_ProviderQuota(t *testing.T) {
+	t.Run("reserves up to the limit and counts per provider", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+
+		for i := range 3 {
+			ok, err := s.ReserveProviderRequest(ctx, "omdb", 3)
+			require.NoError(t, err)
+			require.True(t, ok, "Expected request %d to fit under the limit", i+1)
+		}
+		ok, err := s.ReserveProviderRequest(ctx, "omdb", 3)
+		require.NoError(t, err)
+		require.False(t, ok, "Expected the fourth request to be refused")
+
+		// A higher limit (the interactive one) still has room.
+		ok, err = s.ReserveProviderRequest(ctx, "omdb", 4)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		ok, err = s.ReserveProviderRequest(ctx, "tmdb", 0)
+		require.NoError(t, err)
+		require.False(t, ok, "Expected a zero limit to reserve nothing")
+
+		counts, err := s.GetProviderRequestCountsToday(ctx)
+		require.NoError(t, err)
+		require.Len(t, counts, 1, "Expected only the provider with requests to be listed")
+		require.Equal(t, "omdb", counts[0].Provider)
+		require.Equal(t, 4, counts[0].Requests)
+	})
+
+	t.Run("no requests yet is an empty list", func(t *testing.T) {
+		resetDB(t)
+		s := newTestStore(t)
+
+		counts, err := s.GetProviderRequestCountsToday(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, counts)
+		require.Empty(t, counts)
+	})
+}
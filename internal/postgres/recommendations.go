@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/lealre/movies-backend/internal/database"
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+// GetRecommendationCandidates returns up to limit catalogue titles groupId has
+// neither watched nor rated, best IMDb rating first (see the query for the
+// exact rule). The slice is empty, never nil, when there is nothing left to
+// recommend.
+func (s *Store) GetRecommendationCandidates(ctx context.Context, groupId string, limit int) ([]models.RecommendationCandidate, error) {
+	if limit <= 0 {
+		return []models.RecommendationCandidate{}, nil
+	}
+
+	rows, err := s.queries(ctx).GetRecommendationCandidates(ctx, database.GetRecommendationCandidatesParams{
+		GroupID:        groupId,
+		CandidateLimit: int64(limit),
+	})
+	if err != nil {
+		return []models.RecommendationCandidate{}, err
+	}
+
+	candidates := make([]models.RecommendationCandidate, 0, len(rows))
+	for _, row := range rows {
+		title, err := rowToTitle(database.Title{
+			ID:              row.ID,
+			PrimaryTitle:    row.PrimaryTitle,
+			Type:            row.Type,
+			StartYear:       row.StartYear,
+			RatingAggregate: row.RatingAggregate,
+			VoteCount:       row.VoteCount,
+			AddedAt:         row.AddedAt,
+			UpdatedAt:       row.UpdatedAt,
+			Metadata:        row.Metadata,
+		})
+		if err != nil {
+			return []models.RecommendationCandidate{}, err
+		}
+		candidates = append(candidates, models.RecommendationCandidate{Title: title, InGroup: row.InGroup})
+	}
+	return candidates, nil
+}
+
+// GetSharedRatingsByTitleIds returns the ratings other groups left on
+// titleIds, restricted to groups that opted in to sharing and excluding
+// excludeGroupId. Season rows are not assembled: the collaborative model only
+// reads each rating's overall note, and the per-season breakdown of another
+// group's ratings is exactly the kind of detail sharing does not hand out.
+func (s *Store) GetSharedRatingsByTitleIds(ctx context.Context, titleIds []string, excludeGroupId string) ([]models.UserRating, error) {
+	if len(titleIds) == 0 {
+		return []models.UserRating{}, nil
+	}
+
+	rows, err := s.queries(ctx).GetSharedRatingRowsByTitleIds(ctx, database.GetSharedRatingRowsByTitleIdsParams{
+		TitleIds: titleIds,
+		GroupID:  excludeGroupId,
+	})
+	if err != nil {
+		return []models.UserRating{}, err
+	}
+
+	ratings := make([]models.UserRating, 0, len(rows))
+	for _, row := range rows {
+		ratings = append(ratings, ratingRowToModel(row, nil))
+	}
+	return ratings, nil
+}
+
+// GroupSharesRecommendations reports whether groupId has opted in to pooling
+// its ratings into other groups' recommendations.
+func (s *Store) GroupSharesRecommendations(ctx context.Context, groupId string) (bool, error) {
+	return s.queries(ctx).GroupSharesRecommendations(ctx, groupId)
+}
+
+// SetGroupRecommendationSharing turns groupId's opt-in on or off. Both
+// directions are idempotent; userId is recorded as who enabled it, and only
+// when the opt-in is created.
+func (s *Store) SetGroupRecommendationSharing(ctx context.Context, groupId, userId string, enabled bool) error {
+	if !enabled {
+		return s.queries(ctx).DeleteGroupRecommendationSharing(ctx, groupId)
+	}
+	return s.queries(ctx).InsertGroupRecommendationSharing(ctx, database.InsertGroupRecommendationSharingParams{
+		GroupID:   groupId,
+		EnabledBy: userId,
+	})
+}
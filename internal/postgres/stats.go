@@ -0,0 +1,10 @@
+package postgres
+
+import "context"
+
+// GetGroupStatsVersion returns an opaque fingerprint of the rows groupId's
+// statistics are computed from. Equal fingerprints mean nothing those stats
+// read has changed in between.
+func (s *Store) GetGroupStatsVersion(ctx context.Context, groupId string) (string, error) {
+	return s.queries(ctx).GetGroupStatsVersion(ctx, groupId)
+}
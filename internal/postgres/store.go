@@ -4,6 +4,7 @@ import (
 	"context"
 	"math"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/lealre/movies-backend/internal/database"
 	"github.com/lealre/movies-backend/internal/store"
@@ -18,6 +19,14 @@ type Store struct {
 
 var _ store.Store = (*Store)(nil)
 
+// Asserted because nothing else would notice their absence: the server
+// type-asserts for them, and a Store without them just runs the title provider
+// cache memory-only, the providers unmetered and the activity outbox
+// unavailable.
+var _ store.ProviderCache = (*Store)(nil)
+var _ store.ProviderQuota = (*Store)(nil)
+var _ store.ActivityOutbox = (*Store)(nil)
+
 // New builds a Store from an already-connected pgxpool.Pool.
 func New(pool *pgxpool.Pool) *Store {
 	return &Store{pool: pool, q: database.New(pool)}
@@ -65,7 +74,14 @@ func pageOffset(size, page int) (int64, bool) {
 // than one statement (a rating and its season rows, a group and its members);
 // a single-statement write needs no transaction, since Postgres commits it on
 // its own.
+//
+// Under InTransaction it is a savepoint in the request's transaction instead,
+// so the write is still all-or-nothing on its own and also commits with the
+// rest of the request or not at all.
 func (s *Store) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	if rt := requestTxFrom(ctx); rt != nil {
+		return rt.savepoint(ctx, func(sp pgx.Tx) error { return fn(s.q.WithTx(sp)) })
+	}
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return err
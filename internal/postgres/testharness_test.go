@@ -74,7 +74,11 @@ func resetDB(t *testing.T) {
 	ctx := context.Background()
 	const stmt = `TRUNCATE users, titles, ratings, rating_seasons, comments,
 		comment_seasons, groups, group_members, group_titles,
-		group_title_seasons, activity_events, activity_event_reads, activity_read_floors
+		group_title_seasons, activity_events, activity_event_reads, activity_read_floors, activity_outbox,
+		webhook_endpoints, webhook_deliveries,
+		activity_mutes, activity_quiet_hours, activity_digests,
+		group_recommendation_sharing, provider_cache, provider_request_counts,
+		title_availability, title_availability_offers, user_streaming_services
 		RESTART IDENTITY CASCADE`
 
 	if _, err := newTestPool(t).Exec(ctx, stmt); err != nil {
@@ -150,6 +154,10 @@ var tableNames = []string{
 	"comments", "comment_seasons", "groups", "group_members",
 	"group_titles", "group_title_seasons",
 	"activity_events", "activity_event_reads", "activity_read_floors", "activity_visible_events",
+	"activity_outbox", "webhook_endpoints", "webhook_deliveries",
+	"activity_mutes", "activity_quiet_hours", "activity_digests",
+	"group_recommendation_sharing", "provider_cache", "provider_request_counts",
+	"title_availability", "title_availability_offers", "user_streaming_services",
 }
 
 // existingTables returns which of tableNames are currently present in the
@@ -9,7 +9,7 @@ import (
 )
 
 func (s *Store) GetTitleById(ctx context.Context, id string) (models.Title, error) {
-	row, err := s.q.GetTitleById(ctx, id)
+	row, err := s.queries(ctx).GetTitleById(ctx, id)
 	if err != nil {
 		return models.Title{}, notFound(err)
 	}
@@ -23,7 +23,7 @@ func (s *Store) AddTitle(ctx context.Context, title models.Title) error {
 	if err != nil {
 		return err
 	}
-	if err := s.q.InsertTitle(ctx, params); err != nil {
+	if err := s.queries(ctx).InsertTitle(ctx, params); err != nil {
 		if isUniqueViolation(err) {
 			return store.ErrDuplicatedRecord
 		}
@@ -33,7 +33,7 @@ func (s *Store) AddTitle(ctx context.Context, title models.Title) error {
 }
 
 func (s *Store) DeleteTitle(ctx context.Context, id string) (bool, error) {
-	n, err := s.q.DeleteTitle(ctx, id)
+	n, err := s.queries(ctx).DeleteTitle(ctx, id)
 	if err != nil {
 		return false, err
 	}
@@ -41,7 +41,27 @@ func (s *Store) DeleteTitle(ctx context.Context, id string) (bool, error) {
 }
 
 func (s *Store) TitleExists(ctx context.Context, id string) (bool, error) {
-	return s.q.TitleExists(ctx, id)
+	return s.queries(ctx).TitleExists(ctx, id)
+}
+
+// GetTitlesByIds fetches the titles with the given ids in one round trip,
+// ordered by id. Ids with no catalogue row are simply absent from the result;
+// the slice is empty, never nil, when none match.
+func (s *Store) GetTitlesByIds(ctx context.Context, ids []string) ([]models.Title, error) {
+	rows, err := s.queries(ctx).GetTitleRowsByIds(ctx, ids)
+	if err != nil {
+		return []models.Title{}, err
+	}
+
+	titles := make([]models.Title, 0, len(rows))
+	for _, row := range rows {
+		title, err := rowToTitle(row)
+		if err != nil {
+			return []models.Title{}, err
+		}
+		titles = append(titles, title)
+	}
+	return titles, nil
 }
 
 // titleOrderKeys is the sort-key whitelist for GetTitlesPage. Unknown keys
@@ -93,7 +113,7 @@ func (s *Store) GetTitlesPage(
 	}
 	descending := ascending != nil && !*ascending
 
-	total, err := s.q.CountTitles(ctx)
+	total, err := s.queries(ctx).CountTitles(ctx)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -106,7 +126,7 @@ func (s *Store) GetTitlesPage(
 		return []models.Title{}, total, nil
 	}
 
-	rows, err := s.q.GetTitlesPage(ctx, database.GetTitlesPageParams{
+	rows, err := s.queries(ctx).GetTitlesPage(ctx, database.GetTitlesPageParams{
 		OrderBy:    orderBy,
 		Descending: descending,
 		PageSize:   int64(size),
@@ -131,7 +151,7 @@ func (s *Store) GetTitlesPage(
 // ListTitleIds returns every title id, ordered. Not part of store.Store —
 // used by internal tools (cmd/routines).
 func (s *Store) ListTitleIds(ctx context.Context) ([]string, error) {
-	ids, err := s.q.ListTitleIds(ctx)
+	ids, err := s.queries(ctx).ListTitleIds(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -156,7 +176,7 @@ func (s *Store) UpdateTitle(ctx context.Context, title models.Title) error {
 	// a literal listing every field silently omits any column added later,
 	// storing a zero value, whereas a conversion stops compiling the moment
 	// the two shapes diverge — which is exactly when someone should look.
-	n, err := s.q.UpdateTitle(ctx, database.UpdateTitleParams(params))
+	n, err := s.queries(ctx).UpdateTitle(ctx, database.UpdateTitleParams(params))
 	if err != nil {
 		return err
 	}
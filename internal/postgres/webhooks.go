@@ -0,0 +1,290 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/lealre/movies-backend/internal/database"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// CreateWebhookEndpoint inserts an endpoint. The id and timestamps are
+// generated here; the secret is the caller's, since it is the caller that
+// shows it to the owner.
+func (s *Store) CreateWebhookEndpoint(ctx context.Context, endpoint models.WebhookEndpoint) (models.WebhookEndpoint, error) {
+	now := time.Now()
+	row, err := s.queries(ctx).InsertWebhookEndpoint(ctx, database.InsertWebhookEndpointParams{
+		ID:        uuid.NewString(),
+		GroupID:   endpoint.GroupId,
+		Url:       endpoint.URL,
+		Secret:    endpoint.Secret,
+		Kinds:     endpoint.Kinds,
+		CreatedBy: endpoint.CreatedBy,
+		CreatedAt: timeToTimestamptz(now),
+		UpdatedAt: timeToTimestamptz(now),
+	})
+	if err != nil {
+		return models.WebhookEndpoint{}, err
+	}
+	return webhookEndpointRowToModel(row), nil
+}
+
+// GetWebhookEndpoints returns a group's endpoints, oldest first.
+func (s *Store) GetWebhookEndpoints(ctx context.Context, groupId string) ([]models.WebhookEndpoint, error) {
+	rows, err := s.queries(ctx).GetWebhookEndpointsByGroupId(ctx, groupId)
+	if err != nil {
+		return []models.WebhookEndpoint{}, err
+	}
+	return webhookEndpointRowsToModels(rows), nil
+}
+
+func (s *Store) GetWebhookEndpointById(ctx context.Context, groupId, endpointId string) (models.WebhookEndpoint, error) {
+	row, err := s.queries(ctx).GetWebhookEndpointById(ctx, database.GetWebhookEndpointByIdParams{
+		ID:      endpointId,
+		GroupID: groupId,
+	})
+	if err != nil {
+		return models.WebhookEndpoint{}, notFound(err)
+	}
+	return webhookEndpointRowToModel(row), nil
+}
+
+func (s *Store) UpdateWebhookEndpoint(ctx context.Context, endpoint models.WebhookEndpoint) (models.WebhookEndpoint, error) {
+	row, err := s.queries(ctx).UpdateWebhookEndpoint(ctx, database.UpdateWebhookEndpointParams{
+		ID:                  endpoint.Id,
+		GroupID:             endpoint.GroupId,
+		Url:                 endpoint.URL,
+		Kinds:               endpoint.Kinds,
+		DisabledAt:          ptrToTimestamptz(endpoint.DisabledAt),
+		ConsecutiveFailures: clampToInt32(endpoint.ConsecutiveFailures),
+		UpdatedAt:           timeToTimestamptz(time.Now()),
+	})
+	if err != nil {
+		return models.WebhookEndpoint{}, notFound(err)
+	}
+	return webhookEndpointRowToModel(row), nil
+}
+
+// DeleteWebhookEndpoint deletes an endpoint and, with it, its deliveries.
+func (s *Store) DeleteWebhookEndpoint(ctx context.Context, groupId, endpointId string) error {
+	n, err := s.queries(ctx).DeleteWebhookEndpoint(ctx, database.DeleteWebhookEndpointParams{
+		ID:      endpointId,
+		GroupID: groupId,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetWebhookDeliveries returns an endpoint's delivery log newest first. before
+// is an exclusive seq cursor; nil starts at the newest.
+func (s *Store) GetWebhookDeliveries(ctx context.Context, endpointId string, before *int64, limit int) ([]models.WebhookDelivery, error) {
+	if limit <= 0 {
+		return []models.WebhookDelivery{}, nil
+	}
+	rows, err := s.queries(ctx).GetWebhookDeliveries(ctx, database.GetWebhookDeliveriesParams{
+		EndpointID: endpointId,
+		Before:     int64PtrToNullable(before),
+		RowLimit:   int64(limit),
+	})
+	if err != nil {
+		return []models.WebhookDelivery{}, err
+	}
+	deliveries := make([]models.WebhookDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i] = webhookDeliveryRowToModel(row)
+	}
+	return deliveries, nil
+}
+
+func (s *Store) RedeliverWebhookDelivery(ctx context.Context, endpointId, deliveryId string) (models.WebhookDelivery, error) {
+	row, err := s.queries(ctx).RedeliverWebhookDelivery(ctx, database.RedeliverWebhookDeliveryParams{
+		Now:        timeToTimestamptz(time.Now()),
+		ID:         deliveryId,
+		EndpointID: endpointId,
+	})
+	if err != nil {
+		return models.WebhookDelivery{}, notFound(err)
+	}
+	return webhookDeliveryRowToModel(row), nil
+}
+
+// GetWebhookEndpointsForEvent returns the enabled endpoints of groupId that
+// subscribe to kind.
+func (s *Store) GetWebhookEndpointsForEvent(ctx context.Context, groupId, kind string) ([]models.WebhookEndpoint, error) {
+	rows, err := s.queries(ctx).GetWebhookEndpointsForEvent(ctx, database.GetWebhookEndpointsForEventParams{
+		GroupID: groupId,
+		Kind:    kind,
+	})
+	if err != nil {
+		return []models.WebhookEndpoint{}, err
+	}
+	return webhookEndpointRowsToModels(rows), nil
+}
+
+// EnqueueWebhookDeliveries queues deliveries, all of them or none, due at once.
+// Ids are generated here when the caller has none.
+func (s *Store) EnqueueWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.inTx(ctx, func(q *database.Queries) error {
+		now := timeToTimestamptz(time.Now())
+		for _, d := range deliveries {
+			if err := q.InsertWebhookDelivery(ctx, database.InsertWebhookDeliveryParams{
+				ID:            firstNonEmpty(d.Id, uuid.NewString()),
+				EndpointID:    d.EndpointId,
+				EventID:       d.EventId,
+				Kind:          d.Kind,
+				Body:          string(d.Body),
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]models.WebhookDispatch, error) {
+	if limit <= 0 {
+		return []models.WebhookDispatch{}, nil
+	}
+	rows, err := s.queries(ctx).ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+		LeaseUntil: timeToTimestamptz(leaseUntil),
+		Now:        timeToTimestamptz(time.Now()),
+		RowLimit:   int64(limit),
+	})
+	if err != nil {
+		return []models.WebhookDispatch{}, err
+	}
+	dispatches := make([]models.WebhookDispatch, len(rows))
+	for i, row := range rows {
+		dispatches[i] = models.WebhookDispatch{
+			Delivery: webhookDeliveryRowToModel(database.WebhookDelivery{
+				ID:             row.ID,
+				Seq:            row.Seq,
+				EndpointID:     row.EndpointID,
+				EventID:        row.EventID,
+				Kind:           row.Kind,
+				Body:           row.Body,
+				Status:         row.Status,
+				Attempts:       row.Attempts,
+				NextAttemptAt:  row.NextAttemptAt,
+				LastAttemptAt:  row.LastAttemptAt,
+				ResponseStatus: row.ResponseStatus,
+				LastError:      row.LastError,
+				DeliveredAt:    row.DeliveredAt,
+				CreatedAt:      row.CreatedAt,
+			}),
+			URL:    row.Url,
+			Secret: row.Secret,
+		}
+	}
+	return dispatches, nil
+}
+
+// CompleteWebhookDelivery records an attempt on the delivery and on its
+// endpoint's run of failures, in one transaction.
+func (s *Store) CompleteWebhookDelivery(ctx context.Context, attempt models.WebhookAttempt) error {
+	at := timeToTimestamptz(attempt.At)
+	params := database.CompleteWebhookDeliveryParams{
+		ID:             attempt.DeliveryId,
+		LastAttemptAt:  at,
+		ResponseStatus: intPtrToInt4(attempt.ResponseStatus),
+		LastError:      attempt.Error,
+	}
+	switch {
+	case attempt.Succeeded:
+		params.Status = models.WebhookDeliverySucceeded
+		params.NextAttemptAt = at
+		params.DeliveredAt = at
+	case attempt.RetryAt != nil:
+		params.Status = models.WebhookDeliveryPending
+		params.NextAttemptAt = timeToTimestamptz(*attempt.RetryAt)
+	default:
+		params.Status = models.WebhookDeliveryFailed
+		params.NextAttemptAt = at
+	}
+
+	return s.inTx(ctx, func(q *database.Queries) error {
+		if err := q.CompleteWebhookDelivery(ctx, params); err != nil {
+			return err
+		}
+		if attempt.Succeeded {
+			return q.ResetWebhookEndpointFailures(ctx, attempt.EndpointId)
+		}
+		return q.CountWebhookEndpointFailure(ctx, database.CountWebhookEndpointFailureParams{
+			DisableAfter: clampToInt32(attempt.DisableAfter),
+			Now:          at,
+			ID:           attempt.EndpointId,
+		})
+	})
+}
+
+func webhookEndpointRowToModel(row database.WebhookEndpoint) models.WebhookEndpoint {
+	kinds := row.Kinds
+	if kinds == nil {
+		kinds = []string{}
+	}
+	return models.WebhookEndpoint{
+		Id:                  row.ID,
+		GroupId:             row.GroupID,
+		URL:                 row.Url,
+		Secret:              row.Secret,
+		Kinds:               kinds,
+		CreatedBy:           row.CreatedBy,
+		ConsecutiveFailures: int(row.ConsecutiveFailures),
+		DisabledAt:          timestamptzToPtr(row.DisabledAt),
+		CreatedAt:           row.CreatedAt.Time,
+		UpdatedAt:           row.UpdatedAt.Time,
+	}
+}
+
+func webhookEndpointRowsToModels(rows []database.WebhookEndpoint) []models.WebhookEndpoint {
+	endpoints := make([]models.WebhookEndpoint, len(rows))
+	for i, row := range rows {
+		endpoints[i] = webhookEndpointRowToModel(row)
+	}
+	return endpoints
+}
+
+func webhookDeliveryRowToModel(row database.WebhookDelivery) models.WebhookDelivery {
+	var status *int
+	if row.ResponseStatus.Valid {
+		v := int(row.ResponseStatus.Int32)
+		status = &v
+	}
+	return models.WebhookDelivery{
+		Id:             row.ID,
+		Seq:            row.Seq,
+		EndpointId:     row.EndpointID,
+		EventId:        row.EventID,
+		Kind:           row.Kind,
+		Body:           []byte(row.Body),
+		Status:         row.Status,
+		Attempts:       int(row.Attempts),
+		NextAttemptAt:  row.NextAttemptAt.Time,
+		LastAttemptAt:  timestamptzToPtr(row.LastAttemptAt),
+		ResponseStatus: status,
+		LastError:      row.LastError,
+		DeliveredAt:    timestamptzToPtr(row.DeliveredAt),
+		CreatedAt:      row.CreatedAt.Time,
+	}
+}
+
+func intPtrToInt4(v *int) pgtype.Int4 {
+	if v == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: clampToInt32(*v), Valid: true}
+}
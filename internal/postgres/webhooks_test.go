@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+func TestStore_Webhooks(t *testing.T) {
+	setup := func(t *testing.T) (*Store, models.WebhookEndpoint) {
+		t.Helper()
+		resetDB(t)
+		s := newTestStore(t)
+		ctx := context.Background()
+		owner := addTestUser(t, s)
+		group, err := s.CreateGroup(ctx, newTestGroup(t, "hooks", owner))
+		require.NoError(t, err)
+		endpoint, err := s.CreateWebhookEndpoint(ctx, models.WebhookEndpoint{
+			GroupId:   group.Id,
+			URL:       "https://example.com/hook",
+			Secret:    "whsec_test",
+			Kinds:     []string{"title_added", "rating_added"},
+			CreatedBy: owner,
+		})
+		require.NoError(t, err)
+		return s, endpoint
+	}
+
+	enqueue := func(t *testing.T, s *Store, endpoint models.WebhookEndpoint, eventId string) {
+		t.Helper()
+		require.NoError(t, s.EnqueueWebhookDeliveries(context.Background(), []models.WebhookDelivery{
+			{EndpointId: endpoint.Id, EventId: eventId, Kind: "title_added", Body: []byte(`{"id":"` + eventId + `"}`)},
+		}))
+	}
+
+	t.Run("endpoints are matched on group, kind and being enabled", func(t *testing.T) {
+		s, endpoint := setup(t)
+		ctx := context.Background()
+
+		got, err := s.GetWebhookEndpointsForEvent(ctx, endpoint.GroupId, "rating_added")
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		require.Equal(t, endpoint.Id, got[0].Id)
+		got, err = s.GetWebhookEndpointsForEvent(ctx, endpoint.GroupId, "comment_added")
+		require.NoError(t, err)
+		require.Empty(t, got)
+
+		now := time.Now()
+		endpoint.DisabledAt = &now
+		_, err = s.UpdateWebhookEndpoint(ctx, endpoint)
+		require.NoError(t, err)
+		got, err = s.GetWebhookEndpointsForEvent(ctx, endpoint.GroupId, "title_added")
+		require.NoError(t, err)
+		require.Empty(t, got, "a disabled endpoint is sent nothing new")
+	})
+
+	t.Run("an event is queued once per endpoint and claimed once per lease", func(t *testing.T) {
+		s, endpoint := setup(t)
+		ctx := context.Background()
+		enqueue(t, s, endpoint, "e1")
+		enqueue(t, s, endpoint, "e1")
+		require.Equal(t, 1, countRows(t, s, "webhook_deliveries"), "the relay may hand the same event over twice")
+
+		claimed, err := s.ClaimWebhookDeliveries(ctx, 10, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		require.Equal(t, endpoint.URL, claimed[0].URL)
+		require.Equal(t, "whsec_test", claimed[0].Secret)
+		require.JSONEq(t, `{"id":"e1"}`, string(claimed[0].Delivery.Body))
+
+		again, err := s.ClaimWebhookDeliveries(ctx, 10, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Empty(t, again, "a leased delivery is not claimed twice")
+	})
+
+	t.Run("failures retry, disable the endpoint at the limit, and redelivery starts over", func(t *testing.T) {
+		s, endpoint := setup(t)
+		ctx := context.Background()
+		enqueue(t, s, endpoint, "e1")
+		claimed, err := s.ClaimWebhookDeliveries(ctx, 10, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		delivery := claimed[0].Delivery
+
+		status := 500
+		retryAt := time.Now().Add(-time.Second)
+		require.NoError(t, s.CompleteWebhookDelivery(ctx, models.WebhookAttempt{
+			DeliveryId: delivery.Id, EndpointId: endpoint.Id, At: time.Now(),
+			ResponseStatus: &status, Error: "unexpected response status 500", RetryAt: &retryAt, DisableAfter: 2,
+		}))
+		claimed, err = s.ClaimWebhookDeliveries(ctx, 10, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, claimed, 1, "a failure with a retry time comes due again")
+		require.Equal(t, 1, claimed[0].Delivery.Attempts)
+
+		require.NoError(t, s.CompleteWebhookDelivery(ctx, models.WebhookAttempt{
+			DeliveryId: delivery.Id, EndpointId: endpoint.Id, At: time.Now(),
+			ResponseStatus: &status, Error: "unexpected response status 500", DisableAfter: 2,
+		}))
+		endpoint, err = s.GetWebhookEndpointById(ctx, endpoint.GroupId, endpoint.Id)
+		require.NoError(t, err)
+		require.Equal(t, 2, endpoint.ConsecutiveFailures)
+		require.NotNil(t, endpoint.DisabledAt)
+
+		log, err := s.GetWebhookDeliveries(ctx, endpoint.Id, nil, 10)
+		require.NoError(t, err)
+		require.Len(t, log, 1)
+		require.Equal(t, models.WebhookDeliveryFailed, log[0].Status)
+		require.Equal(t, 2, log[0].Attempts)
+		require.Equal(t, 500, *log[0].ResponseStatus)
+
+		redelivered, err := s.RedeliverWebhookDelivery(ctx, endpoint.Id, delivery.Id)
+		require.NoError(t, err)
+		require.Equal(t, models.WebhookDeliveryPending, redelivered.Status)
+		require.Zero(t, redelivered.Attempts)
+
+		_, err = s.RedeliverWebhookDelivery(ctx, endpoint.Id, "missing")
+		require.ErrorIs(t, err, store.ErrRecordNotFound)
+	})
+
+	t.Run("a success clears the run of failures", func(t *testing.T) {
+		s, endpoint := setup(t)
+		ctx := context.Background()
+		enqueue(t, s, endpoint, "e1")
+		enqueue(t, s, endpoint, "e2")
+		claimed, err := s.ClaimWebhookDeliveries(ctx, 10, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, claimed, 2)
+
+		require.NoError(t, s.CompleteWebhookDelivery(ctx, models.WebhookAttempt{
+			DeliveryId: claimed[0].Delivery.Id, EndpointId: endpoint.Id, At: time.Now(), DisableAfter: 5,
+		}))
+		status := 204
+		require.NoError(t, s.CompleteWebhookDelivery(ctx, models.WebhookAttempt{
+			DeliveryId: claimed[1].Delivery.Id, EndpointId: endpoint.Id, At: time.Now(),
+			Succeeded: true, ResponseStatus: &status, DisableAfter: 5,
+		}))
+
+		endpoint, err = s.GetWebhookEndpointById(ctx, endpoint.GroupId, endpoint.Id)
+		require.NoError(t, err)
+		require.Zero(t, endpoint.ConsecutiveFailures)
+		require.Nil(t, endpoint.DisabledAt)
+	})
+
+	t.Run("deleting an endpoint takes its deliveries with it", func(t *testing.T) {
+		s, endpoint := setup(t)
+		ctx := context.Background()
+		enqueue(t, s, endpoint, "e1")
+
+		require.NoError(t, s.DeleteWebhookEndpoint(ctx, endpoint.GroupId, endpoint.Id))
+		require.Zero(t, countRows(t, s, "webhook_deliveries"))
+		require.ErrorIs(t, s.DeleteWebhookEndpoint(ctx, endpoint.GroupId, endpoint.Id), store.ErrRecordNotFound)
+	})
+}
@@ -1,14 +1,19 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/lealre/movies-backend/internal/activity"
 	"github.com/lealre/movies-backend/internal/auth"
 	"github.com/lealre/movies-backend/internal/logx"
 	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
 )
 
 // flushTimeout bounds the post-response sink call. The request context is
@@ -60,6 +65,98 @@ func ActivityMiddleware(sink activity.Sink) func(http.Handler) http.Handler {
 	}
 }
 
+// errRequestFailed rolls back the transaction of a request whose handler
+// responded with an error. It never reaches the client: the handler's own
+// response is sent as it was written.
+var errRequestFailed = errors.New("the request failed")
+
+// ActivityOutboxMiddleware is ActivityMiddleware for outbox mode
+// (ACTIVITY_OUTBOX_ENABLED): it makes a request's events as durable as its
+// writes, trading away the property that a sink cannot fail a request.
+//
+//   - The whole request runs in one store transaction, and its events are
+//     written to the outbox in it, after the handler and before the commit.
+//     A write that commits has its events committed with it; a crash before
+//     the commit loses both. The relay is woken to deliver them.
+//   - A request that fails is rolled back, all of it. That is stricter than
+//     best-effort mode, where a handler that commits and then fails leaves
+//     its row behind without an event: here it leaves neither.
+//   - The response is held back until the commit, so a client is never told
+//     a write succeeded that then failed to commit. If the commit fails the
+//     held response is discarded for a 500. Only mutating requests are held,
+//     so this costs the event stream nothing — it is a GET.
+//
+// The actor is stamped exactly as ActivityMiddleware stamps it.
+func ActivityOutboxMiddleware(outbox store.ActivityOutbox, relay *activity.Relay) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := activity.WithRecorder(r.Context())
+			held := newHeldResponse()
+
+			err := outbox.InTransaction(ctx, func(ctx context.Context) error {
+				next.ServeHTTP(held, r.WithContext(ctx))
+				// The same gate as ActivityMiddleware's, see there.
+				if held.statusCode >= http.StatusBadRequest {
+					return errRequestFailed
+				}
+				return outbox.EnqueueActivityEvents(ctx, stampActor(ctx, activity.Recorded(ctx)))
+			})
+			switch {
+			case err == nil:
+				relay.Wake()
+			case errors.Is(err, errRequestFailed):
+			default:
+				logx.FromContext(ctx).Printf("ERROR: committing the request and its activity failed: %v", err)
+				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+				return
+			}
+			held.writeTo(w)
+		})
+	}
+}
+
+// heldResponse is a response written in full before any of it is sent, so
+// that what is sent can still depend on whether the transaction commits. It
+// is deliberately not an http.Flusher: nothing it wraps streams.
+type heldResponse struct {
+	header      http.Header
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func newHeldResponse() *heldResponse {
+	return &heldResponse{header: http.Header{}, statusCode: http.StatusOK}
+}
+
+func (h *heldResponse) Header() http.Header { return h.header }
+
+func (h *heldResponse) WriteHeader(statusCode int) {
+	if h.wroteHeader {
+		return
+	}
+	h.statusCode = statusCode
+	h.wroteHeader = true
+}
+
+func (h *heldResponse) Write(p []byte) (int, error) {
+	h.wroteHeader = true
+	return h.body.Write(p)
+}
+
+func (h *heldResponse) writeTo(w http.ResponseWriter) {
+	for key, values := range h.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(h.statusCode)
+	_, _ = w.Write(h.body.Bytes())
+}
+
 func isMutating(method string) bool {
 	switch method {
 	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
@@ -77,37 +174,49 @@ func isMutating(method string) bool {
 // disconnected — cancelling the flush for that reason would drop events for the
 // people the feed is actually for.
 func flush(ctx context.Context, sink activity.Sink, events []activity.Event) {
-	if len(events) == 0 {
+	stamped := stampActor(ctx, events)
+	if len(stamped) == 0 {
 		return
 	}
 
-	logger := logx.FromContext(ctx)
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+	defer cancel()
+
+	if err := sink.Append(flushCtx, stamped); err != nil {
+		// Deliberately not propagated: the business write is committed and the
+		// response is sent. Best-effort delivery, stated in the spec.
+		logx.FromContext(ctx).Printf("ERROR: recording %d activity event(s) failed: %v", len(stamped), err)
+	}
+}
+
+// stampActor attributes the buffered events to the request's user, for either
+// delivery mode. With no user it returns nothing, logging what it dropped.
+func stampActor(ctx context.Context, events []activity.Event) []models.ActivityEvent {
+	if len(events) == 0 {
+		return nil
+	}
 
 	actor := auth.GetUserFromContext(ctx)
 	if actor == nil {
 		// Only the two public routes have no actor, and neither records
 		// anything. An unattributable row would be worse than no row.
-		logger.Printf("WARN: %d activity event(s) dropped: no actor in context", len(events))
-		return
+		logx.FromContext(ctx).Printf("WARN: %d activity event(s) dropped: no actor in context", len(events))
+		return nil
 	}
 
 	stamped := make([]models.ActivityEvent, 0, len(events))
 	for _, e := range events {
 		stamped = append(stamped, toModel(*actor, e))
 	}
-
-	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
-	defer cancel()
-
-	if err := sink.Append(flushCtx, stamped); err != nil {
-		// Deliberately not propagated: the business write is committed and the
-		// response is sent. Best-effort delivery, stated in the spec.
-		logger.Printf("ERROR: recording %d activity event(s) failed: %v", len(stamped), err)
-	}
+	return stamped
 }
 
+// toModel gives the event its id and time here rather than in a sink, so that
+// every sink of a Fanout sees the same ones: a webhook delivery names the event
+// by the id the feed shows.
 func toModel(actor models.User, e activity.Event) models.ActivityEvent {
 	return models.ActivityEvent{
+		Id:        uuid.NewString(),
 		GroupId:   e.GroupId,
 		ActorId:   actor.Id,
 		ActorName: actorDisplayName(actor),
@@ -115,6 +224,7 @@ func toModel(actor models.User, e activity.Event) models.ActivityEvent {
 		TitleId:   e.TitleId,
 		TitleName: e.TitleName,
 		Payload:   e.Payload,
+		CreatedAt: time.Now(),
 	}
 }
 
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -112,3 +113,96 @@ func TestActivityMiddleware(t *testing.T) {
 		require.Zero(t, sink.call)
 	})
 }
+
+// fakeOutbox stands in for the store in outbox mode: events enqueued inside a
+// transaction only count once it commits, and commitErr makes the commit fail.
+type fakeOutbox struct {
+	transactions int
+	committed    []models.ActivityEvent
+	commitErr    error
+}
+
+func (f *fakeOutbox) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	f.transactions++
+	var pending []models.ActivityEvent
+	if err := fn(context.WithValue(ctx, fakeTxKey{}, &pending)); err != nil {
+		return err
+	}
+	if f.commitErr != nil {
+		return f.commitErr
+	}
+	f.committed = append(f.committed, pending...)
+	return nil
+}
+
+type fakeTxKey struct{}
+
+func (f *fakeOutbox) EnqueueActivityEvents(ctx context.Context, events []models.ActivityEvent) error {
+	pending := ctx.Value(fakeTxKey{}).(*[]models.ActivityEvent)
+	*pending = append(*pending, events...)
+	return nil
+}
+
+func (f *fakeOutbox) RelayActivityOutbox(context.Context, int, func(context.Context, []models.ActivityEvent) error) (int, error) {
+	return 0, nil
+}
+
+func TestActivityOutboxMiddleware(t *testing.T) {
+	actor := models.User{Id: "u1", Name: "Maria", Username: "maria"}
+	serve := func(outbox *fakeOutbox, h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		relay := activity.NewRelay(outbox, time.Hour, &fakeSink{})
+		ActivityOutboxMiddleware(outbox, relay)(h).ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("a successful request's events commit with it, with the actor stamped", func(t *testing.T) {
+		outbox := &fakeOutbox{}
+		w := serve(outbox, handlerRecording(http.StatusCreated), requestAs(http.MethodPost, &actor))
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Equal(t, 1, outbox.transactions, "the handler must run inside the transaction")
+		require.Len(t, outbox.committed, 1)
+		require.Equal(t, "u1", outbox.committed[0].ActorId)
+		require.Equal(t, "Maria", outbox.committed[0].ActorName)
+	})
+
+	t.Run("a failed request is rolled back and its response sent as written", func(t *testing.T) {
+		outbox := &fakeOutbox{}
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			activity.Record(r.Context(), activity.TitleAdded("g1", "tt1", "Dune"))
+			http.Error(w, "already there", http.StatusConflict)
+		})
+		w := serve(outbox, h, requestAs(http.MethodPost, &actor))
+
+		require.Equal(t, http.StatusConflict, w.Code)
+		require.Contains(t, w.Body.String(), "already there")
+		require.Empty(t, outbox.committed)
+	})
+
+	t.Run("a failed commit replaces the held response with a 500", func(t *testing.T) {
+		outbox := &fakeOutbox{commitErr: errors.New("connection reset")}
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"r1"}`))
+		})
+		var logs bytes.Buffer
+		ctx := logx.WithLogger(auth.WithUser(context.Background(), actor), log.New(&logs, "", 0))
+		w := serve(outbox, h, httptest.NewRequest(http.MethodPost, "/anything", nil).WithContext(ctx))
+
+		require.Equal(t, http.StatusInternalServerError, w.Code,
+			"a write that did not commit must not be reported as created")
+		require.NotContains(t, w.Body.String(), "r1")
+		require.Contains(t, logs.String(), "connection reset")
+	})
+
+	t.Run("a read request runs outside any transaction", func(t *testing.T) {
+		outbox := &fakeOutbox{}
+		w := serve(outbox, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		}), requestAs(http.MethodGet, &actor))
+
+		require.Equal(t, "ok", w.Body.String())
+		require.Zero(t, outbox.transactions)
+	})
+}
@@ -33,42 +33,55 @@ const (
 	streamWireGroupId = "the-readers-group"
 )
 
-// listeningStore is a store that can push. It captures the publish callback the
+// listeningStore is a store that can push. It captures the callbacks the
 // server hands to ListenActivity, which is the seam Postgres's LISTEN loop
-// occupies in production — so a test can inject an event exactly where a
-// committed row would arrive, and can also see whether the loop was started at
-// all.
+// occupies in production — so a test can inject an event, or a membership
+// change or a preferences change, exactly where a committed row would arrive, and can also see whether
+// the loop was started at all.
 type listeningStore struct {
 	store.Store
 	user    models.User
-	listens chan func(models.ActivityEvent)
+	listens chan listener
+}
+
+// listener is what ListenActivity was handed.
+type listener struct {
+	publish     func(models.ActivityEvent)
+	membership  func(models.MembershipChange)
+	preferences func(models.ActivityPreferences)
 }
 
 func newListeningStore(user models.User) *listeningStore {
-	return &listeningStore{user: user, listens: make(chan func(models.ActivityEvent), 1)}
+	return &listeningStore{user: user, listens: make(chan listener, 1)}
 }
 
 func (s *listeningStore) GetUserById(context.Context, string) (models.User, error) {
 	return s.user, nil
 }
 
-func (s *listeningStore) ListenActivity(ctx context.Context, publish func(models.ActivityEvent)) error {
-	s.listens <- publish
+// GetActivityPreferences is read as each stream opens; the reader has muted
+// nothing until a test pushes preferences through the listener.
+func (s *listeningStore) GetActivityPreferences(_ context.Context, userId string) (models.ActivityPreferences, error) {
+	return models.ActivityPreferences{UserId: userId}, nil
+}
+
+func (s *listeningStore) ListenActivity(ctx context.Context, publish func(models.ActivityEvent), membership func(models.MembershipChange), preferences func(models.ActivityPreferences)) error {
+	s.listens <- listener{publish: publish, membership: membership, preferences: preferences}
 	<-ctx.Done()
 	return nil
 }
 
-// startedListener returns the captured publish callback, failing if the loop
-// was never started.
-func (s *listeningStore) startedListener(t *testing.T) func(models.ActivityEvent) {
+// startedListener returns the captured callbacks, failing if the loop was
+// never started.
+func (s *listeningStore) startedListener(t *testing.T) listener {
 	t.Helper()
 
 	select {
-	case publish := <-s.listens:
-		return publish
+	case l := <-s.listens:
+		return l
 	case <-time.After(streamWireTimeout):
 		t.Fatal("the activity listener was never started")
-		return nil
+		return listener{}
 	}
 }
 
@@ -156,7 +169,7 @@ func TestActivityStreamWiring(t *testing.T) {
 
 	t.Run("an event published where Postgres would publish it reaches a live client", func(t *testing.T) {
 		srv, st := newWiredServer(t, true)
-		publish := st.startedListener(t)
+		publish := st.startedListener(t).publish
 
 		token := streamWireToken(t)
 		mint := do(t, http.MethodPost, srv.URL+"/activity/stream-ticket", token)
@@ -205,6 +218,68 @@ func TestActivityStreamWiring(t *testing.T) {
 		require.Contains(t, frame, `"kind":"title_added"`, "the frame carries the event itself, not just a signal")
 	})
 
+	t.Run("a membership change reaches an open stream without a reconnect", func(t *testing.T) {
+		srv, st := newWiredServer(t, true)
+		listener := st.startedListener(t)
+
+		mint := do(t, http.MethodPost, srv.URL+"/activity/stream-ticket", streamWireToken(t))
+		defer mint.Body.Close()
+		var minted struct {
+			Ticket string `json:"ticket"`
+		}
+		require.NoError(t, json.NewDecoder(mint.Body).Decode(&minted), "the ticket response must be JSON")
+
+		stream := do(t, http.MethodGet, srv.URL+"/activity/stream?ticket="+minted.Ticket, "")
+		defer stream.Body.Close()
+		require.Equal(t, http.StatusOK, stream.StatusCode, "a valid ticket must open the stream")
+		body := bufio.NewReader(stream.Body)
+
+		event := func(id string, seq int64, groupId string) models.ActivityEvent {
+			return models.ActivityEvent{Id: id, Seq: seq, GroupId: groupId, ActorId: "another-member", Kind: "title_added"}
+		}
+
+		listener.membership(models.MembershipChange{Kind: models.MembershipRemoved, UserId: streamWireUserId, GroupId: streamWireGroupId})
+		require.Equal(t, "event: resync\ndata: {}\n", readFrameThroughChain(t, body),
+			"what the client has on screen includes the group it left")
+		listener.publish(event("e1", 1, streamWireGroupId))
+
+		listener.membership(models.MembershipChange{Kind: models.MembershipAdded, UserId: streamWireUserId, GroupId: "joined-later"})
+		require.Equal(t, "event: resync\ndata: {}\n", readFrameThroughChain(t, body),
+			"the event in the group the user left must not be sent")
+		listener.publish(event("e2", 2, "joined-later"))
+
+		require.Contains(t, readFrameThroughChain(t, body), "id: 2\n", "a group joined mid-stream is delivered at once")
+	})
+
+	t.Run("a preferences change reaches an open stream without a reconnect", func(t *testing.T) {
+		srv, st := newWiredServer(t, true)
+		listener := st.startedListener(t)
+
+		mint := do(t, http.MethodPost, srv.URL+"/activity/stream-ticket", streamWireToken(t))
+		defer mint.Body.Close()
+		var minted struct {
+			Ticket string `json:"ticket"`
+		}
+		require.NoError(t, json.NewDecoder(mint.Body).Decode(&minted), "the ticket response must be JSON")
+
+		stream := do(t, http.MethodGet, srv.URL+"/activity/stream?ticket="+minted.Ticket, "")
+		defer stream.Body.Close()
+		require.Equal(t, http.StatusOK, stream.StatusCode, "a valid ticket must open the stream")
+		body := bufio.NewReader(stream.Body)
+
+		listener.preferences(models.ActivityPreferences{
+			UserId: streamWireUserId,
+			Mutes:  []models.ActivityMute{{Kind: "rating_added"}},
+		})
+		require.Equal(t, "event: resync\ndata: {}\n", readFrameThroughChain(t, body),
+			"what the client has on screen includes what was just muted")
+
+		listener.publish(models.ActivityEvent{Id: "e1", Seq: 1, GroupId: streamWireGroupId, ActorId: "another-member", Kind: "rating_added"})
+		listener.publish(models.ActivityEvent{Id: "e2", Seq: 2, GroupId: streamWireGroupId, ActorId: "another-member", Kind: "title_added"})
+
+		require.Contains(t, readFrameThroughChain(t, body), "id: 2\n", "the muted kind must not be sent")
+	})
+
 	t.Run("with the feed off there is no stream route and no listener", func(t *testing.T) {
 		srv, st := newWiredServer(t, false)
 
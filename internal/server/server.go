@@ -11,20 +11,33 @@ import (
 	"github.com/lealre/movies-backend/internal/activity"
 	"github.com/lealre/movies-backend/internal/api"
 	"github.com/lealre/movies-backend/internal/config"
+	"github.com/lealre/movies-backend/internal/mailer"
 	activityservice "github.com/lealre/movies-backend/internal/services/activity"
+	"github.com/lealre/movies-backend/internal/services/digests"
+	"github.com/lealre/movies-backend/internal/services/imports"
+	"github.com/lealre/movies-backend/internal/services/webhooks"
 	"github.com/lealre/movies-backend/internal/store"
 	"github.com/lealre/movies-backend/internal/titleprovider"
+	"github.com/lealre/movies-backend/internal/titleprovider/cache"
 	"github.com/lealre/movies-backend/internal/titleprovider/factory"
+	"github.com/lealre/movies-backend/internal/titleprovider/streaming"
+	"github.com/lealre/movies-backend/internal/titleprovider/titleref"
 )
 
 // NewServer builds the production server, selecting the title provider from env
 // and requiring the JWT signing secret (JWT_SECRET) to be set.
 //
-// ctx bounds the background work the server starts — today the activity
-// LISTEN loop. Cancelling it stops that loop and closes its database
-// connection; it is not the context of any request.
+// ctx bounds the background work the server starts — the activity LISTEN
+// loop and CSV imports. Cancelling it stops that loop and closes its database
+// connection, and fails any import still running; it is not the context of
+// any request.
 func NewServer(ctx context.Context, st store.Store) (http.Handler, error) {
-	provider, err := factory.NewFromEnv()
+	// A store that cannot hold the provider cache leaves persistent nil, and
+	// the cache, if enabled, stays memory-only; one that cannot count
+	// requests leaves OMDb unmetered.
+	persistent, _ := st.(store.ProviderCache)
+	quota, _ := st.(store.ProviderQuota)
+	provider, err := factory.NewFromEnv(factory.Options{Cache: persistent, Quota: quota})
 	if err != nil {
 		return nil, err
 	}
@@ -32,18 +45,38 @@ func NewServer(ctx context.Context, st store.Store) (http.Handler, error) {
 	if secret == "" {
 		return nil, fmt.Errorf("JWT_SECRET must be set")
 	}
+	streamingProvider, err := factory.NewStreamingFromEnv()
+	if err != nil {
+		return nil, err
+	}
 	log.Printf("Using title provider: %s", provider.Name())
-	return NewServerWithProvider(ctx, st, provider, secret), nil
+	if _, ok := provider.(*cache.Provider); ok {
+		log.Printf("Title provider cache enabled (persistent: %t)", persistent != nil && config.TitleCachePersistent())
+	}
+	if streamingProvider != nil {
+		log.Printf("Using streaming availability provider: %s", streamingProvider.Name())
+	}
+	return NewServerWithProviders(ctx, st, provider, streamingProvider, secret), nil
 }
 
 // NewServerWithProvider builds the server with an explicit title provider and
-// JWT secret. Tests use this to inject a fixture-backed fake provider (no
-// network) and a test secret. ctx bounds the background work it starts — see
-// NewServer.
+// JWT secret, and no streaming availability provider. Tests use this to inject
+// a fixture-backed fake provider (no network) and a test secret. ctx bounds
+// the background work it starts — see NewServer.
 func NewServerWithProvider(ctx context.Context, st store.Store, provider titleprovider.Provider, secret string) http.Handler {
+	return NewServerWithProviders(ctx, st, provider, nil, secret)
+}
+
+// NewServerWithProviders is NewServerWithProvider with a streaming
+// availability provider as well; nil leaves availability unfetched, though
+// whatever is already stored is still served.
+func NewServerWithProviders(ctx context.Context, st store.Store, provider titleprovider.Provider, streamingProvider streaming.Provider, secret string) http.Handler {
 	mux := http.NewServeMux()
 
 	a := api.NewAPI(st, provider)
+	a.Streaming = streamingProvider
+	a.Resolver = titleref.NewResolver(provider, factory.NewTMDBLookup(provider))
+	a.Imports = imports.NewJobs(ctx)
 
 	a.Secret = &secret
 
@@ -51,6 +84,10 @@ func NewServerWithProvider(ctx context.Context, st store.Store, provider titlepr
 
 	mux.HandleFunc("GET /users", a.GetUsers)
 	mux.HandleFunc("GET /users/me", a.GetUserMe)
+	mux.HandleFunc("GET /users/me/year-in-review/{year}", a.GetMyYearInReview)
+	mux.HandleFunc("GET /users/me/year-in-review/{year}/card", a.GetMyYearInReviewCard)
+	mux.HandleFunc("GET /users/me/streaming-services", a.GetMyStreamingServices)
+	mux.HandleFunc("PUT /users/me/streaming-services", a.SetMyStreamingServices)
 	mux.HandleFunc("GET /users/{id}", a.GetUserById)
 	mux.HandleFunc("POST /users", a.CreateUser)
 	mux.HandleFunc("PATCH /users/{id}", a.UpdateUserInfo)
@@ -72,8 +109,23 @@ func NewServerWithProvider(ctx context.Context, st store.Store, provider titlepr
 	// feed-specific, so ACTIVITY_FEED_ENABLED does not gate it.
 	mux.HandleFunc("GET /groups/{groupId}/titles/{titleId}", a.GetTitleFromGroup)
 	mux.HandleFunc("POST /groups/titles", a.AddTitleToGroup)
+	mux.HandleFunc("POST /groups/{id}/titles/bulk", a.BulkAddTitlesToGroup)
 	mux.HandleFunc("PATCH /groups/{id}/titles", a.UpdateGroupTitleWatched)
+	// Group - Imports and exports
+	mux.HandleFunc("POST /groups/{id}/imports", a.StartImport)
+	mux.HandleFunc("GET /groups/{id}/imports/{jobId}", a.GetImport)
+	mux.HandleFunc("GET /groups/{id}/export", a.ExportGroup)
 	mux.HandleFunc("DELETE /groups/{groupId}/titles/{titleId}", a.DeleteTitleFromGroup)
+	mux.HandleFunc("POST /groups/{id}/pick", a.PickGroupTitle)
+	// Group - Recommendations
+	mux.HandleFunc("GET /groups/{id}/recommendations", a.GetGroupRecommendations)
+	mux.HandleFunc("PUT /groups/{id}/recommendations/sharing", a.SetGroupRecommendationSharing)
+	// Group - Compatibility
+	mux.HandleFunc("GET /groups/{id}/compatibility", a.GetGroupCompatibility)
+	// Group - Stats
+	mux.HandleFunc("GET /groups/{id}/stats", a.GetGroupStats)
+	mux.HandleFunc("GET /groups/{id}/year-in-review/{year}", a.GetGroupYearInReview)
+	mux.HandleFunc("GET /groups/{id}/year-in-review/{year}/card", a.GetGroupYearInReviewCard)
 	// Group - Comments
 	mux.HandleFunc("GET /groups/{groupId}/titles/{titleId}/comments", a.GetCommentsByTitleIDFromGroup)
 	mux.HandleFunc("PATCH /groups/{groupId}/titles/{titleId}/comments/{commentId}", a.UpdateComment)
@@ -83,6 +135,8 @@ func NewServerWithProvider(ctx context.Context, st store.Store, provider titlepr
 	mux.HandleFunc("GET /titles", a.GetTitles)
 	mux.HandleFunc("GET /titles/search", a.SearchTitles)
 	mux.HandleFunc("GET /titles/{id}/episodes", a.GetTitleEpisodes)
+	mux.HandleFunc("GET /titles/provider/cache", a.GetTitleProviderCacheStats)
+	mux.HandleFunc("GET /titles/provider/quota", a.GetTitleProviderQuota)
 	mux.HandleFunc("POST /titles", a.AddTitle)
 	mux.HandleFunc("DELETE /titles/{id}", a.DeleteTitle)
 
@@ -112,6 +166,10 @@ func NewServerWithProvider(ctx context.Context, st store.Store, provider titlepr
 		// changing what a body meant would be worse than removing it.
 		mux.HandleFunc("POST /activity/events/{id}/read", a.MarkActivityEventRead)
 		mux.HandleFunc("POST /activity/read-all", a.MarkAllActivityRead)
+		mux.HandleFunc("GET /groups/{id}/activity", a.GetGroupActivity)
+		mux.HandleFunc("GET /groups/{groupId}/titles/{titleId}/activity", a.GetGroupTitleActivity)
+		mux.HandleFunc("GET /activity/preferences", a.GetActivityPreferences)
+		mux.HandleFunc("PUT /activity/preferences", a.SetActivityPreferences)
 
 		// Everything live is built here and nowhere else: with the flag off
 		// there is no hub, no ticket store, no listener goroutine, no
@@ -121,13 +179,44 @@ func NewServerWithProvider(ctx context.Context, st store.Store, provider titlepr
 
 		mux.HandleFunc("POST /activity/stream-ticket", a.IssueActivityStreamTicket)
 		mux.HandleFunc("GET /activity/stream", a.StreamActivity)
+		mux.HandleFunc("GET /activity/ws", a.StreamActivitySocket)
 
 		startActivityListener(ctx, st, hub)
 	}
 
+	// Webhooks hang off the feed: their events are the feed's, so with the
+	// feed off there is nothing to send and no route to manage them.
+	webhooksEnabled := activityFeedEnabled && config.WebhooksEnabled()
+
+	if webhooksEnabled {
+		mux.HandleFunc("POST /groups/{id}/webhooks", a.CreateWebhook)
+		mux.HandleFunc("GET /groups/{id}/webhooks", a.GetWebhooks)
+		mux.HandleFunc("PATCH /groups/{id}/webhooks/{webhookId}", a.UpdateWebhook)
+		mux.HandleFunc("DELETE /groups/{id}/webhooks/{webhookId}", a.DeleteWebhook)
+		mux.HandleFunc("GET /groups/{id}/webhooks/{webhookId}/deliveries", a.GetWebhookDeliveries)
+		mux.HandleFunc("POST /groups/{id}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", a.RedeliverWebhookDelivery)
+
+		go webhooks.NewDispatcher(st).Run(ctx)
+	}
+
+	// Digests hang off the feed the same way: a digest is the feed's unread.
+	if activityFeedEnabled && config.DigestsEnabled() {
+		mux.HandleFunc("GET /activity/digest", a.GetActivityDigest)
+		mux.HandleFunc("PUT /activity/digest", a.SetActivityDigest)
+
+		startActivityDigests(ctx, st)
+	}
+
 	var handler http.Handler = mux
 	if activityFeedEnabled {
-		handler = ActivityMiddleware(activity.NewStoreSink(st))(handler)
+		var sink activity.Sink = activity.NewStoreSink(st)
+		if webhooksEnabled {
+			sink = activity.NewFanout(sink, webhooks.NewSink(st))
+		}
+		// In front of every sink, so webhooks are sent the merged edit the
+		// feed shows rather than each step of it.
+		sink = activity.NewCoalescer(st, config.ActivityCoalesceWindow(), sink)
+		handler = activityMiddleware(ctx, st, sink)(handler)
 	}
 	handler = AuthMiddleware(*a.Secret, st)(handler)
 	handler = RequestIdMiddleware(handler) // wrap LAST → runs FIRST
@@ -135,6 +224,44 @@ func NewServerWithProvider(ctx context.Context, st store.Store, provider titlepr
 	return handler
 }
 
+// activityMiddleware picks how a request's events reach sink: flushed after
+// the response (ActivityMiddleware), or, with ACTIVITY_OUTBOX_ENABLED and a
+// store that has an outbox, committed with the request and relayed
+// (ActivityOutboxMiddleware). In outbox mode it also starts the relay, which
+// runs until ctx is cancelled.
+func activityMiddleware(ctx context.Context, st store.Store, sink activity.Sink) func(http.Handler) http.Handler {
+	if !config.ActivityOutboxEnabled() {
+		return ActivityMiddleware(sink)
+	}
+	outbox, ok := st.(store.ActivityOutbox)
+	if !ok {
+		// The same call as a store that cannot push: the feed works, just
+		// best-effort, and saying so beats refusing to boot.
+		log.Printf("WARN: %T has no activity outbox; ACTIVITY_OUTBOX_ENABLED is ignored and delivery stays best-effort", st)
+		return ActivityMiddleware(sink)
+	}
+	relay := activity.NewRelay(outbox, config.ActivityOutboxPollInterval(), sink)
+	go relay.Run(ctx)
+	return ActivityOutboxMiddleware(outbox, relay)
+}
+
+// startActivityDigests starts the job that emails due digests, if a mailer is
+// configured. It runs until ctx is cancelled.
+func startActivityDigests(ctx context.Context, st store.Store) {
+	m, err := mailer.NewFromEnv()
+	if err != nil {
+		// A mailer that is set up wrong is the operator's to fix, not a
+		// reason to take the rest of the server down with it.
+		log.Printf("ERROR: no digests will be sent: %v", err)
+		return
+	}
+	if m == nil {
+		log.Printf("WARN: DIGESTS_ENABLED is set without a MAILER; users can subscribe to digests but none will be sent")
+		return
+	}
+	go digests.NewJob(st, m).Run(ctx)
+}
+
 // startActivityListener starts the one LISTEN loop that feeds hub, if this
 // store can push at all. It is only ever called with the feature on.
 //
@@ -153,7 +280,7 @@ func startActivityListener(ctx context.Context, st store.Store, hub *activity.Hu
 	}
 
 	go func() {
-		if err := listener.ListenActivity(ctx, hub.Publish); err != nil {
+		if err := listener.ListenActivity(ctx, hub.Publish, hub.ApplyMembership, hub.ApplyPreferences); err != nil {
 			log.Printf("ERROR: the activity listener stopped: %v", err)
 		}
 	}()
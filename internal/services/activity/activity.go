@@ -3,23 +3,83 @@ package activity
 import (
 	"context"
 	"errors"
+	"slices"
+	"time"
 
+	activitycore "github.com/lealre/movies-backend/internal/activity"
 	"github.com/lealre/movies-backend/internal/config"
+	"github.com/lealre/movies-backend/internal/models"
 	"github.com/lealre/movies-backend/internal/store"
 )
 
-// GetFeed returns the caller's feed newest-first. limit is normalized here, not
-// in the store, matching how every other paged read in this codebase splits
-// policy from storage.
-func GetFeed(db store.Store, ctx context.Context, userId string, before *int64, limit int) (Feed, error) {
+// GetFeed returns the caller's feed newest-first, narrowed by query. limit is
+// normalized here, not in the store, matching how every other paged read in
+// this codebase splits policy from storage.
+//
+// A groupId the caller is not in is not an error: it narrows the feed to
+// nothing, the same as it would for a group that was left a moment ago.
+func GetFeed(db store.Store, ctx context.Context, userId string, query FeedQuery, before *int64, limit int) (Feed, error) {
+	filter, err := parseFilter(query)
+	if err != nil {
+		return Feed{}, err
+	}
 	limit, _ = config.NormalizePageParams(limit, 1)
 
 	// One extra row answers "is there another page" without a second query.
-	rows, err := db.GetActivityFeed(ctx, userId, before, limit+1)
+	rows, err := db.GetActivityFeed(ctx, userId, filter, before, limit+1)
 	if err != nil {
 		return Feed{}, err
 	}
+	return page(rows, limit), nil
+}
+
+// GetGroupHistory returns one group's activity, the caller's own actions
+// included, for the group's own page. Unlike GetFeed it answers for a group
+// the caller is not in, with ErrGroupNotFound. query.GroupIds is ignored: the
+// group is the path's.
+func GetGroupHistory(db store.Store, ctx context.Context, userId, groupId string, query FeedQuery, before *int64, limit int) (Feed, error) {
+	query.GroupIds = nil
+	filter, err := parseFilter(query)
+	if err != nil {
+		return Feed{}, err
+	}
+	return history(db, ctx, userId, groupId, filter, before, limit)
+}
+
+// GetTitleHistory is what happened to one title in one group, the caller's
+// own actions included. The title need not be in the group any more: its
+// history outlives it, as activity outlives the titles it names.
+func GetTitleHistory(db store.Store, ctx context.Context, userId, groupId, titleId string, query FeedQuery, before *int64, limit int) (Feed, error) {
+	query.GroupIds = nil
+	query.TitleId = titleId
+	filter, err := parseFilter(query)
+	if err != nil {
+		return Feed{}, err
+	}
+	return history(db, ctx, userId, groupId, filter, before, limit)
+}
+
+func history(db store.Store, ctx context.Context, userId, groupId string, filter models.ActivityFilter, before *int64, limit int) (Feed, error) {
+	ok, err := db.GroupExists(ctx, groupId, userId)
+	if err != nil {
+		return Feed{}, err
+	}
+	if !ok {
+		return Feed{}, ErrGroupNotFound
+	}
+	filter.GroupIds = []string{groupId}
+	limit, _ = config.NormalizePageParams(limit, 1)
 
+	rows, err := db.GetActivityHistory(ctx, userId, filter, before, limit+1)
+	if err != nil {
+		return Feed{}, err
+	}
+	return page(rows, limit), nil
+}
+
+// page turns limit+1 rows into a page of at most limit events, with the
+// cursor for the next one when the extra row says there is one.
+func page(rows []models.ActivityEvent, limit int) Feed {
 	hasMore := len(rows) > limit
 	if hasMore {
 		rows = rows[:limit]
@@ -35,7 +95,51 @@ func GetFeed(db store.Store, ctx context.Context, userId string, before *int64,
 		last := events[len(events)-1].Seq
 		feed.NextBefore = &last
 	}
-	return feed, nil
+	return feed
+}
+
+// parseFilter checks query and turns it into the store's filter. Kinds must be
+// ones activity records: an unknown kind would only ever match nothing, which
+// hides a typo behind an empty feed.
+func parseFilter(query FeedQuery) (models.ActivityFilter, error) {
+	filter := models.ActivityFilter{
+		GroupIds: query.GroupIds,
+		Kinds:    query.Kinds,
+	}
+	for _, kind := range query.Kinds {
+		if !slices.Contains(activitycore.Kinds, kind) {
+			return models.ActivityFilter{}, ErrUnknownKind
+		}
+	}
+	if query.TitleId != "" {
+		filter.TitleId = &query.TitleId
+	}
+	if query.ActorId != "" {
+		filter.ActorId = &query.ActorId
+	}
+	var err error
+	if filter.CreatedAfter, err = parseTime(query.CreatedAfter); err != nil {
+		return models.ActivityFilter{}, err
+	}
+	if filter.CreatedBefore, err = parseTime(query.CreatedBefore); err != nil {
+		return models.ActivityFilter{}, err
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		return models.ActivityFilter{}, ErrInvalidTimeRange
+	}
+	return filter, nil
+}
+
+// parseTime reads an optional RFC 3339 timestamp; empty is nil.
+func parseTime(raw string) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, ErrInvalidTime
+	}
+	return &t, nil
 }
 
 func GetUnreadCount(db store.Store, ctx context.Context, userId string) (UnreadCount, error) {
@@ -0,0 +1,120 @@
+package activity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// historyStore is a caller in one group, recording the filter each read was
+// given.
+type historyStore struct {
+	store.Store
+	groupId string
+	filter  models.ActivityFilter
+	events  []models.ActivityEvent
+}
+
+func (s *historyStore) GroupExists(_ context.Context, groupId, _ string) (bool, error) {
+	return groupId == s.groupId, nil
+}
+
+func (s *historyStore) GetActivityFeed(_ context.Context, _ string, filter models.ActivityFilter, _ *int64, limit int) ([]models.ActivityEvent, error) {
+	s.filter = filter
+	return s.events[:min(limit, len(s.events))], nil
+}
+
+func (s *historyStore) GetActivityHistory(_ context.Context, _ string, filter models.ActivityFilter, _ *int64, limit int) ([]models.ActivityEvent, error) {
+	s.filter = filter
+	return s.events[:min(limit, len(s.events))], nil
+}
+
+func TestFeedFilters(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("the query becomes the store's filter", func(t *testing.T) {
+		db := &historyStore{}
+
+		_, err := GetFeed(db, ctx, "alice", FeedQuery{
+			GroupIds:      []string{"g1", "g2"},
+			TitleId:       "tt1",
+			Kinds:         []string{"rating_added"},
+			ActorId:       "bob",
+			CreatedAfter:  "2026-03-01T00:00:00Z",
+			CreatedBefore: "2026-04-01T00:00:00+02:00",
+		}, nil, 10)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"g1", "g2"}, db.filter.GroupIds)
+		require.Equal(t, "tt1", *db.filter.TitleId)
+		require.Equal(t, []string{"rating_added"}, db.filter.Kinds)
+		require.Equal(t, "bob", *db.filter.ActorId)
+		require.True(t, db.filter.CreatedAfter.Equal(time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)))
+		require.True(t, db.filter.CreatedBefore.Equal(time.Date(2026, time.March, 31, 22, 0, 0, 0, time.UTC)),
+			"an offset is honoured, not dropped")
+	})
+
+	t.Run("an empty query filters nothing", func(t *testing.T) {
+		db := &historyStore{}
+
+		_, err := GetFeed(db, ctx, "alice", FeedQuery{}, nil, 10)
+		require.NoError(t, err)
+		require.Equal(t, models.ActivityFilter{}, db.filter)
+	})
+
+	t.Run("a bad filter is refused before the store is asked", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			query FeedQuery
+			err   error
+		}{
+			"unknown kind":    {FeedQuery{Kinds: []string{"rating_added", "rated"}}, ErrUnknownKind},
+			"date, not time":  {FeedQuery{CreatedAfter: "2026-03-01"}, ErrInvalidTime},
+			"empty range":     {FeedQuery{CreatedAfter: "2026-03-01T00:00:00Z", CreatedBefore: "2026-03-01T00:00:00Z"}, ErrInvalidTimeRange},
+			"backwards range": {FeedQuery{CreatedAfter: "2026-03-02T00:00:00Z", CreatedBefore: "2026-03-01T00:00:00Z"}, ErrInvalidTimeRange},
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := GetFeed(&historyStore{}, ctx, "alice", tc.query, nil, 10)
+				require.ErrorIs(t, err, tc.err)
+				require.Contains(t, ErrorMap, tc.err, "every filter error must map to a status")
+			})
+		}
+	})
+
+	t.Run("group history is the path's group, whatever the query names", func(t *testing.T) {
+		db := &historyStore{groupId: "g1"}
+
+		_, err := GetGroupHistory(db, ctx, "alice", "g1", FeedQuery{GroupIds: []string{"g2"}}, nil, 10)
+		require.NoError(t, err)
+		require.Equal(t, []string{"g1"}, db.filter.GroupIds)
+
+		_, err = GetTitleHistory(db, ctx, "alice", "g1", "tt1", FeedQuery{TitleId: "tt2"}, nil, 10)
+		require.NoError(t, err)
+		require.Equal(t, []string{"g1"}, db.filter.GroupIds)
+		require.Equal(t, "tt1", *db.filter.TitleId, "the title is the path's too")
+	})
+
+	t.Run("history of a group the caller is not in is not found", func(t *testing.T) {
+		db := &historyStore{groupId: "g1"}
+
+		_, err := GetGroupHistory(db, ctx, "alice", "g2", FeedQuery{}, nil, 10)
+		require.ErrorIs(t, err, ErrGroupNotFound)
+
+		_, err = GetTitleHistory(db, ctx, "alice", "g2", "tt1", FeedQuery{}, nil, 10)
+		require.ErrorIs(t, err, ErrGroupNotFound)
+	})
+
+	t.Run("history pages like the feed", func(t *testing.T) {
+		db := &historyStore{groupId: "g1", events: []models.ActivityEvent{{Id: "e3", Seq: 3}, {Id: "e2", Seq: 2}, {Id: "e1", Seq: 1}}}
+
+		feed, err := GetGroupHistory(db, ctx, "alice", "g1", FeedQuery{}, nil, 2)
+		require.NoError(t, err)
+		require.Len(t, feed.Events, 2)
+		require.True(t, feed.HasMore)
+		require.Equal(t, int64(2), *feed.NextBefore)
+	})
+}
@@ -1,6 +1,10 @@
 package activity
 
-import "github.com/lealre/movies-backend/internal/models"
+import (
+	"fmt"
+
+	"github.com/lealre/movies-backend/internal/models"
+)
 
 // MapDbEventToApiEvent hand-copies the domain event onto the wire type, the
 // same shape as every other mapper in this codebase.
@@ -24,3 +28,31 @@ func MapDbEventToApiEvent(e models.ActivityEvent) Event {
 		Read:      e.Read,
 	}
 }
+
+// mapDbPreferencesToApi splits the store's one list of mutes by shape. The
+// lists come out in the store's order, which is sorted, and never null.
+func mapDbPreferencesToApi(prefs models.ActivityPreferences) Preferences {
+	out := Preferences{Mutes: Mutes{Kinds: []string{}, GroupIds: []string{}, Titles: []TitleMute{}}}
+	for _, mute := range prefs.Mutes {
+		switch {
+		case mute.Kind != "":
+			out.Mutes.Kinds = append(out.Mutes.Kinds, mute.Kind)
+		case mute.TitleId == "":
+			out.Mutes.GroupIds = append(out.Mutes.GroupIds, mute.GroupId)
+		default:
+			out.Mutes.Titles = append(out.Mutes.Titles, TitleMute{GroupId: mute.GroupId, TitleId: mute.TitleId})
+		}
+	}
+	if q := prefs.QuietHours; q != nil {
+		out.QuietHours = &QuietHours{
+			Start:    formatMinute(q.StartMinute),
+			End:      formatMinute(q.EndMinute),
+			TimeZone: q.TimeZone,
+		}
+	}
+	return out
+}
+
+func formatMinute(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
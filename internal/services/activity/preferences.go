@@ -0,0 +1,152 @@
+package activity
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	activitycore "github.com/lealre/movies-backend/internal/activity"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// GetPreferences returns userId's notification preferences: nothing muted and
+// no quiet hours for a user who has set none.
+func GetPreferences(db store.Store, ctx context.Context, userId string) (Preferences, error) {
+	prefs, err := db.GetActivityPreferences(ctx, userId)
+	if err != nil {
+		return Preferences{}, err
+	}
+	return mapDbPreferencesToApi(prefs), nil
+}
+
+// SetPreferences replaces userId's notification preferences. Kinds must be
+// ones the feed records, and every group muted, whole or for one title, must
+// be one the caller is in — a group they are not in is ErrGroupNotFound, as it
+// is for the group's own history. The title is not checked against the group:
+// a title removed from it can still be muted, its history outlives it.
+//
+// Mutes are deduplicated and sorted, so equal choices are stored equally.
+func SetPreferences(db store.Store, ctx context.Context, userId string, req PreferencesRequest) (Preferences, error) {
+	kinds := slices.Clone(req.Mutes.Kinds)
+	for _, kind := range kinds {
+		if !slices.Contains(activitycore.Kinds, kind) {
+			return Preferences{}, ErrUnknownKind
+		}
+	}
+	slices.Sort(kinds)
+	kinds = slices.Compact(kinds)
+
+	groupIds := slices.Clone(req.Mutes.GroupIds)
+	slices.Sort(groupIds)
+	groupIds = slices.Compact(groupIds)
+
+	titles := slices.Clone(req.Mutes.Titles)
+	for _, title := range titles {
+		if title.GroupId == "" || title.TitleId == "" {
+			return Preferences{}, ErrInvalidTitleMute
+		}
+	}
+	slices.SortFunc(titles, func(a, b TitleMute) int {
+		return cmp.Or(cmp.Compare(a.GroupId, b.GroupId), cmp.Compare(a.TitleId, b.TitleId))
+	})
+	titles = slices.Compact(titles)
+
+	quiet, err := parseQuietHours(req.QuietHours)
+	if err != nil {
+		return Preferences{}, err
+	}
+
+	checked := make(map[string]bool)
+	for _, groupId := range groupIds {
+		if err := checkMember(db, ctx, userId, groupId, checked); err != nil {
+			return Preferences{}, err
+		}
+	}
+	for _, title := range titles {
+		if err := checkMember(db, ctx, userId, title.GroupId, checked); err != nil {
+			return Preferences{}, err
+		}
+	}
+
+	mutes := make([]models.ActivityMute, 0, len(kinds)+len(groupIds)+len(titles))
+	for _, kind := range kinds {
+		mutes = append(mutes, models.ActivityMute{Kind: kind})
+	}
+	for _, groupId := range groupIds {
+		mutes = append(mutes, models.ActivityMute{GroupId: groupId})
+	}
+	for _, title := range titles {
+		mutes = append(mutes, models.ActivityMute{GroupId: title.GroupId, TitleId: title.TitleId})
+	}
+
+	prefs, err := db.SetActivityPreferences(ctx, models.ActivityPreferences{
+		UserId:     userId,
+		Mutes:      mutes,
+		QuietHours: quiet,
+	})
+	if err != nil {
+		return Preferences{}, err
+	}
+	return mapDbPreferencesToApi(prefs), nil
+}
+
+// checkMember answers ErrGroupNotFound for a group userId is not in, asking the
+// store once per group however many mutes name it.
+func checkMember(db store.Store, ctx context.Context, userId, groupId string, checked map[string]bool) error {
+	if checked[groupId] {
+		return nil
+	}
+	ok, err := db.GroupExists(ctx, groupId, userId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrGroupNotFound
+	}
+	checked[groupId] = true
+	return nil
+}
+
+// parseQuietHours turns the request's quiet hours into minutes after midnight.
+// An empty zone is UTC. "Local" is refused even though time.LoadLocation takes
+// it: it is this server's zone, not one the user can mean.
+func parseQuietHours(quiet *QuietHours) (*models.QuietHours, error) {
+	if quiet == nil {
+		return nil, nil
+	}
+	start, ok := parseMinute(quiet.Start)
+	if !ok {
+		return nil, ErrInvalidQuietTime
+	}
+	end, ok := parseMinute(quiet.End)
+	if !ok || start == end {
+		return nil, ErrInvalidQuietTime
+	}
+
+	zone := strings.TrimSpace(quiet.TimeZone)
+	if zone == "" {
+		zone = "UTC"
+	}
+	if zone == "Local" {
+		return nil, ErrInvalidTimeZone
+	}
+	if _, err := time.LoadLocation(zone); err != nil {
+		return nil, ErrInvalidTimeZone
+	}
+	return &models.QuietHours{StartMinute: start, EndMinute: end, TimeZone: zone}, nil
+}
+
+// parseMinute reads "HH:MM", two digits each, as minutes after midnight.
+func parseMinute(raw string) (int, bool) {
+	if len(raw) != len("15:04") {
+		return 0, false
+	}
+	t, err := time.Parse("15:04", raw)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
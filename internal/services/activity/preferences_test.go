@@ -0,0 +1,110 @@
+package activity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// preferencesStore is a caller in groups g1 and g2 whose saves are kept as
+// given, so a test sees exactly what the service handed the store.
+type preferencesStore struct {
+	store.Store
+	saved *models.ActivityPreferences
+}
+
+func (s *preferencesStore) GroupExists(_ context.Context, groupId, _ string) (bool, error) {
+	return groupId == "g1" || groupId == "g2", nil
+}
+
+func (s *preferencesStore) SetActivityPreferences(_ context.Context, prefs models.ActivityPreferences) (models.ActivityPreferences, error) {
+	s.saved = &prefs
+	return prefs, nil
+}
+
+func TestSetPreferences(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("mutes are deduplicated, sorted and come back split by shape", func(t *testing.T) {
+		db := &preferencesStore{}
+
+		prefs, err := SetPreferences(db, ctx, "alice", PreferencesRequest{
+			Mutes: Mutes{
+				Kinds:    []string{"rating_added", "comment_added", "rating_added"},
+				GroupIds: []string{"g2", "g2"},
+				Titles:   []TitleMute{{GroupId: "g2", TitleId: "tt2"}, {GroupId: "g1", TitleId: "tt9"}, {GroupId: "g2", TitleId: "tt2"}},
+			},
+			QuietHours: &QuietHours{Start: "22:30", End: "07:00", TimeZone: "Europe/Lisbon"},
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, "alice", db.saved.UserId)
+		require.Equal(t, []models.ActivityMute{
+			{Kind: "comment_added"},
+			{Kind: "rating_added"},
+			{GroupId: "g2"},
+			{GroupId: "g1", TitleId: "tt9"},
+			{GroupId: "g2", TitleId: "tt2"},
+		}, db.saved.Mutes)
+		require.Equal(t, &models.QuietHours{StartMinute: 22*60 + 30, EndMinute: 7 * 60, TimeZone: "Europe/Lisbon"}, db.saved.QuietHours)
+
+		require.Equal(t, Preferences{
+			Mutes: Mutes{
+				Kinds:    []string{"comment_added", "rating_added"},
+				GroupIds: []string{"g2"},
+				Titles:   []TitleMute{{GroupId: "g1", TitleId: "tt9"}, {GroupId: "g2", TitleId: "tt2"}},
+			},
+			QuietHours: &QuietHours{Start: "22:30", End: "07:00", TimeZone: "Europe/Lisbon"},
+		}, prefs)
+	})
+
+	t.Run("an empty request clears everything, and its lists are not null", func(t *testing.T) {
+		db := &preferencesStore{}
+
+		prefs, err := SetPreferences(db, ctx, "alice", PreferencesRequest{})
+		require.NoError(t, err)
+		require.Empty(t, db.saved.Mutes)
+		require.Nil(t, db.saved.QuietHours)
+		require.Equal(t, Mutes{Kinds: []string{}, GroupIds: []string{}, Titles: []TitleMute{}}, prefs.Mutes)
+	})
+
+	t.Run("a zone left out is UTC", func(t *testing.T) {
+		db := &preferencesStore{}
+
+		_, err := SetPreferences(db, ctx, "alice", PreferencesRequest{QuietHours: &QuietHours{Start: "00:00", End: "06:00"}})
+		require.NoError(t, err)
+		require.Equal(t, "UTC", db.saved.QuietHours.TimeZone)
+	})
+
+	t.Run("bad preferences are refused before the store saves anything", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			req PreferencesRequest
+			err error
+		}{
+			"unknown kind":          {PreferencesRequest{Mutes: Mutes{Kinds: []string{"rated"}}}, ErrUnknownKind},
+			"group not joined":      {PreferencesRequest{Mutes: Mutes{GroupIds: []string{"g1", "g3"}}}, ErrGroupNotFound},
+			"title in another":      {PreferencesRequest{Mutes: Mutes{Titles: []TitleMute{{GroupId: "g3", TitleId: "tt1"}}}}, ErrGroupNotFound},
+			"title without a group": {PreferencesRequest{Mutes: Mutes{Titles: []TitleMute{{TitleId: "tt1"}}}}, ErrInvalidTitleMute},
+			"group without a title": {PreferencesRequest{Mutes: Mutes{Titles: []TitleMute{{GroupId: "g1"}}}}, ErrInvalidTitleMute},
+			"not a time":            {PreferencesRequest{QuietHours: &QuietHours{Start: "10pm", End: "07:00"}}, ErrInvalidQuietTime},
+			"one digit":             {PreferencesRequest{QuietHours: &QuietHours{Start: "22:00", End: "7:00"}}, ErrInvalidQuietTime},
+			"past midnight":         {PreferencesRequest{QuietHours: &QuietHours{Start: "24:00", End: "07:00"}}, ErrInvalidQuietTime},
+			"empty window":          {PreferencesRequest{QuietHours: &QuietHours{Start: "07:00", End: "07:00"}}, ErrInvalidQuietTime},
+			"unknown zone":          {PreferencesRequest{QuietHours: &QuietHours{Start: "22:00", End: "07:00", TimeZone: "Mars/Olympus"}}, ErrInvalidTimeZone},
+			"the server's zone":     {PreferencesRequest{QuietHours: &QuietHours{Start: "22:00", End: "07:00", TimeZone: "Local"}}, ErrInvalidTimeZone},
+		} {
+			t.Run(name, func(t *testing.T) {
+				db := &preferencesStore{}
+
+				_, err := SetPreferences(db, ctx, "alice", tc.req)
+				require.ErrorIs(t, err, tc.err)
+				require.Nil(t, db.saved)
+				require.Contains(t, ErrorMap, tc.err, "every preferences error must map to a status")
+			})
+		}
+	})
+}
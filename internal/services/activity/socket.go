@@ -0,0 +1,295 @@
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+
+	activitycore "github.com/lealre/movies-backend/internal/activity"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// The message types of the WebSocket protocol on GET /activity/ws. Every
+// message, in both directions, is one JSON object whose "type" is one of
+// these.
+//
+// A client sends subscribe and unsubscribe (with groupIds), ack (with
+// eventId) and ping. The server sends subscribed after connecting and after
+// every subscribe or unsubscribe, event for each new event in a subscribed
+// group, unread whenever the badge count changes, pong for a ping, resync when
+// it could not keep up, and error for a message it could not act on.
+const (
+	SocketSubscribe   = "subscribe"
+	SocketUnsubscribe = "unsubscribe"
+	SocketAck         = "ack"
+	SocketPing        = "ping"
+
+	SocketSubscribed = "subscribed"
+	SocketEvent      = "event"
+	SocketUnread     = "unread"
+	SocketPong       = "pong"
+	SocketResync     = "resync"
+	SocketError      = "error"
+)
+
+// ClientMessage is anything a client sends. GroupIds is read for subscribe and
+// unsubscribe, where leaving it out means every group; EventId is read for
+// ack.
+type ClientMessage struct {
+	Type     string   `json:"type"`
+	GroupIds []string `json:"groupIds,omitempty"`
+	EventId  string   `json:"eventId,omitempty"`
+}
+
+// SubscribedMessage is the full set of groups the connection is subscribed to,
+// never a difference: a client that missed one cannot get out of step.
+type SubscribedMessage struct {
+	Type     string   `json:"type"`
+	GroupIds []string `json:"groupIds"`
+}
+
+// EventMessage carries one event as the same Event GET /activity returns, so
+// a client renders a pushed event and a fetched one with the same code.
+type EventMessage struct {
+	Type  string `json:"type"`
+	Event Event  `json:"event"`
+}
+
+type UnreadMessage struct {
+	Type   string `json:"type"`
+	Unread int64  `json:"unread"`
+}
+
+// ErrorMessage answers a message the server could not act on. EventId is set
+// when it answers an ack, so the client knows which one failed. An error never
+// closes the connection.
+type ErrorMessage struct {
+	Type    string `json:"type"`
+	Error   string `json:"error"`
+	EventId string `json:"eventId,omitempty"`
+}
+
+// ControlMessage is a message with nothing but its type: pong and resync.
+type ControlMessage struct {
+	Type string `json:"type"`
+}
+
+var (
+	errMalformedMessage = errors.New("message must be a JSON object with a type")
+	errUnknownMessage   = errors.New("unknown message type")
+	errNotAMember       = errors.New("not a member of every group in groupIds")
+)
+
+// Socket is the state of one WebSocket connection, apart from the connection
+// itself: which of the user's groups it is subscribed to, the badge count it
+// last sent, and how far it has kept up with the hub. The handler does the
+// reading and writing; everything the protocol decides is decided here.
+//
+// Subscribing narrows what is pushed as events, not what is counted: the hub
+// subscriber stays on every group the user is in, because the badge counts
+// all of them, and an event in a group the client unsubscribed from still
+// moves it. Membership is the subscriber's, which the hub keeps current: a
+// group the user joins while connected is subscribed to as if it had been
+// there from the start, and one they leave is dropped from the set.
+//
+// Not safe for concurrent use; the handler drives it from one goroutine.
+type Socket struct {
+	db         store.Store
+	subscriber *activitycore.Subscriber
+	subscribed map[string]struct{}
+	members    []string
+	unread     int64
+	dropped    uint64
+	replayed   map[string]struct{}
+}
+
+// OpenSocket starts the protocol on subscriber, subscribed to every group, and
+// returns the messages a client is greeted with: its subscriptions and its
+// badge count, then what it missed when replay has anything to say.
+func OpenSocket(db store.Store, ctx context.Context, subscriber *activitycore.Subscriber, replay Replay) (*Socket, []any, error) {
+	s := &Socket{
+		db:         db,
+		subscriber: subscriber,
+		subscribed: make(map[string]struct{}),
+		members:    subscriber.GroupIds(),
+		dropped:    subscriber.Dropped(),
+		replayed:   make(map[string]struct{}, len(replay.Events)),
+	}
+	for _, groupId := range s.members {
+		s.subscribed[groupId] = struct{}{}
+	}
+
+	unread, err := db.GetActivityUnreadCount(ctx, subscriber.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.unread = unread
+
+	messages := []any{s.subscribedMessage(), UnreadMessage{Type: SocketUnread, Unread: unread}}
+	if replay.Resync {
+		messages = append(messages, ControlMessage{Type: SocketResync})
+	}
+	for _, event := range replay.Events {
+		messages = append(messages, EventMessage{Type: SocketEvent, Event: MapDbEventToApiEvent(event)})
+		s.replayed[event.Id] = struct{}{}
+	}
+	return s, messages, nil
+}
+
+// Deliver returns what to send for an event the hub handed over: the event,
+// if its group is subscribed, and the badge count it raised. An event the
+// replay already sent returns nothing. A resync comes first when the hub has
+// dropped anything since the last look.
+//
+// The count is kept here rather than read back per event: an event arriving
+// from the hub is by definition unread by this user, so adding one is exact,
+// and a query per event per connection is what the hub exists to avoid. It can
+// run one high when an event committed between the subscription and the
+// greeting's read is counted by both, until the next ack or resync reads it
+// back.
+func (s *Socket) Deliver(ctx context.Context, event models.ActivityEvent) ([]any, error) {
+	messages, err := s.CheckDropped(ctx)
+	resynced := len(messages) > 0
+	if _, ok := s.replayed[event.Id]; ok {
+		return messages, err
+	}
+	// A deleted group has already left the subscribed set when its deletion
+	// arrives, and it could never be subscribed to again; the event is the
+	// last word on it, so it is always sent.
+	if _, ok := s.subscribed[event.GroupId]; ok || event.Kind == activitycore.KindGroupDeleted {
+		messages = append(messages, EventMessage{Type: SocketEvent, Event: MapDbEventToApiEvent(event)})
+	}
+	if resynced && err == nil {
+		// The count was just read afresh, and the event was committed before
+		// it was published, so it is already in there.
+		return messages, nil
+	}
+	s.unread++
+	return append(messages, UnreadMessage{Type: SocketUnread, Unread: s.unread}), err
+}
+
+// CheckDropped returns a resync, and the badge count read afresh, if the hub
+// has dropped events for this connection since the last check, and nothing
+// otherwise. The handler calls it between events as well as with them, so a
+// connection that fell behind right before going quiet is still told.
+//
+// Dropping is the hub's backpressure, the same as on the SSE stream: a client
+// too slow to take what the hub publishes loses frames rather than holding up
+// everyone else. The difference is that here the client is told so at once,
+// instead of finding out at its next reconnect.
+func (s *Socket) CheckDropped(ctx context.Context) ([]any, error) {
+	dropped := s.subscriber.Dropped()
+	if dropped == s.dropped {
+		return nil, nil
+	}
+	s.dropped = dropped
+
+	messages := []any{ControlMessage{Type: SocketResync}}
+	unread, err := s.db.GetActivityUnreadCount(ctx, s.subscriber.UserId)
+	if err != nil {
+		return messages, err
+	}
+	s.unread = unread
+	return append(messages, UnreadMessage{Type: SocketUnread, Unread: unread}), nil
+}
+
+// MembershipChanged catches up with the subscriber's groups after the hub
+// changed them, and returns the new subscribed set and the badge count read
+// afresh: a group left takes its unread events out of the count, and one
+// joined brings its own in.
+func (s *Socket) MembershipChanged(ctx context.Context) ([]any, error) {
+	members := s.subscriber.GroupIds()
+	for _, groupId := range members {
+		if !slices.Contains(s.members, groupId) {
+			s.subscribed[groupId] = struct{}{}
+		}
+	}
+	for groupId := range s.subscribed {
+		if !slices.Contains(members, groupId) {
+			delete(s.subscribed, groupId)
+		}
+	}
+	s.members = members
+
+	messages := []any{s.subscribedMessage()}
+	unread, err := s.db.GetActivityUnreadCount(ctx, s.subscriber.UserId)
+	if err != nil {
+		return messages, err
+	}
+	s.unread = unread
+	return append(messages, UnreadMessage{Type: SocketUnread, Unread: unread}), nil
+}
+
+// Handle acts on one message from the client and returns the replies. A
+// message that is malformed, unknown, or names something the user cannot
+// touch is answered with an error message; the error return is for the store
+// failing, and comes with whatever replies were already decided.
+func (s *Socket) Handle(ctx context.Context, data []byte) ([]any, error) {
+	var message ClientMessage
+	if err := json.Unmarshal(data, &message); err != nil || message.Type == "" {
+		return []any{errorMessage(errMalformedMessage)}, nil
+	}
+
+	switch message.Type {
+	case SocketPing:
+		return []any{ControlMessage{Type: SocketPong}}, nil
+
+	case SocketSubscribe, SocketUnsubscribe:
+		groupIds := message.GroupIds
+		if len(groupIds) == 0 {
+			groupIds = s.members
+		}
+		for _, groupId := range groupIds {
+			if !slices.Contains(s.members, groupId) {
+				return []any{errorMessage(errNotAMember)}, nil
+			}
+		}
+		for _, groupId := range groupIds {
+			if message.Type == SocketSubscribe {
+				s.subscribed[groupId] = struct{}{}
+			} else {
+				delete(s.subscribed, groupId)
+			}
+		}
+		return []any{s.subscribedMessage()}, nil
+
+	case SocketAck:
+		if err := MarkEventRead(s.db, ctx, s.subscriber.UserId, message.EventId); err != nil {
+			if errors.Is(err, ErrEventNotFound) {
+				reply := errorMessage(err)
+				reply.EventId = message.EventId
+				return []any{reply}, nil
+			}
+			return nil, err
+		}
+		// Read back rather than decremented: acking an event that was already
+		// read changes nothing, and only the store knows whether it was.
+		unread, err := s.db.GetActivityUnreadCount(ctx, s.subscriber.UserId)
+		if err != nil {
+			return nil, err
+		}
+		s.unread = unread
+		return []any{UnreadMessage{Type: SocketUnread, Unread: unread}}, nil
+
+	default:
+		return []any{errorMessage(errUnknownMessage)}, nil
+	}
+}
+
+// subscribedMessage lists the subscribed groups in the order the user's
+// membership lists them, so the same set always reads the same.
+func (s *Socket) subscribedMessage() SubscribedMessage {
+	groupIds := make([]string, 0, len(s.subscribed))
+	for _, groupId := range s.members {
+		if _, ok := s.subscribed[groupId]; ok {
+			groupIds = append(groupIds, groupId)
+		}
+	}
+	return SubscribedMessage{Type: SocketSubscribed, GroupIds: groupIds}
+}
+
+func errorMessage(err error) ErrorMessage {
+	return ErrorMessage{Type: SocketError, Error: err.Error()}
+}
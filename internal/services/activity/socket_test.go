@@ -0,0 +1,78 @@
+package activity
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	activitycore "github.com/lealre/movies-backend/internal/activity"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// unreadStore answers the badge count and nothing else.
+type unreadStore struct {
+	store.Store
+	unread int64
+}
+
+func (s *unreadStore) GetActivityUnreadCount(context.Context, string) (int64, error) {
+	return s.unread, nil
+}
+
+func TestSocket(t *testing.T) {
+	event := func(i int) models.ActivityEvent {
+		return models.ActivityEvent{Id: fmt.Sprintf("e%d", i), GroupId: "g1", ActorId: "bob"}
+	}
+
+	t.Run("events the hub dropped are a resync with the badge read afresh", func(t *testing.T) {
+		ctx := context.Background()
+		hub := activitycore.NewHub()
+		subscriber := hub.Subscribe("alice", []string{"g1"}, models.ActivityPreferences{})
+		defer hub.Unsubscribe(subscriber)
+		db := &unreadStore{unread: 1}
+		socket, _, err := OpenSocket(db, ctx, subscriber, Replay{})
+		require.NoError(t, err)
+
+		messages, err := socket.CheckDropped(ctx)
+		require.NoError(t, err)
+		require.Empty(t, messages, "nothing dropped, nothing to say")
+
+		// A client that stopped keeping up: the buffer fills and the rest is
+		// dropped.
+		for i := range cap(subscriber.Events) + 2 {
+			hub.Publish(event(i))
+		}
+		db.unread = 40
+
+		messages, err = socket.Deliver(ctx, <-subscriber.Events)
+		require.NoError(t, err)
+		require.Equal(t, ControlMessage{Type: SocketResync}, messages[0], "the client must be told it missed events")
+		require.Equal(t, UnreadMessage{Type: SocketUnread, Unread: 40}, messages[1])
+		require.Len(t, messages, 3, "the event is still sent, and the fresh count already includes it")
+
+		messages, err = socket.Deliver(ctx, <-subscriber.Events)
+		require.NoError(t, err)
+		require.Equal(t, UnreadMessage{Type: SocketUnread, Unread: 41}, messages[len(messages)-1],
+			"one resync per run of drops; after it the count moves by one again")
+	})
+	t.Run("a group's deletion is sent although the group has left the subscribed set", func(t *testing.T) {
+		ctx := context.Background()
+		hub := activitycore.NewHub()
+		subscriber := hub.Subscribe("alice", []string{"g1"}, models.ActivityPreferences{})
+		defer hub.Unsubscribe(subscriber)
+		socket, _, err := OpenSocket(&unreadStore{}, ctx, subscriber, Replay{})
+		require.NoError(t, err)
+
+		hub.ApplyMembership(models.MembershipChange{Kind: models.MembershipGroupDeleted, GroupId: "g1"})
+		_, err = socket.MembershipChanged(ctx)
+		require.NoError(t, err)
+
+		messages, err := socket.Deliver(ctx, models.ActivityEvent{Id: "e1", GroupId: "g1", ActorId: "bob", Kind: activitycore.KindGroupDeleted})
+		require.NoError(t, err)
+		require.Len(t, messages, 2)
+		require.Equal(t, SocketEvent, messages[0].(EventMessage).Type)
+	})
+}
@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	activitycore "github.com/lealre/movies-backend/internal/activity"
+	"github.com/lealre/movies-backend/internal/config"
 	"github.com/lealre/movies-backend/internal/models"
 	"github.com/lealre/movies-backend/internal/store"
 )
@@ -71,10 +73,11 @@ func (s *Streamer) IssueTicket(userId string) StreamTicket {
 //
 // The membership read is GetUserById's, the same one AuthMiddleware performs
 // for every other route and the same predicate the feed query uses: current
-// members of non-deleted groups. It is resolved once, here, and captured in
-// the subscription — a user who joins a group mid-stream sees it on their next
-// connect, which is the trade the design doc makes to keep Publish
-// query-free.
+// members of non-deleted groups. It is resolved once, here, and from then on
+// the hub keeps the subscription current as membership changes are committed,
+// so Publish stays query-free. The user's notification preferences are read
+// here too, once before subscribing and once after, and kept current the same
+// way.
 func (s *Streamer) OpenStream(db store.Store, ctx context.Context, ticket string) (*activitycore.Subscriber, error) {
 	userId, ok := s.tickets.Redeem(ticket)
 	if !ok {
@@ -95,7 +98,26 @@ func (s *Streamer) OpenStream(db store.Store, ctx context.Context, ticket string
 		return nil, ErrInvalidTicket
 	}
 
-	return s.hub.Subscribe(user.Id, user.Groups), nil
+	// Read before subscribing and handed to Subscribe, so nothing the user
+	// muted can be pushed before the preferences are in place.
+	prefs, err := db.GetActivityPreferences(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	subscriber := s.hub.Subscribe(user.Id, user.Groups, prefs)
+
+	// A change saved after that read but before the subscriber was registered
+	// may have had its notification reach the hub while the subscriber was
+	// not yet in it. Reading again now covers that window; one saved after
+	// this read reaches the subscriber through the listener, as a membership
+	// change does.
+	prefs, err = db.GetActivityPreferences(ctx, user.Id)
+	if err != nil {
+		s.hub.Unsubscribe(subscriber)
+		return nil, err
+	}
+	subscriber.RefreshPreferences(prefs)
+	return subscriber, nil
 }
 
 // CloseStream removes a subscriber from the hub. It is safe to call more than
@@ -112,14 +134,15 @@ func (s *Streamer) CloseStream(subscriber *activitycore.Subscriber) {
 // pushing data instead of a signal, which is why there is one function here
 // and not a second serializer, and why a test pins the two byte-identical.
 //
-// The DTO's read flag is false on this path, and correctly so: the event is
-// being pushed because it was just committed, so no subscriber can have read it
-// yet. That is also what keeps this frame byte-identical to the same event's
-// REST element — the reader who has not read it sees read:false there too.
+// The DTO's read flag is false for a live event, and correctly so: the event
+// is being pushed because it was just committed, so no subscriber can have
+// read it yet. That is also what keeps this frame byte-identical to the same
+// event's REST element — the reader who has not read it sees read:false there
+// too. A replayed event carries the reader's read state from the log, which
+// keeps the same property.
 //
-// id: carries the event's seq. Nothing reads it back — reconnect takes a fresh
-// snapshot rather than replaying from Last-Event-ID — but it costs nothing and
-// leaves replay possible later.
+// id: carries the event's seq, which is what the browser sends back as
+// Last-Event-ID and what ReplaySince resumes from.
 func StreamFrame(event models.ActivityEvent) ([]byte, error) {
 	data, err := json.Marshal(MapDbEventToApiEvent(event))
 	if err != nil {
@@ -127,3 +150,59 @@ func StreamFrame(event models.ActivityEvent) ([]byte, error) {
 	}
 	return fmt.Appendf(nil, "id: %d\nevent: activity\ndata: %s\n\n", event.Seq, data), nil
 }
+
+// resyncFrame tells a client that what it has is stale and that it must take a
+// fresh snapshot: the stream could not replay what a resuming client missed,
+// or the user joined or left a group. It has no id: line, so the client's
+// Last-Event-ID is left as it was.
+var resyncFrame = []byte("event: resync\ndata: {}\n\n")
+
+// ResyncFrame renders the resync control event.
+func ResyncFrame() []byte { return resyncFrame }
+
+// Replay is what a resuming stream is sent before it goes live: the events it
+// missed, oldest first, or Resync when they cannot be replayed.
+type Replay struct {
+	Events []models.ActivityEvent
+	Resync bool
+}
+
+// ReplaySince returns what the subscriber missed after lastEventId, the seq of
+// the last frame it saw.
+//
+// An empty lastEventId is a first connect, which snapshots and needs no
+// replay. One that is not a seq, or more missed events than
+// ACTIVITY_STREAM_REPLAY_LIMIT, is a Resync: a snapshot repairs either in one
+// request.
+//
+// During the subscriber's quiet hours nothing is replayed, as nothing is
+// pushed live: a reconnect must not deliver in a burst what Publish is
+// holding back. What was skipped stays in the feed, as what Publish holds
+// back does.
+//
+// Call it after OpenStream, not before: the subscription then covers
+// everything the replay's read does not, at the cost of events committed in
+// between arriving twice, which the caller drops by id.
+func ReplaySince(db store.Store, ctx context.Context, subscriber *activitycore.Subscriber, lastEventId string) (Replay, error) {
+	if lastEventId == "" {
+		return Replay{}, nil
+	}
+	afterSeq, err := strconv.ParseInt(lastEventId, 10, 64)
+	if err != nil || afterSeq < 0 {
+		return Replay{Resync: true}, nil
+	}
+	if subscriber.QuietAt(time.Now()) {
+		return Replay{}, nil
+	}
+
+	limit := config.ActivityStreamReplayLimit()
+	// One extra row says the cap was exceeded without a count.
+	events, err := db.GetActivityEventsSince(ctx, subscriber.UserId, afterSeq, limit+1)
+	if err != nil {
+		return Replay{}, err
+	}
+	if len(events) > limit {
+		return Replay{Resync: true}, nil
+	}
+	return Replay{Events: events}, nil
+}
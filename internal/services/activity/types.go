@@ -31,6 +31,58 @@ type Feed struct {
 	HasMore    bool    `json:"hasMore"`
 }
 
+// FeedQuery is the optional filters of the activity feeds, as the client sent
+// them. GroupIds and Kinds match any of their values; CreatedAfter (inclusive)
+// and CreatedBefore (exclusive) are RFC 3339 timestamps. Empty means unset.
+type FeedQuery struct {
+	GroupIds      []string
+	TitleId       string
+	Kinds         []string
+	ActorId       string
+	CreatedAfter  string
+	CreatedBefore string
+}
+
 type UnreadCount struct {
 	Unread int64 `json:"unread"`
 }
+
+// Preferences are what a user has muted and when they want nothing pushed to
+// them live. Muted events are left out of the feed, the badge and the stream
+// alike; quiet hours hold back the stream only, so what happened during them
+// is waiting in the feed afterwards. QuietHours is null when there are none.
+type Preferences struct {
+	Mutes      Mutes       `json:"mutes"`
+	QuietHours *QuietHours `json:"quietHours"`
+}
+
+// Mutes are the three things a user can mute: a kind wherever it happens,
+// a whole group, or one title's thread in one group. None of the lists is
+// ever null.
+type Mutes struct {
+	Kinds    []string    `json:"kinds"`
+	GroupIds []string    `json:"groupIds"`
+	Titles   []TitleMute `json:"titles"`
+}
+
+type TitleMute struct {
+	GroupId string `json:"groupId"`
+	TitleId string `json:"titleId"`
+}
+
+// QuietHours run from Start (inclusive) to End (exclusive), both "HH:MM" on the
+// wall clock of TimeZone, an IANA name such as "Europe/Lisbon". An End before
+// Start runs across midnight.
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	TimeZone string `json:"timeZone"`
+}
+
+// PreferencesRequest replaces a user's preferences whole: a mute left out is
+// unmuted, and a null or absent QuietHours turns them off. TimeZone may be left
+// out for UTC.
+type PreferencesRequest struct {
+	Mutes      Mutes       `json:"mutes"`
+	QuietHours *QuietHours `json:"quietHours"`
+}
@@ -18,7 +18,24 @@ var ErrEventNotFound = errors.New("activity event not found")
 // report to the client than "not authorized."
 var ErrInvalidTicket = errors.New("ticket is invalid, expired, or already used")
 
+var (
+	ErrGroupNotFound    = errors.New("group not found")
+	ErrUnknownKind      = errors.New("kind contains an unknown activity kind")
+	ErrInvalidTime      = errors.New("createdAfter and createdBefore must be RFC 3339 timestamps")
+	ErrInvalidTimeRange = errors.New("createdAfter must be before createdBefore")
+	ErrInvalidTitleMute = errors.New("a muted title needs both a groupId and a titleId")
+	ErrInvalidQuietTime = errors.New("quietHours start and end must be different HH:MM times")
+	ErrInvalidTimeZone  = errors.New("quietHours timeZone must be an IANA time zone name")
+)
+
 var ErrorMap = map[error]int{
-	ErrEventNotFound: http.StatusNotFound,
-	ErrInvalidTicket: http.StatusUnauthorized,
+	ErrEventNotFound:    http.StatusNotFound,
+	ErrInvalidTicket:    http.StatusUnauthorized,
+	ErrGroupNotFound:    http.StatusNotFound,
+	ErrUnknownKind:      http.StatusBadRequest,
+	ErrInvalidTime:      http.StatusBadRequest,
+	ErrInvalidTimeRange: http.StatusBadRequest,
+	ErrInvalidTitleMute: http.StatusBadRequest,
+	ErrInvalidQuietTime: http.StatusBadRequest,
+	ErrInvalidTimeZone:  http.StatusBadRequest,
 }
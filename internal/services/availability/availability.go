@@ -0,0 +1,160 @@
+// Package availability serves where titles can be watched: fetching it from
+// the streaming provider into the store, reading it back for a region, and
+// the per-user subscription preferences behind the "available on my services"
+// filter. See internal/titleprovider/streaming for the provider side.
+package availability
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/config"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+	"github.com/lealre/movies-backend/internal/titleprovider"
+	"github.com/lealre/movies-backend/internal/titleprovider/streaming"
+)
+
+// Refresh fetches titleId's availability from provider and replaces what the
+// store holds for it. A title the provider does not know is stored as offered
+// nowhere rather than failing: it is a fact about the title, and recording it
+// is what tells readers the title has been looked up.
+func Refresh(db store.Store, ctx context.Context, provider streaming.Provider, titleId string) error {
+	fetched, err := provider.GetAvailability(ctx, titleId)
+	if err != nil && !errors.Is(err, titleprovider.ErrTitleNotFound) {
+		return err
+	}
+
+	stored := models.TitleAvailability{
+		TitleID:   titleId,
+		Provider:  provider.Name(),
+		FetchedAt: time.Now().UTC(),
+		Regions:   map[string]models.RegionAvailability{},
+	}
+	if fetched != nil {
+		for code, r := range fetched.Regions {
+			stored.Regions[code] = models.RegionAvailability{
+				Link:     r.Link,
+				Flatrate: mapServicesToDb(r.Flatrate),
+				Rent:     mapServicesToDb(r.Rent),
+				Buy:      mapServicesToDb(r.Buy),
+			}
+		}
+	}
+	return db.ReplaceTitleAvailability(ctx, stored)
+}
+
+// GetBatch returns the stored availability in region of each of titleIds.
+// Titles never fetched are absent from the map; a title fetched but offered
+// nowhere in region is present, with empty lists.
+func GetBatch(db store.Store, ctx context.Context, titleIds []string, region string) (map[string]Availability, error) {
+	stored, err := db.GetTitleAvailability(ctx, titleIds, region)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]Availability, len(stored))
+	for titleId, a := range stored {
+		r := a.Regions[region]
+		result[titleId] = Availability{
+			Region:    region,
+			Link:      r.Link,
+			Flatrate:  mapServicesToApi(r.Flatrate),
+			Rent:      mapServicesToApi(r.Rent),
+			Buy:       mapServicesToApi(r.Buy),
+			Provider:  a.Provider,
+			FetchedAt: a.FetchedAt,
+		}
+	}
+	return result, nil
+}
+
+// GetPreferences returns userId's streaming preferences, or the defaults (the
+// instance region, no services) for a user who has set none.
+func GetPreferences(db store.Store, ctx context.Context, userId string) (Preferences, error) {
+	prefs, err := db.GetStreamingPreferences(ctx, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return Preferences{Region: config.StreamingDefaultRegion(), ServiceIds: []int{}}, nil
+		}
+		return Preferences{}, err
+	}
+	return mapDbPreferencesToApi(prefs), nil
+}
+
+// SetPreferences replaces userId's streaming preferences. The region is
+// upper-cased and the service ids deduplicated and sorted, so equal choices
+// are stored equally.
+func SetPreferences(db store.Store, ctx context.Context, userId string, req PreferencesRequest) (Preferences, error) {
+	region := strings.ToUpper(strings.TrimSpace(req.Region))
+	if region == "" {
+		region = config.StreamingDefaultRegion()
+	}
+	if !validRegion(region) {
+		return Preferences{}, ErrInvalidRegion
+	}
+	if req.ServiceIds == nil {
+		return Preferences{}, ErrServiceIdsRequired
+	}
+	serviceIds := slices.Clone(req.ServiceIds)
+	for _, id := range serviceIds {
+		if id <= 0 {
+			return Preferences{}, ErrInvalidServiceId
+		}
+	}
+	slices.Sort(serviceIds)
+	serviceIds = slices.Compact(serviceIds)
+
+	prefs, err := db.SetStreamingPreferences(ctx, models.StreamingPreferences{
+		UserID:     userId,
+		Region:     region,
+		ServiceIDs: serviceIds,
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return Preferences{}, err
+	}
+	return mapDbPreferencesToApi(prefs), nil
+}
+
+// RegionFor is the region userId sees availability for: their own, or the
+// instance default.
+func RegionFor(db store.Store, ctx context.Context, userId string) (string, error) {
+	prefs, err := GetPreferences(db, ctx, userId)
+	if err != nil {
+		return "", err
+	}
+	return prefs.Region, nil
+}
+
+// FilterFor is the "available on my services" filter for userId, or
+// ErrNoStreamingServices when they have never said which services they have —
+// filtering on nothing would silently empty every listing.
+func FilterFor(db store.Store, ctx context.Context, userId string) (*models.StreamingFilter, error) {
+	prefs, err := db.GetStreamingPreferences(ctx, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return nil, ErrNoStreamingServices
+		}
+		return nil, err
+	}
+	return &models.StreamingFilter{Region: prefs.Region, ServiceIDs: prefs.ServiceIDs}, nil
+}
+
+// validRegion reports whether region has the shape of an ISO 3166-1 alpha-2
+// code. Whether the provider has data for it is not checked: a region with
+// none just shows no offers.
+func validRegion(region string) bool {
+	if len(region) != 2 {
+		return false
+	}
+	for _, r := range region {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
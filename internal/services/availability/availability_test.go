@@ -0,0 +1,125 @@
+package availability
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+	"github.com/lealre/movies-backend/internal/titleprovider"
+	"github.com/lealre/movies-backend/internal/titleprovider/streaming"
+)
+
+// availabilityStore satisfies store.Store by embedding the interface, so only
+// the methods this package calls need bodies.
+type availabilityStore struct {
+	store.Store
+	replaced []models.TitleAvailability
+	prefs    *models.StreamingPreferences
+}
+
+func (s *availabilityStore) ReplaceTitleAvailability(_ context.Context, a models.TitleAvailability) error {
+	s.replaced = append(s.replaced, a)
+	return nil
+}
+
+func (s *availabilityStore) GetStreamingPreferences(context.Context, string) (models.StreamingPreferences, error) {
+	if s.prefs == nil {
+		return models.StreamingPreferences{}, store.ErrRecordNotFound
+	}
+	return *s.prefs, nil
+}
+
+func (s *availabilityStore) SetStreamingPreferences(_ context.Context, prefs models.StreamingPreferences) (models.StreamingPreferences, error) {
+	s.prefs = &prefs
+	return prefs, nil
+}
+
+type stubProvider struct {
+	availability *streaming.Availability
+	err          error
+}
+
+func (p stubProvider) Name() string { return "stub" }
+
+func (p stubProvider) GetAvailability(context.Context, string) (*streaming.Availability, error) {
+	return p.availability, p.err
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stores every region under the provider's name", func(t *testing.T) {
+		db := &availabilityStore{}
+		provider := stubProvider{availability: &streaming.Availability{Regions: map[string]streaming.Region{
+			"US": {Link: "l", Flatrate: []streaming.Service{{ID: 8, Name: "Netflix", DisplayPriority: 2}}},
+		}}}
+		if err := Refresh(db, ctx, provider, "tt1"); err != nil {
+			t.Fatal(err)
+		}
+		got := db.replaced[0]
+		if got.TitleID != "tt1" || got.Provider != "stub" || got.FetchedAt.IsZero() {
+			t.Fatalf("stored %+v", got)
+		}
+		us := got.Regions["US"]
+		if us.Link != "l" || len(us.Flatrate) != 1 || us.Flatrate[0].DisplayPriority != 2 || us.Rent == nil {
+			t.Fatalf("US = %+v", us)
+		}
+	})
+
+	t.Run("a title the provider does not know is stored as offered nowhere", func(t *testing.T) {
+		db := &availabilityStore{}
+		if err := Refresh(db, ctx, stubProvider{err: titleprovider.ErrTitleNotFound}, "tt1"); err != nil {
+			t.Fatal(err)
+		}
+		if len(db.replaced) != 1 || len(db.replaced[0].Regions) != 0 {
+			t.Fatalf("stored %+v", db.replaced)
+		}
+	})
+
+	t.Run("a failure stores nothing", func(t *testing.T) {
+		db := &availabilityStore{}
+		down := errors.New("503")
+		if err := Refresh(db, ctx, stubProvider{err: down}, "tt1"); !errors.Is(err, down) {
+			t.Fatalf("err = %v", err)
+		}
+		if len(db.replaced) != 0 {
+			t.Fatalf("stored %+v", db.replaced)
+		}
+	})
+}
+
+func TestSetPreferences(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("STREAMING_DEFAULT_REGION", "")
+
+	db := &availabilityStore{}
+	if _, err := FilterFor(db, ctx, "u"); !errors.Is(err, ErrNoStreamingServices) {
+		t.Fatalf("err = %v, want ErrNoStreamingServices before any are set", err)
+	}
+
+	prefs, err := SetPreferences(db, ctx, "u", PreferencesRequest{ServiceIds: []int{337, 8, 337}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefs.Region != "US" || len(prefs.ServiceIds) != 2 || prefs.ServiceIds[0] != 8 {
+		t.Fatalf("got %+v, want the default region and sorted, unique ids", prefs)
+	}
+
+	filter, err := FilterFor(db, ctx, "u")
+	if err != nil || filter.Region != "US" || len(filter.ServiceIDs) != 2 {
+		t.Fatalf("got %+v, %v", filter, err)
+	}
+
+	for _, req := range []PreferencesRequest{
+		{Region: "USA", ServiceIds: []int{8}},
+		{Region: "u1", ServiceIds: []int{8}},
+		{Region: "US"},
+		{Region: "US", ServiceIds: []int{-1}},
+	} {
+		if _, err := SetPreferences(db, ctx, "u", req); err == nil {
+			t.Errorf("%+v: expected an error", req)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package availability
+
+import (
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/titleprovider/streaming"
+)
+
+func mapServicesToDb(in []streaming.Service) []models.StreamingService {
+	out := make([]models.StreamingService, 0, len(in))
+	for _, s := range in {
+		out = append(out, models.StreamingService{
+			ID:              s.ID,
+			Name:            s.Name,
+			LogoURL:         s.LogoURL,
+			DisplayPriority: s.DisplayPriority,
+		})
+	}
+	return out
+}
+
+func mapServicesToApi(in []models.StreamingService) []Service {
+	out := make([]Service, 0, len(in))
+	for _, s := range in {
+		out = append(out, Service{Id: s.ID, Name: s.Name, LogoUrl: s.LogoURL})
+	}
+	return out
+}
+
+func mapDbPreferencesToApi(prefs models.StreamingPreferences) Preferences {
+	serviceIds := prefs.ServiceIDs
+	if serviceIds == nil {
+		serviceIds = []int{}
+	}
+	updatedAt := prefs.UpdatedAt
+	return Preferences{
+		Region:     prefs.Region,
+		ServiceIds: serviceIds,
+		UpdatedAt:  &updatedAt,
+	}
+}
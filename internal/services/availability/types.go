@@ -0,0 +1,41 @@
+package availability
+
+import "time"
+
+// Availability is where a title can be watched in one region. The three lists
+// are never null, and each is in the provider's display order. Provider and
+// FetchedAt say whose data it is and how old: availability changes often, and
+// is only as fresh as the last refresh.
+type Availability struct {
+	Region    string    `json:"region"`
+	Link      string    `json:"link,omitempty"`
+	Flatrate  []Service `json:"flatrate"`
+	Rent      []Service `json:"rent"`
+	Buy       []Service `json:"buy"`
+	Provider  string    `json:"provider"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// Service is one streaming service. Id is the provider's, and is what
+// Preferences.ServiceIds lists.
+type Service struct {
+	Id      int    `json:"id"`
+	Name    string `json:"name"`
+	LogoUrl string `json:"logoUrl,omitempty"`
+}
+
+// Preferences are the services a user subscribes to and the region they
+// watch from. UpdatedAt is absent until the user first sets them, in which
+// case Region is the instance default and ServiceIds is empty.
+type Preferences struct {
+	Region     string     `json:"region"`
+	ServiceIds []int      `json:"serviceIds"`
+	UpdatedAt  *time.Time `json:"updatedAt,omitempty"`
+}
+
+// PreferencesRequest replaces a user's preferences. Region may be left out
+// for the instance default; ServiceIds is required, and may be empty.
+type PreferencesRequest struct {
+	Region     string `json:"region"`
+	ServiceIds []int  `json:"serviceIds"`
+}
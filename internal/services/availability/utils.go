@@ -0,0 +1,20 @@
+package availability
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrInvalidRegion       = errors.New("region must be a two-letter country code, e.g. US")
+	ErrServiceIdsRequired  = errors.New("serviceIds is required")
+	ErrInvalidServiceId    = errors.New("service ids must be positive integers")
+	ErrNoStreamingServices = errors.New("set your streaming services first (PUT /users/me/streaming-services)")
+)
+
+var ErrorMap = map[error]int{
+	ErrInvalidRegion:       http.StatusBadRequest,
+	ErrServiceIdsRequired:  http.StatusBadRequest,
+	ErrInvalidServiceId:    http.StatusBadRequest,
+	ErrNoStreamingServices: http.StatusBadRequest,
+}
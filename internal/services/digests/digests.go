@@ -0,0 +1,97 @@
+// Package digests emails members a summary of the activity they have not read,
+// daily or weekly as each of them chooses, for the ones who do not open the
+// app. A digest covers a reader's unread events — the badge's unread, so
+// nothing muted, already read or from a group they left — of the kinds in
+// Kinds, grouped by group, and each event goes into one digest at most.
+package digests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/activity"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// Kinds are what a digest reports: titles added, ratings with their notes,
+// comments, and titles marked watched or not. Removals, membership and group
+// changes are left to the feed — they are not what someone away from the app
+// is missing out on.
+var Kinds = []string{
+	activity.KindTitleAdded, activity.KindTitlesAdded,
+	activity.KindRatingAdded, activity.KindRatingUpdated,
+	activity.KindCommentAdded, activity.KindCommentUpdated,
+	activity.KindTitleWatchedChanged,
+}
+
+// period is how long after one digest the next is due.
+func period(frequency string) time.Duration {
+	if frequency == models.DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// GetSettings returns userId's digest settings: off for a user who has never
+// subscribed.
+func GetSettings(db store.Store, ctx context.Context, userId string) (Settings, error) {
+	digest, err := db.GetActivityDigest(ctx, userId)
+	if errors.Is(err, store.ErrRecordNotFound) {
+		return Settings{Frequency: FrequencyOff}, nil
+	}
+	if err != nil {
+		return Settings{}, err
+	}
+	return mapDbDigestToApi(digest), nil
+}
+
+// SetSettings subscribes userId to a daily or weekly digest, or unsubscribes
+// them with FrequencyOff. Subscribing needs an email address on the account
+// (ErrNoEmail); unsubscribing never does.
+//
+// A new subscription covers activity from now on, and its first digest is
+// due one period from now; so is the next one of a user who changes their
+// frequency. Saving the frequency a user already has changes nothing.
+func SetSettings(db store.Store, ctx context.Context, userId string, req SettingsRequest) (Settings, error) {
+	frequency := strings.ToLower(strings.TrimSpace(req.Frequency))
+	switch frequency {
+	case FrequencyOff:
+		if err := db.DeleteActivityDigest(ctx, userId); err != nil {
+			return Settings{}, err
+		}
+		return Settings{Frequency: FrequencyOff}, nil
+	case models.DigestDaily, models.DigestWeekly:
+	default:
+		return Settings{}, ErrInvalidFrequency
+	}
+
+	user, err := db.GetUserById(ctx, userId)
+	if err != nil {
+		return Settings{}, err
+	}
+	if user.Email == "" {
+		return Settings{}, ErrNoEmail
+	}
+
+	digest, err := db.SetActivityDigest(ctx, models.ActivityDigest{
+		UserId:    userId,
+		Frequency: frequency,
+		NextDueAt: time.Now().Add(period(frequency)),
+	})
+	if err != nil {
+		return Settings{}, err
+	}
+	return mapDbDigestToApi(digest), nil
+}
+
+func mapDbDigestToApi(digest models.ActivityDigest) Settings {
+	nextDueAt := digest.NextDueAt
+	return Settings{
+		Frequency:  digest.Frequency,
+		NextDueAt:  &nextDueAt,
+		LastSentAt: digest.LastSentAt,
+	}
+}
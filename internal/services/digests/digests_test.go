@@ -0,0 +1,96 @@
+package digests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// settingsStore holds one user's account and digest subscription in memory.
+type settingsStore struct {
+	store.Store
+	email  string
+	digest *models.ActivityDigest
+}
+
+func (s *settingsStore) GetUserById(_ context.Context, id string) (models.User, error) {
+	return models.User{Id: id, Email: s.email}, nil
+}
+
+func (s *settingsStore) GetActivityDigest(context.Context, string) (models.ActivityDigest, error) {
+	if s.digest == nil {
+		return models.ActivityDigest{}, store.ErrRecordNotFound
+	}
+	return *s.digest, nil
+}
+
+func (s *settingsStore) SetActivityDigest(_ context.Context, digest models.ActivityDigest) (models.ActivityDigest, error) {
+	s.digest = &digest
+	return digest, nil
+}
+
+func (s *settingsStore) DeleteActivityDigest(context.Context, string) error {
+	s.digest = nil
+	return nil
+}
+
+func TestSettings(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("a user who never subscribed is off", func(t *testing.T) {
+		settings, err := GetSettings(&settingsStore{}, ctx, "ana")
+		require.NoError(t, err)
+		assert.Equal(t, Settings{Frequency: FrequencyOff}, settings)
+	})
+
+	t.Run("subscribing schedules the first digest one period out", func(t *testing.T) {
+		db := &settingsStore{email: "ana@example.com"}
+		before := time.Now()
+
+		settings, err := SetSettings(db, ctx, "ana", SettingsRequest{Frequency: " Weekly "})
+		require.NoError(t, err)
+
+		require.NotNil(t, db.digest)
+		assert.Equal(t, models.DigestWeekly, db.digest.Frequency)
+		assert.WithinDuration(t, before.Add(7*24*time.Hour), db.digest.NextDueAt, time.Minute)
+		assert.Equal(t, models.DigestWeekly, settings.Frequency)
+		require.NotNil(t, settings.NextDueAt)
+		assert.Nil(t, settings.LastSentAt, "nothing has been sent yet")
+
+		got, err := GetSettings(db, ctx, "ana")
+		require.NoError(t, err)
+		assert.Equal(t, settings, got)
+	})
+
+	t.Run("off unsubscribes", func(t *testing.T) {
+		db := &settingsStore{email: "ana@example.com", digest: &models.ActivityDigest{UserId: "ana", Frequency: models.DigestDaily}}
+
+		settings, err := SetSettings(db, ctx, "ana", SettingsRequest{Frequency: "off"})
+		require.NoError(t, err)
+		assert.Equal(t, Settings{Frequency: FrequencyOff}, settings)
+		assert.Nil(t, db.digest)
+	})
+
+	t.Run("a digest needs an address to go to", func(t *testing.T) {
+		db := &settingsStore{}
+		_, err := SetSettings(db, ctx, "ana", SettingsRequest{Frequency: "daily"})
+		require.ErrorIs(t, err, ErrNoEmail)
+		assert.Nil(t, db.digest)
+
+		_, err = SetSettings(db, ctx, "ana", SettingsRequest{Frequency: "off"})
+		require.NoError(t, err, "turning it off never needs one")
+	})
+
+	t.Run("an unknown frequency is refused", func(t *testing.T) {
+		for _, frequency := range []string{"", "hourly", "monthly"} {
+			_, err := SetSettings(&settingsStore{email: "ana@example.com"}, ctx, "ana", SettingsRequest{Frequency: frequency})
+			require.ErrorIs(t, err, ErrInvalidFrequency, "frequency %q", frequency)
+		}
+	})
+}
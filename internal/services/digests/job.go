@@ -0,0 +1,139 @@
+package digests
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/mailer"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+const (
+	// jobInterval is how often the job looks for due digests. A digest goes
+	// out at most this late, and a schedule drifts by at most this much a
+	// period, which nobody reading a daily email will notice.
+	jobInterval = 15 * time.Minute
+	// jobBatchSize is how many digests are claimed at once.
+	jobBatchSize = 20
+	// jobLease is how long a claimed batch is kept from other servers: enough
+	// to send all of it, one message at a time.
+	jobLease = 15 * time.Minute
+	// eventLimit is how many events one digest covers. Anything past it is
+	// still unread and not yet digested, so the next digest starts with it.
+	eventLimit = 500
+	// retryAfter is how long a digest that could not be sent waits before it
+	// is tried again: a relay that is down now is likely down for a while.
+	retryAfter = time.Hour
+)
+
+// Job sends the digests that are due. Digests are claimed with a lease, as
+// webhook deliveries are, so several servers can run it and one that dies
+// mid-batch only delays the digests it held.
+//
+// Each digest records the newest event it covered once it is sent, and the
+// next one starts after it, so an event is mailed once. The record is written
+// after the send: a crash in between sends that digest again when the lease
+// runs out, rather than losing it.
+type Job struct {
+	db       store.Store
+	mailer   mailer.Mailer
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewJob(db store.Store, m mailer.Mailer) *Job {
+	return &Job{db: db, mailer: m, interval: jobInterval, now: time.Now}
+}
+
+// Run sends due digests until ctx is cancelled.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := j.SendDue(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("ERROR: sending activity digests failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue goes through batch after batch of due digests until none is left,
+// and returns how many it sent. A digest with nothing in it is not sent, and
+// nor is one whose user has no address or is deactivated; each is simply
+// scheduled again. One that fails to send is retried after retryAfter.
+func (j *Job) SendDue(ctx context.Context) (int, error) {
+	sent := 0
+	for ctx.Err() == nil {
+		batch, err := j.db.ClaimActivityDigests(ctx, jobBatchSize, j.now().Add(jobLease))
+		if err != nil {
+			return sent, err
+		}
+		for _, dispatch := range batch {
+			ok, err := j.send(ctx, dispatch)
+			if err != nil {
+				log.Printf("ERROR: sending the activity digest of user %s failed: %v", dispatch.Digest.UserId, err)
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+		if len(batch) < jobBatchSize {
+			break
+		}
+	}
+	return sent, nil
+}
+
+// send sends one digest if there is anything to send, records the run, and
+// reports whether it sent one.
+func (j *Job) send(ctx context.Context, dispatch models.ActivityDigestDispatch) (bool, error) {
+	digest := dispatch.Digest
+	events, err := j.db.GetActivityDigestEvents(ctx, digest.UserId, digest.DigestedSeq, Kinds, eventLimit)
+	if err != nil {
+		return false, err
+	}
+
+	now := j.now()
+	done := models.ActivityDigest{
+		UserId:      digest.UserId,
+		DigestedSeq: digest.DigestedSeq,
+		NextDueAt:   now.Add(period(digest.Frequency)),
+	}
+	if len(events) > 0 {
+		done.DigestedSeq = events[len(events)-1].Seq
+	}
+
+	// Nothing to say, or nobody to say it to: the events, if any, count as
+	// digested, so they do not all arrive at once if an address turns up.
+	if len(events) == 0 || dispatch.Email == "" || !dispatch.IsActive {
+		// Recorded even if ctx was cancelled meanwhile, as a webhook attempt
+		// is: the lease would otherwise repeat the run.
+		return false, j.db.CompleteActivityDigest(context.WithoutCancel(ctx), done)
+	}
+
+	name := dispatch.Name
+	if name == "" {
+		name = dispatch.Username
+	}
+	subject, text, html, err := render(name, digest.Frequency, events)
+	if err != nil {
+		return false, err
+	}
+	if err := j.mailer.Send(ctx, mailer.Message{To: dispatch.Email, Subject: subject, Text: text, HTML: html}); err != nil {
+		retry := models.ActivityDigest{UserId: digest.UserId, DigestedSeq: digest.DigestedSeq, NextDueAt: now.Add(retryAfter)}
+		if recordErr := j.db.CompleteActivityDigest(context.WithoutCancel(ctx), retry); recordErr != nil {
+			log.Printf("ERROR: rescheduling the activity digest of user %s failed: %v", digest.UserId, recordErr)
+		}
+		return false, err
+	}
+
+	done.LastSentAt = &now
+	return true, j.db.CompleteActivityDigest(context.WithoutCancel(ctx), done)
+}
@@ -0,0 +1,245 @@
+package digests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/activity"
+	"github.com/lealre/movies-backend/internal/mailer"
+	"github.com/lealre/movies-backend/internal/mailer/mailtest"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// jobStore is the job's side of the store in memory: the subscriptions that
+// are due, each reader's unread events, and what each run recorded.
+type jobStore struct {
+	store.Store
+	due       []models.ActivityDigestDispatch
+	events    map[string][]models.ActivityEvent
+	completed map[string]models.ActivityDigest
+}
+
+func (s *jobStore) ClaimActivityDigests(_ context.Context, limit int, _ time.Time) ([]models.ActivityDigestDispatch, error) {
+	n := min(limit, len(s.due))
+	batch := s.due[:n]
+	s.due = s.due[n:]
+	return batch, nil
+}
+
+func (s *jobStore) GetActivityDigestEvents(_ context.Context, userId string, afterSeq int64, kinds []string, limit int) ([]models.ActivityEvent, error) {
+	var out []models.ActivityEvent
+	for _, event := range s.events[userId] {
+		if event.Seq > afterSeq && len(out) < limit && contains(kinds, event.Kind) {
+			out = append(out, event)
+		}
+	}
+	return out, nil
+}
+
+func (s *jobStore) CompleteActivityDigest(_ context.Context, digest models.ActivityDigest) error {
+	s.completed[digest.UserId] = digest
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func dispatchFor(userId, email string, digestedSeq int64) models.ActivityDigestDispatch {
+	return models.ActivityDigestDispatch{
+		Digest:   models.ActivityDigest{UserId: userId, Frequency: models.DigestDaily, DigestedSeq: digestedSeq},
+		Name:     strings.ToUpper(userId[:1]) + userId[1:],
+		Email:    email,
+		IsActive: true,
+	}
+}
+
+func event(seq int64, group, kind, title string, payload map[string]any) models.ActivityEvent {
+	e := models.ActivityEvent{
+		Seq: seq, GroupId: group, GroupName: "Group " + strings.ToUpper(group),
+		ActorName: "bob", Kind: kind, Payload: payload,
+	}
+	if title != "" {
+		e.TitleId, e.TitleName = &title, &title
+	}
+	return e
+}
+
+func newJob(t *testing.T, db store.Store, server *mailtest.Server, now time.Time) *Job {
+	t.Helper()
+	m, err := mailer.NewSMTP(mailer.SMTPOptions{Host: server.Host, Port: server.Port, From: "digest@example.com"})
+	require.NoError(t, err)
+	job := NewJob(db, m)
+	job.now = func() time.Time { return now }
+	return job
+}
+
+func TestJob_SendDue(t *testing.T) {
+	server := mailtest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+	now := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+
+	t.Run("a due digest is mailed and recorded up to its newest event", func(t *testing.T) {
+		server.Reset()
+		db := &jobStore{
+			due: []models.ActivityDigestDispatch{dispatchFor("ana", "ana@example.com", 3)},
+			events: map[string][]models.ActivityEvent{"ana": {
+				event(2, "g1", activity.KindTitleAdded, "Too Old", nil),
+				event(4, "g1", activity.KindTitleAdded, "Dune", nil),
+				event(5, "g1", activity.KindRatingUpdated, "Dune", map[string]any{"note": 8.0, "previousNote": 7.5}),
+				event(6, "g2", activity.KindTitleWatchedChanged, "Severance", map[string]any{
+					"watched": true, "previousWatched": false, "season": 2.0, "watchedAt": "2026-10-17T21:00:00Z",
+				}),
+				event(7, "g2", activity.KindMemberLeft, "", nil),
+			}},
+			completed: map[string]models.ActivityDigest{},
+		}
+
+		sent, err := newJob(t, db, server, now).SendDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+
+		messages := server.Messages()
+		require.Len(t, messages, 1)
+		msg := messages[0]
+		assert.Equal(t, []string{"ana@example.com"}, msg.To)
+		assert.Equal(t, "Your daily digest: 3 updates in 2 groups", msg.Subject)
+		for _, body := range []string{msg.Text, msg.HTML} {
+			assert.Contains(t, body, "Group G1")
+			assert.Contains(t, body, "bob added Dune")
+			assert.Contains(t, body, "bob changed their rating of Dune: 7.5 → 8.0")
+			assert.Contains(t, body, "bob watched Severance (season 2) on 17 Oct 2026")
+			assert.NotContains(t, body, "Too Old", "an event at or below the digested seq was already sent")
+		}
+
+		done := db.completed["ana"]
+		assert.Equal(t, int64(6), done.DigestedSeq, "the member_left event is not a digest kind")
+		require.NotNil(t, done.LastSentAt)
+		assert.Equal(t, now, *done.LastSentAt)
+		assert.Equal(t, now.Add(24*time.Hour), done.NextDueAt)
+	})
+
+	t.Run("nothing unread sends nothing and schedules the next one", func(t *testing.T) {
+		server.Reset()
+		db := &jobStore{
+			due:       []models.ActivityDigestDispatch{dispatchFor("ana", "ana@example.com", 9)},
+			completed: map[string]models.ActivityDigest{},
+		}
+
+		sent, err := newJob(t, db, server, now).SendDue(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+		assert.Empty(t, server.Messages())
+
+		done := db.completed["ana"]
+		assert.Equal(t, int64(9), done.DigestedSeq)
+		assert.Nil(t, done.LastSentAt)
+		assert.Equal(t, now.Add(24*time.Hour), done.NextDueAt)
+	})
+
+	t.Run("a user with no address gets nothing, and the events count as digested", func(t *testing.T) {
+		server.Reset()
+		db := &jobStore{
+			due:       []models.ActivityDigestDispatch{dispatchFor("ana", "", 0)},
+			events:    map[string][]models.ActivityEvent{"ana": {event(1, "g1", activity.KindCommentAdded, "Dune", nil)}},
+			completed: map[string]models.ActivityDigest{},
+		}
+
+		sent, err := newJob(t, db, server, now).SendDue(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+		assert.Empty(t, server.Messages())
+		assert.Equal(t, int64(1), db.completed["ana"].DigestedSeq)
+	})
+
+	t.Run("a refused digest keeps its events for the retry", func(t *testing.T) {
+		server.Reset()
+		server.Reject("try again later")
+		defer server.Reject("")
+		db := &jobStore{
+			due:       []models.ActivityDigestDispatch{dispatchFor("ana", "ana@example.com", 0)},
+			events:    map[string][]models.ActivityEvent{"ana": {event(1, "g1", activity.KindCommentAdded, "Dune", nil)}},
+			completed: map[string]models.ActivityDigest{},
+		}
+
+		sent, err := newJob(t, db, server, now).SendDue(ctx)
+		require.NoError(t, err, "one failed digest does not fail the run")
+		assert.Zero(t, sent)
+
+		done := db.completed["ana"]
+		assert.Equal(t, int64(0), done.DigestedSeq, "nothing was sent, so nothing is digested")
+		assert.Nil(t, done.LastSentAt)
+		assert.Equal(t, now.Add(time.Hour), done.NextDueAt)
+	})
+
+	t.Run("every batch is worked through", func(t *testing.T) {
+		server.Reset()
+		db := &jobStore{events: map[string][]models.ActivityEvent{}, completed: map[string]models.ActivityDigest{}}
+		for i := range jobBatchSize + 3 {
+			userId := "user" + string(rune('a'+i))
+			db.due = append(db.due, dispatchFor(userId, userId+"@example.com", 0))
+			db.events[userId] = []models.ActivityEvent{event(1, "g1", activity.KindTitleAdded, "Dune", nil)}
+		}
+
+		sent, err := newJob(t, db, server, now).SendDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, jobBatchSize+3, sent)
+		assert.Len(t, server.Messages(), jobBatchSize+3)
+	})
+}
+
+func TestRender(t *testing.T) {
+	t.Run("names are escaped in the HTML and left alone in the text", func(t *testing.T) {
+		e := event(1, "g1", activity.KindCommentAdded, "<script>x</script>", nil)
+		e.GroupName = "Tom & Jerry"
+
+		_, text, html, err := render("Ana", models.DigestWeekly, []models.ActivityEvent{e})
+		require.NoError(t, err)
+		assert.Contains(t, text, "Tom & Jerry")
+		assert.Contains(t, text, "bob commented on <script>x</script>")
+		assert.Contains(t, html, "Tom &amp; Jerry")
+		assert.NotContains(t, html, "<script>")
+	})
+
+	t.Run("a long section is cut short and counted", func(t *testing.T) {
+		var events []models.ActivityEvent
+		for i := range sectionLimit + 4 {
+			events = append(events, event(int64(i+1), "g1", activity.KindTitleAdded, "Title", nil))
+		}
+
+		subject, text, _, err := render("Ana", models.DigestDaily, events)
+		require.NoError(t, err)
+		assert.Equal(t, "Your daily digest: 14 updates in 1 group", subject)
+		assert.Equal(t, sectionLimit, strings.Count(text, "bob added Title"))
+		assert.Contains(t, text, "and 4 more")
+	})
+
+	t.Run("each kind reads as the feed says it", func(t *testing.T) {
+		cases := map[string]models.ActivityEvent{
+			"bob added Dune, Arrival and 3 more": event(1, "g1", activity.KindTitlesAdded, "", map[string]any{
+				"count": 5.0, "titles": []any{map[string]any{"id": "tt1", "name": "Dune"}, map[string]any{"id": "tt2", "name": "Arrival"}},
+			}),
+			"bob rated Dune: 8.5":                         event(2, "g1", activity.KindRatingAdded, "Dune", map[string]any{"note": 8.5}),
+			"bob edited their comment on Dune":            event(3, "g1", activity.KindCommentUpdated, "Dune", nil),
+			"bob marked Dune as not watched":              event(4, "g1", activity.KindTitleWatchedChanged, "Dune", map[string]any{"watched": false, "previousWatched": true}),
+			"bob changed when they watched Dune":          event(5, "g1", activity.KindTitleWatchedChanged, "Dune", map[string]any{"watched": true, "previousWatched": true}),
+			"bob commented on Severance (season 1)":       event(6, "g1", activity.KindCommentAdded, "Severance", map[string]any{"season": 1}),
+			"bob changed their rating of Dune: 7.0 → 7.5": event(7, "g1", activity.KindRatingUpdated, "Dune", map[string]any{"note": 7.5, "previousNote": 7}),
+		}
+		for want, e := range cases {
+			assert.Equal(t, want, describe(e))
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package digests
+
+import (
+	"bytes"
+	"cmp"
+	_ "embed"
+	"fmt"
+	htmltemplate "html/template"
+	"slices"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/activity"
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+// The digest is sent as both: the HTML for clients that show it, the text for
+// the ones that do not and for anyone who prefers it. Both render the same
+// digest value, so they cannot say different things. html/template escapes
+// every group, user and title name that goes into the HTML one; the HTML
+// keeps its styles inline, since most mail clients drop a <style> block.
+//
+//go:embed digest.html.tmpl
+var digestHTMLTemplate string
+
+//go:embed digest.txt.tmpl
+var digestTextTemplate string
+
+var (
+	digestHTML = htmltemplate.Must(htmltemplate.New("digest_html").Parse(digestHTMLTemplate))
+	digestText = texttemplate.Must(texttemplate.New("digest_text").Parse(digestTextTemplate))
+)
+
+// sectionLimit is how many lines one section of one group lists; the rest are
+// counted. A digest is a nudge to open the app, not a copy of the feed.
+const sectionLimit = 10
+
+// digest is what the templates render.
+type digest struct {
+	Name      string
+	Frequency string
+	Updates   int
+	Groups    []groupDigest
+}
+
+type groupDigest struct {
+	Name     string
+	Sections []section
+}
+
+type section struct {
+	Heading string
+	Lines   []string
+	More    int
+}
+
+// The sections of a group, in the order they are shown.
+var sections = []struct {
+	heading string
+	kinds   []string
+}{
+	{"New titles", []string{activity.KindTitleAdded, activity.KindTitlesAdded}},
+	{"Ratings", []string{activity.KindRatingAdded, activity.KindRatingUpdated}},
+	{"Comments", []string{activity.KindCommentAdded, activity.KindCommentUpdated}},
+	{"Watched", []string{activity.KindTitleWatchedChanged}},
+}
+
+// render is the subject and the two bodies of a digest of events for name.
+// Events are listed oldest first within each section, groups by name.
+func render(name, frequency string, events []models.ActivityEvent) (subject, text, html string, err error) {
+	d := summarize(name, frequency, events)
+
+	var textBuf, htmlBuf bytes.Buffer
+	if err := digestText.Execute(&textBuf, d); err != nil {
+		return "", "", "", err
+	}
+	if err := digestHTML.Execute(&htmlBuf, d); err != nil {
+		return "", "", "", err
+	}
+	return subjectOf(d), textBuf.String(), htmlBuf.String(), nil
+}
+
+func summarize(name, frequency string, events []models.ActivityEvent) digest {
+	type group struct {
+		id, name string
+		lines    map[string][]string
+	}
+	byId := map[string]*group{}
+	for _, event := range events {
+		g := byId[event.GroupId]
+		if g == nil {
+			g = &group{id: event.GroupId, name: event.GroupName, lines: map[string][]string{}}
+			byId[event.GroupId] = g
+		}
+		g.lines[event.Kind] = append(g.lines[event.Kind], describe(event))
+	}
+
+	groups := make([]*group, 0, len(byId))
+	for _, g := range byId {
+		groups = append(groups, g)
+	}
+	slices.SortFunc(groups, func(a, b *group) int {
+		return cmp.Or(cmp.Compare(a.name, b.name), cmp.Compare(a.id, b.id))
+	})
+
+	d := digest{Name: name, Frequency: frequency, Updates: len(events)}
+	for _, g := range groups {
+		gd := groupDigest{Name: g.name}
+		for _, s := range sections {
+			var lines []string
+			for _, kind := range s.kinds {
+				lines = append(lines, g.lines[kind]...)
+			}
+			if len(lines) == 0 {
+				continue
+			}
+			shown := min(len(lines), sectionLimit)
+			gd.Sections = append(gd.Sections, section{Heading: s.heading, Lines: lines[:shown], More: len(lines) - shown})
+		}
+		d.Groups = append(d.Groups, gd)
+	}
+	return d
+}
+
+func subjectOf(d digest) string {
+	return fmt.Sprintf("Your %s digest: %s in %s", d.Frequency,
+		plural(d.Updates, "update", "updates"), plural(len(d.Groups), "group", "groups"))
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return strconv.Itoa(n) + " " + many
+}
+
+// describe is one event as a line of the digest, in the words of the feed.
+// Payload values are read as they come back from the store, numbers as
+// float64 and times as RFC 3339 strings.
+func describe(event models.ActivityEvent) string {
+	actor := event.ActorName
+	subject := titleOf(event)
+	switch event.Kind {
+	case activity.KindTitleAdded:
+		return fmt.Sprintf("%s added %s", actor, subject)
+	case activity.KindTitlesAdded:
+		return fmt.Sprintf("%s added %s", actor, titlesAdded(event.Payload))
+	case activity.KindRatingAdded:
+		return fmt.Sprintf("%s rated %s: %s", actor, subject, note(event.Payload["note"]))
+	case activity.KindRatingUpdated:
+		return fmt.Sprintf("%s changed their rating of %s: %s → %s", actor, subject,
+			note(event.Payload["previousNote"]), note(event.Payload["note"]))
+	case activity.KindCommentAdded:
+		return fmt.Sprintf("%s commented on %s", actor, subject)
+	case activity.KindCommentUpdated:
+		return fmt.Sprintf("%s edited their comment on %s", actor, subject)
+	case activity.KindTitleWatchedChanged:
+		watched, _ := event.Payload["watched"].(bool)
+		previous, _ := event.Payload["previousWatched"].(bool)
+		switch {
+		case !watched:
+			return fmt.Sprintf("%s marked %s as not watched", actor, subject)
+		case previous:
+			return fmt.Sprintf("%s changed when they watched %s%s", actor, subject, on(event.Payload["watchedAt"]))
+		default:
+			return fmt.Sprintf("%s watched %s%s", actor, subject, on(event.Payload["watchedAt"]))
+		}
+	}
+	return fmt.Sprintf("%s updated %s", actor, subject)
+}
+
+// titleOf is the event's title, with its season when it is about one.
+func titleOf(event models.ActivityEvent) string {
+	title := "a title"
+	if event.TitleName != nil && *event.TitleName != "" {
+		title = *event.TitleName
+	}
+	if season, ok := number(event.Payload["season"]); ok {
+		return fmt.Sprintf("%s (season %s)", title, strconv.FormatFloat(season, 'f', -1, 64))
+	}
+	return title
+}
+
+// titlesAdded names the titles a bulk add listed, and counts the rest.
+func titlesAdded(payload map[string]any) string {
+	var names []string
+	if listed, ok := payload["titles"].([]any); ok {
+		for _, entry := range listed {
+			if title, ok := entry.(map[string]any); ok {
+				if name, ok := title["name"].(string); ok && name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	count, _ := number(payload["count"])
+	more := int(count) - len(names)
+	switch {
+	case len(names) == 0:
+		return plural(int(count), "title", "titles")
+	case more > 0:
+		return fmt.Sprintf("%s and %d more", strings.Join(names, ", "), more)
+	case len(names) == 1:
+		return names[0]
+	default:
+		return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+	}
+}
+
+func note(value any) string {
+	n, ok := number(value)
+	if !ok {
+		return "?"
+	}
+	return strconv.FormatFloat(n, 'f', 1, 64)
+}
+
+// on is " on 2 Jan 2026" for a watched date, or nothing without one.
+func on(value any) string {
+	raw, _ := value.(string)
+	at, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return ""
+	}
+	return " on " + at.Format("2 Jan 2006")
+}
+
+func number(value any) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
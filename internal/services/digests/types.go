@@ -0,0 +1,20 @@
+package digests
+
+import "time"
+
+// FrequencyOff is the frequency of a user who gets no digest; the others are
+// models.DigestDaily and models.DigestWeekly.
+const FrequencyOff = "off"
+
+// Settings are whether, and how often, a user is emailed a digest of their
+// unread activity. NextDueAt and LastSentAt are null when they do not apply:
+// both for a user who is off, LastSentAt until the first digest is sent.
+type Settings struct {
+	Frequency  string     `json:"frequency"`
+	NextDueAt  *time.Time `json:"nextDueAt"`
+	LastSentAt *time.Time `json:"lastSentAt"`
+}
+
+type SettingsRequest struct {
+	Frequency string `json:"frequency"`
+}
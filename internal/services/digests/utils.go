@@ -0,0 +1,19 @@
+package digests
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrInvalidFrequency = errors.New("frequency must be daily, weekly or off")
+	// ErrNoEmail refuses a subscription that could never be sent: the account
+	// has no address to send it to. It is a conflict with the account's state
+	// rather than a bad request, and setting an email resolves it.
+	ErrNoEmail = errors.New("add an email address to your account before subscribing to the digest")
+)
+
+var ErrorMap = map[error]int{
+	ErrInvalidFrequency: http.StatusBadRequest,
+	ErrNoEmail:          http.StatusConflict,
+}
@@ -0,0 +1,294 @@
+package exports
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// batchSize is how many titles' catalogue entries, ratings and comments are
+// read at once while exporting.
+const batchSize = 100
+
+// Export is a group export ready to be written. NewExport does the checks
+// that can still become an error status; Write streams the rest, so a large
+// group is never held in memory whole.
+type Export struct {
+	db        store.Store
+	format    string
+	userId    string
+	group     models.Group
+	members   []ExportedMember
+	usernames map[string]string
+	now       time.Time
+}
+
+// NewExport prepares groupId's export in format for userId.
+//
+// Possible errors returned:
+//   - ErrUnknownExportFormat: if format is not one of the Format constants.
+//   - ErrGroupNotFound: if the group does not exist or userId is not a member.
+//   - Any error returned by the store reads.
+func NewExport(db store.Store, ctx context.Context, groupId, userId, format string) (*Export, error) {
+	switch format {
+	case FormatCSV, FormatJSON, FormatLetterboxd:
+	default:
+		return nil, ErrUnknownExportFormat
+	}
+
+	group, err := db.GetGroupById(ctx, groupId, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, err
+	}
+	users, err := db.GetUsersFromGroup(ctx, groupId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Export{
+		db:        db,
+		format:    format,
+		userId:    userId,
+		group:     group,
+		members:   []ExportedMember{},
+		usernames: map[string]string{},
+		now:       time.Now().UTC(),
+	}
+	for _, u := range users {
+		e.members = append(e.members, ExportedMember{Id: u.Id, Username: u.Username})
+		e.usernames[u.Id] = u.Username
+	}
+	return e, nil
+}
+
+// ContentType is the media type of what Write writes.
+func (e *Export) ContentType() string {
+	if e.format == FormatJSON {
+		return "application/json"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Filename names the download after the group and the day.
+func (e *Export) Filename() string {
+	ext := "csv"
+	if e.format == FormatJSON {
+		ext = "json"
+	}
+	suffix := ""
+	if e.format == FormatLetterboxd {
+		suffix = "-letterboxd"
+	}
+	return fmt.Sprintf("%s%s-%s.%s", slug(e.group.Name), suffix, e.now.Format(time.DateOnly), ext)
+}
+
+// Write streams the export to w, batchSize titles at a time. By the time it
+// can fail the response has begun, so an error leaves the file cut short.
+func (e *Export) Write(ctx context.Context, w io.Writer) error {
+	var enc encoder
+	switch e.format {
+	case FormatJSON:
+		enc = &jsonEncoder{w: w}
+	case FormatCSV:
+		enc = newCSVEncoder(w)
+	case FormatLetterboxd:
+		enc = newLetterboxdEncoder(w, e.userId)
+	}
+
+	header := GroupExport{
+		Version:    DocumentVersion,
+		ExportedAt: e.now,
+		Group: ExportedGroup{
+			Id:          e.group.Id,
+			Name:        e.group.Name,
+			Description: e.group.Description,
+			CreatedAt:   e.group.CreatedAt,
+		},
+		Members: e.members,
+	}
+	if err := enc.begin(header); err != nil {
+		return err
+	}
+
+	items := make([]models.GroupTitleItem, 0, len(e.group.Titles))
+	for _, item := range e.group.Titles {
+		items = append(items, item)
+	}
+	slices.SortFunc(items, func(a, b models.GroupTitleItem) int {
+		return cmp.Or(a.AddedAt.Compare(b.AddedAt), cmp.Compare(a.TitleId, b.TitleId))
+	})
+
+	for start := 0; start < len(items); start += batchSize {
+		batch := items[start:min(start+batchSize, len(items))]
+		titles, err := e.exportBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, t := range titles {
+			if err := enc.title(t); err != nil {
+				return err
+			}
+		}
+	}
+	return enc.end()
+}
+
+// exportBatch reads what the export holds for batch, in three queries.
+func (e *Export) exportBatch(ctx context.Context, batch []models.GroupTitleItem) ([]ExportedTitle, error) {
+	ids := make([]string, len(batch))
+	for i, item := range batch {
+		ids[i] = item.TitleId
+	}
+
+	catalogue, err := e.db.GetTitlesByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	titleById := make(map[string]models.Title, len(catalogue))
+	for _, t := range catalogue {
+		titleById[t.ID] = t
+	}
+	ratingsDb, err := e.db.GetRatingsByTitleIds(ctx, ids, e.group.Id)
+	if err != nil {
+		return nil, err
+	}
+	commentsDb, err := e.db.GetCommentsByTitleIds(ctx, ids, e.group.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	exported := make([]ExportedTitle, len(batch))
+	index := make(map[string]int, len(batch))
+	for i, item := range batch {
+		index[item.TitleId] = i
+		t := titleById[item.TitleId]
+		exported[i] = ExportedTitle{
+			TitleId:   item.TitleId,
+			Name:      t.PrimaryTitle,
+			Type:      t.Type,
+			Year:      t.StartYear,
+			AddedAt:   item.AddedAt,
+			Watched:   item.Watched,
+			WatchedAt: item.WatchedAt,
+			Seasons:   exportSeasonsWatched(item.SeasonsWatched),
+			Ratings:   []ExportedRating{},
+			Comments:  []ExportedComment{},
+		}
+	}
+
+	for _, r := range ratingsDb {
+		username, err := e.username(ctx, r.UserId)
+		if err != nil {
+			return nil, err
+		}
+		rating := ExportedRating{
+			UserId:    r.UserId,
+			Username:  username,
+			Note:      r.Note,
+			CreatedAt: r.CreatedAt,
+			UpdatedAt: r.UpdatedAt,
+		}
+		if r.SeasonsRatings != nil {
+			for season, item := range *r.SeasonsRatings {
+				rating.Seasons = append(rating.Seasons, ExportedSeasonNote{Season: atoi(season), Note: item.Rating})
+			}
+			slices.SortFunc(rating.Seasons, func(a, b ExportedSeasonNote) int { return cmp.Compare(a.Season, b.Season) })
+		}
+		t := &exported[index[r.TitleId]]
+		t.Ratings = append(t.Ratings, rating)
+	}
+
+	for _, c := range commentsDb {
+		username, err := e.username(ctx, c.UserId)
+		if err != nil {
+			return nil, err
+		}
+		comment := ExportedComment{
+			UserId:    c.UserId,
+			Username:  username,
+			Comment:   c.Comment,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+		}
+		if c.SeasonsComments != nil {
+			for season, item := range *c.SeasonsComments {
+				comment.Seasons = append(comment.Seasons, ExportedSeasonComment{Season: atoi(season), Comment: item.Comment})
+			}
+			slices.SortFunc(comment.Seasons, func(a, b ExportedSeasonComment) int { return cmp.Compare(a.Season, b.Season) })
+		}
+		t := &exported[index[c.TitleId]]
+		t.Comments = append(t.Comments, comment)
+	}
+
+	for i := range exported {
+		slices.SortFunc(exported[i].Ratings, func(a, b ExportedRating) int { return cmp.Compare(a.Username, b.Username) })
+		slices.SortFunc(exported[i].Comments, func(a, b ExportedComment) int { return cmp.Compare(a.Username, b.Username) })
+	}
+	return exported, nil
+}
+
+// username returns the username of userId. Members are known from the start;
+// a rating or comment left by someone who has since left the group is looked
+// up once, and one whose account is gone has no username.
+func (e *Export) username(ctx context.Context, userId string) (string, error) {
+	if name, ok := e.usernames[userId]; ok {
+		return name, nil
+	}
+	user, err := e.db.GetUserById(ctx, userId)
+	if err != nil && !errors.Is(err, store.ErrRecordNotFound) {
+		return "", err
+	}
+	e.usernames[userId] = user.Username
+	return user.Username, nil
+}
+
+func exportSeasonsWatched(seasons *models.SeasonsWatched) []ExportedSeasonWatched {
+	if seasons == nil {
+		return nil
+	}
+	out := make([]ExportedSeasonWatched, 0, len(*seasons))
+	for season, item := range *seasons {
+		out = append(out, ExportedSeasonWatched{Season: atoi(season), Watched: item.Watched, WatchedAt: item.WatchedAt})
+	}
+	slices.SortFunc(out, func(a, b ExportedSeasonWatched) int { return cmp.Compare(a.Season, b.Season) })
+	return out
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// slug lowercases name and keeps its letters and digits, dash-separated, for a
+// filename that needs no quoting.
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		} else {
+			dash = true
+		}
+	}
+	if b.Len() == 0 {
+		return "group"
+	}
+	return b.String()
+}
@@ -0,0 +1,207 @@
+package exports
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// exportStore satisfies store.Store by embedding the interface, so only the
+// reads an export makes need a body. It holds one group with a movie and a
+// series, rated and commented on by two members and a former member.
+type exportStore struct {
+	store.Store
+	batches int
+}
+
+var (
+	day1 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2 = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func (s *exportStore) GetGroupById(_ context.Context, groupId, userId string) (models.Group, error) {
+	if groupId != "g1" || userId == "outsider" {
+		return models.Group{}, store.ErrRecordNotFound
+	}
+	seasons := models.SeasonsWatched{
+		"2": {Watched: false},
+		"1": {Watched: true, WatchedAt: &day2},
+	}
+	return models.Group{
+		Id:   "g1",
+		Name: "Friday Movies!",
+		Titles: models.GroupTitles{
+			"tt1": {TitleId: "tt1", Watched: true, WatchedAt: &day1, AddedAt: day1},
+			"tt2": {TitleId: "tt2", SeasonsWatched: &seasons, AddedAt: day2},
+			"tt3": {TitleId: "tt3", AddedAt: day2},
+		},
+	}, nil
+}
+
+func (s *exportStore) GetUsersFromGroup(context.Context, string, string) ([]models.User, error) {
+	return []models.User{{Id: "u1", Username: "ana"}, {Id: "u2", Username: "bia"}}, nil
+}
+
+func (s *exportStore) GetUserById(_ context.Context, id string) (models.User, error) {
+	if id == "u3" {
+		return models.User{Id: "u3", Username: "cris"}, nil
+	}
+	return models.User{}, store.ErrRecordNotFound
+}
+
+func (s *exportStore) GetTitlesByIds(context.Context, []string) ([]models.Title, error) {
+	s.batches++
+	return []models.Title{
+		{ID: "tt1", PrimaryTitle: "Movie", Type: "movie", StartYear: 1999},
+		{ID: "tt2", PrimaryTitle: "Series", Type: "tvSeries", StartYear: 2008},
+		{ID: "tt3", PrimaryTitle: "Unseen", Type: "movie", StartYear: 2010},
+	}, nil
+}
+
+func (s *exportStore) GetRatingsByTitleIds(context.Context, []string, string) ([]models.UserRating, error) {
+	seasons := models.SeasonsRatings{"1": {Rating: 8}, "2": {Rating: 9}}
+	return []models.UserRating{
+		{TitleId: "tt1", UserId: "u2", Note: 7.4},
+		{TitleId: "tt1", UserId: "u1", Note: 9},
+		{TitleId: "tt1", UserId: "u3", Note: 5},
+		{TitleId: "tt2", UserId: "u1", Note: 8.5, SeasonsRatings: &seasons},
+		{TitleId: "tt3", UserId: "gone", Note: 3},
+	}, nil
+}
+
+func (s *exportStore) GetCommentsByTitleIds(context.Context, []string, string) ([]models.Comment, error) {
+	text := "Classic"
+	seasons := models.SeasonsComments{"1": {Comment: "Slow start"}}
+	return []models.Comment{
+		{TitleId: "tt1", UserId: "u1", Comment: &text},
+		{TitleId: "tt2", UserId: "u2", SeasonsComments: &seasons},
+	}, nil
+}
+
+func writeExport(t *testing.T, format, userId string) (*Export, []byte) {
+	t.Helper()
+	e, err := NewExport(&exportStore{}, context.Background(), "g1", userId, format)
+	if err != nil {
+		t.Fatalf("NewExport: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := e.Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return e, buf.Bytes()
+}
+
+func TestExportJSON(t *testing.T) {
+	e, data := writeExport(t, FormatJSON, "u1")
+	if e.ContentType() != "application/json" {
+		t.Errorf("content type = %q", e.ContentType())
+	}
+
+	var doc GroupExport
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("the export is not valid JSON: %v\n%s", err, data)
+	}
+	if doc.Version != DocumentVersion || doc.Group.Name != "Friday Movies!" || len(doc.Members) != 2 {
+		t.Errorf("header = %+v", doc)
+	}
+	if len(doc.Titles) != 3 || doc.Titles[0].TitleId != "tt1" || doc.Titles[1].TitleId != "tt2" {
+		t.Fatalf("titles out of added order: %+v", doc.Titles)
+	}
+
+	movie := doc.Titles[0]
+	if !movie.Watched || movie.WatchedAt == nil || !movie.WatchedAt.Equal(day1) || movie.Year != 1999 {
+		t.Errorf("movie = %+v", movie)
+	}
+	if len(movie.Ratings) != 3 || movie.Ratings[0].Username != "ana" || movie.Ratings[2].Username != "cris" {
+		t.Errorf("movie ratings = %+v, want ana, bia and the former member cris", movie.Ratings)
+	}
+	if len(movie.Comments) != 1 || *movie.Comments[0].Comment != "Classic" {
+		t.Errorf("movie comments = %+v", movie.Comments)
+	}
+
+	series := doc.Titles[1]
+	if len(series.Seasons) != 2 || series.Seasons[0].Season != 1 || !series.Seasons[0].Watched {
+		t.Errorf("series seasons = %+v, want 1 (watched) then 2", series.Seasons)
+	}
+	if len(series.Ratings) != 1 || len(series.Ratings[0].Seasons) != 2 || series.Ratings[0].Seasons[1].Note != 9 {
+		t.Errorf("series ratings = %+v", series.Ratings)
+	}
+	if len(series.Comments) != 1 || series.Comments[0].Seasons[0].Comment != "Slow start" {
+		t.Errorf("series comments = %+v", series.Comments)
+	}
+	if got := doc.Titles[2].Ratings[0]; got.UserId != "gone" || got.Username != "" {
+		t.Errorf("a deleted author's rating = %+v, want it kept without a username", got)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	e, data := writeExport(t, FormatCSV, "u1")
+	if e.Filename() != "friday-movies-"+e.now.Format(time.DateOnly)+".csv" {
+		t.Errorf("filename = %q", e.Filename())
+	}
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("the export is not valid CSV: %v", err)
+	}
+
+	want := [][]string{
+		csvHeader,
+		{"tt1", "Movie", "movie", "1999", "", "2024-01-01T00:00:00Z", "true", "2024-01-01", "ana", "9", "Classic"},
+		{"tt1", "Movie", "movie", "1999", "", "2024-01-01T00:00:00Z", "true", "2024-01-01", "bia", "7.4", ""},
+		{"tt1", "Movie", "movie", "1999", "", "2024-01-01T00:00:00Z", "true", "2024-01-01", "cris", "5", ""},
+		{"tt2", "Series", "tvSeries", "2008", "", "2024-02-01T00:00:00Z", "false", "", "ana", "8.5", ""},
+		{"tt2", "Series", "tvSeries", "2008", "1", "2024-02-01T00:00:00Z", "true", "2024-02-01", "ana", "8", ""},
+		{"tt2", "Series", "tvSeries", "2008", "1", "2024-02-01T00:00:00Z", "true", "2024-02-01", "bia", "", "Slow start"},
+		{"tt2", "Series", "tvSeries", "2008", "2", "2024-02-01T00:00:00Z", "false", "", "ana", "9", ""},
+		{"tt3", "Unseen", "movie", "2010", "", "2024-02-01T00:00:00Z", "false", "", "", "3", ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d:\n%s", len(records), len(want), data)
+	}
+	for i := range want {
+		if !slices.Equal(records[i], want[i]) {
+			t.Errorf("record %d = %q, want %q", i, records[i], want[i])
+		}
+	}
+}
+
+func TestExportLetterboxd(t *testing.T) {
+	_, data := writeExport(t, FormatLetterboxd, "u2")
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("the export is not valid CSV: %v", err)
+	}
+	want := [][]string{
+		{"imdbID", "Title", "Year", "Rating", "WatchedDate", "Review"},
+		{"tt1", "Movie", "1999", "3.5", "2024-01-01", ""},
+	}
+	if len(records) != len(want) || !slices.Equal(records[0], want[0]) || !slices.Equal(records[1], want[1]) {
+		t.Errorf("records = %q, want %q: the series and the unrated, unwatched movie are left out", records, want)
+	}
+}
+
+func TestLetterboxdRating(t *testing.T) {
+	for note, want := range map[float64]string{0: "", 0.2: "0.5", 1: "0.5", 7: "3.5", 7.4: "3.5", 7.5: "4", 10: "5"} {
+		if got := letterboxdRating(note); got != want {
+			t.Errorf("letterboxdRating(%v) = %q, want %q", note, got, want)
+		}
+	}
+}
+
+func TestNewExportRejects(t *testing.T) {
+	ctx := context.Background()
+	if _, err := NewExport(&exportStore{}, ctx, "g1", "u1", "xml"); !errors.Is(err, ErrUnknownExportFormat) {
+		t.Errorf("unknown format: err = %v", err)
+	}
+	if _, err := NewExport(&exportStore{}, ctx, "g1", "outsider", FormatJSON); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("not a member: err = %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+package exports
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// encoder writes one format: begin once, title for each title in order, end
+// once.
+type encoder interface {
+	begin(header GroupExport) error
+	title(t ExportedTitle) error
+	end() error
+}
+
+// jsonEncoder writes a GroupExport one title at a time, which encoding/json
+// cannot do with a whole document, so it writes the fields around Titles by
+// hand, in the struct's order.
+type jsonEncoder struct {
+	w      io.Writer
+	titles int
+}
+
+func (e *jsonEncoder) begin(h GroupExport) error {
+	exportedAt, err := json.Marshal(h.ExportedAt)
+	if err != nil {
+		return err
+	}
+	group, err := json.Marshal(h.Group)
+	if err != nil {
+		return err
+	}
+	members, err := json.Marshal(h.Members)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(e.w, `{"version":%d,"exportedAt":%s,"group":%s,"members":%s,"titles":[`, h.Version, exportedAt, group, members)
+	return err
+}
+
+func (e *jsonEncoder) title(t ExportedTitle) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if e.titles > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.titles++
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonEncoder) end() error {
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+// csvEncoder writes a flat table for spreadsheets: a row per title, and per
+// season of a series, for each member who rated or commented on it there (one
+// row with no username when nobody did). Each row repeats the title's, or the
+// season's, watched state.
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+var csvHeader = []string{"imdb_id", "title", "type", "year", "season", "added_at", "watched", "watched_at", "username", "rating", "comment"}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) begin(GroupExport) error {
+	return e.w.Write(csvHeader)
+}
+
+func (e *csvEncoder) title(t ExportedTitle) error {
+	type entry struct{ rating, comment string }
+	// scope "" is the title itself; the others are its seasons.
+	watched := map[string][2]string{"": {strconv.FormatBool(t.Watched), formatDate(t.WatchedAt)}}
+	byScope := map[string]map[string]*entry{"": {}}
+	at := func(scope, username string) *entry {
+		if byScope[scope] == nil {
+			byScope[scope] = map[string]*entry{}
+		}
+		if byScope[scope][username] == nil {
+			byScope[scope][username] = &entry{}
+		}
+		return byScope[scope][username]
+	}
+
+	for _, s := range t.Seasons {
+		watched[strconv.Itoa(s.Season)] = [2]string{strconv.FormatBool(s.Watched), formatDate(s.WatchedAt)}
+	}
+	for _, r := range t.Ratings {
+		at("", r.Username).rating = formatNote(r.Note)
+		for _, s := range r.Seasons {
+			at(strconv.Itoa(s.Season), r.Username).rating = formatNote(s.Note)
+		}
+	}
+	for _, c := range t.Comments {
+		if c.Comment != nil {
+			at("", c.Username).comment = *c.Comment
+		}
+		for _, s := range c.Seasons {
+			at(strconv.Itoa(s.Season), c.Username).comment = s.Comment
+		}
+	}
+
+	scopes := []string{""}
+	for scope := range byScope {
+		if scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	for scope := range watched {
+		if _, ok := byScope[scope]; !ok {
+			scopes = append(scopes, scope)
+		}
+	}
+	slices.SortFunc(scopes[1:], func(a, b string) int { return cmp.Compare(atoi(a), atoi(b)) })
+
+	year := ""
+	if t.Year != 0 {
+		year = strconv.Itoa(t.Year)
+	}
+	for _, scope := range scopes {
+		state, ok := watched[scope]
+		if !ok {
+			state = [2]string{"false", ""}
+		}
+		prefix := []string{t.TitleId, t.Name, t.Type, year, scope, t.AddedAt.UTC().Format(time.RFC3339), state[0], state[1]}
+
+		usernames := make([]string, 0, len(byScope[scope]))
+		for username := range byScope[scope] {
+			usernames = append(usernames, username)
+		}
+		slices.Sort(usernames)
+		if len(usernames) == 0 {
+			if err := e.w.Write(append(prefix, "", "", "")); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, username := range usernames {
+			en := byScope[scope][username]
+			if err := e.w.Write(append(slices.Clip(prefix), username, en.rating, en.comment)); err != nil {
+				return err
+			}
+		}
+	}
+	return e.w.Error()
+}
+
+func (e *csvEncoder) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// letterboxdEncoder writes userId's side of the group in the CSV Letterboxd
+// imports (letterboxd.com/import): the movies the group watched or userId
+// rated, with userId's rating and comment. Letterboxd has no series, so they
+// are left out. Ratings are halved onto its five stars, to the nearest half
+// star.
+type letterboxdEncoder struct {
+	w      *csv.Writer
+	userId string
+}
+
+func newLetterboxdEncoder(w io.Writer, userId string) *letterboxdEncoder {
+	return &letterboxdEncoder{w: csv.NewWriter(w), userId: userId}
+}
+
+func (e *letterboxdEncoder) begin(GroupExport) error {
+	return e.w.Write([]string{"imdbID", "Title", "Year", "Rating", "WatchedDate", "Review"})
+}
+
+func (e *letterboxdEncoder) title(t ExportedTitle) error {
+	if t.Type == "tvSeries" || t.Type == "tvMiniSeries" || t.Type == "tvEpisode" {
+		return nil
+	}
+	rating, review := "", ""
+	for _, r := range t.Ratings {
+		if r.UserId == e.userId {
+			rating = letterboxdRating(r.Note)
+		}
+	}
+	for _, c := range t.Comments {
+		if c.UserId == e.userId && c.Comment != nil {
+			review = *c.Comment
+		}
+	}
+	if !t.Watched && rating == "" {
+		return nil
+	}
+
+	year := ""
+	if t.Year != 0 {
+		year = strconv.Itoa(t.Year)
+	}
+	if err := e.w.Write([]string{t.TitleId, t.Name, year, rating, formatDate(t.WatchedAt), review}); err != nil {
+		return err
+	}
+	return e.w.Error()
+}
+
+func (e *letterboxdEncoder) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// letterboxdRating halves a 0–10 note onto half stars: 7 is 3.5, 7.4 rounds
+// to 3.5 as well, and any note above 0 is at least half a star. A 0 is left
+// unrated, as Letterboxd has no zero.
+func letterboxdRating(note float64) string {
+	if note <= 0 {
+		return ""
+	}
+	stars := max(math.Round(note), 1) / 2
+	return strconv.FormatFloat(stars, 'f', -1, 64)
+}
+
+func formatNote(note float64) string {
+	return strconv.FormatFloat(note, 'f', -1, 64)
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,95 @@
+package exports
+
+import "time"
+
+// The formats a group exports to.
+const (
+	FormatCSV        = "csv"
+	FormatJSON       = "json"
+	FormatLetterboxd = "letterboxd"
+)
+
+// DocumentVersion is the version of the JSON document GroupExport describes.
+// It goes up when a change would make an older import misread a newer export.
+const DocumentVersion = 1
+
+// GroupExport is the JSON export: the whole group, title by title, in the
+// order they were added. It is what POST /groups/{id}/imports reads back, so
+// a group can move between instances; see imports.StartImport.
+//
+// Ratings and comments name their author by username as well as id, since
+// ids mean nothing on another instance. Username is empty for an author whose
+// account has since been deleted.
+type GroupExport struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exportedAt"`
+	Group      ExportedGroup    `json:"group"`
+	Members    []ExportedMember `json:"members"`
+	Titles     []ExportedTitle  `json:"titles"`
+}
+
+type ExportedGroup struct {
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type ExportedMember struct {
+	Id       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// ExportedTitle is one of the group's titles. Watched and WatchedAt are the
+// group's; for a series they are derived from Seasons, which hold the
+// per-season state.
+type ExportedTitle struct {
+	TitleId   string                  `json:"titleId"`
+	Name      string                  `json:"name"`
+	Type      string                  `json:"type"`
+	Year      int                     `json:"year,omitempty"`
+	AddedAt   time.Time               `json:"addedAt"`
+	Watched   bool                    `json:"watched"`
+	WatchedAt *time.Time              `json:"watchedAt,omitempty"`
+	Seasons   []ExportedSeasonWatched `json:"seasons,omitempty"`
+	Ratings   []ExportedRating        `json:"ratings"`
+	Comments  []ExportedComment       `json:"comments"`
+}
+
+type ExportedSeasonWatched struct {
+	Season    int        `json:"season"`
+	Watched   bool       `json:"watched"`
+	WatchedAt *time.Time `json:"watchedAt,omitempty"`
+}
+
+// ExportedRating is one member's rating. A series' Note is the mean of its
+// Seasons, as the app computes it.
+type ExportedRating struct {
+	UserId    string               `json:"userId"`
+	Username  string               `json:"username"`
+	Note      float64              `json:"note"`
+	Seasons   []ExportedSeasonNote `json:"seasons,omitempty"`
+	CreatedAt time.Time            `json:"createdAt"`
+	UpdatedAt time.Time            `json:"updatedAt"`
+}
+
+type ExportedSeasonNote struct {
+	Season int     `json:"season"`
+	Note   float64 `json:"note"`
+}
+
+// ExportedComment is one member's comment: Comment on a movie, Seasons on a
+// series.
+type ExportedComment struct {
+	UserId    string                  `json:"userId"`
+	Username  string                  `json:"username"`
+	Comment   *string                 `json:"comment,omitempty"`
+	Seasons   []ExportedSeasonComment `json:"seasons,omitempty"`
+	CreatedAt time.Time               `json:"createdAt"`
+	UpdatedAt time.Time               `json:"updatedAt"`
+}
+
+type ExportedSeasonComment struct {
+	Season  int    `json:"season"`
+	Comment string `json:"comment"`
+}
@@ -0,0 +1,16 @@
+package exports
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrUnknownExportFormat = errors.New("format must be csv, json or letterboxd")
+	ErrGroupNotFound       = errors.New("group not found")
+)
+
+var ErrorMap = map[error]int{
+	ErrUnknownExportFormat: http.StatusBadRequest,
+	ErrGroupNotFound:       http.StatusNotFound,
+}
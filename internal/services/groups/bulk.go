@@ -0,0 +1,227 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/lealre/movies-backend/internal/config"
+	"github.com/lealre/movies-backend/internal/logx"
+	"github.com/lealre/movies-backend/internal/services/titles"
+	"github.com/lealre/movies-backend/internal/store"
+	"github.com/lealre/movies-backend/internal/titleprovider"
+	"github.com/lealre/movies-backend/internal/titleprovider/titleref"
+)
+
+// bulkInsertBatchSize is how many titles one transaction adds to the group.
+const bulkInsertBatchSize = 50
+
+// BulkAddTitlesToGroup adds every title urls names to groupId and reports, for
+// each reference in order, what became of it. One reference failing — a link
+// nothing matches, a provider error — fails that reference alone.
+//
+// Steps performed by this method:
+//  1. Resolves every reference to an IMDb ID (see titles.ResolveTitleReference).
+//  2. Sets aside titles the group already has, and repeats within the request.
+//  3. Fetches the titles the catalogue lacks from provider, as AddNewTitle does.
+//  4. Adds the rest to the group, bulkInsertBatchSize titles per transaction.
+//
+// Steps 1 and 3 call the provider, at most config.BulkAddConcurrency() calls at
+// a time, so that a list of two hundred stays within the provider's rate limit
+// instead of tripping it; the daily quota, where there is one, is spent as
+// interactive work, like a single add. A batch that fails to insert fails its
+// own references, as BulkNotAdded, and the batches after it are still tried:
+// the ones before it are committed, and the caller is told what they added
+// rather than handed a 500 that hides it.
+//
+// Possible errors returned:
+//   - ErrBulkTitlesRequired, ErrTooManyBulkTitles: if urls is empty, or longer
+//     than config.BulkAddMaxTitles().
+//   - ErrGroupNotFound: if the group does not exist or userId is not a member.
+//   - Any error returned by the store reads and writes.
+func BulkAddTitlesToGroup(
+	db store.Store,
+	provider titleprovider.Provider,
+	resolver *titleref.Resolver,
+	ctx context.Context,
+	groupId, userId string,
+	urls []string,
+) (BulkAddTitlesResponse, error) {
+	if len(urls) == 0 {
+		return BulkAddTitlesResponse{}, ErrBulkTitlesRequired
+	}
+	if len(urls) > config.BulkAddMaxTitles() {
+		return BulkAddTitlesResponse{}, ErrTooManyBulkTitles
+	}
+
+	group, err := db.GetGroupById(ctx, groupId, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return BulkAddTitlesResponse{}, ErrGroupNotFound
+		}
+		return BulkAddTitlesResponse{}, err
+	}
+
+	results := make([]BulkAddTitleResult, len(urls))
+	concurrency := config.BulkAddConcurrency()
+
+	forEachConcurrently(len(urls), concurrency, func(i int) {
+		results[i].URL = urls[i]
+		titleId, err := titles.ResolveTitleReference(resolver, ctx, urls[i])
+		if err != nil {
+			results[i].fail(ctx, err)
+			return
+		}
+		results[i].TitleId = titleId
+	})
+
+	// Each title is added once, for the first reference naming it; the
+	// indexes of every reference naming it share its outcome.
+	refs := map[string][]int{}
+	var titleIds []string
+	for i, r := range results {
+		if r.Status != "" {
+			continue
+		}
+		if _, ok := refs[r.TitleId]; !ok {
+			titleIds = append(titleIds, r.TitleId)
+		}
+		refs[r.TitleId] = append(refs[r.TitleId], i)
+	}
+	setStatus := func(titleId, status string) {
+		for n, i := range refs[titleId] {
+			results[i].Status = status
+			if n > 0 && status == BulkAdded {
+				results[i].Status = BulkAlreadyPresent
+			}
+		}
+	}
+	setName := func(titleId, name string) {
+		for _, i := range refs[titleId] {
+			results[i].TitleName = name
+		}
+	}
+
+	stored, err := db.GetTitlesByIds(ctx, titleIds)
+	if err != nil {
+		return BulkAddTitlesResponse{}, err
+	}
+	inCatalogue := make(map[string]bool, len(stored))
+	for _, t := range stored {
+		inCatalogue[t.ID] = true
+		setName(t.ID, t.PrimaryTitle)
+	}
+
+	var missing, toAdd []string
+	for _, titleId := range titleIds {
+		if _, ok := group.Titles[titleId]; ok {
+			setStatus(titleId, BulkAlreadyPresent)
+		} else if inCatalogue[titleId] {
+			toAdd = append(toAdd, titleId)
+		} else {
+			missing = append(missing, titleId)
+		}
+	}
+
+	fetched := make([]error, len(missing))
+	forEachConcurrently(len(missing), concurrency, func(n int) {
+		title, err := titles.AddNewTitle(db, provider, ctx, missing[n])
+		if err != nil {
+			fetched[n] = err
+			return
+		}
+		setName(missing[n], title.PrimaryTitle)
+	})
+	for n, titleId := range missing {
+		if fetched[n] != nil {
+			for _, i := range refs[titleId] {
+				results[i].fail(ctx, fetched[n])
+			}
+			continue
+		}
+		toAdd = append(toAdd, titleId)
+	}
+
+	for start := 0; start < len(toAdd); start += bulkInsertBatchSize {
+		batch := toAdd[start:min(start+bulkInsertBatchSize, len(toAdd))]
+		added, err := db.AddGroupTitles(ctx, groupId, batch)
+		if err != nil {
+			logx.FromContext(ctx).Printf("ERROR: bulk add to group %s: %v", groupId, err)
+			for _, titleId := range batch {
+				for _, i := range refs[titleId] {
+					results[i].Status, results[i].Error = BulkNotAdded, "the title could not be added to the group; try again"
+				}
+			}
+			continue
+		}
+		addedSet := make(map[string]bool, len(added))
+		for _, titleId := range added {
+			addedSet[titleId] = true
+		}
+		for _, titleId := range batch {
+			if addedSet[titleId] {
+				setStatus(titleId, BulkAdded)
+			} else {
+				// Added by someone else since the group was read.
+				setStatus(titleId, BulkAlreadyPresent)
+			}
+		}
+	}
+
+	resp := BulkAddTitlesResponse{Results: results}
+	for _, r := range results {
+		switch r.Status {
+		case BulkAdded:
+			resp.Added++
+		case BulkAlreadyPresent:
+			resp.AlreadyPresent++
+		default:
+			resp.Failed++
+		}
+	}
+	return resp, nil
+}
+
+// fail records why r's reference could not be added. Provider errors other
+// than the quota running out are logged rather than shown, as a single add
+// does with its 500.
+func (r *BulkAddTitleResult) fail(ctx context.Context, err error) {
+	var refErr *titleref.Error
+	switch {
+	case errors.As(err, &refErr):
+		r.Error = refErr.Error()
+		switch {
+		case errors.Is(refErr, titleref.ErrAmbiguous):
+			r.Status = BulkAmbiguous
+			r.Candidates = titles.MapProviderSearchItemsToTitles(refErr.Candidates)
+		case errors.Is(refErr, titleprovider.ErrTitleNotFound):
+			r.Status = BulkNotFound
+		default:
+			r.Status = BulkInvalidReference
+		}
+	case errors.Is(err, titles.ErrTitleNotFound):
+		r.Status, r.Error = BulkNotFound, err.Error()
+	case errors.Is(err, titles.ErrProviderQuotaExhausted):
+		r.Status, r.Error = BulkProviderError, err.Error()
+	default:
+		logx.FromContext(ctx).Printf("ERROR: bulk add %q: %v", r.URL, err)
+		r.Status, r.Error = BulkProviderError, "the title provider could not be reached"
+	}
+}
+
+// forEachConcurrently calls fn for each index below n, at most limit at a time,
+// and returns once every call has.
+func forEachConcurrently(n, limit int, fn func(i int)) {
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := range n {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,290 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+	"github.com/lealre/movies-backend/internal/titleprovider"
+	"github.com/lealre/movies-backend/internal/titleprovider/titleref"
+)
+
+// bulkStore satisfies store.Store by embedding the interface, so only what
+// BulkAddTitlesToGroup calls needs a body. It holds one group and a
+// catalogue, and records the batches AddGroupTitles was given.
+type bulkStore struct {
+	store.Store
+	mu        sync.Mutex
+	group     models.Group
+	catalogue map[string]models.Title
+	batches   [][]string
+	// failBatch, when set, makes that AddGroupTitles call (1 for the first)
+	// fail.
+	failBatch int
+}
+
+func (s *bulkStore) GetGroupById(_ context.Context, groupId, _ string) (models.Group, error) {
+	if groupId != s.group.Id {
+		return models.Group{}, store.ErrRecordNotFound
+	}
+	return s.group, nil
+}
+
+func (s *bulkStore) GetTitlesByIds(_ context.Context, ids []string) ([]models.Title, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []models.Title{}
+	for _, id := range ids {
+		if t, ok := s.catalogue[id]; ok {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (s *bulkStore) AddTitle(_ context.Context, title models.Title) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.catalogue[title.ID] = title
+	return nil
+}
+
+func (s *bulkStore) AddGroupTitles(_ context.Context, _ string, titleIds []string) ([]string, error) {
+	s.batches = append(s.batches, titleIds)
+	if len(s.batches) == s.failBatch {
+		return nil, errors.New("connection reset")
+	}
+	added := []string{}
+	for _, id := range titleIds {
+		if _, ok := s.group.Titles[id]; !ok {
+			s.group.Titles[id] = models.GroupTitleItem{TitleId: id, AddedAt: time.Now()}
+			added = append(added, id)
+		}
+	}
+	return added, nil
+}
+
+// bulkProvider serves the titles it holds. It counts the GetTitle calls in
+// flight, keeping the most seen at once.
+type bulkProvider struct {
+	titles   map[string]titleprovider.Title
+	inFlight atomic.Int32
+	maxSeen  atomic.Int32
+}
+
+func (p *bulkProvider) Name() string { return "fake" }
+
+func (p *bulkProvider) GetTitle(_ context.Context, id string) (*titleprovider.Title, error) {
+	n := p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+	for {
+		seen := p.maxSeen.Load()
+		if n <= seen || p.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+
+	t, ok := p.titles[id]
+	if !ok {
+		return nil, titleprovider.ErrTitleNotFound
+	}
+	return &t, nil
+}
+
+func (p *bulkProvider) SearchTitles(_ context.Context, query string, limit int) ([]titleprovider.SearchItem, error) {
+	out := []titleprovider.SearchItem{}
+	for _, t := range p.titles {
+		if len(out) < limit {
+			out = append(out, titleprovider.SearchItem{ID: t.ID, Type: t.Type, PrimaryTitle: t.PrimaryTitle, StartYear: t.StartYear})
+		}
+	}
+	return out, nil
+}
+
+func newBulkFixture() (*bulkStore, *bulkProvider) {
+	db := &bulkStore{
+		group: models.Group{Id: "g1", Titles: models.GroupTitles{
+			"tt0000001": {TitleId: "tt0000001"},
+		}},
+		catalogue: map[string]models.Title{
+			"tt0000001": {ID: "tt0000001", PrimaryTitle: "Already Here"},
+			"tt0000002": {ID: "tt0000002", PrimaryTitle: "In Catalogue"},
+		},
+	}
+	provider := &bulkProvider{titles: map[string]titleprovider.Title{
+		"tt0000003": {ID: "tt0000003", Type: "movie", PrimaryTitle: "The Godfather", StartYear: 1972},
+	}}
+	return db, provider
+}
+
+func TestBulkAddTitlesToGroup(t *testing.T) {
+	db, provider := newBulkFixture()
+	resolver := titleref.NewResolver(provider, nil)
+
+	urls := []string{
+		"tt0000001",                                  // already in the group
+		"https://www.imdb.com/title/tt0000002/",      // in the catalogue
+		"https://letterboxd.com/film/the-godfather/", // fetched from the provider
+		"tt0000003",                                  // repeats the one above
+		"tt9999999",                                  // unknown to the provider
+		"https://www.netflix.com/title/1",            // unsupported
+		"tt-one",                                     // not an IMDb id
+	}
+	resp, err := BulkAddTitlesToGroup(db, provider, resolver, context.Background(), "g1", "u1", urls)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	want := []struct{ status, titleId, name string }{
+		{BulkAlreadyPresent, "tt0000001", "Already Here"},
+		{BulkAdded, "tt0000002", "In Catalogue"},
+		{BulkAdded, "tt0000003", "The Godfather"},
+		{BulkAlreadyPresent, "tt0000003", "The Godfather"},
+		{BulkNotFound, "tt9999999", ""},
+		{BulkInvalidReference, "", ""},
+		{BulkInvalidReference, "", ""},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(want))
+	}
+	for i, w := range want {
+		got := resp.Results[i]
+		if got.URL != urls[i] || got.Status != w.status || got.TitleId != w.titleId || got.TitleName != w.name {
+			t.Errorf("result %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if resp.Added != 2 || resp.AlreadyPresent != 2 || resp.Failed != 3 {
+		t.Errorf("counts = %d/%d/%d, want 2/2/3", resp.Added, resp.AlreadyPresent, resp.Failed)
+	}
+	if _, ok := db.catalogue["tt0000003"]; !ok {
+		t.Error("a fetched title must be stored in the catalogue")
+	}
+	if len(db.batches) != 1 {
+		t.Errorf("got %d insert batches, want 1", len(db.batches))
+	}
+}
+
+func TestBulkAddTitlesToGroupProviderError(t *testing.T) {
+	db, provider := newBulkFixture()
+	provider.titles["tt0000004"] = titleprovider.Title{ID: "tt0000004"}
+	resolver := titleref.NewResolver(provider, nil)
+
+	// The failing id has to be a valid IMDb id to reach the provider.
+	failing := &failingProvider{bulkProvider: provider, fail: "tt0000005"}
+	resp, err := BulkAddTitlesToGroup(db, failing, resolver, context.Background(), "g1", "u1", []string{"tt0000004", "tt0000005"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Results[0].Status != BulkAdded {
+		t.Errorf("result 0 = %+v, want added", resp.Results[0])
+	}
+	if got := resp.Results[1]; got.Status != BulkProviderError || got.Error == "" || got.Error == "connection refused" {
+		t.Errorf("result 1 = %+v, want a provider error that does not leak the cause", got)
+	}
+}
+
+// failingProvider is bulkProvider with one id failing as if the provider were
+// unreachable.
+type failingProvider struct {
+	*bulkProvider
+	fail string
+}
+
+func (p *failingProvider) GetTitle(ctx context.Context, id string) (*titleprovider.Title, error) {
+	if id == p.fail {
+		return nil, errors.New("connection refused")
+	}
+	return p.bulkProvider.GetTitle(ctx, id)
+}
+
+func TestBulkAddTitlesToGroupBatchesAndBoundsConcurrency(t *testing.T) {
+	t.Setenv("BULK_ADD_CONCURRENCY", "3")
+	db, provider := newBulkFixture()
+	resolver := titleref.NewResolver(provider, nil)
+
+	var urls []string
+	for i := range bulkInsertBatchSize + 10 {
+		id := fmt.Sprintf("tt1%06d", i)
+		provider.titles[id] = titleprovider.Title{ID: id, PrimaryTitle: id}
+		urls = append(urls, id)
+	}
+
+	resp, err := BulkAddTitlesToGroup(db, provider, resolver, context.Background(), "g1", "u1", urls)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Added != len(urls) {
+		t.Fatalf("added %d, want %d", resp.Added, len(urls))
+	}
+	if len(db.batches) != 2 || len(db.batches[0]) != bulkInsertBatchSize {
+		t.Errorf("batches of %v, want %d then the rest", batchSizes(db.batches), bulkInsertBatchSize)
+	}
+	if got := provider.maxSeen.Load(); got > 3 {
+		t.Errorf("%d provider calls at once, want at most 3", got)
+	}
+}
+
+func TestBulkAddTitlesToGroupBatchFailure(t *testing.T) {
+	db, provider := newBulkFixture()
+	db.failBatch = 2
+	resolver := titleref.NewResolver(provider, nil)
+
+	var urls []string
+	for i := range bulkInsertBatchSize*2 + 5 {
+		id := fmt.Sprintf("tt1%06d", i)
+		provider.titles[id] = titleprovider.Title{ID: id, PrimaryTitle: id}
+		urls = append(urls, id)
+	}
+
+	resp, err := BulkAddTitlesToGroup(db, provider, resolver, context.Background(), "g1", "u1", urls)
+	if err != nil {
+		t.Fatalf("err = %v, want the results of the batches that were added", err)
+	}
+	if len(db.batches) != 3 {
+		t.Errorf("got %d insert batches, want the ones after the failure tried too", len(db.batches))
+	}
+	if resp.Added != bulkInsertBatchSize+5 || resp.Failed != bulkInsertBatchSize {
+		t.Errorf("counts = %d added, %d failed, want %d and %d", resp.Added, resp.Failed, bulkInsertBatchSize+5, bulkInsertBatchSize)
+	}
+	for i, got := range resp.Results {
+		inFailed := i >= bulkInsertBatchSize && i < 2*bulkInsertBatchSize
+		switch {
+		case inFailed && (got.Status != BulkNotAdded || got.Error == "" || got.Error == "connection reset"):
+			t.Errorf("result %d = %+v, want not added, without the cause", i, got)
+		case !inFailed && got.Status != BulkAdded:
+			t.Errorf("result %d = %+v, want added", i, got)
+		}
+	}
+}
+
+func TestBulkAddTitlesToGroupLimits(t *testing.T) {
+	t.Setenv("BULK_ADD_MAX_TITLES", "2")
+	db, provider := newBulkFixture()
+	resolver := titleref.NewResolver(provider, nil)
+	ctx := context.Background()
+
+	if _, err := BulkAddTitlesToGroup(db, provider, resolver, ctx, "g1", "u1", nil); !errors.Is(err, ErrBulkTitlesRequired) {
+		t.Errorf("empty: err = %v", err)
+	}
+	if _, err := BulkAddTitlesToGroup(db, provider, resolver, ctx, "g1", "u1", []string{"tt1", "tt2", "tt3"}); !errors.Is(err, ErrTooManyBulkTitles) {
+		t.Errorf("too many: err = %v", err)
+	}
+	if _, err := BulkAddTitlesToGroup(db, provider, resolver, ctx, "nope", "u1", []string{"tt1"}); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("unknown group: err = %v", err)
+	}
+}
+
+func batchSizes(batches [][]string) []int {
+	sizes := []int{}
+	for _, b := range batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/lealre/movies-backend/internal/config"
 	"github.com/lealre/movies-backend/internal/generics"
 	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/services/availability"
 	"github.com/lealre/movies-backend/internal/services/ratings"
 	"github.com/lealre/movies-backend/internal/services/titles"
 	"github.com/lealre/movies-backend/internal/services/users"
@@ -58,64 +59,65 @@ func GetGroupById(db store.Store, ctx context.Context, groupId, userId string) (
 	return MapDbGroupToApiGroupResponse(groupDb), nil
 }
 
-// RenameGroup renames a group the caller owns. Owner-only; validates a non-empty
-// name and maps a duplicate name to ErrGroupDuplicatedName.
-func UpdateGroupInfo(db store.Store, ctx context.Context, groupId, ownerId, name, description string) (GroupResponse, error) {
+// UpdateGroupInfo renames a group the caller owns and sets its description.
+// Owner-only; validates a non-empty name and maps a duplicate name to
+// ErrGroupDuplicatedName. It returns the group as updated and as it stood just
+// before, from the same read that drove the write, which is what the activity
+// event compares.
+func UpdateGroupInfo(db store.Store, ctx context.Context, groupId, ownerId, name, description string) (GroupResponse, GroupResponse, error) {
 	name = strings.TrimSpace(name)
 	if name == "" {
-		return GroupResponse{}, ErrGroupNameInvalid
+		return GroupResponse{}, GroupResponse{}, ErrGroupNameInvalid
 	}
 	description = strings.TrimSpace(description)
 
 	group, err := db.GetGroupById(ctx, groupId, ownerId)
 	if err != nil {
 		if errors.Is(err, store.ErrRecordNotFound) {
-			return GroupResponse{}, ErrGroupNotFound
+			return GroupResponse{}, GroupResponse{}, ErrGroupNotFound
 		}
-		return GroupResponse{}, err
+		return GroupResponse{}, GroupResponse{}, err
 	}
 
 	if group.OwnerId != ownerId {
-		return GroupResponse{}, ErrGroupNotOwnedByUser
+		return GroupResponse{}, GroupResponse{}, ErrGroupNotOwnedByUser
 	}
 
 	if err := db.UpdateGroupInfo(ctx, groupId, name, description); err != nil {
 		if errors.Is(err, store.ErrDuplicatedRecord) {
-			return GroupResponse{}, ErrGroupDuplicatedName
+			return GroupResponse{}, GroupResponse{}, ErrGroupDuplicatedName
 		}
-		return GroupResponse{}, err
+		return GroupResponse{}, GroupResponse{}, err
 	}
 
+	previous := MapDbGroupToApiGroupResponse(group)
 	group.Name = name
 	group.Description = description
-	return MapDbGroupToApiGroupResponse(group), nil
+	return MapDbGroupToApiGroupResponse(group), previous, nil
 }
 
-func AddUserToGroup(db store.Store, ctx context.Context, groupId, ownerId, userId string) error {
+// AddUserToGroup adds userId to a group the caller owns and returns the user
+// added.
+func AddUserToGroup(db store.Store, ctx context.Context, groupId, ownerId, userId string) (users.UserResponse, error) {
 	group, err := db.GetGroupById(ctx, groupId, ownerId)
 	if err != nil {
 		if errors.Is(err, store.ErrRecordNotFound) {
-			return ErrGroupNotFound
+			return users.UserResponse{}, ErrGroupNotFound
 		}
-		return err
+		return users.UserResponse{}, err
 	}
 
 	// Only the owner of the group can add users to it
 	if group.OwnerId != ownerId {
-		return ErrGroupNotOwnedByUser
+		return users.UserResponse{}, ErrGroupNotOwnedByUser
 	}
 
 	err = db.AddUserToGroup(ctx, groupId, ownerId, userId)
 	if err != nil {
-		return err
-	}
-
-	_, err = users.UpdateUserGroup(db, ctx, userId, groupId)
-	if err != nil {
-		return err
+		return users.UserResponse{}, err
 	}
 
-	return nil
+	return users.UpdateUserGroup(db, ctx, userId, groupId)
 }
 
 // GetTitlesFromGroup returns one page of a group's titles.
@@ -125,7 +127,10 @@ func AddUserToGroup(db store.Store, ctx context.Context, groupId, ownerId, userI
 // groups.GroupExists — a single EXISTS whose 404 this function cannot improve
 // on — so running the same check again here would only add a round trip to
 // the endpoint. That is also why it takes no userId: an ignored one would
-// imply a per-user scoping this function does not perform.
+// imply a per-user scoping this function does not perform. What is per-user
+// arrives already resolved — region, the region whose streaming availability
+// each title carries ("" for none), and availableOn, the caller's services
+// when they asked for only the titles those carry (nil for every title).
 func GetTitlesFromGroup(
 	db store.Store,
 	ctx context.Context,
@@ -135,18 +140,12 @@ func GetTitlesFromGroup(
 	watched *bool,
 	ascending *bool,
 	titleType *string,
+	region string,
+	availableOn *models.StreamingFilter,
 ) (generics.Page[GroupTitleDetail], error) {
 	// API vocabulary -> title.type values; anything unrecognized means no
 	// filter, matching the previous behavior.
-	var titleTypes []string
-	if titleType != nil {
-		switch *titleType {
-		case "serie":
-			titleTypes = models.SeriesTitleTypes()
-		case "movie":
-			titleTypes = []string{"movie"}
-		}
-	}
+	titleTypes := titleTypesFor(titleType)
 
 	// App-level pagination normalization for the query itself, shared with
 	// titles.GetPageOfTitles. The raw, caller-given size/page are deliberately
@@ -154,7 +153,7 @@ func GetTitlesFromGroup(
 	// reports them unnormalized — see the comment there.
 	querySize, queryPage := config.NormalizePageParams(size, page)
 
-	pageRows, total, err := db.GetGroupTitlesPage(ctx, groupId, watched, titleTypes, orderBy, ascending, querySize, queryPage)
+	pageRows, total, err := db.GetGroupTitlesPage(ctx, groupId, watched, titleTypes, availableOn, orderBy, ascending, querySize, queryPage)
 	if err != nil {
 		return generics.Page[GroupTitleDetail]{}, err
 	}
@@ -165,8 +164,8 @@ func GetTitlesFromGroup(
 	// (CONVENTIONS §5) that this function must keep:
 	//
 	//  1. the group holds no title entry matching the filters — an empty
-	//     group, or a watched/titleType filter that matches none of its
-	//     entries: `[]`, with the caller's raw size/page echoed back;
+	//     group, or a watched/titleType/availableOn filter that matches none
+	//     of its entries: `[]`, with the caller's raw size/page echoed back;
 	//  2. every matching entry points at a title that is gone from the
 	//     catalogue (group_titles has no FK to titles, so entries outlive
 	//     deleted titles): `null`, with normalized size/page;
@@ -180,7 +179,7 @@ func GetTitlesFromGroup(
 	// leaves allTitlesDetails nil, which is exactly the `null` those two
 	// return.
 	if total == 0 {
-		hasEntries, err := db.GroupHasTitleEntries(ctx, groupId, watched, titleTypes)
+		hasEntries, err := db.GroupHasTitleEntries(ctx, groupId, watched, titleTypes, availableOn)
 		if err != nil {
 			return generics.Page[GroupTitleDetail]{}, err
 		}
@@ -195,7 +194,7 @@ func GetTitlesFromGroup(
 		}
 	}
 
-	allTitlesDetails, err := buildGroupTitleDetails(db, ctx, groupId, pageRows)
+	allTitlesDetails, err := buildGroupTitleDetails(db, ctx, groupId, pageRows, region)
 	if err != nil {
 		return generics.Page[GroupTitleDetail]{}, err
 	}
@@ -223,7 +222,10 @@ func GetTitlesFromGroup(
 // empty-page envelope distinguishes `"Content":null` from `"Content":[]`
 // (CONVENTIONS §5), and only its caller knows which of the two an empty result
 // means.
-func buildGroupTitleDetails(db store.Store, ctx context.Context, groupId string, rows []models.GroupPagedTitle) ([]GroupTitleDetail, error) {
+//
+// With a region, each title also carries its streaming availability there,
+// when it has been fetched; "" leaves availability out.
+func buildGroupTitleDetails(db store.Store, ctx context.Context, groupId string, rows []models.GroupPagedTitle, region string) ([]GroupTitleDetail, error) {
 	titleIds := make([]string, 0, len(rows))
 	for _, row := range rows {
 		titleIds = append(titleIds, row.Title.ID)
@@ -237,6 +239,14 @@ func buildGroupTitleDetails(db store.Store, ctx context.Context, groupId string,
 		return nil, err
 	}
 
+	var streamable map[string]availability.Availability
+	if region != "" && len(titleIds) > 0 {
+		streamable, err = availability.GetBatch(db, ctx, titleIds, region)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var details []GroupTitleDetail
 	for _, row := range rows {
 		detail := GroupTitleDetail{
@@ -246,6 +256,9 @@ func buildGroupTitleDetails(db store.Store, ctx context.Context, groupId string,
 			AddedAt:      row.Item.AddedAt,
 			UpdatedAt:    row.Item.UpdatedAt,
 		}
+		if a, ok := streamable[row.Title.ID]; ok {
+			detail.Availability = &a
+		}
 
 		// Map seasons watched from database to API type
 		if row.Item.SeasonsWatched != nil {
@@ -280,7 +293,8 @@ func buildGroupTitleDetails(db store.Store, ctx context.Context, groupId string,
 // does not hold is ErrTitleNotInGroup, which is the 404 that guard would
 // already have produced — this exists for the race where the entry disappears
 // between the two calls, and for an entry whose title has left the catalogue.
-func GetGroupTitleDetail(db store.Store, ctx context.Context, groupId, titleId string) (GroupTitleDetail, error) {
+// region is as for GetTitlesFromGroup.
+func GetGroupTitleDetail(db store.Store, ctx context.Context, groupId, titleId, region string) (GroupTitleDetail, error) {
 	row, err := db.GetGroupTitle(ctx, groupId, titleId)
 	if err != nil {
 		if errors.Is(err, store.ErrRecordNotFound) {
@@ -289,7 +303,7 @@ func GetGroupTitleDetail(db store.Store, ctx context.Context, groupId, titleId s
 		return GroupTitleDetail{}, err
 	}
 
-	details, err := buildGroupTitleDetails(db, ctx, groupId, []models.GroupPagedTitle{row})
+	details, err := buildGroupTitleDetails(db, ctx, groupId, []models.GroupPagedTitle{row}, region)
 	if err != nil {
 		return GroupTitleDetail{}, err
 	}
@@ -575,30 +589,31 @@ func RemoveTitleFromGroup(db store.Store, ctx context.Context, groupId, titleId,
 }
 
 // SoftDeleteGroup marks a group deleted (owner only) and removes it from every
-// member's group list. No cascade to titles/ratings/comments.
-func SoftDeleteGroup(db store.Store, ctx context.Context, groupId, ownerId string) error {
+// member's group list. No cascade to titles/ratings/comments. It returns the
+// group as it was, members included.
+func SoftDeleteGroup(db store.Store, ctx context.Context, groupId, ownerId string) (GroupResponse, error) {
 	group, err := db.GetGroupById(ctx, groupId, ownerId)
 	if err != nil {
 		if errors.Is(err, store.ErrRecordNotFound) {
-			return ErrGroupNotFound
+			return GroupResponse{}, ErrGroupNotFound
 		}
-		return err
+		return GroupResponse{}, err
 	}
 	if group.OwnerId != ownerId {
-		return ErrGroupNotOwnedByUser
+		return GroupResponse{}, ErrGroupNotOwnedByUser
 	}
 	if err := db.SoftDeleteGroup(ctx, groupId); err != nil {
 		if errors.Is(err, store.ErrRecordNotFound) {
-			return ErrGroupNotFound
+			return GroupResponse{}, ErrGroupNotFound
 		}
-		return err
+		return GroupResponse{}, err
 	}
 	for _, memberId := range group.Users {
 		if err := db.RemoveGroupFromUser(ctx, memberId, groupId); err != nil {
-			return err
+			return GroupResponse{}, err
 		}
 	}
-	return nil
+	return MapDbGroupToApiGroupResponse(group), nil
 }
 
 // LeaveGroup removes a non-owner member from a group (and the group from their
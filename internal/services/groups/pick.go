@@ -0,0 +1,370 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/config"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+const (
+	// maxPickWeight bounds each factor's weight. A factor is raised to its
+	// weight, so past this one factor drowns out the rest and the pick stops
+	// being a weighted draw and becomes a sort.
+	maxPickWeight = 5
+	// unknownFactor is what a factor counts for when the title does not carry
+	// what it needs (no IMDb rating, no runtime): neither favoured nor ruled
+	// out.
+	unknownFactor = 0.5
+	// ageHorizonDays is how long a title keeps gaining weight for sitting in
+	// the group; a title added a year ago counts double one added today, and
+	// one added five years ago counts no more than that.
+	ageHorizonDays = 365
+	// maxTimeBudgetMinutes bounds timeBudgetMinutes at a day: no evening is
+	// longer, and the budget is held in seconds, which a larger one could
+	// overflow.
+	maxTimeBudgetMinutes = 24 * 60
+	// overBudgetExponent sets how fast a title that runs over the time budget
+	// falls away: one running 50% over keeps (1/1.5)^3, about 30%, of its
+	// weight.
+	overBudgetExponent = 3
+)
+
+// PickTitle chooses one unwatched title of groupId at random, weighted by the
+// factors in req, and explains the choice.
+//
+// The candidates are read with GetGroupTitlesPage — the same watched and
+// title-type filtering the titles list applies — one page at a time, so the
+// group is never held in memory at once. Each page is then narrowed by the
+// genre filters and, with present members named, by what they have already
+// seen, and every remaining title takes part in a single weighted draw
+// (Efraimidis–Spirakis: each candidate draws a key u^(1/w) and the largest key
+// wins), which needs nothing from earlier pages but the best key so far. The
+// pages are walked in added order, ending in the title id, so for the same
+// group state, seed and asOf the same title comes out.
+//
+// asOf is the moment the age factor counts each title's wait up to. Without
+// it the pick uses the current time and returns it, as it does the seed: a
+// replay that sends back only the seed counts one more day for every day
+// since, and can come out differently.
+//
+// Possible errors returned:
+//   - ErrInvalidPickWeight, ErrInvalidTimeBudget: if req is out of range.
+//   - ErrGroupNotFound: if the group does not exist or userId is not a member.
+//   - ErrPresentUserNotInGroup: if a present user is not a member.
+//   - ErrNothingToPick: if no unwatched title passes the filters.
+//   - Any error returned by the store reads.
+func PickTitle(db store.Store, ctx context.Context, groupId, userId string, req PickRequest) (PickResponse, error) {
+	opts, err := newPickOptions(req)
+	if err != nil {
+		return PickResponse{}, err
+	}
+
+	if len(req.PresentUserIds) > 0 {
+		members, err := db.GetUsersFromGroup(ctx, groupId, userId)
+		if err != nil {
+			if errors.Is(err, store.ErrRecordNotFound) {
+				return PickResponse{}, ErrGroupNotFound
+			}
+			return PickResponse{}, err
+		}
+		isMember := map[string]bool{}
+		for _, member := range members {
+			isMember[member.Id] = true
+		}
+		for _, presentId := range req.PresentUserIds {
+			if !isMember[presentId] {
+				return PickResponse{}, ErrPresentUserNotInGroup
+			}
+		}
+	}
+
+	var seed int64
+	if req.Seed != nil {
+		seed = *req.Seed
+	} else {
+		seed = rand.Int64() // #nosec G404 -- a replayable draw, not a secret
+	}
+	rng := rand.New(rand.NewPCG(uint64(seed), 0)) // #nosec G404 -- seeded on purpose, see above
+
+	var (
+		watched    = false
+		ascending  = true
+		pageSize   = config.MaxPageSize()
+		titleTypes = titleTypesFor(req.TitleType)
+		now        = time.Now().UTC()
+
+		best        *pickCandidate
+		bestKey     = math.Inf(-1)
+		totalWeight float64
+		response    PickResponse
+	)
+	if req.AsOf != nil {
+		now = *req.AsOf
+	}
+	response.Seed, response.AsOf = seed, now
+
+	for page := 1; ; page++ {
+		rows, total, err := db.GetGroupTitlesPage(ctx, groupId, &watched, titleTypes, nil, "addedAt", &ascending, pageSize, page)
+		if err != nil {
+			return PickResponse{}, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		eligible := make([]models.GroupPagedTitle, 0, len(rows))
+		for _, row := range rows {
+			if !opts.genresAllow(row.Title.Genres) {
+				response.Excluded.Genre++
+				continue
+			}
+			eligible = append(eligible, row)
+		}
+
+		if len(req.PresentUserIds) > 0 && len(eligible) > 0 {
+			titleIds := make([]string, 0, len(eligible))
+			for _, row := range eligible {
+				titleIds = append(titleIds, row.Title.ID)
+			}
+			seenIds, err := db.GetTitleIdsSeenByUsers(ctx, req.PresentUserIds, titleIds, groupId)
+			if err != nil {
+				return PickResponse{}, err
+			}
+			seen := map[string]bool{}
+			for _, titleId := range seenIds {
+				seen[titleId] = true
+			}
+			unseen := eligible[:0]
+			for _, row := range eligible {
+				if seen[row.Title.ID] {
+					response.Excluded.Seen++
+					continue
+				}
+				unseen = append(unseen, row)
+			}
+			eligible = unseen
+		}
+
+		for _, row := range eligible {
+			candidate := scorePick(row, now, opts)
+			response.Candidates++
+			totalWeight += candidate.weight
+
+			// 1-u is in (0, 1], so the log is finite and the key is
+			// ln(u)/w, the log of u^(1/w): same order, no underflow.
+			key := math.Log(1-rng.Float64()) / candidate.weight
+			if best == nil || key > bestKey {
+				best = &candidate
+				bestKey = key
+			}
+		}
+
+		if int64(page)*int64(pageSize) >= total {
+			break
+		}
+	}
+
+	if best == nil {
+		return PickResponse{}, ErrNothingToPick
+	}
+
+	details, err := buildGroupTitleDetails(db, ctx, groupId, []models.GroupPagedTitle{best.row}, "")
+	if err != nil {
+		return PickResponse{}, err
+	}
+	response.Title = details[0]
+	response.Probability = math.Round(best.weight/totalWeight*10000) / 10000
+	response.Reasons = best.reasons
+	if len(req.PresentUserIds) > 0 {
+		response.Reasons = append(response.Reasons, PickReason{
+			Factor:     "seen",
+			Multiplier: 1,
+			Message:    fmt.Sprintf("None of the %d present members has seen it", len(req.PresentUserIds)),
+		})
+	}
+	return response, nil
+}
+
+// titleTypesFor maps the titles list's type vocabulary to title.type values.
+// Anything unrecognized means no filter.
+func titleTypesFor(titleType *string) []string {
+	if titleType == nil {
+		return nil
+	}
+	switch *titleType {
+	case "serie":
+		return models.SeriesTitleTypes()
+	case "movie":
+		return []string{"movie"}
+	}
+	return nil
+}
+
+type pickOptions struct {
+	budgetSeconds int
+	include       map[string]bool
+	exclude       map[string]bool
+
+	ratingWeight  float64
+	ageWeight     float64
+	runtimeWeight float64
+}
+
+func newPickOptions(req PickRequest) (pickOptions, error) {
+	opts := pickOptions{
+		include:       lowerSet(req.IncludeGenres),
+		exclude:       lowerSet(req.ExcludeGenres),
+		ratingWeight:  1,
+		ageWeight:     1,
+		runtimeWeight: 1,
+	}
+
+	if req.TimeBudgetMinutes != nil {
+		if *req.TimeBudgetMinutes <= 0 || *req.TimeBudgetMinutes > maxTimeBudgetMinutes {
+			return pickOptions{}, ErrInvalidTimeBudget
+		}
+		opts.budgetSeconds = *req.TimeBudgetMinutes * 60
+	}
+
+	if req.Weights != nil {
+		for _, weight := range []struct {
+			value  *float64
+			target *float64
+		}{
+			{req.Weights.Rating, &opts.ratingWeight},
+			{req.Weights.Age, &opts.ageWeight},
+			{req.Weights.Runtime, &opts.runtimeWeight},
+		} {
+			if weight.value == nil {
+				continue
+			}
+			if *weight.value < 0 || *weight.value > maxPickWeight || math.IsNaN(*weight.value) {
+				return pickOptions{}, ErrInvalidPickWeight
+			}
+			*weight.target = *weight.value
+		}
+	}
+	return opts, nil
+}
+
+func lowerSet(values []string) map[string]bool {
+	set := map[string]bool{}
+	for _, value := range values {
+		set[strings.ToLower(strings.TrimSpace(value))] = true
+	}
+	return set
+}
+
+// genresAllow applies the genre filters, case-insensitively: any excluded
+// genre rules a title out, and with genres to include it needs at least one.
+func (o pickOptions) genresAllow(genres []string) bool {
+	included := len(o.include) == 0
+	for _, genre := range genres {
+		lower := strings.ToLower(genre)
+		if o.exclude[lower] {
+			return false
+		}
+		if o.include[lower] {
+			included = true
+		}
+	}
+	return included
+}
+
+type pickCandidate struct {
+	row     models.GroupPagedTitle
+	weight  float64
+	reasons []PickReason
+}
+
+// scorePick weighs one candidate: the product of each factor raised to its
+// weight. Every factor is positive, so every candidate keeps some chance.
+func scorePick(row models.GroupPagedTitle, now time.Time, opts pickOptions) pickCandidate {
+	candidate := pickCandidate{row: row, weight: 1, reasons: []PickReason{}}
+	apply := func(factor string, weight, value float64, message string) {
+		if weight == 0 {
+			return
+		}
+		multiplier := math.Pow(value, weight)
+		candidate.weight *= multiplier
+		candidate.reasons = append(candidate.reasons, PickReason{
+			Factor:     factor,
+			Weight:     weight,
+			Multiplier: math.Round(multiplier*1000) / 1000,
+			Message:    message,
+		})
+	}
+
+	if imdb := row.Title.Rating.AggregateRating; imdb > 0 {
+		apply("rating", opts.ratingWeight, imdb/10, fmt.Sprintf("Rated %.1f on IMDb", imdb))
+	} else {
+		apply("rating", opts.ratingWeight, unknownFactor, "No IMDb rating yet")
+	}
+
+	days := int(now.Sub(row.Item.AddedAt).Hours() / 24)
+	if days < 0 {
+		days = 0
+	}
+	apply("age", opts.ageWeight, 1+float64(min(days, ageHorizonDays))/ageHorizonDays,
+		fmt.Sprintf("In the group for %d %s", days, plural(days, "day", "days")))
+
+	if opts.budgetSeconds > 0 {
+		// A series' runtime is one episode's: the evening is spent on an
+		// episode or a few, never the whole show.
+		runtime := row.Title.RuntimeSeconds
+		budget := formatRuntime(opts.budgetSeconds)
+		switch {
+		case runtime <= 0:
+			apply("runtime", opts.runtimeWeight, unknownFactor, "Runtime unknown")
+		case runtime <= opts.budgetSeconds:
+			apply("runtime", opts.runtimeWeight, 1,
+				fmt.Sprintf("Runs %s, within your %s budget", formatRuntime(runtime), budget))
+		default:
+			apply("runtime", opts.runtimeWeight, math.Pow(float64(opts.budgetSeconds)/float64(runtime), overBudgetExponent),
+				fmt.Sprintf("Runs %s, over your %s budget", formatRuntime(runtime), budget))
+		}
+	}
+
+	if len(opts.include) > 0 {
+		var matched []string
+		for _, genre := range row.Title.Genres {
+			if opts.include[strings.ToLower(genre)] {
+				matched = append(matched, genre)
+			}
+		}
+		candidate.reasons = append(candidate.reasons, PickReason{
+			Factor:     "genre",
+			Multiplier: 1,
+			Message:    "Matches " + strings.Join(matched, ", "),
+		})
+	}
+
+	return candidate
+}
+
+func formatRuntime(seconds int) string {
+	hours, minutes := seconds/3600, (seconds%3600)/60
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
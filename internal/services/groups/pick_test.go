@@ -0,0 +1,253 @@
+package groups
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// pickStore satisfies store.Store by embedding the interface, so only the
+// reads PickTitle makes need bodies; anything else panics on the nil embedded
+// interface. It serves titles in pages the way GetGroupTitlesPage does and
+// records the page sizes it was asked for.
+type pickStore struct {
+	store.Store
+	titles  []models.GroupPagedTitle
+	seen    map[string]bool
+	members []models.User
+	pages   []int
+}
+
+func (s *pickStore) GetGroupTitlesPage(_ context.Context, _ string, _ *bool, _ []string, _ *models.StreamingFilter, _ string, _ *bool, size, page int) ([]models.GroupPagedTitle, int64, error) {
+	s.pages = append(s.pages, page)
+	start := (page - 1) * size
+	if start >= len(s.titles) {
+		return []models.GroupPagedTitle{}, int64(len(s.titles)), nil
+	}
+	end := min(start+size, len(s.titles))
+	return s.titles[start:end], int64(len(s.titles)), nil
+}
+
+func (s *pickStore) GetTitleIdsSeenByUsers(_ context.Context, _ []string, titleIds []string, _ string) ([]string, error) {
+	out := []string{}
+	for _, titleId := range titleIds {
+		if s.seen[titleId] {
+			out = append(out, titleId)
+		}
+	}
+	return out, nil
+}
+
+func (s *pickStore) GetUsersFromGroup(context.Context, string, string) ([]models.User, error) {
+	return s.members, nil
+}
+
+func (s *pickStore) GetRatingsByTitleIds(context.Context, []string, string) ([]models.UserRating, error) {
+	return []models.UserRating{}, nil
+}
+
+func pickTitle(id string, imdb float64, runtimeMinutes int, genres ...string) models.GroupPagedTitle {
+	return models.GroupPagedTitle{
+		Title: models.Title{
+			ID: id, Type: "movie", PrimaryTitle: id, Genres: genres,
+			RuntimeSeconds: runtimeMinutes * 60,
+			Rating:         models.Rating{AggregateRating: imdb},
+		},
+		Item: models.GroupTitleItem{TitleId: id, AddedAt: time.Now()},
+	}
+}
+
+func TestPickTitle(t *testing.T) {
+	ctx := context.Background()
+	seed := func(v int64) *int64 { return &v }
+
+	t.Run("the same seed picks the same title, and every page is read", func(t *testing.T) {
+		t.Setenv("MAX_PAGE_SIZE", "2")
+		db := &pickStore{titles: []models.GroupPagedTitle{
+			pickTitle("tt1", 7, 100), pickTitle("tt2", 8, 100), pickTitle("tt3", 6, 100),
+			pickTitle("tt4", 9, 100), pickTitle("tt5", 5, 100),
+		}}
+
+		first, err := PickTitle(db, ctx, "g", "u", PickRequest{Seed: seed(42)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := PickTitle(db, ctx, "g", "u", PickRequest{Seed: seed(42)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if first.Title.Id != second.Title.Id || first.Seed != 42 {
+			t.Errorf("picks = %s, %s (seed %d); want the same title twice", first.Title.Id, second.Title.Id, first.Seed)
+		}
+		if first.Candidates != 5 {
+			t.Errorf("candidates = %d, want 5", first.Candidates)
+		}
+		if got := db.pages[:3]; got[0] != 1 || got[1] != 2 || got[2] != 3 {
+			t.Errorf("pages = %v, want 1, 2, 3 for five titles in pages of two", db.pages)
+		}
+	})
+
+	t.Run("a pick without a seed returns the one it used", func(t *testing.T) {
+		db := &pickStore{titles: []models.GroupPagedTitle{pickTitle("tt1", 7, 100), pickTitle("tt2", 7, 100)}}
+
+		pick, err := PickTitle(db, ctx, "g", "u", PickRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		replay, err := PickTitle(db, ctx, "g", "u", PickRequest{Seed: &pick.Seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replay.Title.Id != pick.Title.Id {
+			t.Errorf("replay = %s, want %s", replay.Title.Id, pick.Title.Id)
+		}
+	})
+
+	t.Run("ages are counted up to asOf, which the response returns", func(t *testing.T) {
+		title := pickTitle("tt1", 7, 100)
+		title.Item.AddedAt = time.Date(2026, time.January, 1, 20, 0, 0, 0, time.UTC)
+		db := &pickStore{titles: []models.GroupPagedTitle{title}}
+		asOf := time.Date(2026, time.January, 11, 21, 0, 0, 0, time.UTC)
+
+		pick, err := PickTitle(db, ctx, "g", "u", PickRequest{Seed: seed(7), AsOf: &asOf})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pick.AsOf.Equal(asOf) {
+			t.Errorf("asOf = %v, want %v", pick.AsOf, asOf)
+		}
+		for _, reason := range pick.Reasons {
+			if reason.Factor == "age" && reason.Message != "In the group for 10 days" {
+				t.Errorf("age reason = %q, want it counted to asOf, not to today", reason.Message)
+			}
+		}
+
+		unpinned, err := PickTitle(db, ctx, "g", "u", PickRequest{Seed: seed(7)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if time.Since(unpinned.AsOf) > time.Minute {
+			t.Errorf("asOf = %v, want the current time when none is sent", unpinned.AsOf)
+		}
+	})
+
+	t.Run("genre filters and seen titles are excluded and counted", func(t *testing.T) {
+		db := &pickStore{
+			titles: []models.GroupPagedTitle{
+				pickTitle("tt-horror", 9, 90, "Horror", "Comedy"),
+				pickTitle("tt-drama", 9, 90, "Drama"),
+				pickTitle("tt-seen", 9, 90, "Comedy"),
+				pickTitle("tt-comedy", 6, 90, "comedy"),
+			},
+			seen:    map[string]bool{"tt-seen": true},
+			members: []models.User{{Id: "u"}, {Id: "friend"}},
+		}
+
+		pick, err := PickTitle(db, ctx, "g", "u", PickRequest{
+			Seed:           seed(1),
+			IncludeGenres:  []string{"Comedy"},
+			ExcludeGenres:  []string{"horror"},
+			PresentUserIds: []string{"friend"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if pick.Title.Id != "tt-comedy" || pick.Candidates != 1 || pick.Probability != 1 {
+			t.Errorf("pick = %s of %d (p=%v), want the only remaining comedy", pick.Title.Id, pick.Candidates, pick.Probability)
+		}
+		if pick.Excluded.Genre != 2 || pick.Excluded.Seen != 1 {
+			t.Errorf("excluded = %+v, want 2 by genre and 1 seen", pick.Excluded)
+		}
+	})
+
+	t.Run("requests that cannot be served are rejected", func(t *testing.T) {
+		db := &pickStore{titles: []models.GroupPagedTitle{pickTitle("tt1", 7, 100, "Drama")}, members: []models.User{{Id: "u"}}}
+		budget, overlong := 0, maxTimeBudgetMinutes+1
+		weight := 6.0
+
+		cases := map[string]struct {
+			req  PickRequest
+			want error
+		}{
+			"zero budget":          {PickRequest{TimeBudgetMinutes: &budget}, ErrInvalidTimeBudget},
+			"budget over a day":    {PickRequest{TimeBudgetMinutes: &overlong}, ErrInvalidTimeBudget},
+			"weight out of range":  {PickRequest{Weights: &PickWeights{Age: &weight}}, ErrInvalidPickWeight},
+			"stranger present":     {PickRequest{PresentUserIds: []string{"stranger"}}, ErrPresentUserNotInGroup},
+			"nothing left to pick": {PickRequest{IncludeGenres: []string{"Western"}}, ErrNothingToPick},
+		}
+		for name, c := range cases {
+			if _, err := PickTitle(db, ctx, "g", "u", c.req); err != c.want {
+				t.Errorf("%s: err = %v, want %v", name, err, c.want)
+			}
+		}
+	})
+}
+
+func TestScorePick(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	defaults, err := newPickOptions(PickRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("a better IMDb rating and a longer wait both weigh more", func(t *testing.T) {
+		good := pickTitle("tt-good", 9, 100)
+		good.Item.AddedAt = now
+		bad := pickTitle("tt-bad", 4.5, 100)
+		bad.Item.AddedAt = now
+		old := pickTitle("tt-old", 4.5, 100)
+		old.Item.AddedAt = now.AddDate(-2, 0, 0)
+
+		if g, b := scorePick(good, now, defaults).weight, scorePick(bad, now, defaults).weight; g != 2*b {
+			t.Errorf("weights = %v, %v; want 9/10 to be twice 4.5/10", g, b)
+		}
+		// Capped at a year: two years in the group counts double, not triple.
+		if o, b := scorePick(old, now, defaults).weight, scorePick(bad, now, defaults).weight; o != 2*b {
+			t.Errorf("weights = %v, %v; want the old title to count double", o, b)
+		}
+	})
+
+	t.Run("a title over the time budget falls away and says why", func(t *testing.T) {
+		budget := 120
+		opts, err := newPickOptions(PickRequest{TimeBudgetMinutes: &budget})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fits := scorePick(pickTitle("tt-fits", 8, 110), now, opts)
+		long := scorePick(pickTitle("tt-long", 8, 180), now, opts)
+
+		// (120/180)^3 = 0.296...
+		if ratio := long.weight / fits.weight; ratio < 0.29 || ratio > 0.3 {
+			t.Errorf("ratio = %v, want about 0.296", ratio)
+		}
+		last := long.reasons[len(long.reasons)-1]
+		if last.Factor != "runtime" || last.Message != "Runs 3h, over your 2h budget" {
+			t.Errorf("reason = %+v", last)
+		}
+	})
+
+	t.Run("a zero weight switches a factor off", func(t *testing.T) {
+		zero := 0.0
+		opts, err := newPickOptions(PickRequest{Weights: &PickWeights{Rating: &zero}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		high := scorePick(pickTitle("tt-high", 9, 100), now, opts)
+		low := scorePick(pickTitle("tt-low", 2, 100), now, opts)
+
+		if high.weight != low.weight {
+			t.Errorf("weights = %v, %v; want the rating ignored", high.weight, low.weight)
+		}
+		for _, reason := range high.reasons {
+			if strings.HasPrefix(reason.Message, "Rated") {
+				t.Errorf("reasons = %+v, want no rating reason", high.reasons)
+			}
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/lealre/movies-backend/internal/generics"
+	"github.com/lealre/movies-backend/internal/services/availability"
 	"github.com/lealre/movies-backend/internal/services/ratings"
 	"github.com/lealre/movies-backend/internal/services/titles"
 )
@@ -72,6 +73,9 @@ type GroupTitleDetail struct {
 	AddedAt        time.Time        `json:"addedAt"`
 	UpdatedAt      time.Time        `json:"updatedAt"`
 	WatchedAt      *time.Time       `json:"watchedAt,omitempty"`
+	// Availability is where the title streams in the caller's region; absent
+	// until the title's availability has been fetched.
+	Availability *availability.Availability `json:"availability,omitempty"`
 }
 
 type AddTitleToGroupRequest struct {
@@ -79,6 +83,42 @@ type AddTitleToGroupRequest struct {
 	GroupId string `json:"groupId"`
 }
 
+// BulkAddTitlesRequest is the body of POST /groups/{id}/titles/bulk: title
+// references in any form AddTitleToGroupRequest.URL accepts.
+type BulkAddTitlesRequest struct {
+	URLs []string `json:"urls"`
+}
+
+// What became of one reference in a bulk add.
+const (
+	BulkAdded            = "added"
+	BulkAlreadyPresent   = "already_present"
+	BulkNotFound         = "not_found"
+	BulkInvalidReference = "invalid_reference"
+	BulkAmbiguous        = "ambiguous"
+	BulkProviderError    = "provider_error"
+	BulkNotAdded         = "not_added"
+)
+
+// BulkAddTitleResult is the outcome for one reference, in request order.
+// TitleId is set once the reference resolved, TitleName once the title is in
+// the catalogue; Candidates lists the matches of an ambiguous reference.
+type BulkAddTitleResult struct {
+	URL        string         `json:"url"`
+	Status     string         `json:"status"`
+	TitleId    string         `json:"titleId,omitempty"`
+	TitleName  string         `json:"titleName,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	Candidates []titles.Title `json:"candidates,omitempty"`
+}
+
+type BulkAddTitlesResponse struct {
+	Added          int                  `json:"added"`
+	AlreadyPresent int                  `json:"alreadyPresent"`
+	Failed         int                  `json:"failed"`
+	Results        []BulkAddTitleResult `json:"results"`
+}
+
 // WatchedState is a watched flag with the date it was watched, when there is
 // one.
 type WatchedState struct {
@@ -110,3 +150,57 @@ type UpdateGroupTitleWatchedRequest struct {
 	Watched   *bool                  `json:"watched,omitempty"`
 	WatchedAt *generics.FlexibleDate `json:"watchedAt,omitempty"`
 }
+
+// PickRequest is the body of POST /groups/{id}/pick. Every field is optional:
+// an empty body picks any unwatched title, weighted by the default factors.
+//
+// TitleType uses the titles list's vocabulary ("movie" or "serie").
+// PresentUserIds names the members watching tonight; when set, titles any of
+// them has already seen are left out. Seed and AsOf make the pick
+// reproducible; when absent a seed is chosen and the current time used, and
+// both are returned, so a pick can always be replayed.
+type PickRequest struct {
+	Seed              *int64       `json:"seed,omitempty"`
+	AsOf              *time.Time   `json:"asOf,omitempty"`
+	TitleType         *string      `json:"titleType,omitempty"`
+	TimeBudgetMinutes *int         `json:"timeBudgetMinutes,omitempty"`
+	IncludeGenres     []string     `json:"includeGenres,omitempty"`
+	ExcludeGenres     []string     `json:"excludeGenres,omitempty"`
+	PresentUserIds    []string     `json:"presentUserIds,omitempty"`
+	Weights           *PickWeights `json:"weights,omitempty"`
+}
+
+// PickWeights sets how strongly each factor pulls, from 0 (ignored) to
+// maxPickWeight. A nil weight is the default, 1.
+type PickWeights struct {
+	Rating  *float64 `json:"rating,omitempty"`
+	Age     *float64 `json:"age,omitempty"`
+	Runtime *float64 `json:"runtime,omitempty"`
+}
+
+// PickResponse is the chosen title and why. Probability is the chance this
+// title had of being picked among the Candidates that passed every filter.
+type PickResponse struct {
+	Seed        int64            `json:"seed"`
+	AsOf        time.Time        `json:"asOf"`
+	Title       GroupTitleDetail `json:"title"`
+	Candidates  int              `json:"candidates"`
+	Probability float64          `json:"probability"`
+	Reasons     []PickReason     `json:"reasons"`
+	Excluded    PickExclusions   `json:"excluded"`
+}
+
+// PickReason is one factor's contribution: Multiplier is what it multiplied
+// the title's weight by, after applying Weight.
+type PickReason struct {
+	Factor     string  `json:"factor"`
+	Weight     float64 `json:"weight"`
+	Multiplier float64 `json:"multiplier"`
+	Message    string  `json:"message"`
+}
+
+// PickExclusions counts the unwatched titles each filter removed.
+type PickExclusions struct {
+	Genre int `json:"genre"`
+	Seen  int `json:"seen"`
+}
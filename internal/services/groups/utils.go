@@ -16,6 +16,12 @@ var (
 	ErrInvalidSeasonValue                  = errors.New("season value is invalid")
 	ErrSeasonDoesNotExist                  = errors.New("season does not exist for this title")
 	ErrOwnerCannotLeaveGroup               = errors.New("the group owner cannot leave; delete the group instead")
+	ErrInvalidPickWeight                   = errors.New("pick weights must be between 0 and 5")
+	ErrInvalidTimeBudget                   = errors.New("timeBudgetMinutes must be between 1 and 1440")
+	ErrPresentUserNotInGroup               = errors.New("present users must be members of the group")
+	ErrNothingToPick                       = errors.New("no unwatched title in this group matches the request")
+	ErrBulkTitlesRequired                  = errors.New("urls must list at least one title")
+	ErrTooManyBulkTitles                   = errors.New("too many titles in one bulk add")
 )
 
 var ErrorMap = map[error]int{
@@ -29,4 +35,10 @@ var ErrorMap = map[error]int{
 	ErrInvalidSeasonValue:                  http.StatusBadRequest,
 	ErrSeasonDoesNotExist:                  http.StatusBadRequest,
 	ErrOwnerCannotLeaveGroup:               http.StatusForbidden,
+	ErrInvalidPickWeight:                   http.StatusBadRequest,
+	ErrInvalidTimeBudget:                   http.StatusBadRequest,
+	ErrPresentUserNotInGroup:               http.StatusBadRequest,
+	ErrNothingToPick:                       http.StatusNotFound,
+	ErrBulkTitlesRequired:                  http.StatusBadRequest,
+	ErrTooManyBulkTitles:                   http.StatusBadRequest,
 }
@@ -0,0 +1,154 @@
+package imports
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The exports an import reads: a group's own JSON export, and CSV exports
+// told apart by their header row.
+const (
+	FormatGroupExport       = "group_export"
+	FormatIMDbRatings       = "imdb_ratings"
+	FormatLetterboxdDiary   = "letterboxd_diary"
+	FormatLetterboxdRatings = "letterboxd_ratings"
+)
+
+// row is one line of an export, read into what the import needs. ImdbId is
+// set for IMDb exports; Letterboxd ones carry only Title and Year, which are
+// matched through the title provider. Note is already on the app's 0–10
+// scale; WatchedAt is the day the title was watched, or rated when the export
+// has no watch date.
+type row struct {
+	Line      int
+	ImdbId    string
+	Title     string
+	Year      int
+	Series    bool
+	Episode   bool
+	Note      *float64
+	WatchedAt *time.Time
+}
+
+// parseExport reads an IMDb ratings.csv, or a Letterboxd diary.csv or
+// ratings.csv (watched.csv too: it is ratings.csv without the ratings), and
+// returns its format and rows. A file with none of their headers is
+// ErrUnknownFormat. Cells the format does not require may be blank.
+func parseExport(r io.Reader) (string, []row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return "", nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return "", nil, ErrInvalidCSV
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		// A file saved from a spreadsheet opens with a byte order mark.
+		cols[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+
+	var format string
+	var parse func(get func(string) string) row
+	switch {
+	case has(cols, "Const", "Your Rating"):
+		format, parse = FormatIMDbRatings, parseIMDbRow
+	case has(cols, "Name", "Year", "Letterboxd URI", "Watched Date"):
+		format, parse = FormatLetterboxdDiary, parseLetterboxdRow
+	case has(cols, "Name", "Year", "Letterboxd URI"):
+		format, parse = FormatLetterboxdRatings, parseLetterboxdRow
+	default:
+		return "", nil, ErrUnknownFormat
+	}
+
+	var rows []row
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", nil, ErrInvalidCSV
+		}
+		get := func(col string) string {
+			if i, ok := cols[col]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		r := parse(get)
+		r.Line = line
+		rows = append(rows, r)
+	}
+	return format, rows, nil
+}
+
+// parseIMDbRow reads a ratings.csv line. "Your Rating" is already 1–10; the
+// title type tells series and episodes, which import differently, apart.
+func parseIMDbRow(get func(string) string) row {
+	r := row{
+		ImdbId:    get("Const"),
+		Title:     get("Title"),
+		Year:      atoi(get("Year")),
+		WatchedAt: parseDate(get("Date Rated")),
+	}
+	switch strings.ToLower(get("Title Type")) {
+	case "tv series", "tv mini series", "tvseries", "tvminiseries":
+		r.Series = true
+	case "tv episode", "tvepisode":
+		r.Episode = true
+	}
+	if n, err := strconv.ParseFloat(get("Your Rating"), 64); err == nil && n > 0 {
+		r.Note = &n
+	}
+	return r
+}
+
+// parseLetterboxdRow reads a diary.csv or ratings.csv line. Ratings are half
+// stars out of five, doubled onto the 0–10 scale, so ★★★½ is 7. A diary
+// entry's "Watched Date" is when it was watched; ratings.csv only has the day
+// it was logged.
+func parseLetterboxdRow(get func(string) string) row {
+	r := row{
+		Title:     get("Name"),
+		Year:      atoi(get("Year")),
+		WatchedAt: parseDate(get("Watched Date")),
+	}
+	if r.WatchedAt == nil {
+		r.WatchedAt = parseDate(get("Date"))
+	}
+	if stars, err := strconv.ParseFloat(get("Rating"), 64); err == nil && stars > 0 {
+		note := stars * 2
+		r.Note = &note
+	}
+	return r
+}
+
+func has(cols map[string]int, names ...string) bool {
+	for _, name := range names {
+		if _, ok := cols[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// parseDate reads the YYYY-MM-DD both exports use, as midnight UTC.
+func parseDate(s string) *time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
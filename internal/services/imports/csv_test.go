@@ -0,0 +1,123 @@
+package imports
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExportIMDbRatings(t *testing.T) {
+	const export = "\ufeffConst,Your Rating,Date Rated,Title,Original Title,URL,Title Type,IMDb Rating,Runtime (mins),Year,Genres,Num Votes,Release Date,Directors\n" +
+		"tt0068646,10,2023-04-01,The Godfather,The Godfather,https://www.imdb.com/title/tt0068646,Movie,9.2,175,1972,\"Crime, Drama\",2000000,1972-03-14,Francis Ford Coppola\n" +
+		"tt0903747,9,2022-01-15,Breaking Bad,Breaking Bad,https://www.imdb.com/title/tt0903747,TV Series,9.5,49,2008,Drama,2000000,2008-01-20,\n" +
+		"tt0959621,8,2022-01-16,Pilot,Pilot,https://www.imdb.com/title/tt0959621,TV Episode,8.2,58,2008,Drama,40000,2008-01-20,Vince Gilligan\n"
+
+	format, rows, err := parseExport(strings.NewReader(export))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if format != FormatIMDbRatings {
+		t.Errorf("format = %q", format)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+
+	godfather := rows[0]
+	if godfather.Line != 2 || godfather.ImdbId != "tt0068646" || godfather.Title != "The Godfather" || godfather.Year != 1972 {
+		t.Errorf("row 0 = %+v", godfather)
+	}
+	if godfather.Note == nil || *godfather.Note != 10 {
+		t.Errorf("row 0 note = %v, want 10", godfather.Note)
+	}
+	if godfather.WatchedAt == nil || !godfather.WatchedAt.Equal(time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("row 0 watched = %v, want 2023-04-01", godfather.WatchedAt)
+	}
+	if godfather.Series || godfather.Episode {
+		t.Errorf("row 0 is a movie: %+v", godfather)
+	}
+	if !rows[1].Series || rows[1].Episode {
+		t.Errorf("row 1 is a series: %+v", rows[1])
+	}
+	if !rows[2].Episode {
+		t.Errorf("row 2 is an episode: %+v", rows[2])
+	}
+}
+
+func TestParseExportLetterboxd(t *testing.T) {
+	tests := []struct {
+		name    string
+		export  string
+		format  string
+		note    float64
+		watched string
+	}{
+		{
+			name: "diary",
+			export: "Date,Name,Year,Letterboxd URI,Rating,Rewatch,Tags,Watched Date\n" +
+				"2024-02-03,The Matrix,1999,https://boxd.it/abc,3.5,Yes,,2024-02-01\n",
+			format:  FormatLetterboxdDiary,
+			note:    7,
+			watched: "2024-02-01",
+		},
+		{
+			name: "ratings",
+			export: "Date,Name,Year,Letterboxd URI,Rating\n" +
+				"2024-02-03,The Matrix,1999,https://boxd.it/abc,0.5\n",
+			format:  FormatLetterboxdRatings,
+			note:    1,
+			watched: "2024-02-03",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, rows, err := parseExport(strings.NewReader(tt.export))
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if format != tt.format || len(rows) != 1 {
+				t.Fatalf("format %q with %d rows", format, len(rows))
+			}
+			r := rows[0]
+			if r.ImdbId != "" || r.Title != "The Matrix" || r.Year != 1999 {
+				t.Errorf("row = %+v", r)
+			}
+			if r.Note == nil || *r.Note != tt.note {
+				t.Errorf("note = %v, want %v", r.Note, tt.note)
+			}
+			if r.WatchedAt == nil || r.WatchedAt.Format(time.DateOnly) != tt.watched {
+				t.Errorf("watched = %v, want %s", r.WatchedAt, tt.watched)
+			}
+		})
+	}
+}
+
+func TestParseExportLetterboxdWatchedHasNoNote(t *testing.T) {
+	_, rows, err := parseExport(strings.NewReader("Date,Name,Year,Letterboxd URI\n2024-02-03,Rocky,1976,https://boxd.it/x\n"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Note != nil || rows[0].WatchedAt == nil {
+		t.Errorf("rows = %+v", rows)
+	}
+}
+
+func TestParseExportRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		export string
+		want   error
+	}{
+		{"empty file", "", ErrUnknownFormat},
+		{"other CSV", "id,name\n1,x\n", ErrUnknownFormat},
+		{"broken quoting", "Const,Your Rating\n\"tt1,5\n", ErrInvalidCSV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := parseExport(strings.NewReader(tt.export)); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
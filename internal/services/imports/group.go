@@ -0,0 +1,249 @@
+package imports
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/generics"
+	"github.com/lealre/movies-backend/internal/services/comments"
+	"github.com/lealre/movies-backend/internal/services/exports"
+	"github.com/lealre/movies-backend/internal/services/groups"
+	"github.com/lealre/movies-backend/internal/services/ratings"
+	"github.com/lealre/movies-backend/internal/services/titles"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// isJSON reports whether r starts, past any whitespace or byte order mark,
+// with an object: a group export rather than a CSV, whose header row cannot.
+func isJSON(r *bufio.Reader) bool {
+	for {
+		c, _, err := r.ReadRune()
+		if err != nil {
+			return false
+		}
+		if c == '\ufeff' || c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			continue
+		}
+		_ = r.UnreadRune()
+		return c == '{'
+	}
+}
+
+// parseGroupExport reads a GET /groups/{id}/export?format=json document.
+func parseGroupExport(r io.Reader) (exports.GroupExport, error) {
+	var doc exports.GroupExport
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return exports.GroupExport{}, ErrInvalidGroupExport
+	}
+	if doc.Version == 0 {
+		return exports.GroupExport{}, ErrInvalidGroupExport
+	}
+	if doc.Version > exports.DocumentVersion {
+		return exports.GroupExport{}, ErrUnsupportedExportVersion
+	}
+	return doc, nil
+}
+
+// startGroupImport restores a group's JSON export into the importer's group,
+// title by title; a title's report line is its position in the export,
+// counting from 1.
+//
+// Steps performed for each title:
+//  1. Adds it to the catalogue if needed, then to the group.
+//  2. Marks it, or each of its seasons, watched as the export has it, with
+//     the watch date. Nothing marked watched here is marked unwatched.
+//  3. Restores the ratings and comments of the export's members who are
+//     members of this group too, matched by username: ids differ from one
+//     instance to the next. Only missing ones are created, with the import's
+//     time rather than the original one; a rating or comment a member already
+//     has is theirs, and is left as it is.
+//
+// Only the group's owner can import an export (groups.ErrGroupNotOwnedByUser):
+// the document is the uploader's word for what everyone else rated and said,
+// and it is posted under their names. The owner already decides who is in the
+// group; a member should not be able to speak for the others.
+//
+// A title is Partial when anything in it could not be restored: the
+// ratings and comments of someone who is not a member here, or a season the
+// catalogue no longer lists. The group's name and description are this
+// group's own and are left alone.
+func (im *importer) startGroupImport(ctx context.Context, jobs *Jobs, r io.Reader) (Job, error) {
+	group, err := im.db.GetGroupById(ctx, im.groupId, im.userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return Job{}, groups.ErrGroupNotFound
+		}
+		return Job{}, err
+	}
+	if group.OwnerId != im.userId {
+		return Job{}, groups.ErrGroupNotOwnedByUser
+	}
+
+	doc, err := parseGroupExport(r)
+	if err != nil {
+		return Job{}, err
+	}
+	if len(doc.Titles) == 0 {
+		return Job{}, ErrEmptyImport
+	}
+	if len(doc.Titles) > maxRows {
+		return Job{}, ErrImportTooLarge
+	}
+
+	users, err := im.db.GetUsersFromGroup(ctx, im.groupId, im.userId)
+	if err != nil {
+		return Job{}, err
+	}
+	members := make(map[string]string, len(users))
+	for _, u := range users {
+		members[u.Username] = u.Id
+	}
+
+	return im.start(jobs, FormatGroupExport, len(doc.Titles), func(ctx context.Context, i int) (RowReport, outcome) {
+		t := doc.Titles[i]
+		report := RowReport{Line: i + 1, Title: t.Name, Year: t.Year}
+		if report.Title == "" {
+			report.Title = t.TitleId
+		}
+		return report, im.restoreTitle(ctx, i+1, t, members)
+	}), nil
+}
+
+func (im *importer) restoreTitle(ctx context.Context, line int, t exports.ExportedTitle, members map[string]string) outcome {
+	title, err := im.ensureTitle(ctx, t.TitleId)
+	if err != nil {
+		return outcome{reason: im.reason(ctx, line, err)}
+	}
+	if _, err := im.db.AddGroupTitles(ctx, im.groupId, []string{title.Id}); err != nil {
+		return outcome{reason: im.reason(ctx, line, err)}
+	}
+
+	out := outcome{imported: true}
+	var problems []string
+	fail := func(err error) { problems = append(problems, im.reason(ctx, line, err)) }
+	series := title.Type == "tvSeries" || title.Type == "tvMiniSeries"
+	watched := true
+
+	if series {
+		for _, s := range t.Seasons {
+			if !s.Watched {
+				continue
+			}
+			if !hasSeason(title, s.Season) {
+				problems = append(problems, fmt.Sprintf("season %d is no longer listed for this title", s.Season))
+				continue
+			}
+			if _, err := im.db.UpdateGroupTitleWatchedForTVSeries(ctx, im.groupId, title.Id, &watched, flexibleDate(s.WatchedAt), s.Season, im.userId); err != nil {
+				fail(err)
+			}
+		}
+	} else if t.Watched {
+		if _, err := im.db.UpdateGroupTitleWatchedForMovie(ctx, im.groupId, title.Id, &watched, flexibleDate(t.WatchedAt)); err != nil {
+			fail(err)
+		}
+	}
+
+	absent := map[string]bool{}
+	for _, r := range t.Ratings {
+		userId, ok := members[r.Username]
+		if !ok || r.Username == "" {
+			absent[r.Username] = true
+			continue
+		}
+		if !series {
+			created, err := im.restoreRating(ctx, userId, ratings.NewRating{GroupId: im.groupId, TitleId: title.Id, Note: r.Note})
+			if err != nil {
+				fail(err)
+			}
+			out.rated = out.rated || created
+			continue
+		}
+		for _, s := range r.Seasons {
+			created, err := im.restoreRating(ctx, userId, ratings.NewRating{GroupId: im.groupId, TitleId: title.Id, Note: s.Note, Season: &s.Season})
+			if err != nil {
+				fail(err)
+			}
+			out.rated = out.rated || created
+		}
+	}
+
+	for _, c := range t.Comments {
+		userId, ok := members[c.Username]
+		if !ok || c.Username == "" {
+			absent[c.Username] = true
+			continue
+		}
+		if !series && c.Comment != nil {
+			if err := im.restoreComment(ctx, userId, title, nil, *c.Comment); err != nil {
+				fail(err)
+			}
+		}
+		if series {
+			for _, s := range c.Seasons {
+				if err := im.restoreComment(ctx, userId, title, &s.Season, s.Comment); err != nil {
+					fail(err)
+				}
+			}
+		}
+	}
+
+	if len(absent) > 0 {
+		names := make([]string, 0, len(absent))
+		for name := range absent {
+			if name == "" {
+				name = "a deleted account"
+			}
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		problems = append(problems, "not restored, as not members of this group: the ratings and comments of "+strings.Join(names, ", "))
+	}
+	out.reason = strings.Join(problems, "; ")
+	return out
+}
+
+// restoreRating creates userId's rating, of a movie or of one season of a
+// series, and reports whether it did. One they already have is kept.
+func (im *importer) restoreRating(ctx context.Context, userId string, rating ratings.NewRating) (bool, error) {
+	_, _, err := ratings.AddRating(im.db, ctx, rating, userId)
+	if errors.Is(err, ratings.ErrRatingAlreadyExists) || errors.Is(err, ratings.ErrSeasonRatingAlreadyExists) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// restoreComment creates userId's comment on a movie, or on one season of a
+// series. One they already have is kept.
+func (im *importer) restoreComment(ctx context.Context, userId string, title titles.Title, season *int, text string) error {
+	_, err := comments.AddComment(im.db, ctx, comments.NewComment{GroupId: im.groupId, TitleId: title.Id, Comment: text, Season: season}, userId, title)
+	if errors.Is(err, comments.ErrCommentAlreadyExists) || errors.Is(err, comments.ErrSeasonCommentAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+func hasSeason(title titles.Title, season int) bool {
+	for _, s := range title.Seasons {
+		if s.Season == strconv.Itoa(season) {
+			return true
+		}
+	}
+	return false
+}
+
+// flexibleDate passes a watch date on to the store; nil leaves the stored
+// one as it is.
+func flexibleDate(t *time.Time) *generics.FlexibleDate {
+	if t == nil {
+		return nil
+	}
+	return &generics.FlexibleDate{Time: t}
+}
@@ -0,0 +1,197 @@
+package imports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/generics"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/services/exports"
+	"github.com/lealre/movies-backend/internal/services/groups"
+	"github.com/lealre/movies-backend/internal/store"
+	"github.com/lealre/movies-backend/internal/titleprovider/titleref"
+)
+
+func (s *importStore) GetGroupById(_ context.Context, groupId, _ string) (models.Group, error) {
+	return models.Group{Id: groupId, OwnerId: s.owner}, nil
+}
+
+func (s *importStore) GetUsersFromGroup(context.Context, string, string) ([]models.User, error) {
+	return []models.User{{Id: "u1", Username: "ana"}}, nil
+}
+
+func (s *importStore) UpdateGroupTitleWatchedForTVSeries(_ context.Context, _, titleId string, watched *bool, watchedAt *generics.FlexibleDate, season int, _ string) (*models.GroupTitleItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item := s.group[titleId]
+	if item.SeasonsWatched == nil {
+		item.SeasonsWatched = &models.SeasonsWatched{}
+	}
+	(*item.SeasonsWatched)[strconv.Itoa(season)] = models.SeasonWatchedItem{Watched: *watched, WatchedAt: watchedAt.Time}
+	s.group[titleId] = item
+	return &item, nil
+}
+
+func (s *importStore) GetUserCommentByTitleId(_ context.Context, titleId, _, _ string) (models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.comments[titleId]
+	if !ok {
+		return models.Comment{}, store.ErrRecordNotFound
+	}
+	return c, nil
+}
+
+func (s *importStore) AddComment(_ context.Context, comment models.Comment) (models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.comments[comment.TitleId]; ok {
+		return models.Comment{}, store.ErrDuplicatedRecord
+	}
+	comment.Id = "c-" + comment.TitleId
+	s.comments[comment.TitleId] = comment
+	return comment, nil
+}
+
+func TestStartImportGroupExport(t *testing.T) {
+	db, provider := newImportFixture()
+	jobs := NewJobs(context.Background())
+	watchedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	classic := "Classic"
+
+	doc := exports.GroupExport{
+		Version: exports.DocumentVersion,
+		Titles: []exports.ExportedTitle{
+			{
+				TitleId: "tt0000002", Name: "The Godfather", Type: "movie", Watched: true, WatchedAt: &watchedAt,
+				Ratings: []exports.ExportedRating{
+					{Username: "ana", Note: 9.5},
+					{Username: "zoe", Note: 4},
+				},
+				Comments: []exports.ExportedComment{{Username: "ana", Comment: &classic}},
+			},
+			{
+				TitleId: "tt0000003", Name: "Breaking Bad", Type: "tvSeries",
+				Seasons: []exports.ExportedSeasonWatched{
+					{Season: 1, Watched: true, WatchedAt: &watchedAt},
+					{Season: 7, Watched: true},
+				},
+				Ratings: []exports.ExportedRating{
+					{Username: "ana", Note: 8.5, Seasons: []exports.ExportedSeasonNote{{Season: 1, Note: 8}, {Season: 2, Note: 9}}},
+				},
+			},
+			{TitleId: "tt9999999", Name: "Gone"},
+		},
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started, err := StartImport(db, provider, titleref.NewResolver(provider, nil), jobs, context.Background(), "g1", "u1", strings.NewReader("\n"+string(data)))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if started.Format != FormatGroupExport || started.Total != 3 {
+		t.Errorf("started = %+v", started)
+	}
+
+	job := waitForImport(t, jobs, started.Id)
+	if job.Imported != 2 || job.Rated != 2 {
+		t.Fatalf("job = %+v", job)
+	}
+	if len(job.Unmatched) != 1 || job.Unmatched[0].Line != 3 {
+		t.Errorf("unmatched = %+v, want the unknown title", job.Unmatched)
+	}
+	if len(job.Partial) != 2 || !strings.Contains(job.Partial[0].Reason, "zoe") || !strings.Contains(job.Partial[1].Reason, "season 7") {
+		t.Errorf("partial = %+v, want zoe's rating and season 7 reported", job.Partial)
+	}
+
+	if item := db.group["tt0000002"]; !item.Watched || !item.WatchedAt.Equal(watchedAt) {
+		t.Errorf("movie = %+v, want watched on %v", item, watchedAt)
+	}
+	if got := db.ratings["tt0000002"].Note; got != 9.5 {
+		t.Errorf("movie rating = %v, want 9.5", got)
+	}
+	if c := db.comments["tt0000002"]; c.Comment == nil || *c.Comment != "Classic" {
+		t.Errorf("movie comment = %+v", c)
+	}
+	series := db.group["tt0000003"]
+	if series.SeasonsWatched == nil || !(*series.SeasonsWatched)["1"].Watched {
+		t.Errorf("series = %+v, want season 1 watched", series)
+	}
+	seasons := db.ratings["tt0000003"].SeasonsRatings
+	if seasons == nil || len(*seasons) != 2 || (*seasons)["2"].Rating != 9 {
+		t.Errorf("series ratings = %+v, want seasons 1 and 2", seasons)
+	}
+
+	// Importing the same export again changes nothing and fails nothing new,
+	// and never overwrites what a member has since changed.
+	db.mu.Lock()
+	changed := db.ratings["tt0000002"]
+	changed.Note = 6
+	db.ratings["tt0000002"] = changed
+	db.mu.Unlock()
+
+	again, err := StartImport(db, provider, titleref.NewResolver(provider, nil), jobs, context.Background(), "g1", "u1", strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("again: %v", err)
+	}
+	if job := waitForImport(t, jobs, again.Id); job.Imported != 2 || job.Rated != 0 || len(job.Partial) != 2 {
+		t.Errorf("again = %+v", job)
+	}
+	if got := db.ratings["tt0000002"].Note; got != 6 {
+		t.Errorf("movie rating = %v, want the member's own 6 kept", got)
+	}
+}
+
+func TestStartImportGroupExportRejects(t *testing.T) {
+	db, provider := newImportFixture()
+	jobs := NewJobs(context.Background())
+	resolver := titleref.NewResolver(provider, nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		file string
+		want error
+	}{
+		{"broken JSON", `{"version":1,`, ErrInvalidGroupExport},
+		{"no version", `{"titles":[]}`, ErrInvalidGroupExport},
+		{"newer version", `{"version":99,"titles":[{"titleId":"tt0000002"}]}`, ErrUnsupportedExportVersion},
+		{"no titles", `{"version":1,"titles":[]}`, ErrEmptyImport},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := StartImport(db, provider, resolver, jobs, ctx, "g1", "u1", strings.NewReader(tt.file)); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartImportGroupExportNeedsTheOwner(t *testing.T) {
+	db, provider := newImportFixture()
+	db.owner = "u2"
+	jobs := NewJobs(context.Background())
+
+	_, err := StartImport(db, provider, titleref.NewResolver(provider, nil), jobs, context.Background(), "g1", "u1",
+		strings.NewReader(`{"version":1,"titles":[{"titleId":"tt0000002","ratings":[{"username":"ana","note":1}]}]}`))
+	if !errors.Is(err, groups.ErrGroupNotOwnedByUser) {
+		t.Fatalf("err = %v, want ErrGroupNotOwnedByUser", err)
+	}
+	if _, ok := db.ratings["tt0000002"]; ok {
+		t.Errorf("ratings = %+v, want nothing restored", db.ratings)
+	}
+
+	// A CSV is the member's own history, and still theirs to import.
+	if _, err := StartImport(db, provider, titleref.NewResolver(provider, nil), jobs, context.Background(), "g1", "u1",
+		strings.NewReader("Const,Your Rating\ntt0000002,5\n")); err != nil {
+		t.Errorf("csv err = %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+package imports
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+
+	"github.com/lealre/movies-backend/internal/generics"
+	"github.com/lealre/movies-backend/internal/logx"
+	"github.com/lealre/movies-backend/internal/services/comments"
+	"github.com/lealre/movies-backend/internal/services/groups"
+	"github.com/lealre/movies-backend/internal/services/ratings"
+	"github.com/lealre/movies-backend/internal/services/titles"
+	"github.com/lealre/movies-backend/internal/store"
+	"github.com/lealre/movies-backend/internal/titleprovider"
+	"github.com/lealre/movies-backend/internal/titleprovider/titleref"
+)
+
+// maxRows bounds one import. A lifetime of IMDb ratings is a few thousand
+// rows; more than this is not an export of one person's history.
+const maxRows = 10000
+
+// StartImport reads an export and imports it into groupId for userId in the
+// background, returning the job to poll with GetImport. file is either a
+// group's JSON export, which restores the group's titles, watch state,
+// ratings and comments (see importGroup), or one of the CSV exports
+// parseExport reads, imported as below.
+//
+// Steps performed for each CSV row, one row at a time so that an import
+// spends the provider's rate limit no faster than someone adding titles by
+// hand:
+//  1. Finds the row's IMDb ID: an IMDb export has it; a Letterboxd one is
+//     matched by title and year (see titleref.Resolver.ResolveTitle).
+//  2. Adds the title to the catalogue if needed, then to the group.
+//  3. For a movie, marks it watched on the row's date, and sets userId's
+//     rating to the row's note, creating or updating it.
+//
+// Series are added without a rating or watch date, reported as Partial: the
+// app rates and watches them per season, which neither export records.
+// Episodes are Unmatched, as a group holds titles, not episodes. Every step is
+// idempotent, so importing the same export twice changes nothing the second
+// time but ratings changed in between.
+//
+// Nothing is recorded on the activity stream: a thousand-row import would
+// bury the group's feed.
+//
+// Possible errors returned:
+//   - groups.ErrGroupNotFound: if the group does not exist or userId is not a
+//     member.
+//   - groups.ErrGroupNotOwnedByUser: if file is a group's JSON export and
+//     userId is not the group's owner.
+//   - ErrInvalidCSV, ErrUnknownFormat, ErrInvalidGroupExport,
+//     ErrUnsupportedExportVersion: if file is not an export it reads.
+//   - ErrEmptyImport, ErrImportTooLarge: if it has no rows, or over maxRows.
+func StartImport(
+	db store.Store,
+	provider titleprovider.Provider,
+	resolver *titleref.Resolver,
+	jobs *Jobs,
+	ctx context.Context,
+	groupId, userId string,
+	file io.Reader,
+) (Job, error) {
+	exists, err := db.GroupExists(ctx, groupId, userId)
+	if err != nil {
+		return Job{}, err
+	}
+	if !exists {
+		return Job{}, groups.ErrGroupNotFound
+	}
+
+	im := &importer{db: db, provider: provider, resolver: resolver, groupId: groupId, userId: userId}
+	buffered := bufio.NewReader(file)
+	if isJSON(buffered) {
+		return im.startGroupImport(ctx, jobs, buffered)
+	}
+
+	format, rows, err := parseExport(buffered)
+	if err != nil {
+		return Job{}, err
+	}
+	if len(rows) == 0 {
+		return Job{}, ErrEmptyImport
+	}
+	if len(rows) > maxRows {
+		return Job{}, ErrImportTooLarge
+	}
+
+	return im.start(jobs, format, len(rows), func(ctx context.Context, i int) (RowReport, outcome) {
+		r := rows[i]
+		report := RowReport{Line: r.Line, Title: r.Title, Year: r.Year}
+		if report.Title == "" {
+			report.Title = r.ImdbId
+		}
+		return report, im.importRow(ctx, r)
+	}), nil
+}
+
+// start runs item for each of total items in a job, one at a time, filling
+// in the job's counts and reports from what each returns. report's Reason is
+// taken from the outcome.
+func (im *importer) start(jobs *Jobs, format string, total int, item func(ctx context.Context, i int) (RowReport, outcome)) Job {
+	job := Job{GroupId: im.groupId, Format: format, Total: total}
+	return jobs.start(im.userId, job, func(ctx context.Context, update func(func(*Job))) error {
+		for i := range total {
+			report, out := item(ctx, i)
+			if ctx.Err() != nil {
+				return errors.New("the server stopped before the import finished; importing the file again resumes it")
+			}
+			report.Reason = out.reason
+			update(func(job *Job) {
+				job.Processed++
+				switch {
+				case !out.imported:
+					job.Unmatched = append(job.Unmatched, report)
+				case out.reason != "":
+					job.Imported++
+					job.Partial = append(job.Partial, report)
+				default:
+					job.Imported++
+				}
+				if out.rated {
+					job.Rated++
+				}
+			})
+		}
+		return nil
+	})
+}
+
+// GetImport returns the job jobId as it stands. Only the member who started
+// it sees it; to anyone else it is ErrImportNotFound.
+func GetImport(jobs *Jobs, groupId, userId, jobId string) (Job, error) {
+	return jobs.get(userId, groupId, jobId)
+}
+
+type importer struct {
+	db       store.Store
+	provider titleprovider.Provider
+	resolver *titleref.Resolver
+	groupId  string
+	userId   string
+}
+
+// outcome is what became of a row: whether its title is in the group, and
+// whether its rating was set. reason says why a row was not imported, or
+// only partly.
+type outcome struct {
+	imported bool
+	rated    bool
+	reason   string
+}
+
+func (im *importer) importRow(ctx context.Context, r row) outcome {
+	if r.Episode {
+		return outcome{reason: "episodes cannot be added to a group; add the series instead"}
+	}
+
+	titleId := r.ImdbId
+	if titleId == "" {
+		if r.Title == "" {
+			return outcome{reason: "the row names no title"}
+		}
+		id, err := im.resolver.ResolveTitle(ctx, titleref.SourceLetterboxd, r.Title, r.Year, "")
+		if err != nil {
+			return outcome{reason: im.reason(ctx, r.Line, err)}
+		}
+		titleId = id
+	}
+
+	title, err := im.ensureTitle(ctx, titleId)
+	if err != nil {
+		return outcome{reason: im.reason(ctx, r.Line, err)}
+	}
+	if _, err := im.db.AddGroupTitles(ctx, im.groupId, []string{titleId}); err != nil {
+		return outcome{reason: im.reason(ctx, r.Line, err)}
+	}
+
+	out := outcome{imported: true}
+	if title.Type == "tvSeries" || title.Type == "tvMiniSeries" || r.Series {
+		out.reason = "series are rated and watched per season; added without a rating or watch date"
+		return out
+	}
+
+	if r.Note != nil || r.WatchedAt != nil {
+		watched := true
+		var watchedAt *generics.FlexibleDate
+		if r.WatchedAt != nil {
+			watchedAt = &generics.FlexibleDate{Time: r.WatchedAt}
+		}
+		if _, err := im.db.UpdateGroupTitleWatchedForMovie(ctx, im.groupId, titleId, &watched, watchedAt); err != nil {
+			out.reason = im.reason(ctx, r.Line, err)
+			return out
+		}
+	}
+
+	if r.Note != nil {
+		if err := im.rate(ctx, im.userId, titleId, *r.Note); err != nil {
+			out.reason = im.reason(ctx, r.Line, err)
+			return out
+		}
+		out.rated = true
+	}
+	return out
+}
+
+// ensureTitle returns the title, fetching it into the catalogue first if it
+// is not there yet.
+func (im *importer) ensureTitle(ctx context.Context, titleId string) (titles.Title, error) {
+	exists, err := titles.TitleExists(im.db, ctx, titleId)
+	if err != nil {
+		return titles.Title{}, err
+	}
+	if exists {
+		return titles.GetTitleById(im.db, ctx, titleId)
+	}
+	return titles.AddNewTitle(im.db, im.provider, ctx, titleId)
+}
+
+// rate sets userId's rating of a movie to note, whether or not they had
+// rated it already.
+func (im *importer) rate(ctx context.Context, userId, titleId string, note float64) error {
+	existing, err := im.db.GetRatingByUserIdAndTitleId(ctx, userId, titleId, im.groupId)
+	if errors.Is(err, store.ErrRecordNotFound) {
+		_, _, err = ratings.AddRating(im.db, ctx, ratings.NewRating{GroupId: im.groupId, TitleId: titleId, Note: note}, userId)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if existing.Note == note {
+		return nil
+	}
+	_, _, err = ratings.UpdateRating(im.db, ctx, existing.Id, userId, ratings.UpdateRatingRequest{Note: note})
+	return err
+}
+
+// reason words err for the row's report. Errors the user can act on are shown
+// as they are; the rest are logged, and the row reported as not saved.
+func (im *importer) reason(ctx context.Context, line int, err error) string {
+	var refErr *titleref.Error
+	switch {
+	case errors.As(err, &refErr):
+		return refErr.Error()
+	case errors.Is(err, titles.ErrTitleNotFound):
+		return "no title has this IMDb ID"
+	case errors.Is(err, titles.ErrProviderQuotaExhausted):
+		return err.Error()
+	}
+	if _, ok := ratings.ErrorMap[err]; ok {
+		return err.Error()
+	}
+	if _, ok := comments.ErrorMap[err]; ok {
+		return err.Error()
+	}
+	logx.FromContext(ctx).Printf("ERROR: import line %d into group %s: %v", line, im.groupId, err)
+	return "the row could not be imported; importing the file again retries it"
+}
@@ -0,0 +1,336 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/generics"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/services/groups"
+	"github.com/lealre/movies-backend/internal/store"
+	"github.com/lealre/movies-backend/internal/titleprovider"
+	"github.com/lealre/movies-backend/internal/titleprovider/titleref"
+)
+
+// importStore satisfies store.Store by embedding the interface, so only what
+// an import calls needs a body. It holds one group, its member u1, the
+// catalogue, and u1's ratings and comments by title.
+type importStore struct {
+	store.Store
+	owner     string
+	mu        sync.Mutex
+	catalogue map[string]models.Title
+	group     map[string]models.GroupTitleItem
+	ratings   map[string]models.UserRating
+	comments  map[string]models.Comment
+}
+
+func (s *importStore) GroupExists(_ context.Context, groupId, userId string) (bool, error) {
+	return groupId == "g1" && userId == "u1", nil
+}
+
+func (s *importStore) TitleExists(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.catalogue[id]
+	return ok, nil
+}
+
+func (s *importStore) GetTitleById(_ context.Context, id string) (models.Title, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.catalogue[id]
+	if !ok {
+		return models.Title{}, store.ErrRecordNotFound
+	}
+	return t, nil
+}
+
+func (s *importStore) AddTitle(_ context.Context, title models.Title) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.catalogue[title.ID] = title
+	return nil
+}
+
+func (s *importStore) AddGroupTitles(_ context.Context, _ string, titleIds []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := []string{}
+	for _, id := range titleIds {
+		if _, ok := s.group[id]; !ok {
+			s.group[id] = models.GroupTitleItem{TitleId: id}
+			added = append(added, id)
+		}
+	}
+	return added, nil
+}
+
+func (s *importStore) UpdateGroupTitleWatchedForMovie(_ context.Context, _, titleId string, watched *bool, watchedAt *generics.FlexibleDate) (*models.GroupTitleItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item := s.group[titleId]
+	item.Watched = *watched
+	if watchedAt != nil {
+		item.WatchedAt = watchedAt.Time
+	}
+	s.group[titleId] = item
+	return &item, nil
+}
+
+func (s *importStore) GetRatingByUserIdAndTitleId(_ context.Context, _, titleId, _ string) (models.UserRating, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.ratings[titleId]
+	if !ok {
+		return models.UserRating{}, store.ErrRecordNotFound
+	}
+	return r, nil
+}
+
+func (s *importStore) GetRatingById(_ context.Context, ratingId, _ string) (models.UserRating, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.ratings {
+		if r.Id == ratingId {
+			return r, nil
+		}
+	}
+	return models.UserRating{}, store.ErrRecordNotFound
+}
+
+func (s *importStore) AddRating(_ context.Context, rating models.UserRating) (models.UserRating, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rating.Id = "r-" + rating.TitleId
+	s.ratings[rating.TitleId] = rating
+	return rating, nil
+}
+
+func (s *importStore) UpdateRating(_ context.Context, rating models.UserRating, _ string) (models.UserRating, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for titleId, r := range s.ratings {
+		if r.Id == rating.Id {
+			r.Note = rating.Note
+			r.SeasonsRatings = rating.SeasonsRatings
+			s.ratings[titleId] = r
+			return r, nil
+		}
+	}
+	return models.UserRating{}, store.ErrRecordNotFound
+}
+
+// importProvider serves a fixed set of titles, and answers every search with
+// all of them, leaving the matching to the resolver.
+type importProvider map[string]titleprovider.Title
+
+func (p importProvider) Name() string { return "fake" }
+
+func (p importProvider) GetTitle(_ context.Context, id string) (*titleprovider.Title, error) {
+	t, ok := p[id]
+	if !ok {
+		return nil, titleprovider.ErrTitleNotFound
+	}
+	return &t, nil
+}
+
+func (p importProvider) SearchTitles(_ context.Context, _ string, limit int) ([]titleprovider.SearchItem, error) {
+	out := []titleprovider.SearchItem{}
+	for _, t := range p {
+		if len(out) < limit {
+			out = append(out, titleprovider.SearchItem{ID: t.ID, Type: t.Type, PrimaryTitle: t.PrimaryTitle, StartYear: t.StartYear})
+		}
+	}
+	return out, nil
+}
+
+func newImportFixture() (*importStore, importProvider) {
+	db := &importStore{
+		owner: "u1",
+		catalogue: map[string]models.Title{
+			"tt0000001": {ID: "tt0000001", Type: "movie", PrimaryTitle: "In Catalogue", StartYear: 2001},
+		},
+		group: map[string]models.GroupTitleItem{},
+		ratings: map[string]models.UserRating{
+			"tt0000001": {Id: "r-tt0000001", TitleId: "tt0000001", Note: 5},
+		},
+		comments: map[string]models.Comment{},
+	}
+	provider := importProvider{
+		"tt0000002": {ID: "tt0000002", Type: "movie", PrimaryTitle: "The Godfather", StartYear: 1972},
+		"tt0000003": {ID: "tt0000003", Type: "tvSeries", PrimaryTitle: "Breaking Bad", StartYear: 2008, Seasons: []titleprovider.Season{{Season: "1"}, {Season: "2"}}},
+		"tt0000004": {ID: "tt0000004", Type: "movie", PrimaryTitle: "Twin", StartYear: 1990},
+		"tt0000005": {ID: "tt0000005", Type: "movie", PrimaryTitle: "Twin", StartYear: 1995},
+	}
+	return db, provider
+}
+
+// waitForImport polls the job until it is no longer running.
+func waitForImport(t *testing.T, jobs *Jobs, jobId string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := GetImport(jobs, "g1", "u1", jobId)
+		if err != nil {
+			t.Fatalf("GetImport: %v", err)
+		}
+		if job.Status != StatusRunning {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("import did not finish")
+	return Job{}
+}
+
+func TestStartImportIMDb(t *testing.T) {
+	db, provider := newImportFixture()
+	jobs := NewJobs(context.Background())
+	export := "Const,Your Rating,Date Rated,Title,Title Type,Year\n" +
+		"tt0000001,8,2023-04-01,In Catalogue,Movie,2001\n" +
+		"tt0000002,10,2023-05-02,The Godfather,Movie,1972\n" +
+		"tt0000003,9,2023-06-03,Breaking Bad,TV Series,2008\n" +
+		"tt0000009,7,2023-06-03,Pilot,TV Episode,2008\n" +
+		"tt9999999,6,2023-06-03,Nowhere,Movie,2000\n"
+
+	started, err := StartImport(db, provider, titleref.NewResolver(provider, nil), jobs, context.Background(), "g1", "u1", strings.NewReader(export))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if started.Format != FormatIMDbRatings || started.Total != 5 {
+		t.Errorf("started = %+v", started)
+	}
+
+	job := waitForImport(t, jobs, started.Id)
+	if job.Status != StatusDone || job.Processed != 5 || job.Imported != 3 || job.Rated != 2 {
+		t.Fatalf("job = %+v", job)
+	}
+	if len(job.Partial) != 1 || job.Partial[0].Line != 4 {
+		t.Errorf("partial = %+v, want the series on line 4", job.Partial)
+	}
+	if len(job.Unmatched) != 2 || job.Unmatched[0].Line != 5 || job.Unmatched[1].Line != 6 {
+		t.Errorf("unmatched = %+v, want the episode and the unknown id", job.Unmatched)
+	}
+
+	if got := db.ratings["tt0000001"].Note; got != 8 {
+		t.Errorf("existing rating = %v, want updated to 8", got)
+	}
+	if got := db.ratings["tt0000002"].Note; got != 10 {
+		t.Errorf("new rating = %v, want 10", got)
+	}
+	if _, ok := db.ratings["tt0000003"]; ok {
+		t.Error("a series must not be rated")
+	}
+	godfather := db.group["tt0000002"]
+	if !godfather.Watched || godfather.WatchedAt == nil || godfather.WatchedAt.Format(time.DateOnly) != "2023-05-02" {
+		t.Errorf("group title = %+v, want watched on 2023-05-02", godfather)
+	}
+	if _, ok := db.group["tt0000003"]; !ok {
+		t.Error("the series must still be added to the group")
+	}
+}
+
+func TestStartImportLetterboxd(t *testing.T) {
+	db, provider := newImportFixture()
+	jobs := NewJobs(context.Background())
+	export := "Date,Name,Year,Letterboxd URI,Rating,Rewatch,Tags,Watched Date\n" +
+		"2024-01-02,The Godfather,1972,https://boxd.it/a,4.5,,,2024-01-01\n" +
+		"2024-01-02,Twin,,https://boxd.it/b,3,,,2024-01-01\n" +
+		"2024-01-02,Twin,1995,https://boxd.it/c,,,,2024-01-01\n"
+
+	started, err := StartImport(db, provider, titleref.NewResolver(provider, nil), jobs, context.Background(), "g1", "u1", strings.NewReader(export))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	job := waitForImport(t, jobs, started.Id)
+	if job.Imported != 2 || job.Rated != 1 {
+		t.Fatalf("job = %+v", job)
+	}
+	if len(job.Unmatched) != 1 || job.Unmatched[0].Title != "Twin" || job.Unmatched[0].Reason == "" {
+		t.Errorf("unmatched = %+v, want the ambiguous Twin", job.Unmatched)
+	}
+	if got := db.ratings["tt0000002"].Note; got != 9 {
+		t.Errorf("rating = %v, want 4.5 stars as 9", got)
+	}
+	if item := db.group["tt0000005"]; !item.Watched {
+		t.Errorf("Twin (1995) = %+v, want watched without a rating", item)
+	}
+}
+
+func TestStartImportRejects(t *testing.T) {
+	db, provider := newImportFixture()
+	jobs := NewJobs(context.Background())
+	resolver := titleref.NewResolver(provider, nil)
+	ctx := context.Background()
+
+	if _, err := StartImport(db, provider, resolver, jobs, ctx, "g2", "u1", strings.NewReader("Const,Your Rating\n")); !errors.Is(err, groups.ErrGroupNotFound) {
+		t.Errorf("not a member: err = %v", err)
+	}
+	if _, err := StartImport(db, provider, resolver, jobs, ctx, "g1", "u1", strings.NewReader("Const,Your Rating\n")); !errors.Is(err, ErrEmptyImport) {
+		t.Errorf("no rows: err = %v", err)
+	}
+	if _, err := GetImport(jobs, "g1", "u1", "nope"); !errors.Is(err, ErrImportNotFound) {
+		t.Errorf("unknown job: err = %v", err)
+	}
+}
+
+func TestJobsOwnerOnlyAndSweep(t *testing.T) {
+	jobs := NewJobs(context.Background())
+	now := time.Now()
+	jobs.now = func() time.Time { return now }
+
+	done := make(chan struct{})
+	started := jobs.start("u1", Job{GroupId: "g1"}, func(context.Context, func(func(*Job))) error {
+		defer close(done)
+		return nil
+	})
+	<-done
+
+	if _, err := jobs.get("u2", "g1", started.Id); !errors.Is(err, ErrImportNotFound) {
+		t.Errorf("another member: err = %v", err)
+	}
+	if _, err := jobs.get("u1", "g2", started.Id); !errors.Is(err, ErrImportNotFound) {
+		t.Errorf("another group: err = %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := jobs.get("u1", "g1", started.Id)
+		if err != nil {
+			t.Fatalf("owner: err = %v", err)
+		}
+		if job.Status == StatusDone {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("job did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	now = now.Add(jobRetention + time.Minute)
+	if _, err := jobs.get("u1", "g1", started.Id); !errors.Is(err, ErrImportNotFound) {
+		t.Errorf("after retention: err = %v", err)
+	}
+}
+
+func TestJobsCancelledContextFails(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	db, provider := newImportFixture()
+	jobs := NewJobs(ctx)
+	export := "Const,Your Rating,Date Rated,Title,Title Type,Year\ntt0000002,10,2023-05-02,The Godfather,Movie,1972\n"
+
+	started, err := StartImport(db, provider, titleref.NewResolver(provider, nil), jobs, context.Background(), "g1", "u1", strings.NewReader(export))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	job := waitForImport(t, jobs, started.Id)
+	if job.Status != StatusFailed || job.Error == "" {
+		t.Errorf("job = %+v, want failed", job)
+	}
+}
@@ -0,0 +1,105 @@
+package imports
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// jobRetention is how long a finished import stays pollable.
+const jobRetention = time.Hour
+
+type jobEntry struct {
+	userId string
+	job    Job
+}
+
+// Jobs runs imports in the background and keeps their progress for polling.
+//
+// Deliberately in-memory, like the activity stream's tickets: a job is worth
+// keeping for as long as someone is watching its progress bar, not across a
+// restart. A restart stops a running import part way, and the rows it had
+// already imported stay imported; importing the same export again finishes
+// the job, as every step of a row is idempotent.
+type Jobs struct {
+	ctx  context.Context
+	mu   sync.Mutex
+	jobs map[string]*jobEntry
+	now  func() time.Time
+}
+
+// NewJobs returns a registry whose imports run on ctx, not on the request that
+// started them: cancelling ctx (the server shutting down) stops them all.
+func NewJobs(ctx context.Context) *Jobs {
+	return &Jobs{ctx: ctx, jobs: map[string]*jobEntry{}, now: time.Now}
+}
+
+// start registers a job and runs it in a goroutine, returning it as first
+// seen. run reports progress through update, which applies a change to the
+// job under the registry's lock.
+func (j *Jobs) start(userId string, job Job, run func(ctx context.Context, update func(func(*Job))) error) Job {
+	job.Id = uuid.NewString()
+	job.Status = StatusRunning
+	job.StartedAt = j.now()
+
+	j.mu.Lock()
+	j.sweepLocked()
+	entry := &jobEntry{userId: userId, job: job}
+	j.jobs[job.Id] = entry
+	snapshot := entry.job.snapshot()
+	j.mu.Unlock()
+
+	update := func(change func(*Job)) {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		change(&entry.job)
+	}
+	go func() {
+		err := run(j.ctx, update)
+		update(func(job *Job) {
+			finished := j.now()
+			job.FinishedAt = &finished
+			job.Status = StatusDone
+			if err != nil {
+				job.Status = StatusFailed
+				job.Error = err.Error()
+			}
+		})
+	}()
+	return snapshot
+}
+
+// get returns the job with jobId as it stands, if userId started it for
+// groupId; anyone else's job, like one that never existed, is
+// ErrImportNotFound.
+func (j *Jobs) get(userId, groupId, jobId string) (Job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.sweepLocked()
+
+	entry, ok := j.jobs[jobId]
+	if !ok || entry.userId != userId || entry.job.GroupId != groupId {
+		return Job{}, ErrImportNotFound
+	}
+	return entry.job.snapshot(), nil
+}
+
+// sweepLocked drops jobs finished more than jobRetention ago. Callers hold mu.
+func (j *Jobs) sweepLocked() {
+	cutoff := j.now().Add(-jobRetention)
+	for id, entry := range j.jobs {
+		if entry.job.FinishedAt != nil && entry.job.FinishedAt.Before(cutoff) {
+			delete(j.jobs, id)
+		}
+	}
+}
+
+// snapshot copies job so the caller can read it while the import goes on
+// appending to the original's reports.
+func (job Job) snapshot() Job {
+	job.Unmatched = append([]RowReport{}, job.Unmatched...)
+	job.Partial = append([]RowReport{}, job.Partial...)
+	return job
+}
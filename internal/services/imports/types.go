@@ -0,0 +1,40 @@
+package imports
+
+import "time"
+
+// What an import job is doing.
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// Job is an import as its owner polls it. Processed climbs to Total while it
+// runs; Imported counts rows whose title is now in the group, Rated those
+// whose rating was set too. Unmatched lists the rows nothing was imported
+// for, and Partial the rows whose title was added but whose rating or watch
+// date could not be (series, whose ratings and watches are per season).
+type Job struct {
+	Id         string      `json:"id"`
+	GroupId    string      `json:"groupId"`
+	Format     string      `json:"format"`
+	Status     string      `json:"status"`
+	Total      int         `json:"total"`
+	Processed  int         `json:"processed"`
+	Imported   int         `json:"imported"`
+	Rated      int         `json:"rated"`
+	Unmatched  []RowReport `json:"unmatched"`
+	Partial    []RowReport `json:"partial"`
+	Error      string      `json:"error,omitempty"`
+	StartedAt  time.Time   `json:"startedAt"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+}
+
+// RowReport is one row of the export that was not fully imported. Line is
+// its line in the file, counting the header as line 1.
+type RowReport struct {
+	Line   int    `json:"line"`
+	Title  string `json:"title"`
+	Year   int    `json:"year,omitempty"`
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,28 @@
+package imports
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrImportFileRequired       = errors.New("an export is required as the file field")
+	ErrImportTooLarge           = errors.New("the export is too large to import")
+	ErrInvalidCSV               = errors.New("the file is not a valid CSV")
+	ErrUnknownFormat            = errors.New("unrecognised export: expected a group's JSON export, an IMDb ratings.csv or a Letterboxd diary.csv or ratings.csv")
+	ErrInvalidGroupExport       = errors.New("the file is not a valid group export")
+	ErrUnsupportedExportVersion = errors.New("the group export was made by a newer version and cannot be imported")
+	ErrEmptyImport              = errors.New("the export has no rows")
+	ErrImportNotFound           = errors.New("import not found")
+)
+
+var ErrorMap = map[error]int{
+	ErrImportFileRequired:       http.StatusBadRequest,
+	ErrImportTooLarge:           http.StatusRequestEntityTooLarge,
+	ErrInvalidCSV:               http.StatusBadRequest,
+	ErrUnknownFormat:            http.StatusBadRequest,
+	ErrInvalidGroupExport:       http.StatusBadRequest,
+	ErrUnsupportedExportVersion: http.StatusBadRequest,
+	ErrEmptyImport:              http.StatusBadRequest,
+	ErrImportNotFound:           http.StatusNotFound,
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"math"
+	"sort"
 	"strconv"
 	"time"
 
@@ -640,3 +641,225 @@ func DeleteRatingSeason(db store.Store, ctx context.Context, ratingId, userId st
 
 	return nil
 }
+
+// maxDisagreements caps the titles listed per member pair. The list answers
+// "where do we differ most", which a handful of entries does; past that it is
+// a dump of every shared note.
+const maxDisagreements = 5
+
+// GetGroupCompatibility compares every pair of current members of groupId over
+// the notes both of them gave inside the group.
+//
+// The group's title ids come from the group itself and its ratings from
+// GetRatingsByTitleIds, the same group-scoped batch read title detail uses, so
+// seasons arrive assembled and per-season notes can be compared one by one.
+// Ratings left by someone who has since left the group are ignored: the
+// answer is about the people in it now.
+//
+// Possible errors returned:
+//   - ErrGroupNotFound: if the group does not exist or userId is not a member.
+//   - Any error returned by the store reads.
+func GetGroupCompatibility(db store.Store, ctx context.Context, groupId, userId string) (GroupCompatibility, error) {
+	group, err := db.GetGroupById(ctx, groupId, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return GroupCompatibility{}, ErrGroupNotFound
+		}
+		return GroupCompatibility{}, err
+	}
+
+	members, err := db.GetUsersFromGroup(ctx, groupId, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return GroupCompatibility{}, ErrGroupNotFound
+		}
+		return GroupCompatibility{}, err
+	}
+
+	titleIds := make([]string, 0, len(group.Titles))
+	for titleId := range group.Titles {
+		titleIds = append(titleIds, titleId)
+	}
+
+	var ratingsDb []models.UserRating
+	if len(titleIds) > 0 {
+		ratingsDb, err = db.GetRatingsByTitleIds(ctx, titleIds, groupId)
+		if err != nil {
+			return GroupCompatibility{}, err
+		}
+	}
+
+	pairs := compareMembers(members, notesByUser(ratingsDb))
+
+	// Names only for the titles that made a disagreement list.
+	names, err := disagreementTitleNames(db, ctx, pairs)
+	if err != nil {
+		return GroupCompatibility{}, err
+	}
+	for i := range pairs {
+		for j := range pairs[i].Disagreements {
+			pairs[i].Disagreements[j].PrimaryTitle = names[pairs[i].Disagreements[j].TitleId]
+		}
+	}
+
+	return GroupCompatibility{Pairs: pairs}, nil
+}
+
+// noteKey identifies one comparable note: a movie's note (season 0) or one
+// season rating of a series.
+type noteKey struct {
+	titleId string
+	season  int
+}
+
+// notesByUser flattens ratings into each user's comparable notes. A rating
+// with season ratings contributes those and not its overall note, which is
+// only their mean; one without contributes its note.
+func notesByUser(ratingsDb []models.UserRating) map[string]map[noteKey]float64 {
+	notes := map[string]map[noteKey]float64{}
+	for _, rating := range ratingsDb {
+		userNotes, ok := notes[rating.UserId]
+		if !ok {
+			userNotes = map[noteKey]float64{}
+			notes[rating.UserId] = userNotes
+		}
+
+		if rating.SeasonsRatings == nil || len(*rating.SeasonsRatings) == 0 {
+			userNotes[noteKey{titleId: rating.TitleId}] = rating.Note
+			continue
+		}
+		for seasonKey, item := range *rating.SeasonsRatings {
+			season, err := strconv.Atoi(seasonKey)
+			if err != nil || season <= 0 {
+				continue
+			}
+			userNotes[noteKey{titleId: rating.TitleId, season: season}] = item.Rating
+		}
+	}
+	return notes
+}
+
+// compareMembers builds one MemberCompatibility per pair of members, pairs
+// ordered by (userA.Id, userB.Id) with userA.Id < userB.Id.
+func compareMembers(members []models.User, notes map[string]map[noteKey]float64) []MemberCompatibility {
+	sorted := make([]models.User, len(members))
+	copy(sorted, members)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Id < sorted[j].Id })
+
+	pairs := []MemberCompatibility{}
+	for i := 0; i < len(sorted); i++ {
+		for j := i + 1; j < len(sorted); j++ {
+			pair := comparePair(notes[sorted[i].Id], notes[sorted[j].Id])
+			pair.UserA = CompatibilityMember{Id: sorted[i].Id, Name: sorted[i].Name}
+			pair.UserB = CompatibilityMember{Id: sorted[j].Id, Name: sorted[j].Name}
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// comparePair computes the agreement statistics over the notes a and b share.
+// Disagreements lists shared notes that differ, largest difference first, then
+// by title id and season so equal differences come back in a stable order.
+func comparePair(a, b map[noteKey]float64) MemberCompatibility {
+	var shared []noteKey
+	for key := range a {
+		if _, ok := b[key]; ok {
+			shared = append(shared, key)
+		}
+	}
+
+	result := MemberCompatibility{
+		Overlap:       len(shared),
+		Disagreements: []RatingDisagreement{},
+	}
+	if len(shared) == 0 {
+		return result
+	}
+
+	sort.Slice(shared, func(i, j int) bool {
+		di, dj := math.Abs(a[shared[i]]-b[shared[i]]), math.Abs(a[shared[j]]-b[shared[j]])
+		if di != dj {
+			return di > dj
+		}
+		if shared[i].titleId != shared[j].titleId {
+			return shared[i].titleId < shared[j].titleId
+		}
+		return shared[i].season < shared[j].season
+	})
+
+	var sumA, sumB, sumDiff float64
+	for _, key := range shared {
+		sumA += a[key]
+		sumB += b[key]
+		sumDiff += math.Abs(a[key] - b[key])
+	}
+	n := float64(len(shared))
+	mad := math.Round(sumDiff/n*100) / 100
+	result.MeanAbsoluteDifference = &mad
+
+	if len(shared) >= 2 {
+		meanA, meanB := sumA/n, sumB/n
+		var cov, varA, varB float64
+		for _, key := range shared {
+			devA, devB := a[key]-meanA, b[key]-meanB
+			cov += devA * devB
+			varA += devA * devA
+			varB += devB * devB
+		}
+		if varA > 0 && varB > 0 {
+			r := math.Round(cov/math.Sqrt(varA*varB)*100) / 100
+			result.Correlation = &r
+		}
+	}
+
+	for _, key := range shared {
+		if len(result.Disagreements) == maxDisagreements {
+			break
+		}
+		diff := roundToOneDecimal(math.Abs(a[key] - b[key]))
+		if diff == 0 {
+			break
+		}
+		disagreement := RatingDisagreement{
+			TitleId:    key.titleId,
+			NoteA:      a[key],
+			NoteB:      b[key],
+			Difference: diff,
+		}
+		if key.season > 0 {
+			season := key.season
+			disagreement.Season = &season
+		}
+		result.Disagreements = append(result.Disagreements, disagreement)
+	}
+
+	return result
+}
+
+func disagreementTitleNames(db store.Store, ctx context.Context, pairs []MemberCompatibility) (map[string]string, error) {
+	seen := map[string]bool{}
+	var ids []string
+	for _, pair := range pairs {
+		for _, disagreement := range pair.Disagreements {
+			if !seen[disagreement.TitleId] {
+				seen[disagreement.TitleId] = true
+				ids = append(ids, disagreement.TitleId)
+			}
+		}
+	}
+
+	names := map[string]string{}
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	titlesDb, err := db.GetTitlesByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, title := range titlesDb {
+		names[title.ID] = title.PrimaryTitle
+	}
+	return names, nil
+}
@@ -80,3 +80,109 @@ func TestRoundToOneDecimal(t *testing.T) {
 		}
 	}
 }
+
+func TestCompareMembers(t *testing.T) {
+	movie := func(id string) noteKey { return noteKey{titleId: id} }
+	season := func(id string, n int) noteKey { return noteKey{titleId: id, season: n} }
+
+	t.Run("members who rate alike correlate and differ little", func(t *testing.T) {
+		a := map[noteKey]float64{movie("tt1"): 9, movie("tt2"): 5, movie("tt3"): 7}
+		b := map[noteKey]float64{movie("tt1"): 8, movie("tt2"): 4, movie("tt3"): 6, movie("tt4"): 10}
+
+		pair := comparePair(a, b)
+
+		if pair.Overlap != 3 {
+			t.Errorf("overlap = %d, want 3", pair.Overlap)
+		}
+		if pair.Correlation == nil || *pair.Correlation != 1 {
+			t.Errorf("correlation = %v, want 1", pair.Correlation)
+		}
+		if pair.MeanAbsoluteDifference == nil || *pair.MeanAbsoluteDifference != 1 {
+			t.Errorf("mean absolute difference = %v, want 1", pair.MeanAbsoluteDifference)
+		}
+	})
+
+	t.Run("season ratings are compared one by one", func(t *testing.T) {
+		a := map[noteKey]float64{season("tt-show", 1): 9, season("tt-show", 2): 3, movie("tt1"): 6}
+		b := map[noteKey]float64{season("tt-show", 1): 9, season("tt-show", 2): 9, movie("tt1"): 7}
+
+		pair := comparePair(a, b)
+
+		if pair.Overlap != 3 {
+			t.Fatalf("overlap = %d, want 3", pair.Overlap)
+		}
+		if len(pair.Disagreements) != 2 {
+			t.Fatalf("disagreements = %+v, want 2 (equal notes are not listed)", pair.Disagreements)
+		}
+		first := pair.Disagreements[0]
+		if first.TitleId != "tt-show" || first.Season == nil || *first.Season != 2 || first.Difference != 6 {
+			t.Errorf("first disagreement = %+v, want season 2 of tt-show by 6", first)
+		}
+		if pair.Disagreements[1].Season != nil {
+			t.Errorf("second disagreement = %+v, want the movie with no season", pair.Disagreements[1])
+		}
+	})
+
+	t.Run("statistics are nil where they are undefined", func(t *testing.T) {
+		none := comparePair(map[noteKey]float64{movie("tt1"): 5}, map[noteKey]float64{movie("tt2"): 5})
+		if none.Overlap != 0 || none.Correlation != nil || none.MeanAbsoluteDifference != nil {
+			t.Errorf("no overlap = %+v, want zero overlap and nil statistics", none)
+		}
+		if none.Disagreements == nil {
+			t.Error("disagreements = nil, want an empty, non-nil slice")
+		}
+
+		// One member gave every shared title the same note: r divides by zero.
+		flat := comparePair(
+			map[noteKey]float64{movie("tt1"): 7, movie("tt2"): 7},
+			map[noteKey]float64{movie("tt1"): 2, movie("tt2"): 9},
+		)
+		if flat.Correlation != nil {
+			t.Errorf("correlation = %v, want nil", *flat.Correlation)
+		}
+		if flat.MeanAbsoluteDifference == nil || *flat.MeanAbsoluteDifference != 3.5 {
+			t.Errorf("mean absolute difference = %v, want 3.5", flat.MeanAbsoluteDifference)
+		}
+	})
+
+	t.Run("disagreements are capped and ties broken by title id", func(t *testing.T) {
+		a, b := map[noteKey]float64{}, map[noteKey]float64{}
+		for _, id := range []string{"tt7", "tt3", "tt5", "tt1", "tt6", "tt2", "tt4"} {
+			a[movie(id)], b[movie(id)] = 2, 8
+		}
+
+		pair := comparePair(a, b)
+
+		if len(pair.Disagreements) != maxDisagreements {
+			t.Fatalf("disagreements = %d, want %d", len(pair.Disagreements), maxDisagreements)
+		}
+		for i, want := range []string{"tt1", "tt2", "tt3", "tt4", "tt5"} {
+			if got := pair.Disagreements[i].TitleId; got != want {
+				t.Errorf("disagreement %d = %s, want %s", i, got, want)
+			}
+		}
+	})
+
+	t.Run("pairs are ordered by user id and a series note is its seasons", func(t *testing.T) {
+		seasons := models.SeasonsRatings{"1": {Rating: 4}, "2": {Rating: 8}}
+		notes := notesByUser([]models.UserRating{
+			{UserId: "u-b", TitleId: "tt-show", Note: 6, SeasonsRatings: &seasons},
+			{UserId: "u-a", TitleId: "tt-show", Note: 6},
+		})
+		members := []models.User{{Id: "u-c"}, {Id: "u-b"}, {Id: "u-a"}}
+
+		pairs := compareMembers(members, notes)
+
+		if len(pairs) != 3 {
+			t.Fatalf("pairs = %d, want 3", len(pairs))
+		}
+		if pairs[0].UserA.Id != "u-a" || pairs[0].UserB.Id != "u-b" || pairs[2].UserA.Id != "u-b" {
+			t.Errorf("pairs = %+v, want (u-a,u-b) (u-a,u-c) (u-b,u-c)", pairs)
+		}
+		// u-a's overall note and u-b's season notes are not the same kind of
+		// note, so they do not overlap.
+		if pairs[0].Overlap != 0 {
+			t.Errorf("overlap = %d, want 0", pairs[0].Overlap)
+		}
+	})
+}
@@ -44,3 +44,42 @@ type GetRatingsBatchRequest struct {
 type TitlesRatings struct {
 	Titles map[string][]Rating `json:"titles"`
 }
+
+// GroupCompatibility is how alike the members of a group rate: one entry per
+// pair of current members, ordered by the pair's user ids.
+type GroupCompatibility struct {
+	Pairs []MemberCompatibility `json:"pairs"`
+}
+
+// MemberCompatibility compares two members over the notes both of them gave.
+// A movie contributes its note; a series contributes one comparison per season
+// both rated, so two members who agree on a show overall but split on its
+// seasons still show up as disagreeing.
+//
+// Correlation is Pearson's r, nil when it is undefined: fewer than two shared
+// notes, or one member giving every shared note the same value.
+// MeanAbsoluteDifference is nil when the two share no notes at all.
+type MemberCompatibility struct {
+	UserA                  CompatibilityMember  `json:"userA"`
+	UserB                  CompatibilityMember  `json:"userB"`
+	Overlap                int                  `json:"overlap"`
+	Correlation            *float64             `json:"correlation"`
+	MeanAbsoluteDifference *float64             `json:"meanAbsoluteDifference"`
+	Disagreements          []RatingDisagreement `json:"disagreements"`
+}
+
+type CompatibilityMember struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// RatingDisagreement is one shared note the pair split on. Season is set when
+// the note is a season rating of a series.
+type RatingDisagreement struct {
+	TitleId      string  `json:"titleId"`
+	PrimaryTitle string  `json:"primaryTitle"`
+	Season       *int    `json:"season,omitempty"`
+	NoteA        float64 `json:"noteA"`
+	NoteB        float64 `json:"noteB"`
+	Difference   float64 `json:"difference"`
+}
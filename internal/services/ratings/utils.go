@@ -14,6 +14,7 @@ var (
 	ErrInvalidSeasonValue        = errors.New("season number must be greater than 0")
 	ErrSeasonDoesNotExist        = errors.New("season does not exist for this title")
 	ErrSeasonRatingAlreadyExists = errors.New("rating already exists for this season")
+	ErrGroupNotFound             = errors.New("group not found")
 )
 
 var ErrorMap = map[error]int{
@@ -25,4 +26,5 @@ var ErrorMap = map[error]int{
 	ErrInvalidSeasonValue:        http.StatusBadRequest,
 	ErrSeasonDoesNotExist:        http.StatusBadRequest,
 	ErrSeasonRatingAlreadyExists: http.StatusConflict,
+	ErrGroupNotFound:             http.StatusNotFound,
 }
@@ -0,0 +1,503 @@
+package recommendations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/lealre/movies-backend/internal/config"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/services/titles"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// The model ranks every catalogue title a group has not seen by a score in
+// note points — roughly "how far above this group's usual note would it land"
+// — summed from three independent parts:
+//
+//   - content: how close the title's genres, interests and people are to the
+//     titles the group rated, each neighbour pulling the score towards its own
+//     note (up when the group rated it above its average, down when below);
+//   - collaborative: how groups with similar taste rated the title, for groups
+//     that opted in to sharing (see SetSharing);
+//   - popularity: a small nudge from the IMDb aggregate, which is all there is
+//     to go on for a group that has not rated anything yet.
+//
+// Every constant below is a tuning knob, not a contract: the ranking is what
+// clients see, never these numbers.
+const (
+	// candidatePoolSize bounds how many unseen titles are scored per request.
+	// The store hands them over best IMDb rating first, so on a catalogue
+	// larger than this the model still ranks the titles most likely to matter.
+	candidatePoolSize = 500
+
+	// neutralNote is the pivot a note is measured against until a group has
+	// rated enough titles for its own average to mean something.
+	neutralNote               = 5.5
+	minRatedTitlesForOwnPivot = 3
+
+	// contentShrinkage damps a content score built on little evidence: one
+	// faint neighbour should not outrank a title several rated titles agree on.
+	contentShrinkage = 0.5
+
+	// A peer group's similarity is its agreement with this group over the
+	// titles both rated, shrunk towards zero when that overlap is small. A
+	// mean disagreement of maxUsefulDisagreement points or more counts as no
+	// agreement at all.
+	maxUsefulDisagreement  = 4.0
+	overlapShrinkage       = 3.0
+	collaborativeShrinkage = 1.0
+
+	popularityPivot  = 6.5
+	popularityWeight = 0.3
+	// popularityReasonFloor is the IMDb rating below which popularity is not
+	// offered as a reason on its own — "rated 6.8 on IMDb" explains nothing.
+	popularityReasonFloor = 7.0
+
+	// inGroupBonus favours titles someone in the group already put on the
+	// list: that is the group's own intent to watch them.
+	inGroupBonus = 0.25
+
+	// maxSharedFeaturesInReason caps the "shares X, Y, Z" tail of a reason.
+	maxSharedFeaturesInReason = 3
+)
+
+// Feature weights for the content similarity. People are more telling than
+// genres (a director is a stronger signal of taste than "Drama"), and stars
+// weigh least because a title lists many of them.
+const (
+	genreWeight    = 1.0
+	interestWeight = 0.75
+	directorWeight = 1.5
+	writerWeight   = 1.0
+	starWeight     = 0.5
+)
+
+// GetGroupRecommendations ranks the catalogue titles groupId has not seen and
+// returns the best limit of them with the reasons behind each.
+//
+// Like GetTitlesFromGroup it does NOT check that the group exists or that the
+// caller may see it: the handler does that first with groups.GroupExists.
+func GetGroupRecommendations(db store.Store, ctx context.Context, groupId string, limit int) (Recommendations, error) {
+	limit, _ = config.NormalizePageParams(limit, 1)
+
+	sharing, err := db.GroupSharesRecommendations(ctx, groupId)
+	if err != nil {
+		return Recommendations{}, err
+	}
+
+	groupRatings, err := db.GetRatingsByGroupId(ctx, groupId)
+	if err != nil {
+		return Recommendations{}, err
+	}
+	ratedNotes := meanNoteByTitle(groupRatings)
+
+	ratedIds := sortedKeys(ratedNotes)
+	ratedTitles, err := db.GetTitlesByIds(ctx, ratedIds)
+	if err != nil {
+		return Recommendations{}, err
+	}
+
+	candidates, err := db.GetRecommendationCandidates(ctx, groupId, candidatePoolSize)
+	if err != nil {
+		return Recommendations{}, err
+	}
+
+	// The collaborative read is scoped to the titles that can matter: the
+	// candidates (what peers are asked about) and the group's rated titles
+	// (what peer similarity is measured over).
+	sharedIds := append([]string{}, ratedIds...)
+	for _, candidate := range candidates {
+		sharedIds = append(sharedIds, candidate.Title.ID)
+	}
+	sharedRatings, err := db.GetSharedRatingsByTitleIds(ctx, sharedIds, groupId)
+	if err != nil {
+		return Recommendations{}, err
+	}
+
+	taste := newGroupTaste(ratedNotes, ratedTitles)
+	peers := newPeerGroups(sharedRatings, ratedNotes)
+
+	return Recommendations{
+		Sharing: sharing,
+		Results: rank(taste, peers, candidates, limit),
+	}, nil
+}
+
+// SetSharing turns the group's opt-in to collaborative recommendations on or
+// off. Owner-only: it decides what happens to every member's ratings, not just
+// the caller's.
+func SetSharing(db store.Store, ctx context.Context, groupId, userId string, req SharingRequest) (SharingResponse, error) {
+	if req.Enabled == nil {
+		return SharingResponse{}, ErrSharingFlagRequired
+	}
+
+	group, err := db.GetGroupById(ctx, groupId, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return SharingResponse{}, ErrGroupNotFound
+		}
+		return SharingResponse{}, err
+	}
+	if group.OwnerId != userId {
+		return SharingResponse{}, ErrGroupNotOwnedByUser
+	}
+
+	if err := db.SetGroupRecommendationSharing(ctx, groupId, userId, *req.Enabled); err != nil {
+		return SharingResponse{}, err
+	}
+	return SharingResponse{Enabled: *req.Enabled}, nil
+}
+
+// rank scores every candidate and returns the best limit of them, ordered by
+// score descending and then by title id, so equal scores still come back in
+// the same order on every request (CONVENTIONS §6). The slice is empty, never
+// nil.
+func rank(taste groupTaste, peers []peerGroup, candidates []models.RecommendationCandidate, limit int) []Recommendation {
+	results := make([]Recommendation, 0, len(candidates))
+	for _, candidate := range candidates {
+		var score float64
+		var reasons []Reason
+
+		content, contentReason := taste.score(candidate.Title)
+		score += content
+		if contentReason != nil {
+			reasons = append(reasons, *contentReason)
+		}
+
+		collaborative, collaborativeReason := collaborativeScore(peers, candidate.Title.ID)
+		score += collaborative
+		if collaborativeReason != nil {
+			reasons = append(reasons, *collaborativeReason)
+		}
+
+		imdb := candidate.Title.Rating.AggregateRating
+		if imdb > 0 {
+			score += popularityWeight * (imdb - popularityPivot)
+			if len(reasons) == 0 && imdb >= popularityReasonFloor {
+				reasons = append(reasons, Reason{
+					Kind:    "popularity",
+					Message: fmt.Sprintf("Rated %.1f on IMDb", imdb),
+				})
+			}
+		}
+
+		if candidate.InGroup {
+			score += inGroupBonus
+			reasons = append(reasons, Reason{
+				Kind:    "inGroup",
+				Message: "Already on your group's list",
+			})
+		}
+
+		if reasons == nil {
+			reasons = []Reason{}
+		}
+
+		title := titles.MapDbTitleToApiTitle(candidate.Title)
+		// Same as the group titles list: episodes are loaded on demand via
+		// GET /titles/{id}/episodes, the season summary is enough here.
+		title.Episodes = nil
+
+		results = append(results, Recommendation{
+			Title:   title,
+			Score:   math.Round(score*100) / 100,
+			InGroup: candidate.InGroup,
+			Reasons: reasons,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Title.Id < results[j].Title.Id
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// meanNoteByTitle collapses ratings into one note per title: the mean of the
+// members' notes, rounded to one decimal like every other note the API shows.
+// A TV series rating's Note is already the mean of its seasons, so per-season
+// ratings are accounted for without being read again here.
+func meanNoteByTitle(ratings []models.UserRating) map[string]float64 {
+	sums := map[string]float64{}
+	counts := map[string]int{}
+	for _, rating := range ratings {
+		sums[rating.TitleId] += rating.Note
+		counts[rating.TitleId]++
+	}
+
+	means := make(map[string]float64, len(sums))
+	for titleId, sum := range sums {
+		means[titleId] = math.Round(sum/float64(counts[titleId])*10) / 10
+	}
+	return means
+}
+
+// pivotNote is the note a group's ratings are measured against: the group's
+// own average once it has rated enough titles for that to be meaningful, the
+// neutral midpoint before then.
+func pivotNote(notes map[string]float64) float64 {
+	if len(notes) < minRatedTitlesForOwnPivot {
+		return neutralNote
+	}
+	// Summed in key order: float addition is not associative, and map order
+	// would otherwise make the same request score differently run to run.
+	var sum float64
+	for _, titleId := range sortedKeys(notes) {
+		sum += notes[titleId]
+	}
+	return sum / float64(len(notes))
+}
+
+// ----- Content -----
+
+type feature struct {
+	weight float64
+	label  string
+}
+
+// titleFeatures is the set of things two titles can have in common, keyed so
+// that a genre and a person with the same name never collide. People are
+// keyed by id when the provider gave one and by name otherwise.
+func titleFeatures(title models.Title) map[string]feature {
+	features := map[string]feature{}
+	for _, genre := range title.Genres {
+		features["genre:"+strings.ToLower(genre)] = feature{genreWeight, genre}
+	}
+	for _, interest := range title.Interests {
+		key := interest.ID
+		if key == "" {
+			key = strings.ToLower(interest.Name)
+		}
+		features["interest:"+key] = feature{interestWeight, interest.Name}
+	}
+	addPeople := func(kind string, weight float64, people []models.Person) {
+		for _, person := range people {
+			key := person.ID
+			if key == "" {
+				key = strings.ToLower(person.DisplayName)
+			}
+			if key == "" {
+				continue
+			}
+			features[kind+":"+key] = feature{weight, person.DisplayName}
+		}
+	}
+	addPeople("director", directorWeight, title.Directors)
+	addPeople("writer", writerWeight, title.Writers)
+	addPeople("star", starWeight, title.Stars)
+	return features
+}
+
+// similarity is the weighted Jaccard index of two feature sets: the weight
+// they share over the weight either has. It also returns the shared features,
+// heaviest first, for the reason text.
+func similarity(a, b map[string]feature) (float64, []feature) {
+	var shared, union float64
+	var common []feature
+	for key, f := range a {
+		union += f.weight
+		if _, ok := b[key]; ok {
+			shared += f.weight
+			common = append(common, f)
+		}
+	}
+	for key, f := range b {
+		if _, ok := a[key]; !ok {
+			union += f.weight
+		}
+	}
+	if union == 0 {
+		return 0, nil
+	}
+
+	sort.Slice(common, func(i, j int) bool {
+		if common[i].weight != common[j].weight {
+			return common[i].weight > common[j].weight
+		}
+		return common[i].label < common[j].label
+	})
+	return shared / union, common
+}
+
+type ratedTitle struct {
+	title    models.Title
+	note     float64
+	features map[string]feature
+}
+
+// groupTaste is what the content model knows about a group: each title it
+// rated, with the group's note and the title's features.
+type groupTaste struct {
+	rated []ratedTitle
+	pivot float64
+}
+
+// newGroupTaste pairs the group's per-title notes with the titles' metadata.
+// A rated title no longer in the catalogue has no features to compare and is
+// left out; it still counts towards the pivot, which is about the group's
+// notes, not the catalogue.
+func newGroupTaste(notes map[string]float64, ratedTitles []models.Title) groupTaste {
+	taste := groupTaste{pivot: pivotNote(notes)}
+	for _, title := range ratedTitles {
+		note, ok := notes[title.ID]
+		if !ok {
+			continue
+		}
+		taste.rated = append(taste.rated, ratedTitle{title: title, note: note, features: titleFeatures(title)})
+	}
+	// Ties in the reason below resolve by id, not by map order.
+	sort.Slice(taste.rated, func(i, j int) bool { return taste.rated[i].title.ID < taste.rated[j].title.ID })
+	return taste
+}
+
+// score is the content half of the model: the similarity-weighted mean of how
+// far each rated title's note sits from the group's pivot, damped by
+// contentShrinkage. The reason names the rated title that pulled the score up
+// the most, if any did.
+func (g groupTaste) score(candidate models.Title) (float64, *Reason) {
+	candidateFeatures := titleFeatures(candidate)
+
+	var weighted, totalSimilarity, bestPull float64
+	var best *ratedTitle
+	var bestShared []feature
+	for i := range g.rated {
+		rated := &g.rated[i]
+		sim, shared := similarity(candidateFeatures, rated.features)
+		if sim == 0 {
+			continue
+		}
+		pull := sim * (rated.note - g.pivot)
+		weighted += pull
+		totalSimilarity += sim
+		if pull > bestPull {
+			bestPull, best, bestShared = pull, rated, shared
+		}
+	}
+	if totalSimilarity == 0 {
+		return 0, nil
+	}
+
+	score := weighted / (totalSimilarity + contentShrinkage)
+	if best == nil {
+		return score, nil
+	}
+
+	labels := make([]string, 0, maxSharedFeaturesInReason)
+	for _, f := range bestShared {
+		if len(labels) == maxSharedFeaturesInReason {
+			break
+		}
+		labels = append(labels, f.label)
+	}
+	titleId := best.title.ID
+	return score, &Reason{
+		Kind:    "content",
+		Message: fmt.Sprintf("Because you rated %s %.1f (shares %s)", best.title.PrimaryTitle, best.note, strings.Join(labels, ", ")),
+		TitleId: &titleId,
+	}
+}
+
+// ----- Collaborative -----
+
+// peerGroup is another, opted-in group as the collaborative model sees it:
+// only its per-title mean notes and how much it agrees with the asking group.
+// Which group it is never leaves this package.
+type peerGroup struct {
+	notes      map[string]float64
+	pivot      float64
+	similarity float64
+}
+
+// newPeerGroups builds the peers out of the shared ratings, keeping only the
+// groups that agree with mine at all over the titles both rated. A group with
+// no overlap says nothing about whether its taste is this group's.
+func newPeerGroups(shared []models.UserRating, mine map[string]float64) []peerGroup {
+	byGroup := map[string][]models.UserRating{}
+	for _, rating := range shared {
+		byGroup[rating.GroupId] = append(byGroup[rating.GroupId], rating)
+	}
+
+	var peers []peerGroup
+	for _, groupId := range sortedKeys(byGroup) {
+		notes := meanNoteByTitle(byGroup[groupId])
+
+		var disagreement float64
+		var overlap int
+		for _, titleId := range sortedKeys(notes) {
+			if myNote, ok := mine[titleId]; ok {
+				disagreement += math.Abs(notes[titleId] - myNote)
+				overlap++
+			}
+		}
+		if overlap == 0 {
+			continue
+		}
+
+		agreement := 1 - (disagreement/float64(overlap))/maxUsefulDisagreement
+		if agreement <= 0 {
+			continue
+		}
+		n := float64(overlap)
+		peers = append(peers, peerGroup{
+			notes:      notes,
+			pivot:      pivotNote(notes),
+			similarity: agreement * n / (n + overlapShrinkage),
+		})
+	}
+	return peers
+}
+
+// collaborativeScore is the similarity-weighted mean of how far each peer that
+// rated the title placed it from that peer's own pivot, damped by
+// collaborativeShrinkage. The reason reports the peers' plain mean note, and
+// only when the signal is a recommendation rather than a warning.
+func collaborativeScore(peers []peerGroup, titleId string) (float64, *Reason) {
+	var weighted, totalSimilarity, noteSum float64
+	var voters int
+	for _, peer := range peers {
+		note, ok := peer.notes[titleId]
+		if !ok {
+			continue
+		}
+		weighted += peer.similarity * (note - peer.pivot)
+		totalSimilarity += peer.similarity
+		noteSum += note
+		voters++
+	}
+	if voters == 0 {
+		return 0, nil
+	}
+
+	score := weighted / (totalSimilarity + collaborativeShrinkage)
+	if score <= 0 {
+		return score, nil
+	}
+
+	noun := "group"
+	if voters > 1 {
+		noun = "groups"
+	}
+	return score, &Reason{
+		Kind:    "collaborative",
+		Message: fmt.Sprintf("Rated %.1f on average by %d %s with similar taste", noteSum/float64(voters), voters, noun),
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
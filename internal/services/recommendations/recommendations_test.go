@@ -0,0 +1,187 @@
+package recommendations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+func movie(id, name string, imdb float64, genres []string, directors ...string) models.Title {
+	title := models.Title{
+		ID:           id,
+		Type:         "movie",
+		PrimaryTitle: name,
+		Genres:       genres,
+		Rating:       models.Rating{AggregateRating: imdb},
+	}
+	for _, director := range directors {
+		title.Directors = append(title.Directors, models.Person{ID: director, DisplayName: director})
+	}
+	return title
+}
+
+func candidates(titles ...models.Title) []models.RecommendationCandidate {
+	out := make([]models.RecommendationCandidate, 0, len(titles))
+	for _, title := range titles {
+		out = append(out, models.RecommendationCandidate{Title: title})
+	}
+	return out
+}
+
+func resultIds(results []Recommendation) []string {
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, result.Title.Id)
+	}
+	return ids
+}
+
+// TestRank pins the ranking on small in-memory fixtures — no store in the
+// path — so each part of the model can be checked in isolation.
+func TestRank(t *testing.T) {
+	loved := movie("tt-loved", "Loved", 7, []string{"Sci-Fi"}, "nolan")
+	disliked := movie("tt-disliked", "Disliked", 7, []string{"Romance"}, "someone")
+	meh := movie("tt-meh", "Meh", 7, []string{"Comedy"})
+	rated := map[string]float64{"tt-loved": 9, "tt-disliked": 3, "tt-meh": 6}
+	taste := newGroupTaste(rated, []models.Title{loved, disliked, meh})
+
+	t.Run("a title like one the group loved outranks one like a title it disliked", func(t *testing.T) {
+		// Identical IMDb ratings, so only the content model can separate them.
+		likeLoved := movie("tt-a", "Like Loved", 7, []string{"Sci-Fi"}, "nolan")
+		likeDisliked := movie("tt-b", "Like Disliked", 7, []string{"Romance"}, "someone")
+
+		results := rank(taste, nil, candidates(likeDisliked, likeLoved), 10)
+
+		if got := resultIds(results); got[0] != "tt-a" || got[1] != "tt-b" {
+			t.Fatalf("order = %v, want [tt-a tt-b]", got)
+		}
+		if results[0].Score <= 0 || results[1].Score >= 0 {
+			t.Errorf("scores = %v, %v; want one above and one below the group's pivot", results[0].Score, results[1].Score)
+		}
+	})
+
+	t.Run("the content reason names the rated title and its note", func(t *testing.T) {
+		likeLoved := movie("tt-a", "Like Loved", 7, []string{"Sci-Fi"}, "nolan")
+
+		results := rank(taste, nil, candidates(likeLoved), 10)
+
+		reason := results[0].Reasons[0]
+		if reason.Kind != "content" {
+			t.Fatalf("first reason kind = %q, want content", reason.Kind)
+		}
+		if !strings.HasPrefix(reason.Message, "Because you rated Loved 9.0") {
+			t.Errorf("reason = %q, want it to start with %q", reason.Message, "Because you rated Loved 9.0")
+		}
+		if reason.TitleId == nil || *reason.TitleId != "tt-loved" {
+			t.Errorf("reason titleId = %v, want tt-loved", reason.TitleId)
+		}
+		if !strings.Contains(reason.Message, "nolan") || !strings.Contains(reason.Message, "Sci-Fi") {
+			t.Errorf("reason = %q, want it to list the shared director and genre", reason.Message)
+		}
+	})
+
+	t.Run("a group with no ratings falls back to IMDb and says so", func(t *testing.T) {
+		empty := newGroupTaste(map[string]float64{}, nil)
+		low := movie("tt-low", "Low", 6.0, nil)
+		high := movie("tt-high", "High", 8.5, nil)
+
+		results := rank(empty, nil, candidates(low, high), 10)
+
+		if got := resultIds(results); got[0] != "tt-high" {
+			t.Fatalf("order = %v, want tt-high first", got)
+		}
+		if len(results[0].Reasons) != 1 || results[0].Reasons[0].Message != "Rated 8.5 on IMDb" {
+			t.Errorf("reasons = %+v, want the single IMDb reason", results[0].Reasons)
+		}
+		if results[1].Reasons == nil || len(results[1].Reasons) != 0 {
+			t.Errorf("reasons = %#v, want an empty, non-nil slice below the popularity floor", results[1].Reasons)
+		}
+	})
+
+	t.Run("ties are broken by title id and the limit is applied after sorting", func(t *testing.T) {
+		empty := newGroupTaste(map[string]float64{}, nil)
+		c := movie("tt-c", "C", 7, nil)
+		a := movie("tt-a", "A", 7, nil)
+		b := movie("tt-b", "B", 7, nil)
+
+		results := rank(empty, nil, candidates(c, a, b), 2)
+
+		if got := resultIds(results); len(got) != 2 || got[0] != "tt-a" || got[1] != "tt-b" {
+			t.Errorf("order = %v, want [tt-a tt-b]", got)
+		}
+	})
+
+	t.Run("a title already on the group's list gets a bump and a reason", func(t *testing.T) {
+		empty := newGroupTaste(map[string]float64{}, nil)
+		listed := models.RecommendationCandidate{Title: movie("tt-z", "Listed", 6.5, nil), InGroup: true}
+		other := models.RecommendationCandidate{Title: movie("tt-a", "Other", 6.5, nil)}
+
+		results := rank(empty, nil, []models.RecommendationCandidate{other, listed}, 10)
+
+		if results[0].Title.Id != "tt-z" || !results[0].InGroup {
+			t.Fatalf("first = %+v, want the in-group title", results[0])
+		}
+		if results[0].Reasons[0].Kind != "inGroup" {
+			t.Errorf("reasons = %+v, want an inGroup reason", results[0].Reasons)
+		}
+	})
+}
+
+func TestCollaborative(t *testing.T) {
+	mine := map[string]float64{"tt-1": 8, "tt-2": 4, "tt-3": 7}
+
+	rating := func(groupId, titleId string, note float64) models.UserRating {
+		return models.UserRating{GroupId: groupId, TitleId: titleId, Note: note}
+	}
+
+	t.Run("a peer that agrees recommends what it rated highly", func(t *testing.T) {
+		shared := []models.UserRating{
+			rating("peer", "tt-1", 8), rating("peer", "tt-2", 4), rating("peer", "tt-3", 7),
+			rating("peer", "tt-new", 9.5),
+		}
+
+		peers := newPeerGroups(shared, mine)
+		score, reason := collaborativeScore(peers, "tt-new")
+
+		if len(peers) != 1 {
+			t.Fatalf("peers = %d, want 1", len(peers))
+		}
+		if score <= 0 || reason == nil {
+			t.Fatalf("score = %v, reason = %v; want a positive score with a reason", score, reason)
+		}
+		if reason.Message != "Rated 9.5 on average by 1 group with similar taste" {
+			t.Errorf("reason = %q", reason.Message)
+		}
+	})
+
+	t.Run("a peer that disagrees on everything is ignored", func(t *testing.T) {
+		shared := []models.UserRating{
+			rating("contrarian", "tt-1", 1), rating("contrarian", "tt-2", 10), rating("contrarian", "tt-3", 1),
+			rating("contrarian", "tt-new", 10),
+		}
+
+		if peers := newPeerGroups(shared, mine); len(peers) != 0 {
+			t.Errorf("peers = %d, want 0", len(peers))
+		}
+	})
+
+	t.Run("a peer with no overlap is ignored", func(t *testing.T) {
+		shared := []models.UserRating{rating("stranger", "tt-new", 10)}
+
+		if peers := newPeerGroups(shared, mine); len(peers) != 0 {
+			t.Errorf("peers = %d, want 0", len(peers))
+		}
+	})
+
+	t.Run("members' notes are averaged per title before comparing", func(t *testing.T) {
+		notes := meanNoteByTitle([]models.UserRating{
+			rating("g", "tt-1", 5), rating("g", "tt-1", 8), rating("g", "tt-1", 10),
+		})
+
+		// (5 + 8 + 10) / 3 = 7.666..., shown to one decimal like every note.
+		if notes["tt-1"] != 7.7 {
+			t.Errorf("mean = %v, want 7.7", notes["tt-1"])
+		}
+	})
+}
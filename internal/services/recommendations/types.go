@@ -0,0 +1,37 @@
+package recommendations
+
+import "github.com/lealre/movies-backend/internal/services/titles"
+
+// Recommendations is one ranked list for a group, best first. Sharing reports
+// whether this group pools its own ratings into other groups' lists — the
+// switch the collaborative half of the model depends on, surfaced here so a
+// client can show it next to the results it affects.
+type Recommendations struct {
+	Sharing bool             `json:"sharing"`
+	Results []Recommendation `json:"results"`
+}
+
+type Recommendation struct {
+	Title   titles.Title `json:"title"`
+	Score   float64      `json:"score"`
+	InGroup bool         `json:"inGroup"`
+	Reasons []Reason     `json:"reasons"`
+}
+
+// Reason is one human-readable explanation of why a title was ranked where it
+// was. Kind says which part of the model produced it ("content",
+// "collaborative", "popularity" or "inGroup"); TitleId is set on a content
+// reason, naming the rated title it refers to, so a client can link it.
+type Reason struct {
+	Kind    string  `json:"kind"`
+	Message string  `json:"message"`
+	TitleId *string `json:"titleId,omitempty"`
+}
+
+type SharingRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+type SharingResponse struct {
+	Enabled bool `json:"enabled"`
+}
@@ -0,0 +1,18 @@
+package recommendations
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrGroupNotFound       = errors.New("group not found")
+	ErrGroupNotOwnedByUser = errors.New("only the group owner can perform this action")
+	ErrSharingFlagRequired = errors.New("enabled is required")
+)
+
+var ErrorMap = map[error]int{
+	ErrGroupNotFound:       http.StatusNotFound,
+	ErrGroupNotOwnedByUser: http.StatusForbidden,
+	ErrSharingFlagRequired: http.StatusBadRequest,
+}
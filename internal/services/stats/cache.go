@@ -0,0 +1,95 @@
+package stats
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultCacheSize is how many computed dashboards the server keeps. One entry
+// is one (group, date range) pair, a few kilobytes of JSON-shaped structs.
+const DefaultCacheSize = 256
+
+type cacheKey struct {
+	groupId string
+	from    string
+	to      string
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	version string
+	stats   GroupStats
+}
+
+// Cache keeps recently computed dashboards, least recently used evicted first.
+//
+// An entry is only ever served for the store fingerprint it was computed at
+// (store.GetGroupStatsVersion), so there is no TTL and no invalidation hook to
+// call from every write path: the first request after a change sees a new
+// fingerprint, misses, and recomputes. That also keeps several server
+// processes correct without sharing anything — each one checks the database,
+// not its own memory, for whether its entry is still current.
+//
+// A nil *Cache is valid and caches nothing.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[cacheKey]*list.Element
+}
+
+func NewCache(size int) *Cache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &Cache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[cacheKey]*list.Element),
+	}
+}
+
+// get returns the stats stored under key if they were computed at version.
+func (c *Cache) get(key cacheKey, version string) (GroupStats, bool) {
+	if c == nil {
+		return GroupStats{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return GroupStats{}, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if entry.version != version {
+		return GroupStats{}, false
+	}
+	c.order.MoveToFront(element)
+	return entry.stats, true
+}
+
+// put stores stats under key at version, replacing any older entry for the
+// same key and evicting the least recently used one when full.
+func (c *Cache) put(key cacheKey, version string, stats GroupStats) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = &cacheEntry{key: key, version: version, stats: stats}
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, version: version, stats: stats})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
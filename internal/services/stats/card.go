@@ -0,0 +1,46 @@
+package stats
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"strconv"
+)
+
+// reviewCardTemplate is the shareable year-in-review card: one self-contained
+// HTML page with its styles inline and no images, fonts or scripts to fetch,
+// so it renders the same offline as on the server and can be saved or
+// screenshotted as-is. html/template escapes every group, user and title name
+// that goes into it.
+//
+//go:embed review_card.html.tmpl
+var reviewCardTemplate string
+
+var reviewCard = template.Must(template.New("review_card").Funcs(template.FuncMap{
+	"delta": func(current, previous int) string {
+		switch {
+		case current > previous:
+			return fmt.Sprintf("+%d on last year", current-previous)
+		case current < previous:
+			return fmt.Sprintf("%d on last year", current-previous)
+		default:
+			return "same as last year"
+		}
+	},
+	"note": func(note float64) string {
+		return strconv.FormatFloat(note, 'f', 1, 64)
+	},
+	"hours": func(hours float64) string {
+		return strconv.FormatFloat(hours, 'f', -1, 64)
+	},
+}).Parse(reviewCardTemplate))
+
+// RenderYearInReviewCard renders review as the HTML card.
+func RenderYearInReviewCard(review YearInReview) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := reviewCard.Execute(&buf, review); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
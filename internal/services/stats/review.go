@@ -0,0 +1,424 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// topRatedSize caps the year's top-rated titles.
+const topRatedSize = 5
+
+// GetGroupYearInReview returns the recap of groupId's year, from the watched
+// dates and ratings the group already stores. Only dated watches take part:
+// an undated one cannot be placed in any year.
+//
+// Possible errors returned:
+//   - ErrInvalidYear: if year is not a four-digit year.
+//   - ErrGroupNotFound: if the group does not exist or userId is not a member.
+//   - Any error returned by the store reads.
+func GetGroupYearInReview(db store.Store, ctx context.Context, groupId, userId, year string) (YearInReview, error) {
+	reviewYear, err := parseYear(year)
+	if err != nil {
+		return YearInReview{}, err
+	}
+
+	group, err := db.GetGroupById(ctx, groupId, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return YearInReview{}, ErrGroupNotFound
+		}
+		return YearInReview{}, err
+	}
+
+	members, err := db.GetUsersFromGroup(ctx, groupId, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return YearInReview{}, ErrGroupNotFound
+		}
+		return YearInReview{}, err
+	}
+
+	titles, err := loadTitles(db, ctx, sortedKeys(group.Titles))
+	if err != nil {
+		return YearInReview{}, err
+	}
+
+	ratingsDb, err := db.GetRatingsByGroupId(ctx, groupId)
+	if err != nil {
+		return YearInReview{}, err
+	}
+
+	review := buildReview(reviewYear, datedWatches(group.Titles, titles), ratingsDb, titles, members)
+	review.Scope = "group"
+	review.Id = group.Id
+	review.Name = group.Name
+	return review, nil
+}
+
+// GetUserYearInReview returns the recap of userId's year across every group
+// they belong to: the watches of those groups and the user's own ratings in
+// them. A title watched in two groups counts once, at its first watch of the
+// year.
+//
+// Possible errors returned:
+//   - ErrInvalidYear: if year is not a four-digit year.
+//   - ErrUserNotFound: if the user does not exist.
+//   - Any error returned by the store reads.
+func GetUserYearInReview(db store.Store, ctx context.Context, userId, year string) (YearInReview, error) {
+	reviewYear, err := parseYear(year)
+	if err != nil {
+		return YearInReview{}, err
+	}
+
+	user, err := db.GetUserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return YearInReview{}, ErrUserNotFound
+		}
+		return YearInReview{}, err
+	}
+
+	var userGroups []models.Group
+	var ratingsDb []models.UserRating
+	titleIds := map[string]bool{}
+	for _, groupId := range user.Groups {
+		group, err := db.GetGroupById(ctx, groupId, userId)
+		if err != nil {
+			// A group deleted since reads as not found; it has no year to add.
+			if errors.Is(err, store.ErrRecordNotFound) {
+				continue
+			}
+			return YearInReview{}, err
+		}
+		userGroups = append(userGroups, group)
+		for titleId := range group.Titles {
+			titleIds[titleId] = true
+		}
+
+		groupRatings, err := db.GetRatingsByGroupId(ctx, groupId)
+		if err != nil {
+			return YearInReview{}, err
+		}
+		for _, rating := range groupRatings {
+			if rating.UserId == userId {
+				ratingsDb = append(ratingsDb, rating)
+			}
+		}
+	}
+
+	titles, err := loadTitles(db, ctx, sortedKeys(titleIds))
+	if err != nil {
+		return YearInReview{}, err
+	}
+
+	var watches []datedWatch
+	for _, group := range userGroups {
+		watches = append(watches, datedWatches(group.Titles, titles)...)
+	}
+
+	review := buildReview(reviewYear, watches, ratingsDb, titles, nil)
+	review.Scope = "user"
+	review.Id = user.Id
+	review.Name = user.Name
+	if review.Name == "" {
+		review.Name = user.Username
+	}
+	return review, nil
+}
+
+func parseYear(year string) (int, error) {
+	if len(year) != 4 {
+		return 0, ErrInvalidYear
+	}
+	value, err := strconv.Atoi(year)
+	if err != nil || value < 1000 {
+		return 0, ErrInvalidYear
+	}
+	return value, nil
+}
+
+func loadTitles(db store.Store, ctx context.Context, titleIds []string) (map[string]models.Title, error) {
+	titles := map[string]models.Title{}
+	if len(titleIds) == 0 {
+		return titles, nil
+	}
+	titlesDb, err := db.GetTitlesByIds(ctx, titleIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, title := range titlesDb {
+		titles[title.ID] = title
+	}
+	return titles, nil
+}
+
+// datedWatch is a watch that has a date, tied to its title.
+type datedWatch struct {
+	titleId  string
+	season   string
+	at       time.Time
+	seconds  int
+	episodes int
+}
+
+func datedWatches(items models.GroupTitles, titles map[string]models.Title) []datedWatch {
+	var out []datedWatch
+	for _, titleId := range sortedKeys(items) {
+		for _, w := range watchesOf(items[titleId], titles[titleId]) {
+			if w.at != nil {
+				out = append(out, datedWatch{
+					titleId:  titleId,
+					season:   w.season,
+					at:       w.at.UTC(),
+					seconds:  w.seconds,
+					episodes: w.episodes,
+				})
+			}
+		}
+	}
+	return out
+}
+
+// yearWatches keeps the watches dated in year, one per (title, season) at its
+// earliest date, in chronological order with title and season breaking ties.
+func yearWatches(watches []datedWatch, year int) []datedWatch {
+	type unit struct{ titleId, season string }
+	earliest := map[unit]datedWatch{}
+	for _, w := range watches {
+		if w.at.Year() != year {
+			continue
+		}
+		key := unit{titleId: w.titleId, season: w.season}
+		if existing, ok := earliest[key]; !ok || w.at.Before(existing.at) {
+			earliest[key] = w
+		}
+	}
+
+	out := make([]datedWatch, 0, len(earliest))
+	for _, w := range earliest {
+		out = append(out, w)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].at.Equal(out[j].at) {
+			return out[i].at.Before(out[j].at)
+		}
+		if out[i].titleId != out[j].titleId {
+			return out[i].titleId < out[j].titleId
+		}
+		return out[i].season < out[j].season
+	})
+	return out
+}
+
+// buildReview assembles a recap from already-loaded rows. members is nil for a
+// user recap, which has no per-member breakdown.
+func buildReview(
+	year int,
+	watches []datedWatch,
+	ratingsDb []models.UserRating,
+	titles map[string]models.Title,
+	members []models.User,
+) YearInReview {
+	thisYear := yearWatches(watches, year)
+	review := YearInReview{
+		Year:      year,
+		ThisYear:  yearTotals(thisYear, ratingsIn(ratingsDb, year)),
+		LastYear:  yearTotals(yearWatches(watches, year-1), ratingsIn(ratingsDb, year-1)),
+		TopRated:  topRated(thisYear, notesByTitle(ratingsDb), titles),
+		NewGenres: newGenres(watches, thisYear, year, titles),
+	}
+
+	if len(thisYear) > 0 {
+		review.FirstWatch = reviewWatch(thisYear[0], titles)
+		review.LastWatch = reviewWatch(thisYear[len(thisYear)-1], titles)
+	}
+	review.LongestBinge = longestBinge(thisYear, titles)
+
+	if members != nil {
+		review.Members = membersYear(members, thisYear, ratingsDb, year)
+	}
+	return review
+}
+
+func ratingsIn(ratingsDb []models.UserRating, year int) []models.UserRating {
+	var out []models.UserRating
+	for _, rating := range ratingsDb {
+		if rating.CreatedAt.UTC().Year() == year {
+			out = append(out, rating)
+		}
+	}
+	return out
+}
+
+func yearTotals(watches []datedWatch, ratingsDb []models.UserRating) YearTotals {
+	totals := YearTotals{Watches: len(watches), Ratings: len(ratingsDb)}
+	titleIds := map[string]bool{}
+	var seconds int
+	for _, w := range watches {
+		titleIds[w.titleId] = true
+		totals.Episodes += w.episodes
+		seconds += w.seconds
+	}
+	totals.TitlesWatched = len(titleIds)
+	totals.HoursWatched = math.Round(float64(seconds)/3600*10) / 10
+
+	if len(ratingsDb) > 0 {
+		var sum float64
+		for _, rating := range ratingsDb {
+			sum += rating.Note
+		}
+		average := round2(sum / float64(len(ratingsDb)))
+		totals.AverageNote = &average
+	}
+	return totals
+}
+
+// topRated ranks the titles watched in the year by their mean note, whenever
+// it was given: a title watched in December and rated in January is still
+// one of December's titles.
+func topRated(watches []datedWatch, notes map[string][]float64, titles map[string]models.Title) []RatedTitle {
+	out := []RatedTitle{}
+	seen := map[string]bool{}
+	for _, w := range watches {
+		if seen[w.titleId] || len(notes[w.titleId]) == 0 {
+			continue
+		}
+		seen[w.titleId] = true
+		out = append(out, RatedTitle{
+			TitleId:      w.titleId,
+			PrimaryTitle: titles[w.titleId].PrimaryTitle,
+			Note:         math.Round(mean(notes[w.titleId])*10) / 10,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Note != out[j].Note {
+			return out[i].Note > out[j].Note
+		}
+		return out[i].TitleId < out[j].TitleId
+	})
+	if len(out) > topRatedSize {
+		out = out[:topRatedSize]
+	}
+	return out
+}
+
+// newGenres lists, alphabetically, the genres of the year's watches that no
+// watch dated before the year had.
+func newGenres(all, thisYear []datedWatch, year int, titles map[string]models.Title) []string {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	before := map[string]bool{}
+	for _, w := range all {
+		if w.at.Before(start) {
+			for _, genre := range titles[w.titleId].Genres {
+				before[genre] = true
+			}
+		}
+	}
+
+	found := map[string]bool{}
+	for _, w := range thisYear {
+		for _, genre := range titles[w.titleId].Genres {
+			if !before[genre] {
+				found[genre] = true
+			}
+		}
+	}
+	return sortedKeys(found)
+}
+
+func reviewWatch(w datedWatch, titles map[string]models.Title) *ReviewWatch {
+	out := &ReviewWatch{TitleId: w.titleId, PrimaryTitle: titles[w.titleId].PrimaryTitle, WatchedAt: w.at}
+	if season, err := strconv.Atoi(w.season); err == nil {
+		out.Season = &season
+	}
+	return out
+}
+
+// weekStart is the Monday, 00:00 UTC, of the week t falls in.
+func weekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// longestBinge finds the week with the most episodes, then the most watches,
+// the earliest such week on a tie. Its titles are listed in the order they
+// were first watched that week.
+func longestBinge(watches []datedWatch, titles map[string]models.Title) *Binge {
+	if len(watches) == 0 {
+		return nil
+	}
+
+	var best *Binge
+	var bestStart time.Time
+	for i := 0; i < len(watches); {
+		start := weekStart(watches[i].at)
+		binge := &Binge{Titles: []ReviewTitle{}}
+		seen := map[string]bool{}
+		for ; i < len(watches) && weekStart(watches[i].at).Equal(start); i++ {
+			w := watches[i]
+			binge.Episodes += w.episodes
+			binge.Watches++
+			if !seen[w.titleId] {
+				seen[w.titleId] = true
+				binge.Titles = append(binge.Titles, ReviewTitle{TitleId: w.titleId, PrimaryTitle: titles[w.titleId].PrimaryTitle})
+			}
+		}
+
+		if best == nil || binge.Episodes > best.Episodes ||
+			(binge.Episodes == best.Episodes && binge.Watches > best.Watches) {
+			best = binge
+			bestStart = start
+		}
+	}
+
+	best.WeekStart = bestStart.Format(dateLayout)
+	best.WeekEnd = bestStart.AddDate(0, 0, 6).Format(dateLayout)
+	return best
+}
+
+// membersYear is each current member's share of the group's year, ordered by
+// user id.
+func membersYear(members []models.User, watches []datedWatch, ratingsDb []models.UserRating, year int) []MemberYear {
+	rated := map[string]map[string]bool{}
+	for _, rating := range ratingsDb {
+		if rated[rating.UserId] == nil {
+			rated[rating.UserId] = map[string]bool{}
+		}
+		rated[rating.UserId][rating.TitleId] = true
+	}
+
+	yearRatings := map[string][]models.UserRating{}
+	for _, rating := range ratingsIn(ratingsDb, year) {
+		yearRatings[rating.UserId] = append(yearRatings[rating.UserId], rating)
+	}
+
+	sorted := make([]models.User, len(members))
+	copy(sorted, members)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Id < sorted[j].Id })
+
+	out := make([]MemberYear, 0, len(sorted))
+	for _, member := range sorted {
+		entry := MemberYear{UserId: member.Id, Name: member.Name}
+
+		watched := map[string]bool{}
+		for _, w := range watches {
+			if rated[member.Id][w.titleId] {
+				watched[w.titleId] = true
+			}
+		}
+		entry.TitlesWatched = len(watched)
+
+		totals := yearTotals(nil, yearRatings[member.Id])
+		entry.Ratings = totals.Ratings
+		entry.AverageNote = totals.AverageNote
+		out = append(out, entry)
+	}
+	return out
+}
@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+func TestBuildReview(t *testing.T) {
+	at := func(value string) time.Time { return *day(value) }
+
+	titles := map[string]models.Title{
+		"tt-old":    {ID: "tt-old", PrimaryTitle: "Old", Genres: []string{"Drama"}},
+		"tt-movie":  {ID: "tt-movie", PrimaryTitle: "Movie", Genres: []string{"Drama", "Horror"}, RuntimeSeconds: 3600},
+		"tt-series": {ID: "tt-series", PrimaryTitle: "Series", Genres: []string{"Comedy"}},
+		"tt-late":   {ID: "tt-late", PrimaryTitle: "Late", Genres: []string{"Drama"}},
+	}
+	watches := []datedWatch{
+		{titleId: "tt-old", at: at("2024-06-01"), seconds: 7200},
+		{titleId: "tt-movie", at: at("2025-01-05"), seconds: 3600},
+		// Wednesday and Sunday of the same week, 2025-03-10 to 2025-03-16.
+		{titleId: "tt-series", season: "1", at: at("2025-03-12"), episodes: 8},
+		{titleId: "tt-series", season: "2", at: at("2025-03-16"), episodes: 6},
+		{titleId: "tt-late", at: at("2025-12-30")},
+		// The same movie watched again, in another group: counted once.
+		{titleId: "tt-movie", at: at("2025-02-01"), seconds: 3600},
+	}
+	ratingsDb := []models.UserRating{
+		{UserId: "u-a", TitleId: "tt-movie", Note: 9, CreatedAt: at("2025-01-06")},
+		{UserId: "u-b", TitleId: "tt-movie", Note: 7, CreatedAt: at("2025-01-07")},
+		{UserId: "u-a", TitleId: "tt-late", Note: 6, CreatedAt: at("2026-01-02")},
+		{UserId: "u-a", TitleId: "tt-old", Note: 4, CreatedAt: at("2024-06-02")},
+	}
+	members := []models.User{{Id: "u-b", Name: "Bea"}, {Id: "u-a", Name: "Ann"}}
+
+	review := buildReview(2025, watches, ratingsDb, titles, members)
+
+	t.Run("totals for this year and last", func(t *testing.T) {
+		this := review.ThisYear
+		if this.TitlesWatched != 3 || this.Watches != 4 || this.Episodes != 14 || this.HoursWatched != 1 {
+			t.Errorf("this year = %+v", this)
+		}
+		if this.Ratings != 2 || this.AverageNote == nil || *this.AverageNote != 8 {
+			t.Errorf("this year ratings = %+v", this)
+		}
+		if review.LastYear.TitlesWatched != 1 || review.LastYear.HoursWatched != 2 || review.LastYear.Ratings != 1 {
+			t.Errorf("last year = %+v", review.LastYear)
+		}
+	})
+
+	t.Run("first and last watch, binge and new genres", func(t *testing.T) {
+		if review.FirstWatch == nil || review.FirstWatch.TitleId != "tt-movie" || !review.FirstWatch.WatchedAt.Equal(at("2025-01-05")) {
+			t.Errorf("first watch = %+v, want the earliest watch of the movie", review.FirstWatch)
+		}
+		if review.LastWatch == nil || review.LastWatch.TitleId != "tt-late" {
+			t.Errorf("last watch = %+v", review.LastWatch)
+		}
+
+		binge := review.LongestBinge
+		if binge == nil || binge.WeekStart != "2025-03-10" || binge.WeekEnd != "2025-03-16" || binge.Episodes != 14 || binge.Watches != 2 {
+			t.Fatalf("binge = %+v, want the week of both seasons", binge)
+		}
+		if len(binge.Titles) != 1 || binge.Titles[0].PrimaryTitle != "Series" {
+			t.Errorf("binge titles = %+v, want the series once", binge.Titles)
+		}
+
+		if strings.Join(review.NewGenres, ",") != "Comedy,Horror" {
+			t.Errorf("new genres = %v, want Comedy and Horror (Drama was watched in 2024)", review.NewGenres)
+		}
+	})
+
+	t.Run("top rated uses notes given after the year, members by user id", func(t *testing.T) {
+		if len(review.TopRated) != 2 || review.TopRated[0].TitleId != "tt-movie" || review.TopRated[0].Note != 8 || review.TopRated[1].TitleId != "tt-late" {
+			t.Errorf("top rated = %+v", review.TopRated)
+		}
+
+		if len(review.Members) != 2 || review.Members[0].UserId != "u-a" {
+			t.Fatalf("members = %+v", review.Members)
+		}
+		ann := review.Members[0]
+		if ann.TitlesWatched != 2 || ann.Ratings != 1 || *ann.AverageNote != 9 {
+			t.Errorf("ann = %+v, want two watched titles rated and one rating given in 2025", ann)
+		}
+	})
+
+	t.Run("an empty year has empty lists and no highlights", func(t *testing.T) {
+		empty := buildReview(2030, watches, ratingsDb, titles, nil)
+
+		if empty.FirstWatch != nil || empty.LastWatch != nil || empty.LongestBinge != nil {
+			t.Errorf("highlights = %+v, want none", empty)
+		}
+		if empty.TopRated == nil || empty.NewGenres == nil {
+			t.Error("lists are nil, want empty, non-nil slices")
+		}
+		if empty.Members != nil {
+			t.Errorf("members = %+v, want none on a user recap", empty.Members)
+		}
+	})
+
+	t.Run("the year must be four digits", func(t *testing.T) {
+		for _, year := range []string{"", "25", "20x5", "0999", "20255"} {
+			if _, err := parseYear(year); err != ErrInvalidYear {
+				t.Errorf("parseYear(%q) err = %v, want ErrInvalidYear", year, err)
+			}
+		}
+	})
+}
+
+func TestRenderYearInReviewCard(t *testing.T) {
+	note := 8.5
+	review := YearInReview{
+		Year:     2025,
+		Name:     `<script>alert("x")</script>`,
+		ThisYear: YearTotals{TitlesWatched: 12, AverageNote: &note},
+		LastYear: YearTotals{TitlesWatched: 9},
+		TopRated: []RatedTitle{{PrimaryTitle: "Movie", Note: 9}},
+	}
+
+	card, err := RenderYearInReviewCard(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(card)
+
+	if strings.Contains(html, "<script>") {
+		t.Error("card contains an unescaped name")
+	}
+	for _, want := range []string{"2025 in review", "3 on last year", "avg 8.5", "Movie"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("card does not contain %q", want)
+		}
+	}
+	for _, external := range []string{"http://", "https://", "<img", "<link", "<script"} {
+		if strings.Contains(html, external) {
+			t.Errorf("card references %q, want it fully self-contained", external)
+		}
+	}
+}
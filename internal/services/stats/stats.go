@@ -0,0 +1,545 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+const (
+	// topListSize caps the genre, director and star lists.
+	topListSize = 10
+	// divisiveListSize caps the most divisive titles.
+	divisiveListSize = 5
+	// dateLayout is the only date format the range accepts.
+	dateLayout = "2006-01-02"
+)
+
+// GetGroupStats returns the dashboard of groupId, optionally limited to a date
+// range.
+//
+// Everything is computed from what the group already stores — its titles and
+// their watch state, the catalogue metadata behind them, and its ratings — so
+// there is nothing to keep in step on the write path. What keeps it cheap is
+// the cache: the one query made on every call is the store's fingerprint of
+// those rows (GetGroupStatsVersion), and the full reads only run when it no
+// longer matches what the cache holds. The fingerprint is read before the
+// data, so a write landing in between can at worst cache its result under the
+// older fingerprint, which the next call will not match.
+//
+// Possible errors returned:
+//   - ErrInvalidDate: if from or to is not a YYYY-MM-DD date.
+//   - ErrInvalidDateRange: if from is after to.
+//   - ErrGroupNotFound: if the group does not exist or userId is not a member.
+//   - Any error returned by the store reads.
+func GetGroupStats(db store.Store, ctx context.Context, cache *Cache, groupId, userId string, query StatsQuery) (GroupStats, error) {
+	window, err := parseRange(query)
+	if err != nil {
+		return GroupStats{}, err
+	}
+
+	version, err := db.GetGroupStatsVersion(ctx, groupId)
+	if err != nil {
+		return GroupStats{}, err
+	}
+
+	key := cacheKey{groupId: groupId, from: query.From, to: query.To}
+	if cached, ok := cache.get(key, version); ok {
+		return cached, nil
+	}
+
+	group, err := db.GetGroupById(ctx, groupId, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return GroupStats{}, ErrGroupNotFound
+		}
+		return GroupStats{}, err
+	}
+
+	members, err := db.GetUsersFromGroup(ctx, groupId, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return GroupStats{}, ErrGroupNotFound
+		}
+		return GroupStats{}, err
+	}
+
+	titles := map[string]models.Title{}
+	if len(group.Titles) > 0 {
+		titleIds := make([]string, 0, len(group.Titles))
+		for titleId := range group.Titles {
+			titleIds = append(titleIds, titleId)
+		}
+		titlesDb, err := db.GetTitlesByIds(ctx, titleIds)
+		if err != nil {
+			return GroupStats{}, err
+		}
+		for _, title := range titlesDb {
+			titles[title.ID] = title
+		}
+	}
+
+	ratingsDb, err := db.GetRatingsByGroupId(ctx, groupId)
+	if err != nil {
+		return GroupStats{}, err
+	}
+
+	stats := compute(window, group.Titles, titles, ratingsDb, members)
+	if query.From != "" {
+		stats.From = &query.From
+	}
+	if query.To != "" {
+		stats.To = &query.To
+	}
+
+	cache.put(key, version, stats)
+	return stats, nil
+}
+
+// dateRange is a day-granular window in UTC. A nil end is open.
+type dateRange struct {
+	from *time.Time
+	// until is the exclusive end: the start of the day after the requested to.
+	until *time.Time
+}
+
+func parseRange(query StatsQuery) (dateRange, error) {
+	var window dateRange
+	if query.From != "" {
+		from, err := time.Parse(dateLayout, query.From)
+		if err != nil {
+			return dateRange{}, ErrInvalidDate
+		}
+		window.from = &from
+	}
+	if query.To != "" {
+		to, err := time.Parse(dateLayout, query.To)
+		if err != nil {
+			return dateRange{}, ErrInvalidDate
+		}
+		until := to.AddDate(0, 0, 1)
+		window.until = &until
+	}
+	if window.from != nil && window.until != nil && !window.from.Before(*window.until) {
+		return dateRange{}, ErrInvalidDateRange
+	}
+	return window, nil
+}
+
+func (r dateRange) open() bool { return r.from == nil && r.until == nil }
+
+// includes reports whether t falls inside the window. An undated event only
+// belongs to the open window: with a range set there is no telling whether it
+// happened inside it.
+func (r dateRange) includes(t *time.Time) bool {
+	if t == nil {
+		return r.open()
+	}
+	if r.from != nil && t.Before(*r.from) {
+		return false
+	}
+	if r.until != nil && !t.Before(*r.until) {
+		return false
+	}
+	return true
+}
+
+// watch is one thing a group watched: a movie, or one season of a series.
+// season is empty for a movie or a series watched as a whole.
+type watch struct {
+	season   string
+	at       *time.Time
+	seconds  int
+	episodes int
+}
+
+// compute builds the dashboard from already-loaded rows. It reads no store,
+// which is what lets it be tested against plain fixtures.
+func compute(
+	window dateRange,
+	items models.GroupTitles,
+	titles map[string]models.Title,
+	ratingsDb []models.UserRating,
+	members []models.User,
+) GroupStats {
+	stats := GroupStats{
+		WatchesByMonth:  []MonthCount{},
+		FavouriteGenres: []GenreCount{},
+		TopDirectors:    []PersonCount{},
+		TopStars:        []PersonCount{},
+		MostDivisive:    []DivisiveTitle{},
+		Members:         []MemberHabits{},
+	}
+
+	months := map[string]int{}
+	genres := map[string]int{}
+	directors := map[string]*PersonCount{}
+	stars := map[string]*PersonCount{}
+	var seconds int
+
+	for titleId, item := range items {
+		if window.includes(&item.AddedAt) {
+			stats.TotalTitles++
+		}
+
+		title := titles[titleId]
+		var watched bool
+		for _, w := range watchesOf(item, title) {
+			if !window.includes(w.at) {
+				continue
+			}
+			watched = true
+			seconds += w.seconds
+			if w.at != nil {
+				months[w.at.UTC().Format("2006-01")]++
+			}
+		}
+		if !watched {
+			continue
+		}
+
+		// Genres and people count once per watched title, not per season: a
+		// six-season show is not six times anyone's favourite.
+		stats.WatchedTitles++
+		for _, genre := range title.Genres {
+			genres[genre]++
+		}
+		countPeople(directors, title.Directors)
+		countPeople(stars, title.Stars)
+	}
+
+	stats.HoursWatched = math.Round(float64(seconds)/3600*10) / 10
+
+	for _, month := range sortedKeys(months) {
+		stats.WatchesByMonth = append(stats.WatchesByMonth, MonthCount{Month: month, Count: months[month]})
+	}
+	stats.FavouriteGenres = topGenres(genres)
+	stats.TopDirectors = topPeople(directors)
+	stats.TopStars = topPeople(stars)
+
+	var inRange []models.UserRating
+	for _, rating := range ratingsDb {
+		if window.includes(&rating.CreatedAt) {
+			inRange = append(inRange, rating)
+		}
+	}
+	notes := notesByTitle(inRange)
+
+	stats.Ratings = compareWithImdb(notes, titles)
+	stats.MostDivisive = mostDivisive(notes, titles)
+	stats.Members = memberHabits(members, inRange, notes, titles)
+
+	return stats
+}
+
+// watchesOf lists the watches recorded on one group title. A series with
+// per-season state is counted season by season, each at its own date; its
+// top-level flag is derived from those seasons and would double count them.
+// Anything else — a movie, or a series marked watched as a whole — is one
+// watch of the whole title.
+func watchesOf(item models.GroupTitleItem, title models.Title) []watch {
+	if item.SeasonsWatched != nil && len(*item.SeasonsWatched) > 0 {
+		var watches []watch
+		for _, season := range sortedKeys(*item.SeasonsWatched) {
+			state := (*item.SeasonsWatched)[season]
+			if state.Watched {
+				watches = append(watches, watch{
+					season:   season,
+					at:       state.WatchedAt,
+					seconds:  seasonRuntime(title, season),
+					episodes: seasonEpisodes(title, season),
+				})
+			}
+		}
+		return watches
+	}
+
+	if !item.Watched {
+		return nil
+	}
+	if models.IsSeriesTitleType(title.Type) {
+		var seconds, episodes int
+		for _, season := range title.Seasons {
+			seconds += seasonRuntime(title, season.Season)
+			episodes += seasonEpisodes(title, season.Season)
+		}
+		return []watch{{at: item.WatchedAt, seconds: seconds, episodes: episodes}}
+	}
+	return []watch{{at: item.WatchedAt, seconds: title.RuntimeSeconds}}
+}
+
+// seasonRuntime is the summed episode runtime of one season. A series'
+// RuntimeSeconds is its typical episode length, so it stands in for an episode
+// with no runtime of its own, and for every episode of a season whose episodes
+// the catalogue does not list (by its episode count).
+func seasonRuntime(title models.Title, season string) int {
+	var seconds, listed int
+	for _, episode := range title.Episodes {
+		if episode.Season != season {
+			continue
+		}
+		listed++
+		if episode.RuntimeSeconds != nil {
+			seconds += *episode.RuntimeSeconds
+		} else {
+			seconds += title.RuntimeSeconds
+		}
+	}
+	if listed > 0 {
+		return seconds
+	}
+
+	for _, s := range title.Seasons {
+		if s.Season == season {
+			return s.EpisodeCount * title.RuntimeSeconds
+		}
+	}
+	return 0
+}
+
+// seasonEpisodes is the number of episodes in one season: those the catalogue
+// lists, or the season's episode count when it lists none.
+func seasonEpisodes(title models.Title, season string) int {
+	var listed int
+	for _, episode := range title.Episodes {
+		if episode.Season == season {
+			listed++
+		}
+	}
+	if listed > 0 {
+		return listed
+	}
+
+	for _, s := range title.Seasons {
+		if s.Season == season {
+			return s.EpisodeCount
+		}
+	}
+	return 0
+}
+
+func countPeople(counts map[string]*PersonCount, people []models.Person) {
+	for _, person := range people {
+		if entry, ok := counts[person.ID]; ok {
+			entry.Count++
+			continue
+		}
+		counts[person.ID] = &PersonCount{Id: person.ID, Name: person.DisplayName, Count: 1}
+	}
+}
+
+func topGenres(counts map[string]int) []GenreCount {
+	out := make([]GenreCount, 0, len(counts))
+	for genre, count := range counts {
+		out = append(out, GenreCount{Genre: genre, Count: count})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Genre < out[j].Genre
+	})
+	if len(out) > topListSize {
+		out = out[:topListSize]
+	}
+	return out
+}
+
+func topPeople(counts map[string]*PersonCount) []PersonCount {
+	out := make([]PersonCount, 0, len(counts))
+	for _, entry := range counts {
+		out = append(out, *entry)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Id < out[j].Id
+	})
+	if len(out) > topListSize {
+		out = out[:topListSize]
+	}
+	return out
+}
+
+// notesByTitle groups the overall notes left on each title. A series' overall
+// note is already the mean of its season ratings, which is the right grain
+// here: every figure below compares titles, not seasons.
+func notesByTitle(ratingsDb []models.UserRating) map[string][]float64 {
+	notes := map[string][]float64{}
+	for _, rating := range ratingsDb {
+		notes[rating.TitleId] = append(notes[rating.TitleId], rating.Note)
+	}
+	return notes
+}
+
+// compareWithImdb averages, over the rated titles that carry an IMDb rating,
+// the group's mean note against IMDb's. Each title weighs the same however
+// many members rated it.
+func compareWithImdb(notes map[string][]float64, titles map[string]models.Title) RatingComparison {
+	var comparison RatingComparison
+	var groupSum, imdbSum float64
+	for _, titleId := range sortedKeys(notes) {
+		imdb := titles[titleId].Rating.AggregateRating
+		if imdb <= 0 {
+			continue
+		}
+		comparison.RatedTitles++
+		groupSum += mean(notes[titleId])
+		imdbSum += imdb
+	}
+	if comparison.RatedTitles == 0 {
+		return comparison
+	}
+
+	n := float64(comparison.RatedTitles)
+	groupAverage := round2(groupSum / n)
+	imdbAverage := round2(imdbSum / n)
+	difference := round2(groupSum/n - imdbSum/n)
+	comparison.GroupAverage = &groupAverage
+	comparison.ImdbAverage = &imdbAverage
+	comparison.Difference = &difference
+	return comparison
+}
+
+// mostDivisive ranks titles at least two members rated by the standard
+// deviation of their notes, ties broken by title id. Titles everyone rated
+// the same are not divisive and are left out.
+func mostDivisive(notes map[string][]float64, titles map[string]models.Title) []DivisiveTitle {
+	out := []DivisiveTitle{}
+	for titleId, titleNotes := range notes {
+		if len(titleNotes) < 2 {
+			continue
+		}
+		deviation := round2(standardDeviation(titleNotes))
+		if deviation == 0 {
+			continue
+		}
+		lowest, highest := titleNotes[0], titleNotes[0]
+		for _, note := range titleNotes[1:] {
+			lowest = math.Min(lowest, note)
+			highest = math.Max(highest, note)
+		}
+		out = append(out, DivisiveTitle{
+			TitleId:           titleId,
+			PrimaryTitle:      titles[titleId].PrimaryTitle,
+			Ratings:           len(titleNotes),
+			Lowest:            lowest,
+			Highest:           highest,
+			StandardDeviation: deviation,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].StandardDeviation != out[j].StandardDeviation {
+			return out[i].StandardDeviation > out[j].StandardDeviation
+		}
+		return out[i].TitleId < out[j].TitleId
+	})
+	if len(out) > divisiveListSize {
+		out = out[:divisiveListSize]
+	}
+	return out
+}
+
+// memberHabits describes how each current member rates, ordered by user id.
+// VersusGroup compares a member's note with the mean of everyone else's on the
+// same title, so a member is never measured against themselves.
+func memberHabits(members []models.User, ratingsDb []models.UserRating, notes map[string][]float64, titles map[string]models.Title) []MemberHabits {
+	byUser := map[string][]models.UserRating{}
+	for _, rating := range ratingsDb {
+		byUser[rating.UserId] = append(byUser[rating.UserId], rating)
+	}
+
+	sorted := make([]models.User, len(members))
+	copy(sorted, members)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Id < sorted[j].Id })
+
+	out := make([]MemberHabits, 0, len(sorted))
+	for _, member := range sorted {
+		habits := MemberHabits{UserId: member.Id, Name: member.Name}
+		own := byUser[member.Id]
+		habits.Ratings = len(own)
+		if len(own) == 0 {
+			out = append(out, habits)
+			continue
+		}
+
+		var sum, versusGroup, versusImdb float64
+		var comparedToGroup, comparedToImdb int
+		lowest, highest := own[0].Note, own[0].Note
+		for _, rating := range own {
+			sum += rating.Note
+			lowest = math.Min(lowest, rating.Note)
+			highest = math.Max(highest, rating.Note)
+
+			titleNotes := notes[rating.TitleId]
+			if others := len(titleNotes) - 1; others > 0 {
+				var othersSum float64
+				for _, note := range titleNotes {
+					othersSum += note
+				}
+				othersSum -= rating.Note
+				versusGroup += rating.Note - othersSum/float64(others)
+				comparedToGroup++
+			}
+			if imdb := titles[rating.TitleId].Rating.AggregateRating; imdb > 0 {
+				versusImdb += rating.Note - imdb
+				comparedToImdb++
+			}
+		}
+
+		average := round2(sum / float64(len(own)))
+		habits.AverageNote = &average
+		habits.Lowest = &lowest
+		habits.Highest = &highest
+		if comparedToGroup > 0 {
+			value := round2(versusGroup / float64(comparedToGroup))
+			habits.VersusGroup = &value
+		}
+		if comparedToImdb > 0 {
+			value := round2(versusImdb / float64(comparedToImdb))
+			habits.VersusImdb = &value
+		}
+		out = append(out, habits)
+	}
+	return out
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// standardDeviation is the population standard deviation: the notes are every
+// note the group gave, not a sample of them.
+func standardDeviation(values []float64) float64 {
+	m := mean(values)
+	var sum float64
+	for _, value := range values {
+		sum += (value - m) * (value - m)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// sortedKeys returns m's keys in ascending order, so every sum and list built
+// from a map comes out the same on every call.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
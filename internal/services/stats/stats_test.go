@@ -0,0 +1,184 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+)
+
+func day(value string) *time.Time {
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestCompute(t *testing.T) {
+	added := *day("2025-01-01")
+
+	movie := models.Title{
+		ID: "tt-movie", Type: "movie", PrimaryTitle: "Movie", RuntimeSeconds: 7200,
+		Genres:    []string{"Drama", "Sci-Fi"},
+		Directors: []models.Person{{ID: "nm-dir", DisplayName: "Director"}},
+		Rating:    models.Rating{AggregateRating: 8},
+	}
+	series := models.Title{
+		ID: "tt-series", Type: "tvSeries", PrimaryTitle: "Series", RuntimeSeconds: 1800,
+		Genres:  []string{"Drama"},
+		Seasons: []models.Seasons{{Season: "1", EpisodeCount: 2}, {Season: "2", EpisodeCount: 4}},
+		Episodes: []models.Episode{
+			{Season: "1", RuntimeSeconds: intPtr(3600)},
+			{Season: "1"}, // no runtime of its own: counts as the series' 1800
+		},
+		Rating: models.Rating{AggregateRating: 7},
+	}
+	unwatched := models.Title{ID: "tt-unwatched", Type: "movie", RuntimeSeconds: 5400, Genres: []string{"Horror"}}
+	titles := map[string]models.Title{movie.ID: movie, series.ID: series, unwatched.ID: unwatched}
+
+	items := models.GroupTitles{
+		movie.ID: {TitleId: movie.ID, Watched: true, WatchedAt: day("2025-03-10"), AddedAt: added},
+		series.ID: {TitleId: series.ID, Watched: true, AddedAt: added, SeasonsWatched: &models.SeasonsWatched{
+			"1": {Watched: true, WatchedAt: day("2025-03-20")},
+			"2": {Watched: true, WatchedAt: day("2025-05-02")},
+		}},
+		unwatched.ID: {TitleId: unwatched.ID, AddedAt: *day("2025-06-01")},
+	}
+
+	ratingsDb := []models.UserRating{
+		{UserId: "u-a", TitleId: movie.ID, Note: 9, CreatedAt: *day("2025-03-11")},
+		{UserId: "u-b", TitleId: movie.ID, Note: 5, CreatedAt: *day("2025-03-12")},
+		{UserId: "u-a", TitleId: series.ID, Note: 7, CreatedAt: *day("2025-05-03")},
+	}
+	members := []models.User{{Id: "u-b", Name: "Bea"}, {Id: "u-a", Name: "Ann"}}
+
+	t.Run("watches, hours and months across movies and seasons", func(t *testing.T) {
+		stats := compute(dateRange{}, items, titles, ratingsDb, members)
+
+		if stats.TotalTitles != 3 || stats.WatchedTitles != 2 {
+			t.Errorf("total, watched = %d, %d; want 3, 2", stats.TotalTitles, stats.WatchedTitles)
+		}
+		// Movie 2h + season 1 (1h + 0.5h) + season 2 (4 unlisted episodes x 0.5h).
+		if stats.HoursWatched != 5.5 {
+			t.Errorf("hours = %v, want 5.5", stats.HoursWatched)
+		}
+		want := []MonthCount{{Month: "2025-03", Count: 2}, {Month: "2025-05", Count: 1}}
+		if len(stats.WatchesByMonth) != len(want) {
+			t.Fatalf("months = %+v, want %+v", stats.WatchesByMonth, want)
+		}
+		for i := range want {
+			if stats.WatchesByMonth[i] != want[i] {
+				t.Errorf("month %d = %+v, want %+v", i, stats.WatchesByMonth[i], want[i])
+			}
+		}
+		if stats.FavouriteGenres[0] != (GenreCount{Genre: "Drama", Count: 2}) {
+			t.Errorf("top genre = %+v, want Drama x2 (once per watched title)", stats.FavouriteGenres[0])
+		}
+		if len(stats.TopDirectors) != 1 || stats.TopDirectors[0].Name != "Director" {
+			t.Errorf("directors = %+v", stats.TopDirectors)
+		}
+	})
+
+	t.Run("ratings against IMDb, divisive titles and member habits", func(t *testing.T) {
+		stats := compute(dateRange{}, items, titles, ratingsDb, members)
+
+		// Movie: group 7 vs IMDb 8; series: group 7 vs IMDb 7.
+		if stats.Ratings.RatedTitles != 2 || *stats.Ratings.GroupAverage != 7 || *stats.Ratings.ImdbAverage != 7.5 || *stats.Ratings.Difference != -0.5 {
+			t.Errorf("ratings = %+v", stats.Ratings)
+		}
+		if len(stats.MostDivisive) != 1 || stats.MostDivisive[0].TitleId != movie.ID || stats.MostDivisive[0].StandardDeviation != 2 {
+			t.Errorf("most divisive = %+v, want only the movie with deviation 2", stats.MostDivisive)
+		}
+
+		if stats.Members[0].UserId != "u-a" || stats.Members[1].UserId != "u-b" {
+			t.Fatalf("members = %+v, want ordered by user id", stats.Members)
+		}
+		ann := stats.Members[0]
+		if ann.Ratings != 2 || *ann.AverageNote != 8 || *ann.VersusGroup != 4 || *ann.VersusImdb != 0.5 {
+			t.Errorf("ann = %+v", ann)
+		}
+	})
+
+	t.Run("a date range limits every figure and drops undated watches", func(t *testing.T) {
+		undated := models.GroupTitles{
+			movie.ID: {TitleId: movie.ID, Watched: true, AddedAt: added},
+		}
+		open := compute(dateRange{}, undated, titles, nil, nil)
+		if open.WatchedTitles != 1 || len(open.WatchesByMonth) != 0 {
+			t.Errorf("open range = %+v, want the undated watch counted but not in a month", open)
+		}
+
+		window, err := parseRange(StatsQuery{From: "2025-05-01", To: "2025-06-01"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats := compute(window, items, titles, ratingsDb, members)
+
+		if stats.TotalTitles != 1 {
+			t.Errorf("total = %d, want only the title added on 2025-06-01 (to is inclusive)", stats.TotalTitles)
+		}
+		if stats.WatchedTitles != 1 || stats.HoursWatched != 2 {
+			t.Errorf("watched, hours = %d, %v; want season 2 only", stats.WatchedTitles, stats.HoursWatched)
+		}
+		if stats.Ratings.RatedTitles != 1 || len(stats.MostDivisive) != 0 {
+			t.Errorf("ratings = %+v, divisive = %+v; want only the series rating", stats.Ratings, stats.MostDivisive)
+		}
+		if stats.Members[1].Ratings != 0 || stats.Members[1].AverageNote != nil {
+			t.Errorf("bea = %+v, want no ratings in range", stats.Members[1])
+		}
+	})
+
+	t.Run("invalid ranges are rejected", func(t *testing.T) {
+		if _, err := parseRange(StatsQuery{From: "March"}); err != ErrInvalidDate {
+			t.Errorf("err = %v, want ErrInvalidDate", err)
+		}
+		if _, err := parseRange(StatsQuery{From: "2025-02-01", To: "2025-01-01"}); err != ErrInvalidDateRange {
+			t.Errorf("err = %v, want ErrInvalidDateRange", err)
+		}
+		if _, err := parseRange(StatsQuery{From: "2025-01-01", To: "2025-01-01"}); err != nil {
+			t.Errorf("err = %v, want a single day to be a valid range", err)
+		}
+	})
+}
+
+func TestCache(t *testing.T) {
+	key := func(groupId string) cacheKey { return cacheKey{groupId: groupId} }
+
+	t.Run("an entry is only served for the version it was computed at", func(t *testing.T) {
+		cache := NewCache(2)
+		cache.put(key("g"), "v1", GroupStats{TotalTitles: 1})
+
+		if got, ok := cache.get(key("g"), "v1"); !ok || got.TotalTitles != 1 {
+			t.Errorf("get(v1) = %+v, %v; want the stored entry", got, ok)
+		}
+		if _, ok := cache.get(key("g"), "v2"); ok {
+			t.Error("get(v2) hit, want a miss once the version moved on")
+		}
+	})
+
+	t.Run("the least recently used entry is evicted first", func(t *testing.T) {
+		cache := NewCache(2)
+		cache.put(key("a"), "v", GroupStats{})
+		cache.put(key("b"), "v", GroupStats{})
+		cache.get(key("a"), "v")
+		cache.put(key("c"), "v", GroupStats{})
+
+		if _, ok := cache.get(key("b"), "v"); ok {
+			t.Error("b survived, want it evicted as least recently used")
+		}
+		if _, ok := cache.get(key("a"), "v"); !ok {
+			t.Error("a was evicted, want it kept after being read")
+		}
+	})
+
+	t.Run("a nil cache caches nothing", func(t *testing.T) {
+		var cache *Cache
+		cache.put(key("g"), "v", GroupStats{})
+		if _, ok := cache.get(key("g"), "v"); ok {
+			t.Error("nil cache hit")
+		}
+	})
+}
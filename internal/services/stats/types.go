@@ -0,0 +1,154 @@
+package stats
+
+import "time"
+
+// StatsQuery is the optional date range of GET /groups/{id}/stats, both ends
+// inclusive, as YYYY-MM-DD. Either end may be empty for an open range.
+type StatsQuery struct {
+	From string
+	To   string
+}
+
+// GroupStats is the group dashboard. With a date range, every figure is about
+// what happened inside it: titles added, watches and ratings made in it.
+// Without one it covers the group's whole history, and watches that were
+// never given a date count everywhere except WatchesByMonth.
+type GroupStats struct {
+	From            *string          `json:"from"`
+	To              *string          `json:"to"`
+	TotalTitles     int              `json:"totalTitles"`
+	WatchedTitles   int              `json:"watchedTitles"`
+	HoursWatched    float64          `json:"hoursWatched"`
+	WatchesByMonth  []MonthCount     `json:"watchesByMonth"`
+	FavouriteGenres []GenreCount     `json:"favouriteGenres"`
+	TopDirectors    []PersonCount    `json:"topDirectors"`
+	TopStars        []PersonCount    `json:"topStars"`
+	Ratings         RatingComparison `json:"ratings"`
+	MostDivisive    []DivisiveTitle  `json:"mostDivisive"`
+	Members         []MemberHabits   `json:"members"`
+}
+
+// MonthCount is the number of watches (a movie, or one season of a series)
+// dated in a calendar month, "YYYY-MM" in UTC.
+type MonthCount struct {
+	Month string `json:"month"`
+	Count int    `json:"count"`
+}
+
+type GenreCount struct {
+	Genre string `json:"genre"`
+	Count int    `json:"count"`
+}
+
+type PersonCount struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// RatingComparison sets the group's notes against IMDb over the titles the
+// group rated that also carry an IMDb rating. The averages are nil when there
+// are none.
+type RatingComparison struct {
+	RatedTitles  int      `json:"ratedTitles"`
+	GroupAverage *float64 `json:"groupAverage"`
+	ImdbAverage  *float64 `json:"imdbAverage"`
+	Difference   *float64 `json:"difference"`
+}
+
+// DivisiveTitle is a title at least two members rated, ranked by how far
+// apart their notes are.
+type DivisiveTitle struct {
+	TitleId           string  `json:"titleId"`
+	PrimaryTitle      string  `json:"primaryTitle"`
+	Ratings           int     `json:"ratings"`
+	Lowest            float64 `json:"lowest"`
+	Highest           float64 `json:"highest"`
+	StandardDeviation float64 `json:"standardDeviation"`
+}
+
+// MemberHabits is how one current member rates. The averages are nil for a
+// member with no ratings; VersusGroup and VersusImdb are how far above (or,
+// negative, below) the rest of the group and IMDb the member rates the same
+// titles, nil when there is nothing to compare against.
+type MemberHabits struct {
+	UserId      string   `json:"userId"`
+	Name        string   `json:"name"`
+	Ratings     int      `json:"ratings"`
+	AverageNote *float64 `json:"averageNote"`
+	Lowest      *float64 `json:"lowest"`
+	Highest     *float64 `json:"highest"`
+	VersusGroup *float64 `json:"versusGroup"`
+	VersusImdb  *float64 `json:"versusImdb"`
+}
+
+// YearInReview is a yearly recap, either of a group (Scope "group", Id the
+// group id) or of one user across every group they belong to (Scope "user",
+// Id the user id). Every list is empty rather than nil, and the single-valued
+// highlights are nil, when the year has nothing to show for them.
+type YearInReview struct {
+	Year         int          `json:"year"`
+	Scope        string       `json:"scope"`
+	Id           string       `json:"id"`
+	Name         string       `json:"name"`
+	ThisYear     YearTotals   `json:"thisYear"`
+	LastYear     YearTotals   `json:"lastYear"`
+	Members      []MemberYear `json:"members,omitempty"`
+	TopRated     []RatedTitle `json:"topRated"`
+	FirstWatch   *ReviewWatch `json:"firstWatch"`
+	LastWatch    *ReviewWatch `json:"lastWatch"`
+	LongestBinge *Binge       `json:"longestBinge"`
+	NewGenres    []string     `json:"newGenres"`
+}
+
+// YearTotals are the headline figures of one year. A watch is a movie or one
+// season of a series; TitlesWatched counts distinct titles.
+type YearTotals struct {
+	TitlesWatched int      `json:"titlesWatched"`
+	Watches       int      `json:"watches"`
+	Episodes      int      `json:"episodes"`
+	HoursWatched  float64  `json:"hoursWatched"`
+	Ratings       int      `json:"ratings"`
+	AverageNote   *float64 `json:"averageNote"`
+}
+
+// MemberYear is one current member's share of a group's year. A group watches
+// together and records one watched state for everyone, so the titles a member
+// watched are the group's watches of the year that member rated.
+type MemberYear struct {
+	UserId        string   `json:"userId"`
+	Name          string   `json:"name"`
+	TitlesWatched int      `json:"titlesWatched"`
+	Ratings       int      `json:"ratings"`
+	AverageNote   *float64 `json:"averageNote"`
+}
+
+// RatedTitle is a title watched in the year with its note: the group's mean
+// note for a group recap, the user's own for a user recap.
+type RatedTitle struct {
+	TitleId      string  `json:"titleId"`
+	PrimaryTitle string  `json:"primaryTitle"`
+	Note         float64 `json:"note"`
+}
+
+type ReviewWatch struct {
+	TitleId      string    `json:"titleId"`
+	PrimaryTitle string    `json:"primaryTitle"`
+	Season       *int      `json:"season,omitempty"`
+	WatchedAt    time.Time `json:"watchedAt"`
+}
+
+// Binge is the calendar week (Monday to Sunday, UTC) with the most episodes
+// watched, movies counting as watches but not as episodes.
+type Binge struct {
+	WeekStart string        `json:"weekStart"`
+	WeekEnd   string        `json:"weekEnd"`
+	Episodes  int           `json:"episodes"`
+	Watches   int           `json:"watches"`
+	Titles    []ReviewTitle `json:"titles"`
+}
+
+type ReviewTitle struct {
+	TitleId      string `json:"titleId"`
+	PrimaryTitle string `json:"primaryTitle"`
+}
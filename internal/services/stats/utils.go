@@ -0,0 +1,22 @@
+package stats
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrGroupNotFound    = errors.New("group not found")
+	ErrInvalidDate      = errors.New("from and to must be dates in the form YYYY-MM-DD")
+	ErrInvalidDateRange = errors.New("from must not be after to")
+	ErrInvalidYear      = errors.New("year must be a four-digit year")
+	ErrUserNotFound     = errors.New("user not found")
+)
+
+var ErrorMap = map[error]int{
+	ErrGroupNotFound:    http.StatusNotFound,
+	ErrInvalidDate:      http.StatusBadRequest,
+	ErrInvalidDateRange: http.StatusBadRequest,
+	ErrInvalidYear:      http.StatusBadRequest,
+	ErrUserNotFound:     http.StatusNotFound,
+}
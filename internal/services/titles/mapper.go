@@ -49,6 +49,7 @@ func MapDbTitleToApiTitle(title models.Title) Title {
 		Seasons:         MapDbSeasonsToImdbSeasons(title.Seasons),
 		Episodes:        MapDbEpisodesToImdbEpisodes(title.Episodes),
 		OriginCountries: originCountries,
+		Source:          title.Source,
 		AddedAt:         title.AddedAt,
 		UpdatedAt:       title.UpdatedAt,
 	}
@@ -24,6 +24,7 @@ func MapProviderTitleToDb(t titleprovider.Title) models.Title {
 		Stars:          mapProviderPersons(t.Stars),
 		Seasons:        mapProviderSeasons(t.Seasons),
 		Episodes:       mapProviderEpisodes(t.Episodes),
+		Source:         t.Source,
 	}
 	if t.Metacritic != nil {
 		db.Metacritic = &models.Metacritic{Score: t.Metacritic.Score, ReviewCount: t.Metacritic.ReviewCount}
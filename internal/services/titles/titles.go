@@ -10,6 +10,8 @@ import (
 	"github.com/lealre/movies-backend/internal/logx"
 	"github.com/lealre/movies-backend/internal/store"
 	"github.com/lealre/movies-backend/internal/titleprovider"
+	"github.com/lealre/movies-backend/internal/titleprovider/quota"
+	"github.com/lealre/movies-backend/internal/titleprovider/titleref"
 )
 
 // GetPageOfTitles returns a page of titles from the native titles
@@ -59,13 +61,14 @@ func AddNewTitle(db store.Store, provider titleprovider.Provider, ctx context.Co
 		if errors.Is(err, titleprovider.ErrTitleNotFound) {
 			return Title{}, ErrTitleNotFound
 		}
-		return Title{}, err
+		return Title{}, providerError(err)
 	}
 	if providerTitle.Type == "tvSeries" || providerTitle.Type == "tvMiniSeries" {
 		logger.Printf("Title %s is a TV series with %d seasons", titleId, len(providerTitle.Seasons))
 	}
 
 	title := MapProviderTitleToDb(*providerTitle)
+	title.Source = titleprovider.SourceOf(provider, providerTitle)
 
 	// Set missing fields
 	now := time.Now()
@@ -116,11 +119,55 @@ func GetEpisodes(db store.Store, ctx context.Context, titleId string) ([]Episode
 func SearchTitles(provider titleprovider.Provider, ctx context.Context, searchQuery string, limit int) ([]Title, error) {
 	items, err := provider.SearchTitles(ctx, searchQuery, limit)
 	if err != nil {
-		return nil, err
+		return nil, providerError(err)
 	}
 	return MapProviderSearchItemsToTitles(items), nil
 }
 
+// ResolveTitleReference returns the IMDb ID a title reference names — an IMDb
+// link or ID, or a TMDB, Letterboxd or Trakt link (see the titleref package).
+// A reference that cannot be resolved is a *titleref.Error, answered with
+// TitleReferenceStatus; a provider failure on the way is returned like
+// AddNewTitle's.
+func ResolveTitleReference(resolver *titleref.Resolver, ctx context.Context, reference string) (string, error) {
+	titleId, err := resolver.Resolve(ctx, reference)
+	if err != nil {
+		var refErr *titleref.Error
+		if errors.As(err, &refErr) {
+			return "", refErr
+		}
+		return "", providerError(err)
+	}
+	return titleId, nil
+}
+
+// providerError translates a provider that is out of quota into
+// ErrProviderQuotaExhausted, which handlers map via ErrorMap; anything else
+// is returned as is.
+func providerError(err error) error {
+	if errors.Is(err, titleprovider.ErrQuotaExhausted) {
+		return ErrProviderQuotaExhausted
+	}
+	return err
+}
+
+// GetProviderQuota reports the title provider requests made on now's day (UTC)
+// against the configured daily budgets, as counted across the server and the
+// titles routine. A store that does not count requests has nothing to report
+// (ErrProviderRequestsNotCounted).
+func GetProviderQuota(db store.Store, ctx context.Context, now time.Time) (quota.Report, error) {
+	counts, ok := db.(store.ProviderQuota)
+	if !ok {
+		return quota.Report{}, ErrProviderRequestsNotCounted
+	}
+	budgets := []quota.Budget{{
+		Provider: "omdb",
+		Budget:   config.OmdbDailyBudget(),
+		Reserve:  config.OmdbInteractiveReserve(),
+	}}
+	return quota.Today(ctx, counts, budgets, now)
+}
+
 // TitleExists reports whether a title with the given id exists. It is a thin
 // service passthrough so handlers reach the DB only through the service layer.
 func TitleExists(db store.Store, ctx context.Context, titleId string) (bool, error) {
@@ -23,6 +23,7 @@ type Title struct {
 	Seasons         []Seasons  `json:"seasons"`
 	Episodes        []Episode  `json:"episodes"`
 	OriginCountries []string   `json:"originCountries"`
+	Source          string     `json:"source,omitempty"`
 	AddedAt         *time.Time `json:"addedAt,omitempty"`
 	UpdatedAt       *time.Time `json:"updatedAt,omitempty"`
 }
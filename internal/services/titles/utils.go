@@ -3,6 +3,9 @@ package titles
 import (
 	"errors"
 	"net/http"
+
+	"github.com/lealre/movies-backend/internal/titleprovider"
+	"github.com/lealre/movies-backend/internal/titleprovider/titleref"
 )
 
 // Service-level errors for the titles package. Handlers translate these to HTTP
@@ -11,11 +14,33 @@ import (
 var (
 	ErrTitleNotFound      = errors.New("title not found")
 	ErrTitleAlreadyExists = errors.New("title already added")
-	ErrInvalidIMDbURL     = errors.New("invalid IMDb title URL")
+	// ErrProviderQuotaExhausted is the title provider having spent its daily
+	// request budget; it clears at midnight UTC.
+	ErrProviderQuotaExhausted = errors.New("title provider daily quota exhausted, try again tomorrow")
+	// ErrProviderRequestsNotCounted is a store that keeps no count of title
+	// provider requests, so there is no quota to report.
+	ErrProviderRequestsNotCounted = errors.New("title provider requests are not counted")
 )
 
 var ErrorMap = map[error]int{
 	ErrTitleNotFound:      http.StatusNotFound,
 	ErrTitleAlreadyExists: http.StatusBadRequest,
-	ErrInvalidIMDbURL:     http.StatusBadRequest,
+
+	ErrProviderQuotaExhausted:     http.StatusServiceUnavailable,
+	ErrProviderRequestsNotCounted: http.StatusNotFound,
+}
+
+// TitleReferenceStatus is the HTTP status for a title reference that could
+// not be resolved. These are *titleref.Error values rather than ErrorMap
+// entries because they carry detail — the source, the reason, the candidates
+// of an ambiguous reference — that a fixed error cannot.
+func TitleReferenceStatus(err *titleref.Error) int {
+	switch {
+	case errors.Is(err, titleref.ErrAmbiguous):
+		return http.StatusConflict
+	case errors.Is(err, titleprovider.ErrTitleNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
 }
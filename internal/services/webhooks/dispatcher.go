@@ -0,0 +1,246 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/config"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// The headers every delivery carries. The signature covers the timestamp as
+// well as the body, so a receiver that rejects old timestamps cannot be fed a
+// captured delivery again later.
+const (
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	// dispatchInterval is how often the dispatcher looks for due deliveries.
+	// It is also the worst extra delay on a new one, which is fine for a
+	// notification nobody is waiting on a spinner for.
+	dispatchInterval = 5 * time.Second
+	// dispatchBatchSize is how many deliveries are sent at once.
+	dispatchBatchSize = 20
+	// backoffBase and backoffCap bound the wait before a retry: 30s after the
+	// first failure, doubling after each one, never more than six hours.
+	backoffBase = 30 * time.Second
+	backoffCap  = 6 * time.Hour
+)
+
+// Dispatcher sends queued deliveries and records what happened to each. A
+// delivery is claimed with a lease rather than locked for the duration of the
+// request, so several dispatchers can share the queue and one that dies only
+// delays its batch until the lease runs out.
+//
+// Any 2xx is a success. Anything else — another status, a redirect, a timeout,
+// a refused connection — is a failure, retried with exponential back-off until
+// it has been tried WEBHOOK_MAX_ATTEMPTS times, when it is marked failed for
+// good. Failures also count against the endpoint, which is disabled after
+// WEBHOOK_DISABLE_AFTER in a row.
+type Dispatcher struct {
+	db           store.Store
+	client       *http.Client
+	interval     time.Duration
+	maxAttempts  int
+	disableAfter int
+}
+
+// NewDispatcher's client dials only public addresses, unless
+// WEBHOOK_ALLOW_PRIVATE_HOSTS says otherwise: see validateURL for why, and
+// publicOnly for how.
+func NewDispatcher(db store.Store) *Dispatcher {
+	dialer := &net.Dialer{}
+	if !config.WebhookAllowPrivateHosts() {
+		dialer.Control = publicOnly
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// No proxy: through one, the address dialed is the proxy's, and checking
+	// it would say nothing about where the delivery ends up.
+	transport.Proxy = nil
+
+	return &Dispatcher{
+		db: db,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.WebhookTimeout(),
+			// A redirect is answered, not followed: the owner registered one
+			// URL, and the signed body goes to it and nowhere else.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		interval:     dispatchInterval,
+		maxAttempts:  config.WebhookMaxAttempts(),
+		disableAfter: config.WebhookDisableAfter(),
+	}
+}
+
+// publicOnly is the dispatcher's dialer Control. It runs on the address about
+// to be connected to, after the name was resolved, so an endpoint whose host
+// passed validateURL and has since been pointed at an internal address — DNS
+// rebinding — is refused here rather than posted to.
+func publicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !isPublic(addr) {
+		return ErrURLNotAllowed
+	}
+	return nil
+}
+
+// Run sends due deliveries until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		d.dispatchDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchDue sends batch after batch until nothing is due.
+func (d *Dispatcher) dispatchDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		// Long enough for the whole batch, which is sent concurrently, to time
+		// out with room to spare for recording the outcomes.
+		leaseUntil := time.Now().Add(d.client.Timeout + time.Minute)
+		batch, err := d.db.ClaimWebhookDeliveries(ctx, dispatchBatchSize, leaseUntil)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("ERROR: claiming webhook deliveries failed: %v", err)
+			}
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, dispatch := range batch {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				attempt := d.send(ctx, dispatch)
+				// Recorded even if ctx was cancelled mid-send: the attempt
+				// happened, and the lease would otherwise repeat it unrecorded.
+				if err := d.db.CompleteWebhookDelivery(context.WithoutCancel(ctx), attempt); err != nil {
+					log.Printf("ERROR: recording webhook delivery %s failed: %v", dispatch.Delivery.Id, err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(batch) < dispatchBatchSize {
+			return
+		}
+	}
+}
+
+// send POSTs one delivery and says what came of it.
+func (d *Dispatcher) send(ctx context.Context, dispatch models.WebhookDispatch) models.WebhookAttempt {
+	delivery := dispatch.Delivery
+	now := time.Now()
+	attempt := models.WebhookAttempt{
+		DeliveryId:   delivery.Id,
+		EndpointId:   delivery.EndpointId,
+		At:           now,
+		DisableAfter: d.disableAfter,
+	}
+
+	status, err := d.post(ctx, dispatch, now)
+	if status != 0 {
+		attempt.ResponseStatus = &status
+	}
+	if err == nil {
+		attempt.Succeeded = true
+		return attempt
+	}
+
+	attempt.Error = err.Error()
+	if attempts := delivery.Attempts + 1; attempts < d.maxAttempts {
+		retryAt := now.Add(backoff(attempts))
+		attempt.RetryAt = &retryAt
+	}
+	return attempt
+}
+
+// post sends the request and returns the response status, if there was a
+// response, and an error unless it was a 2xx.
+func (d *Dispatcher) post(ctx context.Context, dispatch models.WebhookDispatch, now time.Time) (int, error) {
+	delivery := dispatch.Delivery
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dispatch.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "movies-backend-webhooks")
+	req.Header.Set(HeaderDelivery, delivery.Id)
+	req.Header.Set(HeaderEvent, delivery.Kind)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(dispatch.Secret, timestamp, delivery.Body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drained, a little of it, so the connection can be reused; what the
+	// receiver says is not kept.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the X-Webhook-Signature value for body sent at timestamp (Unix
+// seconds): "sha256=" and the hex HMAC-SHA256, keyed with the endpoint's
+// secret, of the timestamp, a dot, and the body. A receiver recomputes it from
+// the raw body and the X-Webhook-Timestamp header and compares in constant
+// time.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff is the wait before trying a delivery again after its attempts-th
+// failure.
+func backoff(attempts int) time.Duration {
+	wait := backoffBase
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= backoffCap {
+			return backoffCap
+		}
+	}
+	return wait
+}
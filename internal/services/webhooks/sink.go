@@ -0,0 +1,70 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// Sink is the activity.Sink that turns events into webhook deliveries: one per
+// enabled endpoint of the event's group that subscribes to its kind. It only
+// queues them — the Dispatcher sends them — so a slow or dead endpoint costs
+// the request, or the outbox relay, nothing but an insert.
+//
+// It is meant to sit beside activity.StoreSink in an activity.Fanout, never to
+// replace it. Handed the same event twice, as the relay may, it queues nothing
+// the second time: a delivery is unique per endpoint and event id.
+type Sink struct{ db store.Store }
+
+func NewSink(db store.Store) Sink { return Sink{db: db} }
+
+func (s Sink) Append(ctx context.Context, events []models.ActivityEvent) error {
+	var deliveries []models.WebhookDelivery
+	for _, e := range events {
+		endpoints, err := s.db.GetWebhookEndpointsForEvent(ctx, e.GroupId, e.Kind)
+		if err != nil {
+			return err
+		}
+		if len(endpoints) == 0 {
+			continue
+		}
+		body, err := json.Marshal(mapEvent(e))
+		if err != nil {
+			return err
+		}
+		for _, endpoint := range endpoints {
+			deliveries = append(deliveries, models.WebhookDelivery{
+				EndpointId: endpoint.Id,
+				EventId:    e.Id,
+				Kind:       e.Kind,
+				Body:       body,
+			})
+		}
+	}
+	return s.db.EnqueueWebhookDeliveries(ctx, deliveries)
+}
+
+func mapEvent(e models.ActivityEvent) Event {
+	payload := e.Payload
+	if payload == nil {
+		payload = map[string]any{}
+	}
+	createdAt := e.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	return Event{
+		Id:        e.Id,
+		Kind:      e.Kind,
+		GroupId:   e.GroupId,
+		ActorId:   e.ActorId,
+		ActorName: e.ActorName,
+		TitleId:   e.TitleId,
+		TitleName: e.TitleName,
+		Payload:   payload,
+		CreatedAt: createdAt.UTC(),
+	}
+}
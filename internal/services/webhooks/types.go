@@ -0,0 +1,72 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type CreateWebhookRequest struct {
+	URL   string   `json:"url"`
+	Kinds []string `json:"kinds"`
+}
+
+// UpdateWebhookRequest changes only what it carries. Enabling an endpoint
+// clears its run of failures, so one more failure does not disable it again.
+type UpdateWebhookRequest struct {
+	URL     *string   `json:"url"`
+	Kinds   *[]string `json:"kinds"`
+	Enabled *bool     `json:"enabled"`
+}
+
+type Webhook struct {
+	Id                  string     `json:"id"`
+	GroupId             string     `json:"groupId"`
+	URL                 string     `json:"url"`
+	Kinds               []string   `json:"kinds"`
+	Enabled             bool       `json:"enabled"`
+	DisabledAt          *time.Time `json:"disabledAt"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+	// Secret is only ever set in the response to the request that created
+	// the webhook: it is shown once, and the owner keeps it.
+	Secret string `json:"secret,omitempty"`
+}
+
+type Delivery struct {
+	Id             string          `json:"id"`
+	Seq            int64           `json:"seq"`
+	EventId        string          `json:"eventId"`
+	Kind           string          `json:"kind"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt"`
+	LastAttemptAt  *time.Time      `json:"lastAttemptAt"`
+	ResponseStatus *int            `json:"responseStatus"`
+	LastError      string          `json:"lastError"`
+	DeliveredAt    *time.Time      `json:"deliveredAt"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	Body           json.RawMessage `json:"body"`
+}
+
+// Deliveries is a page of an endpoint's delivery log, with the same keyset
+// cursor as the activity feed.
+type Deliveries struct {
+	Deliveries []Delivery `json:"deliveries"`
+	NextBefore *int64     `json:"nextBefore"`
+	HasMore    bool       `json:"hasMore"`
+}
+
+// Event is the body POSTed to an endpoint: the activity event as the feed
+// shows it, minus the reader-specific parts.
+type Event struct {
+	Id        string         `json:"id"`
+	Kind      string         `json:"kind"`
+	GroupId   string         `json:"groupId"`
+	ActorId   string         `json:"actorId"`
+	ActorName string         `json:"actorName"`
+	TitleId   *string        `json:"titleId,omitempty"`
+	TitleName *string        `json:"titleName,omitempty"`
+	Payload   map[string]any `json:"payload"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
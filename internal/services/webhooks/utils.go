@@ -0,0 +1,35 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrGroupNotFound       = errors.New("group not found")
+	ErrGroupNotOwnedByUser = errors.New("only the group owner can perform this action")
+	ErrWebhookNotFound     = errors.New("webhook not found")
+	ErrDeliveryNotFound    = errors.New("webhook delivery not found")
+	ErrInvalidURL          = errors.New("url must be an absolute http or https URL")
+	ErrURLNotAllowed       = errors.New("url must point to a public address, not a loopback, private or link-local one")
+	ErrUnresolvableHost    = errors.New("url's host could not be resolved")
+	ErrKindsRequired       = errors.New("kinds must name at least one activity kind")
+	ErrUnknownKind         = errors.New("kinds contains an unknown activity kind")
+	// ErrWebhookDisabled refuses a redelivery to a disabled endpoint: it would
+	// only sit in the queue until the endpoint is enabled again, which is the
+	// thing the owner has to do first anyway.
+	ErrWebhookDisabled = errors.New("webhook is disabled; enable it before redelivering")
+)
+
+var ErrorMap = map[error]int{
+	ErrGroupNotFound:       http.StatusNotFound,
+	ErrGroupNotOwnedByUser: http.StatusForbidden,
+	ErrWebhookNotFound:     http.StatusNotFound,
+	ErrDeliveryNotFound:    http.StatusNotFound,
+	ErrInvalidURL:          http.StatusBadRequest,
+	ErrURLNotAllowed:       http.StatusBadRequest,
+	ErrUnresolvableHost:    http.StatusBadRequest,
+	ErrKindsRequired:       http.StatusBadRequest,
+	ErrUnknownKind:         http.StatusBadRequest,
+	ErrWebhookDisabled:     http.StatusConflict,
+}
@@ -0,0 +1,367 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/activity"
+	"github.com/lealre/movies-backend/internal/config"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// secretPrefix marks a signing secret as one, so one pasted into the wrong
+// place is recognisable.
+const secretPrefix = "whsec_"
+
+// Create registers an endpoint on a group the caller owns. The response is the
+// only one that carries the signing secret.
+func Create(db store.Store, ctx context.Context, groupId, userId string, req CreateWebhookRequest) (Webhook, error) {
+	if err := requireOwner(db, ctx, groupId, userId); err != nil {
+		return Webhook{}, err
+	}
+	if err := validateURL(ctx, req.URL); err != nil {
+		return Webhook{}, err
+	}
+	kinds, err := normalizeKinds(req.Kinds)
+	if err != nil {
+		return Webhook{}, err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	endpoint, err := db.CreateWebhookEndpoint(ctx, models.WebhookEndpoint{
+		GroupId:   groupId,
+		URL:       req.URL,
+		Secret:    secret,
+		Kinds:     kinds,
+		CreatedBy: userId,
+	})
+	if err != nil {
+		return Webhook{}, err
+	}
+	webhook := mapEndpoint(endpoint)
+	webhook.Secret = endpoint.Secret
+	return webhook, nil
+}
+
+func List(db store.Store, ctx context.Context, groupId, userId string) ([]Webhook, error) {
+	if err := requireOwner(db, ctx, groupId, userId); err != nil {
+		return nil, err
+	}
+	endpoints, err := db.GetWebhookEndpoints(ctx, groupId)
+	if err != nil {
+		return nil, err
+	}
+	webhooks := make([]Webhook, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		webhooks = append(webhooks, mapEndpoint(endpoint))
+	}
+	return webhooks, nil
+}
+
+// Update changes an endpoint's URL, kinds, or whether it is enabled. Enabling
+// a disabled endpoint clears its run of failures and lets the deliveries that
+// were waiting on it go out; disabling one holds them.
+func Update(db store.Store, ctx context.Context, groupId, webhookId, userId string, req UpdateWebhookRequest) (Webhook, error) {
+	endpoint, err := getOwnedEndpoint(db, ctx, groupId, webhookId, userId)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	if req.URL != nil {
+		if err := validateURL(ctx, *req.URL); err != nil {
+			return Webhook{}, err
+		}
+		endpoint.URL = *req.URL
+	}
+	if req.Kinds != nil {
+		kinds, err := normalizeKinds(*req.Kinds)
+		if err != nil {
+			return Webhook{}, err
+		}
+		endpoint.Kinds = kinds
+	}
+	if req.Enabled != nil {
+		switch {
+		case *req.Enabled:
+			endpoint.DisabledAt = nil
+			endpoint.ConsecutiveFailures = 0
+		case endpoint.DisabledAt == nil:
+			now := time.Now()
+			endpoint.DisabledAt = &now
+		}
+	}
+
+	updated, err := db.UpdateWebhookEndpoint(ctx, endpoint)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return Webhook{}, ErrWebhookNotFound
+		}
+		return Webhook{}, err
+	}
+	return mapEndpoint(updated), nil
+}
+
+// Delete removes an endpoint along with its delivery log and anything still
+// queued for it.
+func Delete(db store.Store, ctx context.Context, groupId, webhookId, userId string) error {
+	if err := requireOwner(db, ctx, groupId, userId); err != nil {
+		return err
+	}
+	if err := db.DeleteWebhookEndpoint(ctx, groupId, webhookId); err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return ErrWebhookNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ListDeliveries returns an endpoint's delivery log newest first, paged like
+// the activity feed: limit is normalized here, and one extra row answers
+// whether there is another page.
+func ListDeliveries(db store.Store, ctx context.Context, groupId, webhookId, userId string, before *int64, limit int) (Deliveries, error) {
+	if _, err := getOwnedEndpoint(db, ctx, groupId, webhookId, userId); err != nil {
+		return Deliveries{}, err
+	}
+	limit, _ = config.NormalizePageParams(limit, 1)
+
+	rows, err := db.GetWebhookDeliveries(ctx, webhookId, before, limit+1)
+	if err != nil {
+		return Deliveries{}, err
+	}
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	deliveries := make([]Delivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, mapDelivery(row))
+	}
+	page := Deliveries{Deliveries: deliveries, HasMore: hasMore}
+	if hasMore && len(deliveries) > 0 {
+		last := deliveries[len(deliveries)-1].Seq
+		page.NextBefore = &last
+	}
+	return page, nil
+}
+
+// Redeliver queues a delivery to be sent again now, whatever became of it
+// before, with a fresh set of attempts. The body is the one first queued, so
+// the receiver sees the same event id and can tell it is a repeat.
+func Redeliver(db store.Store, ctx context.Context, groupId, webhookId, deliveryId, userId string) (Delivery, error) {
+	endpoint, err := getOwnedEndpoint(db, ctx, groupId, webhookId, userId)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if endpoint.DisabledAt != nil {
+		return Delivery{}, ErrWebhookDisabled
+	}
+
+	delivery, err := db.RedeliverWebhookDelivery(ctx, webhookId, deliveryId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return Delivery{}, ErrDeliveryNotFound
+		}
+		return Delivery{}, err
+	}
+	return mapDelivery(delivery), nil
+}
+
+// requireOwner lets only the group's owner manage its webhooks: an endpoint
+// receives every member's activity, so adding one is the owner's call, the
+// same as sharing the group's taste with recommendations.
+func requireOwner(db store.Store, ctx context.Context, groupId, userId string) error {
+	group, err := db.GetGroupById(ctx, groupId, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return ErrGroupNotFound
+		}
+		return err
+	}
+	if group.OwnerId != userId {
+		return ErrGroupNotOwnedByUser
+	}
+	return nil
+}
+
+func getOwnedEndpoint(db store.Store, ctx context.Context, groupId, webhookId, userId string) (models.WebhookEndpoint, error) {
+	if err := requireOwner(db, ctx, groupId, userId); err != nil {
+		return models.WebhookEndpoint{}, err
+	}
+	endpoint, err := db.GetWebhookEndpointById(ctx, groupId, webhookId)
+	if err != nil {
+		if errors.Is(err, store.ErrRecordNotFound) {
+			return models.WebhookEndpoint{}, ErrWebhookNotFound
+		}
+		return models.WebhookEndpoint{}, err
+	}
+	return endpoint, nil
+}
+
+// validateURL checks raw is an absolute http or https URL, and that every
+// address its host resolves to is a public one. Without the second check an
+// owner could point the dispatcher at the server's own network — a database,
+// an admin port, a cloud metadata endpoint — and read the answers off the
+// delivery log. The dispatcher checks again when it dials (see publicOnly): a
+// name that resolves to a public address now can resolve elsewhere later.
+func validateURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	if config.WebhookAllowPrivateHosts() {
+		return nil
+	}
+
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !isPublic(addr) {
+			return ErrURLNotAllowed
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return ErrUnresolvableHost
+	}
+	for _, addr := range addrs {
+		if !isPublic(addr) {
+			return ErrURLNotAllowed
+		}
+	}
+	return nil
+}
+
+// deniedPrefixes are the ranges a webhook is never delivered to: every one
+// that reaches this host, its network or something on a provider's side of it
+// rather than a receiver out on the internet. An explicit list, because the
+// net/netip predicates stop short of several that matter here — CGNAT space
+// holds one cloud's metadata service, and Linux routes 0.0.0.0/8 to itself.
+var deniedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this network"; the local host on Linux
+	netip.MustParsePrefix("10.0.0.0/8"),     // private
+	netip.MustParsePrefix("100.64.0.0/10"),  // shared (CGNAT) address space
+	netip.MustParsePrefix("127.0.0.0/8"),    // loopback
+	netip.MustParsePrefix("169.254.0.0/16"), // link-local, cloud metadata
+	netip.MustParsePrefix("172.16.0.0/12"),  // private
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("192.88.99.0/24"), // 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"), // private
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("224.0.0.0/4"),    // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved, and broadcast
+	netip.MustParsePrefix("::/96"),          // unspecified, loopback, IPv4-compatible
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("100::/64"),       // discard-only
+	netip.MustParsePrefix("2001::/32"),      // Teredo, which tunnels to an IPv4 host
+	netip.MustParsePrefix("fc00::/7"),       // unique local
+	netip.MustParsePrefix("fe80::/10"),      // link-local
+	netip.MustParsePrefix("fec0::/10"),      // site-local, deprecated
+	netip.MustParsePrefix("ff00::/8"),       // multicast
+}
+
+// The IPv6 ranges that carry an IPv4 address inside them, and where: the
+// address is judged by the IPv4 one, since that is the host it reaches.
+var (
+	nat64Prefix = netip.MustParsePrefix("64:ff9b::/96") // the IPv4 address is the last 4 bytes
+	sixToFour   = netip.MustParsePrefix("2002::/16")    // the IPv4 address is bytes 2 to 5
+)
+
+// isPublic reports whether addr is somewhere a webhook may be delivered: in
+// none of deniedPrefixes. IPv4 addresses written as IPv6 ones — mapped, NAT64
+// or 6to4 — are judged by the IPv4 address inside.
+func isPublic(addr netip.Addr) bool {
+	// Prefix.Contains never matches an address with a zone.
+	addr = addr.Unmap().WithZone("")
+	if addr.Is6() {
+		b := addr.As16()
+		switch {
+		case nat64Prefix.Contains(addr):
+			return isPublic(netip.AddrFrom4([4]byte(b[12:16])))
+		case sixToFour.Contains(addr):
+			return isPublic(netip.AddrFrom4([4]byte(b[2:6])))
+		}
+	}
+	for _, prefix := range deniedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeKinds checks every kind is one activity records and drops repeats,
+// keeping the order the owner gave.
+func normalizeKinds(kinds []string) ([]string, error) {
+	if len(kinds) == 0 {
+		return nil, ErrKindsRequired
+	}
+	out := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		if !slices.Contains(activity.Kinds, kind) {
+			return nil, ErrUnknownKind
+		}
+		if !slices.Contains(out, kind) {
+			out = append(out, kind)
+		}
+	}
+	return out, nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+func mapEndpoint(e models.WebhookEndpoint) Webhook {
+	return Webhook{
+		Id:                  e.Id,
+		GroupId:             e.GroupId,
+		URL:                 e.URL,
+		Kinds:               e.Kinds,
+		Enabled:             e.DisabledAt == nil,
+		DisabledAt:          e.DisabledAt,
+		ConsecutiveFailures: e.ConsecutiveFailures,
+		CreatedAt:           e.CreatedAt,
+		UpdatedAt:           e.UpdatedAt,
+	}
+}
+
+// mapDelivery shows NextAttemptAt only while the delivery is still pending:
+// for a finished one it is a leftover, not a plan.
+func mapDelivery(d models.WebhookDelivery) Delivery {
+	delivery := Delivery{
+		Id:             d.Id,
+		Seq:            d.Seq,
+		EventId:        d.EventId,
+		Kind:           d.Kind,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastAttemptAt:  d.LastAttemptAt,
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+		Body:           d.Body,
+	}
+	if d.Status == models.WebhookDeliveryPending {
+		next := d.NextAttemptAt
+		delivery.NextAttemptAt = &next
+	}
+	return delivery
+}
@@ -0,0 +1,356 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lealre/movies-backend/internal/activity"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/store"
+)
+
+// fakeStore is the webhook half of the store, in memory. Every other method
+// panics through the nil embedded interface, so a test that reaches one fails
+// loudly.
+type fakeStore struct {
+	store.Store
+
+	mu         sync.Mutex
+	group      models.Group
+	endpoints  []models.WebhookEndpoint
+	queued     []models.WebhookDelivery
+	dispatches []models.WebhookDispatch
+	attempts   []models.WebhookAttempt
+}
+
+func (s *fakeStore) GetGroupById(_ context.Context, groupId, _ string) (models.Group, error) {
+	if groupId != s.group.Id {
+		return models.Group{}, store.ErrRecordNotFound
+	}
+	return s.group, nil
+}
+
+func (s *fakeStore) CreateWebhookEndpoint(_ context.Context, endpoint models.WebhookEndpoint) (models.WebhookEndpoint, error) {
+	endpoint.Id = "wh" + strconv.Itoa(len(s.endpoints)+1)
+	s.endpoints = append(s.endpoints, endpoint)
+	return endpoint, nil
+}
+
+func (s *fakeStore) GetWebhookEndpointsForEvent(_ context.Context, groupId, kind string) ([]models.WebhookEndpoint, error) {
+	var out []models.WebhookEndpoint
+	for _, e := range s.endpoints {
+		for _, k := range e.Kinds {
+			if e.GroupId == groupId && k == kind && e.DisabledAt == nil {
+				out = append(out, e)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStore) EnqueueWebhookDeliveries(_ context.Context, deliveries []models.WebhookDelivery) error {
+	s.queued = append(s.queued, deliveries...)
+	return nil
+}
+
+func (s *fakeStore) ClaimWebhookDeliveries(_ context.Context, limit int, _ time.Time) ([]models.WebhookDispatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch := s.dispatches[:min(limit, len(s.dispatches))]
+	s.dispatches = s.dispatches[len(batch):]
+	return batch, nil
+}
+
+func (s *fakeStore) CompleteWebhookDelivery(_ context.Context, attempt models.WebhookAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts = append(s.attempts, attempt)
+	return nil
+}
+
+func TestSign(t *testing.T) {
+	// Computed independently, so a change to what is signed cannot pass by
+	// changing both sides of the comparison.
+	require.Equal(t,
+		"sha256=6a85cb117c993612a34c72b4cfb5a16baed25a80d26e298ce0e6671320fd07c8",
+		Sign("whsec_test", 1700000000, []byte(`{"id":"e1"}`)))
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, backoff(1))
+	require.Equal(t, time.Minute, backoff(2))
+	require.Equal(t, 4*time.Minute, backoff(4))
+	require.Equal(t, backoffCap, backoff(12))
+	require.Equal(t, backoffCap, backoff(1000), "a large attempt count must not overflow past the cap")
+}
+
+func TestIsPublic(t *testing.T) {
+	cases := map[string]bool{
+		"203.0.113.10":         true,
+		"8.8.8.8":              true,
+		"2606:4700:4700::1111": true,
+		"0.0.0.0":              false,
+		"0.1.2.3":              false,
+		"10.0.0.1":             false,
+		"100.64.0.1":           false,
+		"100.100.100.200":      false,
+		"127.0.0.1":            false,
+		"169.254.169.254":      false,
+		"172.16.5.4":           false,
+		"192.0.0.170":          false,
+		"192.88.99.1":          false,
+		"192.168.1.10":         false,
+		"198.18.0.1":           false,
+		"198.19.255.254":       false,
+		"224.0.0.251":          false,
+		"239.255.255.250":      false,
+		"240.0.0.1":            false,
+		"255.255.255.255":      false,
+		"::":                   false,
+		"::1":                  false,
+		"::ffff:10.0.0.1":      false,
+		"::ffff:8.8.8.8":       true,
+		"64:ff9b::a00:1":       false,
+		"64:ff9b::808:808":     true,
+		"64:ff9b:1::1":         false,
+		"100::1":               false,
+		"2001:0:4136:e378::1":  false,
+		"2002:a00:1::1":        false,
+		"2002:c0a8:10a::1":     false,
+		"2002:808:808::1":      true,
+		"fd00::1":              false,
+		"fe80::1":              false,
+		"fe80::1%eth0":         false,
+		"fec0::1":              false,
+		"ff02::1":              false,
+		"ff0e::1":              false,
+	}
+	for raw, want := range cases {
+		require.Equal(t, want, isPublic(netip.MustParseAddr(raw)), raw)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	db := &fakeStore{group: models.Group{Id: "g1", OwnerId: "owner"}}
+	ctx := context.Background()
+	// Literal addresses keep these tests off the network: a host name would be
+	// resolved. 203.0.113.0/24 is reserved for documentation, and public.
+	const public = "https://203.0.113.10/hook"
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "")
+
+	t.Run("returns the secret once and keeps the kinds without repeats", func(t *testing.T) {
+		webhook, err := Create(db, ctx, "g1", "owner", CreateWebhookRequest{
+			URL:   public,
+			Kinds: []string{activity.KindTitleAdded, activity.KindRatingAdded, activity.KindTitleAdded},
+		})
+		require.NoError(t, err)
+		require.Regexp(t, `^whsec_[0-9a-f]{64}$`, webhook.Secret)
+		require.Equal(t, []string{activity.KindTitleAdded, activity.KindRatingAdded}, webhook.Kinds)
+		require.True(t, webhook.Enabled)
+
+		listed := mapEndpoint(db.endpoints[0])
+		require.Empty(t, listed.Secret, "only the create response may carry the secret")
+	})
+
+	t.Run("rejects what cannot be delivered to", func(t *testing.T) {
+		cases := map[string]struct {
+			req  CreateWebhookRequest
+			want error
+		}{
+			"relative url":  {CreateWebhookRequest{URL: "/hook", Kinds: []string{activity.KindTitleAdded}}, ErrInvalidURL},
+			"other scheme":  {CreateWebhookRequest{URL: "ftp://example.com", Kinds: []string{activity.KindTitleAdded}}, ErrInvalidURL},
+			"no kinds":      {CreateWebhookRequest{URL: public}, ErrKindsRequired},
+			"unknown kinds": {CreateWebhookRequest{URL: public, Kinds: []string{"title_teleported"}}, ErrUnknownKind},
+			"loopback":      {CreateWebhookRequest{URL: "http://127.0.0.1:5432", Kinds: []string{activity.KindTitleAdded}}, ErrURLNotAllowed},
+			"ipv6 loopback": {CreateWebhookRequest{URL: "http://[::1]/hook", Kinds: []string{activity.KindTitleAdded}}, ErrURLNotAllowed},
+			"mapped ipv4":   {CreateWebhookRequest{URL: "http://[::ffff:10.0.0.1]/hook", Kinds: []string{activity.KindTitleAdded}}, ErrURLNotAllowed},
+			"private":       {CreateWebhookRequest{URL: "http://192.168.1.10/admin", Kinds: []string{activity.KindTitleAdded}}, ErrURLNotAllowed},
+			"link-local":    {CreateWebhookRequest{URL: "http://169.254.169.254/latest/meta-data", Kinds: []string{activity.KindTitleAdded}}, ErrURLNotAllowed},
+			"unspecified":   {CreateWebhookRequest{URL: "http://0.0.0.0:8080", Kinds: []string{activity.KindTitleAdded}}, ErrURLNotAllowed},
+			"resolves home": {CreateWebhookRequest{URL: "http://localhost:8080", Kinds: []string{activity.KindTitleAdded}}, ErrURLNotAllowed},
+		}
+		for name, tc := range cases {
+			_, err := Create(db, ctx, "g1", "owner", tc.req)
+			require.ErrorIs(t, err, tc.want, name)
+		}
+	})
+
+	t.Run("internal addresses are allowed only when configured to be", func(t *testing.T) {
+		t.Setenv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "true")
+		_, err := Create(db, ctx, "g1", "owner", CreateWebhookRequest{URL: "http://127.0.0.1:8080", Kinds: []string{activity.KindTitleAdded}})
+		require.NoError(t, err)
+	})
+
+	t.Run("only the owner can register one", func(t *testing.T) {
+		req := CreateWebhookRequest{URL: public, Kinds: []string{activity.KindTitleAdded}}
+		_, err := Create(db, ctx, "g1", "member", req)
+		require.ErrorIs(t, err, ErrGroupNotOwnedByUser)
+		_, err = Create(db, ctx, "g2", "owner", req)
+		require.ErrorIs(t, err, ErrGroupNotFound)
+	})
+}
+
+func TestSink(t *testing.T) {
+	disabled := time.Now()
+	db := &fakeStore{endpoints: []models.WebhookEndpoint{
+		{Id: "titles", GroupId: "g1", Kinds: []string{activity.KindTitleAdded}},
+		{Id: "everything", GroupId: "g1", Kinds: []string{activity.KindTitleAdded, activity.KindRatingAdded}},
+		{Id: "off", GroupId: "g1", Kinds: []string{activity.KindTitleAdded}, DisabledAt: &disabled},
+		{Id: "other-group", GroupId: "g2", Kinds: []string{activity.KindTitleAdded}},
+	}}
+
+	err := NewSink(db).Append(context.Background(), []models.ActivityEvent{
+		{Id: "e1", GroupId: "g1", ActorId: "u1", ActorName: "Ana", Kind: activity.KindTitleAdded},
+		{Id: "e2", GroupId: "g1", ActorId: "u1", ActorName: "Ana", Kind: activity.KindRatingAdded},
+		{Id: "e3", GroupId: "g1", ActorId: "u1", ActorName: "Ana", Kind: activity.KindCommentAdded},
+	})
+	require.NoError(t, err)
+
+	got := map[string][]string{}
+	for _, d := range db.queued {
+		got[d.EventId] = append(got[d.EventId], d.EndpointId)
+	}
+	require.Equal(t, map[string][]string{
+		"e1": {"titles", "everything"},
+		"e2": {"everything"},
+	}, got, "an event goes to the enabled endpoints of its group that subscribe to its kind")
+
+	var body Event
+	require.NoError(t, json.Unmarshal(db.queued[0].Body, &body))
+	require.Equal(t, "e1", body.Id)
+	require.Equal(t, "Ana", body.ActorName)
+	require.NotNil(t, body.Payload)
+	require.False(t, body.CreatedAt.IsZero())
+}
+
+func TestDispatcher(t *testing.T) {
+	// The receivers are httptest servers on 127.0.0.1.
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "true")
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+
+	newServer := func(t *testing.T, status int) (*httptest.Server, chan received) {
+		got := make(chan received, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			got <- received{header: r.Header.Clone(), body: body}
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(srv.Close)
+		return srv, got
+	}
+
+	newDispatcher := func(db *fakeStore, maxAttempts int) *Dispatcher {
+		d := NewDispatcher(db)
+		d.maxAttempts = maxAttempts
+		d.disableAfter = 5
+		return d
+	}
+
+	dispatch := func(url string, attempts int) models.WebhookDispatch {
+		return models.WebhookDispatch{
+			Delivery: models.WebhookDelivery{
+				Id: "d1", EndpointId: "wh1", Kind: activity.KindTitleAdded,
+				Body: []byte(`{"id":"e1"}`), Attempts: attempts,
+			},
+			URL:    url,
+			Secret: "whsec_test",
+		}
+	}
+
+	t.Run("a 2xx is a signed, recorded success", func(t *testing.T) {
+		srv, got := newServer(t, http.StatusNoContent)
+		db := &fakeStore{dispatches: []models.WebhookDispatch{dispatch(srv.URL, 0)}}
+
+		newDispatcher(db, 3).dispatchDue(context.Background())
+
+		req := <-got
+		require.Equal(t, `{"id":"e1"}`, string(req.body))
+		require.Equal(t, "d1", req.header.Get(HeaderDelivery))
+		require.Equal(t, activity.KindTitleAdded, req.header.Get(HeaderEvent))
+		timestamp, err := strconv.ParseInt(req.header.Get(HeaderTimestamp), 10, 64)
+		require.NoError(t, err)
+		require.Equal(t, Sign("whsec_test", timestamp, req.body), req.header.Get(HeaderSignature))
+
+		require.Len(t, db.attempts, 1)
+		attempt := db.attempts[0]
+		require.True(t, attempt.Succeeded)
+		require.Equal(t, http.StatusNoContent, *attempt.ResponseStatus)
+		require.Equal(t, 5, attempt.DisableAfter)
+	})
+
+	t.Run("a failure is retried after the back-off", func(t *testing.T) {
+		srv, _ := newServer(t, http.StatusInternalServerError)
+		db := &fakeStore{dispatches: []models.WebhookDispatch{dispatch(srv.URL, 1)}}
+
+		newDispatcher(db, 3).dispatchDue(context.Background())
+
+		attempt := db.attempts[0]
+		require.False(t, attempt.Succeeded)
+		require.Equal(t, http.StatusInternalServerError, *attempt.ResponseStatus)
+		require.NotEmpty(t, attempt.Error)
+		require.NotNil(t, attempt.RetryAt)
+		require.Equal(t, backoff(2), attempt.RetryAt.Sub(attempt.At), "the second failure waits the second back-off")
+	})
+
+	t.Run("the last allowed attempt is not retried", func(t *testing.T) {
+		srv, _ := newServer(t, http.StatusBadGateway)
+		db := &fakeStore{dispatches: []models.WebhookDispatch{dispatch(srv.URL, 2)}}
+
+		newDispatcher(db, 3).dispatchDue(context.Background())
+
+		require.Nil(t, db.attempts[0].RetryAt)
+	})
+
+	t.Run("an endpoint that cannot be reached is a failure with no status", func(t *testing.T) {
+		srv, _ := newServer(t, http.StatusOK)
+		srv.Close()
+		db := &fakeStore{dispatches: []models.WebhookDispatch{dispatch(srv.URL, 0)}}
+
+		newDispatcher(db, 3).dispatchDue(context.Background())
+
+		attempt := db.attempts[0]
+		require.False(t, attempt.Succeeded)
+		require.Nil(t, attempt.ResponseStatus)
+		require.NotNil(t, attempt.RetryAt)
+	})
+
+	t.Run("a redirect is not followed", func(t *testing.T) {
+		target, got := newServer(t, http.StatusOK)
+		redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+		t.Cleanup(redirect.Close)
+		db := &fakeStore{dispatches: []models.WebhookDispatch{dispatch(redirect.URL, 0)}}
+
+		newDispatcher(db, 3).dispatchDue(context.Background())
+
+		require.False(t, db.attempts[0].Succeeded)
+		require.Empty(t, got, "the signed body must not follow a redirect elsewhere")
+	})
+
+	t.Run("an internal address is refused when dialed", func(t *testing.T) {
+		t.Setenv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "false")
+		srv, got := newServer(t, http.StatusOK)
+		db := &fakeStore{dispatches: []models.WebhookDispatch{dispatch(srv.URL, 0)}}
+
+		newDispatcher(db, 3).dispatchDue(context.Background())
+
+		attempt := db.attempts[0]
+		require.False(t, attempt.Succeeded)
+		require.Nil(t, attempt.ResponseStatus)
+		require.Contains(t, attempt.Error, ErrURLNotAllowed.Error())
+		require.Empty(t, got, "a host that resolves somewhere internal since it was registered is not posted to")
+	})
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/lealre/movies-backend/internal/generics"
 	"github.com/lealre/movies-backend/internal/models"
@@ -36,6 +37,7 @@ type Store interface {
 	DeleteTitle(ctx context.Context, id string) (bool, error)
 	GetTitlesPage(ctx context.Context, orderBy string, ascending *bool, size, page int) ([]models.Title, int64, error)
 	TitleExists(ctx context.Context, id string) (bool, error)
+	GetTitlesByIds(ctx context.Context, ids []string) ([]models.Title, error)
 
 	// ----- Ratings -----
 	//
@@ -50,12 +52,14 @@ type Store interface {
 	UpdateRating(ctx context.Context, rating models.UserRating, userId string) (models.UserRating, error)
 	GetRatingsByTitleIds(ctx context.Context, titleIds []string, groupId string) ([]models.UserRating, error)
 	DeleteRating(ctx context.Context, ratingId, userId string) (int64, error)
+	GetRatingsByGroupId(ctx context.Context, groupId string) ([]models.UserRating, error)
 
 	// ----- Comments -----
 	//
 	// Group-scoped on the same terms as ratings.
 
 	GetCommentsByTitleId(ctx context.Context, titleId, groupId string) ([]models.Comment, error)
+	GetCommentsByTitleIds(ctx context.Context, titleIds []string, groupId string) ([]models.Comment, error)
 	GetUserCommentByTitleId(ctx context.Context, titleId, userId, groupId string) (models.Comment, error)
 	GetCommentById(ctx context.Context, commentId string, userId string) (models.Comment, error)
 	AddComment(ctx context.Context, comment models.Comment) (models.Comment, error)
@@ -71,30 +75,129 @@ type Store interface {
 	AddUserToGroup(ctx context.Context, groupId, ownerId, userToAddId string) error
 	GetUsersFromGroup(ctx context.Context, groupId, userId string) ([]models.User, error)
 	AddNewGroupTitle(ctx context.Context, groupId string, titleId string) error
+	AddGroupTitles(ctx context.Context, groupId string, titleIds []string) ([]string, error)
 	UpdateGroupTitleWatchedForMovie(ctx context.Context, groupId string, titleId string, watched *bool, watchedAt *generics.FlexibleDate) (*models.GroupTitleItem, error)
 	UpdateGroupTitleWatchedForTVSeries(ctx context.Context, groupId string, titleId string, watched *bool, watchedAt *generics.FlexibleDate, season int, userId string) (*models.GroupTitleItem, error)
 	UpdateGroupInfo(ctx context.Context, groupId, name, description string) error
 	SoftDeleteGroup(ctx context.Context, groupId string) error
 	RemoveUserFromGroup(ctx context.Context, groupId, userId string) error
 	RemoveTitleFromGroup(ctx context.Context, groupId, titleId, userId string) error
-	GetGroupTitlesPage(ctx context.Context, groupId string, watched *bool, titleTypes []string, orderBy string, ascending *bool, size, page int) ([]models.GroupPagedTitle, int64, error)
+	GetGroupTitlesPage(ctx context.Context, groupId string, watched *bool, titleTypes []string, availableOn *models.StreamingFilter, orderBy string, ascending *bool, size, page int) ([]models.GroupPagedTitle, int64, error)
 	GetGroupTitle(ctx context.Context, groupId, titleId string) (models.GroupPagedTitle, error)
-	GroupHasTitleEntries(ctx context.Context, groupId string, watched *bool, titleTypes []string) (bool, error)
+	GroupHasTitleEntries(ctx context.Context, groupId string, watched *bool, titleTypes []string, availableOn *models.StreamingFilter) (bool, error)
+	GetTitleIdsSeenByUsers(ctx context.Context, userIds, titleIds []string, groupId string) ([]string, error)
+
+	// ----- Recommendations -----
+	//
+	// The shared-ratings read is the one place a group's ratings are read for
+	// the benefit of another group, so it only ever returns ratings of groups
+	// that opted in (SetGroupRecommendationSharing), never the asking group's.
+
+	GetRecommendationCandidates(ctx context.Context, groupId string, limit int) ([]models.RecommendationCandidate, error)
+	GetSharedRatingsByTitleIds(ctx context.Context, titleIds []string, excludeGroupId string) ([]models.UserRating, error)
+	GroupSharesRecommendations(ctx context.Context, groupId string) (bool, error)
+	SetGroupRecommendationSharing(ctx context.Context, groupId, userId string, enabled bool) error
+
+	// ----- Stats -----
+	//
+	// The stats themselves are computed from the group, ratings and titles
+	// reads above; the store only answers whether anything they depend on has
+	// changed since they were last computed.
+
+	GetGroupStatsVersion(ctx context.Context, groupId string) (string, error)
+
+	// ----- Streaming availability -----
+	//
+	// ReplaceTitleAvailability swaps a title's stored availability for a new
+	// fetch, every region at once. GetTitleAvailability returns, for each of
+	// titleIds ever fetched, its availability in region alone; titles never
+	// fetched are absent. GetStreamingPreferences returns ErrRecordNotFound
+	// for a user who has set none.
+
+	ReplaceTitleAvailability(ctx context.Context, availability models.TitleAvailability) error
+	GetTitleAvailability(ctx context.Context, titleIds []string, region string) (map[string]models.TitleAvailability, error)
+	GetStreamingPreferences(ctx context.Context, userId string) (models.StreamingPreferences, error)
+	SetStreamingPreferences(ctx context.Context, prefs models.StreamingPreferences) (models.StreamingPreferences, error)
 
 	// ----- ActivityEvents -----
+	//
+	// GetActivityFeed is what the badge counts: the user's groups, never their
+	// own actions. GetActivityHistory is the same with their own actions in.
+	// Both take the same filter, whose zero value filters nothing.
+	// GetCoalescibleActivityEvent and DeleteActivityEvent are the two halves of
+	// merging an edit into the one before it (activity.Coalescer).
 
 	InsertActivityEvents(ctx context.Context, events []models.ActivityEvent) error
-	GetActivityFeed(ctx context.Context, userId string, before *int64, limit int) ([]models.ActivityEvent, error)
+	GetActivityFeed(ctx context.Context, userId string, filter models.ActivityFilter, before *int64, limit int) ([]models.ActivityEvent, error)
+	GetActivityHistory(ctx context.Context, userId string, filter models.ActivityFilter, before *int64, limit int) ([]models.ActivityEvent, error)
+	GetActivityEventsSince(ctx context.Context, userId string, afterSeq int64, limit int) ([]models.ActivityEvent, error)
 	GetActivityEventById(ctx context.Context, id string) (models.ActivityEvent, error)
+	GetCoalescibleActivityEvent(ctx context.Context, event models.ActivityEvent, since time.Time) (models.ActivityEvent, error)
+	DeleteActivityEvent(ctx context.Context, id string) error
 	GetActivityUnreadCount(ctx context.Context, userId string) (int64, error)
 	MarkActivityEventRead(ctx context.Context, userId, eventId string) error
 	MarkAllActivityEventsRead(ctx context.Context, userId string) error
+
+	// GetActivityPreferences returns the zero value, which mutes nothing, for
+	// a user who has set none. SetActivityPreferences replaces them whole and
+	// returns them as stored.
+
+	GetActivityPreferences(ctx context.Context, userId string) (models.ActivityPreferences, error)
+	SetActivityPreferences(ctx context.Context, prefs models.ActivityPreferences) (models.ActivityPreferences, error)
+
+	// ----- Activity digests -----
+	//
+	// GetActivityDigest returns ErrRecordNotFound for a user who has not
+	// subscribed. SetActivityDigest subscribes digest.UserId, or changes the
+	// frequency of their subscription, and returns it as stored; a new one
+	// covers events recorded from then on.
+	//
+	// The rest is the digest job. ClaimActivityDigests returns up to limit due
+	// digests and keeps them from being claimed again before leaseUntil.
+	// GetActivityDigestEvents returns userId's unread events of kinds with a
+	// seq after afterSeq, oldest first. CompleteActivityDigest records a run:
+	// DigestedSeq, LastSentAt when it sent one, and NextDueAt.
+
+	GetActivityDigest(ctx context.Context, userId string) (models.ActivityDigest, error)
+	SetActivityDigest(ctx context.Context, digest models.ActivityDigest) (models.ActivityDigest, error)
+	DeleteActivityDigest(ctx context.Context, userId string) error
+	ClaimActivityDigests(ctx context.Context, limit int, leaseUntil time.Time) ([]models.ActivityDigestDispatch, error)
+	GetActivityDigestEvents(ctx context.Context, userId string, afterSeq int64, kinds []string, limit int) ([]models.ActivityEvent, error)
+	CompleteActivityDigest(ctx context.Context, digest models.ActivityDigest) error
+
+	// ----- Webhooks -----
+	//
+	// Endpoints are always read and written within their group: an endpoint
+	// id under another group is ErrRecordNotFound. CreateWebhookEndpoint
+	// generates the id and timestamps and keeps the caller's secret;
+	// UpdateWebhookEndpoint writes URL, Kinds, DisabledAt and
+	// ConsecutiveFailures.
+	//
+	// The rest is the delivery queue. EnqueueWebhookDeliveries skips a
+	// delivery of an event the endpoint already has one for.
+	// ClaimWebhookDeliveries returns up to limit due deliveries of enabled
+	// endpoints and keeps them from being claimed again before leaseUntil;
+	// CompleteWebhookDelivery records how the attempt went.
+
+	CreateWebhookEndpoint(ctx context.Context, endpoint models.WebhookEndpoint) (models.WebhookEndpoint, error)
+	GetWebhookEndpoints(ctx context.Context, groupId string) ([]models.WebhookEndpoint, error)
+	GetWebhookEndpointById(ctx context.Context, groupId, endpointId string) (models.WebhookEndpoint, error)
+	UpdateWebhookEndpoint(ctx context.Context, endpoint models.WebhookEndpoint) (models.WebhookEndpoint, error)
+	DeleteWebhookEndpoint(ctx context.Context, groupId, endpointId string) error
+	GetWebhookDeliveries(ctx context.Context, endpointId string, before *int64, limit int) ([]models.WebhookDelivery, error)
+	RedeliverWebhookDelivery(ctx context.Context, endpointId, deliveryId string) (models.WebhookDelivery, error)
+	GetWebhookEndpointsForEvent(ctx context.Context, groupId, kind string) ([]models.WebhookEndpoint, error)
+	EnqueueWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	ClaimWebhookDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]models.WebhookDispatch, error)
+	CompleteWebhookDelivery(ctx context.Context, attempt models.WebhookAttempt) error
 }
 
 // ActivityListener is the optional push half of the storage contract: a store
 // that can tell this process about events as they are committed, instead of
 // only answering reads. ListenActivity blocks until ctx is cancelled, calling
-// publish once per committed event.
+// publish once per committed event, membership once per committed change to
+// who is in a group, and preferences with a user's preferences once per
+// committed change to them, in commit order.
 //
 // It is kept out of Store on purpose. Every method there is one that
 // services/api call on every store; this one is called once at startup, by the
@@ -104,5 +207,114 @@ type Store interface {
 // without it, rather than the interface forcing every implementation to have
 // one.
 type ActivityListener interface {
-	ListenActivity(ctx context.Context, publish func(models.ActivityEvent)) error
+	ListenActivity(ctx context.Context, publish func(models.ActivityEvent), membership func(models.MembershipChange), preferences func(models.ActivityPreferences)) error
+}
+
+// ActivityOutbox is the optional transactional delivery of activity events
+// (ACTIVITY_OUTBOX_ENABLED): an event written in the same transaction as the
+// write it describes, and relayed to the feed after it commits, so that a
+// committed write cannot lose its event the way a best-effort flush can.
+//
+// InTransaction runs fn with a context under which every write this store
+// makes on fn's behalf joins one transaction, committed when fn returns nil
+// and rolled back otherwise. Writes go on failing and succeeding one at a
+// time as they would outside it — a duplicate is still ErrDuplicatedRecord,
+// and the next write still runs — but none of them is visible to anyone else
+// until the commit.
+//
+// EnqueueActivityEvents writes events to the outbox, in ctx's transaction when
+// there is one. Each is given its id here, once, if it has none: the relay
+// delivers at least once, and the id is what lets a sink recognise an event
+// it already holds.
+//
+// RelayActivityOutbox hands the oldest undelivered events, up to limit, to
+// deliver, and removes them from the outbox only if it returns nil. It
+// reports how many it handed over; zero means the outbox was empty. It is
+// safe to call from several processes at once: a batch being delivered by one
+// is skipped by the others.
+type ActivityOutbox interface {
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	EnqueueActivityEvents(ctx context.Context, events []models.ActivityEvent) error
+	RelayActivityOutbox(ctx context.Context, limit int, deliver func(ctx context.Context, events []models.ActivityEvent) error) (int, error)
+}
+
+// ProviderCache is the optional persistent tier of the title provider cache
+// (internal/titleprovider/cache): fetched titles kept across restarts so a
+// cold process does not re-spend the upstream quota on them.
+//
+// Like ActivityListener it is kept out of Store. Nothing in services/api reads
+// it — only the provider cache does, and only when TITLE_CACHE_PERSISTENT is
+// on — and a store without it is fine: the cache stays memory-only. The server
+// type-asserts for it.
+//
+// GetProviderCacheEntry returns ErrRecordNotFound for a key that is absent or
+// past expiresAt.
+type ProviderCache interface {
+	GetProviderCacheEntry(ctx context.Context, key string) (value []byte, expiresAt time.Time, err error)
+	PutProviderCacheEntry(ctx context.Context, key string, value []byte, expiresAt time.Time) error
+}
+
+// ProviderQuota is the optional shared request counter behind title provider
+// quotas (internal/titleprovider/quota): per provider, per UTC day, across
+// every process using the store. Optional for the same reason as
+// ProviderCache — without it providers are simply not metered.
+//
+// ReserveProviderRequest counts one request for provider if today's count is
+// under limit, and reports whether it did; a false means the request must not
+// be made.
+type ProviderQuota interface {
+	ReserveProviderRequest(ctx context.Context, provider string, limit int) (bool, error)
+	GetProviderRequestCountsToday(ctx context.Context) ([]models.ProviderRequestCount, error)
+}
+
+// Archive is the optional whole-instance half of the storage contract: every
+// record of every kind, read out in pages and written back exactly as read
+// (ids, timestamps, password hashes, deleted groups), for the portable backups
+// of cmd/database -export and -import (internal/archive).
+//
+// Kept out of Store like ProviderCache: nothing in services/api walks the
+// whole instance, and Store's writes deliberately do not take ids or
+// timestamps from the caller. A store without it cannot be backed up or
+// restored this way, and nothing else about it changes.
+//
+// The List methods return up to limit records ordered by their key, starting
+// after the given one ("" or 0 for the first page); a page shorter than limit
+// is the last. ListGroups returns groups with their Users and Titles filled
+// in; ListRatings and ListComments, with their seasons; ListActivityPreferences,
+// one record per user with any mutes or quiet hours.
+//
+// The Restore methods write one page each, all of it or none. They are meant
+// for an empty store (ArchiveIsEmpty), in the order the List methods are
+// declared, so that whatever a record refers to is already there.
+// RestoreActivityEvents keeps each event's Seq, and leaves the store to give
+// events recorded later higher ones; RestoreActivityDigests keeps each
+// digest's DigestedSeq, which counts on that.
+type Archive interface {
+	ArchiveIsEmpty(ctx context.Context) (bool, error)
+
+	ListUsers(ctx context.Context, afterId string, limit int) ([]models.User, error)
+	ListTitles(ctx context.Context, afterId string, limit int) ([]models.Title, error)
+	ListGroups(ctx context.Context, afterId string, limit int) ([]models.Group, error)
+	ListRecommendationSharing(ctx context.Context, afterGroupId string, limit int) ([]models.RecommendationSharing, error)
+	ListStreamingPreferences(ctx context.Context, afterUserId string, limit int) ([]models.StreamingPreferences, error)
+	ListRatings(ctx context.Context, afterId string, limit int) ([]models.UserRating, error)
+	ListComments(ctx context.Context, afterId string, limit int) ([]models.Comment, error)
+	ListActivityEvents(ctx context.Context, afterSeq int64, limit int) ([]models.ActivityEvent, error)
+	ListActivityEventReads(ctx context.Context, afterUserId, afterEventId string, limit int) ([]models.ActivityEventRead, error)
+	ListActivityReadFloors(ctx context.Context, afterUserId string, limit int) ([]models.ActivityReadFloor, error)
+	ListActivityPreferences(ctx context.Context, afterUserId string, limit int) ([]models.ActivityPreferences, error)
+	ListActivityDigests(ctx context.Context, afterUserId string, limit int) ([]models.ActivityDigest, error)
+
+	RestoreUsers(ctx context.Context, users []models.User) error
+	RestoreTitles(ctx context.Context, titles []models.Title) error
+	RestoreGroups(ctx context.Context, groups []models.Group) error
+	RestoreRecommendationSharing(ctx context.Context, sharing []models.RecommendationSharing) error
+	RestoreStreamingPreferences(ctx context.Context, prefs []models.StreamingPreferences) error
+	RestoreRatings(ctx context.Context, ratings []models.UserRating) error
+	RestoreComments(ctx context.Context, comments []models.Comment) error
+	RestoreActivityEvents(ctx context.Context, events []models.ActivityEvent) error
+	RestoreActivityEventReads(ctx context.Context, reads []models.ActivityEventRead) error
+	RestoreActivityReadFloors(ctx context.Context, floors []models.ActivityReadFloor) error
+	RestoreActivityPreferences(ctx context.Context, prefs []models.ActivityPreferences) error
+	RestoreActivityDigests(ctx context.Context, digests []models.ActivityDigest) error
 }
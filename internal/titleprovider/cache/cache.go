@@ -0,0 +1,262 @@
+// Package cache wraps any titleprovider.Provider with a cache, so repeated
+// lookups — a search retyped while a user is still typing, the same title
+// added by two groups — are answered without another upstream call. Upstream
+// calls are what OMDb's 1,000-a-day quota counts. See ../README.md.
+//
+// There are two tiers. Every result goes into a size-bounded in-memory LRU
+// with a TTL (titles and searches each have their own). Fetched titles can
+// also be kept in Postgres (store.ProviderCache), so a restart does not start
+// cold; searches never are, since their keys are whatever was typed. On top of
+// both, identical calls that arrive while one is already in flight share that
+// one upstream request instead of each making their own.
+//
+// Errors are never cached — not even ErrTitleNotFound, since a title missing
+// upstream today may well be there tomorrow — and a failing persistent tier
+// never fails a call: it is logged and the provider is asked instead.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/lealre/movies-backend/internal/store"
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+// Options configures a cache. Zero or negative values fall back to the
+// config package defaults the factory reads them from.
+type Options struct {
+	// Size bounds the in-memory entries, titles and searches together.
+	Size int
+	// TitleTTL is how long a fetched title is served without asking again.
+	TitleTTL time.Duration
+	// SearchTTL is the same for search results.
+	SearchTTL time.Duration
+	// Persistent, when non-nil, keeps fetched titles across restarts.
+	Persistent store.ProviderCache
+}
+
+// Stats is a snapshot of the cache's counters since the process started.
+type Stats struct {
+	// Hits were answered from memory.
+	Hits int64 `json:"hits"`
+	// PersistentHits missed memory but were answered from Postgres.
+	PersistentHits int64 `json:"persistentHits"`
+	// Misses went upstream. Each is one provider call.
+	Misses int64 `json:"misses"`
+	// Shared missed too but waited for an identical call already in flight
+	// rather than making their own.
+	Shared int64 `json:"shared"`
+	// Evictions dropped the least recently used entry to make room.
+	Evictions int64 `json:"evictions"`
+	// PersistentErrors are failed reads or writes of the Postgres tier.
+	PersistentErrors int64 `json:"persistentErrors"`
+	// Entries is how many entries memory holds right now.
+	Entries int `json:"entries"`
+}
+
+// Provider implements titleprovider.Provider by caching another one.
+type Provider struct {
+	next      titleprovider.Provider
+	memory    *lru
+	persist   store.ProviderCache
+	titleTTL  time.Duration
+	searchTTL time.Duration
+	flight    singleflight.Group
+	now       func() time.Time
+
+	hits, persistentHits, misses, shared, evictions, persistentErrors atomic.Int64
+}
+
+// Option defaults, matching internal/config.
+const (
+	defaultSize      = 1000
+	defaultTitleTTL  = 24 * time.Hour
+	defaultSearchTTL = 10 * time.Minute
+)
+
+// fetchTimeout bounds a shared upstream call. It cannot borrow a deadline
+// from any one caller (see fetch), and the provider clients set none of their
+// own, so without it a hung upstream would hold the key forever and every
+// later call for it would queue behind that one.
+const fetchTimeout = 30 * time.Second
+
+// New wraps next with a cache configured by opts.
+func New(next titleprovider.Provider, opts Options) *Provider {
+	if opts.Size <= 0 {
+		opts.Size = defaultSize
+	}
+	if opts.TitleTTL <= 0 {
+		opts.TitleTTL = defaultTitleTTL
+	}
+	if opts.SearchTTL <= 0 {
+		opts.SearchTTL = defaultSearchTTL
+	}
+	return &Provider{
+		next:      next,
+		memory:    newLRU(opts.Size),
+		persist:   opts.Persistent,
+		titleTTL:  opts.TitleTTL,
+		searchTTL: opts.SearchTTL,
+		now:       time.Now,
+	}
+}
+
+// Name returns the wrapped provider's name: the cache changes where an answer
+// comes from, not whose answer it is.
+func (p *Provider) Name() string { return p.next.Name() }
+
+// Stats returns the current counters.
+func (p *Provider) Stats() Stats {
+	return Stats{
+		Hits:             p.hits.Load(),
+		PersistentHits:   p.persistentHits.Load(),
+		Misses:           p.misses.Load(),
+		Shared:           p.shared.Load(),
+		Evictions:        p.evictions.Load(),
+		PersistentErrors: p.persistentErrors.Load(),
+		Entries:          p.memory.len(),
+	}
+}
+
+// GetTitle answers from memory, then from the persistent tier, then from the
+// wrapped provider, filling the tiers it missed on the way back.
+func (p *Provider) GetTitle(ctx context.Context, imdbID string) (*titleprovider.Title, error) {
+	key := "title:" + imdbID
+
+	var title titleprovider.Title
+	if p.lookup(ctx, key, true, &title) {
+		return &title, nil
+	}
+
+	encoded, err := p.fetch(ctx, key, func(ctx context.Context) (any, error) {
+		return p.next.GetTitle(ctx, imdbID)
+	}, p.titleTTL, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(encoded, &title); err != nil {
+		return nil, err
+	}
+	return &title, nil
+}
+
+// SearchTitles answers from memory or from the wrapped provider. Queries that
+// differ only in case or surrounding/repeated spaces share an entry.
+func (p *Provider) SearchTitles(ctx context.Context, query string, limit int) ([]titleprovider.SearchItem, error) {
+	key := "search:" + strconv.Itoa(limit) + ":" + normalizeQuery(query)
+
+	var items []titleprovider.SearchItem
+	if p.lookup(ctx, key, false, &items) {
+		return items, nil
+	}
+
+	encoded, err := p.fetch(ctx, key, func(ctx context.Context) (any, error) {
+		return p.next.SearchTitles(ctx, query, limit)
+	}, p.searchTTL, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(encoded, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// lookup decodes the entry stored under key into dst, reporting whether there
+// was one. With persistent set it falls back to the Postgres tier and, on a
+// hit there, copies the entry into memory until the same expiry.
+func (p *Provider) lookup(ctx context.Context, key string, persistent bool, dst any) bool {
+	now := p.now()
+	if encoded, ok := p.memory.get(key, now); ok && json.Unmarshal(encoded, dst) == nil {
+		p.hits.Add(1)
+		return true
+	}
+
+	if !persistent || p.persist == nil {
+		return false
+	}
+	encoded, expiresAt, err := p.persist.GetProviderCacheEntry(ctx, key)
+	if err != nil {
+		if !errors.Is(err, store.ErrRecordNotFound) {
+			p.persistentErrors.Add(1)
+			log.Printf("WARN: title cache: reading %s from the persistent tier: %v", key, err)
+		}
+		return false
+	}
+	if json.Unmarshal(encoded, dst) != nil {
+		return false
+	}
+	p.remember(key, encoded, expiresAt)
+	p.persistentHits.Add(1)
+	return true
+}
+
+// fetch calls the wrapped provider through call, once per key however many
+// callers ask at the same time, and stores the encoded result for ttl.
+//
+// The upstream call runs detached from the caller's cancellation: it is shared
+// with every caller waiting on the same key, so the first caller going away
+// must not fail the rest. It is bounded by fetchTimeout instead, and each
+// caller still stops waiting when its own context ends.
+func (p *Provider) fetch(ctx context.Context, key string, call func(context.Context) (any, error), ttl time.Duration, persistent bool) ([]byte, error) {
+	leader := false
+	result := p.flight.DoChan(key, func() (any, error) {
+		leader = true
+		p.misses.Add(1)
+
+		detached, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		defer cancel()
+		value, err := call(detached)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		expiresAt := p.now().Add(ttl)
+		p.remember(key, encoded, expiresAt)
+		if persistent && p.persist != nil {
+			if err := p.persist.PutProviderCacheEntry(detached, key, encoded, expiresAt); err != nil {
+				p.persistentErrors.Add(1)
+				log.Printf("WARN: title cache: writing %s to the persistent tier: %v", key, err)
+			}
+		}
+		return encoded, nil
+	})
+
+	select {
+	case res := <-result:
+		if !leader {
+			p.shared.Add(1)
+		}
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]byte), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *Provider) remember(key string, encoded []byte, expiresAt time.Time) {
+	if p.memory.put(key, encoded, expiresAt) {
+		p.evictions.Add(1)
+	}
+}
+
+// normalizeQuery folds the differences between two searches that every
+// provider treats as the same search.
+func normalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
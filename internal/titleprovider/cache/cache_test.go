@@ -0,0 +1,225 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/store"
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+// countingProvider counts upstream calls. With release set, GetTitle blocks
+// until it is closed, so concurrent callers can be lined up behind one call.
+type countingProvider struct {
+	titles   atomic.Int64
+	searches atomic.Int64
+	release  chan struct{}
+	err      error
+}
+
+func (p *countingProvider) GetTitle(_ context.Context, imdbID string) (*titleprovider.Title, error) {
+	p.titles.Add(1)
+	if p.release != nil {
+		<-p.release
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &titleprovider.Title{ID: imdbID, PrimaryTitle: "Title " + imdbID, Genres: []string{"Drama"}}, nil
+}
+
+func (p *countingProvider) SearchTitles(_ context.Context, query string, _ int) ([]titleprovider.SearchItem, error) {
+	p.searches.Add(1)
+	return []titleprovider.SearchItem{{ID: "tt1", PrimaryTitle: query}}, nil
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+// memoryStore is a store.ProviderCache in a map.
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+	expiry  map[string]time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: map[string][]byte{}, expiry: map[string]time.Time{}}
+}
+
+func (s *memoryStore) GetProviderCacheEntry(_ context.Context, key string) ([]byte, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.entries[key]
+	if !ok {
+		return nil, time.Time{}, store.ErrRecordNotFound
+	}
+	return value, s.expiry[key], nil
+}
+
+func (s *memoryStore) PutProviderCacheEntry(_ context.Context, key string, value []byte, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key], s.expiry[key] = value, expiresAt
+	return nil
+}
+
+func TestGetTitle(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("a second call is a hit, and callers get their own copy", func(t *testing.T) {
+		upstream := &countingProvider{}
+		p := New(upstream, Options{})
+
+		first, err := p.GetTitle(ctx, "tt1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		first.Genres[0] = "changed by the caller"
+
+		second, err := p.GetTitle(ctx, "tt1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if upstream.titles.Load() != 1 {
+			t.Fatalf("upstream calls = %d, want 1", upstream.titles.Load())
+		}
+		if second.Genres[0] != "Drama" {
+			t.Fatalf("genres = %v, want the cached copy untouched", second.Genres)
+		}
+		if stats := p.Stats(); stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 {
+			t.Fatalf("stats = %+v", stats)
+		}
+	})
+
+	t.Run("an expired entry goes upstream again", func(t *testing.T) {
+		upstream := &countingProvider{}
+		p := New(upstream, Options{TitleTTL: time.Hour})
+		now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		p.now = func() time.Time { return now }
+
+		if _, err := p.GetTitle(ctx, "tt1"); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Hour)
+		if _, err := p.GetTitle(ctx, "tt1"); err != nil {
+			t.Fatal(err)
+		}
+		if upstream.titles.Load() != 2 {
+			t.Fatalf("upstream calls = %d, want 2", upstream.titles.Load())
+		}
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		upstream := &countingProvider{err: titleprovider.ErrTitleNotFound}
+		p := New(upstream, Options{})
+
+		for range 2 {
+			if _, err := p.GetTitle(ctx, "tt1"); !errors.Is(err, titleprovider.ErrTitleNotFound) {
+				t.Fatalf("err = %v, want ErrTitleNotFound", err)
+			}
+		}
+		if upstream.titles.Load() != 2 {
+			t.Fatalf("upstream calls = %d, want 2", upstream.titles.Load())
+		}
+	})
+
+	t.Run("concurrent identical calls share one upstream request", func(t *testing.T) {
+		upstream := &countingProvider{release: make(chan struct{})}
+		p := New(upstream, Options{})
+
+		const callers = 5
+		var wg sync.WaitGroup
+		for range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := p.GetTitle(ctx, "tt1"); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		// Hold the first call open until the others have had time to join
+		// it. A straggler arriving after it returns is a memory hit instead,
+		// so the upstream count below holds either way.
+		for upstream.titles.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(upstream.release)
+		wg.Wait()
+
+		if upstream.titles.Load() != 1 {
+			t.Fatalf("upstream calls = %d, want 1", upstream.titles.Load())
+		}
+		if stats := p.Stats(); stats.Misses != 1 || stats.Shared+stats.Hits != callers-1 {
+			t.Fatalf("stats = %+v", stats)
+		}
+	})
+
+	t.Run("the persistent tier survives a new process", func(t *testing.T) {
+		persistent := newMemoryStore()
+		upstream := &countingProvider{}
+
+		if _, err := New(upstream, Options{Persistent: persistent}).GetTitle(ctx, "tt1"); err != nil {
+			t.Fatal(err)
+		}
+		restarted := New(upstream, Options{Persistent: persistent})
+		got, err := restarted.GetTitle(ctx, "tt1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.PrimaryTitle != "Title tt1" || upstream.titles.Load() != 1 {
+			t.Fatalf("got %q after %d upstream calls, want one call", got.PrimaryTitle, upstream.titles.Load())
+		}
+		if stats := restarted.Stats(); stats.PersistentHits != 1 || stats.Entries != 1 {
+			t.Fatalf("stats = %+v, want the persistent hit copied into memory", stats)
+		}
+	})
+}
+
+func TestSearchTitles(t *testing.T) {
+	ctx := context.Background()
+	upstream := &countingProvider{}
+	persistent := newMemoryStore()
+	p := New(upstream, Options{Persistent: persistent})
+
+	for _, query := range []string{"The Godfather", "  the   GODFATHER "} {
+		if _, err := p.SearchTitles(ctx, query, 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := p.SearchTitles(ctx, "the godfather", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if upstream.searches.Load() != 2 {
+		t.Fatalf("upstream searches = %d, want 2 (one per limit)", upstream.searches.Load())
+	}
+	if len(persistent.entries) != 0 {
+		t.Fatalf("persistent entries = %d, want searches kept in memory only", len(persistent.entries))
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	c := newLRU(2)
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	c.put("a", []byte("1"), later)
+	c.put("b", []byte("2"), later)
+	c.get("a", now) // a is now the most recently used
+	if !c.put("c", []byte("3"), later) {
+		t.Fatal("expected an eviction past the size bound")
+	}
+
+	if _, ok := c.get("b", now); ok {
+		t.Error("expected b, the least recently used, to be evicted")
+	}
+	if _, ok := c.get("a", now); !ok {
+		t.Error("expected a to survive")
+	}
+}
@@ -0,0 +1,75 @@
+package chain
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker is one provider's circuit breaker. It is closed (calls go through)
+// until threshold consecutive failures, then open (calls skip the provider)
+// for cooldown, then half-open: exactly one probe call is let through, and its
+// outcome either closes the breaker again or re-opens it for another cooldown.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int       // consecutive; reset by any success
+	openedAt time.Time // when failures last reached threshold
+	probing  bool      // a half-open probe is in flight
+}
+
+// allow reports whether a call may go to the provider at now. In the
+// half-open state it says yes once and then no until that probe is recorded.
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if now.Sub(b.openedAt) < b.cooldown || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record feeds back the outcome of a call allow let through, and reports the
+// transition it caused, if any: "opened" when this failure opened a closed
+// breaker, "closed" when a success closed an open one. A failed probe keeps
+// the breaker open and restarts its cooldown without reporting anything new.
+func (b *breaker) record(failed bool, now time.Time) (transition string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasOpen := b.failures >= b.threshold
+	b.probing = false
+
+	if !failed {
+		b.failures = 0
+		if wasOpen {
+			return "closed"
+		}
+		return ""
+	}
+
+	b.failures++
+	if b.failures < b.threshold {
+		return ""
+	}
+	b.openedAt = now
+	if wasOpen {
+		return ""
+	}
+	return "opened"
+}
+
+// release ends a call allow let through without an outcome to record — the
+// caller went away before the provider could be judged — so a half-open
+// breaker may probe again.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
@@ -0,0 +1,183 @@
+// Package chain composes several providers into one that falls back down an
+// ordered list: each call goes to the first provider that is up, and to the
+// next one when it fails, so one supplier being down does not make adding a
+// title fail. Selected with TITLE_PROVIDER=chain:hybrid,omdb. See ../README.md.
+//
+// Each provider has its own circuit breaker, so a provider that keeps failing
+// is skipped outright for a while rather than costing every call a timeout
+// first, and each call to a provider has its own deadline, so a hung one falls
+// through instead of holding the request.
+//
+// A "not found" is an answer, not a failure: it does not count against the
+// provider's breaker. It still falls through, since providers do not all know
+// the same titles (TMDB's IMDb-id lookup misses some that OMDb has). A
+// provider out of its daily quota (titleprovider.ErrQuotaExhausted) falls
+// through too, without counting against its breaker either: it is not down,
+// and the quota already stops it being asked.
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+// ErrNoProviderAvailable is returned when every provider in the chain is
+// skipped by its open breaker, so nothing was even asked.
+var ErrNoProviderAvailable = errors.New("no title provider available")
+
+// Options configures a chain. Zero or negative values fall back to the
+// config package defaults the factory reads them from.
+type Options struct {
+	// Timeout bounds each call to one provider.
+	Timeout time.Duration
+	// BreakerFailures is how many consecutive failures open a breaker.
+	BreakerFailures int
+	// BreakerCooldown is how long an open breaker skips its provider before
+	// letting one probe call through.
+	BreakerCooldown time.Duration
+}
+
+// Option defaults, matching internal/config.
+const (
+	defaultTimeout         = 10 * time.Second
+	defaultBreakerFailures = 5
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+// Provider implements titleprovider.Provider over an ordered list of others.
+type Provider struct {
+	links   []link
+	timeout time.Duration
+	now     func() time.Time
+}
+
+type link struct {
+	provider titleprovider.Provider
+	breaker  *breaker
+}
+
+// New builds a chain that tries providers in the given order.
+func New(providers []titleprovider.Provider, opts Options) *Provider {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.BreakerFailures <= 0 {
+		opts.BreakerFailures = defaultBreakerFailures
+	}
+	if opts.BreakerCooldown <= 0 {
+		opts.BreakerCooldown = defaultBreakerCooldown
+	}
+
+	links := make([]link, len(providers))
+	for i, provider := range providers {
+		links[i] = link{
+			provider: provider,
+			breaker:  &breaker{threshold: opts.BreakerFailures, cooldown: opts.BreakerCooldown},
+		}
+	}
+	return &Provider{links: links, timeout: opts.Timeout, now: time.Now}
+}
+
+// Name lists the chain in order, in the same form TITLE_PROVIDER takes.
+func (p *Provider) Name() string {
+	names := make([]string, len(p.links))
+	for i, l := range p.links {
+		names[i] = l.provider.Name()
+	}
+	return "chain:" + strings.Join(names, ",")
+}
+
+// GetTitle returns the title from the first provider that has it, with Source
+// set to that provider's name unless it reported a more precise one itself.
+func (p *Provider) GetTitle(ctx context.Context, imdbID string) (*titleprovider.Title, error) {
+	title, servedBy, err := try(p, ctx, func(ctx context.Context, provider titleprovider.Provider) (*titleprovider.Title, error) {
+		return provider.GetTitle(ctx, imdbID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if title.Source == "" {
+		title.Source = servedBy
+	}
+	return title, nil
+}
+
+// SearchTitles returns the results of the first provider that answers. An
+// empty result is an answer: searching the next provider too would spend its
+// quota on what is most likely the same nothing.
+func (p *Provider) SearchTitles(ctx context.Context, query string, limit int) ([]titleprovider.SearchItem, error) {
+	items, _, err := try(p, ctx, func(ctx context.Context, provider titleprovider.Provider) ([]titleprovider.SearchItem, error) {
+		return provider.SearchTitles(ctx, query, limit)
+	})
+	return items, err
+}
+
+// try runs call against each provider in turn until one answers, returning
+// that answer and the name of the provider that gave it.
+//
+// When none answers, the error says why, in order of what the caller can do
+// about it: the caller's own context ending is returned as is; a real failure
+// anywhere is returned, joined with the others, since a provider that failed
+// might have had the title; ErrTitleNotFound only when every provider asked
+// said so; ErrNoProviderAvailable when no provider was asked at all.
+func try[T any](p *Provider, ctx context.Context, call func(context.Context, titleprovider.Provider) (T, error)) (T, string, error) {
+	var zero T
+	var failures []error
+	asked := 0
+
+	for _, l := range p.links {
+		name := l.provider.Name()
+		if !l.breaker.allow(p.now()) {
+			continue
+		}
+		asked++
+
+		callCtx, cancel := context.WithTimeout(ctx, p.timeout)
+		result, err := call(callCtx, l.provider)
+		cancel()
+
+		if ctx.Err() != nil {
+			// The caller gave up; that says nothing about the provider, so it
+			// is neither recorded nor worth trying the next one for.
+			l.breaker.release()
+			return zero, "", ctx.Err()
+		}
+
+		if errors.Is(err, titleprovider.ErrQuotaExhausted) {
+			l.breaker.release()
+			failures = append(failures, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+
+		failed := err != nil && !errors.Is(err, titleprovider.ErrTitleNotFound)
+		switch l.breaker.record(failed, p.now()) {
+		case "opened":
+			log.Printf("WARN: title provider %s failed %d times in a row; skipping it for %s: %v",
+				name, l.breaker.threshold, l.breaker.cooldown, err)
+		case "closed":
+			log.Printf("Title provider %s has recovered", name)
+		}
+
+		if err == nil {
+			return result, name, nil
+		}
+		if failed {
+			failures = append(failures, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	switch {
+	case len(failures) > 0:
+		return zero, "", fmt.Errorf("no title provider answered: %w", errors.Join(failures...))
+	case asked > 0:
+		return zero, "", titleprovider.ErrTitleNotFound
+	default:
+		return zero, "", ErrNoProviderAvailable
+	}
+}
@@ -0,0 +1,183 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+// scriptedProvider answers with err when set, or after hang when set (until
+// its context ends), and otherwise with a title; calls counts every call.
+type scriptedProvider struct {
+	name  string
+	err   error
+	hang  bool
+	calls int
+}
+
+func (p *scriptedProvider) GetTitle(ctx context.Context, imdbID string) (*titleprovider.Title, error) {
+	p.calls++
+	if p.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &titleprovider.Title{ID: imdbID, PrimaryTitle: "from " + p.name}, nil
+}
+
+func (p *scriptedProvider) SearchTitles(context.Context, string, int) ([]titleprovider.SearchItem, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return []titleprovider.SearchItem{}, nil
+}
+
+func (p *scriptedProvider) Name() string { return p.name }
+
+var errDown = errors.New("503 service unavailable")
+
+func TestGetTitle(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("falls through to the next provider and records which one served", func(t *testing.T) {
+		first := &scriptedProvider{name: "tmdb", err: errDown}
+		second := &scriptedProvider{name: "omdb"}
+		p := New([]titleprovider.Provider{first, second}, Options{})
+
+		title, err := p.GetTitle(ctx, "tt1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if title.PrimaryTitle != "from omdb" || title.Source != "omdb" {
+			t.Fatalf("got %q from %q, want omdb", title.PrimaryTitle, title.Source)
+		}
+		if got := titleprovider.SourceOf(p, title); got != "omdb" {
+			t.Fatalf("SourceOf = %q, want omdb", got)
+		}
+	})
+
+	t.Run("a hung provider times out and falls through", func(t *testing.T) {
+		first := &scriptedProvider{name: "tmdb", hang: true}
+		second := &scriptedProvider{name: "omdb"}
+		p := New([]titleprovider.Provider{first, second}, Options{Timeout: 10 * time.Millisecond})
+
+		title, err := p.GetTitle(ctx, "tt1")
+		if err != nil || title.Source != "omdb" {
+			t.Fatalf("got %+v, %v; want omdb's answer", title, err)
+		}
+	})
+
+	t.Run("not found everywhere is not found, and failures win over it", func(t *testing.T) {
+		missing := &scriptedProvider{name: "tmdb", err: titleprovider.ErrTitleNotFound}
+		alsoMissing := &scriptedProvider{name: "omdb", err: titleprovider.ErrTitleNotFound}
+		p := New([]titleprovider.Provider{missing, alsoMissing}, Options{})
+		if _, err := p.GetTitle(ctx, "tt1"); !errors.Is(err, titleprovider.ErrTitleNotFound) {
+			t.Fatalf("err = %v, want ErrTitleNotFound", err)
+		}
+		if alsoMissing.calls != 1 {
+			t.Fatalf("second provider calls = %d, want a not-found to fall through", alsoMissing.calls)
+		}
+
+		down := &scriptedProvider{name: "omdb", err: errDown}
+		p = New([]titleprovider.Provider{missing, down}, Options{})
+		_, err := p.GetTitle(ctx, "tt1")
+		if !errors.Is(err, errDown) || errors.Is(err, titleprovider.ErrTitleNotFound) {
+			t.Fatalf("err = %v, want the failure, not a not-found", err)
+		}
+	})
+
+	t.Run("the breaker skips a failing provider, then probes it", func(t *testing.T) {
+		flaky := &scriptedProvider{name: "tmdb", err: errDown}
+		backup := &scriptedProvider{name: "omdb"}
+		p := New([]titleprovider.Provider{flaky, backup}, Options{BreakerFailures: 2, BreakerCooldown: time.Minute})
+		now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		p.now = func() time.Time { return now }
+
+		for range 4 {
+			if _, err := p.GetTitle(ctx, "tt1"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if flaky.calls != 2 {
+			t.Fatalf("flaky calls = %d, want 2 before the breaker opened", flaky.calls)
+		}
+
+		// After the cooldown one probe goes through; it succeeds and closes
+		// the breaker, so the provider is first in line again.
+		now = now.Add(time.Minute)
+		flaky.err = nil
+		title, err := p.GetTitle(ctx, "tt1")
+		if err != nil || title.Source != "tmdb" {
+			t.Fatalf("got %+v, %v; want the recovered provider to answer", title, err)
+		}
+		if _, err := p.GetTitle(ctx, "tt1"); err != nil || flaky.calls != 4 {
+			t.Fatalf("flaky calls = %d, want the breaker closed again", flaky.calls)
+		}
+	})
+
+	t.Run("not found does not count against the breaker", func(t *testing.T) {
+		missing := &scriptedProvider{name: "tmdb", err: titleprovider.ErrTitleNotFound}
+		p := New([]titleprovider.Provider{missing}, Options{BreakerFailures: 1})
+
+		for range 3 {
+			_, _ = p.GetTitle(ctx, "tt1")
+		}
+		if missing.calls != 3 {
+			t.Fatalf("calls = %d, want every call to reach the provider", missing.calls)
+		}
+	})
+
+	t.Run("an exhausted quota falls through without counting against the breaker", func(t *testing.T) {
+		spent := &scriptedProvider{name: "omdb", err: fmt.Errorf("omdb: %w", titleprovider.ErrQuotaExhausted)}
+		backup := &scriptedProvider{name: "tmdb"}
+		p := New([]titleprovider.Provider{spent, backup}, Options{BreakerFailures: 1})
+
+		for range 2 {
+			title, err := p.GetTitle(ctx, "tt1")
+			if err != nil || title.Source != "tmdb" {
+				t.Fatalf("got %+v, %v; want tmdb's answer", title, err)
+			}
+		}
+		if spent.calls != 2 {
+			t.Fatalf("spent provider calls = %d, want its breaker left closed", spent.calls)
+		}
+
+		p = New([]titleprovider.Provider{spent}, Options{})
+		if _, err := p.GetTitle(ctx, "tt1"); !errors.Is(err, titleprovider.ErrQuotaExhausted) {
+			t.Fatalf("err = %v, want ErrQuotaExhausted to reach the caller", err)
+		}
+	})
+
+	t.Run("every breaker open means no provider is available", func(t *testing.T) {
+		down := &scriptedProvider{name: "tmdb", err: errDown}
+		p := New([]titleprovider.Provider{down}, Options{BreakerFailures: 1, BreakerCooldown: time.Hour})
+
+		if _, err := p.GetTitle(ctx, "tt1"); !errors.Is(err, errDown) {
+			t.Fatalf("err = %v, want the failure", err)
+		}
+		if _, err := p.GetTitle(ctx, "tt1"); !errors.Is(err, ErrNoProviderAvailable) {
+			t.Fatalf("err = %v, want ErrNoProviderAvailable", err)
+		}
+	})
+}
+
+func TestSearchTitlesDoesNotFallThroughOnEmpty(t *testing.T) {
+	first := &scriptedProvider{name: "tmdb"}
+	second := &scriptedProvider{name: "omdb"}
+	p := New([]titleprovider.Provider{first, second}, Options{})
+
+	items, err := p.SearchTitles(context.Background(), "nothing matches", 5)
+	if err != nil || len(items) != 0 {
+		t.Fatalf("got %v, %v", items, err)
+	}
+	if second.calls != 0 {
+		t.Fatalf("second provider calls = %d, want an empty answer to stand", second.calls)
+	}
+}
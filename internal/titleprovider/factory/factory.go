@@ -3,26 +3,127 @@ package factory
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/lealre/movies-backend/internal/config"
+	"github.com/lealre/movies-backend/internal/store"
 	"github.com/lealre/movies-backend/internal/titleprovider"
+	"github.com/lealre/movies-backend/internal/titleprovider/cache"
+	"github.com/lealre/movies-backend/internal/titleprovider/chain"
+	"github.com/lealre/movies-backend/internal/titleprovider/fixture"
 	"github.com/lealre/movies-backend/internal/titleprovider/hybrid"
 	"github.com/lealre/movies-backend/internal/titleprovider/imdbapi"
 	"github.com/lealre/movies-backend/internal/titleprovider/omdb"
+	"github.com/lealre/movies-backend/internal/titleprovider/quota"
+	"github.com/lealre/movies-backend/internal/titleprovider/streaming"
+	"github.com/lealre/movies-backend/internal/titleprovider/titleref"
 	"github.com/lealre/movies-backend/internal/titleprovider/tmdb"
 )
 
+// Options carries what the process building the provider can offer it.
+// The zero value suits a process without a store: no persistent cache tier and
+// no quota metering.
+type Options struct {
+	// Cache is the Postgres tier of the title cache, used only when
+	// TITLE_CACHE_PERSISTENT is also on. Leave it nil where reading titles
+	// back from it would defeat the purpose (the refresh routine), and the
+	// cache stays memory-only whatever TITLE_CACHE_PERSISTENT says — both
+	// processes read the same environment.
+	Cache store.ProviderCache
+	// Quota meters OMDb requests (by the omdb and hybrid providers) against
+	// OMDB_DAILY_BUDGET. Nil leaves them unmetered.
+	Quota store.ProviderQuota
+	// Background marks the provider as serving background work, which stops
+	// OMDB_INTERACTIVE_RESERVE requests short of the budget and has hybrid
+	// fail rather than degrade once the quota is spent.
+	Background bool
+}
+
 // NewFromEnv builds the provider selected by the TITLE_PROVIDER env var.
 // Allowed values (see internal/titleprovider/README.md for a comparison):
 //   - "hybrid"  : TMDB metadata + OMDb IMDb ratings (recommended; needs both keys)
 //   - "tmdb"    : TMDB only (default; rich seasons/episodes, TMDB's own ratings)
 //   - "omdb"    : OMDb only (real IMDb ratings + Metacritic, thinner episode data)
 //   - "imdbapi" : legacy api.imdbapi.dev (offline; kept for port-back)
-func NewFromEnv() (titleprovider.Provider, error) {
+//   - "fixture" : JSON files in TITLE_FIXTURE_DIR; no network at all
+//   - "chain:a,b,...": the named providers above, tried in order, falling
+//     through to the next when one fails (see the chain package)
+//
+// With TITLE_FIXTURE_RECORD on, every answer is also written into
+// TITLE_FIXTURE_DIR for the fixture provider to serve later. With
+// TITLE_CACHE_ENABLED on, the provider comes back wrapped in a
+// *cache.Provider sized and timed by the TITLE_CACHE_* settings; the cache
+// sits outside the recorder, so a cache hit is not recorded twice.
+func NewFromEnv(opts Options) (titleprovider.Provider, error) {
 	name := os.Getenv("TITLE_PROVIDER")
 	if name == "" {
 		name = "tmdb"
 	}
 
+	var provider titleprovider.Provider
+	var err error
+	if names, ok := strings.CutPrefix(name, "chain:"); ok {
+		provider, err = newChain(names, opts)
+	} else {
+		provider, err = newProvider(name, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if config.TitleFixtureRecord() {
+		if name == "fixture" {
+			return nil, fmt.Errorf("TITLE_FIXTURE_RECORD records a real provider; it cannot be used with TITLE_PROVIDER=fixture")
+		}
+		provider, err = fixture.NewRecorder(provider, config.TitleFixtureDir())
+		if err != nil {
+			return nil, fmt.Errorf("TITLE_FIXTURE_RECORD: %w", err)
+		}
+	}
+	if !config.TitleCacheEnabled() {
+		return provider, nil
+	}
+
+	cacheOpts := cache.Options{
+		Size:      config.TitleCacheSize(),
+		TitleTTL:  config.TitleCacheTTL(),
+		SearchTTL: config.TitleCacheSearchTTL(),
+	}
+	if config.TitleCachePersistent() {
+		cacheOpts.Persistent = opts.Cache
+	}
+	return cache.New(provider, cacheOpts), nil
+}
+
+// newChain builds the chain named by the comma-separated list after
+// "chain:". Every name must be a plain provider, listed once; the chain is
+// built around the cache, not inside it, so one cache serves whichever link
+// answers.
+func newChain(names string, opts Options) (titleprovider.Provider, error) {
+	var providers []titleprovider.Provider
+	seen := map[string]bool{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			return nil, fmt.Errorf("TITLE_PROVIDER=chain:%s must list each provider once, with no empty entries", names)
+		}
+		seen[name] = true
+
+		provider, err := newProvider(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	return chain.New(providers, chain.Options{
+		Timeout:         config.TitleProviderTimeout(),
+		BreakerFailures: config.TitleProviderBreakerFailures(),
+		BreakerCooldown: config.TitleProviderBreakerCooldown(),
+	}), nil
+}
+
+// newProvider builds the single, uncached provider called name.
+func newProvider(name string, opts Options) (titleprovider.Provider, error) {
 	switch name {
 	case "hybrid":
 		tmdbKey := os.Getenv("TMDB_API_KEY")
@@ -33,7 +134,7 @@ func NewFromEnv() (titleprovider.Provider, error) {
 		if omdbKey == "" {
 			return nil, fmt.Errorf("TITLE_PROVIDER=hybrid requires OMDB_API_KEY to be set")
 		}
-		return hybrid.New(tmdb.New(tmdbKey), omdbKey), nil
+		return hybrid.NewWithRatings(tmdb.New(tmdbKey), newOmdb(omdbKey, opts), opts.Background), nil
 	case "tmdb":
 		key := os.Getenv("TMDB_API_KEY")
 		if key == "" {
@@ -45,10 +146,61 @@ func NewFromEnv() (titleprovider.Provider, error) {
 		if key == "" {
 			return nil, fmt.Errorf("TITLE_PROVIDER=omdb requires OMDB_API_KEY to be set")
 		}
-		return omdb.New(key), nil
+		return newOmdb(key, opts), nil
 	case "imdbapi":
 		return imdbapi.New(), nil
+	case "fixture":
+		provider, err := fixture.New(config.TitleFixtureDir())
+		if err != nil {
+			return nil, fmt.Errorf("TITLE_PROVIDER=fixture needs the TITLE_FIXTURE_DIR directory: %w", err)
+		}
+		return provider, nil
+	default:
+		return nil, fmt.Errorf("unknown TITLE_PROVIDER %q (allowed: hybrid, tmdb, omdb, imdbapi, fixture, or chain: followed by a comma-separated list of those)", name)
+	}
+}
+
+// newOmdb builds the OMDb client, metered against the daily budget when opts
+// carries a quota store.
+func newOmdb(key string, opts Options) *omdb.Provider {
+	provider := omdb.New(key)
+	if opts.Quota == nil {
+		return provider
+	}
+	return provider.WithQuota(quota.NewCounter(opts.Quota, "omdb",
+		config.OmdbDailyBudget(), config.OmdbInteractiveReserve(), opts.Background))
+}
+
+// NewStreamingFromEnv builds the availability provider selected by the
+// STREAMING_PROVIDER env var, or returns nil when it is unset: availability is
+// an optional extra, and without a provider nothing is fetched and title
+// listings simply carry none. Allowed values:
+//   - "tmdb" : TMDB watch providers (data from JustWatch); needs TMDB_API_KEY
+func NewStreamingFromEnv() (streaming.Provider, error) {
+	switch name := os.Getenv("STREAMING_PROVIDER"); name {
+	case "":
+		return nil, nil
+	case "tmdb":
+		key := os.Getenv("TMDB_API_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("STREAMING_PROVIDER=tmdb requires TMDB_API_KEY to be set")
+		}
+		return tmdb.New(key), nil
 	default:
-		return nil, fmt.Errorf("unknown TITLE_PROVIDER %q (allowed: hybrid, tmdb, omdb, imdbapi)", name)
+		return nil, fmt.Errorf("unknown STREAMING_PROVIDER %q (allowed: tmdb, or unset for none)", name)
+	}
+}
+
+// NewTMDBLookup returns what maps TMDB links to IMDb IDs when adding titles:
+// provider itself when it can (a provider that knows TMDB IDs, as the tests'
+// fake does), otherwise a TMDB client when TMDB_API_KEY is set, whatever
+// TITLE_PROVIDER is, and otherwise nil, which leaves TMDB links unsupported.
+func NewTMDBLookup(provider titleprovider.Provider) titleref.TMDBLookup {
+	if lookup, ok := provider.(titleref.TMDBLookup); ok {
+		return lookup
+	}
+	if key := os.Getenv("TMDB_API_KEY"); key != "" {
+		return tmdb.New(key)
 	}
+	return nil
 }
@@ -1,16 +1,21 @@
 package factory_test
 
 import (
+	"path/filepath"
 	"testing"
 
+	"github.com/lealre/movies-backend/internal/titleprovider/cache"
 	"github.com/lealre/movies-backend/internal/titleprovider/factory"
+	"github.com/lealre/movies-backend/internal/titleprovider/fixture"
+	"github.com/lealre/movies-backend/internal/titleprovider/imdbapi"
+	"github.com/lealre/movies-backend/internal/titleprovider/tmdb"
 )
 
 func TestNewFromEnv(t *testing.T) {
 	t.Run("defaults to tmdb", func(t *testing.T) {
 		t.Setenv("TITLE_PROVIDER", "")
 		t.Setenv("TMDB_API_KEY", "k")
-		p, err := factory.NewFromEnv()
+		p, err := factory.NewFromEnv(factory.Options{})
 		if err != nil {
 			t.Fatalf("err: %v", err)
 		}
@@ -22,14 +27,14 @@ func TestNewFromEnv(t *testing.T) {
 	t.Run("tmdb requires key", func(t *testing.T) {
 		t.Setenv("TITLE_PROVIDER", "tmdb")
 		t.Setenv("TMDB_API_KEY", "")
-		if _, err := factory.NewFromEnv(); err == nil {
+		if _, err := factory.NewFromEnv(factory.Options{}); err == nil {
 			t.Fatal("expected error for missing TMDB_API_KEY")
 		}
 	})
 
 	t.Run("imdbapi", func(t *testing.T) {
 		t.Setenv("TITLE_PROVIDER", "imdbapi")
-		p, err := factory.NewFromEnv()
+		p, err := factory.NewFromEnv(factory.Options{})
 		if err != nil {
 			t.Fatalf("err: %v", err)
 		}
@@ -41,7 +46,7 @@ func TestNewFromEnv(t *testing.T) {
 	t.Run("omdb", func(t *testing.T) {
 		t.Setenv("TITLE_PROVIDER", "omdb")
 		t.Setenv("OMDB_API_KEY", "k")
-		p, err := factory.NewFromEnv()
+		p, err := factory.NewFromEnv(factory.Options{})
 		if err != nil {
 			t.Fatalf("err: %v", err)
 		}
@@ -53,7 +58,7 @@ func TestNewFromEnv(t *testing.T) {
 	t.Run("omdb requires key", func(t *testing.T) {
 		t.Setenv("TITLE_PROVIDER", "omdb")
 		t.Setenv("OMDB_API_KEY", "")
-		if _, err := factory.NewFromEnv(); err == nil {
+		if _, err := factory.NewFromEnv(factory.Options{}); err == nil {
 			t.Fatal("expected error for missing OMDB_API_KEY")
 		}
 	})
@@ -62,12 +67,12 @@ func TestNewFromEnv(t *testing.T) {
 		t.Setenv("TITLE_PROVIDER", "hybrid")
 		t.Setenv("TMDB_API_KEY", "tk")
 		t.Setenv("OMDB_API_KEY", "")
-		if _, err := factory.NewFromEnv(); err == nil {
+		if _, err := factory.NewFromEnv(factory.Options{}); err == nil {
 			t.Fatal("expected error when OMDB_API_KEY missing for hybrid")
 		}
 		t.Setenv("TMDB_API_KEY", "")
 		t.Setenv("OMDB_API_KEY", "ok")
-		if _, err := factory.NewFromEnv(); err == nil {
+		if _, err := factory.NewFromEnv(factory.Options{}); err == nil {
 			t.Fatal("expected error when TMDB_API_KEY missing for hybrid")
 		}
 	})
@@ -76,7 +81,7 @@ func TestNewFromEnv(t *testing.T) {
 		t.Setenv("TITLE_PROVIDER", "hybrid")
 		t.Setenv("TMDB_API_KEY", "tk")
 		t.Setenv("OMDB_API_KEY", "ok")
-		p, err := factory.NewFromEnv()
+		p, err := factory.NewFromEnv(factory.Options{})
 		if err != nil {
 			t.Fatalf("err: %v", err)
 		}
@@ -87,8 +92,152 @@ func TestNewFromEnv(t *testing.T) {
 
 	t.Run("unknown value errors", func(t *testing.T) {
 		t.Setenv("TITLE_PROVIDER", "bogus")
-		if _, err := factory.NewFromEnv(); err == nil {
+		if _, err := factory.NewFromEnv(factory.Options{}); err == nil {
 			t.Fatal("expected error for unknown provider")
 		}
 	})
+
+	t.Run("cache wraps the provider when enabled", func(t *testing.T) {
+		t.Setenv("TITLE_PROVIDER", "omdb")
+		t.Setenv("OMDB_API_KEY", "k")
+		t.Setenv("TITLE_CACHE_ENABLED", "true")
+		p, err := factory.NewFromEnv(factory.Options{})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, ok := p.(*cache.Provider); !ok {
+			t.Fatalf("got %T, want *cache.Provider", p)
+		}
+		if p.Name() != "omdb" {
+			t.Fatalf("name %q", p.Name())
+		}
+
+		t.Setenv("TITLE_CACHE_ENABLED", "")
+		if p, _ := factory.NewFromEnv(factory.Options{}); p.Name() != "omdb" {
+			t.Fatalf("name %q", p.Name())
+		} else if _, ok := p.(*cache.Provider); ok {
+			t.Fatal("expected no cache with TITLE_CACHE_ENABLED unset")
+		}
+	})
+
+	t.Run("chain", func(t *testing.T) {
+		t.Setenv("TITLE_PROVIDER", "chain:hybrid, omdb")
+		t.Setenv("TMDB_API_KEY", "tk")
+		t.Setenv("OMDB_API_KEY", "ok")
+		p, err := factory.NewFromEnv(factory.Options{})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if p.Name() != "chain:hybrid,omdb" {
+			t.Fatalf("name %q", p.Name())
+		}
+	})
+
+	t.Run("chain rejects bad lists", func(t *testing.T) {
+		t.Setenv("TMDB_API_KEY", "tk")
+		t.Setenv("OMDB_API_KEY", "")
+		for _, value := range []string{"chain:", "chain:tmdb,tmdb", "chain:tmdb,bogus", "chain:tmdb,chain:tmdb", "chain:tmdb,omdb"} {
+			t.Setenv("TITLE_PROVIDER", value)
+			if _, err := factory.NewFromEnv(factory.Options{}); err == nil {
+				t.Errorf("%s: expected error", value)
+			}
+		}
+	})
+
+	t.Run("fixture serves TITLE_FIXTURE_DIR, which must exist", func(t *testing.T) {
+		t.Setenv("TITLE_PROVIDER", "fixture")
+		t.Setenv("TITLE_FIXTURE_DIR", t.TempDir())
+		p, err := factory.NewFromEnv(factory.Options{})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if p.Name() != "fixture" {
+			t.Fatalf("name %q", p.Name())
+		}
+
+		t.Setenv("TITLE_FIXTURE_DIR", filepath.Join(t.TempDir(), "missing"))
+		if _, err := factory.NewFromEnv(factory.Options{}); err == nil {
+			t.Fatal("expected error for a missing fixture directory")
+		}
+	})
+
+	t.Run("record mode wraps a real provider, never the fixture one", func(t *testing.T) {
+		t.Setenv("TITLE_PROVIDER", "omdb")
+		t.Setenv("OMDB_API_KEY", "k")
+		t.Setenv("TITLE_FIXTURE_RECORD", "true")
+		t.Setenv("TITLE_FIXTURE_DIR", filepath.Join(t.TempDir(), "recorded"))
+		p, err := factory.NewFromEnv(factory.Options{})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, ok := p.(*fixture.Recorder); !ok || p.Name() != "omdb" {
+			t.Fatalf("got %T named %q, want a recorder around omdb", p, p.Name())
+		}
+
+		t.Setenv("TITLE_PROVIDER", "fixture")
+		if _, err := factory.NewFromEnv(factory.Options{}); err == nil {
+			t.Fatal("expected error for recording the fixture provider")
+		}
+	})
+}
+
+func TestNewStreamingFromEnv(t *testing.T) {
+	t.Run("off by default", func(t *testing.T) {
+		t.Setenv("STREAMING_PROVIDER", "")
+		p, err := factory.NewStreamingFromEnv()
+		if err != nil || p != nil {
+			t.Fatalf("got %v, %v; want no provider", p, err)
+		}
+	})
+
+	t.Run("tmdb", func(t *testing.T) {
+		t.Setenv("STREAMING_PROVIDER", "tmdb")
+		t.Setenv("TMDB_API_KEY", "k")
+		p, err := factory.NewStreamingFromEnv()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if p.Name() != "tmdb" {
+			t.Fatalf("name %q", p.Name())
+		}
+	})
+
+	t.Run("tmdb requires key", func(t *testing.T) {
+		t.Setenv("STREAMING_PROVIDER", "tmdb")
+		t.Setenv("TMDB_API_KEY", "")
+		if _, err := factory.NewStreamingFromEnv(); err == nil {
+			t.Fatal("expected error for missing TMDB_API_KEY")
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Setenv("STREAMING_PROVIDER", "justwatch")
+		if _, err := factory.NewStreamingFromEnv(); err == nil {
+			t.Fatal("expected error for an unknown provider")
+		}
+	})
+}
+
+func TestNewTMDBLookup(t *testing.T) {
+	t.Run("from key", func(t *testing.T) {
+		t.Setenv("TMDB_API_KEY", "k")
+		if _, ok := factory.NewTMDBLookup(imdbapi.New()).(*tmdb.Provider); !ok {
+			t.Fatal("expected a TMDB client")
+		}
+	})
+
+	t.Run("provider itself", func(t *testing.T) {
+		t.Setenv("TMDB_API_KEY", "")
+		provider := tmdb.New("k")
+		if got := factory.NewTMDBLookup(provider); got != provider {
+			t.Fatalf("got %v, want the provider itself", got)
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		t.Setenv("TMDB_API_KEY", "")
+		if got := factory.NewTMDBLookup(imdbapi.New()); got != nil {
+			t.Fatalf("got %v, want nil", got)
+		}
+	})
 }
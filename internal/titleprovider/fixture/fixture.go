@@ -0,0 +1,178 @@
+// Package fixture is an offline title provider: it answers from a directory of
+// JSON files instead of a network API, for local development, demos, and
+// regression fixtures. Selected with TITLE_PROVIDER=fixture. See ../README.md.
+//
+// The directory holds one file per title and one per search, each in the
+// neutral titleprovider shape:
+//
+//	<dir>/titles/tt0068646.json          a titleprovider.Title
+//	<dir>/searches/the-godfather.json    a []titleprovider.SearchItem
+//
+// Nobody has to write them by hand: a Recorder wraps a real provider and
+// writes every answer it gives into the same layout, so browsing the app once
+// against TMDB with recording on leaves behind a directory the fixture
+// provider can serve forever after.
+//
+// All file access goes through an os.Root, so an ID or query can never name a
+// file outside the directory.
+package fixture
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+const (
+	titlesDir   = "titles"
+	searchesDir = "searches"
+)
+
+// Provider implements titleprovider.Provider over a fixture directory.
+type Provider struct {
+	root *os.Root
+}
+
+// New serves the fixture directory at dir, which must exist. Files are read on
+// every call, so fixtures recorded while the server runs are picked up.
+func New(dir string) (*Provider, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{root: root}, nil
+}
+
+func (p *Provider) Name() string { return "fixture" }
+
+// GetTitle returns the title stored for imdbID, or ErrTitleNotFound when
+// there is none.
+func (p *Provider) GetTitle(_ context.Context, imdbID string) (*titleprovider.Title, error) {
+	name, ok := titleFile(imdbID)
+	if !ok {
+		return nil, titleprovider.ErrTitleNotFound
+	}
+
+	var title titleprovider.Title
+	if err := p.read(name, &title); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, titleprovider.ErrTitleNotFound
+		}
+		return nil, err
+	}
+	return &title, nil
+}
+
+// SearchTitles returns the recorded results for query when there are some,
+// cut to limit (a search recorded with a smaller limit stays that short).
+// Otherwise it searches the stored titles themselves, matching every word of
+// the query against the primary title, so a directory of titles alone is
+// enough to demo adding one.
+func (p *Provider) SearchTitles(_ context.Context, query string, limit int) ([]titleprovider.SearchItem, error) {
+	if name, ok := searchFile(query); ok {
+		var items []titleprovider.SearchItem
+		err := p.read(name, &items)
+		if err == nil {
+			return items[:min(limit, len(items))], nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return p.searchTitles(query, limit)
+}
+
+// searchTitles matches query against every stored title, in file name (that
+// is, ID) order.
+func (p *Provider) searchTitles(query string, limit int) ([]titleprovider.SearchItem, error) {
+	items := []titleprovider.SearchItem{}
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return items, nil
+	}
+
+	entries, err := fs.ReadDir(p.root.FS(), titlesDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return items, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if len(items) == limit {
+			break
+		}
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		var title titleprovider.Title
+		if err := p.read(path.Join(titlesDir, entry.Name()), &title); err != nil {
+			return nil, err
+		}
+		primary := strings.ToLower(title.PrimaryTitle)
+		if !slices.ContainsFunc(words, func(w string) bool { return !strings.Contains(primary, w) }) {
+			items = append(items, searchItem(title))
+		}
+	}
+	return items, nil
+}
+
+func (p *Provider) read(name string, dst any) error {
+	data, err := fs.ReadFile(p.root.FS(), name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// searchItem is the search result a provider would have returned for t.
+func searchItem(t titleprovider.Title) titleprovider.SearchItem {
+	return titleprovider.SearchItem{
+		ID:           t.ID,
+		Type:         t.Type,
+		PrimaryTitle: t.PrimaryTitle,
+		PrimaryImage: t.PrimaryImage,
+		StartYear:    t.StartYear,
+		Rating:       t.Rating,
+	}
+}
+
+// titleFile is the file holding imdbID, relative to the fixture directory.
+// IDs that could not be a plain file name (empty, or holding a separator or a
+// dot) have none.
+func titleFile(imdbID string) (string, bool) {
+	if imdbID == "" || strings.ContainsAny(imdbID, `/\.`) {
+		return "", false
+	}
+	return path.Join(titlesDir, imdbID+".json"), true
+}
+
+// searchFile is the file holding the results for query: its letters and
+// digits, lowercased, with every other run of characters folded into one
+// hyphen, so queries that differ only in case, spacing or punctuation share a
+// file. A query with no letters or digits has none.
+func searchFile(query string) (string, bool) {
+	var b strings.Builder
+	for _, r := range strings.ToLower(query) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "", false
+	}
+	return path.Join(searchesDir, slug+".json"), true
+}
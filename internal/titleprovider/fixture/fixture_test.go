@@ -0,0 +1,153 @@
+package fixture
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+// upstream stands in for the real provider a Recorder wraps.
+type upstream struct{}
+
+func (upstream) GetTitle(_ context.Context, imdbID string) (*titleprovider.Title, error) {
+	if imdbID != "tt0068646" {
+		return nil, titleprovider.ErrTitleNotFound
+	}
+	return &titleprovider.Title{
+		ID:           imdbID,
+		Type:         "movie",
+		PrimaryTitle: "The Godfather",
+		StartYear:    1972,
+		Rating:       titleprovider.Rating{AggregateRating: 9.2, VoteCount: 2100000},
+		Genres:       []string{"Crime", "Drama"},
+	}, nil
+}
+
+func (upstream) SearchTitles(context.Context, string, int) ([]titleprovider.SearchItem, error) {
+	return []titleprovider.SearchItem{
+		{ID: "tt0068646", PrimaryTitle: "The Godfather"},
+		{ID: "tt0071562", PrimaryTitle: "The Godfather Part II"},
+	}, nil
+}
+
+func (upstream) Name() string { return "tmdb" }
+
+func TestRecordThenServe(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "fixtures")
+
+	recorder, err := NewRecorder(upstream{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Name() != "tmdb" {
+		t.Fatalf("recorder name = %q, want the wrapped provider's", recorder.Name())
+	}
+	if _, err := recorder.GetTitle(ctx, "tt0068646"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.GetTitle(ctx, "tt404"); !errors.Is(err, titleprovider.ErrTitleNotFound) {
+		t.Fatalf("err = %v, want the upstream not-found passed through", err)
+	}
+	if _, err := recorder.SearchTitles(ctx, "The Godfather!", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	title, err := p.GetTitle(ctx, "tt0068646")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title.PrimaryTitle != "The Godfather" || title.Rating.AggregateRating != 9.2 || len(title.Genres) != 2 {
+		t.Fatalf("got %+v, want the recorded title", title)
+	}
+	if _, err := p.GetTitle(ctx, "tt404"); !errors.Is(err, titleprovider.ErrTitleNotFound) {
+		t.Fatalf("err = %v, want ErrTitleNotFound for an unrecorded title", err)
+	}
+
+	items, err := p.SearchTitles(ctx, "  the GODFATHER ", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != "tt0068646" {
+		t.Fatalf("got %+v, want the recorded search cut to the limit", items)
+	}
+}
+
+func TestSearchFallsBackToTitles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "titles", "tt0068646.json"), `{"ID":"tt0068646","PrimaryTitle":"The Godfather","StartYear":1972}`)
+	writeFile(t, filepath.Join(dir, "titles", "tt0099685.json"), `{"ID":"tt0099685","PrimaryTitle":"Goodfellas"}`)
+	writeFile(t, filepath.Join(dir, "titles", "README.md"), "not a title")
+
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := p.SearchTitles(context.Background(), "godfather the", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != "tt0068646" || items[0].StartYear != 1972 {
+		t.Fatalf("got %+v, want only The Godfather", items)
+	}
+
+	items, err = p.SearchTitles(context.Background(), "?!", 5)
+	if err != nil || items == nil || len(items) != 0 {
+		t.Fatalf("got %v, %v; want an empty result", items, err)
+	}
+}
+
+func TestIDsStayInsideTheDirectory(t *testing.T) {
+	parent := t.TempDir()
+	writeFile(t, filepath.Join(parent, "secret.json"), `{"ID":"secret"}`)
+	dir := filepath.Join(parent, "fixtures")
+	if err := os.Mkdir(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"../../secret", "..", "", "a/b"} {
+		if _, err := p.GetTitle(context.Background(), id); !errors.Is(err, titleprovider.ErrTitleNotFound) {
+			t.Errorf("GetTitle(%q) = %v, want ErrTitleNotFound", id, err)
+		}
+	}
+}
+
+func TestSearchFile(t *testing.T) {
+	for query, want := range map[string]string{
+		"The Godfather":           "searches/the-godfather.json",
+		"  the   GODFATHER!! ":    "searches/the-godfather.json",
+		"Amélie (2001)":           "searches/amélie-2001.json",
+		"../../etc/passwd":        "searches/etc-passwd.json",
+		"Spider-Man: No Way Home": "searches/spider-man-no-way-home.json",
+	} {
+		if got, ok := searchFile(query); !ok || got != want {
+			t.Errorf("searchFile(%q) = %q, want %q", query, got, want)
+		}
+	}
+	if _, ok := searchFile(" -- "); ok {
+		t.Error("expected no file for a query with no letters or digits")
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
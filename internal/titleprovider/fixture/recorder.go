@@ -0,0 +1,89 @@
+package fixture
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+// Recorder wraps a real provider and writes every successful answer into a
+// fixture directory that Provider can serve. Enabled with
+// TITLE_FIXTURE_RECORD=true.
+//
+// Recording never changes an answer: a file that cannot be written is logged
+// and the answer is returned anyway. A later answer for the same title or
+// query overwrites the file, so the directory holds the latest of each.
+type Recorder struct {
+	next titleprovider.Provider
+	root *os.Root
+}
+
+// NewRecorder records next's answers into dir, creating it if needed.
+func NewRecorder(next titleprovider.Provider, dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range []string{titlesDir, searchesDir} {
+		if err := root.Mkdir(sub, 0o750); err != nil && !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+	}
+	return &Recorder{next: next, root: root}, nil
+}
+
+// Name returns the wrapped provider's name: recording does not change whose
+// answer it is.
+func (r *Recorder) Name() string { return r.next.Name() }
+
+func (r *Recorder) GetTitle(ctx context.Context, imdbID string) (*titleprovider.Title, error) {
+	title, err := r.next.GetTitle(ctx, imdbID)
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := titleFile(title.ID); ok {
+		r.write(name, title)
+	}
+	return title, nil
+}
+
+func (r *Recorder) SearchTitles(ctx context.Context, query string, limit int) ([]titleprovider.SearchItem, error) {
+	items, err := r.next.SearchTitles(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := searchFile(query); ok {
+		r.write(name, items)
+	}
+	return items, nil
+}
+
+func (r *Recorder) write(name string, v any) {
+	if err := r.writeFile(name, v); err != nil {
+		log.Printf("WARN: title fixture: recording %s: %v", name, err)
+	}
+}
+
+func (r *Recorder) writeFile(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := r.root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
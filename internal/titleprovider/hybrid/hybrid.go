@@ -6,6 +6,7 @@ package hybrid
 
 import (
 	"context"
+	"errors"
 
 	"github.com/lealre/movies-backend/internal/titleprovider"
 	"github.com/lealre/movies-backend/internal/titleprovider/omdb"
@@ -22,12 +23,28 @@ type RatingSource interface {
 type Provider struct {
 	meta  titleprovider.Provider
 	rater RatingSource
+	// strict fails GetTitle when the rating source is out of quota, rather
+	// than degrading to the metadata provider's rating.
+	strict bool
 }
 
 // New builds a hybrid provider from a metadata provider (e.g. TMDB) and an OMDb
 // API key used to fetch IMDb ratings.
 func New(meta titleprovider.Provider, omdbAPIKey string) *Provider {
-	return &Provider{meta: meta, rater: omdb.New(omdbAPIKey)}
+	return NewWithRatings(meta, omdb.New(omdbAPIKey), false)
+}
+
+// NewWithRatings builds a hybrid provider over a rating source the caller has
+// already configured — in practice an *omdb.Provider metered by a daily quota.
+//
+// strict is for background work (the refresh routine): when the rating source
+// reports titleprovider.ErrQuotaExhausted, GetTitle returns that error instead
+// of degrading, since a refresh that degraded would overwrite each stored IMDb
+// rating with the metadata provider's. Interactive callers leave it off, so
+// adding a title still works, with the metadata provider's rating, once the
+// day's OMDb requests are spent.
+func NewWithRatings(meta titleprovider.Provider, rater RatingSource, strict bool) *Provider {
+	return &Provider{meta: meta, rater: rater, strict: strict}
 }
 
 // newWith is the test seam; it injects an arbitrary metadata provider and rating source.
@@ -40,7 +57,9 @@ func (p *Provider) Name() string { return "hybrid" }
 // GetTitle fetches metadata from the metadata provider, then overlays the IMDb
 // rating (and Metacritic) from the rating source. If the rating source fails or
 // has no rating, the metadata provider's rating is kept (graceful degradation),
-// so a momentary OMDb outage never blocks adding or refreshing a title.
+// so a momentary OMDb outage never blocks adding or refreshing a title. The
+// exception is a strict provider whose rating source is out of quota (see
+// NewWithRatings).
 func (p *Provider) GetTitle(ctx context.Context, imdbID string) (*titleprovider.Title, error) {
 	t, err := p.meta.GetTitle(ctx, imdbID)
 	if err != nil {
@@ -48,6 +67,9 @@ func (p *Provider) GetTitle(ctx context.Context, imdbID string) (*titleprovider.
 	}
 
 	rating, metacritic, rerr := p.rater.RatingByID(ctx, imdbID)
+	if p.strict && errors.Is(rerr, titleprovider.ErrQuotaExhausted) {
+		return nil, rerr
+	}
 	if rerr == nil {
 		if rating.AggregateRating > 0 {
 			t.Rating = rating
@@ -3,6 +3,7 @@ package hybrid
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/lealre/movies-backend/internal/titleprovider"
@@ -83,6 +84,30 @@ func TestGetTitle_RaterErrorKeepsMetaRating(t *testing.T) {
 	}
 }
 
+func TestGetTitle_QuotaExhausted(t *testing.T) {
+	meta := fakeMeta{title: baseTitle()}
+	rater := fakeRater{err: fmt.Errorf("omdb: %w", titleprovider.ErrQuotaExhausted)}
+
+	// Interactive: degrade, exactly as for any other rating source error.
+	got, err := newWith(meta, rater).GetTitle(context.Background(), "tt11378946")
+	if err != nil || got.Rating.AggregateRating != 8.6 {
+		t.Fatalf("got %+v, %v; want the meta rating without an error", got, err)
+	}
+
+	// Strict (the refresh routine): fail, so the stored IMDb rating is not
+	// overwritten with the meta provider's.
+	strict := NewWithRatings(meta, rater, true)
+	if _, err := strict.GetTitle(context.Background(), "tt11378946"); !errors.Is(err, titleprovider.ErrQuotaExhausted) {
+		t.Fatalf("err = %v, want ErrQuotaExhausted", err)
+	}
+
+	// Strict only concerns the quota; an outage still degrades.
+	outage := NewWithRatings(meta, fakeRater{err: errors.New("omdb down")}, true)
+	if _, err := outage.GetTitle(context.Background(), "tt11378946"); err != nil {
+		t.Fatalf("strict hybrid must still degrade on an outage: %v", err)
+	}
+}
+
 func TestGetTitle_ZeroImdbRatingKeepsMeta(t *testing.T) {
 	meta := fakeMeta{title: baseTitle()}
 	rater := fakeRater{rating: titleprovider.Rating{AggregateRating: 0}} // OMDb had no rating (N/A)
@@ -19,11 +19,18 @@ import (
 
 const defaultBaseURL = "https://www.omdbapi.com"
 
+// Quota meters OMDb requests against the daily allowance. Implemented by
+// *quota.Counter; an interface here keeps omdb free of the store.
+type Quota interface {
+	Reserve(ctx context.Context) error
+}
+
 // Provider implements titleprovider.Provider against OMDb.
 type Provider struct {
 	baseURL string
 	apiKey  string
 	client  *http.Client
+	quota   Quota
 }
 
 // New returns an OMDb provider. apiKey is a free OMDb API key (omdbapi.com/apikey.aspx).
@@ -35,12 +42,30 @@ func newWithBaseURL(baseURL, apiKey string) *Provider {
 	return &Provider{baseURL: baseURL, apiKey: apiKey, client: http.DefaultClient}
 }
 
+// WithQuota meters every request p makes with q, and returns p. Unmetered by
+// default.
+func (p *Provider) WithQuota(q Quota) *Provider {
+	p.quota = q
+	return p
+}
+
 func (p *Provider) Name() string { return "omdb" }
 
 // get issues a GET against OMDb with the api key attached and decodes the JSON
 // body into out. OMDb always replies 200; logical errors live in the body's
 // Response/Error fields, which callers inspect.
+//
+// With a quota set, the request is reserved against it first and not made at
+// all once the day's allowance is spent — every call lands here, so a series
+// lookup that needs one request per season is metered per request, not per
+// title.
 func (p *Provider) get(ctx context.Context, params url.Values, out any) error {
+	if p.quota != nil {
+		if err := p.quota.Reserve(ctx); err != nil {
+			return err
+		}
+	}
+
 	params.Set("apikey", p.apiKey)
 	reqURL := p.baseURL + "/?" + params.Encode()
 
@@ -64,12 +89,17 @@ func (p *Provider) get(ctx context.Context, params url.Values, out any) error {
 }
 
 // omdbErr classifies an OMDb "Response":"False" body: genuine not-found becomes
-// ErrTitleNotFound; anything else (e.g. "Request limit reached!") is a real error.
+// ErrTitleNotFound; OMDb's own "Request limit reached!" wraps ErrQuotaExhausted,
+// the same as running out of the locally metered budget; anything else is a
+// real error.
 func omdbErr(errMsg string) error {
 	low := strings.ToLower(errMsg)
 	if strings.Contains(low, "not found") || strings.Contains(low, "incorrect imdb id") {
 		return titleprovider.ErrTitleNotFound
 	}
+	if strings.Contains(low, "request limit reached") {
+		return fmt.Errorf("omdb: %s: %w", errMsg, titleprovider.ErrQuotaExhausted)
+	}
 	return fmt.Errorf("omdb: %s", errMsg)
 }
 
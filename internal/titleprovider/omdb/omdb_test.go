@@ -138,6 +138,50 @@ func TestGetTitle_RequestLimitIsNotNotFound(t *testing.T) {
 	if err == nil || errors.Is(err, titleprovider.ErrTitleNotFound) {
 		t.Fatalf("limit error must be a real error, not ErrTitleNotFound; got %v", err)
 	}
+	if !errors.Is(err, titleprovider.ErrQuotaExhausted) {
+		t.Fatalf("OMDb's own limit must read as ErrQuotaExhausted; got %v", err)
+	}
+}
+
+// fakeQuota allows left more requests, counting the ones it refuses.
+type fakeQuota struct {
+	left    int
+	refused int
+}
+
+func (q *fakeQuota) Reserve(context.Context) error {
+	if q.left == 0 {
+		q.refused++
+		return titleprovider.ErrQuotaExhausted
+	}
+	q.left--
+	return nil
+}
+
+func TestQuotaMetersEveryRequest(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Query().Get("Season") {
+		case "":
+			_, _ = w.Write([]byte(`{"Response":"True","Type":"series","Title":"Severance","totalSeasons":"2"}`))
+		default:
+			_, _ = w.Write([]byte(`{"Response":"True","Episodes":[]}`))
+		}
+	}))
+	defer srv.Close()
+
+	// The series needs three requests (the title, then one per season); the
+	// quota has room for two, so the third is never made.
+	q := &fakeQuota{left: 2}
+	p := newWithBaseURL(srv.URL, "key").WithQuota(q)
+	_, err := p.GetTitle(context.Background(), "tt11280740")
+	if !errors.Is(err, titleprovider.ErrQuotaExhausted) {
+		t.Fatalf("err = %v, want ErrQuotaExhausted", err)
+	}
+	if requests != 2 || q.refused != 1 {
+		t.Fatalf("requests = %d, refused = %d; want 2 made and 1 refused", requests, q.refused)
+	}
 }
 
 func TestSearchTitles(t *testing.T) {
@@ -8,6 +8,10 @@ import (
 // ErrTitleNotFound is returned when a title cannot be found for the given IMDb ID.
 var ErrTitleNotFound = errors.New("title not found")
 
+// ErrQuotaExhausted is returned when a provider's daily request budget is
+// spent (see the quota package), so the request was never made.
+var ErrQuotaExhausted = errors.New("title provider daily quota exhausted")
+
 // Provider is the vendor-neutral interface every title metadata source implements.
 type Provider interface {
 	// GetTitle returns a fully-populated title by IMDb ID (tt...), including
@@ -42,6 +46,19 @@ type Title struct {
 	Interests       []Interest // empty when the provider has no equivalent
 	Seasons         []Season
 	Episodes        []Episode
+	// Source names the provider that actually served this title, when that is
+	// not simply the provider it was asked of — a chain sets it to the link
+	// that answered. Empty otherwise; read it through SourceOf.
+	Source string
+}
+
+// SourceOf reports which provider served t, having asked p for it: t.Source
+// when set, otherwise p's own name.
+func SourceOf(p Provider, t *Title) string {
+	if t.Source != "" {
+		return t.Source
+	}
+	return p.Name()
 }
 
 type Image struct {
@@ -0,0 +1,111 @@
+// Package quota meters title provider requests against a daily budget shared
+// by every process — the server and the titles routine spend from the same
+// allowance (OMDb's free tier is 1,000 requests a day). See ../README.md.
+//
+// The count lives in the store (store.ProviderQuota), and a request is
+// reserved there before it is made, so two processes can never overspend
+// between them. Background work gets a lower limit than interactive work: the
+// budget minus a reserve, so a library refresh cannot use up the requests a
+// user adding a title needs.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/store"
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+// Counter reserves requests to one provider. It implements the Quota
+// interface of the providers that are metered (omdb).
+type Counter struct {
+	store    store.ProviderQuota
+	provider string
+	limit    int
+}
+
+// NewCounter meters provider against budget requests a day. background
+// callers stop reserve requests short of it.
+func NewCounter(st store.ProviderQuota, provider string, budget, reserve int, background bool) *Counter {
+	limit := budget
+	if background {
+		limit = max(budget-reserve, 0)
+	}
+	return &Counter{store: st, provider: provider, limit: limit}
+}
+
+// Reserve counts one request, or returns an error wrapping
+// titleprovider.ErrQuotaExhausted when today's limit has been reached and the
+// request must not be made.
+func (c *Counter) Reserve(ctx context.Context) error {
+	ok, err := c.store.ReserveProviderRequest(ctx, c.provider, c.limit)
+	if err != nil {
+		return fmt.Errorf("%s: reserving a request: %w", c.provider, err)
+	}
+	if !ok {
+		return fmt.Errorf("%s: %w (%d requests today)", c.provider, titleprovider.ErrQuotaExhausted, c.limit)
+	}
+	return nil
+}
+
+// Budget is a metered provider's daily allowance.
+type Budget struct {
+	Provider string
+	Budget   int
+	Reserve  int
+}
+
+// Usage is one provider's consumption so far today.
+type Usage struct {
+	Provider string `json:"provider"`
+	Requests int    `json:"requests"`
+	// Budget and Reserve are zero for a provider that made requests but is
+	// not metered.
+	Budget  int `json:"budget"`
+	Reserve int `json:"reserve"`
+	// Remaining is what interactive requests may still spend today;
+	// background work may spend Remaining - Reserve.
+	Remaining int `json:"remaining"`
+}
+
+// Report is today's consumption across providers.
+type Report struct {
+	Day       string  `json:"day"`
+	Providers []Usage `json:"providers"`
+}
+
+// Today reports today's (UTC) consumption: every budgeted provider, whether or
+// not it has been called yet, followed by any other provider with requests on
+// record.
+func Today(ctx context.Context, st store.ProviderQuota, budgets []Budget, now time.Time) (Report, error) {
+	counts, err := st.GetProviderRequestCountsToday(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	requests := make(map[string]int, len(counts))
+	for _, count := range counts {
+		requests[count.Provider] = count.Requests
+	}
+
+	report := Report{Day: now.UTC().Format(time.DateOnly), Providers: []Usage{}}
+	budgeted := map[string]bool{}
+	for _, b := range budgets {
+		budgeted[b.Provider] = true
+		used := requests[b.Provider]
+		report.Providers = append(report.Providers, Usage{
+			Provider:  b.Provider,
+			Requests:  used,
+			Budget:    b.Budget,
+			Reserve:   b.Reserve,
+			Remaining: max(b.Budget-used, 0),
+		})
+	}
+	for _, count := range counts {
+		if !budgeted[count.Provider] {
+			report.Providers = append(report.Providers, Usage{Provider: count.Provider, Requests: count.Requests})
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,83 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+// memoryCounts is a store.ProviderQuota in a map, for a single day.
+type memoryCounts map[string]int
+
+func (m memoryCounts) ReserveProviderRequest(_ context.Context, provider string, limit int) (bool, error) {
+	if m[provider] >= limit {
+		return false, nil
+	}
+	m[provider]++
+	return true, nil
+}
+
+func (m memoryCounts) GetProviderRequestCountsToday(context.Context) ([]models.ProviderRequestCount, error) {
+	counts := []models.ProviderRequestCount{}
+	for provider, requests := range m {
+		counts = append(counts, models.ProviderRequestCount{Provider: provider, Requests: requests})
+	}
+	return counts, nil
+}
+
+func TestCounterKeepsTheReserveForInteractiveWork(t *testing.T) {
+	ctx := context.Background()
+	counts := memoryCounts{}
+	background := NewCounter(counts, "omdb", 5, 2, true)
+	interactive := NewCounter(counts, "omdb", 5, 2, false)
+
+	for range 3 {
+		if err := background.Reserve(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := background.Reserve(ctx); !errors.Is(err, titleprovider.ErrQuotaExhausted) {
+		t.Fatalf("err = %v, want background work stopped at budget minus reserve", err)
+	}
+
+	for range 2 {
+		if err := interactive.Reserve(ctx); err != nil {
+			t.Fatalf("interactive work must still have the reserve: %v", err)
+		}
+	}
+	if err := interactive.Reserve(ctx); !errors.Is(err, titleprovider.ErrQuotaExhausted) {
+		t.Fatalf("err = %v, want interactive work stopped at the budget", err)
+	}
+	if counts["omdb"] != 5 {
+		t.Fatalf("requests = %d, want exactly the budget", counts["omdb"])
+	}
+}
+
+func TestToday(t *testing.T) {
+	counts := memoryCounts{"omdb": 1200, "tmdb": 40}
+	now := time.Date(2026, 3, 1, 23, 30, 0, 0, time.FixedZone("BRT", -3*60*60))
+
+	report, err := Today(context.Background(), counts, []Budget{{Provider: "omdb", Budget: 1000, Reserve: 100}}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Day != "2026-03-02" {
+		t.Fatalf("day = %q, want the UTC day", report.Day)
+	}
+	want := []Usage{
+		{Provider: "omdb", Requests: 1200, Budget: 1000, Reserve: 100, Remaining: 0},
+		{Provider: "tmdb", Requests: 40},
+	}
+	if len(report.Providers) != len(want) {
+		t.Fatalf("providers = %+v, want %+v", report.Providers, want)
+	}
+	for i := range want {
+		if report.Providers[i] != want[i] {
+			t.Fatalf("providers[%d] = %+v, want %+v", i, report.Providers[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Package streaming is where a title can be watched: which services carry it
+// on a subscription (flatrate), for rent, or to buy, region by region. It is
+// a separate interface from titleprovider.Provider on purpose — availability
+// changes weekly while a title's metadata barely changes at all, so the two
+// are fetched, cached and refreshed on different schedules, and a deployment
+// can have either without the other. Selected with STREAMING_PROVIDER. See
+// ../README.md.
+package streaming
+
+import "context"
+
+// Provider is the vendor-neutral interface every availability source
+// implements.
+type Provider interface {
+	// GetAvailability returns where the title with this IMDb ID (tt...) can
+	// be watched, in every region the source knows. A title the source knows
+	// but nobody offers has no regions; a title it does not know at all is
+	// titleprovider.ErrTitleNotFound.
+	GetAvailability(ctx context.Context, imdbID string) (*Availability, error)
+
+	// Name returns the provider identifier, stored alongside what it fetched.
+	Name() string
+}
+
+// Availability is one title's offers, by region code (ISO 3166-1, e.g. "US").
+type Availability struct {
+	Regions map[string]Region
+}
+
+// Region is where a title can be watched in one region, each list in the
+// source's display order. Link is the source's page for the title there, if
+// it has one.
+type Region struct {
+	Link     string
+	Flatrate []Service
+	Rent     []Service
+	Buy      []Service
+}
+
+// Service is one streaming service. ID is the source's own, stable across
+// titles, and is what users pick their subscriptions by.
+type Service struct {
+	ID              int
+	Name            string
+	LogoURL         string
+	DisplayPriority int
+}
@@ -0,0 +1,186 @@
+package titleref
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+// searchLimit bounds how many search results a slug is matched against.
+const searchLimit = 10
+
+// TMDBLookup maps a TMDB ID to its IMDb ID. *tmdb.Provider implements it.
+// mediaType is KindMovie or KindTV.
+type TMDBLookup interface {
+	IMDbID(ctx context.Context, mediaType string, tmdbID int) (string, error)
+}
+
+// Resolver resolves references to IMDb IDs. Slugs are searched for with
+// provider; TMDB IDs are mapped with tmdb, which may be nil, in which case
+// TMDB links are ErrUnsupported.
+type Resolver struct {
+	provider titleprovider.Provider
+	tmdb     TMDBLookup
+}
+
+func NewResolver(provider titleprovider.Provider, tmdb TMDBLookup) *Resolver {
+	return &Resolver{provider: provider, tmdb: tmdb}
+}
+
+// Resolve parses input and returns the IMDb ID it names. It makes no request
+// for an IMDb reference; it does not check that the title exists either — the
+// caller's GetTitle does that.
+func (r *Resolver) Resolve(ctx context.Context, input string) (string, error) {
+	ref, err := Parse(input)
+	if err != nil {
+		return "", err
+	}
+
+	switch ref.Source {
+	case SourceIMDb:
+		return ref.ID, nil
+	case SourceTMDB:
+		return r.resolveTMDB(ctx, input, ref)
+	default:
+		return r.resolveSlug(ctx, input, ref)
+	}
+}
+
+func (r *Resolver) resolveTMDB(ctx context.Context, input string, ref Reference) (string, error) {
+	if r.tmdb == nil {
+		return "", unsupported(input, SourceTMDB, "TMDB links need TMDB_API_KEY to be set")
+	}
+	id, err := strconv.Atoi(ref.ID)
+	if err != nil {
+		return "", unsupported(input, SourceTMDB, "the link does not name a TMDB title")
+	}
+	imdbID, err := r.tmdb.IMDbID(ctx, ref.Kind, id)
+	if errors.Is(err, titleprovider.ErrTitleNotFound) {
+		return "", &Error{Input: input, Source: SourceTMDB, Reason: "TMDB has no IMDb ID for this title", Err: titleprovider.ErrTitleNotFound}
+	}
+	return imdbID, err
+}
+
+// resolveSlug searches for the title a slug names and accepts it only when
+// exactly one result matches: same normalized title, same kind when the
+// reference has one, and same year when the slug ends in one. A trailing
+// number that turns out not to be a year ("blade-runner-2049") is tried as
+// part of the title when reading it as a year matches nothing.
+func (r *Resolver) resolveSlug(ctx context.Context, input string, ref Reference) (string, error) {
+	type reading struct {
+		title string
+		year  int
+	}
+	readings := []reading{{title: ref.ID}}
+	if ref.Year != 0 {
+		readings = []reading{{title: strings.TrimSuffix(ref.ID, "-"+strconv.Itoa(ref.Year)), year: ref.Year}, {title: ref.ID}}
+	}
+
+	for _, rd := range readings {
+		id, err := r.match(ctx, input, ref.Source, strings.ReplaceAll(rd.title, "-", " "), rd.year, ref.Kind)
+		if !errors.Is(err, titleprovider.ErrTitleNotFound) {
+			return id, err
+		}
+	}
+	return "", &Error{Input: input, Source: ref.Source, Reason: "no title matches the link", Err: titleprovider.ErrTitleNotFound}
+}
+
+// ResolveTitle returns the IMDb ID of the one title called title, released in
+// year when year is not 0 and of kind when kind is not "", matched as a slug
+// is. It is for sources that name a title rather than link to it, such as a
+// Letterboxd export; source is reported on the *Error when there is no single
+// match.
+func (r *Resolver) ResolveTitle(ctx context.Context, source, title string, year int, kind string) (string, error) {
+	input := title
+	if year != 0 {
+		input = fmt.Sprintf("%s (%d)", title, year)
+	}
+	return r.match(ctx, input, source, title, year, kind)
+}
+
+// match searches for query and returns the one result matching it, by
+// normalized title, year and kind. No match is an *Error wrapping
+// ErrTitleNotFound; more than one, ErrAmbiguous with the candidates.
+func (r *Resolver) match(ctx context.Context, input, source, query string, year int, kind string) (string, error) {
+	items, err := r.provider.SearchTitles(ctx, query, searchLimit)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []titleprovider.SearchItem
+	for _, item := range items {
+		if normalize(item.PrimaryTitle) != normalize(query) {
+			continue
+		}
+		if year != 0 && item.StartYear != year {
+			continue
+		}
+		if kind != "" && kindOf(item.Type) != kind {
+			continue
+		}
+		matches = append(matches, item)
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", &Error{Input: input, Source: source, Reason: "no title matches", Err: titleprovider.ErrTitleNotFound}
+	case 1:
+		return matches[0].ID, nil
+	default:
+		return "", &Error{
+			Input:      input,
+			Source:     source,
+			Reason:     "add the year, or use the IMDb link",
+			Candidates: matches,
+			Err:        ErrAmbiguous,
+		}
+	}
+}
+
+// normalize reduces a title to lowercase letters and digits separated by
+// single spaces, which is how a slug spells it: "Léon: The Professional" and
+// "leon the professional" both become "leon the professional" once accents
+// are dropped, which slugs do too.
+func normalize(title string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(title) {
+		r = unaccent(r)
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case r == '\'' || r == '’':
+			// "Schindler's List" is "schindlers-list".
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// unaccent folds the Latin-1 accented letters slugs drop.
+func unaccent(r rune) rune {
+	const from, to = "àáâãäåèéêëìíîïòóôõöùúûüýÿñç", "aaaaaaeeeeiiiiooooouuuuyync"
+	if i := strings.IndexRune(from, r); i >= 0 {
+		return []rune(to)[len([]rune(from[:i]))]
+	}
+	return r
+}
+
+func kindOf(titleType string) string {
+	switch titleType {
+	case "tvSeries", "tvMiniSeries":
+		return KindTV
+	default:
+		return KindMovie
+	}
+}
@@ -0,0 +1,196 @@
+// Package titleref turns what a user pastes to name a title — an IMDb link or
+// bare ID, or a TMDB, Letterboxd or Trakt link — into the IMDb ID the rest of
+// the app keys titles by. See ../README.md.
+//
+// Parse only reads the reference; Resolver.Resolve also looks up the IMDb ID
+// of references that do not carry one. TMDB links name a TMDB ID, which TMDB
+// maps to IMDb directly. Letterboxd and Trakt links carry only a slug (e.g.
+// "the-godfather-1972"), which is resolved by searching the title provider for
+// it and accepting a single exact match — more than one is ErrAmbiguous, with
+// the candidates attached, rather than a guess.
+//
+// Every failure to do with the reference itself is an *Error, so callers can
+// tell the user what was wrong with what they pasted; anything else (the
+// provider being down) is returned as is.
+package titleref
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+// Where a reference comes from.
+const (
+	SourceIMDb       = "imdb"
+	SourceTMDB       = "tmdb"
+	SourceLetterboxd = "letterboxd"
+	SourceTrakt      = "trakt"
+)
+
+// What kind of title a reference names, when the reference says.
+const (
+	KindMovie = "movie"
+	KindTV    = "tv"
+)
+
+var (
+	// ErrUnsupported is a reference in no form this package reads.
+	ErrUnsupported = errors.New("unsupported title reference")
+	// ErrAmbiguous is a reference that matches more than one title.
+	ErrAmbiguous = errors.New("title reference matches more than one title")
+)
+
+// Error is a reference that could not be resolved. Err is ErrUnsupported,
+// ErrAmbiguous or titleprovider.ErrTitleNotFound; Candidates lists the
+// matching titles when it is ErrAmbiguous.
+type Error struct {
+	Input      string
+	Source     string // "" when the reference was not recognised at all
+	Reason     string
+	Candidates []titleprovider.SearchItem
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Reason == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v: %s", e.Err, e.Reason)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Reference is a parsed title reference. ID is an IMDb ID for SourceIMDb, a
+// TMDB ID for SourceTMDB, and a slug for Letterboxd and Trakt. Kind is set
+// when the reference says whether it names a movie or a series; Year when a
+// slug ends in one.
+type Reference struct {
+	Source string
+	ID     string
+	Kind   string
+	Year   int
+}
+
+var (
+	imdbIDPattern = regexp.MustCompile(`^tt[0-9]+$`)
+	tmdbIDPattern = regexp.MustCompile(`^([0-9]+)(?:-.*)?$`)
+	slugPattern   = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	yearSuffix    = regexp.MustCompile(`^(.+)-((?:18|19|20)[0-9]{2})$`)
+)
+
+// Parse reads input as one of the supported forms:
+//
+//	tt0068646
+//	https://www.imdb.com/title/tt0068646/   (also m.imdb.com, /de/title/..., query strings)
+//	https://www.themoviedb.org/movie/238-the-godfather   (and /tv/...)
+//	https://letterboxd.com/film/the-godfather/           (and /<user>/film/...)
+//	https://trakt.tv/movies/the-godfather-1972           (and /shows/...)
+//
+// The scheme may be left out. Anything else is an *Error wrapping
+// ErrUnsupported, whose Reason says what was expected.
+func Parse(input string) (Reference, error) {
+	raw := strings.TrimSpace(input)
+	if imdbIDPattern.MatchString(strings.ToLower(raw)) {
+		return Reference{Source: SourceIMDb, ID: strings.ToLower(raw)}, nil
+	}
+
+	withScheme := raw
+	if !strings.Contains(raw, "://") {
+		withScheme = "https://" + raw
+	}
+	u, err := url.Parse(withScheme)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Reference{}, unsupported(input, "", "expected an IMDb ID (tt...) or a title link")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range []string{"www.", "m.", "app."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	var segments []string
+	for _, s := range strings.Split(u.Path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+
+	switch host {
+	case "imdb.com":
+		return parseIMDb(input, segments)
+	case "themoviedb.org":
+		return parseTMDB(input, segments)
+	case "letterboxd.com":
+		return parseLetterboxd(input, segments)
+	case "boxd.it":
+		return Reference{}, unsupported(input, SourceLetterboxd, "use the film's letterboxd.com link rather than a boxd.it short link")
+	case "trakt.tv":
+		return parseTrakt(input, segments)
+	default:
+		return Reference{}, unsupported(input, "", "links from "+host+" are not supported; use IMDb, TMDB, Letterboxd or Trakt")
+	}
+}
+
+// parseIMDb finds "title/tt..." anywhere in the path, which covers the
+// language-prefixed (/de/title/...) and sub-page (/title/tt.../reviews) forms.
+func parseIMDb(input string, segments []string) (Reference, error) {
+	for i := 0; i+1 < len(segments); i++ {
+		if segments[i] == "title" && imdbIDPattern.MatchString(segments[i+1]) {
+			return Reference{Source: SourceIMDb, ID: segments[i+1]}, nil
+		}
+	}
+	return Reference{}, unsupported(input, SourceIMDb, "expected an IMDb title link (imdb.com/title/tt...)")
+}
+
+func parseTMDB(input string, segments []string) (Reference, error) {
+	if len(segments) >= 2 && (segments[0] == "movie" || segments[0] == "tv") {
+		if m := tmdbIDPattern.FindStringSubmatch(segments[1]); m != nil {
+			return Reference{Source: SourceTMDB, ID: m[1], Kind: segments[0]}, nil
+		}
+	}
+	return Reference{}, unsupported(input, SourceTMDB, "expected a TMDB movie or TV link (themoviedb.org/movie/... or /tv/...)")
+}
+
+// parseLetterboxd accepts a film page, directly or under a member's profile
+// (/<user>/film/<slug>/), as both are what the share button copies.
+func parseLetterboxd(input string, segments []string) (Reference, error) {
+	for i := 0; i+1 < len(segments) && i < 2; i++ {
+		if segments[i] == "film" {
+			return slugReference(input, SourceLetterboxd, "", segments[i+1])
+		}
+	}
+	return Reference{}, unsupported(input, SourceLetterboxd, "expected a Letterboxd film link (letterboxd.com/film/...)")
+}
+
+func parseTrakt(input string, segments []string) (Reference, error) {
+	if len(segments) >= 2 {
+		switch segments[0] {
+		case "movies":
+			return slugReference(input, SourceTrakt, KindMovie, segments[1])
+		case "shows":
+			return slugReference(input, SourceTrakt, KindTV, segments[1])
+		}
+	}
+	return Reference{}, unsupported(input, SourceTrakt, "expected a Trakt movie or show link (trakt.tv/movies/... or /shows/...)")
+}
+
+func slugReference(input, source, kind, slug string) (Reference, error) {
+	slug = strings.ToLower(slug)
+	if !slugPattern.MatchString(slug) {
+		return Reference{}, unsupported(input, source, "the link does not name a title")
+	}
+	ref := Reference{Source: source, ID: slug, Kind: kind}
+	if m := yearSuffix.FindStringSubmatch(slug); m != nil {
+		ref.Year, _ = strconv.Atoi(m[2])
+	}
+	return ref, nil
+}
+
+func unsupported(input, source, reason string) *Error {
+	return &Error{Input: input, Source: source, Reason: reason, Err: ErrUnsupported}
+}
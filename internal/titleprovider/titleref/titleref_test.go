@@ -0,0 +1,151 @@
+package titleref
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+func TestParse(t *testing.T) {
+	valid := map[string]Reference{
+		"tt0068646":                             {Source: SourceIMDb, ID: "tt0068646"},
+		" TT0068646 ":                           {Source: SourceIMDb, ID: "tt0068646"},
+		"https://www.imdb.com/title/tt0068646/": {Source: SourceIMDb, ID: "tt0068646"},
+		"https://m.imdb.com/title/tt0068646/?ref_=nv_sr_srsg_0": {Source: SourceIMDb, ID: "tt0068646"},
+		"imdb.com/de/title/tt0068646/reviews":                   {Source: SourceIMDb, ID: "tt0068646"},
+		"https://www.themoviedb.org/movie/238-the-godfather":    {Source: SourceTMDB, ID: "238", Kind: KindMovie},
+		"https://www.themoviedb.org/tv/1396?language=en-US":     {Source: SourceTMDB, ID: "1396", Kind: KindTV},
+		"https://letterboxd.com/film/the-godfather/":            {Source: SourceLetterboxd, ID: "the-godfather"},
+		"https://letterboxd.com/someone/film/the-thing-1982/":   {Source: SourceLetterboxd, ID: "the-thing-1982", Year: 1982},
+		"https://trakt.tv/movies/the-godfather-1972":            {Source: SourceTrakt, ID: "the-godfather-1972", Kind: KindMovie, Year: 1972},
+		"https://app.trakt.tv/shows/breaking-bad/seasons/1":     {Source: SourceTrakt, ID: "breaking-bad", Kind: KindTV},
+	}
+	for input, want := range valid {
+		got, err := Parse(input)
+		if err != nil || got != want {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v", input, got, err, want)
+		}
+	}
+
+	invalid := map[string]string{
+		"":                                       "",
+		"the godfather":                          "",
+		"ftp://imdb.com/title/tt0068646":         "",
+		"https://www.imdb.com/name/nm0000008/":   SourceIMDb,
+		"https://www.themoviedb.org/person/3084": SourceTMDB,
+		"https://boxd.it/2bg8":                   SourceLetterboxd,
+		"https://letterboxd.com/someone/":        SourceLetterboxd,
+		"https://trakt.tv/users/someone":         SourceTrakt,
+		"https://www.netflix.com/title/60011152": "",
+	}
+	for input, source := range invalid {
+		_, err := Parse(input)
+		var refErr *Error
+		if !errors.As(err, &refErr) || !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Parse(%q) err = %v, want an *Error wrapping ErrUnsupported", input, err)
+			continue
+		}
+		if refErr.Source != source || refErr.Reason == "" {
+			t.Errorf("Parse(%q) = source %q, reason %q; want source %q and a reason", input, refErr.Source, refErr.Reason, source)
+		}
+	}
+}
+
+type searchProvider struct {
+	titleprovider.Provider
+	results map[string][]titleprovider.SearchItem
+	queries []string
+}
+
+func (p *searchProvider) SearchTitles(_ context.Context, query string, _ int) ([]titleprovider.SearchItem, error) {
+	p.queries = append(p.queries, query)
+	return p.results[query], nil
+}
+
+type tmdbLookup map[int]string
+
+func (l tmdbLookup) IMDbID(_ context.Context, _ string, id int) (string, error) {
+	if imdbID, ok := l[id]; ok {
+		return imdbID, nil
+	}
+	return "", titleprovider.ErrTitleNotFound
+}
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+	thing1982 := titleprovider.SearchItem{ID: "tt0084787", Type: "movie", PrimaryTitle: "The Thing", StartYear: 1982}
+	thing2011 := titleprovider.SearchItem{ID: "tt0905372", Type: "movie", PrimaryTitle: "The Thing", StartYear: 2011}
+	provider := &searchProvider{results: map[string][]titleprovider.SearchItem{
+		"the thing":             {thing1982, thing2011, {ID: "tt1", Type: "movie", PrimaryTitle: "The Thing Called Love"}},
+		"leon the professional": {{ID: "tt0110413", Type: "movie", PrimaryTitle: "Léon: The Professional", StartYear: 1994}},
+		"blade runner":          {{ID: "tt0083658", Type: "movie", PrimaryTitle: "Blade Runner", StartYear: 1982}},
+		"blade runner 2049":     {{ID: "tt1856101", Type: "movie", PrimaryTitle: "Blade Runner 2049", StartYear: 2017}},
+		"breaking bad": {
+			{ID: "tt0903747", Type: "tvSeries", PrimaryTitle: "Breaking Bad", StartYear: 2008},
+			{ID: "tt9", Type: "movie", PrimaryTitle: "Breaking Bad", StartYear: 2020},
+		},
+	}}
+	r := NewResolver(provider, tmdbLookup{238: "tt0068646"})
+
+	resolves := map[string]string{
+		"https://www.imdb.com/title/tt0068646/":              "tt0068646",
+		"https://www.themoviedb.org/movie/238-the-godfather": "tt0068646",
+		"https://letterboxd.com/film/the-thing-1982/":        "tt0084787",
+		"https://letterboxd.com/film/leon-the-professional/": "tt0110413",
+		"https://trakt.tv/movies/blade-runner-2049":          "tt1856101",
+		"https://trakt.tv/shows/breaking-bad":                "tt0903747",
+	}
+	for input, want := range resolves {
+		if got, err := r.Resolve(ctx, input); err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+
+	_, err := r.Resolve(ctx, "https://letterboxd.com/film/the-thing/")
+	var refErr *Error
+	if !errors.As(err, &refErr) || !errors.Is(err, ErrAmbiguous) || len(refErr.Candidates) != 2 {
+		t.Fatalf("err = %v, want ErrAmbiguous with both films as candidates", err)
+	}
+
+	for _, input := range []string{"https://letterboxd.com/film/nothing-like-it/", "https://www.themoviedb.org/movie/1"} {
+		if _, err := r.Resolve(ctx, input); !errors.As(err, &refErr) || !errors.Is(err, titleprovider.ErrTitleNotFound) {
+			t.Errorf("Resolve(%q) err = %v, want an *Error wrapping ErrTitleNotFound", input, err)
+		}
+	}
+
+	noTMDB := NewResolver(provider, nil)
+	if _, err := noTMDB.Resolve(ctx, "https://www.themoviedb.org/movie/238"); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("err = %v, want ErrUnsupported without a TMDB lookup", err)
+	}
+}
+
+func TestResolveTitle(t *testing.T) {
+	ctx := context.Background()
+	provider := &searchProvider{results: map[string][]titleprovider.SearchItem{
+		"The Thing": {
+			{ID: "tt0084787", Type: "movie", PrimaryTitle: "The Thing", StartYear: 1982},
+			{ID: "tt0905372", Type: "movie", PrimaryTitle: "The Thing", StartYear: 2011},
+		},
+		"Léon: The Professional": {{ID: "tt0110413", Type: "movie", PrimaryTitle: "Leon the Professional", StartYear: 1994}},
+	}}
+	r := NewResolver(provider, nil)
+
+	if got, err := r.ResolveTitle(ctx, SourceLetterboxd, "The Thing", 2011, ""); err != nil || got != "tt0905372" {
+		t.Errorf("with year = %q, %v; want tt0905372", got, err)
+	}
+	if got, err := r.ResolveTitle(ctx, SourceLetterboxd, "Léon: The Professional", 0, KindMovie); err != nil || got != "tt0110413" {
+		t.Errorf("without year = %q, %v; want tt0110413", got, err)
+	}
+
+	_, err := r.ResolveTitle(ctx, SourceLetterboxd, "The Thing", 0, "")
+	var refErr *Error
+	if !errors.As(err, &refErr) || !errors.Is(err, ErrAmbiguous) || refErr.Input != "The Thing" {
+		t.Errorf("err = %v, want ErrAmbiguous", err)
+	}
+	_, err = r.ResolveTitle(ctx, SourceLetterboxd, "The Thing", 1990, "")
+	if !errors.As(err, &refErr) || !errors.Is(err, titleprovider.ErrTitleNotFound) || refErr.Input != "The Thing (1990)" {
+		t.Errorf("err = %v, want ErrTitleNotFound for The Thing (1990)", err)
+	}
+}
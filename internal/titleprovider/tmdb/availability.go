@@ -0,0 +1,86 @@
+package tmdb
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/lealre/movies-backend/internal/titleprovider"
+	"github.com/lealre/movies-backend/internal/titleprovider/streaming"
+)
+
+// logoBaseURL serves provider logos at a size fit for a badge; the original
+// size is several hundred pixels wide.
+const logoBaseURL = "https://image.tmdb.org/t/p/w92"
+
+// GetAvailability implements streaming.Provider with TMDB's watch providers
+// (data from JustWatch): the IMDb ID is resolved with /find, as in GetTitle,
+// then one call returns every region at once.
+func (p *Provider) GetAvailability(ctx context.Context, imdbID string) (*streaming.Availability, error) {
+	var find findResponse
+	q := url.Values{}
+	q.Set("external_source", "imdb_id")
+	if err := p.getJSON(ctx, "/find/"+imdbID, q, &find); err != nil {
+		return nil, err
+	}
+
+	var path string
+	switch {
+	case len(find.MovieResults) > 0:
+		path = "/movie/" + strconv.Itoa(find.MovieResults[0].ID) + "/watch/providers"
+	case len(find.TVResults) > 0:
+		path = "/tv/" + strconv.Itoa(find.TVResults[0].ID) + "/watch/providers"
+	default:
+		return nil, titleprovider.ErrTitleNotFound
+	}
+
+	var resp watchProvidersResponse
+	if err := p.getJSON(ctx, path, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	availability := &streaming.Availability{Regions: make(map[string]streaming.Region, len(resp.Results))}
+	for code, r := range resp.Results {
+		availability.Regions[code] = streaming.Region{
+			Link:     r.Link,
+			Flatrate: mapWatchProviders(r.Flatrate),
+			Rent:     mapWatchProviders(r.Rent),
+			Buy:      mapWatchProviders(r.Buy),
+		}
+	}
+	return availability, nil
+}
+
+func mapWatchProviders(in []watchProvider) []streaming.Service {
+	out := make([]streaming.Service, 0, len(in))
+	for _, wp := range in {
+		svc := streaming.Service{
+			ID:              wp.ProviderID,
+			Name:            wp.ProviderName,
+			DisplayPriority: wp.DisplayPriority,
+		}
+		if wp.LogoPath != "" {
+			svc.LogoURL = logoBaseURL + wp.LogoPath
+		}
+		out = append(out, svc)
+	}
+	return out
+}
+
+type watchProvidersResponse struct {
+	Results map[string]watchProviderRegion `json:"results"`
+}
+
+type watchProviderRegion struct {
+	Link     string          `json:"link"`
+	Flatrate []watchProvider `json:"flatrate"`
+	Rent     []watchProvider `json:"rent"`
+	Buy      []watchProvider `json:"buy"`
+}
+
+type watchProvider struct {
+	ProviderID      int    `json:"provider_id"`
+	ProviderName    string `json:"provider_name"`
+	LogoPath        string `json:"logo_path"`
+	DisplayPriority int    `json:"display_priority"`
+}
@@ -0,0 +1,81 @@
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+func TestGetAvailability_Movie(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"/find/tt0068646": `{"movie_results":[{"id":238}],"tv_results":[]}`,
+		"/movie/238/watch/providers": `{"id":238,"results":{
+			"US":{"link":"https://www.themoviedb.org/movie/238/watch?locale=US",
+				"flatrate":[{"logo_path":"/para.jpg","provider_id":531,"provider_name":"Paramount Plus","display_priority":4}],
+				"rent":[{"logo_path":"/apple.jpg","provider_id":2,"provider_name":"Apple TV","display_priority":1},
+					{"logo_path":"","provider_id":3,"provider_name":"Google Play Movies","display_priority":2}],
+				"buy":[{"logo_path":"/apple.jpg","provider_id":2,"provider_name":"Apple TV","display_priority":1}]},
+			"BR":{"link":"https://www.themoviedb.org/movie/238/watch?locale=BR",
+				"flatrate":[{"logo_path":"/max.jpg","provider_id":1899,"provider_name":"Max","display_priority":7}]}}}`,
+	})
+	defer srv.Close()
+
+	p := newWithBaseURL(srv.URL, "key")
+	got, err := p.GetAvailability(context.Background(), "tt0068646")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Regions) != 2 {
+		t.Fatalf("regions = %v, want US and BR", got.Regions)
+	}
+
+	us := got.Regions["US"]
+	if us.Link != "https://www.themoviedb.org/movie/238/watch?locale=US" {
+		t.Errorf("link = %q", us.Link)
+	}
+	if len(us.Flatrate) != 1 || us.Flatrate[0].ID != 531 || us.Flatrate[0].Name != "Paramount Plus" ||
+		us.Flatrate[0].LogoURL != "https://image.tmdb.org/t/p/w92/para.jpg" || us.Flatrate[0].DisplayPriority != 4 {
+		t.Errorf("flatrate = %+v", us.Flatrate)
+	}
+	if len(us.Rent) != 2 || us.Rent[1].LogoURL != "" {
+		t.Errorf("rent = %+v, want two, the second without a logo", us.Rent)
+	}
+	if len(us.Buy) != 1 {
+		t.Errorf("buy = %+v", us.Buy)
+	}
+	if br := got.Regions["BR"]; len(br.Flatrate) != 1 || len(br.Rent) != 0 || br.Rent == nil {
+		t.Errorf("BR = %+v, want one flatrate and empty, non-nil rent", br)
+	}
+}
+
+func TestGetAvailability_TV(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"/find/tt0903747":          `{"movie_results":[],"tv_results":[{"id":1396}]}`,
+		"/tv/1396/watch/providers": `{"id":1396,"results":{}}`,
+	})
+	defer srv.Close()
+
+	p := newWithBaseURL(srv.URL, "key")
+	got, err := p.GetAvailability(context.Background(), "tt0903747")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Regions == nil || len(got.Regions) != 0 {
+		t.Fatalf("regions = %v, want known but offered nowhere", got.Regions)
+	}
+}
+
+func TestGetAvailability_NotFound(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"/find/tt0000000": `{"movie_results":[],"tv_results":[]}`,
+	})
+	defer srv.Close()
+
+	p := newWithBaseURL(srv.URL, "key")
+	_, err := p.GetAvailability(context.Background(), "tt0000000")
+	if !errors.Is(err, titleprovider.ErrTitleNotFound) {
+		t.Fatalf("expected ErrTitleNotFound, got %v", err)
+	}
+}
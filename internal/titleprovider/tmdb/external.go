@@ -0,0 +1,26 @@
+package tmdb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+// IMDbID returns the IMDb ID TMDB links to its movie (mediaType "movie") or
+// series ("tv") tmdbID — the reverse of the /find lookup GetTitle starts
+// with. A TMDB title with no IMDb ID, or no such TMDB title, is
+// titleprovider.ErrTitleNotFound.
+func (p *Provider) IMDbID(ctx context.Context, mediaType string, tmdbID int) (string, error) {
+	if mediaType != "movie" && mediaType != "tv" {
+		return "", fmt.Errorf("tmdb: unknown media type %q", mediaType)
+	}
+	var ext tmdbExternalIDs
+	if err := p.getJSON(ctx, fmt.Sprintf("/%s/%d/external_ids", mediaType, tmdbID), nil, &ext); err != nil {
+		return "", err
+	}
+	if ext.IMDbID == "" {
+		return "", titleprovider.ErrTitleNotFound
+	}
+	return ext.IMDbID, nil
+}
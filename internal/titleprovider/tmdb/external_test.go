@@ -0,0 +1,37 @@
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lealre/movies-backend/internal/titleprovider"
+)
+
+func TestIMDbID(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"/movie/238/external_ids": `{"id":238,"imdb_id":"tt0068646"}`,
+		"/tv/1396/external_ids":   `{"id":1396,"imdb_id":"tt0903747"}`,
+		"/movie/1/external_ids":   `{"id":1,"imdb_id":null}`,
+	})
+	defer srv.Close()
+	p := newWithBaseURL(srv.URL, "key")
+	ctx := context.Background()
+
+	if got, err := p.IMDbID(ctx, "movie", 238); err != nil || got != "tt0068646" {
+		t.Fatalf("movie: got %q, %v", got, err)
+	}
+	if got, err := p.IMDbID(ctx, "tv", 1396); err != nil || got != "tt0903747" {
+		t.Fatalf("tv: got %q, %v", got, err)
+	}
+	if _, err := p.IMDbID(ctx, "movie", 1); !errors.Is(err, titleprovider.ErrTitleNotFound) {
+		t.Fatalf("no IMDb ID: err = %v, want ErrTitleNotFound", err)
+	}
+	// Unrouted, so the test server answers 404.
+	if _, err := p.IMDbID(ctx, "tv", 999); !errors.Is(err, titleprovider.ErrTitleNotFound) {
+		t.Fatalf("404: err = %v, want ErrTitleNotFound", err)
+	}
+	if _, err := p.IMDbID(ctx, "person", 1); err == nil {
+		t.Fatal("expected an error for an unknown media type")
+	}
+}
@@ -123,7 +123,8 @@ func retryAfterDelay(header string, attempt int) time.Duration {
 }
 
 // getJSON performs a throttled GET with the api_key query param, retrying on
-// HTTP 429, and decodes the JSON response into out.
+// HTTP 429, and decodes the JSON response into out. A 404 — an ID TMDB does
+// not have — wraps titleprovider.ErrTitleNotFound.
 func (p *Provider) getJSON(ctx context.Context, path string, query url.Values, out any) error {
 	if query == nil {
 		query = url.Values{}
@@ -159,6 +160,11 @@ func (p *Provider) getJSON(ctx context.Context, path string, query url.Values, o
 			return lastErr
 		}
 
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return fmt.Errorf("tmdb: %s: %w", path, titleprovider.ErrTitleNotFound)
+		}
+
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 			body, _ := io.ReadAll(resp.Body)
 			resp.Body.Close()
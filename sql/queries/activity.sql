@@ -1,9 +1,13 @@
 -- name: InsertActivityEventRow :one
+-- An id already in the log inserts nothing and returns no row: the outbox
+-- relay delivers at least once, so the same event can arrive twice, and the
+-- second arrival must neither duplicate the row nor notify again.
 INSERT INTO activity_events (
     id, group_id, actor_id, actor_name, kind, title_id, title_name, payload, created_at
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7, $8, $9
 )
+ON CONFLICT (id) DO NOTHING
 RETURNING *;
 
 -- name: GetActivityFeedRows :many
@@ -15,6 +19,10 @@ RETURNING *;
 -- read_by_me is the reader's own state, joined per row: the same event is read
 -- for one member and unread for another, which is why it cannot live on
 -- activity_events.
+--
+-- Every filter is optional: NULL turns it off, so one statement serves the
+-- bare feed and any combination of filters. A group the reader is not in
+-- matches nothing rather than failing, since the view never holds it.
 SELECT v.id, v.seq, v.group_id, v.actor_id, v.actor_name, v.kind, v.title_id,
        v.title_name, v.payload, v.created_at, v.group_name,
        (v.seq <= COALESCE(f.floor_seq, 0) OR r.event_id IS NOT NULL)::boolean AS read_by_me
@@ -23,9 +31,61 @@ LEFT JOIN activity_read_floors f ON f.user_id = v.reader_id
 LEFT JOIN activity_event_reads r ON r.event_id = v.id AND r.user_id = v.reader_id
 WHERE v.reader_id = sqlc.arg('user_id')
   AND (sqlc.narg('before')::bigint IS NULL OR v.seq < sqlc.narg('before')::bigint)
+  AND (sqlc.narg('group_ids')::text[] IS NULL OR v.group_id = ANY(sqlc.narg('group_ids')::text[]))
+  AND (sqlc.narg('title_id')::text IS NULL OR v.title_id = sqlc.narg('title_id')::text)
+  AND (sqlc.narg('kinds')::text[] IS NULL OR v.kind = ANY(sqlc.narg('kinds')::text[]))
+  AND (sqlc.narg('actor_id')::text IS NULL OR v.actor_id = sqlc.narg('actor_id')::text)
+  AND (sqlc.narg('created_after')::timestamptz IS NULL OR v.created_at >= sqlc.narg('created_after')::timestamptz)
+  AND (sqlc.narg('created_before')::timestamptz IS NULL OR v.created_at < sqlc.narg('created_before')::timestamptz)
 ORDER BY v.seq DESC
 LIMIT sqlc.arg('row_limit')::bigint;
 
+-- name: GetActivityHistoryRows :many
+-- The history of the reader's groups, their own actions included: what a
+-- group page or a title's "what happened" panel shows, where leaving out the
+-- reader's own rating would leave a hole in the story. That is the one thing
+-- it does differently from activity_visible_events, which exists to decide
+-- what is unread, so it joins membership and deleted groups the same way
+-- rather than going through the view.
+--
+-- The reader's own actions read as read: they were never unread, the badge
+-- never counted them, and they cannot be marked read either. The filters are
+-- GetActivityFeedRows'.
+SELECT e.id, e.seq, e.group_id, e.actor_id, e.actor_name, e.kind, e.title_id,
+       e.title_name, e.payload, e.created_at, g.name AS group_name,
+       (e.actor_id = m.user_id OR e.seq <= COALESCE(f.floor_seq, 0) OR r.event_id IS NOT NULL)::boolean AS read_by_me
+FROM activity_events e
+JOIN group_members m ON m.group_id = e.group_id
+JOIN groups g ON g.id = e.group_id AND NOT g.deleted
+LEFT JOIN activity_read_floors f ON f.user_id = m.user_id
+LEFT JOIN activity_event_reads r ON r.event_id = e.id AND r.user_id = m.user_id
+WHERE m.user_id = sqlc.arg('user_id')
+  AND (sqlc.narg('before')::bigint IS NULL OR e.seq < sqlc.narg('before')::bigint)
+  AND (sqlc.narg('group_ids')::text[] IS NULL OR e.group_id = ANY(sqlc.narg('group_ids')::text[]))
+  AND (sqlc.narg('title_id')::text IS NULL OR e.title_id = sqlc.narg('title_id')::text)
+  AND (sqlc.narg('kinds')::text[] IS NULL OR e.kind = ANY(sqlc.narg('kinds')::text[]))
+  AND (sqlc.narg('actor_id')::text IS NULL OR e.actor_id = sqlc.narg('actor_id')::text)
+  AND (sqlc.narg('created_after')::timestamptz IS NULL OR e.created_at >= sqlc.narg('created_after')::timestamptz)
+  AND (sqlc.narg('created_before')::timestamptz IS NULL OR e.created_at < sqlc.narg('created_before')::timestamptz)
+ORDER BY e.seq DESC
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: GetActivityEventsSinceRows :many
+-- What a resuming stream missed: the events visible to the reader after the
+-- seq it last saw, oldest first, so they can be written in the order they
+-- happened. The same view and the same read_by_me as GetActivityFeedRows, so a
+-- replayed frame is the feed's element for that event.
+SELECT v.id, v.seq, v.group_id, v.actor_id, v.actor_name, v.kind, v.title_id,
+       v.title_name, v.payload, v.created_at, v.group_name,
+       (v.seq <= COALESCE(f.floor_seq, 0) OR r.event_id IS NOT NULL)::boolean AS read_by_me
+FROM activity_visible_events v
+LEFT JOIN activity_read_floors f ON f.user_id = v.reader_id
+LEFT JOIN activity_event_reads r ON r.event_id = v.id AND r.user_id = v.reader_id
+WHERE v.reader_id = sqlc.arg('user_id')
+  AND v.seq > sqlc.arg('after_seq')::bigint
+ORDER BY v.seq
+LIMIT sqlc.arg('row_limit')::bigint;
+
 -- name: CountActivityUnread :one
 -- The badge is exactly "visible events with no read row for me" — the same
 -- view, the same anti-join, as the read_by_me column above.
@@ -110,6 +170,12 @@ WHERE r.user_id = sqlc.arg('user_id')
 -- 8000 bytes, and the listener reads the row once and fans it out.
 SELECT pg_notify('activity_events', sqlc.arg('event_id')::text);
 
+-- name: NotifyActivityMembership :exec
+-- Fired with every change to who is in a group, in the change's transaction, so
+-- live streams stop showing a group's activity to someone who left it. The
+-- payload is the change as JSON: it is a few ids, far under pg_notify's cap.
+SELECT pg_notify('activity_membership', sqlc.arg('change')::text);
+
 -- name: GetActivityEventById :one
 -- Used by the LISTEN loop to turn a notified id into the row it pushes. No
 -- visibility predicate here: the loop has no reader, and the hub filters per
@@ -125,3 +191,29 @@ SELECT e.id, e.seq, e.group_id, e.actor_id, e.actor_name, e.kind, e.title_id,
 FROM activity_events e
 JOIN groups g ON g.id = e.group_id
 WHERE e.id = $1;
+
+-- name: GetCoalescibleActivityEvent :one
+-- The event a new one would be merged into: the same actor's latest event of
+-- the same kind, about the same title and season in the same group, recorded
+-- since the window opened. The new event's own id is left out, so delivering
+-- it a second time does not find it merged into itself. Same columns as
+-- GetActivityEventById, for the same reason.
+SELECT e.id, e.seq, e.group_id, e.actor_id, e.actor_name, e.kind, e.title_id,
+       e.title_name, e.payload, e.created_at, g.name AS group_name,
+       FALSE AS read_by_me
+FROM activity_events e
+JOIN groups g ON g.id = e.group_id
+WHERE e.group_id = sqlc.arg('group_id')
+  AND e.actor_id = sqlc.arg('actor_id')
+  AND e.kind = sqlc.arg('kind')
+  AND e.title_id = sqlc.arg('title_id')
+  AND COALESCE(e.payload ->> 'season', '') = sqlc.arg('season')::text
+  AND e.created_at >= sqlc.arg('since')
+  AND e.id <> sqlc.arg('exclude_id')
+ORDER BY e.seq DESC
+LIMIT 1;
+
+-- name: DeleteActivityEvent :exec
+-- The one exception to the log being append-only: an event merged into a
+-- later one. Its read rows go with it.
+DELETE FROM activity_events WHERE id = $1;
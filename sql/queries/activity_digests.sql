@@ -0,0 +1,72 @@
+-- name: GetActivityDigest :one
+SELECT * FROM activity_digests WHERE user_id = $1;
+
+-- name: UpsertActivityDigest :one
+-- Opting in starts from the newest event in the log (see 019). Saving the
+-- frequency a user already has keeps their schedule; changing it starts the new
+-- one from next_due_at as given, rather than waiting out a week for a daily.
+INSERT INTO activity_digests (user_id, frequency, digested_seq, next_due_at)
+VALUES (
+    sqlc.arg('user_id'),
+    sqlc.arg('frequency'),
+    (SELECT COALESCE(max(seq), 0)::bigint FROM activity_events),
+    sqlc.arg('next_due_at')
+)
+ON CONFLICT (user_id) DO UPDATE
+SET next_due_at = CASE WHEN activity_digests.frequency = EXCLUDED.frequency
+                       THEN activity_digests.next_due_at
+                       ELSE EXCLUDED.next_due_at END,
+    frequency   = EXCLUDED.frequency
+RETURNING *;
+
+-- name: DeleteActivityDigest :exec
+DELETE FROM activity_digests WHERE user_id = $1;
+
+-- name: ClaimActivityDigests :many
+-- Takes the due digests, oldest first, with who they go to, and pushes each
+-- one's next_due_at out to lease_until in the same statement, as
+-- ClaimWebhookDeliveries does: another job skips them from here on, and if
+-- this one dies before recording the send they come due again when the lease
+-- runs out.
+UPDATE activity_digests d
+SET next_due_at = sqlc.arg('lease_until')
+FROM users u
+WHERE u.id = d.user_id
+  AND d.user_id IN (
+      SELECT due.user_id
+      FROM activity_digests due
+      WHERE due.next_due_at <= sqlc.arg('now')
+      ORDER BY due.next_due_at, due.user_id
+      LIMIT sqlc.arg('row_limit')::bigint
+      FOR UPDATE OF due SKIP LOCKED
+  )
+RETURNING d.*, u.name, u.username, u.email, u.is_active;
+
+-- name: GetActivityDigestEventRows :many
+-- What a digest covers: the reader's unread events of the given kinds above
+-- after_seq, oldest first. Unread is the badge's — the same view, the same
+-- floor and exception rows — so a digest never mentions what the reader has
+-- already seen in the app, nor anything muted. Same columns as
+-- GetActivityFeedRows; read_by_me is FALSE, since every row is unread.
+SELECT v.id, v.seq, v.group_id, v.actor_id, v.actor_name, v.kind, v.title_id,
+       v.title_name, v.payload, v.created_at, v.group_name,
+       FALSE AS read_by_me
+FROM activity_visible_events v
+LEFT JOIN activity_read_floors f ON f.user_id = v.reader_id
+LEFT JOIN activity_event_reads r ON r.event_id = v.id AND r.user_id = v.reader_id
+WHERE v.reader_id = sqlc.arg('user_id')
+  AND v.seq > GREATEST(COALESCE(f.floor_seq, 0), sqlc.arg('after_seq')::bigint)
+  AND r.event_id IS NULL
+  AND v.kind = ANY(sqlc.arg('kinds')::text[])
+ORDER BY v.seq
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: CompleteActivityDigest :exec
+-- Records a run of the job. GREATEST keeps digested_seq from going back, and a
+-- run that sent nothing leaves last_sent_at as it was. A user who opted out
+-- while it ran has no row left to update.
+UPDATE activity_digests
+SET digested_seq = GREATEST(digested_seq, sqlc.arg('digested_seq')::bigint),
+    last_sent_at = COALESCE(sqlc.narg('last_sent_at')::timestamptz, last_sent_at),
+    next_due_at  = sqlc.arg('next_due_at')
+WHERE user_id = sqlc.arg('user_id');
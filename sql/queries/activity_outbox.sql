@@ -0,0 +1,19 @@
+-- name: InsertActivityOutboxRow :exec
+INSERT INTO activity_outbox (
+    id, group_id, actor_id, actor_name, kind, title_id, title_name, payload, created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+);
+
+-- name: ClaimActivityOutboxRows :many
+-- The oldest undelivered rows, locked until the relay's transaction ends.
+-- SKIP LOCKED is what lets every server run a relay: a second one takes the
+-- rows after these instead of waiting on them, and never delivers a row the
+-- first is still delivering.
+SELECT * FROM activity_outbox
+ORDER BY seq
+LIMIT sqlc.arg('row_limit')::bigint
+FOR UPDATE SKIP LOCKED;
+
+-- name: DeleteActivityOutboxRows :exec
+DELETE FROM activity_outbox WHERE seq = ANY(sqlc.arg('seqs')::bigint[]);
@@ -0,0 +1,36 @@
+-- name: GetActivityMutes :many
+SELECT * FROM activity_mutes
+WHERE user_id = $1
+ORDER BY kind NULLS LAST, group_id, title_id NULLS FIRST;
+
+-- name: DeleteActivityMutes :exec
+DELETE FROM activity_mutes WHERE user_id = $1;
+
+-- name: InsertActivityMute :exec
+-- Saving a mute that is already there is not an error: preferences are
+-- replaced whole, and a client that lists the same mute twice means it once.
+INSERT INTO activity_mutes (user_id, group_id, title_id, kind)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING;
+
+-- name: GetActivityQuietHours :one
+SELECT * FROM activity_quiet_hours WHERE user_id = $1;
+
+-- name: UpsertActivityQuietHours :exec
+INSERT INTO activity_quiet_hours (user_id, start_minute, end_minute, time_zone)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id) DO UPDATE
+SET start_minute = EXCLUDED.start_minute,
+    end_minute   = EXCLUDED.end_minute,
+    time_zone    = EXCLUDED.time_zone;
+
+-- name: DeleteActivityQuietHours :exec
+DELETE FROM activity_quiet_hours WHERE user_id = $1;
+
+-- name: NotifyActivityPreferences :exec
+-- Fired with every change to a user's preferences, in the change's
+-- transaction, so their live streams stop pushing what they just muted. The
+-- payload is the user id alone, as NotifyActivityEvent's is the event id: the
+-- listener reads the preferences once, and a long list of mutes would not fit
+-- under pg_notify's cap.
+SELECT pg_notify('activity_preferences', sqlc.arg('user_id')::text);
@@ -0,0 +1,128 @@
+-- name: ArchiveIsEmpty :one
+-- An archive is restored into an empty database only: every id in it is kept,
+-- so anything already there could collide with it. Users, titles and groups
+-- are the roots everything else hangs off.
+SELECT (
+    NOT EXISTS (SELECT 1 FROM users)
+    AND NOT EXISTS (SELECT 1 FROM titles)
+    AND NOT EXISTS (SELECT 1 FROM groups)
+)::boolean AS empty;
+
+-- name: ListUserRows :many
+SELECT * FROM users
+WHERE id > sqlc.arg('after_id')::text
+ORDER BY id
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: ListTitleRows :many
+SELECT * FROM titles
+WHERE id > sqlc.arg('after_id')::text
+ORDER BY id
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: ListGroupRows :many
+-- Soft-deleted groups included: they are kept, not purged, and a restore
+-- keeps them too.
+SELECT * FROM groups
+WHERE id > sqlc.arg('after_id')::text
+ORDER BY id
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: ListRecommendationSharingRows :many
+SELECT * FROM group_recommendation_sharing
+WHERE group_id > sqlc.arg('after_id')::text
+ORDER BY group_id
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: ListUserStreamingServiceRows :many
+SELECT user_id, region, service_ids, updated_at FROM user_streaming_services
+WHERE user_id > sqlc.arg('after_id')::text
+ORDER BY user_id
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: ListRatingRows :many
+SELECT * FROM ratings
+WHERE id > sqlc.arg('after_id')::text
+ORDER BY id
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: ListCommentRows :many
+SELECT * FROM comments
+WHERE id > sqlc.arg('after_id')::text
+ORDER BY id
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: ListActivityEventRows :many
+SELECT * FROM activity_events
+WHERE seq > sqlc.arg('after_seq')::bigint
+ORDER BY seq
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: ListActivityEventReadRows :many
+SELECT * FROM activity_event_reads
+WHERE (user_id, event_id) > (sqlc.arg('after_user_id')::text, sqlc.arg('after_event_id')::text)
+ORDER BY user_id, event_id
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: ListActivityReadFloorRows :many
+SELECT * FROM activity_read_floors
+WHERE user_id > sqlc.arg('after_id')::text
+ORDER BY user_id
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: ListActivityDigestRows :many
+SELECT * FROM activity_digests
+WHERE user_id > sqlc.arg('after_id')::text
+ORDER BY user_id
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: ListActivityPreferenceUserIds :many
+-- The users who muted something, set quiet hours, or both: the store reads
+-- each one's preferences whole, as GetActivityPreferences does.
+SELECT user_id FROM activity_mutes
+WHERE user_id > sqlc.arg('after_id')::text
+UNION
+SELECT user_id FROM activity_quiet_hours
+WHERE user_id > sqlc.arg('after_id')::text
+ORDER BY user_id
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: RestoreGroupRow :exec
+-- InsertGroup with every column given, deleted state included.
+INSERT INTO groups (id, name, description, owner_id, deleted, deleted_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: RestoreRecommendationSharingRow :exec
+INSERT INTO group_recommendation_sharing (group_id, enabled_by, enabled_at)
+VALUES ($1, $2, $3);
+
+-- name: RestoreActivityEventRow :exec
+-- seq is given rather than drawn from its sequence: read floors are seqs, so
+-- the events they cover must keep theirs. ResetActivityEventSeq moves the
+-- sequence past them afterwards.
+INSERT INTO activity_events (
+    id, seq, group_id, actor_id, actor_name, kind, title_id, title_name, payload, created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+);
+
+-- name: ResetActivityEventSeq :exec
+SELECT setval(
+    pg_get_serial_sequence('activity_events', 'seq'),
+    COALESCE((SELECT max(seq) FROM activity_events), 0) + 1,
+    false
+);
+
+-- name: RestoreActivityEventReadRow :exec
+INSERT INTO activity_event_reads (user_id, event_id, read_at)
+VALUES ($1, $2, $3);
+
+-- name: RestoreActivityReadFloorRow :exec
+INSERT INTO activity_read_floors (user_id, floor_seq, read_at)
+VALUES ($1, $2, $3);
+
+-- name: RestoreActivityDigestRow :exec
+-- digested_seq is kept as it was: it is a seq, like a read floor, and the
+-- events keep theirs, so the next digest starts where the last one stopped.
+INSERT INTO activity_digests (user_id, frequency, digested_seq, next_due_at, last_sent_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6);
@@ -0,0 +1,46 @@
+-- name: UpsertTitleAvailability :exec
+INSERT INTO title_availability (title_id, provider, links, fetched_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (title_id) DO UPDATE
+SET provider = EXCLUDED.provider, links = EXCLUDED.links, fetched_at = EXCLUDED.fetched_at;
+
+-- name: DeleteTitleAvailabilityOffers :exec
+DELETE FROM title_availability_offers WHERE title_id = $1;
+
+-- name: InsertTitleAvailabilityOffers :exec
+-- Every offer of one title in one statement: the columns arrive as parallel
+-- arrays, index i of each describing offer i.
+INSERT INTO title_availability_offers (title_id, region, kind, service_id, service_name, logo_url, display_priority)
+SELECT sqlc.arg('title_id'), o.region, o.kind, o.service_id, o.service_name, o.logo_url, o.display_priority
+FROM unnest(
+    sqlc.arg('regions')::text[],
+    sqlc.arg('kinds')::text[],
+    sqlc.arg('service_ids')::int[],
+    sqlc.arg('service_names')::text[],
+    sqlc.arg('logo_urls')::text[],
+    sqlc.arg('display_priorities')::int[]
+) AS o(region, kind, service_id, service_name, logo_url, display_priority);
+
+-- name: GetTitleAvailabilityHeaders :many
+-- Which of title_ids have been fetched, when, and their link for region (empty if
+-- the provider gave none).
+SELECT title_id, provider, fetched_at, COALESCE(links ->> sqlc.arg('region')::text, '')::text AS link
+FROM title_availability
+WHERE title_id = ANY(sqlc.arg('title_ids')::text[]);
+
+-- name: GetTitleAvailabilityOffers :many
+SELECT title_id, kind, service_id, service_name, logo_url, display_priority
+FROM title_availability_offers
+WHERE title_id = ANY(sqlc.arg('title_ids')::text[]) AND region = sqlc.arg('region')
+ORDER BY title_id, kind, display_priority, service_name;
+
+-- name: GetUserStreamingServices :one
+SELECT user_id, region, service_ids, updated_at FROM user_streaming_services
+WHERE user_id = $1;
+
+-- name: UpsertUserStreamingServices :one
+INSERT INTO user_streaming_services (user_id, region, service_ids, updated_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id) DO UPDATE
+SET region = EXCLUDED.region, service_ids = EXCLUDED.service_ids, updated_at = EXCLUDED.updated_at
+RETURNING user_id, region, service_ids, updated_at;
@@ -21,6 +21,9 @@ SELECT * FROM comments WHERE title_id = $1 AND user_id = $2 AND group_id = $3;
 -- name: GetCommentRowsByTitleId :many
 SELECT * FROM comments WHERE title_id = $1 AND group_id = $2;
 
+-- name: GetCommentRowsByTitleIds :many
+SELECT * FROM comments WHERE title_id = ANY($1::text[]) AND group_id = $2;
+
 -- name: DeleteCommentRow :execrows
 DELETE FROM comments WHERE id = $1 AND user_id = $2 AND group_id = $3;
 
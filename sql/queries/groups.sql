@@ -61,6 +61,15 @@ SET watched = EXCLUDED.watched,
     updated_at = EXCLUDED.updated_at
 RETURNING *;
 
+-- name: InsertGroupTitles :many
+-- Adds each of title_ids to the group, unwatched, skipping any it already has,
+-- and returns the ids actually inserted.
+INSERT INTO group_titles (group_id, title_id, watched, watched_at, added_at, updated_at)
+SELECT sqlc.arg('group_id')::text, t.title_id, false, NULL, sqlc.arg('added_at')::timestamptz, sqlc.arg('added_at')::timestamptz
+FROM unnest(sqlc.arg('title_ids')::text[]) AS t(title_id)
+ON CONFLICT (group_id, title_id) DO NOTHING
+RETURNING title_id;
+
 -- name: GetGroupTitleRow :one
 SELECT * FROM group_titles WHERE group_id = $1 AND title_id = $2;
 
@@ -109,6 +118,10 @@ SELECT * FROM groups WHERE id = $1;
 --
 -- page_size/page_offset are cast to bigint so sqlc generates int64 params —
 -- see the same note on GetTitlesPage.
+--
+-- service_ids/region is the "available on my services" filter: titles a
+-- subscription (flatrate) service among service_ids carries in region. An
+-- empty service_ids matches nothing; NULL is no filter.
 SELECT
     t.id, t.primary_title, t.type, t.start_year, t.rating_aggregate,
     t.vote_count, t.added_at, t.updated_at, t.metadata,
@@ -120,6 +133,11 @@ JOIN titles t ON t.id = gt.title_id
 WHERE gt.group_id = sqlc.arg('group_id')
   AND (sqlc.narg('watched')::boolean IS NULL OR gt.watched = sqlc.narg('watched'))
   AND (sqlc.narg('title_types')::text[] IS NULL OR t.type = ANY(sqlc.narg('title_types')::text[]))
+  AND (sqlc.narg('service_ids')::int[] IS NULL OR EXISTS (
+      SELECT 1 FROM title_availability_offers o
+      WHERE o.title_id = gt.title_id AND o.kind = 'flatrate'
+        AND o.region = sqlc.narg('region')::text
+        AND o.service_id = ANY(sqlc.narg('service_ids')::int[])))
 ORDER BY
     CASE WHEN sqlc.arg('order_by')::text = 'watched'   AND NOT sqlc.arg('descending')::bool THEN gt.watched END ASC,
     CASE WHEN sqlc.arg('order_by')::text = 'watched'   AND sqlc.arg('descending')::bool     THEN gt.watched END DESC,
@@ -176,7 +194,12 @@ SELECT count(*) FROM group_titles gt
 JOIN titles t ON t.id = gt.title_id
 WHERE gt.group_id = sqlc.arg('group_id')
   AND (sqlc.narg('watched')::boolean IS NULL OR gt.watched = sqlc.narg('watched'))
-  AND (sqlc.narg('title_types')::text[] IS NULL OR t.type = ANY(sqlc.narg('title_types')::text[]));
+  AND (sqlc.narg('title_types')::text[] IS NULL OR t.type = ANY(sqlc.narg('title_types')::text[]))
+  AND (sqlc.narg('service_ids')::int[] IS NULL OR EXISTS (
+      SELECT 1 FROM title_availability_offers o
+      WHERE o.title_id = gt.title_id AND o.kind = 'flatrate'
+        AND o.region = sqlc.narg('region')::text
+        AND o.service_id = ANY(sqlc.narg('service_ids')::int[])));
 
 -- name: GroupHasTitleEntries :one
 -- Does the group hold any title entry matching the filters, counting entries
@@ -199,9 +222,38 @@ SELECT EXISTS (
     WHERE gt.group_id = sqlc.arg('group_id')
       AND (sqlc.narg('watched')::boolean IS NULL OR gt.watched = sqlc.narg('watched'))
       AND (sqlc.narg('title_types')::text[] IS NULL OR t.type = ANY(sqlc.narg('title_types')::text[]))
+      AND (sqlc.narg('service_ids')::int[] IS NULL OR EXISTS (
+          SELECT 1 FROM title_availability_offers o
+          WHERE o.title_id = gt.title_id AND o.kind = 'flatrate'
+            AND o.region = sqlc.narg('region')::text
+            AND o.service_id = ANY(sqlc.narg('service_ids')::int[])))
 );
 
 -- name: GetGroupTitleSeasonRowsForTitles :many
 SELECT * FROM group_title_seasons
 WHERE group_id = sqlc.arg('group_id') AND title_id = ANY(sqlc.arg('title_ids')::text[])
 ORDER BY title_id, season;
+
+-- name: GetTitleIdsSeenByUsers :many
+-- Which of title_ids any of user_ids has already seen: rated in any group
+-- (a rating is a statement of having watched it), or watched — the whole
+-- title or any season — in another non-deleted group they belong to. The
+-- asking group's own watch state is left out: its caller only ever asks about
+-- titles that group has not finished, and a series it is halfway through is
+-- one to carry on with, not one its members have seen.
+SELECT r.title_id
+FROM ratings r
+WHERE r.user_id = ANY(sqlc.arg('user_ids')::text[])
+  AND r.title_id = ANY(sqlc.arg('title_ids')::text[])
+UNION
+SELECT gt.title_id
+FROM group_titles gt
+JOIN group_members gm ON gm.group_id = gt.group_id
+JOIN groups g ON g.id = gt.group_id AND NOT g.deleted
+WHERE gm.user_id = ANY(sqlc.arg('user_ids')::text[])
+  AND gt.title_id = ANY(sqlc.arg('title_ids')::text[])
+  AND gt.group_id <> sqlc.arg('group_id')
+  AND (gt.watched OR EXISTS (
+      SELECT 1 FROM group_title_seasons s
+      WHERE s.group_id = gt.group_id AND s.title_id = gt.title_id AND s.watched
+  ));
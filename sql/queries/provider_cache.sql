@@ -0,0 +1,14 @@
+-- name: GetProviderCacheEntry :one
+-- An entry past its expiry is treated as absent: the row may linger until the
+-- next cleanup or overwrite, but it is never served.
+SELECT value, expires_at FROM provider_cache
+WHERE key = $1 AND expires_at > now();
+
+-- name: UpsertProviderCacheEntry :exec
+INSERT INTO provider_cache (key, value, expires_at, stored_at)
+VALUES ($1, $2, $3, now())
+ON CONFLICT (key) DO UPDATE
+SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at, stored_at = now();
+
+-- name: DeleteExpiredProviderCacheEntries :execrows
+DELETE FROM provider_cache WHERE expires_at <= now();
@@ -0,0 +1,17 @@
+-- name: ReserveProviderRequest :one
+-- Counts one request against provider's total for the current UTC day, but
+-- only while that total is under request_limit. The conditional upsert makes
+-- check-and-increment a single atomic statement, so concurrent callers in any
+-- number of processes can never push the total past the limit between them.
+-- No row comes back when the limit has been reached.
+INSERT INTO provider_request_counts AS c (provider, day, requests)
+VALUES ($1, (now() AT TIME ZONE 'UTC')::date, 1)
+ON CONFLICT (provider, day) DO UPDATE
+SET requests = c.requests + 1
+WHERE c.requests < sqlc.arg('request_limit')::int
+RETURNING requests;
+
+-- name: GetProviderRequestCountsToday :many
+SELECT provider, day, requests FROM provider_request_counts
+WHERE day = (now() AT TIME ZONE 'UTC')::date
+ORDER BY provider;
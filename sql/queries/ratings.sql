@@ -42,3 +42,6 @@ INSERT INTO rating_seasons (
 ) VALUES (
     $1, $2, $3, $4, $5
 );
+
+-- name: GetRatingRowsByGroupId :many
+SELECT * FROM ratings WHERE group_id = $1 ORDER BY title_id, user_id;
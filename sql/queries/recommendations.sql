@@ -0,0 +1,48 @@
+-- name: GetRecommendationCandidates :many
+-- Catalogue titles a group has not seen: not in the group at all, or in it but
+-- not yet marked watched. A title the group has rated is excluded even when its
+-- watched flag was never set — a rating is the stronger statement that it has
+-- been seen. in_group tells the two kinds of candidate apart.
+--
+-- The pool is capped by candidate_limit and taken best-rated first, so on a
+-- large catalogue the scorer ranks the titles most worth ranking instead of an
+-- arbitrary slice. Total order, ending in t.id (CONVENTIONS §6).
+SELECT
+    t.id, t.primary_title, t.type, t.start_year, t.rating_aggregate,
+    t.vote_count, t.added_at, t.updated_at, t.metadata,
+    (gt.title_id IS NOT NULL)::boolean AS in_group
+FROM titles t
+LEFT JOIN group_titles gt ON gt.group_id = sqlc.arg('group_id') AND gt.title_id = t.id
+WHERE (gt.title_id IS NULL OR NOT gt.watched)
+  AND NOT EXISTS (
+      SELECT 1 FROM ratings r
+      WHERE r.group_id = sqlc.arg('group_id') AND r.title_id = t.id
+  )
+ORDER BY t.rating_aggregate DESC, t.vote_count DESC, t.id ASC
+LIMIT sqlc.arg('candidate_limit')::bigint;
+
+-- name: GetSharedRatingRowsByTitleIds :many
+-- Ratings other groups left on the given titles, from groups that opted in to
+-- sharing (group_recommendation_sharing) and are not soft-deleted. The asking
+-- group is excluded: its own ratings already drive the content model, and
+-- counting them again as a "similar group" would have it agree with itself.
+SELECT r.id, r.title_id, r.user_id, r.note, r.created_at, r.updated_at, r.group_id
+FROM ratings r
+JOIN group_recommendation_sharing s ON s.group_id = r.group_id
+JOIN groups g ON g.id = r.group_id AND NOT g.deleted
+WHERE r.title_id = ANY(sqlc.arg('title_ids')::text[])
+  AND r.group_id <> sqlc.arg('group_id')
+ORDER BY r.group_id, r.title_id, r.user_id;
+
+-- name: GroupSharesRecommendations :one
+SELECT EXISTS (SELECT 1 FROM group_recommendation_sharing WHERE group_id = $1);
+
+-- name: InsertGroupRecommendationSharing :exec
+-- Idempotent: enabling sharing that is already on keeps the original row, so
+-- enabled_by/enabled_at keep answering "who turned this on, and when".
+INSERT INTO group_recommendation_sharing (group_id, enabled_by, enabled_at)
+VALUES ($1, $2, now())
+ON CONFLICT (group_id) DO NOTHING;
+
+-- name: DeleteGroupRecommendationSharing :exec
+DELETE FROM group_recommendation_sharing WHERE group_id = $1;
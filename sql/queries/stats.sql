@@ -0,0 +1,24 @@
+-- name: GetGroupStatsVersion :one
+-- A fingerprint of everything a group's statistics are computed from: its
+-- titles and their watch state, its ratings, the catalogue rows behind its
+-- titles, and its members. Any write that could change the stats moves at
+-- least one of these counts or timestamps, so two equal fingerprints mean the
+-- stats computed for the first are still the answer for the second. Counts are
+-- there for deletes, which leave no updated_at behind.
+--
+-- Every subquery is served by an index leading with group_id (primary keys of
+-- group_titles, group_title_seasons and group_members; ratings_group_id_idx),
+-- so this stays a handful of index scans however large the group grows.
+SELECT md5(concat_ws('|',
+    (SELECT count(*) FROM group_titles WHERE group_id = $1),
+    (SELECT max(updated_at) FROM group_titles WHERE group_id = $1),
+    (SELECT count(*) FROM group_title_seasons WHERE group_id = $1),
+    (SELECT max(updated_at) FROM group_title_seasons WHERE group_id = $1),
+    (SELECT count(*) FROM ratings WHERE group_id = $1),
+    (SELECT max(updated_at) FROM ratings WHERE group_id = $1),
+    (SELECT count(*) FROM rating_seasons rs JOIN ratings r ON r.id = rs.rating_id WHERE r.group_id = $1),
+    (SELECT max(rs.updated_at) FROM rating_seasons rs JOIN ratings r ON r.id = rs.rating_id WHERE r.group_id = $1),
+    (SELECT max(t.updated_at) FROM titles t JOIN group_titles gt ON gt.title_id = t.id WHERE gt.group_id = $1),
+    (SELECT string_agg(gm.user_id || '@' || u.updated_at::text, ',' ORDER BY gm.user_id)
+       FROM group_members gm JOIN users u ON u.id = gm.user_id WHERE gm.group_id = $1)
+))::text AS version;
@@ -56,3 +56,6 @@ ORDER BY
     CASE WHEN sqlc.arg('order_by')::text = 'updatedAt'  AND sqlc.arg('descending')::bool     THEN updated_at END DESC,
     id ASC
 LIMIT sqlc.arg('page_size')::bigint OFFSET sqlc.arg('page_offset')::bigint;
+
+-- name: GetTitleRowsByIds :many
+SELECT * FROM titles WHERE id = ANY(sqlc.arg('ids')::text[]) ORDER BY id;
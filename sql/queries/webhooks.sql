@@ -0,0 +1,117 @@
+-- name: InsertWebhookEndpoint :one
+INSERT INTO webhook_endpoints (
+    id, group_id, url, secret, kinds, created_by, created_at, updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: GetWebhookEndpointsByGroupId :many
+SELECT * FROM webhook_endpoints
+WHERE group_id = $1
+ORDER BY created_at, id;
+
+-- name: GetWebhookEndpointById :one
+SELECT * FROM webhook_endpoints
+WHERE id = $1 AND group_id = $2;
+
+-- name: UpdateWebhookEndpoint :one
+UPDATE webhook_endpoints
+SET url = $3,
+    kinds = $4,
+    disabled_at = $5,
+    consecutive_failures = $6,
+    updated_at = $7
+WHERE id = $1 AND group_id = $2
+RETURNING *;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1 AND group_id = $2;
+
+-- name: GetWebhookEndpointsForEvent :many
+-- The endpoints an event of this kind in this group goes to. A disabled
+-- endpoint gets nothing queued while it is disabled: enabling it again starts
+-- from the next event, not from a backlog of everything it missed.
+SELECT * FROM webhook_endpoints
+WHERE group_id = $1
+  AND sqlc.arg('kind')::text = ANY(kinds)
+  AND disabled_at IS NULL
+ORDER BY id;
+
+-- name: InsertWebhookDelivery :exec
+INSERT INTO webhook_deliveries (
+    id, endpoint_id, event_id, kind, body, next_attempt_at, created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (endpoint_id, event_id) DO NOTHING;
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = sqlc.arg('endpoint_id')
+  AND (sqlc.narg('before')::bigint IS NULL OR seq < sqlc.narg('before')::bigint)
+ORDER BY seq DESC
+LIMIT sqlc.arg('row_limit')::bigint;
+
+-- name: RedeliverWebhookDelivery :one
+-- Queues the delivery again from scratch, with a fresh allowance of attempts.
+-- Its body is unchanged, so the receiver sees the same event id again.
+UPDATE webhook_deliveries
+SET status = 'pending',
+    attempts = 0,
+    next_attempt_at = sqlc.arg('now'),
+    delivered_at = NULL
+WHERE id = sqlc.arg('id') AND endpoint_id = sqlc.arg('endpoint_id')
+RETURNING *;
+
+-- name: ClaimWebhookDeliveries :many
+-- Takes the due deliveries of enabled endpoints, oldest first, and pushes each
+-- one's next_attempt_at out to lease_until in the same statement: another
+-- dispatcher skips them from here on, and if this one dies before recording
+-- an outcome they simply come due again when the lease runs out.
+UPDATE webhook_deliveries d
+SET next_attempt_at = sqlc.arg('lease_until')
+FROM webhook_endpoints e
+WHERE e.id = d.endpoint_id
+  AND d.id IN (
+      SELECT due.id
+      FROM webhook_deliveries due
+      JOIN webhook_endpoints ep ON ep.id = due.endpoint_id
+      WHERE due.status = 'pending'
+        AND due.next_attempt_at <= sqlc.arg('now')
+        AND ep.disabled_at IS NULL
+      ORDER BY due.next_attempt_at, due.seq
+      LIMIT sqlc.arg('row_limit')::bigint
+      FOR UPDATE OF due SKIP LOCKED
+  )
+RETURNING d.*, e.url, e.secret;
+
+-- name: CompleteWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = $2,
+    attempts = attempts + 1,
+    next_attempt_at = $3,
+    last_attempt_at = $4,
+    response_status = $5,
+    last_error = $6,
+    delivered_at = $7
+WHERE id = $1;
+
+-- name: ResetWebhookEndpointFailures :exec
+UPDATE webhook_endpoints
+SET consecutive_failures = 0
+WHERE id = $1;
+
+-- name: CountWebhookEndpointFailure :exec
+-- One more failure in a row; the one that reaches disable_after disables the
+-- endpoint. Counted in the statement, not by the caller, so two dispatchers
+-- failing at once both count.
+UPDATE webhook_endpoints
+SET consecutive_failures = consecutive_failures + 1,
+    disabled_at = CASE
+        WHEN disabled_at IS NULL AND consecutive_failures + 1 >= sqlc.arg('disable_after')::int
+        THEN sqlc.arg('now')::timestamptz
+        ELSE disabled_at
+    END
+WHERE id = sqlc.arg('id');
@@ -0,0 +1,32 @@
+-- +goose Up
+-- Opt-in for the collaborative half of group recommendations.
+--
+-- Recommendations are content-based by default: a group's own ratings, matched
+-- against the genres, people and interests of catalogue titles. On top of that
+-- a group can learn from how OTHER groups rated the same titles — but a group's
+-- ratings are private to its members, so reading them for anyone else's benefit
+-- needs that group's explicit consent. A row here is that consent: ratings of a
+-- group with a row are pooled (anonymously, as per-title group means — never
+-- names, never individual notes) into other groups' recommendations. No row,
+-- no pooling.
+--
+-- A table rather than a column on groups on purpose: every group read selects
+-- the group row, and none of them care about this flag. Keeping it beside the
+-- group instead of on it leaves those reads, and their generated row types,
+-- untouched. Deleting the group (hard delete) takes the consent with it; a
+-- soft-deleted group is excluded at read time, see GetSharedRatingRowsByTitleIds.
+CREATE TABLE group_recommendation_sharing (
+    group_id   TEXT PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    enabled_by TEXT NOT NULL,
+    enabled_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- The collaborative read fetches every shared rating of a candidate title
+-- (title_id = ANY(...)) across groups. ratings has no index leading with
+-- title_id — its unique key leads with user_id — so without this the read is a
+-- sequential scan of every rating in the instance.
+CREATE INDEX ratings_title_group_idx ON ratings(title_id, group_id);
+
+-- +goose Down
+DROP INDEX ratings_title_group_idx;
+DROP TABLE group_recommendation_sharing;
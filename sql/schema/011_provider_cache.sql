@@ -0,0 +1,26 @@
+-- +goose Up
+-- The persistent tier of the title provider cache (internal/titleprovider/cache).
+--
+-- The in-memory tier dies with the process, so on its own every deploy or
+-- restart starts cold and re-spends the upstream quota (OMDb allows 1,000
+-- requests a day) on titles fetched the day before. A row here survives that:
+-- one fetched title, keyed by the cache's own key ("title:tt..."), stored as
+-- the provider-neutral JSON the cache decodes back into a titleprovider.Title.
+--
+-- Only whole titles are stored, never search results: a search key is whatever
+-- a user typed, so those rows would grow with every keystroke and be read back
+-- almost never. expires_at is checked on read; expired rows are deleted by the
+-- titles routine (cmd/routines) and overwritten in place on the next fetch, so
+-- the table stays about the size of the set of titles anyone has looked at.
+--
+-- The cache is optional and off by default (TITLE_CACHE_PERSISTENT); with it
+-- off this table is simply never read or written.
+CREATE TABLE provider_cache (
+    key        TEXT PRIMARY KEY,
+    value      JSONB NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    stored_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE provider_cache;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Requests made to each title provider, per UTC day.
+--
+-- OMDb's free tier allows 1,000 requests a day, and both the server (titles
+-- added and searched interactively) and the titles routine (cmd/routines, a
+-- refresh of the whole library) spend from that one allowance. Neither process
+-- can see the other's usage in memory, so the count lives here, where both
+-- reserve a request before making it (ReserveProviderRequest): the increment
+-- only happens while the day's count is under the caller's limit, which is how
+-- the routine stops short of the share kept back for interactive adds.
+--
+-- One row per provider per day; old rows are a few bytes each and are kept as
+-- a usage history rather than cleaned up.
+CREATE TABLE provider_request_counts (
+    provider TEXT NOT NULL,
+    day      DATE NOT NULL,
+    requests INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (provider, day)
+);
+
+-- +goose Down
+DROP TABLE provider_request_counts;
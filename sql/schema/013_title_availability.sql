@@ -0,0 +1,45 @@
+-- +goose Up
+-- Where each title can be watched, per region: the streaming services that
+-- carry it on a subscription (flatrate), or offer it to rent or to buy. Filled
+-- by a streaming availability provider (STREAMING_PROVIDER) when a title is
+-- added and refreshed by the titles routine; never edited by hand.
+--
+-- A row in title_availability means "fetched": a title with a row and no
+-- offers in a region is known to be unavailable there, which is a different
+-- answer from a title never fetched at all (no row). links holds, per region
+-- code, the page the provider asks to link back to for that region.
+CREATE TABLE title_availability (
+    title_id   TEXT PRIMARY KEY REFERENCES titles(id) ON DELETE CASCADE,
+    provider   TEXT NOT NULL,
+    links      JSONB NOT NULL DEFAULT '{}',
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One row per (title, region, kind, service). Rows rather than a JSONB
+-- document so the "available on my services" filter on a group's titles can
+-- be an indexed EXISTS instead of unpacking every title's document.
+CREATE TABLE title_availability_offers (
+    title_id         TEXT NOT NULL REFERENCES title_availability(title_id) ON DELETE CASCADE,
+    region           TEXT NOT NULL,
+    kind             TEXT NOT NULL CHECK (kind IN ('flatrate', 'rent', 'buy')),
+    service_id       INTEGER NOT NULL,
+    service_name     TEXT NOT NULL,
+    logo_url         TEXT NOT NULL DEFAULT '',
+    display_priority INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (title_id, region, kind, service_id)
+);
+
+-- Each user's streaming subscriptions: the region they watch from and the
+-- provider's ids of the services they pay for. One row per user, written
+-- whole; no row means none set.
+CREATE TABLE user_streaming_services (
+    user_id     TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    region      TEXT NOT NULL,
+    service_ids INTEGER[] NOT NULL DEFAULT '{}',
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE user_streaming_services;
+DROP TABLE title_availability_offers;
+DROP TABLE title_availability;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- Activity events waiting to be delivered, written in the same transaction as
+-- the write they describe (ACTIVITY_OUTBOX_ENABLED). A row here is a promise
+-- that the event will reach activity_events: the relay copies rows across
+-- oldest first and deletes them only once every sink has taken them, so a
+-- crash between the two delivers a row twice — which is why id is assigned
+-- when the row is written here and activity_events ignores an id it already
+-- holds.
+--
+-- The columns are activity_events' own, less the feed's seq: this table's seq
+-- only orders delivery. created_at is when the write committed, not when the
+-- relay got to it, so a backlog does not make old events look new.
+CREATE TABLE activity_outbox (
+    seq        BIGSERIAL PRIMARY KEY,
+    id         TEXT NOT NULL UNIQUE,
+    group_id   TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    actor_id   TEXT NOT NULL,
+    actor_name TEXT NOT NULL,
+    kind       TEXT NOT NULL,
+    title_id   TEXT,
+    title_name TEXT,
+    payload    JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE activity_outbox;
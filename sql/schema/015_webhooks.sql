@@ -0,0 +1,59 @@
+-- +goose Up
+-- Outgoing webhooks: endpoints a group owner registers to be told about the
+-- group's activity, and the deliveries made to them.
+--
+-- secret is kept as given, not hashed: it is the HMAC key every delivery is
+-- signed with, so it has to be readable. kinds is never empty; an endpoint
+-- subscribes to the activity kinds it names and nothing else.
+--
+-- consecutive_failures counts failed attempts since the last success, across
+-- all of the endpoint's deliveries; reaching the configured limit sets
+-- disabled_at, and nothing is sent to a disabled endpoint until its owner
+-- enables it again.
+CREATE TABLE webhook_endpoints (
+    id                   TEXT PRIMARY KEY,
+    group_id             TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    url                  TEXT NOT NULL,
+    secret               TEXT NOT NULL,
+    kinds                TEXT[] NOT NULL,
+    created_by           TEXT NOT NULL,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    disabled_at          TIMESTAMPTZ,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX webhook_endpoints_group_idx ON webhook_endpoints(group_id);
+
+-- One row per (endpoint, event): the queue and the delivery log in one. A
+-- pending row is due at next_attempt_at; a claimed one has that pushed out by
+-- a lease, so a dispatcher that dies mid-delivery leaves it due again rather
+-- than lost. The unique pair is what makes enqueueing idempotent — the outbox
+-- relay can hand the same event over twice.
+--
+-- body is TEXT, not JSONB: it is sent byte for byte as built, on every
+-- attempt, and JSONB would reformat it.
+CREATE TABLE webhook_deliveries (
+    id              TEXT PRIMARY KEY,
+    seq             BIGSERIAL NOT NULL UNIQUE,
+    endpoint_id     TEXT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    event_id        TEXT NOT NULL,
+    kind            TEXT NOT NULL,
+    body            TEXT NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_attempt_at TIMESTAMPTZ,
+    response_status INTEGER,
+    last_error      TEXT NOT NULL DEFAULT '',
+    delivered_at    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (endpoint_id, event_id)
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_endpoint_seq_idx ON webhook_deliveries(endpoint_id, seq DESC);
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_endpoints;
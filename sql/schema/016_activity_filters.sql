@@ -0,0 +1,27 @@
+-- +goose Up
+-- Indexes for the activity feed's filters. Every activity read is scoped to
+-- the reader's groups first, so each index leads with group_id and ends with
+-- the order the reads page in: within one group, a filtered page is an index
+-- range read instead of a walk down activity_events_group_seq_idx discarding
+-- whatever does not match.
+--
+-- The title index is partial: most kinds carry no title, and those rows are
+-- never what a title filter looks for.
+CREATE INDEX activity_events_group_title_seq_idx
+    ON activity_events(group_id, title_id, seq DESC)
+    WHERE title_id IS NOT NULL;
+
+CREATE INDEX activity_events_group_kind_seq_idx ON activity_events(group_id, kind, seq DESC);
+
+CREATE INDEX activity_events_group_actor_seq_idx ON activity_events(group_id, actor_id, seq DESC);
+
+-- created_at gets its own index rather than riding on seq: it is the time the
+-- action happened, stamped by whoever recorded it, and an event relayed late
+-- through the outbox has a higher seq than events that happened after it.
+CREATE INDEX activity_events_group_created_at_idx ON activity_events(group_id, created_at);
+
+-- +goose Down
+DROP INDEX activity_events_group_created_at_idx;
+DROP INDEX activity_events_group_actor_seq_idx;
+DROP INDEX activity_events_group_kind_seq_idx;
+DROP INDEX activity_events_group_title_seq_idx;
@@ -0,0 +1,74 @@
+-- +goose Up
+-- Deleting a group ends every membership in it, so a group_deleted event has
+-- no members left to be visible to, and the view hid it besides: it shows
+-- nothing from a deleted group. Yet it is the one event in that group everyone
+-- who was in it needs to see.
+--
+-- The event carries who was in the group as payload.memberIds, and the view
+-- gains a second branch that reads it in place of group_members. Only
+-- group_deleted events of deleted groups take that branch, so everything else
+-- is exactly as visible as before, and nothing the badge, the feed or marking
+-- read agree on has to change: they all read through here.
+CREATE OR REPLACE VIEW activity_visible_events AS
+SELECT
+    e.id,
+    e.seq,
+    e.group_id,
+    e.actor_id,
+    e.actor_name,
+    e.kind,
+    e.title_id,
+    e.title_name,
+    e.payload,
+    e.created_at,
+    g.name AS group_name,
+    m.user_id AS reader_id
+FROM activity_events e
+JOIN group_members m ON m.group_id = e.group_id
+JOIN groups g ON g.id = e.group_id AND NOT g.deleted
+WHERE e.actor_id <> m.user_id
+UNION ALL
+SELECT
+    e.id,
+    e.seq,
+    e.group_id,
+    e.actor_id,
+    e.actor_name,
+    e.kind,
+    e.title_id,
+    e.title_name,
+    e.payload,
+    e.created_at,
+    g.name AS group_name,
+    former.user_id AS reader_id
+FROM activity_events e
+JOIN groups g ON g.id = e.group_id AND g.deleted
+CROSS JOIN LATERAL jsonb_array_elements_text(e.payload -> 'memberIds') AS former(user_id)
+WHERE e.kind = 'group_deleted'
+  AND e.actor_id <> former.user_id;
+
+-- The second branch cannot start from a reader, since the reader is inside
+-- the payload; it starts from the deletions instead, of which there are few.
+CREATE INDEX activity_events_group_deleted_idx ON activity_events(seq) WHERE kind = 'group_deleted';
+
+-- +goose Down
+DROP INDEX activity_events_group_deleted_idx;
+
+CREATE OR REPLACE VIEW activity_visible_events AS
+SELECT
+    e.id,
+    e.seq,
+    e.group_id,
+    e.actor_id,
+    e.actor_name,
+    e.kind,
+    e.title_id,
+    e.title_name,
+    e.payload,
+    e.created_at,
+    g.name AS group_name,
+    m.user_id AS reader_id
+FROM activity_events e
+JOIN group_members m ON m.group_id = e.group_id
+JOIN groups g ON g.id = e.group_id AND NOT g.deleted
+WHERE e.actor_id <> m.user_id;
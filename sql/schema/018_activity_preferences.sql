@@ -0,0 +1,125 @@
+-- +goose Up
+-- Per-user notification preferences: what a reader has muted, and when they
+-- do not want anything pushed live.
+--
+-- A mute row is one of three shapes: a kind (kind set, nothing else), a whole
+-- group (group_id alone), or one title's thread in a group (group_id and
+-- title_id). The checks keep it to those; the unique index makes saving the
+-- same mute twice one row, and leads with user_id for the view's lookup.
+CREATE TABLE activity_mutes (
+    user_id  TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    group_id TEXT REFERENCES groups(id) ON DELETE CASCADE,
+    title_id TEXT,
+    kind     TEXT,
+    CHECK ((kind IS NULL) <> (group_id IS NULL)),
+    CHECK (title_id IS NULL OR group_id IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX activity_mutes_unique_idx
+    ON activity_mutes(user_id, COALESCE(group_id, ''), COALESCE(title_id, ''), COALESCE(kind, ''));
+
+-- Quiet hours hold back live push only, so they are not in the view: what
+-- happened during them is in the feed and the badge as usual. start_minute and
+-- end_minute are minutes after midnight in time_zone, an IANA name; a window
+-- whose end is before its start runs across midnight.
+CREATE TABLE activity_quiet_hours (
+    user_id      TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    start_minute SMALLINT NOT NULL CHECK (start_minute BETWEEN 0 AND 1439),
+    end_minute   SMALLINT NOT NULL CHECK (end_minute BETWEEN 0 AND 1439),
+    time_zone    TEXT NOT NULL,
+    CHECK (start_minute <> end_minute)
+);
+
+-- A muted event is not visible to its reader, in either branch: the feed, the
+-- badge, marking read and a resuming stream all read through here, so muting
+-- takes it out of all of them at once, and unmuting puts it back as it was.
+CREATE OR REPLACE VIEW activity_visible_events AS
+SELECT
+    e.id,
+    e.seq,
+    e.group_id,
+    e.actor_id,
+    e.actor_name,
+    e.kind,
+    e.title_id,
+    e.title_name,
+    e.payload,
+    e.created_at,
+    g.name AS group_name,
+    m.user_id AS reader_id
+FROM activity_events e
+JOIN group_members m ON m.group_id = e.group_id
+JOIN groups g ON g.id = e.group_id AND NOT g.deleted
+WHERE e.actor_id <> m.user_id
+  AND NOT EXISTS (
+      SELECT 1 FROM activity_mutes mu
+      WHERE mu.user_id = m.user_id
+        AND (mu.kind = e.kind
+             OR (mu.group_id = e.group_id AND (mu.title_id IS NULL OR mu.title_id = e.title_id)))
+  )
+UNION ALL
+SELECT
+    e.id,
+    e.seq,
+    e.group_id,
+    e.actor_id,
+    e.actor_name,
+    e.kind,
+    e.title_id,
+    e.title_name,
+    e.payload,
+    e.created_at,
+    g.name AS group_name,
+    former.user_id AS reader_id
+FROM activity_events e
+JOIN groups g ON g.id = e.group_id AND g.deleted
+CROSS JOIN LATERAL jsonb_array_elements_text(e.payload -> 'memberIds') AS former(user_id)
+WHERE e.kind = 'group_deleted'
+  AND e.actor_id <> former.user_id
+  AND NOT EXISTS (
+      SELECT 1 FROM activity_mutes mu
+      WHERE mu.user_id = former.user_id
+        AND (mu.kind = e.kind OR (mu.group_id = e.group_id AND mu.title_id IS NULL))
+  );
+
+-- +goose Down
+CREATE OR REPLACE VIEW activity_visible_events AS
+SELECT
+    e.id,
+    e.seq,
+    e.group_id,
+    e.actor_id,
+    e.actor_name,
+    e.kind,
+    e.title_id,
+    e.title_name,
+    e.payload,
+    e.created_at,
+    g.name AS group_name,
+    m.user_id AS reader_id
+FROM activity_events e
+JOIN group_members m ON m.group_id = e.group_id
+JOIN groups g ON g.id = e.group_id AND NOT g.deleted
+WHERE e.actor_id <> m.user_id
+UNION ALL
+SELECT
+    e.id,
+    e.seq,
+    e.group_id,
+    e.actor_id,
+    e.actor_name,
+    e.kind,
+    e.title_id,
+    e.title_name,
+    e.payload,
+    e.created_at,
+    g.name AS group_name,
+    former.user_id AS reader_id
+FROM activity_events e
+JOIN groups g ON g.id = e.group_id AND g.deleted
+CROSS JOIN LATERAL jsonb_array_elements_text(e.payload -> 'memberIds') AS former(user_id)
+WHERE e.kind = 'group_deleted'
+  AND e.actor_id <> former.user_id;
+
+DROP TABLE activity_quiet_hours;
+DROP TABLE activity_mutes;
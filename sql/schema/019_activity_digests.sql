@@ -0,0 +1,31 @@
+-- +goose Up
+-- Email digests of unread activity, for the members who do not open the app.
+--
+-- A row is a user who asked for one, and how often. No row is no digest: the
+-- feature is opt-in, and opting out deletes the row.
+--
+-- digested_seq is what has been sent. A digest covers the reader's unread
+-- events above both their read floor and this, and sending it raises this to
+-- the newest event it covered, so the next one starts after it: nothing is
+-- mailed twice, however long it then stays unread. It starts at the newest
+-- event in the log when the user opts in — a first digest of everything they
+-- ever left unread would be a backlog, not a digest.
+--
+-- next_due_at is when the next one is due. The job claims due rows by pushing
+-- it out to a lease, as webhook deliveries are claimed (015), so several
+-- servers can run the job and one that dies mid-send only delays its batch.
+-- last_sent_at is the last digest actually mailed; a run with nothing unread
+-- moves next_due_at on without sending anything.
+CREATE TABLE activity_digests (
+    user_id      TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    frequency    TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly')),
+    digested_seq BIGINT NOT NULL,
+    next_due_at  TIMESTAMPTZ NOT NULL,
+    last_sent_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX activity_digests_next_due_at_idx ON activity_digests(next_due_at);
+
+-- +goose Down
+DROP TABLE activity_digests;
@@ -208,6 +208,34 @@ func getActivityFeedRawBody(t *testing.T, token, query string) string {
 	return string(body)
 }
 
+// getActivityHistoryResponse calls a history route — path is everything after
+// the host, query included — and returns the response for the caller to
+// assert on.
+func getActivityHistoryResponse(t *testing.T, token, path string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, testServer.URL+path, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// getActivityHistory decodes a successful history response.
+func getActivityHistory(t *testing.T, token, path string) activity.Feed {
+	t.Helper()
+
+	resp := getActivityHistoryResponse(t, token, path)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var feed activity.Feed
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&feed))
+	return feed
+}
+
 // getActivityUnreadCountResponse calls GET /activity/unread-count and returns
 // the response for the caller to assert on.
 func getActivityUnreadCountResponse(t *testing.T, token string) *http.Response {
@@ -250,8 +278,13 @@ type activityFeedFixture struct {
 }
 
 // seedActivityFeed puts an actor and a reader in one group and has the actor
-// add n titles to it, which is n events in the reader's feed and none in the
-// actor's.
+// add n titles to it, which is n unread events in the reader's feed and none
+// in the actor's.
+//
+// Being added to the group is an event too, one the reader would otherwise
+// count among the fixture's; they mark it read before the titles arrive,
+// which leaves their floor just below the fixture's events. It is still in
+// their feed, so the fixture reads its ids back by kind.
 func seedActivityFeed(t *testing.T, n int) activityFeedFixture {
 	t.Helper()
 
@@ -259,6 +292,7 @@ func seedActivityFeed(t *testing.T, n int) activityFeedFixture {
 	reader, readerToken := addUser(t, users.NewUserRequest{Username: "reader", Password: "pass"})
 	group := createGroup(t, groups.CreateGroupRequest{Name: "shared"}, actorToken)
 	addUserToGroup(t, groups.AddUserToGroupRequest{UserId: reader.Id}, group.Id, actorToken)
+	markAllActivityRead(t, readerToken)
 
 	// One more title than events: seededTitleIds is deterministic, so a case
 	// cannot simply seed another one later without colliding with these.
@@ -270,7 +304,7 @@ func seedActivityFeed(t *testing.T, n int) activityFeedFixture {
 		}, actorToken)
 	}
 
-	feed := getActivityFeed(t, readerToken, "?limit=50")
+	feed := getActivityFeed(t, readerToken, "?limit=50&kind=title_added")
 	require.Len(t, feed.Events, n, "the fixture must record exactly one event per title added")
 
 	// The feed is newest first; the ids come back oldest first so event 1 is
@@ -288,6 +322,54 @@ func seedActivityFeed(t *testing.T, n int) activityFeedFixture {
 	}
 }
 
+// setActivityPreferencesResponse calls PUT /activity/preferences and returns
+// the response for the caller to assert on.
+func setActivityPreferencesResponse(t *testing.T, token string, body activity.PreferencesRequest) *http.Response {
+	t.Helper()
+
+	jsonData, err := json.Marshal(body)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPut, testServer.URL+"/activity/preferences", bytes.NewBuffer(jsonData))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// setActivityPreferences decodes a successful PUT /activity/preferences.
+func setActivityPreferences(t *testing.T, token string, body activity.PreferencesRequest) activity.Preferences {
+	t.Helper()
+
+	resp := setActivityPreferencesResponse(t, token, body)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var prefs activity.Preferences
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&prefs))
+	return prefs
+}
+
+// getActivityPreferences decodes a successful GET /activity/preferences.
+func getActivityPreferences(t *testing.T, token string) activity.Preferences {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, testServer.URL+"/activity/preferences", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var prefs activity.Preferences
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&prefs))
+	return prefs
+}
+
 // markActivityEventReadResponse calls POST /activity/events/{id}/read and
 // returns the response for the caller to assert on.
 func markActivityEventReadResponse(t *testing.T, token, eventId string) *http.Response {
@@ -326,14 +408,15 @@ func markAllActivityRead(t *testing.T, token string) {
 	require.Equal(t, http.StatusNoContent, resp.StatusCode)
 }
 
-// activityReadStateById returns each event in token's feed by id with the read
-// flag the API reported for it. Per-row read state is a set property — "this
-// one moved and the others did not" — so cases assert on the whole map.
+// activityReadStateById returns each title_added event in token's feed by id
+// with the read flag the API reported for it. Per-row read state is a set
+// property — "this one moved and the others did not" — so cases assert on the
+// whole map, and the membership events around a fixture are left out of it.
 func activityReadStateById(t *testing.T, token string) map[string]bool {
 	t.Helper()
 
 	state := map[string]bool{}
-	for _, event := range getActivityFeed(t, token, "?limit=50").Events {
+	for _, event := range getActivityFeed(t, token, "?limit=50&kind=title_added").Events {
 		state[event.Id] = event.Read
 	}
 	return state
@@ -1,15 +1,19 @@
 package tests
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/lealre/movies-backend/internal/server"
+	"github.com/lealre/movies-backend/internal/services/activity"
 	"github.com/lealre/movies-backend/internal/services/groups"
 	"github.com/lealre/movies-backend/internal/services/ratings"
 	"github.com/lealre/movies-backend/internal/services/users"
@@ -349,6 +353,81 @@ func TestActivityIsRecorded(t *testing.T) {
 	})
 }
 
+// TestActivityMembershipEvents covers the events about a group rather than its
+// titles: who joined and left it, what it is called, and its deletion — the
+// one event its readers see after they are no longer in the group.
+func TestActivityMembershipEvents(t *testing.T) {
+	t.Run("adding a member records member_added, naming them", func(t *testing.T) {
+		resetDB(t)
+		owner, ownerToken := addUser(t, users.NewUserRequest{Username: "owner", Password: "pass"})
+		member, _ := addUser(t, users.NewUserRequest{Username: "member", Password: "pass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "club"}, ownerToken)
+
+		addUserToGroup(t, groups.AddUserToGroupRequest{UserId: member.Id}, group.Id, ownerToken)
+
+		rows := getActivityRows(t)
+		require.Len(t, rows, 1)
+		require.Equal(t, owner.Id, rows[0].ActorId, "the actor is whoever added them")
+		require.Equal(t, map[string]any{"userId": member.Id, "username": "member"}, rows[0].Payload)
+	})
+
+	t.Run("leaving records member_left, which the rest of the group sees", func(t *testing.T) {
+		resetDB(t)
+		_, ownerToken := addUser(t, users.NewUserRequest{Username: "owner", Password: "pass"})
+		member, memberToken := addUser(t, users.NewUserRequest{Username: "member", Password: "pass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "club"}, ownerToken)
+		addUserToGroup(t, groups.AddUserToGroupRequest{UserId: member.Id}, group.Id, ownerToken)
+
+		resp := removeUserFromGroupApi(t, group.Id, member.Id, memberToken)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		feed := getActivityFeed(t, ownerToken, "?kind=member_left")
+		require.Len(t, feed.Events, 1)
+		require.Equal(t, member.Id, feed.Events[0].ActorId)
+		require.Empty(t, getActivityFeed(t, memberToken, "").Events,
+			"the member who left sees nothing of the group any more, their arrival included")
+	})
+
+	t.Run("updating a group records only the fields that changed, and a no-op save nothing", func(t *testing.T) {
+		resetDB(t)
+		_, ownerToken := addUser(t, users.NewUserRequest{Username: "owner", Password: "pass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "club"}, ownerToken)
+
+		resp := updateGroupFromApi(t, group.Id, groups.UpdateGroupRequest{Name: "film club"}, ownerToken)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.Equal(t, map[string]any{"name": "film club", "previousName": "club"},
+			lastActivityPayload(t, "group_updated"))
+
+		resp = updateGroupFromApi(t, group.Id, groups.UpdateGroupRequest{Name: "film club"}, ownerToken)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 1, countActivityRows(t), "saving the same name again is not news")
+	})
+
+	t.Run("a deleted group's deletion stays in its former members' feeds", func(t *testing.T) {
+		resetDB(t)
+		fixture := seedActivityFeed(t, 2)
+		_, outsiderToken := addUser(t, users.NewUserRequest{Username: "outsider", Password: "pass"})
+
+		resp := deleteGroupFromApi(t, fixture.groupId, fixture.actorToken)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		feed := getActivityFeed(t, fixture.readerToken, "")
+		require.Len(t, feed.Events, 1, "the group's history goes with it; its deletion does not")
+		require.Equal(t, "group_deleted", feed.Events[0].Kind)
+		require.Equal(t, "shared", feed.Events[0].GroupName)
+		require.EqualValues(t, 1, getActivityUnreadCount(t, fixture.readerToken))
+		markActivityEventRead(t, fixture.readerToken, feed.Events[0].Id)
+
+		require.Empty(t, getActivityFeed(t, fixture.actorToken, "").Events, "the actor is not told what they did")
+		require.Empty(t, getActivityFeed(t, outsiderToken, "").Events)
+	})
+}
+
 // TestActivityWatchedPayload pins the payload of title_watched_changed, which
 // is one kind covering several different sentences: marked watched, marked not
 // watched, a date added where there was none, a date moved from one day to
@@ -579,8 +658,10 @@ func TestActivityFeedEndpoints(t *testing.T) {
 		}, actorToken)
 
 		readerFeed := getActivityFeed(t, readerToken, "")
-		require.Len(t, readerFeed.Events, 1, "the reader must see the actor's event")
+		require.Len(t, readerFeed.Events, 2, "the reader must see the actor's events")
 		require.Equal(t, "title_added", readerFeed.Events[0].Kind)
+		require.Equal(t, "member_added", readerFeed.Events[1].Kind,
+			"being added to the group is the actor's doing, so it is news to the reader")
 		require.Equal(t, group.Name, readerFeed.Events[0].GroupName,
 			"each row carries its group's name for the label")
 
@@ -646,9 +727,10 @@ func TestActivityFeedEndpoints(t *testing.T) {
 
 		markActivityEventRead(t, fixture.readerToken, fixture.eventIds[1])
 
-		require.EqualValues(t, 2, getActivityUnreadCount(t, fixture.readerToken))
-		require.EqualValues(t, 3, getActivityUnreadCount(t, otherToken),
-			"reading an event as one member must leave it unread for another")
+		require.EqualValues(t, 3, getActivityUnreadCount(t, fixture.readerToken),
+			"two of the titles and the other member's arrival")
+		require.EqualValues(t, 5, getActivityUnreadCount(t, otherToken),
+			"reading an event as one member must leave it unread for another: the three titles and both arrivals")
 		for id, read := range activityReadStateById(t, otherToken) {
 			require.False(t, read, "event %s must still be unread for the other member", id)
 		}
@@ -705,8 +787,10 @@ func TestActivityFeedEndpoints(t *testing.T) {
 			}, actorToken)
 		}
 
+		// The reader's own arrival is in the feed too; the walk is over the
+		// titles only.
 		seen := map[string]int{}
-		query := "?limit=5"
+		query := "?limit=5&kind=title_added"
 		for {
 			page := getActivityFeed(t, readerToken, query)
 			for _, e := range page.Events {
@@ -715,7 +799,7 @@ func TestActivityFeedEndpoints(t *testing.T) {
 			if !page.HasMore || page.NextBefore == nil {
 				break
 			}
-			query = fmt.Sprintf("?limit=5&before=%d", *page.NextBefore)
+			query = fmt.Sprintf("?limit=5&kind=title_added&before=%d", *page.NextBefore)
 		}
 
 		require.Len(t, seen, len(titleIds),
@@ -767,6 +851,150 @@ func TestActivityFeedEndpoints(t *testing.T) {
 	})
 }
 
+func TestActivityFeedFilters(t *testing.T) {
+	t.Run("the feed narrows by title, kind, actor and time", func(t *testing.T) {
+		resetDB(t)
+		fixture := seedActivityFeed(t, 3)
+		titleIds := seededTitleIds(t, 3)
+
+		feed := getActivityFeed(t, fixture.readerToken, "?titleId="+titleIds[1])
+		require.Len(t, feed.Events, 1)
+		require.Equal(t, fixture.eventIds[1], feed.Events[0].Id)
+
+		feed = getActivityFeed(t, fixture.readerToken, "?kind=title_added&kind=rating_added")
+		require.Len(t, feed.Events, 3, "kind repeats, and matches any of its values")
+		actorId := feed.Events[0].ActorId
+
+		require.Empty(t, getActivityFeed(t, fixture.readerToken, "?kind=comment_added").Events)
+		require.Len(t, getActivityFeed(t, fixture.readerToken, "?actorId="+actorId).Events, 4,
+			"the actor added the reader as well as the three titles")
+		require.Empty(t, getActivityFeed(t, fixture.readerToken, "?actorId=someone-else").Events)
+
+		future := url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))
+		require.Empty(t, getActivityFeed(t, fixture.readerToken, "?createdAfter="+future).Events)
+		require.Len(t, getActivityFeed(t, fixture.readerToken, "?createdBefore="+future).Events, 4)
+	})
+
+	t.Run("a bad filter is a 400", func(t *testing.T) {
+		resetDB(t)
+		_, token := addUser(t, users.NewUserRequest{Username: "filterer", Password: "pass"})
+
+		for _, query := range []string{
+			"?kind=no_such_kind",
+			"?createdAfter=yesterday",
+			"?createdAfter=2026-03-02T00:00:00Z&createdBefore=2026-03-01T00:00:00Z",
+		} {
+			resp := getActivityFeedResponse(t, token, query)
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode, "GET /activity%s", query)
+			resp.Body.Close()
+		}
+	})
+
+	t.Run("a group's history includes the caller's own actions", func(t *testing.T) {
+		resetDB(t)
+		fixture := seedActivityFeed(t, 2)
+
+		history := getActivityHistory(t, fixture.actorToken, "/groups/"+fixture.groupId+"/activity")
+		require.Len(t, history.Events, 3, "the actor's own actions are the group's history too: the reader's arrival and two titles")
+		for _, event := range history.Events {
+			require.True(t, event.Read, "an own action is never unread")
+		}
+		require.Empty(t, getActivityFeed(t, fixture.actorToken, "").Events, "the feed still leaves them out")
+
+		history = getActivityHistory(t, fixture.readerToken, "/groups/"+fixture.groupId+"/activity?limit=1")
+		require.Len(t, history.Events, 1)
+		require.True(t, history.HasMore, "history pages like the feed")
+	})
+
+	t.Run("a title's history is what happened to it in the group", func(t *testing.T) {
+		resetDB(t)
+		fixture := seedActivityFeed(t, 2)
+		titleIds := seededTitleIds(t, 2)
+
+		path := "/groups/" + fixture.groupId + "/titles/" + titleIds[0] + "/activity"
+		history := getActivityHistory(t, fixture.actorToken, path)
+		require.Len(t, history.Events, 1)
+		require.Equal(t, fixture.eventIds[0], history.Events[0].Id)
+	})
+
+	t.Run("history of a group the caller is not in is a 404", func(t *testing.T) {
+		resetDB(t)
+		fixture := seedActivityFeed(t, 1)
+		_, outsiderToken := addUser(t, users.NewUserRequest{Username: "outsider", Password: "pass"})
+
+		for _, path := range []string{
+			"/groups/" + fixture.groupId + "/activity",
+			"/groups/" + fixture.groupId + "/titles/" + fixture.spareTitleId + "/activity",
+		} {
+			resp := getActivityHistoryResponse(t, outsiderToken, path)
+			require.Equal(t, http.StatusNotFound, resp.StatusCode, "GET %s", path)
+			resp.Body.Close()
+		}
+	})
+}
+
+func TestActivityPreferences(t *testing.T) {
+	t.Run("a new user has muted nothing", func(t *testing.T) {
+		resetDB(t)
+		_, token := addUser(t, users.NewUserRequest{Username: "reader", Password: "pass"})
+
+		prefs := getActivityPreferences(t, token)
+		require.Equal(t, activity.Mutes{Kinds: []string{}, GroupIds: []string{}, Titles: []activity.TitleMute{}}, prefs.Mutes)
+		require.Nil(t, prefs.QuietHours)
+	})
+
+	t.Run("muting takes events out of the feed and the badge, and unmuting puts them back", func(t *testing.T) {
+		resetDB(t)
+		fixture := seedActivityFeed(t, 3)
+		titleIds := seededTitleIds(t, 3)
+
+		prefs := setActivityPreferences(t, fixture.readerToken, activity.PreferencesRequest{
+			Mutes:      activity.Mutes{Titles: []activity.TitleMute{{GroupId: fixture.groupId, TitleId: titleIds[1]}}},
+			QuietHours: &activity.QuietHours{Start: "22:00", End: "07:00", TimeZone: "Europe/Lisbon"},
+		})
+		require.Equal(t, []activity.TitleMute{{GroupId: fixture.groupId, TitleId: titleIds[1]}}, prefs.Mutes.Titles)
+		require.Equal(t, prefs, getActivityPreferences(t, fixture.readerToken), "what was saved is what is read back")
+
+		feed := getActivityFeed(t, fixture.readerToken, "?kind=title_added")
+		require.Len(t, feed.Events, 2)
+		for _, event := range feed.Events {
+			require.NotEqual(t, fixture.eventIds[1], event.Id, "the muted title's event is not in the feed")
+		}
+		require.Equal(t, int64(2), getActivityUnreadCount(t, fixture.readerToken), "nor in the badge")
+		resp := markActivityEventReadResponse(t, fixture.readerToken, fixture.eventIds[1])
+		resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode, "nor can it be marked read")
+
+		setActivityPreferences(t, fixture.readerToken, activity.PreferencesRequest{
+			Mutes: activity.Mutes{GroupIds: []string{fixture.groupId}},
+		})
+		require.Zero(t, getActivityUnreadCount(t, fixture.readerToken), "a group mute takes the whole group")
+
+		setActivityPreferences(t, fixture.readerToken, activity.PreferencesRequest{})
+		require.Equal(t, int64(3), getActivityUnreadCount(t, fixture.readerToken), "unmuted events come back unread")
+	})
+
+	t.Run("bad preferences are refused", func(t *testing.T) {
+		resetDB(t)
+		fixture := seedActivityFeed(t, 1)
+		_, outsiderToken := addUser(t, users.NewUserRequest{Username: "outsider", Password: "pass"})
+
+		for name, tc := range map[string]struct {
+			req  activity.PreferencesRequest
+			code int
+		}{
+			"unknown kind":     {activity.PreferencesRequest{Mutes: activity.Mutes{Kinds: []string{"rated"}}}, http.StatusBadRequest},
+			"group not joined": {activity.PreferencesRequest{Mutes: activity.Mutes{GroupIds: []string{fixture.groupId}}}, http.StatusNotFound},
+			"bad time":         {activity.PreferencesRequest{QuietHours: &activity.QuietHours{Start: "10pm", End: "07:00"}}, http.StatusBadRequest},
+			"bad zone":         {activity.PreferencesRequest{QuietHours: &activity.QuietHours{Start: "22:00", End: "07:00", TimeZone: "Nowhere/Else"}}, http.StatusBadRequest},
+		} {
+			resp := setActivityPreferencesResponse(t, outsiderToken, tc.req)
+			resp.Body.Close()
+			require.Equal(t, tc.code, resp.StatusCode, name)
+		}
+	})
+}
+
 // TestActivityFeedDisabled is the plug-out test (spec test 9): it is the
 // switch under test, not the feature, so it stays to this one case. The
 // shared testServer is flag-on for the whole suite (TestMain sets
@@ -844,7 +1072,7 @@ func TestActivityStreamEndToEnd(t *testing.T) {
 
 		pushed := activityFrameData(t, readActivityFrame(t, stream.r))
 
-		feedResp := getActivityFeedResponse(t, readerToken, "")
+		feedResp := getActivityFeedResponse(t, readerToken, "?kind=title_added")
 		defer feedResp.Body.Close()
 		require.Equal(t, http.StatusOK, feedResp.StatusCode)
 
@@ -916,7 +1144,7 @@ func TestActivityStreamEndToEnd(t *testing.T) {
 
 		pushed := activityFrameEvent(t, readActivityFrame(t, stream.r))
 
-		feed := getActivityFeed(t, readerToken, "")
+		feed := getActivityFeed(t, readerToken, "?kind=title_added")
 		require.Len(t, feed.Events, 1, "the already-committed event must also appear in the snapshot")
 		require.Equal(t, pushed.Id, feed.Events[0].Id,
 			"the snapshot and the stream must describe the same event")
@@ -980,7 +1208,9 @@ func TestActivityStreamEndToEnd(t *testing.T) {
 		// A reconnect takes a fresh snapshot rather than resuming from
 		// Last-Event-ID — precisely what catches the late, lower-seq event a
 		// `seq > lastDelivered` resume would have missed permanently.
-		reconnectFeed := getActivityFeed(t, readerToken, "")
+		// The reader's arrival predates both streams, so neither client saw it
+		// live; the comparison is over the titles.
+		reconnectFeed := getActivityFeed(t, readerToken, "?kind=title_added")
 		reconnectSeen := map[string]bool{}
 		for _, e := range reconnectFeed.Events {
 			reconnectSeen[e.Id] = true
@@ -1008,6 +1238,36 @@ func TestActivityStreamEndToEnd(t *testing.T) {
 		require.Equal(t, http.StatusUnauthorized, second.StatusCode,
 			"a ticket is single-use: replaying it against the real server must not open a second stream")
 	})
+
+	t.Run("a stream resumed from the last seq it saw replays what it missed", func(t *testing.T) {
+		resetDB(t)
+		first, second := seedTwoActivityTitles(t)
+
+		_, actorToken := addUser(t, users.NewUserRequest{Username: "actor", Password: "pass"})
+		reader, readerToken := addUser(t, users.NewUserRequest{Username: "reader", Password: "pass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "shared"}, actorToken)
+		addUserToGroup(t, groups.AddUserToGroupRequest{UserId: reader.Id}, group.Id, actorToken)
+
+		stream := openActivityStream(t, readerToken)
+		addTitleToGroup(t, groups.AddTitleToGroupRequest{
+			URL: fmt.Sprintf("https://www.imdb.com/title/%s/", first), GroupId: group.Id,
+		}, actorToken)
+		seen := activityFrameEvent(t, readActivityFrame(t, stream.r))
+		stream.resp.Body.Close()
+
+		// Happens while the reader is away.
+		addTitleToGroup(t, groups.AddTitleToGroupRequest{
+			URL: fmt.Sprintf("https://www.imdb.com/title/%s/", second), GroupId: group.Id,
+		}, actorToken)
+
+		ticket := mintStreamTicket(t, readerToken)
+		resp := openActivityStreamWithTicket(t, ticket.Ticket+"&lastEventId="+strconv.FormatInt(seen.Seq, 10))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		missed := activityFrameEvent(t, readActivityFrame(t, bufio.NewReader(resp.Body)))
+		require.Equal(t, second, *missed.TitleId, "the event from while the reader was away is replayed")
+		require.Greater(t, missed.Seq, seen.Seq)
+	})
 }
 
 // TestActivityReadFloor pins the storage half of read state: a floor covering
@@ -1121,8 +1381,8 @@ func TestActivityReadFloor(t *testing.T) {
 			fixture.groupId, fixture.actorToken)
 
 		backlog := getActivityUnreadCount(t, latecomerToken)
-		require.EqualValues(t, 3, backlog,
-			"a joiner with no floor sees the group's history as unread; raising a floor on join is a product decision, not an accident")
+		require.EqualValues(t, 5, backlog,
+			"a joiner with no floor sees the group's history as unread — the reader's arrival, three titles and their own arrival; raising a floor on join is a product decision, not an accident")
 
 		// And they can clear it in one move without writing three rows.
 		markAllActivityRead(t, latecomerToken)
@@ -1176,8 +1436,13 @@ func TestActivityReadFloorAcrossGroups(t *testing.T) {
 		// Now they join the older group.
 		addUserToGroup(t, groups.AddUserToGroupRequest{UserId: reader.Id}, oldGroup.Id, strangerToken)
 
-		require.EqualValues(t, 0, getActivityUnreadCount(t, readerToken),
-			"the older group's history sits below the reader's floor and arrives already read")
+		unread := getActivityFeed(t, readerToken, "?groupId="+oldGroup.Id)
+		require.Len(t, unread.Events, 3)
+		for _, event := range unread.Events {
+			require.Equal(t, event.Kind == "member_added", !event.Read,
+				"the older group's history sits below the reader's floor and arrives already read; only their arrival, which is newer, is not")
+		}
+		markAllActivityRead(t, readerToken)
 
 		// A new event in that same group is above the floor and does notify,
 		// so joining costs the backlog only, never future activity.
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/lealre/movies-backend/internal/services/availability"
+	"github.com/lealre/movies-backend/internal/services/titles"
+	"github.com/stretchr/testify/require"
+)
+
+// getStreamingServices calls GET /users/me/streaming-services and decodes a
+// successful response.
+func getStreamingServices(t *testing.T, token string) availability.Preferences {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, testServer.URL+"/users/me/streaming-services", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var prefs availability.Preferences
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&prefs))
+	return prefs
+}
+
+// putStreamingServicesResponse calls PUT /users/me/streaming-services with a
+// raw JSON body, so tests can leave fields out, and returns the raw response.
+func putStreamingServicesResponse(t *testing.T, body, token string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPut, testServer.URL+"/users/me/streaming-services", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// setStreamingServices replaces the caller's streaming services and decodes
+// the stored result.
+func setStreamingServices(t *testing.T, region string, serviceIds []int, token string) availability.Preferences {
+	t.Helper()
+
+	payload, err := json.Marshal(availability.PreferencesRequest{Region: region, ServiceIds: serviceIds})
+	require.NoError(t, err)
+	resp := putStreamingServicesResponse(t, string(payload), token)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var prefs availability.Preferences
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&prefs))
+	return prefs
+}
+
+// addTitleAsAdmin adds titleId to the catalogue through POST /titles, the path
+// that fetches its streaming availability.
+func addTitleAsAdmin(t *testing.T, titleId, token string) {
+	t.Helper()
+
+	payload, err := json.Marshal(titles.AddTitleRequest{URL: fmt.Sprintf("https://www.imdb.com/title/%s/", titleId)})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, testServer.URL+"/titles", bytes.NewBuffer(payload))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+}
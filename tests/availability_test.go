@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/services/groups"
+	"github.com/lealre/movies-backend/internal/services/users"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamingServices(t *testing.T) {
+	t.Run("Preferences default to the instance region and can be replaced", func(t *testing.T) {
+		resetDB(t)
+		_, token := addUser(t, users.NewUserRequest{Username: "streamer", Password: "testpass"})
+
+		prefs := getStreamingServices(t, token)
+		require.Equal(t, "US", prefs.Region)
+		require.Empty(t, prefs.ServiceIds)
+		require.Nil(t, prefs.UpdatedAt, "Expected no updatedAt before the user sets anything")
+
+		prefs = setStreamingServices(t, "br", []int{8, 337, 8}, token)
+		require.Equal(t, "BR", prefs.Region)
+		require.Equal(t, []int{8, 337}, prefs.ServiceIds)
+		require.NotNil(t, prefs.UpdatedAt)
+
+		require.Equal(t, prefs.ServiceIds, getStreamingServices(t, token).ServiceIds)
+	})
+
+	t.Run("Invalid preferences are rejected", func(t *testing.T) {
+		resetDB(t)
+		_, token := addUser(t, users.NewUserRequest{Username: "streamer", Password: "testpass"})
+
+		for _, body := range []string{
+			`{"region":"Brazil","serviceIds":[8]}`,
+			`{"region":"US"}`,
+			`{"region":"US","serviceIds":[0]}`,
+			`not json`,
+		} {
+			resp := putStreamingServicesResponse(t, body, token)
+			resp.Body.Close()
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode, "body %s", body)
+		}
+	})
+}
+
+func TestGroupTitlesAvailability(t *testing.T) {
+	t.Run("Adding a title fetches its availability, shown in the caller's region", func(t *testing.T) {
+		resetDB(t)
+		_, token := addUserAdminInDb(t, users.NewUserRequest{Username: "admin", Password: "#Admin1234"})
+		movie := loadTitlesFixture(t)[0]
+
+		addTitleAsAdmin(t, movie.ID, token)
+		group := createGroup(t, groups.CreateGroupRequest{Name: "streaming"}, token)
+		addTitleToGroup(t, groups.AddTitleToGroupRequest{
+			URL:     fmt.Sprintf("https://www.imdb.com/title/%s/", movie.ID),
+			GroupId: group.Id,
+		}, token)
+
+		detail := getGroupTitleDetail(t, group.Id, movie.ID, token)
+		require.NotNil(t, detail.Availability, "Expected availability fetched when the title was added")
+		require.Equal(t, "US", detail.Availability.Region)
+		require.Equal(t, "fake", detail.Availability.Provider)
+		require.Equal(t, "https://example.com/watch/"+movie.ID, detail.Availability.Link)
+		require.Len(t, detail.Availability.Flatrate, 1)
+		require.Equal(t, fakeNetflixId, detail.Availability.Flatrate[0].Id)
+		require.Equal(t, "Netflix", detail.Availability.Flatrate[0].Name)
+		require.Empty(t, detail.Availability.Rent)
+
+		setStreamingServices(t, "BR", []int{}, token)
+		single := getGroupTitleById(t, group.Id, movie.ID, token)
+		require.NotNil(t, single.Availability)
+		require.Equal(t, "BR", single.Availability.Region)
+		require.Empty(t, single.Availability.Flatrate)
+		require.Len(t, single.Availability.Rent, 1, "Expected Brazil's offers once the user watches from there")
+	})
+
+	t.Run("availableOnMyServices keeps the titles on the caller's services", func(t *testing.T) {
+		resetDB(t)
+		world := setupTwoGroups(t)
+
+		require.NoError(t, testStore.ReplaceTitleAvailability(context.Background(), models.TitleAvailability{
+			TitleID:   world.movie.ID,
+			Provider:  "fake",
+			FetchedAt: time.Now().UTC(),
+			Regions: map[string]models.RegionAvailability{
+				"US": {Flatrate: []models.StreamingService{{ID: fakeNetflixId, Name: "Netflix"}}},
+			},
+		}))
+
+		resp := getGroupTitlesResponse(t, world.groupB.Id, "availableOnMyServices=true", world.token)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, "Expected the filter to need the user's services")
+
+		page := getGroupTitlesPage(t, world.groupB.Id, "", world.token)
+		require.Equal(t, 2, page.TotalResults)
+		for _, detail := range page.Content {
+			if detail.Id == world.tvSeries.ID {
+				require.Nil(t, detail.Availability, "Expected no availability for a title never fetched")
+			}
+		}
+
+		setStreamingServices(t, "US", []int{fakeNetflixId}, world.token)
+		page = getGroupTitlesPage(t, world.groupB.Id, "availableOnMyServices=true", world.token)
+		require.Equal(t, []string{world.movie.ID}, groupTitleIds(page))
+
+		setStreamingServices(t, "US", []int{fakeNetflixId + 1}, world.token)
+		body := getGroupTitlesRawBody(t, world.groupB.Id, "availableOnMyServices=true", world.token)
+		require.Contains(t, body, `"Content":[]`, "Expected no match to be an empty page, not a missing one")
+	})
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/services/exports"
+	"github.com/lealre/movies-backend/internal/services/groups"
+	"github.com/lealre/movies-backend/internal/services/users"
+	"github.com/stretchr/testify/require"
+)
+
+// exportGroupResponse calls GET /groups/{id}/export?format=... and returns the
+// raw response for the caller to assert on.
+func exportGroupResponse(t *testing.T, groupId, format, token string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, testServer.URL+"/groups/"+groupId+"/export?format="+format, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// exportGroup downloads groupId in format and returns the body.
+func exportGroup(t *testing.T, groupId, format, token string) string {
+	t.Helper()
+
+	resp := exportGroupResponse(t, groupId, format, token)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+// exportGroupJSON downloads groupId's JSON export and decodes it.
+func exportGroupJSON(t *testing.T, groupId, token string) exports.GroupExport {
+	t.Helper()
+
+	var doc exports.GroupExport
+	require.NoError(t, json.Unmarshal([]byte(exportGroup(t, groupId, exports.FormatJSON, token)), &doc))
+	return doc
+}
+
+type exportFixture struct {
+	groupId     string
+	ownerToken  string
+	memberToken string
+	watchedAt   time.Time
+}
+
+// setupExportGroup builds a group of two members holding The Godfather,
+// watched, rated by both and commented on by the owner, The Boys with its
+// first season watched and rated by the member, and Rocky, untouched.
+func setupExportGroup(t *testing.T) exportFixture {
+	t.Helper()
+	resetDB(t)
+
+	_, ownerToken := addUser(t, users.NewUserRequest{Username: "owner", Password: "testpass"})
+	member, memberToken := addUser(t, users.NewUserRequest{Username: "member", Password: "testpass"})
+	group := createGroup(t, groups.CreateGroupRequest{Name: "Movie Night"}, ownerToken)
+	addUserToGroup(t, groups.AddUserToGroupRequest{UserId: member.Id}, group.Id, ownerToken)
+
+	for _, titleId := range []string{"tt0068646", "tt1190634", "tt0075148"} {
+		addTitleToGroup(t, groups.AddTitleToGroupRequest{URL: titleId, GroupId: group.Id}, ownerToken)
+	}
+
+	watchedAt := time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC)
+	setGroupTitleWatched(t, group.Id, "tt0068646", true, &watchedAt, ownerToken)
+	season := 1
+	applyWatchedUpdate(t, group.Id, groups.UpdateGroupTitleWatchedRequest{
+		TitleId: "tt1190634", Season: &season, Watched: watchedFlag(true), WatchedAt: watchedDate(watchedAt),
+	}, ownerToken)
+
+	addRatingAndGetResult(t, group.Id, "tt0068646", 9.5, nil, ownerToken)
+	addRatingAndGetResult(t, group.Id, "tt0068646", 7, nil, memberToken)
+	addRatingAndGetResult(t, group.Id, "tt1190634", 8, &season, memberToken)
+	addCommentAndGetResult(t, group.Id, "tt0068646", "A classic", nil, ownerToken)
+
+	return exportFixture{groupId: group.Id, ownerToken: ownerToken, memberToken: memberToken, watchedAt: watchedAt}
+}
@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lealre/movies-backend/internal/services/exports"
+	"github.com/lealre/movies-backend/internal/services/groups"
+	"github.com/lealre/movies-backend/internal/services/imports"
+	"github.com/lealre/movies-backend/internal/services/users"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExports(t *testing.T) {
+	t.Run("Exports a group as JSON", func(t *testing.T) {
+		f := setupExportGroup(t)
+
+		resp := exportGroupResponse(t, f.groupId, exports.FormatJSON, f.memberToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		require.True(t, strings.HasPrefix(resp.Header.Get("Content-Disposition"), `attachment; filename="movie-night-`))
+
+		var doc exports.GroupExport
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+		require.Equal(t, exports.DocumentVersion, doc.Version)
+		require.Equal(t, "Movie Night", doc.Group.Name)
+		require.Len(t, doc.Members, 2)
+		require.Len(t, doc.Titles, 3)
+
+		byId := map[string]exports.ExportedTitle{}
+		for _, title := range doc.Titles {
+			byId[title.TitleId] = title
+		}
+
+		godfather := byId["tt0068646"]
+		require.Equal(t, "The Godfather", godfather.Name)
+		require.True(t, godfather.Watched)
+		require.NotNil(t, godfather.WatchedAt)
+		require.True(t, godfather.WatchedAt.Equal(f.watchedAt))
+		require.Len(t, godfather.Ratings, 2)
+		require.Equal(t, "member", godfather.Ratings[0].Username)
+		require.Equal(t, 7.0, godfather.Ratings[0].Note)
+		require.Equal(t, "owner", godfather.Ratings[1].Username)
+		require.Equal(t, 9.5, godfather.Ratings[1].Note)
+		require.Len(t, godfather.Comments, 1)
+		require.Equal(t, "A classic", *godfather.Comments[0].Comment)
+
+		boys := byId["tt1190634"]
+		require.Len(t, boys.Seasons, 1)
+		require.Equal(t, 1, boys.Seasons[0].Season)
+		require.True(t, boys.Seasons[0].Watched)
+		require.Len(t, boys.Ratings, 1)
+		require.Equal(t, []exports.ExportedSeasonNote{{Season: 1, Note: 8}}, boys.Ratings[0].Seasons)
+
+		rocky := byId["tt0075148"]
+		require.False(t, rocky.Watched)
+		require.Empty(t, rocky.Ratings)
+		require.Empty(t, rocky.Comments)
+	})
+
+	t.Run("Exports a group as CSV", func(t *testing.T) {
+		f := setupExportGroup(t)
+
+		records, err := csv.NewReader(strings.NewReader(exportGroup(t, f.groupId, exports.FormatCSV, f.ownerToken))).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, "imdb_id", records[0][0])
+
+		var godfather [][]string
+		for _, record := range records[1:] {
+			if record[0] == "tt0068646" {
+				godfather = append(godfather, record)
+			}
+		}
+		require.Len(t, godfather, 2)
+		require.Equal(t, []string{"member", "7", ""}, godfather[0][8:])
+		require.Equal(t, []string{"owner", "9.5", "A classic"}, godfather[1][8:])
+		require.Equal(t, "true", godfather[1][6])
+		require.Equal(t, "2024-05-04", godfather[1][7])
+	})
+
+	t.Run("Exports the caller's movies for Letterboxd", func(t *testing.T) {
+		f := setupExportGroup(t)
+
+		records, err := csv.NewReader(strings.NewReader(exportGroup(t, f.groupId, exports.FormatLetterboxd, f.ownerToken))).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, [][]string{
+			{"imdbID", "Title", "Year", "Rating", "WatchedDate", "Review"},
+			{"tt0068646", "The Godfather", "1972", "5", "2024-05-04", "A classic"},
+		}, records)
+	})
+
+	t.Run("Rejects an unknown format", func(t *testing.T) {
+		f := setupExportGroup(t)
+
+		resp := exportGroupResponse(t, f.groupId, "xml", f.ownerToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Does not export a group to someone outside it", func(t *testing.T) {
+		f := setupExportGroup(t)
+		_, outsiderToken := addUser(t, users.NewUserRequest{Username: "outsider", Password: "testpass"})
+
+		resp := exportGroupResponse(t, f.groupId, exports.FormatJSON, outsiderToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("A JSON export imports back into another group", func(t *testing.T) {
+		f := setupExportGroup(t)
+		export := exportGroup(t, f.groupId, exports.FormatJSON, f.ownerToken)
+
+		copyGroup := createGroup(t, groups.CreateGroupRequest{Name: "Copy"}, f.ownerToken)
+		job := importExport(t, copyGroup.Id, export, f.ownerToken)
+		require.Equal(t, imports.StatusDone, job.Status)
+		require.Equal(t, imports.FormatGroupExport, job.Format)
+		require.Equal(t, 3, job.Imported)
+		// member is not in the copy, so their ratings stay behind.
+		require.Len(t, job.Partial, 2)
+
+		restored := exportGroupJSON(t, copyGroup.Id, f.ownerToken)
+		byId := map[string]exports.ExportedTitle{}
+		for _, title := range restored.Titles {
+			byId[title.TitleId] = title
+		}
+		require.Len(t, byId, 3)
+
+		godfather := byId["tt0068646"]
+		require.True(t, godfather.Watched)
+		require.True(t, godfather.WatchedAt.Equal(f.watchedAt))
+		require.Len(t, godfather.Ratings, 1)
+		require.Equal(t, 9.5, godfather.Ratings[0].Note)
+		require.Equal(t, "A classic", *godfather.Comments[0].Comment)
+
+		boys := byId["tt1190634"]
+		require.Len(t, boys.Seasons, 1)
+		require.True(t, boys.Seasons[0].Watched)
+		require.Empty(t, boys.Ratings)
+
+		again := importExport(t, copyGroup.Id, export, f.ownerToken)
+		require.Equal(t, imports.StatusDone, again.Status)
+		require.Equal(t, 3, again.Imported)
+	})
+}
@@ -3,10 +3,13 @@ package tests
 import (
 	"context"
 	"encoding/json"
+	"maps"
 	"os"
+	"slices"
 
 	"github.com/lealre/movies-backend/internal/models"
 	"github.com/lealre/movies-backend/internal/titleprovider"
+	"github.com/lealre/movies-backend/internal/titleprovider/streaming"
 )
 
 // fakeTitleProvider implements titleprovider.Provider for integration tests,
@@ -55,6 +58,53 @@ func (f *fakeTitleProvider) GetTitle(_ context.Context, imdbID string) (*titlepr
 	return &t, nil
 }
 
-func (f *fakeTitleProvider) SearchTitles(_ context.Context, _ string, _ int) ([]titleprovider.SearchItem, error) {
-	return nil, nil
+// SearchTitles answers every query with every fixture title, in ID order, up
+// to limit: the fixtures are few, and what tests search for (title references
+// resolved by name) does its own matching on the results.
+func (f *fakeTitleProvider) SearchTitles(_ context.Context, _ string, limit int) ([]titleprovider.SearchItem, error) {
+	ids := slices.Sorted(maps.Keys(f.byID))
+	items := []titleprovider.SearchItem{}
+	for _, id := range ids[:min(limit, len(ids))] {
+		d := f.byID[id]
+		items = append(items, titleprovider.SearchItem{ID: d.ID, Type: d.Type, PrimaryTitle: d.PrimaryTitle, StartYear: d.StartYear})
+	}
+	return items, nil
+}
+
+// fakeTMDBIds is the TMDB side of the fixtures, for TMDB links.
+var fakeTMDBIds = map[int]string{238: "tt0068646"}
+
+// IMDbID makes the fake a titleref.TMDBLookup, so TMDB links resolve without
+// TMDB.
+func (f *fakeTitleProvider) IMDbID(_ context.Context, _ string, tmdbID int) (string, error) {
+	if id, ok := fakeTMDBIds[tmdbID]; ok {
+		return id, nil
+	}
+	return "", titleprovider.ErrTitleNotFound
+}
+
+// fakeStreamingProvider implements streaming.Provider for integration tests:
+// every title the fake title provider knows streams on Netflix in the US and
+// is to rent in Brazil, and any other title is unknown.
+type fakeStreamingProvider struct {
+	titles *fakeTitleProvider
+}
+
+const fakeNetflixId = 8
+
+func (f *fakeStreamingProvider) Name() string { return "fake" }
+
+func (f *fakeStreamingProvider) GetAvailability(_ context.Context, imdbID string) (*streaming.Availability, error) {
+	if _, ok := f.titles.byID[imdbID]; !ok {
+		return nil, titleprovider.ErrTitleNotFound
+	}
+	return &streaming.Availability{Regions: map[string]streaming.Region{
+		"US": {
+			Link:     "https://example.com/watch/" + imdbID,
+			Flatrate: []streaming.Service{{ID: fakeNetflixId, Name: "Netflix", LogoURL: "https://example.com/netflix.png", DisplayPriority: 1}},
+		},
+		"BR": {
+			Rent: []streaming.Service{{ID: 2, Name: "Apple TV", DisplayPriority: 1}},
+		},
+	}}, nil
 }
@@ -10,11 +10,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/lealre/movies-backend/internal/activity"
 	"github.com/lealre/movies-backend/internal/api"
 	"github.com/lealre/movies-backend/internal/generics"
 	"github.com/lealre/movies-backend/internal/models"
 	"github.com/lealre/movies-backend/internal/services/groups"
 	"github.com/lealre/movies-backend/internal/services/users"
+	"github.com/lealre/movies-backend/internal/titleprovider/titleref"
 	"github.com/stretchr/testify/require"
 )
 
@@ -2740,3 +2742,200 @@ func TestGetGroupTitleById(t *testing.T) {
 		require.Equal(t, otherMovie.ID, detail.Id)
 	})
 }
+
+func TestPickGroupTitle(t *testing.T) {
+	t.Run("The same seed picks the same title and the response explains it", func(t *testing.T) {
+		resetDB(t)
+		world := setupTwoGroups(t)
+
+		seed := int64(2024)
+		first := pickGroupTitle(t, world.groupB.Id, groups.PickRequest{Seed: &seed}, world.token)
+		second := pickGroupTitle(t, world.groupB.Id, groups.PickRequest{Seed: &seed}, world.token)
+
+		require.Equal(t, seed, first.Seed)
+		require.Equal(t, first.Title.Id, second.Title.Id, "Expected a seed to make the pick reproducible")
+		require.Equal(t, 2, first.Candidates, "Expected both unwatched titles to be candidates")
+		require.NotEmpty(t, first.Reasons)
+	})
+
+	t.Run("Watched titles and titles a present member has seen are left out", func(t *testing.T) {
+		resetDB(t)
+		world := setupTwoGroups(t)
+
+		setGroupTitleWatched(t, world.groupB.Id, world.tvSeries.ID, true, nil, world.token)
+
+		pick := pickGroupTitle(t, world.groupB.Id, groups.PickRequest{}, world.token)
+		require.Equal(t, world.movie.ID, pick.Title.Id, "Expected the only unwatched title")
+		require.Equal(t, 1, pick.Candidates)
+
+		addRatingAndGetResult(t, world.groupB.Id, world.movie.ID, 7, nil, world.otherToken)
+
+		resp := pickGroupTitleResponse(t, world.groupB.Id, groups.PickRequest{
+			PresentUserIds: []string{world.otherUser.Id},
+		}, world.token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode, "Expected nothing left once the rated movie is excluded")
+	})
+
+	t.Run("Invalid requests and non-members are rejected", func(t *testing.T) {
+		resetDB(t)
+		world := setupTwoGroups(t)
+
+		weight := -1.0
+		resp := pickGroupTitleResponse(t, world.groupB.Id, groups.PickRequest{
+			Weights: &groups.PickWeights{Rating: &weight},
+		}, world.token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = pickGroupTitleResponse(t, world.groupB.Id, groups.PickRequest{
+			PresentUserIds: []string{"not-a-member"},
+		}, world.token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = pickGroupTitleResponse(t, world.groupA.Id, groups.PickRequest{}, world.otherToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode, "Expected a non-member to be told the group does not exist")
+	})
+}
+
+func TestAddTitleToGroupByReference(t *testing.T) {
+	setup := func(t *testing.T) (string, string) {
+		resetDB(t)
+		_, token := addUser(t, users.NewUserRequest{Username: "linker", Password: "testpass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "links"}, token)
+		return group.Id, token
+	}
+
+	for _, tc := range []struct {
+		name, reference, titleId string
+	}{
+		{"Bare IMDb id", "tt0068646", "tt0068646"},
+		{"Mobile IMDb link", "https://m.imdb.com/title/tt0068646/?ref_=nv_sr_srsg_0", "tt0068646"},
+		{"IMDb link without scheme", "imdb.com/title/tt0068646", "tt0068646"},
+		{"TMDB link", "https://www.themoviedb.org/movie/238-the-godfather", "tt0068646"},
+		{"Letterboxd link", "https://letterboxd.com/film/meu-nome-nao-e-johnny/", "tt1092016"},
+		{"Letterboxd link under a member", "https://letterboxd.com/someone/film/the-godfather/", "tt0068646"},
+		{"Trakt link with year", "https://trakt.tv/movies/the-godfather-1972", "tt0068646"},
+	} {
+		t.Run(tc.name+" adds the title", func(t *testing.T) {
+			groupId, token := setup(t)
+			addTitleToGroup(t, groups.AddTitleToGroupRequest{URL: tc.reference, GroupId: groupId}, token)
+			requireGroupHasTitle(t, groupId, tc.titleId)
+		})
+	}
+
+	t.Run("Links from other sites are unsupported", func(t *testing.T) {
+		groupId, token := setup(t)
+		body := addTitleReferenceToGroupError(t, "https://www.netflix.com/title/80100172", groupId, token, http.StatusBadRequest)
+		require.Contains(t, body.ErrorMessage, "netflix.com")
+		require.Empty(t, body.Source)
+	})
+
+	t.Run("Letterboxd short links are unsupported", func(t *testing.T) {
+		groupId, token := setup(t)
+		body := addTitleReferenceToGroupError(t, "https://boxd.it/2b0q", groupId, token, http.StatusBadRequest)
+		require.Equal(t, titleref.SourceLetterboxd, body.Source)
+	})
+
+	t.Run("A Trakt show link does not match a movie", func(t *testing.T) {
+		groupId, token := setup(t)
+		body := addTitleReferenceToGroupError(t, "https://trakt.tv/shows/the-godfather", groupId, token, http.StatusNotFound)
+		require.Equal(t, titleref.SourceTrakt, body.Source)
+	})
+
+	t.Run("A TMDB id without an IMDb id is not found", func(t *testing.T) {
+		groupId, token := setup(t)
+		body := addTitleReferenceToGroupError(t, "https://www.themoviedb.org/movie/1-nothing", groupId, token, http.StatusNotFound)
+		require.Equal(t, titleref.SourceTMDB, body.Source)
+		require.Empty(t, getGroup(t, groupId).Titles)
+	})
+}
+
+func TestBulkAddTitlesToGroup(t *testing.T) {
+	setup := func(t *testing.T) (string, string) {
+		resetDB(t)
+		_, token := addUser(t, users.NewUserRequest{Username: "seeder", Password: "testpass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "seeded"}, token)
+		return group.Id, token
+	}
+
+	t.Run("Adds what it can and reports every item", func(t *testing.T) {
+		groupId, token := setup(t)
+		addTitleToGroup(t, groups.AddTitleToGroupRequest{URL: "tt0068646", GroupId: groupId}, token)
+
+		resp := bulkAddTitles(t, groupId, []string{
+			"https://www.imdb.com/title/tt0068646/",
+			"https://letterboxd.com/film/meu-nome-nao-e-johnny/",
+			"tt1092016",
+			"tt0000000",
+			"https://www.netflix.com/title/1",
+		}, token)
+
+		require.Equal(t, 1, resp.Added)
+		require.Equal(t, 2, resp.AlreadyPresent)
+		require.Equal(t, 2, resp.Failed)
+		require.Len(t, resp.Results, 5)
+
+		statuses := []string{}
+		for _, r := range resp.Results {
+			statuses = append(statuses, r.Status)
+		}
+		require.Equal(t, []string{
+			groups.BulkAlreadyPresent,
+			groups.BulkAdded,
+			groups.BulkAlreadyPresent,
+			groups.BulkNotFound,
+			groups.BulkInvalidReference,
+		}, statuses)
+		require.Equal(t, "tt1092016", resp.Results[1].TitleId)
+		require.Equal(t, "Meu Nome Não é Johnny", resp.Results[1].TitleName)
+		require.NotEmpty(t, resp.Results[4].Error)
+
+		requireGroupHasTitle(t, groupId, "tt1092016")
+		require.Len(t, getGroup(t, groupId).Titles, 2)
+	})
+
+	t.Run("Records one event for the whole batch", func(t *testing.T) {
+		groupId, token := setup(t)
+		before := countActivityRows(t)
+
+		resp := bulkAddTitles(t, groupId, []string{"tt0068646", "tt1092016", "tt0075148"}, token)
+		require.Equal(t, 3, resp.Added)
+
+		rows := getActivityRows(t)
+		require.Len(t, rows, before+1, "a bulk add is one feed event, not one per title")
+		last := rows[len(rows)-1]
+		require.Equal(t, activity.KindTitlesAdded, last.Kind)
+		require.Equal(t, groupId, last.GroupId)
+		require.Nil(t, last.TitleName)
+		require.EqualValues(t, 3, last.Payload["count"])
+		require.Len(t, last.Payload["titles"], 3)
+	})
+
+	t.Run("A single added title is an ordinary title added event", func(t *testing.T) {
+		groupId, token := setup(t)
+
+		bulkAddTitles(t, groupId, []string{"tt0068646", "tt0000000"}, token)
+		rows := getActivityRows(t)
+		require.NotEmpty(t, rows)
+		last := rows[len(rows)-1]
+		require.Equal(t, activity.KindTitleAdded, last.Kind)
+		require.Equal(t, "The Godfather", *last.TitleName)
+	})
+
+	t.Run("Invalid requests are rejected", func(t *testing.T) {
+		groupId, token := setup(t)
+
+		resp := bulkAddTitlesResponse(t, groupId, nil, token)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		_, outsider := addUser(t, users.NewUserRequest{Username: "outsider", Password: "testpass"})
+		resp = bulkAddTitlesResponse(t, groupId, []string{"tt0068646"}, outsider)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+		require.Empty(t, getGroup(t, groupId).Titles)
+	})
+}
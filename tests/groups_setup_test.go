@@ -676,3 +676,88 @@ func watchedFlag(watched bool) *bool { return &watched }
 func watchedDate(when time.Time) *generics.FlexibleDate {
 	return &generics.FlexibleDate{Time: &when}
 }
+
+// pickGroupTitleResponse calls POST /groups/{id}/pick with the given request
+// and returns the raw response for the caller to assert on.
+func pickGroupTitleResponse(t *testing.T, groupId string, body groups.PickRequest, token string) *http.Response {
+	t.Helper()
+
+	payload, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, testServer.URL+"/groups/"+groupId+"/pick", bytes.NewReader(payload))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// pickGroupTitle decodes a successful pick.
+func pickGroupTitle(t *testing.T, groupId string, body groups.PickRequest, token string) groups.PickResponse {
+	t.Helper()
+
+	resp := pickGroupTitleResponse(t, groupId, body, token)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "pick request should succeed")
+
+	var result groups.PickResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return result
+}
+
+// addTitleReferenceToGroupError adds the title reference to groupId, expecting
+// it to be refused with wantStatus, and decodes the error body.
+func addTitleReferenceToGroupError(t *testing.T, reference, groupId, token string, wantStatus int) api.TitleReferenceErrorResponse {
+	t.Helper()
+
+	resp := addTitleToGroupResponse(t, groups.AddTitleToGroupRequest{URL: reference, GroupId: groupId}, token)
+	defer resp.Body.Close()
+	require.Equal(t, wantStatus, resp.StatusCode)
+
+	var body api.TitleReferenceErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Equal(t, wantStatus, body.StatusCode)
+	return body
+}
+
+// requireGroupHasTitle asserts that titleId is among groupId's titles.
+func requireGroupHasTitle(t *testing.T, groupId, titleId string) {
+	t.Helper()
+
+	groupDb := getGroup(t, groupId)
+	_, ok := groupDb.Titles[titleId]
+	require.True(t, ok, "Expected title %s in group %s", titleId, groupId)
+}
+
+// bulkAddTitlesResponse calls POST /groups/{id}/titles/bulk and returns the raw
+// response for the caller to assert on.
+func bulkAddTitlesResponse(t *testing.T, groupId string, urls []string, token string) *http.Response {
+	t.Helper()
+
+	payload, err := json.Marshal(groups.BulkAddTitlesRequest{URLs: urls})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, testServer.URL+"/groups/"+groupId+"/titles/bulk", bytes.NewBuffer(payload))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// bulkAddTitles bulk-adds urls to groupId and decodes the per-item results.
+func bulkAddTitles(t *testing.T, groupId string, urls []string, token string) groups.BulkAddTitlesResponse {
+	t.Helper()
+
+	resp := bulkAddTitlesResponse(t, groupId, urls, token)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body groups.BulkAddTitlesResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return body
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/services/imports"
+	"github.com/stretchr/testify/require"
+)
+
+// startImportResponse uploads export as the file of POST /groups/{id}/imports
+// and returns the raw response for the caller to assert on.
+func startImportResponse(t *testing.T, groupId, export, token string) *http.Response {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "ratings.csv")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(export))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req, err := http.NewRequest(http.MethodPost, testServer.URL+"/groups/"+groupId+"/imports", &body)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// getImportResponse calls GET /groups/{id}/imports/{jobId} and returns the raw
+// response.
+func getImportResponse(t *testing.T, groupId, jobId, token string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, testServer.URL+"/groups/"+groupId+"/imports/"+jobId, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// importExport starts importing export into groupId and polls the job until
+// it finishes, returning it as last polled.
+func importExport(t *testing.T, groupId, export, token string) imports.Job {
+	t.Helper()
+
+	resp := startImportResponse(t, groupId, export, token)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var job imports.Job
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+	require.NotEmpty(t, job.Id)
+
+	require.Eventually(t, func() bool {
+		resp := getImportResponse(t, groupId, job.Id, token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+		return job.Status != imports.StatusRunning
+	}, 10*time.Second, 20*time.Millisecond)
+	return job
+}
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/lealre/movies-backend/internal/services/groups"
+	"github.com/lealre/movies-backend/internal/services/imports"
+	"github.com/lealre/movies-backend/internal/services/users"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImports(t *testing.T) {
+	setup := func(t *testing.T) (users.UserResponse, string, string) {
+		resetDB(t)
+		user, token := addUser(t, users.NewUserRequest{Username: "importer", Password: "testpass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "imported"}, token)
+		return user, group.Id, token
+	}
+
+	t.Run("Imports an IMDb ratings export", func(t *testing.T) {
+		user, groupId, token := setup(t)
+		export := "Const,Your Rating,Date Rated,Title,Title Type,Year\n" +
+			"tt0068646,10,2023-04-01,The Godfather,Movie,1972\n" +
+			"tt0903747,9,2023-04-02,Breaking Bad,TV Series,2008\n" +
+			"tt0000000,5,2023-04-03,Nothing,Movie,2000\n"
+
+		job := importExport(t, groupId, export, token)
+
+		require.Equal(t, imports.StatusDone, job.Status)
+		require.Equal(t, imports.FormatIMDbRatings, job.Format)
+		require.Equal(t, 3, job.Processed)
+		require.Equal(t, 2, job.Imported)
+		require.Equal(t, 1, job.Rated)
+		require.Len(t, job.Partial, 1)
+		require.Equal(t, 3, job.Partial[0].Line)
+		require.Len(t, job.Unmatched, 1)
+		require.Equal(t, 4, job.Unmatched[0].Line)
+
+		groupDb := getGroup(t, groupId)
+		godfather := groupDb.Titles["tt0068646"]
+		require.True(t, godfather.Watched)
+		require.NotNil(t, godfather.WatchedAt)
+		require.Equal(t, "2023-04-01", godfather.WatchedAt.UTC().Format("2006-01-02"))
+		requireGroupHasTitle(t, groupId, "tt0903747")
+
+		ratingsDb := getRatingsForTitleFromDB(t, "tt0068646")
+		require.Len(t, ratingsDb, 1)
+		require.Equal(t, user.Id, ratingsDb[0].UserId)
+		require.Equal(t, 10.0, ratingsDb[0].Note)
+	})
+
+	t.Run("Matches a Letterboxd diary by title and year", func(t *testing.T) {
+		_, groupId, token := setup(t)
+		export := "Date,Name,Year,Letterboxd URI,Rating,Rewatch,Tags,Watched Date\n" +
+			"2024-01-02,The Matrix,1999,https://boxd.it/a,4.5,,,2024-01-01\n" +
+			"2024-01-02,Rocky,1976,https://boxd.it/b,,,,2024-01-01\n" +
+			"2024-01-02,Unknown Film,2020,https://boxd.it/c,3,,,2024-01-01\n"
+
+		job := importExport(t, groupId, export, token)
+
+		require.Equal(t, imports.FormatLetterboxdDiary, job.Format)
+		require.Equal(t, 2, job.Imported)
+		require.Equal(t, 1, job.Rated)
+		require.Len(t, job.Unmatched, 1)
+		require.Equal(t, "Unknown Film", job.Unmatched[0].Title)
+		require.Equal(t, 9.0, getRatingsForTitleFromDB(t, "tt0133093")[0].Note)
+		require.True(t, getGroup(t, groupId).Titles["tt0075148"].Watched)
+	})
+
+	t.Run("Importing again updates ratings and adds nothing twice", func(t *testing.T) {
+		_, groupId, token := setup(t)
+		importExport(t, groupId, "Const,Your Rating,Date Rated,Title,Title Type,Year\ntt0068646,7,2023-04-01,The Godfather,Movie,1972\n", token)
+		job := importExport(t, groupId, "Const,Your Rating,Date Rated,Title,Title Type,Year\ntt0068646,8,2023-04-01,The Godfather,Movie,1972\n", token)
+
+		require.Equal(t, 1, job.Imported)
+		require.Len(t, getGroup(t, groupId).Titles, 1)
+		ratingsDb := getRatingsForTitleFromDB(t, "tt0068646")
+		require.Len(t, ratingsDb, 1)
+		require.Equal(t, 8.0, ratingsDb[0].Note)
+	})
+
+	t.Run("Rejects what it cannot import", func(t *testing.T) {
+		_, groupId, token := setup(t)
+
+		resp := startImportResponse(t, groupId, "id,name\n1,x\n", token)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		_, outsider := addUser(t, users.NewUserRequest{Username: "outsider", Password: "testpass"})
+		resp = startImportResponse(t, groupId, "Const,Your Rating\ntt0068646,5\n", outsider)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Only the member who started an import can poll it", func(t *testing.T) {
+		_, groupId, token := setup(t)
+		member, memberToken := addUser(t, users.NewUserRequest{Username: "member", Password: "testpass"})
+		addUserToGroup(t, groups.AddUserToGroupRequest{UserId: member.Id}, groupId, token)
+
+		job := importExport(t, groupId, "Const,Your Rating\ntt0068646,5\n", token)
+
+		resp := getImportResponse(t, groupId, job.Id, memberToken)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Only the group owner can restore a group export", func(t *testing.T) {
+		f := setupExportGroup(t)
+		forged := `{"version":1,"titles":[{"titleId":"tt0068646",` +
+			`"ratings":[{"username":"owner","note":1}],` +
+			`"comments":[{"username":"owner","comment":"Overrated"}]}]}`
+
+		resp := startImportResponse(t, f.groupId, forged, f.memberToken)
+		resp.Body.Close()
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		restored := exportGroupJSON(t, f.groupId, f.ownerToken)
+		for _, title := range restored.Titles {
+			if title.TitleId != "tt0068646" {
+				continue
+			}
+			for _, rating := range title.Ratings {
+				require.NotEqual(t, 1.0, rating.Note, "no rating is forged in the owner's name")
+			}
+			require.Len(t, title.Comments, 1)
+			require.Equal(t, "A classic", *title.Comments[0].Comment)
+		}
+	})
+
+	t.Run("Restoring a group export keeps the ratings and comments already there", func(t *testing.T) {
+		f := setupExportGroup(t)
+		export := `{"version":1,"titles":[{"titleId":"tt0068646",` +
+			`"ratings":[{"username":"owner","note":1},{"username":"member","note":1}],` +
+			`"comments":[{"username":"owner","comment":"Overrated"}]}]}`
+
+		job := importExport(t, f.groupId, export, f.ownerToken)
+		require.Equal(t, imports.StatusDone, job.Status)
+		require.Equal(t, 1, job.Imported)
+		require.Zero(t, job.Rated)
+
+		restored := exportGroupJSON(t, f.groupId, f.ownerToken)
+		for _, title := range restored.Titles {
+			if title.TitleId != "tt0068646" {
+				continue
+			}
+			notes := map[string]float64{}
+			for _, rating := range title.Ratings {
+				notes[rating.Username] = rating.Note
+			}
+			require.Equal(t, map[string]float64{"owner": 9.5, "member": 7}, notes)
+			require.Len(t, title.Comments, 1)
+			require.Equal(t, "A classic", *title.Comments[0].Comment)
+		}
+	})
+}
@@ -313,3 +313,30 @@ func stripSQLComments(s string) string {
 	}
 	return strings.Join(kept, "\n")
 }
+
+// getGroupCompatibilityResponse calls GET /groups/{id}/compatibility and
+// returns the raw response.
+func getGroupCompatibilityResponse(t *testing.T, groupId, token string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, testServer.URL+"/groups/"+groupId+"/compatibility", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// getGroupCompatibility decodes a successful compatibility response.
+func getGroupCompatibility(t *testing.T, groupId, token string) ratings.GroupCompatibility {
+	t.Helper()
+
+	resp := getGroupCompatibilityResponse(t, groupId, token)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "compatibility request should succeed")
+
+	var result ratings.GroupCompatibility
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return result
+}
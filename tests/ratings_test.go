@@ -1497,3 +1497,63 @@ func TestRatingRoundingMigration(t *testing.T) {
 		require.Equal(t, float64(7.4), getSeasonRating(t, "rating-dirty-2", 2))
 	})
 }
+
+func TestGroupCompatibility(t *testing.T) {
+	season1 := 1
+	season2 := 2
+
+	t.Run("Members are compared over shared movie notes and season notes", func(t *testing.T) {
+		resetDB(t)
+		world := setupTwoGroups(t)
+
+		addRatingAndGetResult(t, world.groupB.Id, world.movie.ID, 8, nil, world.token)
+		addRatingAndGetResult(t, world.groupB.Id, world.movie.ID, 6, nil, world.otherToken)
+		addRatingAndGetResult(t, world.groupB.Id, world.tvSeries.ID, 9, &season1, world.token)
+		addRatingAndGetResult(t, world.groupB.Id, world.tvSeries.ID, 3, &season1, world.otherToken)
+		// Rated by one member only: not part of the overlap.
+		addRatingAndGetResult(t, world.groupB.Id, world.tvSeries.ID, 7, &season2, world.token)
+
+		result := getGroupCompatibility(t, world.groupB.Id, world.token)
+
+		require.Len(t, result.Pairs, 1, "Expected one pair for a two-member group")
+		pair := result.Pairs[0]
+		require.ElementsMatch(t, []string{world.user.Id, world.otherUser.Id}, []string{pair.UserA.Id, pair.UserB.Id},
+			"Expected the pair to be the group's two members")
+		require.Less(t, pair.UserA.Id, pair.UserB.Id, "Expected the pair to be ordered by user id")
+		require.Equal(t, 2, pair.Overlap, "Expected the movie note and season 1 to be the only shared notes")
+		require.NotNil(t, pair.MeanAbsoluteDifference)
+		require.Equal(t, 4.0, *pair.MeanAbsoluteDifference, "Expected (|8-6| + |9-3|) / 2")
+
+		require.Len(t, pair.Disagreements, 2, "Expected both shared notes to be listed as disagreements")
+		top := pair.Disagreements[0]
+		require.Equal(t, world.tvSeries.ID, top.TitleId, "Expected the season rating to be the largest disagreement")
+		require.NotNil(t, top.Season)
+		require.Equal(t, 1, *top.Season)
+		require.Equal(t, 6.0, top.Difference)
+		require.Equal(t, world.tvSeries.PrimaryTitle, top.PrimaryTitle, "Expected the disagreement to carry the title's name")
+	})
+
+	t.Run("Ratings from another group are not compared", func(t *testing.T) {
+		resetDB(t)
+		world := setupTwoGroups(t)
+
+		addRatingAndGetResult(t, world.groupA.Id, world.movie.ID, 10, nil, world.token)
+		addRatingAndGetResult(t, world.groupB.Id, world.movie.ID, 2, nil, world.otherToken)
+
+		result := getGroupCompatibility(t, world.groupB.Id, world.token)
+
+		require.Len(t, result.Pairs, 1)
+		require.Equal(t, 0, result.Pairs[0].Overlap, "Expected group A's rating to be invisible to group B's comparison")
+		require.Nil(t, result.Pairs[0].Correlation)
+		require.NotNil(t, result.Pairs[0].Disagreements, "Expected an empty, non-nil disagreements list")
+	})
+
+	t.Run("A non-member gets 404", func(t *testing.T) {
+		resetDB(t)
+		world := setupTwoGroups(t)
+
+		resp := getGroupCompatibilityResponse(t, world.groupA.Id, world.otherToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode, "Expected a non-member to be told the group does not exist")
+	})
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/services/groups"
+	"github.com/lealre/movies-backend/internal/services/recommendations"
+	"github.com/stretchr/testify/require"
+)
+
+// getRecommendationsResponse calls GET /groups/{id}/recommendations with a raw
+// query string (no leading "?") and returns the response for the caller to
+// assert on.
+func getRecommendationsResponse(t *testing.T, groupId, query, token string) *http.Response {
+	t.Helper()
+
+	url := testServer.URL + "/groups/" + groupId + "/recommendations"
+	if query != "" {
+		url += "?" + query
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// getRecommendations decodes a successful recommendations response.
+func getRecommendations(t *testing.T, groupId, query, token string) recommendations.Recommendations {
+	t.Helper()
+
+	resp := getRecommendationsResponse(t, groupId, query, token)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "recommendations request should succeed")
+
+	var result recommendations.Recommendations
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return result
+}
+
+// setRecommendationSharingResponse calls PUT /groups/{id}/recommendations/sharing
+// and returns the raw response.
+func setRecommendationSharingResponse(t *testing.T, groupId string, enabled bool, token string) *http.Response {
+	t.Helper()
+
+	body, err := json.Marshal(recommendations.SharingRequest{Enabled: &enabled})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPut,
+		testServer.URL+"/groups/"+groupId+"/recommendations/sharing",
+		bytes.NewBuffer(body),
+	)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// setRecommendationSharing turns a group's sharing opt-in on or off, asserting
+// success.
+func setRecommendationSharing(t *testing.T, groupId string, enabled bool, token string) {
+	t.Helper()
+
+	resp := setRecommendationSharingResponse(t, groupId, enabled, token)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "owner should be able to change sharing")
+}
+
+// recommendationFor finds one title's entry in a recommendations response, or
+// nil when it was not recommended.
+func recommendationFor(result recommendations.Recommendations, titleId string) *recommendations.Recommendation {
+	for i := range result.Results {
+		if result.Results[i].Title.Id == titleId {
+			return &result.Results[i]
+		}
+	}
+	return nil
+}
+
+// recommendationReasonKinds lists the kinds of a recommendation's reasons, in
+// order.
+func recommendationReasonKinds(rec *recommendations.Recommendation) []string {
+	kinds := make([]string, 0, len(rec.Reasons))
+	for _, reason := range rec.Reasons {
+		kinds = append(kinds, reason.Kind)
+	}
+	return kinds
+}
+
+// seedRecommendationTitles seeds n movie titles tt9000001.. with distinct IMDb
+// ratings, highest first, and returns them in that order.
+func seedRecommendationTitles(t *testing.T, n int) []models.Title {
+	t.Helper()
+
+	seeded := make([]models.Title, 0, n)
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("tt%07d", 9000001+i)
+		seeded = append(seeded, newSortableMovieTitle(id, "Recommendation "+id, 2000+i, 8.0-float64(i)*0.1, 1000, nil))
+	}
+	seedTitles(t, seeded)
+	return seeded
+}
+
+// addTitlesToGroupAndRate adds each title to the group and rates it with the
+// matching note.
+func addTitlesToGroupAndRate(t *testing.T, groupId string, notes map[string]float64, token string) {
+	t.Helper()
+
+	for titleId, note := range notes {
+		addTitleToGroup(t, groups.AddTitleToGroupRequest{
+			URL:     fmt.Sprintf("https://www.imdb.com/title/%s/", titleId),
+			GroupId: groupId,
+		}, token)
+		addRatingAndGetResult(t, groupId, titleId, note, nil, token)
+	}
+}
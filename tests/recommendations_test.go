@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/lealre/movies-backend/internal/services/groups"
+	"github.com/lealre/movies-backend/internal/services/users"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupRecommendations(t *testing.T) {
+	t.Run("recommends unseen titles only, flagging the ones already on the list", func(t *testing.T) {
+		resetDB(t)
+
+		_, token := addUser(t, users.NewUserRequest{Username: "recommender", Password: "testpass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "recommendations"}, token)
+		seeded := seedRecommendationTitles(t, 4)
+		rated, watched, listed, catalogueOnly := seeded[0].ID, seeded[1].ID, seeded[2].ID, seeded[3].ID
+
+		addTitlesToGroupAndRate(t, group.Id, map[string]float64{rated: 9}, token)
+		addTitleToGroup(t, groups.AddTitleToGroupRequest{URL: fmt.Sprintf("https://www.imdb.com/title/%s/", watched), GroupId: group.Id}, token)
+		setGroupTitleWatched(t, group.Id, watched, true, nil, token)
+		addTitleToGroup(t, groups.AddTitleToGroupRequest{URL: fmt.Sprintf("https://www.imdb.com/title/%s/", listed), GroupId: group.Id}, token)
+
+		result := getRecommendations(t, group.Id, "", token)
+
+		require.Nil(t, recommendationFor(result, rated), "a rated title must not be recommended")
+		require.Nil(t, recommendationFor(result, watched), "a watched title must not be recommended")
+		require.NotNil(t, recommendationFor(result, listed), "an unwatched title on the list should be recommended")
+		require.True(t, recommendationFor(result, listed).InGroup, "a title on the list should be flagged inGroup")
+		require.NotNil(t, recommendationFor(result, catalogueOnly), "a catalogue title should be recommended")
+		require.False(t, recommendationFor(result, catalogueOnly).InGroup, "a catalogue-only title is not inGroup")
+		require.Contains(t, recommendationReasonKinds(recommendationFor(result, catalogueOnly)), "content",
+			"a title sharing a genre with a highly rated one should carry a content reason")
+	})
+
+	t.Run("a non-member gets 404", func(t *testing.T) {
+		resetDB(t)
+
+		_, ownerToken := addUser(t, users.NewUserRequest{Username: "owner", Password: "testpass"})
+		_, outsiderToken := addUser(t, users.NewUserRequest{Username: "outsider", Password: "testpass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "private"}, ownerToken)
+
+		resp := getRecommendationsResponse(t, group.Id, "", outsiderToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode, "a non-member must not read a group's recommendations")
+	})
+
+	t.Run("only the owner can change sharing", func(t *testing.T) {
+		resetDB(t)
+
+		_, ownerToken := addUser(t, users.NewUserRequest{Username: "owner", Password: "testpass"})
+		member, memberToken := addUser(t, users.NewUserRequest{Username: "member", Password: "testpass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "shared"}, ownerToken)
+		addUserToGroup(t, groups.AddUserToGroupRequest{UserId: member.Id}, group.Id, ownerToken)
+
+		resp := setRecommendationSharingResponse(t, group.Id, true, memberToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusForbidden, resp.StatusCode, "a member who is not the owner cannot opt the group in")
+
+		setRecommendationSharing(t, group.Id, true, ownerToken)
+		require.True(t, getRecommendations(t, group.Id, "", memberToken).Sharing, "the opt-in should be reported")
+	})
+
+	t.Run("another group's ratings count only once it opts in", func(t *testing.T) {
+		resetDB(t)
+
+		_, mineToken := addUser(t, users.NewUserRequest{Username: "mine", Password: "testpass"})
+		_, peerToken := addUser(t, users.NewUserRequest{Username: "peer", Password: "testpass"})
+		mine := createGroup(t, groups.CreateGroupRequest{Name: "mine"}, mineToken)
+		peer := createGroup(t, groups.CreateGroupRequest{Name: "peer"}, peerToken)
+		seeded := seedRecommendationTitles(t, 3)
+		first, second, peerFavourite := seeded[0].ID, seeded[1].ID, seeded[2].ID
+
+		addTitlesToGroupAndRate(t, mine.Id, map[string]float64{first: 9, second: 3}, mineToken)
+		addTitlesToGroupAndRate(t, peer.Id, map[string]float64{first: 9, second: 3, peerFavourite: 10}, peerToken)
+
+		before := recommendationFor(getRecommendations(t, mine.Id, "", mineToken), peerFavourite)
+		require.NotNil(t, before, "the peer's favourite is unseen, so it is a candidate")
+		require.NotContains(t, recommendationReasonKinds(before), "collaborative",
+			"a group that has not opted in must not influence anyone else's recommendations")
+
+		setRecommendationSharing(t, peer.Id, true, peerToken)
+
+		after := recommendationFor(getRecommendations(t, mine.Id, "", mineToken), peerFavourite)
+		require.NotNil(t, after)
+		require.Contains(t, recommendationReasonKinds(after), "collaborative",
+			"an opted-in group with the same taste should add a collaborative reason")
+		require.Greater(t, after.Score, before.Score, "the collaborative signal should raise the score")
+	})
+}
@@ -62,13 +62,24 @@ func TestMain(m *testing.M) {
 	// t.Setenv is per-test and cannot reach a server already built, so the
 	// flag is set here, before the server is constructed, for the whole suite.
 	os.Setenv("ACTIVITY_FEED_ENABLED", "true")
-
-	// Bounds the background work the server starts (the activity LISTEN loop),
-	// so it and its dedicated connection go away with the suite instead of
-	// outliving the pool they are using.
+	// The same goes for webhooks, which hang off the feed.
+	os.Setenv("WEBHOOKS_ENABLED", "true")
+	// The receivers the webhook tests register are httptest servers on
+	// 127.0.0.1, which is refused unless allowed.
+	os.Setenv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "true")
+	// Merging quick successive edits is off: the emit-site tests make two
+	// edits in a row on purpose, to pin what each one records. The merge is
+	// pinned against the store in internal/postgres.
+	os.Setenv("ACTIVITY_COALESCE_WINDOW", "0s")
+
+	// Bounds the background work the server starts (the activity LISTEN loop
+	// and the webhook dispatcher), so it and its dedicated connection go away
+	// with the suite instead of outliving the pool they are using.
 	serverCtx, stopServerWork := context.WithCancel(ctx)
 
-	handler := server.NewServerWithProvider(serverCtx, testStore, newFakeTitleProvider(), "test-secret")
+	titleProvider := newFakeTitleProvider()
+	handler := server.NewServerWithProviders(serverCtx, testStore, titleProvider,
+		&fakeStreamingProvider{titles: titleProvider}, "test-secret")
 	testServer = httptest.NewServer(handler)
 
 	code := m.Run()
@@ -97,7 +108,11 @@ func resetDB(t *testing.T) {
 	t.Helper()
 	const stmt = `TRUNCATE users, titles, ratings, rating_seasons, comments,
 		comment_seasons, groups, group_members, group_titles,
-		group_title_seasons, activity_events, activity_event_reads, activity_read_floors
+		group_title_seasons, activity_events, activity_event_reads, activity_read_floors, activity_outbox,
+		webhook_endpoints, webhook_deliveries,
+		activity_mutes, activity_quiet_hours, activity_digests,
+		group_recommendation_sharing, provider_cache, provider_request_counts,
+		title_availability, title_availability_offers, user_streaming_services
 		RESTART IDENTITY CASCADE`
 	if _, err := testPool.Exec(context.Background(), stmt); err != nil {
 		t.Fatalf("failed to reset db: %v", err)
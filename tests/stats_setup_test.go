@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/lealre/movies-backend/internal/services/stats"
+	"github.com/stretchr/testify/require"
+)
+
+// getGroupStatsResponse calls GET /groups/{id}/stats with a raw query string
+// (no leading "?") and returns the response for the caller to assert on.
+func getGroupStatsResponse(t *testing.T, groupId, query, token string) *http.Response {
+	t.Helper()
+
+	url := testServer.URL + "/groups/" + groupId + "/stats"
+	if query != "" {
+		url += "?" + query
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// getGroupStats decodes a successful stats response.
+func getGroupStats(t *testing.T, groupId, query, token string) stats.GroupStats {
+	t.Helper()
+
+	resp := getGroupStatsResponse(t, groupId, query, token)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "stats request should succeed")
+
+	var result stats.GroupStats
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return result
+}
+
+// getYearInReviewResponse calls a year-in-review route by its path (e.g.
+// "/groups/{id}/year-in-review/2025") and returns the raw response.
+func getYearInReviewResponse(t *testing.T, path, token string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, testServer.URL+path, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// getYearInReview decodes a successful year-in-review response.
+func getYearInReview(t *testing.T, path, token string) stats.YearInReview {
+	t.Helper()
+
+	resp := getYearInReviewResponse(t, path, token)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "year-in-review request should succeed")
+
+	var result stats.YearInReview
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return result
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupStats(t *testing.T) {
+	t.Run("Stats reflect watches and ratings, and a later write is not hidden by the cache", func(t *testing.T) {
+		resetDB(t)
+		world := setupTwoGroups(t)
+
+		watchedAt := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+		setGroupTitleWatched(t, world.groupB.Id, world.movie.ID, true, &watchedAt, world.token)
+		addRatingAndGetResult(t, world.groupB.Id, world.movie.ID, 9, nil, world.token)
+
+		first := getGroupStats(t, world.groupB.Id, "", world.token)
+		require.Equal(t, 2, first.TotalTitles, "Expected both of group B's titles to be counted")
+		require.Equal(t, 1, first.WatchedTitles, "Expected the watched movie to be counted")
+		require.Len(t, first.WatchesByMonth, 1)
+		require.Equal(t, "2025-03", first.WatchesByMonth[0].Month)
+		require.Equal(t, 1, first.Ratings.RatedTitles)
+		require.Len(t, first.Members, 2, "Expected one entry per current member")
+
+		// Served from the cache on an unchanged group.
+		again := getGroupStats(t, world.groupB.Id, "", world.token)
+		require.Equal(t, first, again, "Expected an unchanged group to return the same stats")
+
+		addRatingAndGetResult(t, world.groupB.Id, world.movie.ID, 3, nil, world.otherToken)
+
+		after := getGroupStats(t, world.groupB.Id, "", world.token)
+		require.Len(t, after.MostDivisive, 1, "Expected the new rating to show up despite the cached entry")
+		require.Equal(t, world.movie.ID, after.MostDivisive[0].TitleId)
+	})
+
+	t.Run("A date range excludes watches outside it", func(t *testing.T) {
+		resetDB(t)
+		world := setupTwoGroups(t)
+
+		watchedAt := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+		setGroupTitleWatched(t, world.groupB.Id, world.movie.ID, true, &watchedAt, world.token)
+
+		inside := getGroupStats(t, world.groupB.Id, "from=2025-03-01&to=2025-03-31", world.token)
+		require.Equal(t, 1, inside.WatchedTitles)
+		require.NotNil(t, inside.From)
+		require.Equal(t, "2025-03-01", *inside.From)
+
+		outside := getGroupStats(t, world.groupB.Id, "from=2025-04-01", world.token)
+		require.Equal(t, 0, outside.WatchedTitles, "Expected a watch before the range to be left out")
+	})
+
+	t.Run("Invalid ranges and non-members are rejected", func(t *testing.T) {
+		resetDB(t)
+		world := setupTwoGroups(t)
+
+		resp := getGroupStatsResponse(t, world.groupB.Id, "from=2025-05-01&to=2025-04-01", world.token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = getGroupStatsResponse(t, world.groupA.Id, "", world.otherToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode, "Expected a non-member to be told the group does not exist")
+	})
+}
+
+func TestYearInReview(t *testing.T) {
+	t.Run("Group and user recaps count the year's dated watches", func(t *testing.T) {
+		resetDB(t)
+		world := setupTwoGroups(t)
+
+		watchedAt := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+		setGroupTitleWatched(t, world.groupA.Id, world.movie.ID, true, &watchedAt, world.token)
+		// The same movie in a second group: one title in the user's recap.
+		setGroupTitleWatched(t, world.groupB.Id, world.movie.ID, true, &watchedAt, world.token)
+		addRatingAndGetResult(t, world.groupB.Id, world.movie.ID, 8, nil, world.otherToken)
+
+		group := getYearInReview(t, "/groups/"+world.groupB.Id+"/year-in-review/2025", world.token)
+		require.Equal(t, "group", group.Scope)
+		require.Equal(t, 1, group.ThisYear.TitlesWatched)
+		require.NotNil(t, group.FirstWatch)
+		require.Equal(t, world.movie.ID, group.FirstWatch.TitleId)
+		require.Len(t, group.Members, 2, "Expected one entry per current member")
+
+		user := getYearInReview(t, "/users/me/year-in-review/2025", world.token)
+		require.Equal(t, "user", user.Scope)
+		require.Equal(t, world.user.Id, user.Id)
+		require.Equal(t, 1, user.ThisYear.Watches, "Expected a title watched in two groups to count once")
+		require.Empty(t, user.Members, "Expected no member breakdown on a user recap")
+
+		previous := getYearInReview(t, "/groups/"+world.groupB.Id+"/year-in-review/2024", world.token)
+		require.Equal(t, 0, previous.ThisYear.TitlesWatched, "Expected nothing watched in 2024")
+		require.Equal(t, 0, group.LastYear.TitlesWatched, "Expected 2025's recap to compare against an empty 2024")
+	})
+
+	t.Run("The card is self-contained HTML", func(t *testing.T) {
+		resetDB(t)
+		world := setupTwoGroups(t)
+
+		resp := getYearInReviewResponse(t, "/groups/"+world.groupB.Id+"/year-in-review/2025/card", world.token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+		require.Contains(t, resp.Header.Get("Content-Security-Policy"), "default-src 'none'")
+	})
+
+	t.Run("A bad year is a 400 and a non-member gets 404", func(t *testing.T) {
+		resetDB(t)
+		world := setupTwoGroups(t)
+
+		resp := getYearInReviewResponse(t, "/users/me/year-in-review/last", world.token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = getYearInReviewResponse(t, "/groups/"+world.groupA.Id+"/year-in-review/2025", world.otherToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
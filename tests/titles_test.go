@@ -2,16 +2,19 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/lealre/movies-backend/internal/api"
 	"github.com/lealre/movies-backend/internal/generics"
 	"github.com/lealre/movies-backend/internal/models"
 	"github.com/lealre/movies-backend/internal/services/titles"
 	"github.com/lealre/movies-backend/internal/services/users"
+	"github.com/lealre/movies-backend/internal/titleprovider/quota"
 	"github.com/stretchr/testify/require"
 )
 
@@ -51,6 +54,7 @@ func TestAddTitlesAdmin(t *testing.T) {
 		require.Equal(t, expectedTitle.PrimaryTitle, titleResp.PrimaryTitle)
 		require.Equal(t, expectedTitle.Type, titleResp.Type)
 		require.NotNil(t, titleResp.AddedAt, "the addedAt field should not be nil when adding a title")
+		require.Equal(t, "fake", titleResp.Source, "the title should record the provider that supplied it")
 	})
 
 	t.Run("Test adding a title with an unresolvable IMDb URL should return 404", func(t *testing.T) {
@@ -297,3 +301,52 @@ func TestGetTitleEpisodes(t *testing.T) {
 	defer resp2.Body.Close()
 	require.Equal(t, http.StatusNotFound, resp2.StatusCode)
 }
+
+func TestGetTitleProviderQuota(t *testing.T) {
+	resetDB(t)
+	ctx := context.Background()
+	for range 3 {
+		ok, err := testStore.ReserveProviderRequest(ctx, "omdb", 1000)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	t.Run("Test reading today's quota as admin sucessfully", func(t *testing.T) {
+		t.Setenv("OMDB_DAILY_BUDGET", "500")
+		t.Setenv("OMDB_INTERACTIVE_RESERVE", "50")
+		_, token := addUserAdminInDb(t, users.NewUserRequest{
+			Username: "adminusertest",
+			Password: "#Usertest1234",
+		})
+
+		req, err := http.NewRequest(http.MethodGet, testServer.URL+"/titles/provider/quota", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var report quota.Report
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		require.Equal(t, time.Now().UTC().Format(time.DateOnly), report.Day)
+		require.Equal(t, []quota.Usage{
+			{Provider: "omdb", Requests: 3, Budget: 500, Reserve: 50, Remaining: 497},
+		}, report.Providers)
+	})
+
+	t.Run("Test reading the quota as regular user should return 403", func(t *testing.T) {
+		_, token := addUser(t, users.NewUserRequest{
+			Username: "usertest",
+			Password: "#Usertest1234",
+		})
+
+		req, err := http.NewRequest(http.MethodGet, testServer.URL+"/titles/provider/quota", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/lealre/movies-backend/internal/services/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookRequest sends method to a /groups/{id}/webhooks path with an optional
+// JSON body and returns the response for the caller to assert on.
+func webhookRequest(t *testing.T, method, path string, body any, token string) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewBuffer(raw)
+	}
+
+	req, err := http.NewRequest(method, testServer.URL+path, reader)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// decodeWebhookResponse asserts the status and decodes the body into out.
+func decodeWebhookResponse(t *testing.T, resp *http.Response, status int, out any) {
+	t.Helper()
+	defer resp.Body.Close()
+
+	require.Equal(t, status, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+}
+
+// createWebhook registers url on groupId for kinds, asserting success.
+func createWebhook(t *testing.T, groupId, url string, kinds []string, token string) webhooks.Webhook {
+	t.Helper()
+
+	var webhook webhooks.Webhook
+	resp := webhookRequest(t, http.MethodPost, "/groups/"+groupId+"/webhooks",
+		webhooks.CreateWebhookRequest{URL: url, Kinds: kinds}, token)
+	decodeWebhookResponse(t, resp, http.StatusCreated, &webhook)
+	return webhook
+}
+
+// getWebhookDeliveries reads an endpoint's delivery log, asserting success.
+func getWebhookDeliveries(t *testing.T, groupId, webhookId, token string) webhooks.Deliveries {
+	t.Helper()
+
+	var page webhooks.Deliveries
+	resp := webhookRequest(t, http.MethodGet, "/groups/"+groupId+"/webhooks/"+webhookId+"/deliveries", nil, token)
+	decodeWebhookResponse(t, resp, http.StatusOK, &page)
+	return page
+}
+
+// webhookReceiver is an endpoint to deliver to, answering every request with
+// status and keeping what it was sent.
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	Header http.Header
+	Body   []byte
+}
+
+func newWebhookReceiver(t *testing.T, status int) *webhookReceiver {
+	t.Helper()
+
+	rec := &webhookReceiver{status: status}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.requests = append(rec.requests, receivedWebhook{Header: r.Header.Clone(), Body: body})
+		w.WriteHeader(rec.status)
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+func (r *webhookReceiver) received() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.requests...)
+}
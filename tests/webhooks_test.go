@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lealre/movies-backend/internal/activity"
+	"github.com/lealre/movies-backend/internal/models"
+	"github.com/lealre/movies-backend/internal/services/groups"
+	"github.com/lealre/movies-backend/internal/services/users"
+	"github.com/lealre/movies-backend/internal/services/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookDeliveryTimeout covers the dispatcher's poll interval with room to
+// spare: a new delivery waits for the next poll, not for a wake-up.
+const webhookDeliveryTimeout = 15 * time.Second
+
+func TestWebhookEndpoints(t *testing.T) {
+	t.Run("the owner manages endpoints and sees the secret only once", func(t *testing.T) {
+		resetDB(t)
+
+		_, token := addUser(t, users.NewUserRequest{Username: "owner", Password: "testpass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "hooks"}, token)
+
+		created := createWebhook(t, group.Id, "https://example.com/hook", []string{activity.KindTitleAdded}, token)
+		require.NotEmpty(t, created.Secret, "the create response carries the signing secret")
+		require.True(t, created.Enabled)
+
+		var listed []webhooks.Webhook
+		decodeWebhookResponse(t, webhookRequest(t, http.MethodGet, "/groups/"+group.Id+"/webhooks", nil, token),
+			http.StatusOK, &listed)
+		require.Len(t, listed, 1)
+		require.Empty(t, listed[0].Secret, "the secret is never shown again")
+
+		var updated webhooks.Webhook
+		enabled := false
+		kinds := []string{activity.KindRatingAdded, activity.KindCommentAdded}
+		decodeWebhookResponse(t, webhookRequest(t, http.MethodPatch, "/groups/"+group.Id+"/webhooks/"+created.Id,
+			webhooks.UpdateWebhookRequest{Kinds: &kinds, Enabled: &enabled}, token), http.StatusOK, &updated)
+		require.False(t, updated.Enabled)
+		require.NotNil(t, updated.DisabledAt)
+		require.Equal(t, kinds, updated.Kinds)
+		require.Equal(t, "https://example.com/hook", updated.URL, "what the update leaves out is kept")
+
+		resp := webhookRequest(t, http.MethodDelete, "/groups/"+group.Id+"/webhooks/"+created.Id, nil, token)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = webhookRequest(t, http.MethodDelete, "/groups/"+group.Id+"/webhooks/"+created.Id, nil, token)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("only the owner can manage endpoints", func(t *testing.T) {
+		resetDB(t)
+
+		_, ownerToken := addUser(t, users.NewUserRequest{Username: "owner", Password: "testpass"})
+		member, memberToken := addUser(t, users.NewUserRequest{Username: "member", Password: "testpass"})
+		_, outsiderToken := addUser(t, users.NewUserRequest{Username: "outsider", Password: "testpass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "hooks"}, ownerToken)
+		addUserToGroup(t, groups.AddUserToGroupRequest{UserId: member.Id}, group.Id, ownerToken)
+
+		body := webhooks.CreateWebhookRequest{URL: "https://example.com/hook", Kinds: []string{activity.KindTitleAdded}}
+		resp := webhookRequest(t, http.MethodPost, "/groups/"+group.Id+"/webhooks", body, memberToken)
+		resp.Body.Close()
+		require.Equal(t, http.StatusForbidden, resp.StatusCode, "a member who is not the owner cannot add one")
+
+		resp = webhookRequest(t, http.MethodGet, "/groups/"+group.Id+"/webhooks", nil, outsiderToken)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode, "a non-member cannot learn the group exists")
+	})
+
+	t.Run("an endpoint that cannot be delivered to is rejected", func(t *testing.T) {
+		resetDB(t)
+
+		_, token := addUser(t, users.NewUserRequest{Username: "owner", Password: "testpass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "hooks"}, token)
+
+		for _, body := range []webhooks.CreateWebhookRequest{
+			{URL: "not a url", Kinds: []string{activity.KindTitleAdded}},
+			{URL: "https://example.com/hook"},
+			{URL: "https://example.com/hook", Kinds: []string{"title_teleported"}},
+		} {
+			resp := webhookRequest(t, http.MethodPost, "/groups/"+group.Id+"/webhooks", body, token)
+			resp.Body.Close()
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode, "%+v", body)
+		}
+	})
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	t.Run("a subscribed event is delivered signed and logged", func(t *testing.T) {
+		resetDB(t)
+
+		_, token := addUser(t, users.NewUserRequest{Username: "owner", Password: "testpass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "hooks"}, token)
+		titleId, _ := seedTwoActivityTitles(t)
+		receiver := newWebhookReceiver(t, http.StatusNoContent)
+		webhook := createWebhook(t, group.Id, receiver.URL, []string{activity.KindTitleAdded}, token)
+
+		addTitleToGroup(t, groups.AddTitleToGroupRequest{
+			URL: fmt.Sprintf("https://www.imdb.com/title/%s/", titleId), GroupId: group.Id,
+		}, token)
+
+		require.Eventually(t, func() bool { return len(receiver.received()) == 1 },
+			webhookDeliveryTimeout, 50*time.Millisecond, "the title_added event should be delivered")
+		got := receiver.received()[0]
+
+		require.Equal(t, activity.KindTitleAdded, got.Header.Get(webhooks.HeaderEvent))
+		timestamp, err := strconv.ParseInt(got.Header.Get(webhooks.HeaderTimestamp), 10, 64)
+		require.NoError(t, err)
+		require.Equal(t, webhooks.Sign(webhook.Secret, timestamp, got.Body), got.Header.Get(webhooks.HeaderSignature))
+
+		var event webhooks.Event
+		require.NoError(t, json.Unmarshal(got.Body, &event))
+		require.Equal(t, group.Id, event.GroupId)
+		require.Equal(t, titleId, *event.TitleId)
+		var eventId string
+		require.NoError(t, testPool.QueryRow(context.Background(), "SELECT id FROM activity_events").Scan(&eventId))
+		require.Equal(t, eventId, event.Id, "the delivery names the event by its feed id")
+
+		require.Eventually(t, func() bool {
+			page := getWebhookDeliveries(t, group.Id, webhook.Id, token)
+			return len(page.Deliveries) == 1 && page.Deliveries[0].Status == models.WebhookDeliverySucceeded
+		}, webhookDeliveryTimeout, 50*time.Millisecond, "the log should record the success")
+	})
+
+	t.Run("a failed delivery is logged for retry and can be redelivered", func(t *testing.T) {
+		resetDB(t)
+
+		_, token := addUser(t, users.NewUserRequest{Username: "owner", Password: "testpass"})
+		group := createGroup(t, groups.CreateGroupRequest{Name: "hooks"}, token)
+		titleId, _ := seedTwoActivityTitles(t)
+		receiver := newWebhookReceiver(t, http.StatusInternalServerError)
+		webhook := createWebhook(t, group.Id, receiver.URL, []string{activity.KindTitleAdded}, token)
+
+		addTitleToGroup(t, groups.AddTitleToGroupRequest{
+			URL: fmt.Sprintf("https://www.imdb.com/title/%s/", titleId), GroupId: group.Id,
+		}, token)
+
+		var delivery webhooks.Delivery
+		require.Eventually(t, func() bool {
+			page := getWebhookDeliveries(t, group.Id, webhook.Id, token)
+			if len(page.Deliveries) != 1 || page.Deliveries[0].Attempts != 1 {
+				return false
+			}
+			delivery = page.Deliveries[0]
+			return true
+		}, webhookDeliveryTimeout, 50*time.Millisecond, "the failed attempt should be logged")
+		require.Equal(t, models.WebhookDeliveryPending, delivery.Status, "a failure is retried, not given up on")
+		require.Equal(t, http.StatusInternalServerError, *delivery.ResponseStatus)
+		require.NotNil(t, delivery.NextAttemptAt)
+		require.True(t, delivery.NextAttemptAt.After(time.Now()), "the retry waits out the back-off")
+
+		var redelivered webhooks.Delivery
+		decodeWebhookResponse(t, webhookRequest(t, http.MethodPost,
+			"/groups/"+group.Id+"/webhooks/"+webhook.Id+"/deliveries/"+delivery.Id+"/redeliver", nil, token),
+			http.StatusAccepted, &redelivered)
+		require.Zero(t, redelivered.Attempts, "a redelivery starts with a fresh allowance of attempts")
+
+		require.Eventually(t, func() bool { return len(receiver.received()) == 2 },
+			webhookDeliveryTimeout, 50*time.Millisecond, "the redelivery should go out at the next poll")
+		first, second := receiver.received()[0], receiver.received()[1]
+		require.Equal(t, first.Body, second.Body, "a redelivery sends the same event")
+	})
+}